@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for attach command
+	attachVersion     string
+	attachName        string
+	attachContentType string
+
+	// attachCmd represents the attach command
+	attachCmd = &cobra.Command{
+		Use:   "attach <url> <file>...",
+		Short: "Attach files to an archived version of a page",
+		Long: `Keep files such as a screenshot, a HAR file or a rendered PDF with a
+version of a page archived by 'hawkeye watch --archive' or
+'hawkeye serve --archive'. Attachments are named after their file, and a
+file with the name of an existing attachment replaces it. They are listed
+by 'hawkeye show --list', printed by 'hawkeye show --attachment' and
+dropped with their version.
+
+Without --version files are attached to the latest version. Versions are
+given like for 'hawkeye diff'.
+Example:
+  hawkeye attach https://example.com screenshot.png page.har
+  hawkeye attach https://example.com render.pdf --version 3
+  hawkeye attach https://example.com /tmp/out.png --name before.png --version 2024-07-01`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			url, files := args[0], args[1:]
+			if attachName != "" && len(files) > 1 {
+				fmt.Fprintln(os.Stderr, "Error: --name requires a single file")
+				os.Exit(1)
+			}
+
+			dir, err := archivePath()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+			a := archive.New(dir, archive.Retention{})
+			versions, err := a.Versions(url)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading archive: %s\n", err)
+				os.Exit(1)
+			}
+			if len(versions) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: no versions of %s archived in %s\n", url, dir)
+				os.Exit(1)
+			}
+			index, err := findVersion(versions, attachVersion)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			for _, file := range files {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+				name := attachName
+				if name == "" {
+					name = filepath.Base(file)
+				}
+				contentType := attachContentType
+				if contentType == "" {
+					contentType = mime.TypeByExtension(filepath.Ext(name))
+				}
+				if contentType == "" {
+					contentType = http.DetectContentType(content)
+				}
+
+				attachment, err := a.Attach(url, versions[index], name, content, contentType)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error attaching %s: %s\n", file, err)
+					os.Exit(1)
+				}
+				fmt.Printf("Attached %s (%d bytes) to version %d of %s, seen at %s\n", attachment.Name, attachment.Size, index+1, url, versions[index].Time.Local().Format(time.DateTime))
+			}
+		},
+	}
+)
+
+func init() {
+	attachCmd.Flags().StringVar(&attachVersion, "version", "-1", "Version to attach to: its number, a negative number counting back from the latest, or a time")
+	attachCmd.Flags().StringVar(&attachName, "name", "", "Name of the attachment (default: the name of the file)")
+	attachCmd.Flags().StringVar(&attachContentType, "content-type", "", "Content type of the attachments (default: guessed from the name and the content)")
+	attachCmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Directory of the archive (default: archive in the data directory)")
+}
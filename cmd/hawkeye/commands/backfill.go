@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/api"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/recorder"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+	"github.com/nemuizzz/hawkeye/pkg/wayback"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for backfill command
+	backfillDir     string
+	backfillArchive string
+	backfillFrom    string
+	backfillTo      string
+	backfillLimit   int
+	backfillDelay   time.Duration
+	backfillTimeout time.Duration
+
+	// backfillCmd represents the backfill command
+	backfillCmd = &cobra.Command{
+		Use:   "backfill [urls...]",
+		Short: "Add Wayback Machine snapshots of URLs to their cassettes",
+		Long: `Read the snapshots the Internet Archive's Wayback Machine took of each URL
+and add them to the URL's cassette, the file 'hawkeye watch --record' records
+checks to, so its history goes back to before hawkeye watched it. Only
+snapshots whose content differs from the one before are read, the most recent
+first. Replay the cassettes to see the changes, or pass the directory to
+'hawkeye serve --history-from' to start the change history with them.
+Example:
+  hawkeye backfill https://example.com/pricing --record ./cassettes --from 2023-01-01
+  hawkeye replay ./cassettes/example.com_pricing-*.jsonl
+  hawkeye serve --from-file monitors.yaml --history-from ./cassettes`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			archive := &wayback.Archive{
+				BaseURL: backfillArchive,
+				Client: customhttp.NewClient(&customhttp.ClientOptions{
+					Timeout:         backfillTimeout,
+					FollowRedirects: true,
+					UserAgent:       version.UserAgent(),
+				}),
+				Limit: backfillLimit,
+				Delay: backfillDelay,
+			}
+			var err error
+			if backfillFrom != "" {
+				if archive.From, err = parseDate(backfillFrom); err != nil {
+					fmt.Printf("Invalid --from: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if backfillTo != "" {
+				if archive.To, err = parseDate(backfillTo); err != nil {
+					fmt.Printf("Invalid --to: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if err := os.MkdirAll(backfillDir, 0755); err != nil {
+				fmt.Printf("Error creating record directory: %s\n", err)
+				os.Exit(1)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			failed := false
+			var paths []string
+			for _, url := range args {
+				path, added, err := backfill(ctx, archive, url)
+				if err != nil && added == 0 {
+					fmt.Printf("Error backfilling %s: %s\n", url, err)
+					failed = true
+					continue
+				}
+				if err != nil {
+					fmt.Printf("Warning: %s\n", strings.ReplaceAll(err.Error(), "\n", "\nWarning: "))
+				}
+				if added == 0 {
+					fmt.Printf("No new snapshots of %s found\n", url)
+					continue
+				}
+				fmt.Printf("Added %d snapshots of %s to %s\n", added, url, path)
+				paths = append(paths, path)
+			}
+
+			if len(paths) > 0 {
+				fmt.Printf("Replay them with: hawkeye replay %s\n", strings.Join(paths, " "))
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	backfillCmd.Flags().StringVar(&backfillDir, "record", "cassettes", "Directory of the cassettes the snapshots are added to")
+	backfillCmd.Flags().StringVar(&backfillArchive, "archive-url", wayback.DefaultBaseURL, "Address of the Wayback Machine, or of another archive with its CDX API such as pywb")
+	backfillCmd.Flags().StringVar(&backfillFrom, "from", "", "Only read snapshots taken since this date (e.g., 2023-01-01)")
+	backfillCmd.Flags().StringVar(&backfillTo, "to", "", "Only read snapshots taken until this date")
+	backfillCmd.Flags().IntVar(&backfillLimit, "limit", wayback.DefaultLimit, "Number of most recent snapshots read for each URL")
+	backfillCmd.Flags().DurationVar(&backfillDelay, "delay", time.Second, "Time between requests to the archive, which limits their rate")
+	backfillCmd.Flags().DurationVar(&backfillTimeout, "timeout", 30*time.Second, "How long to wait for each response of the archive")
+}
+
+// backfill adds the snapshots of url to its cassette in backfillDir. It
+// returns the cassette and the number of snapshots added, along with the
+// errors of snapshots that couldn't be read.
+func backfill(ctx context.Context, archive *wayback.Archive, url string) (string, int, error) {
+	interactions, fetchErr := archive.Backfill(ctx, url)
+	if len(interactions) == 0 {
+		return "", 0, fetchErr
+	}
+
+	path := recorder.CassettePath(backfillDir, url)
+	var existing []recorder.Interaction
+	cassette, err := recorder.LoadCassette(path)
+	switch {
+	case err == nil:
+		existing = cassette.Interactions
+	case !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, recorder.ErrEmptyCassette):
+		return "", 0, err
+	}
+
+	merged := recorder.Merge(existing, interactions)
+	if len(merged) == len(existing) {
+		return path, 0, fetchErr
+	}
+	if err := recorder.SaveCassette(path, merged); err != nil {
+		return "", 0, err
+	}
+	return path, len(merged) - len(existing), fetchErr
+}
+
+// parseDate parses a date such as 2023-01-01, or a time accepted by
+// schedule.ParseTime, in the local time zone
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(value), time.Local); err == nil {
+		return t, nil
+	}
+	return schedule.ParseTime(value, nil)
+}
+
+// seedHistory replays the cassettes in dir of the monitors of manager, e.g.
+// those added by backfill, into history, so it starts with the changes they
+// recorded. It returns the number of changes added.
+func seedHistory(history *api.History, manager *monitor.Manager, dir string) (int, error) {
+	added := 0
+	for _, url := range manager.ListMonitors() {
+		m, err := manager.GetMonitor(url)
+		if err != nil {
+			continue
+		}
+
+		cassette, err := recorder.LoadCassette(recorder.CassettePath(dir, url))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return added, err
+		}
+
+		config := m.GetConfig()
+		steps, err := recorder.Replay(cassette, &config)
+		if err != nil {
+			return added, fmt.Errorf("error replaying %s: %w", cassette.Path, err)
+		}
+		for _, step := range steps {
+			if step.Change.HasChanged {
+				history.Add(step.Change)
+				added++
+			}
+		}
+	}
+	return added, nil
+}
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"github.com/nemuizzz/hawkeye/pkg/browser"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Browser flags shared by watch and serve
+	browserURL  string
+	browserPath string
+)
+
+// addBrowserFlags registers the flags of the browser rendering pages
+func addBrowserFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&browserURL, "browser-url", "", "DevTools WebSocket URL of a running Chrome that renders pages, instead of launching one")
+	cmd.Flags().StringVar(&browserPath, "browser-path", "", "Chrome or Chromium binary launched to render pages (default: found on PATH)")
+}
+
+// setupBrowser sets the browser of the manager from the flags. It must be
+// called before monitors are added.
+func setupBrowser(manager *monitor.Manager) {
+	if browserURL == "" && browserPath == "" {
+		return
+	}
+	manager.SetBrowser(browser.New(&browser.Options{URL: browserURL, ExecPath: browserPath}))
+}
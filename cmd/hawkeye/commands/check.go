@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/nagios"
+	"github.com/spf13/cobra"
+)
+
+// checkService names hawkeye in Nagios plugin output
+const checkService = "HAWKEYE"
+
+var (
+	// Flags for check command
+	checkNagios           bool
+	checkTimeout          string
+	checkRetries          int
+	checkHeaders          []string
+	checkWarning          string
+	checkCritical         string
+	checkOnChange         string
+	checkStateFile        string
+	checkNormalize        bool
+	checkIgnoreTimestamps bool
+	checkFilters          []string
+
+	// checkCmd represents the check command
+	checkCmd = &cobra.Command{
+		Use:   "check <url>",
+		Short: "Check a URL once, e.g. as a Nagios or Icinga plugin",
+		Long: `Fetch a URL once and report whether it is up, how fast it responded and
+whether its content changed since the previous check. The hash of the
+content is kept in a state file between runs, so a scheduler such as cron,
+Nagios or NRPE can run the command repeatedly.
+
+Without --nagios the exit code is 1 unless the state is OK, or 2 with
+--fail-on-change if the content changed and 3 with --fail-on-error if the
+URL couldn't be checked.
+
+With --nagios the output and exit code follow the Nagios plugin guidelines:
+a single line with OK, WARNING, CRITICAL or UNKNOWN and the response time
+as performance data, and exit code 0, 1, 2 or 3.
+Example:
+  hawkeye check https://example.com
+  hawkeye check --fail-on-change https://example.com || echo changed
+  hawkeye check --nagios --warning 1s --critical 5s https://example.com
+
+  # nrpe.cfg
+  command[check_example]=/usr/bin/hawkeye check --nagios https://example.com`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if checkNagios {
+				result := runCheck(args).Result
+				if failOnChange || failOnError {
+					result = nagios.Result{Service: checkService, State: nagios.Unknown, Summary: "--fail-on-change and --fail-on-error can't be combined with --nagios"}
+				}
+				fmt.Println(result.String())
+				os.Exit(int(result.State))
+			}
+
+			result := runCheck(args)
+			fmt.Printf("%s: %s\n", result.State, result.Summary)
+			switch {
+			case result.failed && failOnError:
+				os.Exit(exitCheckFailed)
+			case result.changed && failOnChange:
+				os.Exit(exitChanged)
+			case result.State != nagios.OK:
+				os.Exit(exitFailure)
+			}
+		},
+	}
+)
+
+func init() {
+	checkCmd.Flags().BoolVar(&checkNagios, "nagios", false, "Print the result as a Nagios plugin and exit with its state")
+	checkCmd.Flags().StringVarP(&checkTimeout, "timeout", "t", "10s", "Request timeout")
+	checkCmd.Flags().IntVarP(&checkRetries, "retries", "r", 0, "Number of retries on failure")
+	checkCmd.Flags().StringArrayVarP(&checkHeaders, "header", "H", []string{}, "Custom HTTP headers (key:value)")
+	checkCmd.Flags().StringVarP(&checkWarning, "warning", "w", "", "Response time above which the state is WARNING (e.g., 1s)")
+	checkCmd.Flags().StringVarP(&checkCritical, "critical", "c", "", "Response time above which the state is CRITICAL (e.g., 5s)")
+	checkCmd.Flags().StringVar(&checkOnChange, "on-change", "warning", "State when the content changed since the previous check (ok/warning/critical)")
+	checkCmd.Flags().StringVar(&checkStateFile, "state-file", "", "File keeping the content hashes between checks (default: checks.json in the data directory)")
+	checkCmd.Flags().BoolVarP(&checkNormalize, "normalize", "n", false, "Normalize whitespace to ignore insignificant changes")
+	checkCmd.Flags().BoolVarP(&checkIgnoreTimestamps, "ignore-timestamps", "T", false, "Ignore timestamps when comparing content")
+	checkCmd.Flags().StringArrayVar(&checkFilters, "filter", []string{}, "Regular expression to strip before comparing (repeatable)")
+	addExitFlags(checkCmd)
+}
+
+// checkState is the content hash of a URL saved between checks
+type checkState struct {
+	Hash      string    `json:"hash"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// checkResult is the result of a check and what it found
+type checkResult struct {
+	nagios.Result
+	// changed is set if the content changed since the previous check
+	changed bool
+	// failed is set if the URL couldn't be checked
+	failed bool
+}
+
+// runCheck checks the URL of args once. Invalid arguments give the unknown
+// state.
+func runCheck(args []string) checkResult {
+	result := checkResult{Result: nagios.Result{Service: checkService, State: nagios.Unknown}}
+	if len(args) != 1 {
+		result.Summary = "exactly one URL is required"
+		return result
+	}
+	url := args[0]
+
+	config, err := checkConfig(url)
+	if err != nil {
+		result.Summary = err.Error()
+		return result
+	}
+	warning, critical, err := checkThresholds()
+	if err != nil {
+		result.Summary = err.Error()
+		return result
+	}
+	onChange, err := parseState(checkOnChange)
+	if err != nil {
+		result.Summary = "invalid --on-change: " + err.Error()
+		return result
+	}
+	stateFile, err := checkStatePath()
+	if err != nil {
+		result.Summary = err.Error()
+		return result
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := monitor.NewMonitorWithConfig(config)
+	stopMonitor := context.AfterFunc(ctx, m.Stop)
+	hash, change := m.Fingerprint()
+	if stopMonitor() {
+		m.Stop()
+	}
+
+	if change.Error != "" {
+		result.State = nagios.Critical
+		result.failed = true
+		result.Summary = fmt.Sprintf("%s: %s", url, change.Error)
+		if change.Latency > 0 {
+			result.Perfdata = []nagios.Perf{nagios.Seconds("time", change.Latency, warning, critical)}
+		}
+		return result
+	}
+
+	result.State = nagios.OK
+	result.Summary = fmt.Sprintf("%s: %d in %s", url, change.StatusCode, change.Latency.Round(time.Millisecond))
+	result.Perfdata = []nagios.Perf{nagios.Seconds("time", change.Latency, warning, critical)}
+	switch {
+	case critical > 0 && change.Latency > critical:
+		result.State = nagios.Critical
+		result.Summary += fmt.Sprintf(", slower than %s", critical)
+	case warning > 0 && change.Latency > warning:
+		result.State = nagios.Warning
+		result.Summary += fmt.Sprintf(", slower than %s", warning)
+	}
+
+	states, err := loadCheckStates(stateFile)
+	if err != nil {
+		result.State = nagios.Worst(result.State, nagios.Unknown)
+		result.Summary += ", " + err.Error()
+		return result
+	}
+	previous, found := states[url]
+	switch {
+	case !found:
+		result.Summary += ", content recorded"
+	case previous.Hash != hash:
+		result.State = nagios.Worst(result.State, onChange)
+		result.changed = true
+		result.Summary += fmt.Sprintf(", content changed since %s", previous.CheckedAt.Local().Format(time.DateTime))
+	}
+
+	states[url] = checkState{Hash: hash, CheckedAt: time.Now().UTC()}
+	if err := saveCheckStates(stateFile, states); err != nil {
+		result.State = nagios.Worst(result.State, nagios.Unknown)
+		result.Summary += ", " + err.Error()
+	}
+	return result
+}
+
+// checkConfig builds the monitor configuration of the check from its flags
+func checkConfig(url string) (*monitor.Config, error) {
+	timeout, err := time.ParseDuration(checkTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	config := monitor.DefaultConfig(url)
+	config.Timeout = timeout
+	config.RetryCount = checkRetries
+	config.RetryInterval = time.Second
+	config.Headers = parseHeaders(checkHeaders)
+	config.NormalizeWhitespace = checkNormalize
+	config.IgnoreTimestamps = checkIgnoreTimestamps
+	for _, pattern := range checkFilters {
+		filter, err := monitor.NewRegexFilter(pattern, "", "Ignore "+pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", pattern, err)
+		}
+		config.ContentFilters = append(config.ContentFilters, filter)
+	}
+	return config, nil
+}
+
+// checkThresholds parses the response time thresholds. Zero means none.
+func checkThresholds() (time.Duration, time.Duration, error) {
+	var warning, critical time.Duration
+	var err error
+	if checkWarning != "" {
+		if warning, err = time.ParseDuration(checkWarning); err != nil {
+			return 0, 0, fmt.Errorf("invalid --warning: %w", err)
+		}
+	}
+	if checkCritical != "" {
+		if critical, err = time.ParseDuration(checkCritical); err != nil {
+			return 0, 0, fmt.Errorf("invalid --critical: %w", err)
+		}
+	}
+	return warning, critical, nil
+}
+
+// parseState parses the name of a plugin state for --on-change
+func parseState(name string) (nagios.State, error) {
+	for _, state := range []nagios.State{nagios.OK, nagios.Warning, nagios.Critical} {
+		if strings.EqualFold(state.String(), name) {
+			return state, nil
+		}
+	}
+	return nagios.Unknown, fmt.Errorf("'%s' (expected ok, warning or critical)", name)
+}
+
+// checkStatePath returns the state file of checks
+func checkStatePath() (string, error) {
+	if checkStateFile != "" {
+		return checkStateFile, nil
+	}
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "checks.json"), nil
+}
+
+// loadCheckStates reads the content hashes of previous checks by URL. A
+// missing file has none.
+func loadCheckStates(path string) (map[string]checkState, error) {
+	states := make(map[string]checkState)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %w", path, err)
+	}
+	return states, nil
+}
+
+// saveCheckStates writes the content hashes of checks by URL
+func saveCheckStates(path string, states map[string]checkState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// ANSI escape sequences of the colors of terminal output
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// colorEnabled reports whether output to f may be colored: f is a terminal
+// and colors weren't turned off with NO_COLOR, see https://no-color.org
+func colorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the escape sequences of color
+func paint(color, s string) string {
+	return color + s + colorReset
+}
+
+// richChange formats a change for a terminal: a timestamped summary line
+// with the event in color, followed by its details with added and removed
+// lines highlighted
+func richChange(change monitor.Change) string {
+	var tag string
+	switch {
+	case change.Error != "":
+		tag = paint(colorBold+colorRed, "ERROR")
+	case change.HasChanged && (change.Silenced || change.Informational):
+		tag = paint(colorDim, "INFO")
+	case change.HasChanged:
+		tag = paint(colorBold+colorYellow, "CHANGED")
+	case change.Event == monitor.EventRecovery:
+		tag = paint(colorGreen, "RECOVERED")
+	default:
+		tag = paint(colorCyan, strings.ToUpper(string(change.Event)))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s%s", paint(colorDim, change.Timestamp.Local().Format(time.TimeOnly)), tag, change.URL, checkSummary(change))
+	if change.Silenced {
+		b.WriteString(paint(colorDim, " silenced"))
+	}
+	if change.Error != "" {
+		b.WriteString(": " + change.Error)
+	}
+	b.WriteString("\n")
+	if change.Details != "" {
+		b.WriteString(colorDiff(change.Details))
+	}
+	return b.String()
+}
+
+// richCheck formats a check that found no change for a terminal, as a dim
+// summary line
+func richCheck(change monitor.Change) string {
+	return paint(colorDim, fmt.Sprintf("%s ok %s%s", change.Timestamp.Local().Format(time.TimeOnly), change.URL, checkSummary(change))) + "\n"
+}
+
+// checkSummary describes the response of a check, e.g. " (200, 120ms)"
+func checkSummary(change monitor.Change) string {
+	var parts []string
+	if change.StatusCode > 0 {
+		parts = append(parts, fmt.Sprint(change.StatusCode))
+	}
+	if change.Latency > 0 {
+		parts = append(parts, change.Latency.Round(time.Millisecond).String())
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// colorDiff indents the lines of details, with the added lines of diffs in
+// green, the removed ones in red and hunk headers in cyan
+func colorDiff(details string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(details, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			line = paint(colorCyan, line)
+		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+			line = paint(colorGreen, line)
+		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+			line = paint(colorRed, line)
+		}
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
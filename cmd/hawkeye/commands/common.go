@@ -1,9 +1,10 @@
 package commands
 
 import (
-	"os"
 	"path/filepath"
 
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+
 	"github.com/spf13/viper"
 )
 
@@ -11,17 +12,59 @@ import (
 type MonitorConfig struct {
 	URL                 string            `json:"url"`
 	Interval            string            `json:"interval"`
+	Schedule            string            `json:"schedule,omitempty"`
 	Group               string            `json:"group,omitempty"`
+	Timeout             string            `json:"timeout,omitempty"`
+	FailureThreshold    int               `json:"failure_threshold,omitempty"`
+	ConfirmDelay        string            `json:"confirm_delay,omitempty"`
+	Expires             string            `json:"expires,omitempty"`
+	Method              string            `json:"method,omitempty"`
+	ExpectedStatus      []int             `json:"expected_status,omitempty"`
+	Match               []string          `json:"match,omitempty"`
+	MatchAbsent         []string          `json:"match_absent,omitempty"`
+	Count               []string          `json:"count,omitempty"`
+	CSVKeys             []string          `json:"csv_keys,omitempty"`
+	CSVDelimiter        string            `json:"csv_delimiter,omitempty"`
+	TailSeparator       string            `json:"tail_separator,omitempty"`
+	ImageThreshold      float64           `json:"image_threshold,omitempty"`
+	ImageDiffDir        string            `json:"image_diff_dir,omitempty"`
+	Paginate            string            `json:"paginate,omitempty"`
+	PaginateItems       string            `json:"paginate_items,omitempty"`
+	MaxPages            int               `json:"max_pages,omitempty"`
+	AppendOnly          bool              `json:"append_only,omitempty"`
+	Representations     []string          `json:"representations,omitempty"`
+	Variants            []monitor.Variant `json:"variants,omitempty"`
+	Extract             string            `json:"extract,omitempty"`
+	Below               *float64          `json:"below,omitempty"`
+	Above               *float64          `json:"above,omitempty"`
+	Delta               float64           `json:"delta,omitempty"`
+	DeltaPercent        float64           `json:"delta_percent,omitempty"`
 	Headers             map[string]string `json:"headers,omitempty"`
+	Baggage             map[string]string `json:"baggage,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
 	Ignore              []string          `json:"ignore,omitempty"`
+	Select              []string          `json:"select,omitempty"`
+	Alert               []string          `json:"alert,omitempty"`
+	Filters             []string          `json:"filters,omitempty"`
+	Proxy               string            `json:"proxy,omitempty"`
+	AcceptEncoding      string            `json:"accept_encoding,omitempty"`
+	NoCache             bool              `json:"no_cache,omitempty"`
+	CacheBust           string            `json:"cache_bust,omitempty"`
+	CaptureHAR          bool              `json:"capture_har,omitempty"`
 	CreatedAt           string            `json:"created_at,omitempty"`
 	NormalizeWhitespace bool              `json:"normalize_whitespace,omitempty"`
 	IgnoreTimestamps    bool              `json:"ignore_timestamps,omitempty"`
+	Paused              bool              `json:"paused,omitempty"`
 }
 
-// getConfigDir returns the directory where config files are stored
+// getConfigDir returns the directory where config files are stored. It is
+// not created, so read-only file systems work as long as nothing is saved.
 func getConfigDir() (string, error) {
-	// First try to get from viper
+	if dataDir != "" {
+		return dataDir, nil
+	}
+
+	// Then try to get from viper
 	configFile := viper.ConfigFileUsed()
 	if configFile != "" {
 		return filepath.Dir(configFile), nil
@@ -33,13 +76,5 @@ func getConfigDir() (string, error) {
 		return "", err
 	}
 
-	configDir := filepath.Join(home, ".hawkeye")
-	// Create directory if it doesn't exist
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			return "", err
-		}
-	}
-
-	return configDir, nil
+	return filepath.Join(home, ".hawkeye"), nil
 }
@@ -0,0 +1,559 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/config"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/lint"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+)
+
+// loadMonitorConfigs reads per-URL monitor settings from a JSON file. Both the
+// monitors.json format written by watch (an object keyed by URL) and a plain
+// array of monitor configs are accepted.
+func loadMonitorConfigs(path string) ([]MonitorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var configs []MonitorConfig
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		return configs, nil
+	}
+
+	var byURL map[string]MonitorConfig
+	if err := json.Unmarshal(data, &byURL); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	configs := make([]MonitorConfig, 0, len(byURL))
+	for url, config := range byURL {
+		if config.URL == "" {
+			config.URL = url
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, nil
+}
+
+// parseURLArg splits a "URL@interval" argument into its URL and interval.
+// The suffix is only treated as an interval if it parses as a duration, so
+// URLs with user info such as https://user@example.com are left intact.
+func parseURLArg(arg string) (string, string) {
+	idx := strings.LastIndex(arg, "@")
+	if idx <= 0 || idx == len(arg)-1 {
+		return arg, ""
+	}
+
+	suffix := arg[idx+1:]
+	if _, err := time.ParseDuration(suffix); err != nil {
+		return arg, ""
+	}
+
+	return arg[:idx], suffix
+}
+
+// toMonitorConfig converts a stored monitor configuration into a monitor.Config.
+// Settings that are not set on c are taken from defaults.
+func (c MonitorConfig) toMonitorConfig(defaults *monitor.Config) (*monitor.Config, error) {
+	config := *defaults
+	config.URL = c.URL
+
+	if c.Interval != "" {
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval for %s: %w", c.URL, err)
+		}
+		config.Interval = interval
+
+		// A URL's own interval overrides a default schedule
+		config.Schedule = nil
+	}
+
+	if c.Schedule != "" {
+		cron, err := schedule.ParseCron(c.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule for %s: %w", c.URL, err)
+		}
+		config.Schedule = cron
+	}
+
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for %s: %w", c.URL, err)
+		}
+		config.Timeout = timeout
+	}
+
+	if c.FailureThreshold != 0 {
+		config.FailureThreshold = c.FailureThreshold
+	}
+
+	if c.ConfirmDelay != "" {
+		delay, err := time.ParseDuration(c.ConfirmDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid confirm delay for %s: %w", c.URL, err)
+		}
+		config.ConfirmDelay = delay
+	}
+
+	if c.Expires != "" {
+		expires, err := schedule.ParseDeadline(c.Expires, time.Now(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry for %s: %w", c.URL, err)
+		}
+		config.Expires = expires
+	}
+
+	if c.Proxy != "" {
+		proxy, err := customhttp.ParseProxyURL(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy for %s: %w", c.URL, err)
+		}
+		config.ProxyURL = proxy
+	}
+
+	if c.AcceptEncoding != "" {
+		if err := customhttp.ValidateAcceptEncoding(c.AcceptEncoding); err != nil {
+			return nil, fmt.Errorf("invalid accept encoding for %s: %w", c.URL, err)
+		}
+		config.AcceptEncoding = c.AcceptEncoding
+	}
+
+	if c.Method != "" {
+		method, err := monitor.ParseMethod(c.Method)
+		if err != nil {
+			return nil, fmt.Errorf("invalid method for %s: %w", c.URL, err)
+		}
+		if method == monitor.MethodCustom {
+			return nil, fmt.Errorf("method 'custom' is not available from the command line")
+		}
+		config.Method = method
+	}
+
+	if len(c.ExpectedStatus) > 0 {
+		config.ExpectedStatus = c.ExpectedStatus
+	}
+
+	if len(c.Match)+len(c.MatchAbsent) > 0 {
+		matches, err := monitor.ParseMatches(c.Match, c.MatchAbsent)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match for %s: %w", c.URL, err)
+		}
+		config.Matches = matches
+		if c.Method == "" {
+			config.Method = monitor.MethodKeyword
+		}
+	}
+
+	if len(c.Count) > 0 {
+		keywords, err := monitor.ParseKeywords(c.Count)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count for %s: %w", c.URL, err)
+		}
+		config.Keywords = keywords
+		if c.Method == "" {
+			config.Method = monitor.MethodCount
+		}
+	}
+
+	if len(c.CSVKeys) > 0 || c.CSVDelimiter != "" {
+		delimiter, err := monitor.ParseDelimiter(c.CSVDelimiter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV delimiter for %s: %w", c.URL, err)
+		}
+		config.CSVKeys = c.CSVKeys
+		config.CSVDelimiter = delimiter
+		if c.Method == "" {
+			config.Method = monitor.MethodCSV
+		}
+	}
+
+	if c.TailSeparator != "" {
+		separator, err := monitor.ParseSeparator(c.TailSeparator)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tail separator for %s: %w", c.URL, err)
+		}
+		config.TailSeparator = separator
+		if c.Method == "" {
+			config.Method = monitor.MethodTail
+		}
+	}
+
+	if c.ImageThreshold != 0 || c.ImageDiffDir != "" {
+		config.ImageThreshold = c.ImageThreshold
+		config.ImageDiffDir = c.ImageDiffDir
+		if c.Method == "" {
+			config.Method = monitor.MethodImage
+		}
+	}
+
+	if len(c.Representations) > 0 {
+		config.Representations = c.Representations
+	}
+
+	if len(c.Variants) > 0 {
+		config.Variants = c.Variants
+	}
+
+	if c.Paginate != "" {
+		pagination, err := monitor.ParsePagination(c.Paginate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pagination for %s: %w", c.URL, err)
+		}
+		pagination.Items = c.PaginateItems
+		pagination.MaxPages = c.MaxPages
+		config.Pagination = pagination
+	}
+
+	if c.Extract != "" {
+		extractor, err := monitor.ParseExtractor(c.Extract)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extract for %s: %w", c.URL, err)
+		}
+		config.Extract = extractor
+		if c.Method == "" {
+			config.Method = monitor.MethodValue
+		}
+	}
+	if c.Below != nil || c.Above != nil {
+		config.Thresholds = nil
+		if c.Below != nil {
+			config.Thresholds = append(config.Thresholds, monitor.Threshold{Value: *c.Below})
+		}
+		if c.Above != nil {
+			config.Thresholds = append(config.Thresholds, monitor.Threshold{Value: *c.Above, Above: true})
+		}
+	}
+	if c.Delta > 0 {
+		config.Delta = c.Delta
+	}
+	if c.DeltaPercent > 0 {
+		config.DeltaPercent = c.DeltaPercent
+	}
+
+	// Per-URL baggage is added on top of the default baggage
+	if len(c.Baggage) > 0 {
+		baggage := make(map[string]string, len(defaults.Baggage)+len(c.Baggage))
+		for key, value := range defaults.Baggage {
+			baggage[key] = value
+		}
+		for key, value := range c.Baggage {
+			baggage[key] = value
+		}
+		if err := monitor.ValidateBaggage(baggage); err != nil {
+			return nil, fmt.Errorf("invalid baggage for %s: %w", c.URL, err)
+		}
+		config.Baggage = baggage
+	}
+
+	// Per-URL labels are added on top of the default labels
+	if len(c.Labels) > 0 {
+		labels := maps.Clone(defaults.Labels)
+		if labels == nil {
+			labels = make(map[string]string, len(c.Labels))
+		}
+		maps.Copy(labels, c.Labels)
+		if err := monitor.ValidateLabels(labels); err != nil {
+			return nil, fmt.Errorf("invalid labels for %s: %w", c.URL, err)
+		}
+		config.Labels = labels
+	}
+
+	// Per-URL headers are added on top of the default headers
+	if len(c.Headers) > 0 {
+		headers := make(map[string]string, len(defaults.Headers)+len(c.Headers))
+		for key, value := range defaults.Headers {
+			headers[key] = value
+		}
+		for key, value := range c.Headers {
+			headers[key] = value
+		}
+		config.Headers = headers
+	}
+
+	if len(c.Ignore) > 0 {
+		config.IgnoreSelectors = c.Ignore
+	}
+	if len(c.Select) > 0 {
+		config.WatchSelectors = c.Select
+	}
+	if len(c.Alert) > 0 {
+		config.AlertSelectors = c.Alert
+	}
+	if _, err := monitor.NewSelectorFilter(config.AlertSelectors, nil); err != nil {
+		return nil, fmt.Errorf("invalid alert selector for %s: %w", c.URL, err)
+	}
+	if _, err := monitor.NewSelectorFilter(config.WatchSelectors, config.IgnoreSelectors); err != nil {
+		return nil, fmt.Errorf("invalid selector for %s: %w", c.URL, err)
+	}
+
+	if len(c.Filters) > 0 {
+		filters := make(monitor.ContentFilterList, 0, len(c.Filters))
+		for _, pattern := range c.Filters {
+			filter, err := monitor.NewRegexFilter(pattern, "", "Ignore "+pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter for %s: %w", c.URL, err)
+			}
+			filters = append(filters, filter)
+		}
+		config.ContentFilters = filters
+	}
+
+	config.NormalizeWhitespace = defaults.NormalizeWhitespace || c.NormalizeWhitespace
+	config.IgnoreTimestamps = defaults.IgnoreTimestamps || c.IgnoreTimestamps
+	config.NoCache = defaults.NoCache || c.NoCache
+	config.CaptureHAR = defaults.CaptureHAR || c.CaptureHAR
+	config.AppendOnly = defaults.AppendOnly || c.AppendOnly
+	if c.CacheBust != "" {
+		config.CacheBust = c.CacheBust
+	}
+
+	return &config, nil
+}
+
+// parseDomainPolicy parses a --domain-policy value such as
+// "example.com:min-interval=1m,max-parallel=1,delay=2s"
+func parseDomainPolicy(value string) (string, monitor.DomainPolicy, error) {
+	var policy monitor.DomainPolicy
+
+	domain, settings, ok := strings.Cut(value, ":")
+	if !ok || domain == "" || settings == "" {
+		return "", policy, fmt.Errorf("invalid domain policy '%s' (expected domain:setting=value,...)", value)
+	}
+
+	for _, setting := range strings.Split(settings, ",") {
+		key, val, _ := strings.Cut(setting, "=")
+		var err error
+		switch strings.TrimSpace(key) {
+		case "min-interval":
+			policy.MinInterval, err = time.ParseDuration(val)
+		case "max-parallel":
+			policy.MaxParallel, err = strconv.Atoi(val)
+			if err == nil && policy.MaxParallel < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "delay":
+			policy.Delay, err = time.ParseDuration(val)
+		default:
+			return "", policy, fmt.Errorf("unknown setting '%s' in domain policy for %s (expected min-interval, max-parallel or delay)", key, domain)
+		}
+		if err != nil {
+			return "", policy, fmt.Errorf("invalid %s in domain policy for %s: %w", key, domain, err)
+		}
+	}
+
+	return domain, policy, nil
+}
+
+// xpathSelectors marks XPath expressions given as flags for
+// monitor.ParseSelector
+func xpathSelectors(expressions []string) []string {
+	selectors := make([]string, len(expressions))
+	for i, expression := range expressions {
+		selectors[i] = "xpath:" + expression
+	}
+	return selectors
+}
+
+// parseBaggage parses baggage given as "key=value"
+func parseBaggage(values []string) (map[string]string, error) {
+	baggage := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid baggage format: %s (expected 'key=value')", v)
+		}
+		baggage[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return baggage, monitor.ValidateBaggage(baggage)
+}
+
+// parseLabels parses labels given as "key=value"
+func parseLabels(values []string) (map[string]string, error) {
+	labels := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label format: %s (expected 'key=value')", v)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return labels, monitor.ValidateLabels(labels)
+}
+
+// parseCookies parses cookies given as "name=value"
+func parseCookies(values []string) (map[string]string, error) {
+	cookies := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cookie format: %s (expected 'name=value')", v)
+		}
+		cookies[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return cookies, monitor.ValidateCookies(cookies)
+}
+
+// parseVariants parses variants given as "name=key:value", collecting the
+// headers of variants given more than once in their first position
+func parseVariants(values []string) ([]monitor.Variant, error) {
+	var variants []monitor.Variant
+	index := make(map[string]int, len(values))
+	for _, v := range values {
+		name, header, ok := strings.Cut(v, "=")
+		key, value, hasValue := strings.Cut(header, ":")
+		if !ok || !hasValue {
+			return nil, fmt.Errorf("invalid variant format: %s (expected 'name=key:value')", v)
+		}
+		name = strings.TrimSpace(name)
+		i, seen := index[name]
+		if !seen {
+			i = len(variants)
+			index[name] = i
+			variants = append(variants, monitor.Variant{Name: name, Headers: map[string]string{}})
+		}
+		variants[i].Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return variants, monitor.ValidateVariants(variants)
+}
+
+// parseHeaders parses headers given as "key:value", warning about and
+// skipping malformed ones
+func parseHeaders(values []string) map[string]string {
+	headerMap := make(map[string]string)
+	for _, h := range values {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			fmt.Printf("Warning: invalid header format: %s (expected 'key:value')\n", h)
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		headerMap[key] = value
+	}
+	return headerMap
+}
+
+// readRequestData returns a --data value, reading it from a file if it
+// starts with @ as with curl
+func readRequestData(value string) (string, error) {
+	path, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return value, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseLogin builds the login of the --login flags, if any
+func parseLogin(url, data, token string) (*monitor.Login, error) {
+	if url == "" {
+		if data != "" || token != "" {
+			return nil, fmt.Errorf("--login-data and --login-token require --login")
+		}
+		return nil, nil
+	}
+
+	body, err := readRequestData(data)
+	if err != nil {
+		return nil, err
+	}
+	login := &monitor.Login{URL: url, Body: body, Token: token}
+	return login, monitor.ValidateLogin(login)
+}
+
+// parseOAuth2 builds the OAuth2 options of the --oauth2 flags, if any
+func parseOAuth2(tokenURL, clientID, clientSecret string, scopes []string) (*customhttp.OAuth2Options, error) {
+	if tokenURL == "" {
+		if clientID != "" || clientSecret != "" || len(scopes) > 0 {
+			return nil, fmt.Errorf("--oauth2-client-id, --oauth2-client-secret and --oauth2-scope require --oauth2-token-url")
+		}
+		return nil, nil
+	}
+
+	secret, err := readRequestData(clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	options := &customhttp.OAuth2Options{
+		TokenURL: tokenURL,
+		ClientID: clientID,
+		// Files of secrets usually end with a newline
+		ClientSecret: strings.TrimSpace(secret),
+		Scopes:       scopes,
+	}
+	return options, options.Validate()
+}
+
+// parseHostRateLimit parses a --host-rate-limit value such as
+// "api.example.com=10"
+func parseHostRateLimit(value string) (string, int, error) {
+	host, perMinute, ok := strings.Cut(value, "=")
+	if !ok || host == "" {
+		return "", 0, fmt.Errorf("invalid host rate limit '%s' (expected host=requests-per-minute)", value)
+	}
+
+	limit, err := strconv.Atoi(perMinute)
+	if err != nil || limit < 0 {
+		return "", 0, fmt.Errorf("invalid rate limit '%s' for %s (expected requests per minute)", perMinute, host)
+	}
+
+	return host, limit, nil
+}
+
+// describeSchedule describes when a monitor is checked, for messages such as
+// "Monitoring <URL> every 5m"
+func describeSchedule(config *monitor.Config) string {
+	if !config.At.IsZero() {
+		return fmt.Sprintf("once at %s", config.At.Format(time.RFC3339))
+	}
+	description := fmt.Sprintf("every %s", config.Interval)
+	if config.Schedule != nil {
+		description = fmt.Sprintf("on schedule '%s'", config.Schedule)
+	}
+	if !config.Expires.IsZero() {
+		description += fmt.Sprintf(" until %s", config.Expires.Format(time.RFC3339))
+	}
+	return description
+}
+
+// printLintWarnings warns about risky settings of the monitors set up from
+// the command line and config file, followed by those of the definition file
+// if there is one. Warnings about definition file monitors point at their
+// line.
+func printLintWarnings(w io.Writer, configs []*monitor.Config, definition *config.File) {
+	cliCount := len(configs)
+	if definition != nil {
+		if definitionConfigs, err := definition.Configs(); err == nil {
+			configs = append(configs, definitionConfigs...)
+		}
+	}
+
+	for _, warning := range lint.Check(configs) {
+		if warning.Index >= cliCount {
+			fmt.Fprintf(w, "Warning: %s: %s\n", definition.Position(warning.Index-cliCount, warning.Field), warning)
+			continue
+		}
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/nemuizzz/hawkeye/pkg/crawl"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Crawl flags of watch
+	crawlURLs     []string
+	crawlDepth    int
+	crawlMaxPages int
+	crawlInclude  []string
+	crawlExclude  []string
+)
+
+// addCrawlFlags registers the crawl flags on a command
+func addCrawlFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&crawlURLs, "crawl", []string{}, "Watch the pages found by following the links of a site from this URL, respecting robots.txt")
+	cmd.Flags().IntVar(&crawlDepth, "crawl-depth", crawl.DefaultMaxDepth, "Number of links followed from the --crawl URL")
+	cmd.Flags().IntVar(&crawlMaxPages, "crawl-max-pages", crawl.DefaultMaxPages, "Maximum number of pages fetched per --crawl URL")
+	cmd.Flags().StringArrayVar(&crawlInclude, "crawl-include", []string{}, "Only watch crawled pages matching this regular expression (e.g., '/docs/')")
+	cmd.Flags().StringArrayVar(&crawlExclude, "crawl-exclude", []string{}, "Neither crawl nor watch URLs matching this regular expression")
+}
+
+// addCrawlMonitors crawls the sites and adds a monitor with the default
+// settings for each page found. It returns, for linting, the configuration
+// of the first monitor of each site, as they all share the same settings.
+func addCrawlMonitors(manager *monitor.Manager, defaults *monitor.Config) ([]*monitor.Config, error) {
+	if len(crawlURLs) == 0 {
+		return nil, nil
+	}
+
+	include, err := compilePatterns(crawlInclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --crawl-include: %w", err)
+	}
+	exclude, err := compilePatterns(crawlExclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --crawl-exclude: %w", err)
+	}
+
+	crawler := &crawl.Crawler{
+		Client: customhttp.NewClient(&customhttp.ClientOptions{
+			Timeout:         defaults.Timeout,
+			FollowRedirects: true,
+			UserAgent:       version.UserAgent(),
+			ProxyURL:        defaults.ProxyURL,
+			TLS:             defaults.TLS,
+		}),
+		Headers:  defaults.Headers,
+		MaxDepth: crawlDepth,
+		MaxPages: crawlMaxPages,
+		Include:  include,
+		Exclude:  exclude,
+	}
+
+	var configs []*monitor.Config
+	for _, seed := range crawlURLs {
+		fmt.Printf("Crawling %s...\n", seed)
+		pages, err := crawler.Crawl(context.Background(), seed)
+		if err != nil {
+			return nil, err
+		}
+
+		var added []string
+		for _, page := range pages {
+			// URLs given on the command line keep their own settings
+			if _, err := manager.GetMonitor(page); err == nil {
+				continue
+			}
+
+			config := *defaults
+			config.URL = page
+			applyRecording(&config)
+			if _, err := manager.AddMonitorWithConfig(&config); err != nil {
+				fmt.Printf("Error setting up monitor for %s: %s\n", page, err)
+				continue
+			}
+			if len(added) == 0 {
+				configs = append(configs, &config)
+			}
+			added = append(added, page)
+		}
+
+		addToGroupFlag(manager, added)
+		fmt.Printf("Monitoring %d pages found from %s %s\n", len(added), seed, describeSchedule(defaults))
+	}
+
+	return configs, nil
+}
+
+// compilePatterns compiles regular expressions given as flags
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/config"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/summarize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Definition file flags shared by watch and serve
+	definitionFiles []string
+	environment     string
+)
+
+// addDefinitionFlags registers the definition file flags on a command
+func addDefinitionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&definitionFiles, "from-file", []string{}, "YAML file declaring monitors, groups, filters and notifications; repeat to apply overlays in order")
+	cmd.Flags().StringVar(&environment, "env", "", "Environment whose documents of the definition files apply (e.g., prod)")
+}
+
+// loadDefinitions loads and merges the definition files, or returns nil if
+// there are none
+func loadDefinitions() (*config.File, error) {
+	if len(definitionFiles) == 0 {
+		return nil, nil
+	}
+
+	file, err := config.LoadLayers(definitionFiles, environment)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s:\n%w", strings.Join(definitionFiles, ", "), err)
+	}
+	return file, nil
+}
+
+// addDefinitionMonitors adds the monitors and groups declared in a definition
+// file to the manager and returns the notifiers to use for each URL, and the
+// composites declared, if any, which raise changes from those of the manager
+func addDefinitionMonitors(manager *monitor.Manager, file *config.File) (map[string]notify.NotifierList, *monitor.Composites, error) {
+	for _, spec := range file.Groups {
+		if _, err := manager.CreateGroup(spec.Name, spec.Description); err != nil {
+			return nil, nil, err
+		}
+		if spec.ShareCookies {
+			if err := manager.ShareCookies(spec.Name, true); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	configs, err := file.Configs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policies, err := file.DomainPolicies()
+	if err != nil {
+		return nil, nil, err
+	}
+	for domain, policy := range policies {
+		manager.SetDomainPolicy(domain, policy)
+	}
+
+	if file.Defaults.RateLimit > 0 {
+		manager.SetRateLimit(file.Defaults.RateLimit)
+	}
+	for host, limit := range file.HostRateLimits() {
+		manager.SetHostRateLimit(host, limit)
+	}
+
+	notifiers, err := file.Notifiers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lists, err := file.MonitorNotifiers(notifiers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routes := make(map[string]notify.NotifierList)
+	for i, cfg := range configs {
+		spec := file.Monitors[i]
+		applyRecording(cfg)
+		cfg.MaxChecks = maxChecks
+		cfg.CaptureHAR = cfg.CaptureHAR || captureHAR
+
+		if _, err := manager.AddMonitorWithConfig(cfg); err != nil {
+			fmt.Printf("Error setting up monitor for %s: %s\n", cfg.URL, err)
+			continue
+		}
+
+		if spec.Group != "" {
+			if err := manager.AddToGroup(cfg.URL, spec.Group); err != nil {
+				fmt.Printf("Error adding %s to group '%s': %s\n", cfg.URL, spec.Group, err)
+			}
+		}
+
+		if len(lists[i]) > 0 {
+			routes[cfg.URL] = lists[i]
+		}
+
+		fmt.Printf("Monitoring %s %s\n", cfg.URL, describeSchedule(cfg))
+	}
+
+	if len(file.Composites) == 0 {
+		return routes, nil, nil
+	}
+	specs, err := file.CompositeConfigs()
+	if err != nil {
+		return nil, nil, err
+	}
+	composites, err := monitor.NewComposites(manager, specs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	compositeLists, err := file.CompositeNotifiers(notifiers)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, spec := range specs {
+		if len(compositeLists[i]) > 0 {
+			routes[monitor.CompositeURL(spec.Name)] = compositeLists[i]
+		}
+		fmt.Printf("Watching composite %s: %s\n", spec.Name, spec.Rule)
+	}
+
+	return routes, composites, nil
+}
+
+// flushDigests sends the changes notification digests hold back, reporting
+// failures
+func flushDigests(routes map[string]notify.NotifierList) {
+	var notifiers []notify.Notifier
+	for _, list := range routes {
+		notifiers = append(notifiers, list...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	if err := notify.Flush(ctx, notifiers...); err != nil {
+		fmt.Printf("Warning: failed to send notification digest: %s\n", err)
+	}
+}
+
+// sendNotifications summarizes a change and delivers it to notifiers,
+// reporting failures
+func sendNotifications(notifiers notify.NotifierList, change monitor.Change) {
+	// A slow summarizer leaves the notifiers their own time
+	summaryCtx, cancelSummary := context.WithTimeout(context.Background(), time.Second*15)
+	change, err := summarize.Apply(summaryCtx, summarizer, change)
+	cancelSummary()
+	if err != nil {
+		fmt.Printf("Warning: failed to summarize change of %s: %s\n", change.URL, err)
+	}
+
+	// Commands of --exec may take longer than notifications usually do
+	ctx, cancel := context.WithTimeout(context.Background(), max(time.Second*30, execTimeout))
+	defer cancel()
+
+	if err := notifiers.Notify(ctx, change); err != nil {
+		fmt.Printf("Warning: failed to send notification for %s: %s\n", change.URL, err)
+	}
+}
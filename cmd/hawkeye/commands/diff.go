@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for diff command
+	diffFormat       string
+	diffContextLines int
+
+	// diffCmd represents the diff command
+	diffCmd = &cobra.Command{
+		Use:   "diff <url> [from] [to]",
+		Short: "Show the differences between archived versions of a page",
+		Long: `Show the differences between two versions of a page archived by
+'hawkeye watch --archive' or 'hawkeye serve --archive'.
+
+Versions are given by their number in 'hawkeye show --list', counting back
+from the latest with negative numbers, or by a time or a duration for that
+long ago, which picks the version current at that time. Without versions
+the previous and the latest one are compared, and with one version it is
+compared with the latest. The html and json formats list the attachments
+of both versions, see 'hawkeye attach'.
+Example:
+  hawkeye diff https://example.com
+  hawkeye diff https://example.com 1 3
+  hawkeye diff https://example.com 2024-07-01 --format html > changes.html
+  hawkeye diff https://example.com 168h -1 --format json`,
+		Args: cobra.RangeArgs(1, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			url := args[0]
+			if diffFormat != "text" && diffFormat != "html" && diffFormat != "json" {
+				fmt.Fprintf(os.Stderr, "Invalid --format '%s' (expected text, html or json)\n", diffFormat)
+				os.Exit(1)
+			}
+
+			dir, err := archivePath()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+			a := archive.New(dir, archive.Retention{})
+			versions, err := a.Versions(url)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading archive: %s\n", err)
+				os.Exit(1)
+			}
+			if len(versions) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: no versions of %s archived in %s\n", url, dir)
+				os.Exit(1)
+			}
+
+			refs := []string{"-2", "-1"}
+			copy(refs, args[1:])
+			if len(args) == 2 {
+				refs[1] = "-1"
+			}
+			var selected [2]diffVersion
+			var contents [2][]byte
+			for i, ref := range refs {
+				index, err := findVersion(versions, ref)
+				if err == nil {
+					selected[i] = diffVersion{Index: index + 1, Version: versions[index]}
+					contents[i], err = a.Read(versions[index])
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			var hunks []monitor.DiffHunk
+			binary := !utf8.Valid(contents[0]) || !utf8.Valid(contents[1])
+			if !binary {
+				hunks = monitor.LineDiff(contents[0], contents[1], diffContextLines)
+			}
+
+			switch diffFormat {
+			case "json":
+				if hunks == nil {
+					hunks = []monitor.DiffHunk{}
+				}
+				data, _ := json.MarshalIndent(map[string]any{
+					"url":    url,
+					"from":   selected[0],
+					"to":     selected[1],
+					"binary": binary,
+					"hunks":  hunks,
+				}, "", "  ")
+				fmt.Println(string(data))
+			case "html":
+				fmt.Print(diffPage(url, selected, binary, hunks))
+			default:
+				if selected[0].Hash == selected[1].Hash {
+					return
+				}
+				fmt.Printf("--- %s\t%s\n+++ %s\t%s\n", url, selected[0], url, selected[1])
+				if binary {
+					fmt.Println("Binary versions differ")
+					return
+				}
+				fmt.Print(monitor.FormatHunks(hunks))
+			}
+		},
+	}
+)
+
+// diffVersion is an archived version and its number, counting from 1
+type diffVersion struct {
+	Index int `json:"index"`
+	archive.Version
+}
+
+// String describes the version in diff headers
+func (v diffVersion) String() string {
+	return fmt.Sprintf("%s (#%d)", v.Time.Local().Format(time.DateTime), v.Index)
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "Output format (text/html/json)")
+	diffCmd.Flags().IntVarP(&diffContextLines, "context", "U", monitor.DefaultDiffContextLines, "Unchanged lines shown around each change")
+	diffCmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Directory of the archive (default: archive in the data directory)")
+}
+
+// findVersion returns the index of the version ref refers to: a number
+// counting from 1, or back from the latest if negative, or a time
+func findVersion(versions []archive.Version, ref string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		index := n - 1
+		if n < 0 {
+			index = len(versions) + n
+		}
+		if n == 0 || index < 0 || index >= len(versions) {
+			return 0, fmt.Errorf("no version %d (%d archived)", n, len(versions))
+		}
+		return index, nil
+	}
+
+	at, err := parseArchiveTime(ref)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version '%s': must be a number, a time or a duration", ref)
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Time.After(at) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no version archived at %s, the first is from %s", at.Local().Format(time.RFC3339), versions[0].Time.Local().Format(time.RFC3339))
+}
+
+// diffPage renders a diff as a standalone HTML page
+func diffPage(url string, versions [2]diffVersion, binary bool, hunks []monitor.DiffHunk) string {
+	body := monitor.FormatHunksHTML(hunks)
+	switch {
+	case binary:
+		body = "<p>Binary versions differ</p>\n"
+	case len(hunks) == 0:
+		body = "<p>No differences</p>\n"
+	}
+	var attachments strings.Builder
+	for _, version := range versions {
+		if len(version.Attachments) == 0 {
+			continue
+		}
+		var names []string
+		for _, attachment := range version.Attachments {
+			names = append(names, html.EscapeString(attachment.Name))
+		}
+		fmt.Fprintf(&attachments, "<p>Attachments of #%d: %s</p>\n", version.Index, strings.Join(names, ", "))
+	}
+	body = attachments.String() + body
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.diff { border-collapse: collapse; font-family: monospace; width: 100%%; }
+table.diff td { padding: 0 0.5em; white-space: pre-wrap; vertical-align: top; }
+table.diff td:nth-child(-n+2) { color: #888; text-align: right; width: 1%%; }
+tr.hunk { background: #eef; color: #558; }
+tr.removed { background: #fee; }
+tr.added { background: #efe; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p>From %s to %s</p>
+%s</body>
+</html>
+`, html.EscapeString(url), html.EscapeString(url), html.EscapeString(versions[0].String()), html.EscapeString(versions[1].String()), body)
+}
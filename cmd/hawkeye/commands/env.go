@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix is the prefix of environment variables that set flags
+const envPrefix = "HAWKEYE_"
+
+// urlsEnv lists URLs to watch, separated by whitespace, in addition to the
+// arguments of watch
+const urlsEnv = envPrefix + "URLS"
+
+// flagEnv returns the environment variable of a flag, e.g. HAWKEYE_MAX_CONCURRENT
+// for --max-concurrent
+func flagEnv(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets every flag of cmd that wasn't given on the command line from
+// its environment variable, so hawkeye can be configured entirely from the
+// environment, e.g. in a container. Repeatable flags take one value per line.
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+
+		value, ok := os.LookupEnv(flagEnv(f.Name))
+		if !ok {
+			return
+		}
+
+		if slice, isSlice := f.Value.(pflag.SliceValue); isSlice {
+			err = slice.Replace(envLines(value))
+		} else {
+			err = f.Value.Set(value)
+		}
+		if err != nil {
+			err = fmt.Errorf("invalid %s: %w", flagEnv(f.Name), err)
+			return
+		}
+		f.Changed = true
+	})
+	return err
+}
+
+// envLines splits the value of a repeatable flag's environment variable into
+// its non-empty lines
+func envLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
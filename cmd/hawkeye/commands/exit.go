@@ -0,0 +1,30 @@
+package commands
+
+import "github.com/spf13/cobra"
+
+// Exit codes of watch and check, so scripts and CI jobs can tell outcomes
+// apart
+const (
+	// exitOK means the checks were done and nothing made them fail
+	exitOK = 0
+	// exitFailure means invalid options, or that hawkeye gave up, e.g. at
+	// a --deadline
+	exitFailure = 1
+	// exitChanged means content changed with --fail-on-change
+	exitChanged = 2
+	// exitCheckFailed means a URL couldn't be checked with --fail-on-error
+	exitCheckFailed = 3
+)
+
+var (
+	// Exit flags shared by watch and check
+	failOnChange bool
+	failOnError  bool
+)
+
+// addExitFlags registers the flags that give changes and failed checks
+// their own exit codes
+func addExitFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&failOnChange, "fail-on-change", false, "Exit with status 2 when content changed")
+	cmd.Flags().BoolVar(&failOnError, "fail-on-error", false, "Exit with status 3 when a URL can't be checked")
+}
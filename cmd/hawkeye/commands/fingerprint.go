@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/fingerprint"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for fingerprint command
+	fingerprintInput            string
+	fingerprintOutput           string
+	fingerprintNormalize        bool
+	fingerprintIgnoreTimestamps bool
+	fingerprintFilters          []string
+
+	// Flags shared by fingerprint and verify
+	fingerprintTimeout     string
+	fingerprintRetries     int
+	fingerprintHeaders     []string
+	fingerprintConcurrency int
+
+	// Flags for verify command
+	verifyFormat string
+
+	// fingerprintCmd represents the fingerprint command
+	fingerprintCmd = &cobra.Command{
+		Use:   "fingerprint [urls...]",
+		Short: "Hash a list of URLs once and write a manifest",
+		Long: `Fetch every URL once and write a manifest of their content hashes, to be
+checked later with 'hawkeye verify'. It is a batch complement to watching:
+fingerprint a site before a deploy and verify it afterwards, or verify a
+manifest from a nightly job. URLs are given as arguments or read from a file
+with one URL per line.
+Example:
+  hawkeye fingerprint https://example.com https://example.org -o manifest.json
+  hawkeye fingerprint --input urls.txt --normalize --filter 'csrf=[a-z0-9]+' -o manifest.json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			urls := args
+			if fingerprintInput != "" {
+				listed, err := readURLList(fingerprintInput)
+				if err != nil {
+					fmt.Printf("Error reading URLs: %s\n", err)
+					os.Exit(1)
+				}
+				urls = append(urls, listed...)
+			}
+			if len(urls) == 0 {
+				fmt.Println("No URLs given")
+				os.Exit(1)
+			}
+
+			fetcher, err := fingerprintFetcher()
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			opts := fingerprint.Options{
+				NormalizeWhitespace: fingerprintNormalize,
+				IgnoreTimestamps:    fingerprintIgnoreTimestamps,
+				Filters:             fingerprintFilters,
+			}
+			manifest, err := fetcher.Build(ctx, urls, opts)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			failed := 0
+			for _, entry := range manifest.Entries {
+				if entry.Error != "" {
+					failed++
+					fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", entry.URL, entry.Error)
+				}
+			}
+
+			if fingerprintOutput == "-" {
+				err = manifest.Write(os.Stdout)
+			} else {
+				err = manifest.Save(fingerprintOutput)
+			}
+			if err != nil {
+				fmt.Printf("Error writing manifest: %s\n", err)
+				os.Exit(1)
+			}
+
+			if fingerprintOutput != "-" {
+				fmt.Printf("Fingerprinted %d URLs (%d failed) to %s\n", len(manifest.Entries), failed, fingerprintOutput)
+			}
+		},
+	}
+
+	// verifyCmd represents the verify command
+	verifyCmd = &cobra.Command{
+		Use:   "verify [manifest]",
+		Short: "Re-fetch the URLs of a manifest and report which differ",
+		Long: `Re-fetch every URL of a manifest written by 'hawkeye fingerprint' and report
+which differ from it. Content is hashed with the settings the manifest was
+created with. Exits with status 1 if any URL changed or couldn't be fetched.
+Example:
+  hawkeye verify manifest.json
+  hawkeye verify manifest.json --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			manifest, err := fingerprint.Load(args[0])
+			if err != nil {
+				fmt.Printf("Error loading manifest: %s\n", err)
+				os.Exit(1)
+			}
+
+			fetcher, err := fingerprintFetcher()
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			results, err := fetcher.Verify(ctx, manifest)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			if !printVerify(results) {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	fingerprintCmd.Flags().StringVarP(&fingerprintInput, "input", "i", "", "File listing URLs to fingerprint, one per line ('-' for stdin)")
+	fingerprintCmd.Flags().StringVarP(&fingerprintOutput, "output", "o", "-", "Manifest file to write ('-' for stdout)")
+	fingerprintCmd.Flags().BoolVarP(&fingerprintNormalize, "normalize", "n", false, "Normalize whitespace to ignore insignificant changes")
+	fingerprintCmd.Flags().BoolVarP(&fingerprintIgnoreTimestamps, "ignore-timestamps", "T", false, "Ignore timestamps when hashing content")
+	fingerprintCmd.Flags().StringArrayVar(&fingerprintFilters, "filter", []string{}, "Regular expression to strip before hashing (repeatable)")
+
+	verifyCmd.Flags().StringVarP(&verifyFormat, "format", "f", "text", "Output format (text/json)")
+
+	for _, cmd := range []*cobra.Command{fingerprintCmd, verifyCmd} {
+		cmd.Flags().StringVarP(&fingerprintTimeout, "timeout", "t", "30s", "Request timeout")
+		cmd.Flags().IntVarP(&fingerprintRetries, "retries", "r", 1, "Number of retries on failure")
+		cmd.Flags().StringArrayVarP(&fingerprintHeaders, "header", "H", []string{}, "Custom HTTP headers (key:value)")
+		cmd.Flags().IntVarP(&fingerprintConcurrency, "concurrency", "c", fingerprint.DefaultConcurrency, "Number of URLs fetched at the same time")
+	}
+}
+
+// fingerprintFetcher builds the fetcher from the shared flags
+func fingerprintFetcher() (*fingerprint.Fetcher, error) {
+	timeoutDuration, err := time.ParseDuration(fingerprintTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	config := monitor.DefaultConfig("")
+	config.Timeout = timeoutDuration
+	config.RetryCount = fingerprintRetries
+	config.RetryInterval = time.Second
+	config.Headers = parseHeaders(fingerprintHeaders)
+
+	return &fingerprint.Fetcher{Config: config, Concurrency: fingerprintConcurrency}, nil
+}
+
+// readURLList reads URLs from a file, one per line. Blank lines and lines
+// starting with # are skipped.
+func readURLList(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// printVerify prints the verification results and reports whether every URL
+// matched the manifest
+func printVerify(results []fingerprint.Result) bool {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	if verifyFormat == "json" {
+		for _, result := range results {
+			jsonOutput, _ := json.Marshal(result)
+			fmt.Println(string(jsonOutput))
+		}
+	} else {
+		for _, result := range results {
+			switch result.Status {
+			case fingerprint.StatusChanged:
+				fmt.Printf("[CHANGED] %s\n", result.URL)
+			case fingerprint.StatusError:
+				fmt.Printf("[ERROR] %s: %s\n", result.URL, result.Error)
+			default:
+				fmt.Printf("[UNCHANGED] %s\n", result.URL)
+			}
+		}
+		fmt.Printf("%d URLs: %d unchanged, %d changed, %d errors\n", len(results),
+			counts[fingerprint.StatusUnchanged], counts[fingerprint.StatusChanged], counts[fingerprint.StatusError])
+	}
+
+	return counts[fingerprint.StatusUnchanged] == len(results)
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Heartbeat flags shared by watch and serve
+	heartbeatURL      string
+	heartbeatInterval time.Duration
+)
+
+// addHeartbeatFlags registers the heartbeat flags on a command
+func addHeartbeatFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&heartbeatURL, "heartbeat", "", "URL pinged while hawkeye is healthy, e.g. a healthchecks.io check")
+	cmd.Flags().DurationVar(&heartbeatInterval, "heartbeat-interval", notify.DefaultHeartbeatInterval, "Time between heartbeat pings")
+}
+
+// startHeartbeat pings the heartbeat URL, if one is set, until ctx is
+// canceled. Heartbeats are refused in offline mode.
+func startHeartbeat(ctx context.Context, manager *monitor.Manager) error {
+	if heartbeatURL == "" {
+		return nil
+	}
+	if err := offline.Check("heartbeat"); err != nil {
+		return err
+	}
+
+	heartbeat := notify.NewHeartbeat(heartbeatURL, heartbeatInterval, manager.Health)
+	go heartbeat.Run(ctx, func(err error) {
+		fmt.Printf("Warning: heartbeat failed: %s\n", err)
+	})
+	fmt.Printf("Sending heartbeats to %s every %s\n", heartbeatURL, heartbeatInterval)
+	return nil
+}
@@ -6,13 +6,15 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Flags for list command
-	listFormat string
-	listGroup  string
+	listFormat   string
+	listGroup    string
+	listSelector string
 
 	// listCmd represents the list command
 	listCmd = &cobra.Command{
@@ -21,6 +23,12 @@ var (
 		Long: `List all URLs currently being monitored.
 Shows information about monitoring status, groups, and more.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			selector, err := monitor.ParseLabelSelector(listSelector)
+			if err != nil {
+				fmt.Printf("Invalid --selector: %s\n", err)
+				os.Exit(1)
+			}
+
 			configDir, err := getConfigDir()
 			if err != nil {
 				fmt.Printf("Error getting config directory: %s\n", err)
@@ -57,6 +65,9 @@ Shows information about monitoring status, groups, and more.`,
 				if listGroup != "" && config.Group != listGroup {
 					continue
 				}
+				if !selector.Matches(config.Labels) {
+					continue
+				}
 
 				if listFormat == "json" {
 					jsonOutput, _ := json.MarshalIndent(config, "", "  ")
@@ -64,21 +75,39 @@ Shows information about monitoring status, groups, and more.`,
 				} else {
 					fmt.Printf("URL: %s\n", url)
 					fmt.Printf("  Interval: %s\n", config.Interval)
+					if config.Schedule != "" {
+						fmt.Printf("  Schedule: %s\n", config.Schedule)
+					}
 					if config.Group != "" {
 						fmt.Printf("  Group: %s\n", config.Group)
 					}
+					if len(config.Labels) > 0 {
+						fmt.Printf("  Labels: %v\n", config.Labels)
+					}
 					if len(config.Headers) > 0 {
 						fmt.Printf("  Headers: %v\n", config.Headers)
 					}
+					if config.Timeout != "" {
+						fmt.Printf("  Timeout: %s\n", config.Timeout)
+					}
+					if config.Method != "" {
+						fmt.Printf("  Method: %s\n", config.Method)
+					}
 					if len(config.Ignore) > 0 {
 						fmt.Printf("  Ignore: %v\n", config.Ignore)
 					}
+					if len(config.Filters) > 0 {
+						fmt.Printf("  Filters: %v\n", config.Filters)
+					}
 					if config.NormalizeWhitespace {
 						fmt.Printf("  Normalize Whitespace: true\n")
 					}
 					if config.IgnoreTimestamps {
 						fmt.Printf("  Ignore Timestamps: true\n")
 					}
+					if config.Paused {
+						fmt.Printf("  Paused: true\n")
+					}
 					if config.CreatedAt != "" {
 						fmt.Printf("  Added: %s\n", config.CreatedAt)
 					}
@@ -86,8 +115,8 @@ Shows information about monitoring status, groups, and more.`,
 				}
 			}
 
-			// List groups if no specific group was requested
-			if listGroup == "" {
+			// List groups if no specific group or labels were requested
+			if listGroup == "" && listSelector == "" {
 				groups := make(map[string]int)
 				for _, config := range monitors {
 					if config.Group != "" {
@@ -109,4 +138,5 @@ Shows information about monitoring status, groups, and more.`,
 func init() {
 	listCmd.Flags().StringVarP(&listFormat, "format", "f", "text", "Output format (text/json)")
 	listCmd.Flags().StringVarP(&listGroup, "group", "g", "", "Filter by group name")
+	listCmd.Flags().StringVarP(&listSelector, "selector", "l", "", "Filter by labels (e.g., env=staging,team!=web)")
 }
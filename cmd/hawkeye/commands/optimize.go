@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	"github.com/nemuizzz/hawkeye/pkg/optimize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for optimize command
+	optimizeSince  string
+	optimizeApply  bool
+	optimizeYes    bool
+	optimizeFormat string
+
+	// optimizeCmd represents the optimize command
+	optimizeCmd = &cobra.Command{
+		Use:   "optimize [URLs...]",
+		Short: "Suggest better check intervals from the history of changes",
+		Long: `Suggest a check interval for each saved monitor from how often its page
+changed, as recorded in the archive by 'hawkeye watch --archive' or
+'hawkeye serve --archive'. A page that changed twice in 90 days doesn't
+need to be checked every minute, while a page that changes every hour is
+caught late when checked every 6 hours.
+
+Intervals are suggested for pages watched for at least a week, so that
+they are checked about 4 times between two changes. With --apply each new
+interval is saved after asking for approval, or right away with --yes, and
+'hawkeye watch --config-file' uses it. Monitors checked on a schedule are
+left alone.
+Example:
+  hawkeye optimize
+  hawkeye optimize https://example.com --since 720h
+  hawkeye optimize --apply`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if optimizeFormat != "text" && optimizeFormat != "json" {
+				fmt.Printf("Invalid --format '%s' (expected text or json)\n", optimizeFormat)
+				os.Exit(1)
+			}
+			since, err := parseArchiveTime(optimizeSince)
+			if err != nil {
+				fmt.Printf("Invalid --since: %s\n", err)
+				os.Exit(1)
+			}
+
+			configFile, monitors, err := readSavedMonitors()
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			urls := args
+			if len(urls) == 0 {
+				for u := range monitors {
+					urls = append(urls, u)
+				}
+				sort.Strings(urls)
+			}
+
+			dir, err := archivePath()
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			a := archive.New(dir, archive.Retention{})
+
+			now := time.Now()
+			var suggestions []optimize.Suggestion
+			for _, u := range urls {
+				entry, exists := monitors[u]
+				if !exists {
+					fmt.Printf("Error: no monitor found for URL '%s'\n", u)
+					os.Exit(1)
+				}
+				if entry.Schedule != "" {
+					suggestions = append(suggestions, optimize.Suggestion{URL: u, Reason: "checked on a schedule"})
+					continue
+				}
+				interval, err := time.ParseDuration(entry.Interval)
+				if err != nil {
+					fmt.Printf("Error: invalid interval of %s: %s\n", u, err)
+					os.Exit(1)
+				}
+				changes, err := archivedChanges(a, u)
+				if err != nil {
+					fmt.Printf("Error reading archive: %s\n", err)
+					os.Exit(1)
+				}
+				if len(changes) == 0 {
+					suggestions = append(suggestions, optimize.Suggestion{URL: u, Current: interval, Reason: "nothing archived, watch it with --archive"})
+					continue
+				}
+				suggestions = append(suggestions, optimize.Suggest(u, interval, changes, since, now))
+			}
+
+			for _, s := range suggestions {
+				if optimizeFormat == "json" {
+					jsonOutput, _ := json.Marshal(suggestionOutput(s))
+					fmt.Println(string(jsonOutput))
+					continue
+				}
+				switch {
+				case s.Changed():
+					fmt.Printf("%s: %s -> %s (%s)\n", s.URL, s.Current, s.Suggested, s.Reason)
+				case s.Suggested != 0:
+					fmt.Printf("%s: keep %s (%s)\n", s.URL, s.Current, s.Reason)
+				default:
+					fmt.Printf("%s: no suggestion (%s)\n", s.URL, s.Reason)
+				}
+			}
+
+			if !optimizeApply {
+				return
+			}
+			stdin := bufio.NewReader(os.Stdin)
+			var applied []optimize.Suggestion
+			for _, s := range suggestions {
+				if !s.Changed() {
+					continue
+				}
+				if !optimizeYes {
+					fmt.Printf("Check %s every %s instead of every %s? [y/N] ", s.URL, s.Suggested, s.Current)
+					answer, _ := stdin.ReadString('\n')
+					if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+						continue
+					}
+				}
+				entry := monitors[s.URL]
+				entry.Interval = s.Suggested.String()
+				monitors[s.URL] = entry
+				applied = append(applied, s)
+			}
+			if len(applied) == 0 {
+				fmt.Println("No intervals changed.")
+				return
+			}
+
+			data, err := json.MarshalIndent(monitors, "", "  ")
+			if err == nil {
+				err = os.WriteFile(configFile, data, 0644)
+			}
+			if err != nil {
+				fmt.Printf("Error saving monitors: %s\n", err)
+				os.Exit(1)
+			}
+			for _, s := range applied {
+				recordAudit(audit.ActionConfig, s.URL, fmt.Sprintf("interval %s -> %s", s.Current, s.Suggested))
+				fmt.Printf("Saved interval %s for %s\n", s.Suggested, s.URL)
+			}
+		},
+	}
+)
+
+func init() {
+	optimizeCmd.Flags().StringVar(&optimizeSince, "since", "2160h", "Only count changes since this time or for this long")
+	optimizeCmd.Flags().BoolVar(&optimizeApply, "apply", false, "Save the suggested intervals, asking for approval of each")
+	optimizeCmd.Flags().BoolVarP(&optimizeYes, "yes", "y", false, "Save the suggested intervals without asking with --apply")
+	optimizeCmd.Flags().StringVarP(&optimizeFormat, "format", "f", "text", "Output format (text/json)")
+	optimizeCmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Directory of the archive (default: archive in the data directory)")
+}
+
+// suggestionOutput is the JSON form of a suggestion, with durations as
+// strings
+func suggestionOutput(s optimize.Suggestion) map[string]any {
+	output := map[string]any{
+		"url":     s.URL,
+		"changes": s.Changes,
+		"period":  s.Period.Round(time.Second).String(),
+		"reason":  s.Reason,
+		"changed": s.Changed(),
+	}
+	if s.Current != 0 {
+		output["current"] = s.Current.String()
+	}
+	if s.Suggested != 0 {
+		output["suggested"] = s.Suggested.String()
+	}
+	return output
+}
+
+// readSavedMonitors reads the monitors saved by watch and the path of their
+// file
+func readSavedMonitors() (string, map[string]MonitorConfig, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", nil, err
+	}
+	configFile := filepath.Join(configDir, "monitors.json")
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("no monitors found, use 'hawkeye watch' to add monitors")
+		}
+		return "", nil, err
+	}
+	var monitors map[string]MonitorConfig
+	if err := json.Unmarshal(data, &monitors); err != nil {
+		return "", nil, fmt.Errorf("error parsing %s: %w", configFile, err)
+	}
+	return configFile, monitors, nil
+}
+
+// archivedChanges returns the times of the archived versions of url, oldest
+// first: when it was first seen and when it changed
+func archivedChanges(a *archive.Archive, url string) ([]time.Time, error) {
+	versions, err := a.Versions(url)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]time.Time, len(versions))
+	for i, version := range versions {
+		changes[i] = version.Time
+	}
+	return changes, nil
+}
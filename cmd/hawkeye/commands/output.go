@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// Output formats of watch. json is the same as ndjson, one JSON document per
+// line.
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// changeCSVHeader are the columns of the csv format
+var changeCSVHeader = []string{"timestamp", "url", "event", "has_changed", "status_code", "content_type", "latency_ms", "error", "summary", "details"}
+
+// changeEncoder writes changes as NDJSON or CSV, one line or record per
+// change. Each change is written as soon as it is encoded, so that pipes and
+// 'tail -f' see it right away.
+type changeEncoder struct {
+	json *json.Encoder
+	csv  *csv.Writer
+	// wroteHeader is set once the CSV header row was written
+	wroteHeader bool
+}
+
+// newChangeEncoder creates an encoder of changes in format to w, or returns
+// nil for the text format
+func newChangeEncoder(w io.Writer, format string) (*changeEncoder, error) {
+	switch format {
+	case formatText:
+		return nil, nil
+	case formatJSON, formatNDJSON:
+		return &changeEncoder{json: json.NewEncoder(w)}, nil
+	case formatCSV:
+		return &changeEncoder{csv: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format '%s' (expected text, json, ndjson or csv)", format)
+	}
+}
+
+// Encode writes a change, preceded by the header row for the first change
+// in CSV
+func (e *changeEncoder) Encode(change monitor.Change) error {
+	if e.json != nil {
+		return e.json.Encode(change)
+	}
+
+	if !e.wroteHeader {
+		e.csv.Write(changeCSVHeader)
+		e.wroteHeader = true
+	}
+	var statusCode, latency string
+	if change.StatusCode > 0 {
+		statusCode = strconv.Itoa(change.StatusCode)
+	}
+	if change.Latency > 0 {
+		latency = strconv.FormatInt(change.Latency.Milliseconds(), 10)
+	}
+	e.csv.Write([]string{
+		change.Timestamp.Format(time.RFC3339),
+		change.URL,
+		string(change.Event),
+		strconv.FormatBool(change.HasChanged),
+		statusCode,
+		change.ContentType,
+		latency,
+		change.Error,
+		change.Summary,
+		change.Details,
+	})
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// writeChange encodes a change for watch, reporting failures on standard
+// error
+func writeChange(encoder *changeEncoder, change monitor.Change) {
+	if err := encoder.Encode(change); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing change of %s: %s\n", change.URL, err)
+	}
+}
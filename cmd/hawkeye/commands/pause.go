@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/api"
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for pause and resume commands
+	pauseGroup    string
+	pauseSelector string
+	pauseServer   string
+	pauseAPIKey   string
+
+	// pauseCmd represents the pause command
+	pauseCmd = &cobra.Command{
+		Use:   "pause [URLs...]",
+		Short: "Temporarily suspend checks of monitors",
+		Long: `Suspend checks of monitors, e.g. during planned maintenance, without
+losing the content they compare against.
+
+With --server the monitors of a running 'hawkeye serve' are paused immediately.
+Otherwise the saved monitors are marked as paused, and 'hawkeye watch
+--config-file' starts them paused.
+With --selector every monitor whose labels match it is paused, see the
+--label flag of 'hawkeye watch'.
+Example:
+  hawkeye pause https://example.com
+  hawkeye pause --group news --server http://localhost:8080
+  hawkeye pause -l env=staging
+
+A server started with --api-keys needs a key with the write role, given with
+--api-key or HAWKEYE_API_KEY.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			runPause(cmd, args, true)
+		},
+	}
+
+	// resumeCmd represents the resume command
+	resumeCmd = &cobra.Command{
+		Use:   "resume [URLs...]",
+		Short: "Resume checks of paused monitors",
+		Long: `Resume checks of monitors suspended with 'hawkeye pause'.
+Example:
+  hawkeye resume https://example.com
+  hawkeye resume --group news --server http://localhost:8080
+  hawkeye resume -l env=staging,team=web`,
+		Run: func(cmd *cobra.Command, args []string) {
+			runPause(cmd, args, false)
+		},
+	}
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{pauseCmd, resumeCmd} {
+		cmd.Flags().StringVarP(&pauseGroup, "group", "g", "", "Apply to every monitor in this group")
+		cmd.Flags().StringVarP(&pauseSelector, "selector", "l", "", "Apply to every monitor whose labels match (e.g., env=staging,team!=web)")
+		cmd.Flags().StringVarP(&pauseServer, "server", "s", "", "Address of a running 'hawkeye serve' API (e.g. http://localhost:8080)")
+		cmd.Flags().StringVar(&pauseAPIKey, "api-key", "", "API key for a server that requires one")
+	}
+}
+
+// runPause pauses or resumes the monitors selected by args, --group and
+// --selector
+func runPause(cmd *cobra.Command, args []string, pause bool) {
+	if len(args) == 0 && pauseGroup == "" && !cmd.Flags().Changed("selector") {
+		fmt.Println("Error: at least one URL, --group or --selector is required")
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	var selector *monitor.LabelSelector
+	if cmd.Flags().Changed("selector") {
+		parsed, err := monitor.ParseLabelSelector(pauseSelector)
+		if err != nil {
+			fmt.Printf("Invalid --selector: %s\n", err)
+			os.Exit(1)
+		}
+		selector = &parsed
+	}
+
+	action := "Resumed"
+	if pause {
+		action = "Paused"
+	}
+
+	var selected []string
+	var err error
+	if pauseServer != "" {
+		selected, err = pauseOnServer(args, selector, pause)
+	} else {
+		selected, err = pauseSaved(args, selector, pause)
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, u := range args {
+		fmt.Printf("%s %s\n", action, u)
+	}
+	if pauseGroup != "" {
+		fmt.Printf("%s group %s\n", action, pauseGroup)
+	}
+	if selector != nil {
+		for _, u := range selected {
+			fmt.Printf("%s %s\n", action, u)
+		}
+		if len(selected) == 0 {
+			fmt.Printf("No monitor matches '%s'\n", selector)
+		}
+	}
+}
+
+// pauseOnServer pauses or resumes monitors through the API of a running
+// server. It returns the URLs of the monitors matching the selector.
+func pauseOnServer(urls []string, selector *monitor.LabelSelector, pause bool) ([]string, error) {
+	action := "resume"
+	if pause {
+		action = "pause"
+	}
+
+	base := apiBase(pauseServer)
+
+	var endpoints []string
+	for _, u := range urls {
+		endpoints = append(endpoints, fmt.Sprintf("%s/monitors/%s?url=%s", base, action, url.QueryEscape(u)))
+	}
+	if pauseGroup != "" {
+		endpoints = append(endpoints, fmt.Sprintf("%s/groups/%s/%s", base, url.PathEscape(pauseGroup), action))
+	}
+	var selectorEndpoint string
+	if selector != nil {
+		selectorEndpoint = fmt.Sprintf("%s/monitors/%s?selector=%s", base, action, url.QueryEscape(selector.String()))
+		endpoints = append(endpoints, selectorEndpoint)
+	}
+
+	var selected []string
+	client := &http.Client{Timeout: time.Second * 10}
+	for _, endpoint := range endpoints {
+		req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		// The server records the action in its audit log
+		req.Header.Set(api.ActorHeader, audit.LocalActor())
+		if pauseAPIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+pauseAPIKey)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			var body errorBody
+			json.Unmarshal(data, &body)
+			if body.Error != "" {
+				return nil, fmt.Errorf("%s", body.Error)
+			}
+			return nil, fmt.Errorf("server returned status code %d", resp.StatusCode)
+		}
+
+		// The monitors matching a selector are listed in the response
+		if endpoint == selectorEndpoint {
+			var monitors []api.MonitorInfo
+			if err := json.Unmarshal(data, &monitors); err != nil {
+				return nil, fmt.Errorf("invalid response from server: %w", err)
+			}
+			for _, m := range monitors {
+				selected = append(selected, m.URL)
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// errorBody is the error response of the API server
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// pauseSaved marks saved monitors as paused or resumed. It returns the URLs
+// of the monitors matching the selector.
+func pauseSaved(urls []string, selector *monitor.LabelSelector, pause bool) ([]string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	configFile := filepath.Join(configDir, "monitors.json")
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no monitors found, use 'hawkeye watch' to add monitors")
+		}
+		return nil, err
+	}
+
+	var monitors map[string]MonitorConfig
+	if err := json.Unmarshal(data, &monitors); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", configFile, err)
+	}
+
+	for _, u := range urls {
+		entry, exists := monitors[u]
+		if !exists {
+			return nil, fmt.Errorf("no monitor found for URL '%s'", u)
+		}
+		entry.Paused = pause
+		monitors[u] = entry
+	}
+
+	if pauseGroup != "" {
+		found := false
+		for u, entry := range monitors {
+			if entry.Group == pauseGroup {
+				entry.Paused = pause
+				monitors[u] = entry
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("group '%s' does not exist", pauseGroup)
+		}
+	}
+
+	var selected []string
+	if selector != nil {
+		for u, entry := range monitors {
+			if selector.Matches(entry.Labels) {
+				entry.Paused = pause
+				monitors[u] = entry
+				selected = append(selected, u)
+			}
+		}
+		slices.Sort(selected)
+	}
+
+	data, err = json.MarshalIndent(monitors, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		return nil, err
+	}
+
+	action := audit.ActionResume
+	if pause {
+		action = audit.ActionPause
+	}
+	for _, u := range urls {
+		recordAudit(action, u, "")
+	}
+	if pauseGroup != "" {
+		recordAudit(action, audit.GroupTarget(pauseGroup), "")
+	}
+	for _, u := range selected {
+		recordAudit(action, u, "selector "+selector.String())
+	}
+	return selected, nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+// readyPollInterval is how often readiness is checked for --ready-file
+const readyPollInterval = time.Second
+
+// readyFile is written once all monitors are ready, for exec readiness probes
+var readyFile string
+
+// addReadyFlags registers the readiness flags on a command
+func addReadyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&readyFile, "ready-file", "", "File created once every monitor has done its first check, for readiness probes")
+}
+
+// startReadyFile creates the ready file, if one is set, once the manager is
+// ready. A file left over from a previous run is removed first.
+func startReadyFile(ctx context.Context, manager *monitor.Manager) {
+	if readyFile == "" {
+		return
+	}
+
+	os.Remove(readyFile)
+	go func() {
+		ticker := time.NewTicker(readyPollInterval)
+		defer ticker.Stop()
+
+		for !manager.Health().Ready() {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := os.WriteFile(readyFile, []byte("ready\n"), 0644); err != nil {
+			fmt.Printf("Warning: failed to write ready file: %s\n", err)
+		}
+	}()
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/recorder"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for replay command
+	replayMethod           string
+	replayNormalize        bool
+	replayIgnoreTimestamps bool
+	replayIgnore           []string
+	replayIgnoreXPaths     []string
+	replaySelects          []string
+	replayXPaths           []string
+	replayFilters          []string
+	replayFormat           string
+
+	// replayCmd represents the replay command
+	replayCmd = &cobra.Command{
+		Use:   "replay [cassettes...]",
+		Short: "Re-run change detection against recorded HTTP sessions",
+		Long: `Replay cassettes recorded with 'hawkeye watch --record --record-bodies'
+through change detection, without touching the network. Use it to tune
+detection methods, filters and ignore selectors against real traffic: a false
+positive that was recorded once can be replayed until the settings stop
+reporting it.
+Example:
+  hawkeye watch https://example.com --record ./cassettes --record-bodies
+  hawkeye replay ./cassettes/example.com-1a2b3c4d.jsonl --normalize
+  hawkeye replay ./cassettes/*.jsonl --filter 'csrf=[a-z0-9]+' --format json`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			methodValue, err := monitor.ParseMethod(replayMethod)
+			if err != nil || methodValue == monitor.MethodCustom {
+				fmt.Printf("Invalid method: %s (expected hash or length)\n", replayMethod)
+				os.Exit(1)
+			}
+
+			config := monitor.DefaultConfig("")
+			config.Method = methodValue
+			config.NormalizeWhitespace = replayNormalize
+			config.IgnoreTimestamps = replayIgnoreTimestamps
+			config.IgnoreSelectors = append(replayIgnore, xpathSelectors(replayIgnoreXPaths)...)
+			config.WatchSelectors = append(replaySelects, xpathSelectors(replayXPaths)...)
+			if _, err := monitor.NewSelectorFilter(config.WatchSelectors, config.IgnoreSelectors); err != nil {
+				fmt.Printf("Invalid selector: %s\n", err)
+				os.Exit(1)
+			}
+			for _, pattern := range replayFilters {
+				filter, err := monitor.NewRegexFilter(pattern, "", "Ignore "+pattern)
+				if err != nil {
+					fmt.Printf("Invalid filter %q: %s\n", pattern, err)
+					os.Exit(1)
+				}
+				config.ContentFilters = append(config.ContentFilters, filter)
+			}
+
+			failed := false
+			for _, path := range args {
+				cassette, err := recorder.LoadCassette(path)
+				if err != nil {
+					fmt.Printf("Error loading cassette: %s\n", err)
+					failed = true
+					continue
+				}
+
+				steps, err := recorder.Replay(cassette, config)
+				if err != nil {
+					fmt.Printf("Error replaying %s: %s\n", path, err)
+					failed = true
+					continue
+				}
+
+				printReplay(cassette, steps)
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	replayCmd.Flags().StringVarP(&replayMethod, "method", "m", "hash", "Change detection method (hash/length)")
+	replayCmd.Flags().BoolVarP(&replayNormalize, "normalize", "n", false, "Normalize whitespace to ignore insignificant changes")
+	replayCmd.Flags().BoolVarP(&replayIgnoreTimestamps, "ignore-timestamps", "T", false, "Ignore timestamps when comparing content")
+	replayCmd.Flags().StringArrayVarP(&replayIgnore, "ignore", "I", []string{}, "CSS selectors to ignore")
+	replayCmd.Flags().StringArrayVar(&replayIgnoreXPaths, "ignore-xpath", []string{}, "XPath expressions of parts to ignore")
+	replayCmd.Flags().StringArrayVar(&replaySelects, "select", []string{}, "CSS selectors of the parts to watch, ignoring the rest of the page")
+	replayCmd.Flags().StringArrayVar(&replayXPaths, "xpath", []string{}, "XPath expressions of the parts to watch, ignoring the rest")
+	replayCmd.Flags().StringArrayVar(&replayFilters, "filter", []string{}, "Regular expression to strip before comparing (repeatable)")
+	replayCmd.Flags().StringVarP(&replayFormat, "format", "f", "text", "Output format (text/json)")
+}
+
+// printReplay prints the outcome of every replayed check of a cassette
+func printReplay(cassette *recorder.Cassette, steps []recorder.Step) {
+	if replayFormat == "json" {
+		for _, step := range steps {
+			jsonOutput, _ := json.Marshal(step.Change)
+			fmt.Println(string(jsonOutput))
+		}
+		return
+	}
+
+	changes := 0
+	fmt.Printf("%s (%s)\n", cassette.URL(), cassette.Path)
+	for _, step := range steps {
+		recordedAt := step.Interaction.RecordedAt.Format(time.RFC3339)
+
+		switch {
+		case step.Change.Error != "":
+			fmt.Printf("  %s [ERROR] %s\n", recordedAt, step.Change.Error)
+		case step.Change.HasChanged:
+			changes++
+			fmt.Printf("  %s [CHANGED] status %d\n", recordedAt, step.Change.StatusCode)
+			if step.Change.Details != "" {
+				fmt.Printf("    Details: %s\n", step.Change.Details)
+			}
+		default:
+			fmt.Printf("  %s [UNCHANGED] status %d\n", recordedAt, step.Change.StatusCode)
+		}
+	}
+	fmt.Printf("  %d checks, %d changes\n", len(steps), changes)
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/optimize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for report command
+	reportSince  string
+	reportFormat string
+
+	// reportCmd represents the report command
+	reportCmd = &cobra.Command{
+		Use:   "report [URLs...]",
+		Short: "Show when and how often pages change",
+		Long: `Report when pages change, from the versions archived by 'hawkeye watch
+--archive' or 'hawkeye serve --archive'. For each page, a heatmap counts
+the changes by day of the week and hour of the day in local time, and a
+trend counts them week by week, to help pick intervals and schedules; see
+also 'hawkeye optimize'.
+
+Without URLs every saved monitor is reported.
+Example:
+  hawkeye report > report.html
+  hawkeye report https://example.com --since 720h
+  hawkeye report --format json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if reportFormat != "html" && reportFormat != "json" {
+				fmt.Fprintf(os.Stderr, "Invalid --format '%s' (expected html or json)\n", reportFormat)
+				os.Exit(1)
+			}
+			since, err := parseArchiveTime(reportSince)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid --since: %s\n", err)
+				os.Exit(1)
+			}
+
+			urls := args
+			if len(urls) == 0 {
+				_, monitors, err := readSavedMonitors()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+				for u := range monitors {
+					urls = append(urls, u)
+				}
+				sort.Strings(urls)
+			}
+
+			dir, err := archivePath()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+			a := archive.New(dir, archive.Retention{})
+
+			now := time.Now()
+			var pages []pageReport
+			for _, u := range urls {
+				changes, err := archivedChanges(a, u)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading archive: %s\n", err)
+					os.Exit(1)
+				}
+				page := pageReport{
+					URL:     u,
+					Heatmap: optimize.NewHeatmap(changes, since, time.Local),
+					Trend:   optimize.Trend(changes, since, now),
+				}
+				for _, n := range page.Trend {
+					page.Changes += n
+				}
+				if len(changes) > 0 {
+					page.FirstSeen = &changes[0]
+				}
+				pages = append(pages, page)
+			}
+
+			if reportFormat == "json" {
+				data, _ := json.MarshalIndent(map[string]any{
+					"since": since,
+					"until": now,
+					"pages": pages,
+				}, "", "  ")
+				fmt.Println(string(data))
+				return
+			}
+			fmt.Print(reportPage(pages, since, now))
+		},
+	}
+)
+
+func init() {
+	reportCmd.Flags().StringVar(&reportSince, "since", "2160h", "Only count changes since this time or for this long")
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "html", "Output format (html/json)")
+	reportCmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Directory of the archive (default: archive in the data directory)")
+}
+
+// pageReport is the history of changes of a page in a report
+type pageReport struct {
+	URL string `json:"url"`
+	// FirstSeen is the time of the first archived version, if any
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	Changes   int        `json:"changes"`
+	// Heatmap counts the changes by day of the week, from Sunday, and hour
+	Heatmap optimize.Heatmap `json:"heatmap"`
+	// Trend counts the changes of each week, oldest first
+	Trend []int `json:"trend"`
+}
+
+// reportPage renders a report as a standalone HTML page
+func reportPage(pages []pageReport, since, now time.Time) string {
+	var body strings.Builder
+	for _, page := range pages {
+		fmt.Fprintf(&body, "<h2>%s</h2>\n", html.EscapeString(page.URL))
+		if page.FirstSeen == nil {
+			body.WriteString("<p>Nothing archived</p>\n")
+			continue
+		}
+		fmt.Fprintf(&body, "<p>%d changes, first seen %s</p>\n", page.Changes, page.FirstSeen.Local().Format(time.DateTime))
+		body.WriteString(heatmapTable(page.Heatmap))
+		body.WriteString(trendChart(page.Trend, since))
+	}
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Changes</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.heatmap { border-collapse: collapse; font-size: 0.8em; }
+table.heatmap th { color: #888; font-weight: normal; padding: 0 0.4em; }
+table.heatmap td { border: 1px solid #fff; height: 1.4em; width: 1.4em; }
+div.trend { align-items: flex-end; display: flex; gap: 2px; height: 4em; margin: 1em 0; }
+div.trend div { background: #36c; min-height: 1px; width: 1em; }
+</style>
+</head>
+<body>
+<h1>Changes</h1>
+<p>From %s to %s</p>
+%s</body>
+</html>
+`, html.EscapeString(since.Local().Format(time.DateTime)), html.EscapeString(now.Local().Format(time.DateTime)), body.String())
+}
+
+// heatmapTable renders a heatmap as a table of days and hours, shaded by
+// their share of the busiest hour, Monday first
+func heatmapTable(h optimize.Heatmap) string {
+	var b strings.Builder
+	b.WriteString("<table class=\"heatmap\">\n<tr><th></th>")
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(&b, "<th>%d</th>", hour)
+	}
+	b.WriteString("</tr>\n")
+	max := h.Max()
+	for i := 1; i <= 7; i++ {
+		day := time.Weekday(i % 7)
+		fmt.Fprintf(&b, "<tr><th>%s</th>", day.String()[:3])
+		for hour, n := range h[day] {
+			opacity := 0.0
+			if max > 0 {
+				opacity = float64(n) / float64(max)
+			}
+			fmt.Fprintf(&b, `<td style="background: rgba(51, 102, 204, %.2f)" title="%s %02d:00: %d"></td>`, opacity, day, hour, n)
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// trendChart renders the weekly counts of changes as bars
+func trendChart(trend []int, since time.Time) string {
+	max := 0
+	for _, n := range trend {
+		if n > max {
+			max = n
+		}
+	}
+	var b strings.Builder
+	b.WriteString("<div class=\"trend\">")
+	for i, n := range trend {
+		height := 0.0
+		if max > 0 {
+			height = float64(n) * 100 / float64(max)
+		}
+		week := since.AddDate(0, 0, i*7).Local().Format(time.DateOnly)
+		fmt.Fprintf(&b, `<div style="height: %.0f%%" title="Week of %s: %d"></div>`, height, week, n)
+	}
+	b.WriteString("</div>\n")
+	return b.String()
+}
@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/nemuizzz/hawkeye/pkg/offline"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
 	// Used for flags
-	cfgFile string
+	cfgFile     string
+	dataDir     string
+	offlineMode bool
 
 	// rootCmd represents the base command
 	rootCmd = &cobra.Command{
@@ -19,6 +22,18 @@ var (
 		Long: `Hawkeye is a powerful URL monitoring tool that helps you 
 track changes in web content. Monitor multiple URLs
 simultaneously and get notified when content changes.`,
+		// Flags not given on the command line are taken from HAWKEYE_*
+		// environment variables before the config file is read
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyEnv(cmd); err != nil {
+				return err
+			}
+			if offlineMode {
+				offline.Set(true)
+			}
+			initConfig()
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			// If no subcommand is provided, print help
 			cmd.Help()
@@ -32,15 +47,32 @@ func Execute() error {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Here you will define your flags and configuration settings
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.hawkeye.yaml)")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "directory where watched URLs are saved (default is the config file's directory or $HOME/.hawkeye)")
+	rootCmd.PersistentFlags().BoolVar(&offlineMode, "offline", false, "make no external calls other than to the monitored URLs, e.g. in air-gapped deployments")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "enable verbose output")
 
 	// Add sub-commands
 	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(simulateCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(backfillCmd)
+	rootCmd.AddCommand(fingerprintCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(zabbixCmd)
+	rootCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(attachCmd)
+	rootCmd.AddCommand(optimizeCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(auditCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -50,10 +82,9 @@ func initConfig() {
 		// Use config file from the flag
 		viper.SetConfigFile(cfgFile)
 	} else {
-		// Find home directory
+		// Find home directory; containers may not have one
 		home, err := getUserHomeDir()
 		if err != nil {
-			fmt.Println(err)
 			return
 		}
 
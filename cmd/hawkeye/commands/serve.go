@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/nemuizzz/hawkeye/pkg/api"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for serve command
+	serveAddr        string
+	serveHistorySize int
+	serveConcurrent  int
+	serveRateLimit   int
+	serveKeysFile    string
+	serveHistoryFrom string
+
+	// serveCmd represents the serve command
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Run the hawkeye HTTP API server",
+		Long: `Start an HTTP API for creating and deleting monitors, listing groups,
+fetching change history and streaming changes as they happen.
+Example:
+  hawkeye serve --addr :8080
+
+Endpoints:
+  GET    /monitors          List monitors
+  POST   /monitors          Create a monitor
+  POST   /trigger           Check monitors now (?url=...&group=...)
+  POST   /hooks/{name}      Deliver the request body to the monitor of webhook://{name}
+  DELETE /monitors?url=...  Delete a monitor
+  GET    /groups            List groups
+  GET    /changes           Fetch change history (?url=...&limit=...)
+  GET    /changes/stream    Stream changes as server-sent events
+  GET    /archive           List archived versions with --archive (?url=...)
+  GET    /archive/attachments  Fetch an attachment (?url=...&name=...&at=...)
+  POST   /archive/attachments  Attach the request body to a version (?url=...&name=...&at=...)
+  GET    /health            Report whether monitors are running (503 if not)
+  GET    /ready             Report whether every monitor has done its first check (503 if not)
+
+Monitors added, removed, paused or resumed through the API are recorded in
+the audit log shown by 'hawkeye audit', with the actor named in the
+X-Hawkeye-Actor header.
+
+With --api-keys, every endpoint but /health and /ready requires an
+"Authorization: Bearer <token>" header with one of the keys in the file,
+except /hooks of monitors with a webhook_secret, which requests are signed
+with instead:
+
+  keys:
+    - name: dashboard
+      role: read      # list monitors and groups, read changes
+      token: ...
+    - name: ci
+      role: write     # also create, pause, resume and trigger monitors
+      token: ...
+    - name: admin
+      role: manage    # also delete monitors and reset baselines
+      token: ...
+
+Actions are then recorded in the audit log with the name of the key.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			manager := monitor.NewManager()
+			manager.SetMaxConcurrentChecks(serveConcurrent)
+			manager.SetRateLimit(serveRateLimit)
+			setupBrowser(manager)
+			store, err := setupArchive(manager)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			options := &api.Options{
+				Addr:        serveAddr,
+				HistorySize: serveHistorySize,
+				Archive:     store,
+			}
+			if log, err := auditLog(); err == nil {
+				options.Audit = log
+			}
+			if serveKeysFile != "" {
+				keys, err := api.LoadKeys(serveKeysFile)
+				if err != nil {
+					fmt.Printf("Error loading API keys: %s\n", err)
+					os.Exit(1)
+				}
+				options.Keys = keys
+			}
+
+			// Monitors declared in a file, e.g. a mounted ConfigMap, are
+			// served like those created through the API
+			definition, err := loadDefinitions()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if err := setupSummarizer(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			var routes map[string]notify.NotifierList
+			if definition != nil {
+				routes, options.Composites, err = addDefinitionMonitors(manager, definition)
+				if err != nil {
+					fmt.Printf("Error setting up monitors from %s: %s\n", strings.Join(definitionFiles, ", "), err)
+					os.Exit(1)
+				}
+
+				onset := notify.NewErrorOnset()
+				options.OnChange = func(change monitor.Change) {
+					if notifiers := routes[change.URL]; len(notifiers) > 0 && onset.Allow(change) && !change.Silenced && !change.Informational {
+						go sendNotifications(notifiers, change)
+					}
+				}
+			}
+
+			server := api.NewServer(manager, options)
+			if serveHistoryFrom != "" {
+				added, err := seedHistory(server.History(), manager, serveHistoryFrom)
+				if err != nil {
+					fmt.Printf("Error reading history from %s: %s\n", serveHistoryFrom, err)
+					os.Exit(1)
+				}
+				fmt.Printf("Added %d changes from %s to the history\n", added, serveHistoryFrom)
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			// Start the monitors before the first heartbeat reports on them
+			server.Start()
+			if err := startHeartbeat(ctx, manager); err != nil {
+				fmt.Printf("Error starting heartbeat: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Hawkeye API listening on %s\n", serveAddr)
+			if err := server.ListenAndServe(ctx); err != nil {
+				fmt.Printf("Error running API server: %s\n", err)
+				os.Exit(1)
+			}
+
+			manager.Stop()
+			flushDigests(routes)
+		},
+	}
+)
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveAddr, "addr", "a", ":8080", "Address to listen on")
+	serveCmd.Flags().IntVar(&serveHistorySize, "history-size", api.DefaultHistorySize, "Number of changes kept in memory")
+	serveCmd.Flags().StringVar(&serveHistoryFrom, "history-from", "", "Directory of cassettes, e.g. from 'hawkeye backfill', whose changes start the history")
+	serveCmd.Flags().IntVar(&serveConcurrent, "max-concurrent", 0, "Maximum number of URLs fetched at the same time (0 for no limit)")
+	serveCmd.Flags().StringVar(&serveKeysFile, "api-keys", "", "YAML file of API keys and their roles; without it the API is open")
+	addDefinitionFlags(serveCmd)
+	serveCmd.Flags().IntVar(&serveRateLimit, "rate-limit", 0, "Maximum requests per minute to any one host (0 for no limit)")
+	addBrowserFlags(serveCmd)
+	addHeartbeatFlags(serveCmd)
+	addSummarizerFlags(serveCmd)
+	addArchiveFlags(serveCmd)
+}
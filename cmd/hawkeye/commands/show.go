@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Archive flags shared by watch, serve and show
+	archiveEnabled     bool
+	archiveDir         string
+	archiveMaxVersions int
+	archiveMaxAge      string
+	// captureHAR implies the archive, see watch --capture-har
+	captureHAR bool
+
+	// Flags for show command
+	showAt         string
+	showList       bool
+	showAttachment string
+
+	// showCmd represents the show command
+	showCmd = &cobra.Command{
+		Use:   "show <url>",
+		Short: "Show an archived version of a page",
+		Long: `Print the content a page had at a given time, as archived by
+'hawkeye watch --archive' or 'hawkeye serve --archive'. Without --at the
+latest version is printed, and with --attachment one of its attachments,
+see 'hawkeye attach'.
+
+--at takes a time, or a duration for that long ago.
+Example:
+  hawkeye show https://example.com --at 2024-07-01T09:00
+  hawkeye show https://example.com --at 24h > yesterday.html
+  hawkeye show https://example.com --attachment screenshot.png > shot.png
+  hawkeye show https://example.com --list`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			url := args[0]
+			dir, err := archivePath()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+			a := archive.New(dir, archive.Retention{})
+
+			if showList {
+				versions, err := a.Versions(url)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading archive: %s\n", err)
+					os.Exit(1)
+				}
+				if len(versions) == 0 {
+					fmt.Printf("No versions of %s archived in %s\n", url, dir)
+					return
+				}
+				for i, version := range versions {
+					fmt.Printf("%3d  %s  %s  %8d bytes  %s\n", i+1, version.Time.Local().Format(time.DateTime), version.Hash[:12], version.Size, version.ContentType)
+					for _, attachment := range version.Attachments {
+						fmt.Printf("     + %s  %d bytes  %s\n", attachment.Name, attachment.Size, attachment.ContentType)
+					}
+				}
+				return
+			}
+
+			at := time.Now()
+			if showAt != "" {
+				if at, err = parseArchiveTime(showAt); err != nil {
+					fmt.Fprintf(os.Stderr, "Invalid --at: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			version, err := a.At(url, at)
+			if errors.Is(err, archive.ErrNotFound) {
+				fmt.Fprintf(os.Stderr, "Error: %s in %s\n", err, dir)
+				os.Exit(1)
+			}
+			if err == nil && showAttachment != "" {
+				attachment, found := version.Attachment(showAttachment)
+				if !found {
+					fmt.Fprintf(os.Stderr, "Error: no attachment '%s' of the version of %s seen at %s\n", showAttachment, url, version.Time.Local().Format(time.DateTime))
+					os.Exit(1)
+				}
+				var content []byte
+				if content, err = a.ReadAttachment(attachment); err == nil {
+					os.Stdout.Write(content)
+					return
+				}
+			} else if err == nil {
+				var content []byte
+				if content, err = a.Read(version); err == nil {
+					os.Stdout.Write(content)
+					return
+				}
+			}
+			fmt.Fprintf(os.Stderr, "Error reading archive: %s\n", err)
+			os.Exit(1)
+		},
+	}
+)
+
+func init() {
+	showCmd.Flags().StringVar(&showAt, "at", "", "Time of the version, or how long ago (e.g., 2024-07-01T09:00 or 24h)")
+	showCmd.Flags().BoolVarP(&showList, "list", "l", false, "List the archived versions and their attachments instead")
+	showCmd.Flags().StringVar(&showAttachment, "attachment", "", "Print the attachment with this name instead of the content")
+	showCmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Directory of the archive (default: archive in the data directory)")
+}
+
+// addArchiveFlags registers the flags of the snapshot archive on a command
+func addArchiveFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&archiveEnabled, "archive", false, "Archive the content of every change, see 'hawkeye show'")
+	cmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Directory of the archive (default: archive in the data directory)")
+	cmd.Flags().IntVar(&archiveMaxVersions, "archive-max-versions", 0, "Number of versions kept of each URL (default: 0, all)")
+	cmd.Flags().StringVar(&archiveMaxAge, "archive-max-age", "", "Drop versions replaced longer ago than this (e.g., 720h)")
+}
+
+// setupArchive sets the archive of the manager from the flags and returns
+// it, or nil without one. It must be called before monitors are added.
+func setupArchive(manager *monitor.Manager) (*archive.Archive, error) {
+	if !archiveEnabled && archiveDir == "" && !captureHAR {
+		return nil, nil
+	}
+
+	retention := archive.Retention{MaxVersions: archiveMaxVersions}
+	if archiveMaxAge != "" {
+		d, err := time.ParseDuration(archiveMaxAge)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid --archive-max-age '%s': must be a positive duration", archiveMaxAge)
+		}
+		retention.MaxAge = d
+	}
+	dir, err := archivePath()
+	if err != nil {
+		return nil, err
+	}
+	a := archive.New(dir, retention)
+	manager.SetArchive(a)
+	fmt.Printf("Archiving changes to: %s\n", dir)
+	return a, nil
+}
+
+// parseArchiveTime parses a time, or a duration for that long ago
+func parseArchiveTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return schedule.ParseTime(value, nil)
+}
+
+// archivePath returns the directory of the archive
+func archivePath() (string, error) {
+	if archiveDir != "" {
+		return archiveDir, nil
+	}
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "archive"), nil
+}
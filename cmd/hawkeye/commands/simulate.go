@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/simulate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for simulate command
+	simMonitors    int
+	simInterval    string
+	simDuration    string
+	simSpeed       float64
+	simLatency     string
+	simChangeRate  float64
+	simBodySize    int
+	simMethod      string
+	simFormat      string
+	simRamp        bool
+	simMaxMonitors int
+	simThreshold   float64
+
+	// simulateCmd represents the simulate command
+	simulateCmd = &cobra.Command{
+		Use:   "simulate",
+		Short: "Estimate monitoring capacity with a dry-run scheduler",
+		Long: `Run the scheduler against a mock fetcher at accelerated time and report how
+many of the scheduled checks this machine actually performed. No network
+requests are made.
+
+With --ramp the number of monitors is doubled until the machine can no longer
+keep up, and the largest sustained load is reported.
+Example:
+  hawkeye simulate --monitors 500 --interval 1m --duration 1h --speed 60
+  hawkeye simulate --ramp --max-monitors 10000`,
+		Run: func(cmd *cobra.Command, args []string) {
+			opts, err := simulateOptions()
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			var results []*simulate.Result
+			if simRamp {
+				results, err = simulate.Ramp(ctx, opts, simThreshold, simMaxMonitors)
+			} else {
+				var result *simulate.Result
+				result, err = simulate.Run(ctx, opts)
+				if result != nil {
+					results = append(results, result)
+				}
+			}
+
+			if simFormat == "json" {
+				jsonOutput, _ := json.MarshalIndent(results, "", "  ")
+				fmt.Println(string(jsonOutput))
+			} else {
+				for _, result := range results {
+					status := "sustained"
+					if !result.Sustained(simThreshold) {
+						status = "overloaded"
+					}
+					fmt.Printf("[%s] %s\n", status, result)
+				}
+
+				if capacity := simulate.Capacity(results, simThreshold); capacity != nil {
+					fmt.Printf("\nEstimated capacity: ~%d monitors every %s\n", capacity.EquivalentMonitors, capacity.Interval)
+				} else if len(results) > 0 {
+					fmt.Println("\nThis machine could not sustain the smallest simulated load")
+				}
+			}
+
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	defaults := simulate.DefaultOptions()
+
+	simulateCmd.Flags().IntVarP(&simMonitors, "monitors", "m", defaults.Monitors, "Number of simulated monitors (starting point with --ramp)")
+	simulateCmd.Flags().StringVarP(&simInterval, "interval", "i", defaults.Interval.String(), "Simulated check interval")
+	simulateCmd.Flags().StringVarP(&simDuration, "duration", "d", defaults.Duration.String(), "Simulated duration of each run")
+	simulateCmd.Flags().Float64VarP(&simSpeed, "speed", "s", defaults.Speed, "Time acceleration factor")
+	simulateCmd.Flags().StringVar(&simLatency, "latency", defaults.Latency.String(), "Simulated response latency")
+	simulateCmd.Flags().Float64Var(&simChangeRate, "change-rate", defaults.ChangeRate, "Probability that content changes between fetches")
+	simulateCmd.Flags().IntVar(&simBodySize, "body-size", defaults.BodySize, "Size in bytes of each simulated response")
+	simulateCmd.Flags().StringVar(&simMethod, "method", defaults.Method.String(), "Change detection method (hash/length)")
+	simulateCmd.Flags().StringVarP(&simFormat, "format", "f", "text", "Output format (text/json)")
+	simulateCmd.Flags().BoolVar(&simRamp, "ramp", false, "Double the number of monitors until the machine falls behind")
+	simulateCmd.Flags().IntVar(&simMaxMonitors, "max-monitors", 100000, "Upper bound for --ramp")
+	simulateCmd.Flags().Float64Var(&simThreshold, "threshold", simulate.DefaultThreshold, "Minimum ratio of performed to expected checks")
+}
+
+// simulateOptions builds simulation options from the command flags
+func simulateOptions() (*simulate.Options, error) {
+	opts := simulate.DefaultOptions()
+	opts.Monitors = simMonitors
+	opts.Speed = simSpeed
+	opts.ChangeRate = simChangeRate
+	opts.BodySize = simBodySize
+
+	var err error
+	if opts.Interval, err = time.ParseDuration(simInterval); err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	if opts.Duration, err = time.ParseDuration(simDuration); err != nil {
+		return nil, fmt.Errorf("invalid duration: %w", err)
+	}
+	if opts.Latency, err = time.ParseDuration(simLatency); err != nil {
+		return nil, fmt.Errorf("invalid latency: %w", err)
+	}
+	if opts.Method, err = monitor.ParseMethod(simMethod); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/sitemap"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Sitemap flags of watch
+	sitemapURLs     []string
+	sitemapInclude  []string
+	sitemapExclude  []string
+	sitemapInterval time.Duration
+	sitemapHints    bool
+)
+
+// addSitemapFlags registers the sitemap flags on a command
+func addSitemapFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&sitemapURLs, "sitemap", []string{}, "Watch every URL of a sitemap or sitemap index, following it as it changes")
+	cmd.Flags().StringArrayVar(&sitemapInclude, "sitemap-include", []string{}, "Only watch sitemap URLs matching this regular expression (e.g., '/blog/')")
+	cmd.Flags().StringArrayVar(&sitemapExclude, "sitemap-exclude", []string{}, "Don't watch sitemap URLs matching this regular expression")
+	cmd.Flags().DurationVar(&sitemapInterval, "sitemap-refresh", sitemap.DefaultRefreshInterval, "Time between reads of the sitemaps (0 to read them only once)")
+	cmd.Flags().BoolVar(&sitemapHints, "sitemap-hints", true, "Check sitemap pages as often as their changefreq and priority suggest, unless --interval is given")
+}
+
+// addSitemapMonitors reads the sitemaps and adds a monitor with the default
+// settings for each of their URLs. With useHints, the interval of each
+// monitor is set from the hints of its page instead. It returns the watchers
+// to refresh the monitors and, for linting, the configuration of the first
+// monitor of each sitemap, as they all share the same settings.
+func addSitemapMonitors(manager *monitor.Manager, defaults *monitor.Config, useHints bool) ([]*sitemap.Watcher, []*monitor.Config, error) {
+	if len(sitemapURLs) == 0 {
+		return nil, nil, nil
+	}
+
+	filter, err := sitemap.NewFilter(sitemapInclude, sitemapExclude)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fetcher := &sitemap.Fetcher{
+		Client: customhttp.NewClient(&customhttp.ClientOptions{
+			Timeout:         defaults.Timeout,
+			FollowRedirects: true,
+			UserAgent:       version.UserAgent(),
+			ProxyURL:        defaults.ProxyURL,
+			TLS:             defaults.TLS,
+		}),
+		Headers: defaults.Headers,
+	}
+
+	var configs []*monitor.Config
+	var watchers []*sitemap.Watcher
+	for _, sitemapURL := range sitemapURLs {
+		w := &sitemap.Watcher{
+			URL:      sitemapURL,
+			Fetcher:  fetcher,
+			Filter:   filter,
+			Manager:  manager,
+			Interval: sitemapInterval,
+			UseHints: useHints,
+			Config: func(url string) *monitor.Config {
+				config := *defaults
+				config.URL = url
+				applyRecording(&config)
+				return &config
+			},
+		}
+
+		added, _, err := w.Sync(context.Background())
+		if len(added) == 0 && err != nil {
+			return nil, nil, err
+		}
+		if err != nil {
+			fmt.Printf("Warning: %s\n", err)
+		}
+		if len(added) > 0 {
+			if m, err := manager.GetMonitor(added[0]); err == nil {
+				config := m.GetConfig()
+				configs = append(configs, &config)
+			}
+		}
+		addToGroupFlag(manager, added)
+		when := describeSchedule(defaults)
+		if useHints && defaults.Schedule == nil && defaults.At.IsZero() {
+			when = "as often as the sitemap suggests"
+		}
+		fmt.Printf("Monitoring %d URLs from %s %s\n", len(added), sitemapURL, when)
+		watchers = append(watchers, w)
+	}
+
+	return watchers, configs, nil
+}
+
+// startSitemapRefresh re-reads the sitemaps every --sitemap-refresh until
+// ctx is canceled, adding monitors for new URLs and removing those of URLs
+// that left the sitemap
+func startSitemapRefresh(ctx context.Context, manager *monitor.Manager, watchers []*sitemap.Watcher) {
+	if sitemapInterval <= 0 {
+		return
+	}
+
+	for _, w := range watchers {
+		go w.Run(ctx, func(added, removed []string, err error) {
+			if err != nil {
+				fmt.Printf("Error refreshing sitemap %s: %s\n", w.URL, err)
+			}
+			addToGroupFlag(manager, added)
+			for _, url := range added {
+				fmt.Printf("Monitoring %s, added to %s\n", url, w.URL)
+			}
+			for _, url := range removed {
+				fmt.Printf("Stopped monitoring %s, removed from %s\n", url, w.URL)
+			}
+		})
+	}
+}
+
+// addToGroupFlag adds URLs found in a sitemap or by crawling to the --group,
+// if one is set
+func addToGroupFlag(manager *monitor.Manager, urls []string) {
+	if group == "" || len(urls) == 0 {
+		return
+	}
+
+	if _, err := manager.GetGroup(group); err != nil {
+		if _, err := manager.CreateGroup(group, "Created via CLI"); err != nil {
+			fmt.Printf("Error creating group '%s': %s\n", group, err)
+			return
+		}
+	}
+	for _, url := range urls {
+		if err := manager.AddToGroup(url, group); err != nil {
+			fmt.Printf("Error adding %s to group '%s': %s\n", url, group, err)
+		}
+	}
+}
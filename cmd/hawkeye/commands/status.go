@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/api"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+// statusVersion is the version of the status document. Fields may be added
+// without changing it; it changes if fields are renamed or removed.
+const statusVersion = 1
+
+// Overall states of a status document, named like the states of Nagios
+// plugins
+const (
+	statusOK       = "ok"
+	statusWarning  = "warning"
+	statusCritical = "critical"
+	statusUnknown  = "unknown"
+)
+
+// statusCodes are the exit codes of the overall states, those of Nagios
+// plugins
+var statusCodes = map[string]int{statusOK: 0, statusWarning: 1, statusCritical: 2, statusUnknown: 3}
+
+// States of a monitor in a status document
+const (
+	monitorOK      = "ok"
+	monitorFailing = "failing"
+	monitorStalled = "stalled"
+	monitorPaused  = "paused"
+	monitorPending = "pending"
+)
+
+var (
+	// Flags for status command
+	statusFormat string
+	statusGroup  string
+	statusServer string
+	statusAPIKey string
+
+	// statusCmd represents the status command
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the monitors of a running server",
+		Long: `Show the state of every monitor of a running 'hawkeye serve': whether its
+last check succeeded, when it was checked and when it is checked next.
+
+The overall status is critical if a monitor is failing or stalled, warning
+if a monitor hasn't been checked yet, and unknown if the server can't be
+reached. A monitor whose checks failed fewer times in a row than its
+failure threshold isn't failing yet; its error and failures are shown.
+The exit code follows Nagios plugins: 0 ok, 1 warning, 2 critical and 3
+unknown.
+
+Monitors whose pages served identical content at their last check, after
+filters, are marked as duplicates, e.g. the www and apex domains of a site,
+so that redundant monitors can be removed. They don't change the status.
+
+With --format json a single document is printed whose field names are
+stable, for dashboards and monitoring wrappers.
+Example:
+  hawkeye status
+  hawkeye status --server http://hawkeye:8080 --format json
+
+A server started with --api-keys needs a key with the read role, given with
+--api-key or HAWKEYE_API_KEY.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if statusFormat != "text" && statusFormat != "json" {
+				fmt.Printf("Invalid --format '%s' (expected text or json)\n", statusFormat)
+				os.Exit(statusCodes[statusUnknown])
+			}
+
+			report := fetchStatus(apiBase(statusServer), statusAPIKey, statusGroup)
+			if statusFormat == "json" {
+				jsonOutput, _ := json.MarshalIndent(report, "", "  ")
+				fmt.Println(string(jsonOutput))
+			} else {
+				printStatus(report)
+			}
+			os.Exit(statusCodes[report.Status])
+		},
+	}
+)
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusFormat, "format", "f", "text", "Output format (text/json)")
+	statusCmd.Flags().StringVarP(&statusGroup, "group", "g", "", "Only show the monitors of this group")
+	statusCmd.Flags().StringVarP(&statusServer, "server", "s", "http://localhost:8080", "Address of a running 'hawkeye serve' API")
+	statusCmd.Flags().StringVar(&statusAPIKey, "api-key", "", "API key for a server that requires one")
+}
+
+// statusReport is the document printed by 'hawkeye status --format json'.
+// Every field is always present, null or empty when it doesn't apply.
+type statusReport struct {
+	Version  int             `json:"version"`
+	Time     time.Time       `json:"time"`
+	Server   string          `json:"server"`
+	Status   string          `json:"status"`
+	Error    string          `json:"error"`
+	Summary  statusSummary   `json:"summary"`
+	Monitors []monitorStatus `json:"monitors"`
+}
+
+// statusSummary counts the monitors of a status document by state
+type statusSummary struct {
+	Total   int `json:"total"`
+	OK      int `json:"ok"`
+	Failing int `json:"failing"`
+	Stalled int `json:"stalled"`
+	Paused  int `json:"paused"`
+	Pending int `json:"pending"`
+	// Duplicates counts the monitors with the same content as another
+	Duplicates int `json:"duplicates"`
+}
+
+// monitorStatus is the state of a monitor in a status document
+type monitorStatus struct {
+	URL        string     `json:"url"`
+	Groups     []string   `json:"groups"`
+	State      string     `json:"state"`
+	Method     string     `json:"method"`
+	LastCheck  *time.Time `json:"last_check"`
+	NextCheck  *time.Time `json:"next_check"`
+	CheckCount int64      `json:"check_count"`
+	LatencyMS  *int64     `json:"latency_ms"`
+	Error      string     `json:"error"`
+	// Failures counts the checks in a row that failed
+	Failures int64 `json:"failures"`
+	// DuplicateOf lists the other monitors whose last content was identical
+	DuplicateOf []string `json:"duplicate_of"`
+}
+
+// apiBase returns the base URL of the API at addr, which may leave out the
+// scheme
+func apiBase(addr string) string {
+	base := strings.TrimSuffix(addr, "/")
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return base
+}
+
+// fetchStatus builds the status document of the monitors of the server at
+// base, only those of group if it isn't empty. A server that can't be
+// queried gives the unknown status with the error.
+func fetchStatus(base, apiKey, group string) *statusReport {
+	report := &statusReport{
+		Version:  statusVersion,
+		Time:     time.Now().UTC(),
+		Server:   base,
+		Monitors: []monitorStatus{},
+	}
+
+	client := &http.Client{Timeout: time.Second * 10}
+	get := func(path string, v any, ok ...int) error {
+		req, err := http.NewRequest(http.MethodGet, base+path, nil)
+		if err != nil {
+			return err
+		}
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && !slices.Contains(ok, resp.StatusCode) {
+			var body errorBody
+			json.NewDecoder(resp.Body).Decode(&body)
+			if body.Error != "" {
+				return fmt.Errorf("%s: %s", path, body.Error)
+			}
+			return fmt.Errorf("%s: server returned status code %d", path, resp.StatusCode)
+		}
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	var monitors []api.MonitorInfo
+	var groups []api.GroupInfo
+	var health monitor.Health
+	// /health describes an unhealthy manager with 503 Service Unavailable
+	err := get("/health", &health, http.StatusServiceUnavailable)
+	if err == nil {
+		err = get("/monitors", &monitors)
+	}
+	if err == nil {
+		err = get("/groups", &groups)
+	}
+	if err != nil {
+		report.Status = statusUnknown
+		report.Error = err.Error()
+		return report
+	}
+
+	groupsOf := make(map[string][]string)
+	for _, g := range groups {
+		for _, u := range g.Monitors {
+			groupsOf[u] = append(groupsOf[u], g.Name)
+		}
+	}
+	stalled := make(map[string]bool)
+	for _, u := range health.Stalled {
+		stalled[u] = true
+	}
+
+	for _, info := range monitors {
+		if group != "" && !slices.Contains(groupsOf[info.URL], group) {
+			continue
+		}
+
+		status := monitorStatus{
+			URL:        info.URL,
+			Groups:     groupsOf[info.URL],
+			Method:     info.Method,
+			NextCheck:  info.NextCheck,
+			CheckCount: info.CheckCount,
+			Error:      info.LastError,
+			Failures:   info.Failures,
+		}
+		if status.Groups == nil {
+			status.Groups = []string{}
+		}
+		status.DuplicateOf = []string{}
+		for _, u := range info.DuplicateOf {
+			// Only duplicates shown in the document
+			if group == "" || slices.Contains(groupsOf[u], group) {
+				status.DuplicateOf = append(status.DuplicateOf, u)
+			}
+		}
+		if len(status.DuplicateOf) > 0 {
+			report.Summary.Duplicates++
+		}
+		if !info.LastCheck.IsZero() {
+			lastCheck := info.LastCheck
+			status.LastCheck = &lastCheck
+		}
+		if latency, err := time.ParseDuration(info.Latency); err == nil {
+			ms := latency.Milliseconds()
+			status.LatencyMS = &ms
+		}
+
+		switch {
+		case info.Paused:
+			status.State = monitorPaused
+			report.Summary.Paused++
+		case stalled[info.URL]:
+			status.State = monitorStalled
+			report.Summary.Stalled++
+		case info.Failing:
+			// Errors below the failure threshold of the monitor aren't
+			// reported yet, they leave it ok
+			status.State = monitorFailing
+			report.Summary.Failing++
+		case info.LastCheck.IsZero() && !monitor.IsWebhookURL(info.URL):
+			// Webhook monitors wait for payloads rather than a check
+			status.State = monitorPending
+			report.Summary.Pending++
+		default:
+			status.State = monitorOK
+			report.Summary.OK++
+		}
+		report.Summary.Total++
+		report.Monitors = append(report.Monitors, status)
+	}
+
+	switch {
+	case !health.Running:
+		report.Status = statusCritical
+		report.Error = "monitors are not running"
+	case report.Summary.Failing > 0 || report.Summary.Stalled > 0:
+		report.Status = statusCritical
+	case report.Summary.Pending > 0:
+		report.Status = statusWarning
+	default:
+		report.Status = statusOK
+	}
+	return report
+}
+
+// printStatus prints a status document for people
+func printStatus(report *statusReport) {
+	if report.Status == statusUnknown {
+		fmt.Printf("Status: UNKNOWN (%s)\n", report.Error)
+		return
+	}
+
+	summary := report.Summary
+	fmt.Printf("Status: %s (%d monitors: %d ok, %d failing, %d stalled, %d paused, %d pending)\n",
+		strings.ToUpper(report.Status), summary.Total, summary.OK, summary.Failing, summary.Stalled, summary.Paused, summary.Pending)
+	if report.Error != "" {
+		fmt.Printf("  %s\n", report.Error)
+	}
+	if len(report.Monitors) == 0 {
+		return
+	}
+
+	fmt.Println()
+	for _, status := range report.Monitors {
+		line := fmt.Sprintf("%-8s %s", status.State, status.URL)
+		if status.LastCheck != nil {
+			line += fmt.Sprintf(" (checked %s ago)", time.Since(*status.LastCheck).Round(time.Second))
+		}
+		if len(status.DuplicateOf) > 0 {
+			line += fmt.Sprintf(" (same content as %s)", strings.Join(status.DuplicateOf, ", "))
+		}
+		if status.Error != "" {
+			line += ": " + status.Error
+		}
+		if status.Failures > 1 {
+			line += fmt.Sprintf(" (%d failed checks in a row)", status.Failures)
+		}
+		fmt.Println(line)
+	}
+	if summary.Duplicates > 0 {
+		fmt.Printf("\n%d monitors serve the same content as another and may be redundant\n", summary.Duplicates)
+	}
+}
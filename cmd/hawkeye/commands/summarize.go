@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/summarize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Summarizer flags shared by watch and serve
+	summarizerName  string
+	summarizerURL   string
+	summarizerModel string
+	summarizerKey   string
+
+	// summarizer describes the changes sent to notifiers, if set
+	summarizer summarize.Summarizer
+)
+
+// addSummarizerFlags registers the summarizer flags on a command
+func addSummarizerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&summarizerName, "summarizer", "heuristic", "How changes are summarized in notifications: heuristic, llm or off")
+	cmd.Flags().StringVar(&summarizerURL, "summarizer-url", "", "OpenAI-compatible chat completions URL of --summarizer llm")
+	cmd.Flags().StringVar(&summarizerModel, "summarizer-model", "", "Model of --summarizer llm (e.g., gpt-4o-mini)")
+	cmd.Flags().StringVar(&summarizerKey, "summarizer-key", "", "API key of --summarizer llm, or @file to read it from a file")
+}
+
+// setupSummarizer sets the summarizer from the flags. The LLM summarizer is
+// refused in offline mode.
+func setupSummarizer() error {
+	switch strings.ToLower(summarizerName) {
+	case "off", "none":
+		summarizer = nil
+	case "", "heuristic":
+		summarizer = summarize.Heuristic{}
+	case "llm":
+		if summarizerURL == "" {
+			return fmt.Errorf("--summarizer llm requires --summarizer-url")
+		}
+		if err := offline.Check("summarizer"); err != nil {
+			return err
+		}
+		key, err := readRequestData(summarizerKey)
+		if err != nil {
+			return err
+		}
+		summarizer = &summarize.LLM{URL: summarizerURL, Model: summarizerModel, APIKey: strings.TrimSpace(key)}
+	default:
+		return fmt.Errorf("unknown summarizer '%s' (expected heuristic, llm or off)", summarizerName)
+	}
+	return nil
+}
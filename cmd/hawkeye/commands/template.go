@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// templateFuncs are the functions of --template besides those of
+// text/template
+var templateFuncs = template.FuncMap{
+	// json encodes a value, e.g. {{json .}} for the whole change
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// csv quotes a value for a CSV field if needed, e.g. {{csv .Details}}
+	"csv":   csvField,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// parseOutputTemplate parses the template printed for each change
+func parseOutputTemplate(text string) (*template.Template, error) {
+	return template.New("template").Funcs(templateFuncs).Parse(text)
+}
+
+// renderChange executes tmpl with change, ending the output with a newline
+// if it doesn't
+func renderChange(tmpl *template.Template, change monitor.Change) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, change); err != nil {
+		return "", err
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// templateOutput renders change with tmpl for watch. A change the template
+// fails on is reported on standard error and prints nothing.
+func templateOutput(tmpl *template.Template, change monitor.Change) string {
+	out, err := renderChange(tmpl, change)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error in --template for %s: %s\n", change.URL, err)
+		return ""
+	}
+	return out
+}
+
+// csvField quotes s as a CSV field when it holds a comma, a quote or a line
+// break
+func csvField(s string) string {
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"runtime"
 
+	"github.com/nemuizzz/hawkeye/pkg/offline"
 	"github.com/nemuizzz/hawkeye/pkg/version"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,9 @@ var versionCmd = &cobra.Command{
 		fmt.Printf("Git Commit: %s\n", version.GitCommit)
 		fmt.Printf("Go Version: %s\n", runtime.Version())
 		fmt.Printf("OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+		if offline.Built() {
+			fmt.Println("Offline: always (offline build)")
+		}
 	},
 }
 
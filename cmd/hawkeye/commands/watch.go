@@ -1,45 +1,184 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/imap"
 	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/recorder"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Flag variables
 	interval            string
+	cronSchedule        string
+	checkAt             string
+	until               string
+	deadline            string
+	expires             string
+	expect              []string
+	labels              []string
+	maxChecks           int
+	jitter              string
+	proxy               string
+	acceptEncoding      string
+	noCache             bool
+	cacheBust           string
+	insecure            bool
+	caFile              string
+	minTLSVersion       string
+	clientCert          string
+	clientKey           string
+	noStagger           bool
+	maxConcurrent       int
+	domainPolicies      []string
+	rateLimit           int
+	hostRateLimits      []string
 	timeout             string
 	format              string
+	outputTemplate      string
+	noColor             bool
 	headers             []string
+	baggage             []string
 	ignore              []string
+	ignoreXPaths        []string
+	selects             []string
+	alerts              []string
+	xpaths              []string
 	output              string
 	group               string
 	retryCount          int
 	retryInterval       string
+	failureThreshold    int
+	confirmDelay        string
 	normalizeWhitespace bool
 	ignoreTimestamps    bool
+	method              string
+	expectStatus        []int
+	matches             []string
+	matchesAbsent       []string
+	counts              []string
+	csvKeys             []string
+	csvDelimiter        string
+	tailSeparator       string
+	imageThreshold      float64
+	imageDiffDir        string
+	paginate            string
+	paginateItems       string
+	maxPages            int
+	appendOnly          bool
+	representations     []string
+	variants            []string
+	extract             string
+	below               float64
+	above               float64
+	delta               float64
+	deltaPercent        float64
+	configFile          string
+	execCommand         string
+	execTimeout         time.Duration
+	execThrottle        time.Duration
+	execEvents          []string
+	recordDir           string
+	recordBodies        bool
+	diffContext         int
+	maxDetailsLines     int
+	maxDetailsBytes     int
+	maxBodySize         int64
+	maxSnapshotSize     int64
+	maintenanceWindows  []string
+	quietWindows        []string
+	noSave              bool
+	respectRobotsTxt    bool
+	fetcher             string
+	waitSelector        string
+	requestMethod       string
+	requestData         string
+	keepCookies         bool
+	cookies             []string
+	shareCookies        bool
+	basicAuth           string
+	bearerToken         string
+	loginURL            string
+	loginData           string
+	loginToken          string
+	oauth2TokenURL      string
+	oauth2ClientID      string
+	oauth2ClientSecret  string
+	oauth2Scopes        []string
 
 	// watchCmd represents the watch command
 	watchCmd = &cobra.Command{
 		Use:   "watch [URLs...]",
 		Short: "Monitor URLs for changes",
 		Long: `Watch one or more URLs for changes and report when content changes.
+
+Flags apply to every URL. Give a URL its own interval with URL@interval, or
+load per-URL intervals, methods, headers and filters from a JSON file with
+--config-file (the monitors.json format written by watch is accepted).
 Example:
-  hawkeye watch https://example.com --interval 5m`,
+  hawkeye watch https://example.com --interval 5m
+  hawkeye watch https://example.com/news@1m https://example.com/about@1h
+  hawkeye watch https://example.com --schedule '*/10 9-17 * * mon-fri'
+  hawkeye watch --config-file monitors.json
+  hawkeye watch --from-file monitors.yaml
+  hawkeye watch --sitemap https://example.com/sitemap.xml --sitemap-include '/blog/'
+  hawkeye watch --crawl https://example.com --crawl-depth 3
+
+Every flag can also be set with an environment variable named after it, e.g.
+HAWKEYE_INTERVAL for --interval, and URLs with HAWKEYE_URLS.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) == 0 {
-				fmt.Println("Error: at least one URL is required")
+			args = append(args, strings.Fields(os.Getenv(urlsEnv))...)
+			if len(args) == 0 && configFile == "" && len(definitionFiles) == 0 && len(sitemapURLs) == 0 && len(crawlURLs) == 0 {
+				fmt.Println("Error: at least one URL, --config-file, --from-file, --sitemap or --crawl is required")
 				cmd.Help()
 				os.Exit(1)
 			}
 
+			// Monitors added by a sitemap refresh would never count as
+			// finished
+			if len(sitemapURLs) > 0 && (checkAt != "" || until != "" || deadline != "" || expires != "" || maxChecks > 0) {
+				fmt.Println("Error: --sitemap can't be combined with --at, --until, --deadline, --expires or --max-checks")
+				os.Exit(1)
+			}
+
+			// Validate the definition files before setting anything up
+			definition, err := loadDefinitions()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if err := setupSummarizer(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			if recordDir != "" {
+				if err := os.MkdirAll(recordDir, 0755); err != nil {
+					fmt.Printf("Error creating record directory: %s\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Recording HTTP sessions to: %s\n", recordDir)
+			}
+
 			// Parse durations
 			intervalDuration, err := time.ParseDuration(interval)
 			if err != nil {
@@ -59,73 +198,609 @@ Example:
 				os.Exit(1)
 			}
 
-			// Parse headers
-			headerMap := make(map[string]string)
-			for _, h := range headers {
-				// Parse header in format "key:value"
-				parts := strings.SplitN(h, ":", 2)
-				if len(parts) != 2 {
-					fmt.Printf("Warning: invalid header format: %s (expected 'key:value')\n", h)
-					continue
+			var confirmDelayDuration time.Duration
+			if confirmDelay != "" {
+				if confirmDelayDuration, err = time.ParseDuration(confirmDelay); err != nil {
+					fmt.Printf("Invalid confirm delay: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			fetcherValue, err := monitor.ParseFetcher(fetcher)
+			if err != nil {
+				fmt.Printf("Invalid fetcher: %s\n", err)
+				os.Exit(1)
+			}
+			if waitSelector != "" && fetcherValue != monitor.FetcherBrowser {
+				fmt.Println("--wait-selector requires --fetcher browser")
+				os.Exit(1)
+			}
+
+			requestMethodValue, err := monitor.ParseRequestMethod(requestMethod)
+			if err != nil {
+				fmt.Printf("Invalid request method: %s\n", err)
+				os.Exit(1)
+			}
+			body, err := readRequestData(requestData)
+			if err != nil {
+				fmt.Printf("Error reading --data: %s\n", err)
+				os.Exit(1)
+			}
+			if body != "" && requestMethod != "" && (requestMethodValue == http.MethodGet || requestMethodValue == http.MethodHead) {
+				fmt.Printf("--data can't be sent with --request-method %s\n", requestMethodValue)
+				os.Exit(1)
+			}
+			if requestMethod == "" {
+				// A body without a method is POSTed
+				requestMethodValue = ""
+			}
+
+			methodValue, err := monitor.ParseMethod(method)
+			if err != nil || methodValue == monitor.MethodCustom {
+				fmt.Printf("Invalid method: %s (expected hash, length, status, keyword, value, feed, count, csv, image or tail)\n", method)
+				os.Exit(1)
+			}
+			// Watching for keywords or a value implies the matching method
+			if len(matches)+len(matchesAbsent) > 0 && !cmd.Flags().Changed("method") {
+				methodValue = monitor.MethodKeyword
+			}
+			if extract != "" && !cmd.Flags().Changed("method") {
+				methodValue = monitor.MethodValue
+			}
+			if len(counts) > 0 && !cmd.Flags().Changed("method") {
+				methodValue = monitor.MethodCount
+			}
+			if (len(csvKeys) > 0 || csvDelimiter != "") && !cmd.Flags().Changed("method") {
+				methodValue = monitor.MethodCSV
+			}
+			if (len(csvKeys) > 0 || csvDelimiter != "") && methodValue != monitor.MethodCSV {
+				fmt.Println("--csv-key and --csv-delimiter require --method csv")
+				os.Exit(1)
+			}
+			if tailSeparator != "" && !cmd.Flags().Changed("method") {
+				methodValue = monitor.MethodTail
+			}
+			if tailSeparator != "" && methodValue != monitor.MethodTail {
+				fmt.Println("--tail-separator requires --method tail")
+				os.Exit(1)
+			}
+			if (imageThreshold != 0 || imageDiffDir != "") && !cmd.Flags().Changed("method") {
+				methodValue = monitor.MethodImage
+			}
+			if (imageThreshold != 0 || imageDiffDir != "") && methodValue != monitor.MethodImage {
+				fmt.Println("--image-threshold and --image-diff-dir require --method image")
+				os.Exit(1)
+			}
+			if imageThreshold < 0 || imageThreshold > 100 {
+				fmt.Println("--image-threshold must be between 0 and 100")
+				os.Exit(1)
+			}
+			if failureThreshold < 0 {
+				fmt.Println("--failure-threshold must not be negative")
+				os.Exit(1)
+			}
+			if methodValue == monitor.MethodCount && len(counts) == 0 {
+				fmt.Println("--method count requires --count")
+				os.Exit(1)
+			}
+			if len(counts) > 0 && methodValue != monitor.MethodCount {
+				fmt.Println("--count requires --method count")
+				os.Exit(1)
+			}
+			if methodValue == monitor.MethodKeyword && len(matches)+len(matchesAbsent) == 0 {
+				fmt.Println("--method keyword requires --match or --match-absent")
+				os.Exit(1)
+			}
+			if len(matches)+len(matchesAbsent) > 0 && methodValue != monitor.MethodKeyword {
+				fmt.Println("--match and --match-absent require --method keyword")
+				os.Exit(1)
+			}
+			for _, name := range []string{"extract", "below", "above", "delta", "delta-percent"} {
+				if cmd.Flags().Changed(name) && methodValue != monitor.MethodValue {
+					fmt.Printf("--%s requires --method value\n", name)
+					os.Exit(1)
+				}
+			}
+			if delta < 0 || deltaPercent < 0 {
+				fmt.Println("--delta and --delta-percent must not be negative")
+				os.Exit(1)
+			}
+			if maxBodySize < 0 || maxSnapshotSize < 0 {
+				fmt.Println("--max-body-size and --max-snapshot-size must not be negative")
+				os.Exit(1)
+			}
+			if (paginateItems != "" || maxPages != 0) && paginate == "" {
+				fmt.Println("--paginate-items and --max-pages require --paginate")
+				os.Exit(1)
+			}
+			if maxPages < 0 {
+				fmt.Println("--max-pages must not be negative")
+				os.Exit(1)
+			}
+			if appendOnly && methodValue != monitor.MethodHash && methodValue != monitor.MethodLength && methodValue != monitor.MethodTail {
+				fmt.Println("--append-only requires --method hash, length or tail")
+				os.Exit(1)
+			}
+			if len(representations) > 0 && methodValue != monitor.MethodHash && methodValue != monitor.MethodLength {
+				fmt.Println("--accept requires --method hash or length")
+				os.Exit(1)
+			}
+			if len(variants) > 0 && (methodValue != monitor.MethodHash && methodValue != monitor.MethodLength || len(representations) > 0) {
+				fmt.Println("--variant requires --method hash or length and can't be combined with --accept")
+				os.Exit(1)
+			}
+			if len(alerts) > 0 && (methodValue != monitor.MethodHash && methodValue != monitor.MethodLength || len(representations)+len(variants) > 0) {
+				fmt.Println("--alert requires --method hash or length and can't be combined with --accept or --variant")
+				os.Exit(1)
+			}
+			if len(expectStatus) > 0 && methodValue != monitor.MethodStatus {
+				fmt.Println("--expect-status requires --method status")
+				os.Exit(1)
+			}
+
+			// XPath expressions are kept with the CSS selectors, marked by
+			// their prefix
+			ignore = append(ignore, xpathSelectors(ignoreXPaths)...)
+			selects = append(selects, xpathSelectors(xpaths)...)
+			if _, err := monitor.NewSelectorFilter(selects, ignore); err != nil {
+				fmt.Printf("Invalid selector: %s\n", err)
+				os.Exit(1)
+			}
+			if _, err := monitor.NewSelectorFilter(alerts, nil); err != nil {
+				fmt.Printf("Invalid alert selector: %s\n", err)
+				os.Exit(1)
+			}
+
+			headerMap := parseHeaders(headers)
+			baggageMap, err := parseBaggage(baggage)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			labelMap, err := parseLabels(labels)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			cookieMap, err := parseCookies(cookies)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			variantList, err := parseVariants(variants)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if _, err := newChangeEncoder(io.Discard, format); err != nil {
+				fmt.Printf("Invalid --format: %s\n", err)
+				os.Exit(1)
+			}
+			// Changes in JSON or CSV may be piped to another program, so
+			// progress messages go to standard error unless changes are
+			// written to --output
+			var progress io.Writer = os.Stdout
+			if format != formatText && output == "" {
+				progress = os.Stderr
+			}
+			var changeTemplate *template.Template
+			if outputTemplate != "" {
+				if format != formatText {
+					fmt.Printf("Invalid --template: can't be combined with --format %s\n", format)
+					os.Exit(1)
+				}
+				if changeTemplate, err = parseOutputTemplate(outputTemplate); err != nil {
+					fmt.Printf("Invalid --template: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			// Text printed to a terminal is colored, with a line for every
+			// check
+			rich := format == formatText && changeTemplate == nil && output == "" && !noColor && colorEnabled(os.Stdout)
+			var execNotifier notify.Notifier
+			if execCommand != "" {
+				if execNotifier, err = newExecNotifier(); err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
+			var basicAuthValue *monitor.BasicAuth
+			if basicAuth != "" {
+				if basicAuthValue, err = monitor.ParseBasicAuth(basicAuth); err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
+			login, err := parseLogin(loginURL, loginData, loginToken)
+			if err != nil {
+				fmt.Printf("Invalid login: %s\n", err)
+				os.Exit(1)
+			}
+			oauth2, err := parseOAuth2(oauth2TokenURL, oauth2ClientID, oauth2ClientSecret, oauth2Scopes)
+			if err != nil {
+				fmt.Printf("Invalid OAuth2 settings: %s\n", err)
+				os.Exit(1)
+			}
+
+			// Settings from flags apply to every URL unless overridden per URL
+			defaults := &monitor.Config{
+				Interval:            intervalDuration,
+				Timeout:             timeoutDuration,
+				Headers:             headerMap,
+				Baggage:             baggageMap,
+				Labels:              labelMap,
+				IgnoreSelectors:     ignore,
+				WatchSelectors:      selects,
+				AlertSelectors:      alerts,
+				Method:              methodValue,
+				RetryCount:          retryCount,
+				RetryInterval:       retryIntervalDuration,
+				FailureThreshold:    failureThreshold,
+				ConfirmDelay:        confirmDelayDuration,
+				FollowRedirects:     true,
+				NormalizeWhitespace: normalizeWhitespace,
+				IgnoreTimestamps:    ignoreTimestamps,
+				ExpectedStatus:      expectStatus,
+				Representations:     representations,
+				Variants:            variantList,
+				Delta:               delta,
+				DeltaPercent:        deltaPercent,
+				DiffContextLines:    diffContext,
+				MaxDetailsLines:     maxDetailsLines,
+				MaxDetailsBytes:     maxDetailsBytes,
+				MaxBodySize:         maxBodySize,
+				MaxSnapshotSize:     maxSnapshotSize,
+				RespectRobotsTxt:    respectRobotsTxt,
+				NoCache:             noCache,
+				CacheBust:           cacheBust,
+				CaptureHAR:          captureHAR,
+				AppendOnly:          appendOnly,
+				Fetcher:             fetcherValue,
+				WaitSelector:        waitSelector,
+				RequestMethod:       requestMethodValue,
+				Body:                body,
+				KeepCookies:         keepCookies,
+				Cookies:             cookieMap,
+				BasicAuth:           basicAuthValue,
+				BearerToken:         bearerToken,
+				Login:               login,
+				OAuth2:              oauth2,
+			}
+
+			if jitter != "" {
+				if defaults.Jitter, err = time.ParseDuration(jitter); err != nil {
+					fmt.Printf("Invalid jitter: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if proxy != "" {
+				if defaults.ProxyURL, err = customhttp.ParseProxyURL(proxy); err != nil {
+					fmt.Printf("Invalid proxy: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if acceptEncoding != "" {
+				if err := customhttp.ValidateAcceptEncoding(acceptEncoding); err != nil {
+					fmt.Printf("Invalid accept encoding: %s\n", err)
+					os.Exit(1)
+				}
+				defaults.AcceptEncoding = acceptEncoding
+			}
+
+			defaults.TLS = customhttp.TLSOptions{
+				InsecureSkipVerify: insecure,
+				CAFile:             caFile,
+				CertFile:           clientCert,
+				KeyFile:            clientKey,
+			}
+			if minTLSVersion != "" {
+				if defaults.TLS.MinVersion, err = customhttp.ParseTLSVersion(minTLSVersion); err != nil {
+					fmt.Printf("Invalid minimum TLS version: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if _, err := defaults.TLS.Config(); err != nil {
+				fmt.Printf("Invalid TLS settings: %s\n", err)
+				os.Exit(1)
+			}
+
+			if cronSchedule != "" {
+				if defaults.Schedule, err = schedule.ParseCron(cronSchedule); err != nil {
+					fmt.Printf("Invalid schedule: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if checkAt != "" {
+				if defaults.At, err = schedule.ParseTime(checkAt, nil); err != nil {
+					fmt.Printf("Invalid check time: %s\n", err)
+					os.Exit(1)
+				}
+				if !defaults.At.After(time.Now()) {
+					fmt.Printf("Invalid check time: %s is in the past\n", checkAt)
+					os.Exit(1)
+				}
+			}
+
+			if until != "" {
+				if defaults.Until, err = monitor.ParseCondition(until); err != nil {
+					fmt.Printf("Invalid condition: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if defaults.Matches, err = monitor.ParseMatches(matches, matchesAbsent); err != nil {
+				fmt.Printf("Invalid match: %s\n", err)
+				os.Exit(1)
+			}
+			if defaults.Keywords, err = monitor.ParseKeywords(counts); err != nil {
+				fmt.Printf("Invalid count: %s\n", err)
+				os.Exit(1)
+			}
+			defaults.CSVKeys = csvKeys
+			if defaults.CSVDelimiter, err = monitor.ParseDelimiter(csvDelimiter); err != nil {
+				fmt.Printf("Invalid CSV delimiter: %s\n", err)
+				os.Exit(1)
+			}
+			if defaults.TailSeparator, err = monitor.ParseSeparator(tailSeparator); err != nil {
+				fmt.Printf("Invalid --tail-separator: %s\n", err)
+				os.Exit(1)
+			}
+			defaults.ImageThreshold = imageThreshold
+			defaults.ImageDiffDir = imageDiffDir
+			if paginate != "" {
+				if defaults.Pagination, err = monitor.ParsePagination(paginate); err != nil {
+					fmt.Printf("Invalid pagination: %s\n", err)
+					os.Exit(1)
+				}
+				defaults.Pagination.Items = paginateItems
+				defaults.Pagination.MaxPages = maxPages
+			}
+
+			if extract != "" {
+				if defaults.Extract, err = monitor.ParseExtractor(extract); err != nil {
+					fmt.Printf("Invalid extract: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if cmd.Flags().Changed("below") {
+				defaults.Thresholds = append(defaults.Thresholds, monitor.Threshold{Value: below})
+			}
+			if cmd.Flags().Changed("above") {
+				defaults.Thresholds = append(defaults.Thresholds, monitor.Threshold{Value: above, Above: true})
+			}
+
+			if deadline != "" {
+				if defaults.Deadline, err = schedule.ParseDeadline(deadline, time.Now(), nil); err != nil {
+					fmt.Printf("Invalid deadline: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if expires != "" {
+				if defaults.Expires, err = schedule.ParseDeadline(expires, time.Now(), nil); err != nil {
+					fmt.Printf("Invalid --expires: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if len(expect) > 0 {
+				if defaults.Expect, err = monitor.ParseExpectation(expect); err != nil {
+					fmt.Printf("Invalid --expect: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if maxChecks < 0 {
+				fmt.Println("Invalid --max-checks: must not be negative")
+				os.Exit(1)
+			}
+			defaults.MaxChecks = maxChecks
+
+			// Parse maintenance windows
+			for _, spec := range maintenanceWindows {
+				window, err := schedule.ParseWindow(spec, schedule.ModeSkip)
+				if err != nil {
+					fmt.Printf("Invalid maintenance window: %s\n", err)
+					os.Exit(1)
+				}
+				defaults.MaintenanceWindows = append(defaults.MaintenanceWindows, window)
+			}
+			for _, spec := range quietWindows {
+				window, err := schedule.ParseWindow(spec, schedule.ModeSilence)
+				if err != nil {
+					fmt.Printf("Invalid quiet window: %s\n", err)
+					os.Exit(1)
+				}
+				defaults.MaintenanceWindows = append(defaults.MaintenanceWindows, window)
+			}
+
+			// Collect per-URL settings from the config file and arguments
+			var entries []MonitorConfig
+			if configFile != "" {
+				entries, err = loadMonitorConfigs(configFile)
+				if err != nil {
+					fmt.Printf("Error loading config file: %s\n", err)
+					os.Exit(1)
 				}
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-				headerMap[key] = value
+			}
+
+			for _, arg := range args {
+				url, urlInterval := parseURLArg(arg)
+				entries = append(entries, MonitorConfig{URL: url, Interval: urlInterval})
 			}
 
 			// Create manager for handling multiple URLs
 			manager := monitor.NewManager()
+			manager.SetStagger(!noStagger)
+			manager.SetMaxConcurrentChecks(maxConcurrent)
+			if rich {
+				manager.SetOnCheck(func(change monitor.Change) {
+					if !change.HasChanged && change.Error == "" {
+						fmt.Print(richCheck(change))
+					}
+				})
+			}
+			setupBrowser(manager)
+			if _, err := setupArchive(manager); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			for _, value := range domainPolicies {
+				domain, policy, err := parseDomainPolicy(value)
+				if err != nil {
+					fmt.Printf("Error: %s\n", err)
+					os.Exit(1)
+				}
+				manager.SetDomainPolicy(domain, policy)
+			}
+			manager.SetRateLimit(rateLimit)
+			for _, value := range hostRateLimits {
+				host, limit, err := parseHostRateLimit(value)
+				if err != nil {
+					fmt.Printf("Error: %s\n", err)
+					os.Exit(1)
+				}
+				manager.SetHostRateLimit(host, limit)
+			}
 
 			// Create and add monitors for each URL
-			for _, url := range args {
-				config := &monitor.Config{
-					URL:                 url,
-					Interval:            intervalDuration,
-					Timeout:             timeoutDuration,
-					Headers:             headerMap,
-					IgnoreSelectors:     ignore,
-					Method:              monitor.MethodHash,
-					RetryCount:          retryCount,
-					RetryInterval:       retryIntervalDuration,
-					FollowRedirects:     true,
-					NormalizeWhitespace: normalizeWhitespace,
-					IgnoreTimestamps:    ignoreTimestamps,
-				}
-
-				_, err := manager.AddMonitorWithConfig(config)
+			var added []MonitorConfig
+			var configs []*monitor.Config
+			for _, entry := range entries {
+				cfg, err := entry.toMonitorConfig(defaults)
 				if err != nil {
-					fmt.Printf("Error setting up monitor for %s: %s\n", url, err)
+					fmt.Printf("Error setting up monitor for %s: %s\n", entry.URL, err)
 					continue
 				}
 
-				fmt.Printf("Monitoring %s every %s\n", url, interval)
-			}
+				// Saved monitors keep their expiry, after which they are
+				// no longer watched
+				if !cfg.Expires.IsZero() && !cfg.Expires.After(time.Now()) {
+					fmt.Fprintf(progress, "Monitor for %s expired at %s, skipping it\n", entry.URL, cfg.Expires.Format(time.RFC3339))
+					continue
+				}
+
+				// Nothing delivers payloads to webhook monitors without the
+				// API of 'hawkeye serve'
+				if monitor.IsWebhookURL(cfg.URL) {
+					fmt.Printf("Error setting up monitor for %s: webhook monitors receive payloads through 'hawkeye serve'\n", entry.URL)
+					continue
+				}
+
+				// Mailboxes are read as feeds of their messages unless
+				// another method was asked for
+				if imap.IsURL(cfg.URL) && entry.Method == "" && cfg.Method == monitor.MethodHash && !cmd.Flags().Changed("method") {
+					cfg.Method = monitor.MethodFeed
+				}
+
+				applyRecording(cfg)
 
-			// If a group is specified, create it
-			if group != "" {
-				_, err := manager.CreateGroup(group, "Created via CLI")
+				m, err := manager.AddMonitorWithConfig(cfg)
 				if err != nil {
-					fmt.Printf("Error creating group '%s': %s\n", group, err)
-				} else {
-					// Add all URLs to the group
-					for _, url := range args {
-						err := manager.AddToGroup(url, group)
-						if err != nil {
-							fmt.Printf("Error adding %s to group '%s': %s\n", url, group, err)
+					fmt.Printf("Error setting up monitor for %s: %s\n", entry.URL, err)
+					continue
+				}
+
+				configs = append(configs, cfg)
+
+				if entry.Paused {
+					m.Pause()
+					fmt.Fprintf(progress, "Monitor for %s is paused, resume it with 'hawkeye resume'\n", entry.URL)
+				}
+
+				// Record the effective settings for saving
+				entry.Interval = cfg.Interval.String()
+				if cfg.Schedule != nil {
+					entry.Schedule = cfg.Schedule.String()
+				}
+				entry.Headers = cfg.Headers
+				entry.Labels = cfg.Labels
+				if !cfg.Expires.IsZero() {
+					entry.Expires = cfg.Expires.Format(time.RFC3339)
+				}
+				if entry.Group == "" {
+					entry.Group = group
+				}
+				added = append(added, entry)
+
+				fmt.Fprintf(progress, "Monitoring %s %s\n", entry.URL, describeSchedule(cfg))
+			}
+
+			// Create groups and add URLs to them
+			for _, entry := range added {
+				if entry.Group == "" {
+					continue
+				}
+
+				if _, err := manager.GetGroup(entry.Group); err != nil {
+					if _, err := manager.CreateGroup(entry.Group, "Created via CLI"); err != nil {
+						fmt.Printf("Error creating group '%s': %s\n", entry.Group, err)
+						continue
+					}
+					fmt.Fprintf(progress, "Added URLs to group: %s\n", entry.Group)
+					if shareCookies {
+						if err := manager.ShareCookies(entry.Group, true); err != nil {
+							fmt.Printf("Error sharing cookies in group '%s': %s\n", entry.Group, err)
 						}
 					}
-					fmt.Printf("Added URLs to group: %s\n", group)
 				}
+
+				if err := manager.AddToGroup(entry.URL, entry.Group); err != nil {
+					fmt.Printf("Error adding %s to group '%s': %s\n", entry.URL, entry.Group, err)
+				}
+			}
+
+			// Save the monitor configurations to a file; monitors that
+			// finish are not watched again
+			if len(added) > 0 && !noSave && checkAt == "" && until == "" && deadline == "" && maxChecks == 0 {
+				if err := saveMonitors(added); err != nil {
+					fmt.Printf("Warning: Failed to save monitor configuration: %s\n", err)
+				}
+			}
+
+			// Add monitors for the URLs of sitemaps; they aren't saved as
+			// the sitemap decides which URLs are watched
+			watchers, sitemapConfigs, err := addSitemapMonitors(manager, defaults, sitemapHints && !cmd.Flags().Changed("interval"))
+			if err != nil {
+				fmt.Printf("Error reading sitemap: %s\n", err)
+				os.Exit(1)
 			}
+			configs = append(configs, sitemapConfigs...)
 
-			// Save the monitor configurations to a file
-			if err := saveMonitors(args, headerMap); err != nil {
-				fmt.Printf("Warning: Failed to save monitor configuration: %s\n", err)
+			// Add monitors for the pages found by crawling, which aren't
+			// saved either
+			crawlConfigs, err := addCrawlMonitors(manager, defaults)
+			if err != nil {
+				fmt.Printf("Error crawling: %s\n", err)
+				os.Exit(1)
+			}
+			configs = append(configs, crawlConfigs...)
+
+			// Add monitors declared in a definition file
+			var routes map[string]notify.NotifierList
+			var composites *monitor.Composites
+			if definition != nil {
+				routes, composites, err = addDefinitionMonitors(manager, definition)
+				if err != nil {
+					fmt.Printf("Error setting up monitors from %s: %s\n", strings.Join(definitionFiles, ", "), err)
+					os.Exit(1)
+				}
 			}
 
+			// Warn about risky settings before monitoring starts
+			printLintWarnings(progress, configs, definition)
+
 			// Start monitoring
 			changes := manager.Start()
-			fmt.Println("Monitoring started. Press Ctrl+C to stop.")
+			if composites != nil {
+				changes = composites.Watch(changes)
+			}
+			if err := startHeartbeat(context.Background(), manager); err != nil {
+				fmt.Printf("Error starting heartbeat: %s\n", err)
+				os.Exit(1)
+			}
+			startReadyFile(context.Background(), manager)
+			startSitemapRefresh(context.Background(), manager, watchers)
+			fmt.Fprintln(progress, "Monitoring started. Press Ctrl+C to stop.")
 
 			// Open output file if specified
 			var outputFile *os.File
@@ -137,21 +812,94 @@ Example:
 					os.Exit(1)
 				}
 				defer outputFile.Close()
-				fmt.Printf("Writing output to file: %s\n", output)
+				fmt.Fprintf(progress, "Writing output to file: %s\n", output)
+			}
+			var out io.Writer = os.Stdout
+			if outputFile != nil {
+				out = outputFile
+			}
+			encoder, _ := newChangeEncoder(out, format)
+
+			// Only the first error of a streak is sent to notifiers, and
+			// nothing is sent during quiet windows
+			onset := notify.NewErrorOnset()
+
+			// Exit once every monitor has finished, failing if a deadline
+			// passed, or at the first change or error that should fail.
+			// Notifications being sent are waited for.
+			pending := finiteMonitors(manager)
+			exitCode := exitOK
+			var notifying sync.WaitGroup
+			exit := func(code int) {
+				manager.Stop()
+				notifying.Wait()
+				flushDigests(routes)
+				os.Exit(code)
 			}
 
 			// Process changes
 			for change := range changes {
+				notifiers := routes[change.URL]
+				if execNotifier != nil {
+					notifiers = append(slices.Clip(notifiers), execNotifier)
+				}
+				if len(notifiers) > 0 && onset.Allow(change) && !change.Silenced && !change.Informational {
+					notifying.Add(1)
+					go func() {
+						defer notifying.Done()
+						sendNotifications(notifiers, change)
+					}()
+				}
+
+				switch change.Event {
+				case monitor.EventRecovery, monitor.EventPaused, monitor.EventResumed, monitor.EventBaselineReset,
+					monitor.EventCompleted, monitor.EventConditionMet, monitor.EventDeadlinePassed, monitor.EventDisallowed, monitor.EventExpired,
+					monitor.EventComposite:
+					var outputString string
+					if changeTemplate != nil {
+						outputString = templateOutput(changeTemplate, change)
+					} else if encoder != nil {
+						writeChange(encoder, change)
+					} else if rich {
+						outputString = richChange(change)
+					} else {
+						outputString = fmt.Sprintf("[%s] %s at %s\n", strings.ToUpper(string(change.Event)), change.URL, change.Timestamp.Format(time.RFC3339))
+						if change.Details != "" {
+							outputString += fmt.Sprintf("  Details: %s\n", change.Details)
+						}
+					}
+
+					if outputFile != nil {
+						outputFile.WriteString(outputString)
+					} else {
+						fmt.Print(outputString)
+					}
+
+					if change.Event == monitor.EventDeadlinePassed {
+						exitCode = exitFailure
+					}
+					if finished(change.Event) && pending > 0 {
+						pending--
+						if pending == 0 {
+							exit(exitCode)
+						}
+					}
+					continue
+				}
+
 				if change.Error != "" {
-					if format == "json" {
-						jsonOutput, _ := json.Marshal(change)
-						outputString := string(jsonOutput) + "\n"
+					if changeTemplate != nil {
+						outputString := templateOutput(changeTemplate, change)
 
 						if outputFile != nil {
 							outputFile.WriteString(outputString)
 						} else {
 							fmt.Print(outputString)
 						}
+					} else if encoder != nil {
+						writeChange(encoder, change)
+					} else if rich {
+						fmt.Print(richChange(change))
 					} else {
 						outputString := fmt.Sprintf("[ERROR] %s: %s\n", change.URL, change.Error)
 
@@ -161,21 +909,32 @@ Example:
 							fmt.Print(outputString)
 						}
 					}
+					if failOnError {
+						exit(exitCheckFailed)
+					}
 					continue
 				}
 
 				if change.HasChanged {
-					if format == "json" {
-						jsonOutput, _ := json.Marshal(change)
-						outputString := string(jsonOutput) + "\n"
+					if changeTemplate != nil {
+						outputString := templateOutput(changeTemplate, change)
 
 						if outputFile != nil {
 							outputFile.WriteString(outputString)
 						} else {
 							fmt.Print(outputString)
 						}
+					} else if encoder != nil {
+						writeChange(encoder, change)
+					} else if rich {
+						fmt.Print(richChange(change))
 					} else {
 						outputString := fmt.Sprintf("[CHANGED] %s at %s\n", change.URL, change.Timestamp.Format(time.RFC3339))
+						if change.Silenced {
+							outputString = fmt.Sprintf("[CHANGED] %s at %s (silenced)\n", change.URL, change.Timestamp.Format(time.RFC3339))
+						} else if change.Informational {
+							outputString = fmt.Sprintf("[INFO] %s at %s (outside of --alert)\n", change.URL, change.Timestamp.Format(time.RFC3339))
+						}
 
 						if outputFile != nil {
 							outputFile.WriteString(outputString)
@@ -212,6 +971,22 @@ Example:
 								fmt.Print(codeString)
 							}
 						}
+
+						if change.Latency > 0 {
+							latencyString := fmt.Sprintf("  Latency: %s\n", change.Latency.Round(time.Millisecond))
+
+							if outputFile != nil {
+								outputFile.WriteString(latencyString)
+							} else {
+								fmt.Print(latencyString)
+							}
+						}
+					}
+
+					// Changes in quiet windows and outside of the alert
+					// selectors are expected
+					if failOnChange && !change.Silenced && !change.Informational {
+						exit(exitChanged)
 					}
 				}
 			}
@@ -221,20 +996,169 @@ Example:
 
 func init() {
 	watchCmd.Flags().StringVarP(&interval, "interval", "i", "5m", "Check interval (e.g., 5m, 1h)")
+	watchCmd.Flags().StringVar(&cronSchedule, "schedule", "", "Cron expression for when to check, instead of --interval (e.g., '*/10 9-17 * * mon-fri')")
+	watchCmd.Flags().StringVar(&checkAt, "at", "", "Check once at this time instead of repeatedly, then exit (e.g., 2024-07-01T09:00)")
+	watchCmd.Flags().StringVar(&until, "until", "", "Watch until a condition is met, then exit (e.g., 'registration open', 'regex:in stock', 'json:open=true')")
+	watchCmd.Flags().StringVar(&deadline, "deadline", "", "Give up at this time or after this duration, exiting with status 1 (e.g., 48h)")
+	watchCmd.Flags().StringVar(&expires, "expires", "", "Stop watching at this time or after this duration with a summary, also when saved (e.g., 2w)")
+	watchCmd.Flags().StringArrayVar(&expect, "expect", nil, "Only watch a URL if its first fetch has this text or condition, or status:CODES (repeatable)")
+	watchCmd.Flags().IntVar(&maxChecks, "max-checks", 0, "Check each URL this many times, then exit")
+	addExitFlags(watchCmd)
+	watchCmd.Flags().StringVar(&jitter, "jitter", "", "Delay each check by a random duration up to this (e.g., 10s)")
+	watchCmd.Flags().BoolVar(&noStagger, "no-stagger", false, "Check every URL immediately instead of spreading first checks across the interval")
+	watchCmd.Flags().IntVar(&maxConcurrent, "max-concurrent", 0, "Maximum number of URLs fetched at the same time (0 for no limit)")
+	watchCmd.Flags().StringArrayVar(&domainPolicies, "domain-policy", []string{}, "Limits shared by all URLs on a domain (e.g., example.com:min-interval=1m,max-parallel=1,delay=2s)")
+	watchCmd.Flags().IntVar(&rateLimit, "rate-limit", 0, "Maximum requests per minute to any one host (0 for no limit)")
+	watchCmd.Flags().StringArrayVar(&hostRateLimits, "host-rate-limit", []string{}, "Maximum requests per minute to a host, overriding --rate-limit (e.g., api.example.com=10)")
+	watchCmd.Flags().StringVar(&proxy, "proxy", "", "Proxy for all requests (e.g., http://proxy:3128, socks5://localhost:1080)")
+	watchCmd.Flags().BoolVar(&noCache, "no-cache", false, "Send Cache-Control: no-cache and Pragma: no-cache so caches and CDNs revalidate with the origin")
+	watchCmd.Flags().BoolVar(&captureHAR, "capture-har", false, "Attach an HTTP archive (HAR) of the requests of each change to its archived version, implies --archive")
+	watchCmd.Flags().StringVar(&cacheBust, "cache-bust", "", "Add this query parameter with a random value to every request, for caches that ignore --no-cache (e.g., _cb)")
+	watchCmd.Flags().StringVar(&acceptEncoding, "accept-encoding", "", "Accept-Encoding sent with requests, e.g. identity for uncompressed responses (default: gzip, deflate, br)")
+	watchCmd.Flags().BoolVar(&insecure, "insecure", false, "Accept any TLS certificate, e.g. self-signed ones (prefer --ca-file)")
+	watchCmd.Flags().StringVar(&caFile, "ca-file", "", "PEM bundle of additional certificate authorities to trust")
+	watchCmd.Flags().StringVar(&minTLSVersion, "min-tls", "", "Minimum TLS version (1.0, 1.1, 1.2 or 1.3)")
+	watchCmd.Flags().StringVar(&clientCert, "cert", "", "PEM client certificate for mutual TLS (requires --key)")
+	watchCmd.Flags().StringVar(&clientKey, "key", "", "PEM private key of the client certificate")
 	watchCmd.Flags().StringVarP(&timeout, "timeout", "t", "30s", "Request timeout")
-	watchCmd.Flags().StringVarP(&format, "format", "f", "text", "Output format (text/json)")
+	watchCmd.Flags().StringVarP(&format, "format", "f", "text", "Output format (text/json/ndjson/csv)")
+	watchCmd.Flags().BoolVar(&noColor, "no-color", false, "Print plain text in a terminal, without colors or a line for every check")
+	watchCmd.Flags().StringVar(&outputTemplate, "template", "", "Go template printed for each change instead of the text output (e.g., '{{.Timestamp.Format \"15:04\"}} {{.URL}} {{.Details}}')")
 	watchCmd.Flags().StringArrayVarP(&headers, "header", "H", []string{}, "Custom HTTP headers (key:value)")
+	watchCmd.Flags().StringArrayVar(&labels, "label", []string{}, "Label to select the monitor by, e.g. with 'hawkeye pause -l' (key=value, e.g., env=staging)")
+	watchCmd.Flags().StringArrayVar(&baggage, "baggage", []string{}, "Value sent in the Baggage header of every request and included in changes (key=value, e.g., tenant=acme)")
 	watchCmd.Flags().StringArrayVarP(&ignore, "ignore", "I", []string{}, "CSS selectors to ignore")
+	watchCmd.Flags().StringArrayVar(&ignoreXPaths, "ignore-xpath", []string{}, "XPath expressions of parts to ignore (e.g., '//div[@class=\"ad\"]')")
+	watchCmd.Flags().StringArrayVar(&selects, "select", []string{}, "CSS selectors of the parts to watch, ignoring the rest of the page")
+	watchCmd.Flags().StringArrayVar(&alerts, "alert", []string{}, "CSS selectors or xpath: expressions of the parts whose changes are notified; other changes are only reported as info")
+	watchCmd.Flags().StringArrayVar(&xpaths, "xpath", []string{}, "XPath expressions of the parts to watch, ignoring the rest (e.g., '//item/title')")
 	watchCmd.Flags().StringVarP(&output, "output", "o", "", "Output file")
 	watchCmd.Flags().StringVarP(&group, "group", "g", "", "Group name for URLs")
 	watchCmd.Flags().IntVarP(&retryCount, "retries", "r", 3, "Number of retry attempts")
 	watchCmd.Flags().StringVarP(&retryInterval, "retry-interval", "R", "10s", "Time between retries")
+	watchCmd.Flags().IntVar(&failureThreshold, "failure-threshold", 0, "Number of checks in a row that must fail before an error is reported (default: every error)")
+	watchCmd.Flags().StringVar(&confirmDelay, "confirm-delay", "", "Confirm changes by fetching again after this delay, reporting them only if they persist (e.g., 30s)")
 	watchCmd.Flags().BoolVarP(&normalizeWhitespace, "normalize", "n", false, "Normalize whitespace to ignore insignificant changes")
 	watchCmd.Flags().BoolVarP(&ignoreTimestamps, "ignore-timestamps", "T", false, "Ignore timestamps when comparing content")
+	watchCmd.Flags().StringVarP(&method, "method", "m", "hash", "Change detection method (hash/length/status/keyword/value/feed/count/csv/image/tail)")
+	watchCmd.Flags().IntSliceVar(&expectStatus, "expect-status", []int{}, "Status codes that count as up with --method status (e.g., 200,204)")
+	watchCmd.Flags().StringArrayVar(&matches, "match", []string{}, "Report when a text or pattern appears, implies --method keyword (e.g., 'in stock', 'regex:[0-9]+ left')")
+	watchCmd.Flags().StringArrayVar(&matchesAbsent, "match-absent", []string{}, "Report when a text or pattern disappears, implies --method keyword (e.g., 'out of stock')")
+	watchCmd.Flags().StringArrayVar(&counts, "count", []string{}, "Report when the number of occurrences of a text or pattern changes, implies --method count (e.g., 'Go developer')")
+	watchCmd.Flags().StringArrayVar(&csvKeys, "csv-key", []string{}, "Column identifying a row of a CSV or TSV response, implies --method csv (default: the first column)")
+	watchCmd.Flags().Float64Var(&imageThreshold, "image-threshold", 0, "Percentage of pixels that must differ to report a change of an image, implies --method image")
+	watchCmd.Flags().StringVar(&imageDiffDir, "image-diff-dir", "", "Directory to write a PNG of each image change into, with the changed pixels in red, implies --method image")
+	watchCmd.Flags().StringVar(&csvDelimiter, "csv-delimiter", "", "Column delimiter with --method csv: comma, tab, semicolon, pipe or a character (default: detected)")
+	watchCmd.Flags().StringVar(&tailSeparator, "tail-separator", "", "Separator of the segments reported with --method tail: line, paragraph or a text, implies --method tail (default: line)")
+	watchCmd.Flags().StringVar(&extract, "extract", "", "Watch a number found with css:, regex: or json:, implies --method value (e.g., 'css:.price')")
+	watchCmd.Flags().Float64Var(&below, "below", 0, "Report when the extracted value drops below this")
+	watchCmd.Flags().Float64Var(&above, "above", 0, "Report when the extracted value rises above this")
+	watchCmd.Flags().Float64Var(&delta, "delta", 0, "Only report when the extracted value changes by more than this")
+	watchCmd.Flags().Float64Var(&deltaPercent, "delta-percent", 0, "Only report when the extracted value changes by more than this percentage")
+	watchCmd.Flags().StringVar(&paginate, "paginate", "", "Follow API pages and compare their combined items: link, json:PATH (next URL) or cursor:PATH=PARAM")
+	watchCmd.Flags().StringVar(&paginateItems, "paginate-items", "", "JSON path of the items of each page with --paginate (default: the page is an array)")
+	watchCmd.Flags().IntVar(&maxPages, "max-pages", 0, "Fail checks of APIs with more pages with --paginate (default: 100)")
+	watchCmd.Flags().BoolVar(&appendOnly, "append-only", false, "Fetch only what was appended to logs and exports with Range requests, and report the new lines")
+	watchCmd.Flags().StringArrayVar(&representations, "accept", []string{}, "Request each URL as this content type and compare it separately, catching diverging representations (repeatable, e.g., text/html)")
+	watchCmd.Flags().StringArrayVar(&variants, "variant", []string{}, "Request each URL with this header as a named variant and compare it separately (repeatable, name=key:value, e.g., de=Accept-Language:de)")
+	watchCmd.Flags().StringVar(&execCommand, "exec", "", "Command to run for each change, with templated arguments (e.g., 'notify-send {{.URL}}') and the change as JSON on stdin")
+	watchCmd.Flags().DurationVar(&execTimeout, "exec-timeout", notify.DefaultExecTimeout, "Kill --exec commands that run longer than this")
+	watchCmd.Flags().DurationVar(&execThrottle, "exec-throttle", 0, "Run --exec at most once in this time for each URL")
+	watchCmd.Flags().StringSliceVar(&execEvents, "exec-events", []string{string(monitor.EventChange)}, "Events that run --exec (e.g., change,error,recovery)")
+	watchCmd.Flags().StringVarP(&configFile, "config-file", "c", "", "JSON file with per-URL monitor settings")
+	addDefinitionFlags(watchCmd)
+	watchCmd.Flags().IntVar(&diffContext, "diff-context", monitor.DefaultDiffContextLines, "Unchanged lines shown around each change in details")
+	watchCmd.Flags().IntVar(&maxDetailsLines, "max-details-lines", monitor.DefaultMaxDetailsLines, "Maximum lines of change details (0 for no limit)")
+	watchCmd.Flags().IntVar(&maxDetailsBytes, "max-details-bytes", monitor.DefaultMaxDetailsBytes, "Maximum bytes of change details (0 for no limit)")
+	watchCmd.Flags().Int64Var(&maxBodySize, "max-body-size", monitor.DefaultMaxBodySize, "Fail checks whose response body is larger than this many bytes (0 for no limit)")
+	watchCmd.Flags().Int64Var(&maxSnapshotSize, "max-snapshot-size", 0, "Keep only this many bytes of each response for diffs; the rest is only hashed (0 keeps everything)")
+	watchCmd.Flags().StringArrayVar(&maintenanceWindows, "maintenance", []string{}, "Window during which checks are skipped (e.g., 'Sat 02:00-04:00')")
+	watchCmd.Flags().StringArrayVar(&quietWindows, "quiet", []string{}, "Window during which changes are recorded but not notified")
+	watchCmd.Flags().StringVar(&fetcher, "fetcher", "http", "How pages are loaded: http, or browser to render JavaScript in headless Chrome")
+	watchCmd.Flags().StringVarP(&requestMethod, "request-method", "X", "", "HTTP method of requests, e.g. POST (default GET, or POST with --data)")
+	watchCmd.Flags().StringVarP(&requestData, "data", "d", "", "Request body sent with every check, or @file to read it from a file")
+	watchCmd.Flags().BoolVar(&keepCookies, "keep-cookies", false, "Keep cookies set by sites between checks, e.g. session or consent cookies")
+	watchCmd.Flags().StringArrayVar(&cookies, "cookie", []string{}, "Cookie sent from the first check, as name=value; implies --keep-cookies (repeatable)")
+	watchCmd.Flags().StringVar(&basicAuth, "basic-auth", "", "Basic auth credentials as user:pass; prefer HAWKEYE_BASIC_AUTH, which keeps them out of process listings")
+	watchCmd.Flags().StringVar(&bearerToken, "bearer-token", "", "Token sent as 'Authorization: Bearer <token>'; prefer HAWKEYE_BEARER_TOKEN, which keeps it out of process listings")
+	watchCmd.Flags().StringVar(&loginURL, "login", "", "URL POSTed --login-data before the first check and when a check gets 401 Unauthorized")
+	watchCmd.Flags().StringVar(&loginData, "login-data", "", "Credentials sent to --login as a form or JSON, or @file to read them from a file")
+	watchCmd.Flags().StringVar(&loginToken, "login-token", "", "Path of a bearer token in the JSON login response (e.g., access_token); without it the session is kept in cookies")
+	watchCmd.Flags().StringVar(&oauth2TokenURL, "oauth2-token-url", "", "OAuth2 token URL; requests carry a client credentials token, cached until it expires")
+	watchCmd.Flags().StringVar(&oauth2ClientID, "oauth2-client-id", "", "OAuth2 client ID (requires --oauth2-token-url)")
+	watchCmd.Flags().StringVar(&oauth2ClientSecret, "oauth2-client-secret", "", "OAuth2 client secret, or @file to read it from a file")
+	watchCmd.Flags().StringArrayVar(&oauth2Scopes, "oauth2-scope", []string{}, "OAuth2 scope requested with the token (repeatable)")
+	watchCmd.Flags().BoolVar(&shareCookies, "share-cookies", false, "Share one cookie jar among the URLs of each group")
+	watchCmd.Flags().StringVar(&waitSelector, "wait-selector", "", "CSS selector the browser waits for before comparing, instead of the network going idle (requires --fetcher browser)")
+	addBrowserFlags(watchCmd)
+	watchCmd.Flags().BoolVar(&respectRobotsTxt, "respect-robots-txt", false, "Skip URLs that the robots.txt of their site disallows, checked daily")
+	watchCmd.Flags().BoolVar(&noSave, "no-save", false, "Don't save the watched URLs, e.g. on a read-only file system")
+	addHeartbeatFlags(watchCmd)
+	addSummarizerFlags(watchCmd)
+	addReadyFlags(watchCmd)
+	addSitemapFlags(watchCmd)
+	addCrawlFlags(watchCmd)
+	addArchiveFlags(watchCmd)
+	watchCmd.Flags().StringVar(&recordDir, "record", "", "Record HTTP sessions of every monitor to cassettes in this directory")
+	watchCmd.Flags().BoolVar(&recordBodies, "record-bodies", false, "Record response bodies too, which replaying cassettes needs")
+}
+
+// finiteMonitors returns the number of monitors of the manager if all of
+// them finish at some point: one-time checks and monitors with a condition,
+// a deadline or an expiry. It returns zero otherwise.
+func finiteMonitors(manager *monitor.Manager) int {
+	urls := manager.ListMonitors()
+	for _, url := range urls {
+		m, err := manager.GetMonitor(url)
+		if err != nil {
+			return 0
+		}
+		config := m.GetConfig()
+		if config.At.IsZero() && config.Until == nil && config.Deadline.IsZero() && config.Expires.IsZero() && config.MaxChecks == 0 {
+			return 0
+		}
+	}
+	return len(urls)
+}
+
+// finished reports whether an event is the last one of a monitor
+func finished(event monitor.EventType) bool {
+	return event == monitor.EventCompleted || event == monitor.EventConditionMet || event == monitor.EventDeadlinePassed || event == monitor.EventExpired
+}
+
+// applyRecording wraps the monitor's transport in a recorder when --record is
+// set. Headers set from secret placeholders are redacted in the cassette, and
+// response bodies are only recorded with --record-bodies.
+func applyRecording(cfg *monitor.Config) {
+	if recordDir == "" {
+		return
+	}
+
+	headers := []map[string]string{cfg.Headers}
+	if cfg.Login != nil {
+		headers = append(headers, cfg.Login.Headers)
+	}
+	var secrets []string
+	for _, header := range headers {
+		for name, value := range header {
+			if secret.Contains(value) {
+				secrets = append(secrets, name)
+			}
+		}
+	}
+	rec := recorder.NewRecorder(recorder.CassettePath(recordDir, cfg.URL), cfg.Transport).
+		WithRedacted(secrets...).
+		WithOnError(func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		})
+	if recordBodies {
+		rec.WithBodies()
+	}
+	cfg.Transport = rec
 }
 
 // saveMonitors saves the monitor configurations to a file
-func saveMonitors(urls []string, headers map[string]string) error {
+func saveMonitors(entries []MonitorConfig) error {
 	configDir, err := getConfigDir()
 	if err != nil {
 		return err
@@ -264,17 +1188,31 @@ func saveMonitors(urls []string, headers map[string]string) error {
 	}
 
 	// Add or update monitors
-	for _, url := range urls {
-		monitors[url] = MonitorConfig{
-			URL:                 url,
-			Interval:            interval,
-			Group:               group,
-			Headers:             headers,
-			Ignore:              ignore,
-			CreatedAt:           time.Now().Format(time.RFC3339),
-			NormalizeWhitespace: normalizeWhitespace,
-			IgnoreTimestamps:    ignoreTimestamps,
+	var audited []audit.Entry
+	for _, entry := range entries {
+		entry.CreatedAt = time.Now().Format(time.RFC3339)
+		if len(entry.Ignore) == 0 {
+			entry.Ignore = ignore
+		}
+		if len(entry.Select) == 0 {
+			entry.Select = selects
+		}
+		if len(entry.Alert) == 0 {
+			entry.Alert = alerts
+		}
+		entry.NormalizeWhitespace = entry.NormalizeWhitespace || normalizeWhitespace
+		entry.IgnoreTimestamps = entry.IgnoreTimestamps || ignoreTimestamps
+
+		// Only new monitors and changed settings are audited
+		previous, exists := monitors[entry.URL]
+		previous.CreatedAt = entry.CreatedAt
+		switch {
+		case !exists:
+			audited = append(audited, audit.Entry{Action: audit.ActionAdd, Target: entry.URL})
+		case !reflect.DeepEqual(previous, entry):
+			audited = append(audited, audit.Entry{Action: audit.ActionConfig, Target: entry.URL})
 		}
+		monitors[entry.URL] = entry
 	}
 
 	// Save to file
@@ -282,6 +1220,31 @@ func saveMonitors(urls []string, headers map[string]string) error {
 	if err != nil {
 		return err
 	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		return err
+	}
+
+	for _, entry := range audited {
+		recordAudit(entry.Action, entry.Target, "")
+	}
+	return nil
+}
 
-	return os.WriteFile(configFile, data, 0644)
+// newExecNotifier creates the notifier of the --exec flags, whose commands
+// write to the standard output of hawkeye
+func newExecNotifier() (notify.Notifier, error) {
+	var events []monitor.EventType
+	for _, name := range execEvents {
+		event, err := monitor.ParseEventType(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exec-events: %w", err)
+		}
+		events = append(events, event)
+	}
+	notifier, err := notify.NewExecNotifier("exec", execCommand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --exec: %w", err)
+	}
+	notifier.WithTimeout(execTimeout).WithThrottle(execThrottle).WithOutput(os.Stdout)
+	return notify.FilterEvents(notifier, events...), nil
 }
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nemuizzz/hawkeye/pkg/zabbix"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for zabbix command
+	zabbixGroup  string
+	zabbixServer string
+	zabbixAPIKey string
+
+	// zabbixCmd represents the zabbix command
+	zabbixCmd = &cobra.Command{
+		Use:   "zabbix <discovery|values>",
+		Short: "Export the monitors of a running server to Zabbix",
+		Long: `Print the monitors of a running 'hawkeye serve' as JSON for Zabbix.
+
+'discovery' prints the result of a low-level discovery rule, one entry per
+monitor with the macros {#URL}, {#HOST}, {#METHOD} and {#GROUPS}.
+
+'values' prints the latest values of every monitor by URL, for a master item
+whose dependent items pick a value with JSONPath, such as
+$.monitors['{#URL}'].latency_ms. The state_code of a monitor is 0 ok,
+1 failing, 2 stalled, 3 paused and 4 pending.
+Example:
+  hawkeye zabbix discovery --server http://hawkeye:8080
+  hawkeye zabbix values --group news
+
+  # zabbix_agentd.conf
+  UserParameter=hawkeye.discovery,/usr/bin/hawkeye zabbix discovery
+  UserParameter=hawkeye.values,/usr/bin/hawkeye zabbix values
+
+A server started with --api-keys needs a key with the read role, given with
+--api-key.`,
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"discovery", "values"},
+		Run: func(cmd *cobra.Command, args []string) {
+			report := fetchStatus(apiBase(zabbixServer), zabbixAPIKey, zabbixGroup)
+			// Without the monitors, a discovery would remove their items
+			if report.Status == statusUnknown {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", report.Error)
+				os.Exit(1)
+			}
+
+			var output any
+			if args[0] == "discovery" {
+				output = zabbixDiscovery(report)
+			} else {
+				output = newZabbixValues(report)
+			}
+			jsonOutput, _ := json.Marshal(output)
+			fmt.Println(string(jsonOutput))
+		},
+	}
+)
+
+func init() {
+	zabbixCmd.Flags().StringVarP(&zabbixGroup, "group", "g", "", "Only export the monitors of this group")
+	zabbixCmd.Flags().StringVarP(&zabbixServer, "server", "s", "http://localhost:8080", "Address of a running 'hawkeye serve' API")
+	zabbixCmd.Flags().StringVar(&zabbixAPIKey, "api-key", "", "API key for a server that requires one")
+}
+
+// zabbixStateCodes are the numeric states of monitors in Zabbix values, for
+// triggers and value maps
+var zabbixStateCodes = map[string]int{
+	monitorOK:      0,
+	monitorFailing: 1,
+	monitorStalled: 2,
+	monitorPaused:  3,
+	monitorPending: 4,
+}
+
+// zabbixValues is the document printed by 'hawkeye zabbix values'
+type zabbixValues struct {
+	Status   string                        `json:"status"`
+	Error    string                        `json:"error"`
+	Monitors map[string]zabbixMonitorValue `json:"monitors"`
+}
+
+// zabbixMonitorValue holds the latest values of a monitor. Times are Unix
+// timestamps, 0 if the monitor wasn't checked yet.
+type zabbixMonitorValue struct {
+	State      string `json:"state"`
+	StateCode  int    `json:"state_code"`
+	LastCheck  int64  `json:"last_check"`
+	NextCheck  int64  `json:"next_check"`
+	CheckCount int64  `json:"check_count"`
+	LatencyMS  *int64 `json:"latency_ms"`
+	Error      string `json:"error"`
+	Failures   int64  `json:"failures"`
+}
+
+// zabbixDiscovery returns the low-level discovery of the monitors of a
+// status document
+func zabbixDiscovery(report *statusReport) zabbix.Discovery {
+	var discovery zabbix.Discovery
+	for _, status := range report.Monitors {
+		host := status.URL
+		if u, err := url.Parse(status.URL); err == nil && u.Host != "" {
+			host = u.Hostname()
+		}
+		discovery.Add(map[string]string{
+			"url":    status.URL,
+			"host":   host,
+			"method": status.Method,
+			"groups": strings.Join(status.Groups, ","),
+		})
+	}
+	return discovery
+}
+
+// newZabbixValues returns the latest values of the monitors of a status
+// document by URL
+func newZabbixValues(report *statusReport) *zabbixValues {
+	values := &zabbixValues{
+		Status:   report.Status,
+		Error:    report.Error,
+		Monitors: make(map[string]zabbixMonitorValue, len(report.Monitors)),
+	}
+	for _, status := range report.Monitors {
+		value := zabbixMonitorValue{
+			State:      status.State,
+			StateCode:  zabbixStateCodes[status.State],
+			CheckCount: status.CheckCount,
+			LatencyMS:  status.LatencyMS,
+			Error:      status.Error,
+			Failures:   status.Failures,
+		}
+		if status.LastCheck != nil {
+			value.LastCheck = status.LastCheck.Unix()
+		}
+		if status.NextCheck != nil {
+			value.NextCheck = status.NextCheck.Unix()
+		}
+		values.Monitors[status.URL] = value
+	}
+	return values
+}
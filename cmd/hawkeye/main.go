@@ -15,7 +15,7 @@ func main() {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		fmt.Println("\nShutting down...")
+		fmt.Fprintln(os.Stderr, "\nShutting down...")
 		os.Exit(0)
 	}()
 
@@ -5,73 +5,143 @@ package hawkeye
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
 )
 
-// Monitor watches a URL for changes
+// ErrStarted is reported by Monitor.Err when a monitor was configured after
+// it was started
+var ErrStarted = errors.New("monitor settings cannot change after Start")
+
+// Monitor watches a URL for changes. Its methods are safe to call from
+// multiple goroutines. The With* settings must be applied before Start;
+// later calls leave the running monitor unchanged and are reported by Err.
 type Monitor struct {
+	mu       sync.Mutex
 	internal *monitor.Monitor
+	changes  <-chan Change
+	err      error
 	ctx      context.Context
 	cancel   context.CancelFunc
 	url      string
 	interval time.Duration
 	headers  map[string]string
+	baggage  map[string]string
+	labels   map[string]string
 	ignore   []string
 	timeout  time.Duration
 	retries  int
 	retryInt time.Duration
+	policy   monitor.RetryPolicy
+	failures int
+	confirm  time.Duration
+	maxBody  int64
+	schedule *schedule.Cron
+	onError  func(error)
+	// transport and clock are test hooks, nil in production
+	transport http.RoundTripper
+	clock     monitor.Clock
 }
 
-// Change represents a detected change in a monitored URL
+// Change represents a detected change in a monitored URL, or another event
+// such as an error or a recovery as given by Event
 type Change struct {
-	URL         string    `json:"url"`
-	Timestamp   time.Time `json:"timestamp"`
-	HasChanged  bool      `json:"has_changed"`
-	StatusCode  int       `json:"status_code,omitempty"`
-	ContentType string    `json:"content_type,omitempty"`
-	Error       string    `json:"error,omitempty"`
-	Details     string    `json:"details,omitempty"`
+	URL         string            `json:"url"`
+	Event       monitor.EventType `json:"event_type"`
+	Timestamp   time.Time         `json:"timestamp"`
+	HasChanged  bool              `json:"has_changed"`
+	StatusCode  int               `json:"status_code,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Details     string            `json:"details,omitempty"`
+	// Baggage holds the values set with WithBaggage or ContextWithBaggage
+	Baggage map[string]string `json:"baggage,omitempty"`
+	// Hunks is the complete line diff of the change
+	Hunks []monitor.DiffHunk `json:"hunks,omitempty"`
+	// Diff is Hunks formatted as a unified diff
+	Diff string `json:"-"`
+	// Latency is the time until the response headers were received
+	Latency time.Duration `json:"latency,omitempty"`
+	// OldHash and NewHash are the SHA-256 hashes of the compared content
+	// before and after the change, and OldLength and NewLength its sizes
+	OldHash   string `json:"old_hash,omitempty"`
+	NewHash   string `json:"new_hash,omitempty"`
+	OldLength int64  `json:"old_length,omitempty"`
+	NewLength int64  `json:"new_length,omitempty"`
+	// FilteredBy describes the filters that removed parts of the content
+	// before it was compared
+	FilteredBy []string `json:"filtered_by,omitempty"`
+}
+
+// newChange converts from the internal Change type to the public API Change
+// type
+func newChange(change monitor.Change) Change {
+	return Change{
+		URL:         change.URL,
+		Event:       change.Event,
+		Timestamp:   change.Timestamp,
+		HasChanged:  change.HasChanged,
+		StatusCode:  change.StatusCode,
+		ContentType: change.ContentType,
+		Error:       change.Error,
+		Details:     change.Details,
+		Baggage:     change.Baggage,
+		Hunks:       change.Hunks,
+		Diff:        change.Diff,
+		Latency:     change.Latency,
+		OldHash:     change.OldHash,
+		NewHash:     change.NewHash,
+		OldLength:   change.OldLength,
+		NewLength:   change.NewLength,
+		FilteredBy:  change.FilteredBy,
+	}
 }
 
 // NewMonitor creates a new monitor with the specified URL and check interval
 func NewMonitor(url string, interval time.Duration) *Monitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	config := &monitor.Config{
-		URL:             url,
-		Interval:        interval,
-		Timeout:         time.Second * 30, // default timeout
-		Headers:         make(map[string]string),
-		IgnoreSelectors: []string{},
-		Method:          monitor.MethodHash,
-		RetryCount:      3,                // default retry count
-		RetryInterval:   time.Second * 10, // default retry interval
-		FollowRedirects: true,
-	}
-
 	return &Monitor{
-		internal: monitor.NewMonitorWithConfig(config),
 		ctx:      ctx,
 		cancel:   cancel,
 		url:      url,
 		interval: interval,
 		headers:  make(map[string]string),
 		ignore:   []string{},
-		timeout:  time.Second * 30,
-		retries:  3,
-		retryInt: time.Second * 10,
+		timeout:  time.Second * 30, // default timeout
+		retries:  3,                // default retry count
+		retryInt: time.Second * 10, // default retry interval
+		maxBody:  monitor.DefaultMaxBodySize,
 	}
 }
 
-// Start begins monitoring the URL for changes
+// Start begins monitoring the URL for changes. Calling it again returns the
+// same channel. The channel is closed once the monitor is stopped.
 func (m *Monitor) Start() <-chan Change {
-	internalChanges := m.internal.Start()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.changes != nil {
+		return m.changes
+	}
+
+	m.internal = monitor.NewMonitorWithConfig(m.config())
+	internal, ctx := m.internal, m.ctx
+	internalChanges := internal.Start()
 	changes := make(chan Change)
+	m.changes = changes
 
 	go func() {
 		defer close(changes)
+		// Stopping the internal monitor on every exit path ends its
+		// goroutines, also when only the context was canceled
+		defer internal.Stop()
 		for {
 			select {
 			case change, ok := <-internalChanges:
@@ -79,17 +149,12 @@ func (m *Monitor) Start() <-chan Change {
 					return
 				}
 
-				// Convert from internal Change type to public API Change type
-				changes <- Change{
-					URL:         change.URL,
-					Timestamp:   change.Timestamp,
-					HasChanged:  change.HasChanged,
-					StatusCode:  change.StatusCode,
-					ContentType: change.ContentType,
-					Error:       change.Error,
-					Details:     change.Details,
+				select {
+				case changes <- newChange(change):
+				case <-ctx.Done():
+					return
 				}
-			case <-m.ctx.Done():
+			case <-ctx.Done():
 				return
 			}
 		}
@@ -98,78 +163,202 @@ func (m *Monitor) Start() <-chan Change {
 	return changes
 }
 
-// Stop stops the monitoring
+// Stop stops the monitoring. It is safe to call more than once, and before
+// Start, in which case the monitor never starts checking.
 func (m *Monitor) Stop() {
-	m.cancel()
-	m.internal.Stop()
-}
-
-// recreateMonitor recreates the internal monitor with current settings
-func (m *Monitor) recreateMonitor() {
-	config := &monitor.Config{
-		URL:             m.url,
-		Interval:        m.interval,
-		Timeout:         m.timeout,
-		Headers:         m.headers,
-		IgnoreSelectors: m.ignore,
-		Method:          monitor.MethodHash,
-		RetryCount:      m.retries,
-		RetryInterval:   m.retryInt,
-		FollowRedirects: true,
-	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Stop the existing monitor if it's running
+	m.cancel()
 	if m.internal != nil {
 		m.internal.Stop()
 	}
+}
+
+// Err returns ErrStarted if a With* setting was applied after Start and
+// therefore ignored, or nil
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// config returns the internal monitor configuration for the current
+// settings. The caller must hold m.mu.
+func (m *Monitor) config() *monitor.Config {
+	return &monitor.Config{
+		URL:              m.url,
+		Interval:         m.interval,
+		Schedule:         m.schedule,
+		Timeout:          m.timeout,
+		Headers:          m.headers,
+		Baggage:          m.mergedBaggage(),
+		Labels:           m.labels,
+		IgnoreSelectors:  m.ignore,
+		Method:           monitor.MethodHash,
+		RetryCount:       m.retries,
+		RetryInterval:    m.retryInt,
+		RetryPolicy:      m.policy,
+		FailureThreshold: m.failures,
+		ConfirmDelay:     m.confirm,
+		MaxBodySize:      m.maxBody,
+		FollowRedirects:  true,
+		DiffContextLines: monitor.DefaultDiffContextLines,
+		MaxDetailsLines:  monitor.DefaultMaxDetailsLines,
+		MaxDetailsBytes:  monitor.DefaultMaxDetailsBytes,
+		OnError:          m.onError,
+		Transport:        m.transport,
+		Clock:            m.clock,
+	}
+}
+
+// mergedBaggage returns the baggage of the monitor's context with the values
+// set with WithBaggage on top. The caller must hold m.mu.
+func (m *Monitor) mergedBaggage() map[string]string {
+	baggage := monitor.BaggageFromContext(m.ctx)
+	if len(m.baggage) == 0 {
+		return baggage
+	}
+	if baggage == nil {
+		baggage = make(map[string]string, len(m.baggage))
+	}
+	maps.Copy(baggage, m.baggage)
+	return baggage
+}
 
-	m.internal = monitor.NewMonitorWithConfig(config)
+// configure applies a setting unless the monitor has already started
+func (m *Monitor) configure(apply func()) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.changes != nil {
+		m.err = ErrStarted
+		return m
+	}
+	apply()
+	return m
 }
 
 // WithHeaders adds custom HTTP headers to the monitor
 func (m *Monitor) WithHeaders(headers map[string]string) *Monitor {
-	m.headers = headers
-	m.recreateMonitor()
-	return m
+	return m.configure(func() { m.headers = headers })
+}
+
+// WithBaggage sets values such as trace or tenant IDs that are sent in the
+// W3C Baggage header of every request and echoed on every Change. They add
+// to the values of a context set with ContextWithBaggage.
+func (m *Monitor) WithBaggage(values map[string]string) *Monitor {
+	return m.configure(func() { m.baggage = maps.Clone(values) })
+}
+
+// WithLabels sets key-value pairs, such as env=staging, by which a Manager
+// selects the monitor, e.g. in Manager.PauseByLabel. Unlike baggage they
+// aren't sent with requests.
+func (m *Monitor) WithLabels(labels map[string]string) *Monitor {
+	return m.configure(func() { m.labels = maps.Clone(labels) })
+}
+
+// ContextWithBaggage returns a copy of ctx carrying baggage values. Monitors
+// created with NewMonitorWithContext or WithContext, and those added to a
+// Manager created with NewManagerWithContext, send them with every request
+// and echo them on every Change, for example:
+//
+//	ctx := hawkeye.ContextWithBaggage(ctx, map[string]string{"tenant": "acme"})
+//	m := hawkeye.NewMonitorWithContext(ctx, url, time.Minute)
+func ContextWithBaggage(ctx context.Context, values map[string]string) context.Context {
+	return monitor.ContextWithBaggage(ctx, values)
 }
 
 // WithIgnoreSelectors adds CSS selectors to ignore when checking for changes
 func (m *Monitor) WithIgnoreSelectors(selectors []string) *Monitor {
-	m.ignore = selectors
-	m.recreateMonitor()
-	return m
+	return m.configure(func() { m.ignore = selectors })
 }
 
 // WithTimeout sets the HTTP request timeout
 func (m *Monitor) WithTimeout(timeout time.Duration) *Monitor {
-	m.timeout = timeout
-	m.recreateMonitor()
-	return m
+	return m.configure(func() { m.timeout = timeout })
 }
 
 // WithRetries sets the number of retry attempts and interval between retries
 func (m *Monitor) WithRetries(count int, interval time.Duration) *Monitor {
-	m.retries = count
-	m.retryInt = interval
-	m.recreateMonitor()
-	return m
+	return m.configure(func() {
+		m.retries = count
+		m.retryInt = interval
+	})
+}
+
+// WithRetryPolicy decides which failed attempts are retried and how long to
+// wait before the next one, instead of WithRetries, e.g. to retry only
+// responses with a Retry-After header. The response passed to the policy has
+// the status code and headers of the failed attempt.
+func (m *Monitor) WithRetryPolicy(policy monitor.RetryPolicy) *Monitor {
+	return m.configure(func() { m.policy = policy })
+}
+
+// WithFailureThreshold reports an error only once count checks in a row
+// failed, so that a single failed fetch doesn't alert. A recovery is
+// reported when a check succeeds after an error was.
+func (m *Monitor) WithFailureThreshold(count int) *Monitor {
+	return m.configure(func() { m.failures = count })
+}
+
+// WithConfirmDelay confirms every change by fetching the URL again after
+// delay, and reports it only if the content fetched again differs too, so
+// that content that flaps between versions doesn't alert.
+func (m *Monitor) WithConfirmDelay(delay time.Duration) *Monitor {
+	return m.configure(func() { m.confirm = delay })
+}
+
+// WithOnError calls fn with every failure of a check, including failed
+// attempts that are retried and content that can't be compared, which the
+// changes only report once retries are used up. The errors are
+// *monitor.CheckError and wrap their cause, e.g. a *net.DNSError:
+//
+//	monitor.WithOnError(func(err error) { log.Printf("check failed: %s", err) })
+//
+// fn is called on the monitor's goroutine and must not block.
+func (m *Monitor) WithOnError(fn func(error)) *Monitor {
+	return m.configure(func() { m.onError = fn })
+}
+
+// WithMaxBodySize fails checks whose response body is larger than size
+// bytes, so a runaway download can't exhaust memory. Without it
+// monitor.DefaultMaxBodySize applies; zero means no limit.
+func (m *Monitor) WithMaxBodySize(size int64) *Monitor {
+	return m.configure(func() { m.maxBody = size })
+}
+
+// WithSchedule checks the URL at the times matching a cron expression
+// instead of at the interval, for example:
+//
+//	monitor.WithSchedule(schedule.MustParseCron("*/10 9-17 * * mon-fri"))
+func (m *Monitor) WithSchedule(cron *schedule.Cron) *Monitor {
+	return m.configure(func() { m.schedule = cron })
+}
+
+// WithTransport sets the HTTP transport used to fetch the URL.
+// It is mainly useful for injecting a scripted transport in tests.
+func (m *Monitor) WithTransport(transport http.RoundTripper) *Monitor {
+	return m.configure(func() { m.transport = transport })
+}
+
+// WithClock sets the clock used for scheduling checks and timestamping changes.
+// It is mainly useful for injecting a fake clock in tests.
+func (m *Monitor) WithClock(clock monitor.Clock) *Monitor {
+	return m.configure(func() { m.clock = clock })
 }
 
 // WithContext associates the monitor with a context
 // This is a more Go 1.23-friendly approach to monitor lifecycle management
 func (m *Monitor) WithContext(ctx context.Context) *Monitor {
-	// Cancel the existing context
-	m.cancel()
+	return m.configure(func() {
+		// Cancel the existing context
+		m.cancel()
 
-	// Create a new context that will be canceled when either the provided context
-	// or our own internal context is canceled
-	newCtx, newCancel := context.WithCancel(ctx)
-
-	// Update the monitor's context
-	m.ctx = newCtx
-	m.cancel = newCancel
-
-	return m
+		// Create a new context that will be canceled when either the provided context
+		// or our own internal context is canceled
+		m.ctx, m.cancel = context.WithCancel(ctx)
+	})
 }
 
 // GetURL returns the URL being monitored
@@ -0,0 +1,181 @@
+package hawkeye
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/monitortest"
+	"github.com/stretchr/testify/require"
+)
+
+// drained reports whether changes is closed within a second, discarding
+// anything still sent on it
+func drained(changes <-chan Change) bool {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func newTestMonitor() (*Monitor, *monitortest.FakeClock) {
+	clock := monitortest.NewFakeClock(time.Now())
+	m := NewMonitor("https://example.com", time.Minute).
+		WithTransport(monitortest.NewTransport(monitortest.OK("version 1"), monitortest.OK("version 2"))).
+		WithClock(clock).
+		WithRetries(0, 0)
+	return m, clock
+}
+
+func TestMonitorChanges(t *testing.T) {
+	m, clock := newTestMonitor()
+	changes := m.Start()
+	defer m.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	change := <-changes
+	require.True(t, change.HasChanged)
+	require.Equal(t, "https://example.com", change.URL)
+}
+
+func TestMonitorSettingsAfterStart(t *testing.T) {
+	m, _ := newTestMonitor()
+	require.NoError(t, m.Err())
+
+	changes := m.Start()
+	require.Equal(t, changes, m.Start())
+
+	m.WithTimeout(time.Second)
+	require.ErrorIs(t, m.Err(), ErrStarted)
+
+	m.Stop()
+	m.Stop()
+	require.True(t, drained(changes))
+}
+
+func TestMonitorStopBeforeStart(t *testing.T) {
+	m, _ := newTestMonitor()
+	m.Stop()
+	require.True(t, drained(m.Start()))
+}
+
+func TestMonitorContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m, clock := newTestMonitor()
+	changes := m.WithContext(ctx).Start()
+
+	clock.BlockUntil(1)
+	cancel()
+	require.True(t, drained(changes))
+}
+
+func TestMonitorConcurrentUse(t *testing.T) {
+	m, _ := newTestMonitor()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.WithHeaders(map[string]string{"X-Test": "1"})
+			m.Start()
+			m.WithIgnoreSelectors([]string{".ad"})
+			m.Stop()
+		}()
+	}
+	wg.Wait()
+
+	require.ErrorIs(t, m.Err(), ErrStarted)
+	require.True(t, drained(m.Start()))
+}
+
+func TestMonitorBaggage(t *testing.T) {
+	ctx := ContextWithBaggage(context.Background(), map[string]string{"tenant": "acme", "trace": "1"})
+	transport := monitortest.NewTransport(monitortest.OK("version 1"), monitortest.OK("version 2"))
+	clock := monitortest.NewFakeClock(time.Now())
+	m := NewMonitorWithContext(ctx, "https://example.com", time.Minute).
+		WithBaggage(map[string]string{"trace": "2"}).
+		WithTransport(transport).
+		WithClock(clock)
+	changes := m.Start()
+	defer m.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	change := <-changes
+	require.Equal(t, map[string]string{"tenant": "acme", "trace": "2"}, change.Baggage)
+	require.Equal(t, "tenant=acme,trace=2", transport.Requests()[0].Header.Get("Baggage"))
+}
+
+// noRetry never retries
+type noRetry struct{}
+
+func (noRetry) ShouldRetry(err error, resp *http.Response, attempt int) bool { return false }
+func (noRetry) NextDelay(attempt int) time.Duration                          { return time.Hour }
+
+func TestMonitorRetryPolicy(t *testing.T) {
+	errs := make(chan error, 1)
+	transport := monitortest.NewTransport(monitortest.Status(http.StatusServiceUnavailable, ""))
+	m := NewMonitor("https://example.com", time.Minute).
+		WithTransport(transport).
+		WithClock(monitortest.NewFakeClock(time.Now())).
+		WithRetries(3, time.Second).
+		WithRetryPolicy(noRetry{}).
+		WithOnError(func(err error) { errs <- err })
+	m.Start()
+	defer m.Stop()
+
+	var checkErr *monitor.CheckError
+	require.ErrorAs(t, <-errs, &checkErr)
+	require.True(t, checkErr.Final)
+	require.Equal(t, 1, checkErr.Attempt)
+	var statusErr *monitor.StatusError
+	require.ErrorAs(t, checkErr, &statusErr)
+	require.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+}
+
+func TestMonitorOnError(t *testing.T) {
+	errs := make(chan error, 1)
+	m := NewMonitor("https://example.com", time.Minute).
+		WithTransport(monitortest.NewTransport(monitortest.Error(&net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}))).
+		WithClock(monitortest.NewFakeClock(time.Now())).
+		WithRetries(0, 0).
+		WithOnError(func(err error) { errs <- err })
+	m.Start()
+	defer m.Stop()
+
+	err := <-errs
+	var checkErr *monitor.CheckError
+	require.ErrorAs(t, err, &checkErr)
+	require.True(t, checkErr.Final)
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	require.Equal(t, "example.com", dnsErr.Name)
+}
+
+func TestMonitorMaxBodySize(t *testing.T) {
+	m := NewMonitor("https://example.com", time.Minute)
+	require.Equal(t, int64(monitor.DefaultMaxBodySize), m.config().MaxBodySize)
+
+	changes := m.WithTransport(monitortest.NewTransport(monitortest.OK("version 1"))).
+		WithClock(monitortest.NewFakeClock(time.Now())).
+		WithRetries(0, 0).
+		WithMaxBodySize(4).
+		Start()
+	defer m.Stop()
+
+	change := <-changes
+	require.Contains(t, change.Error, monitor.ErrBodyTooLarge.Error())
+}
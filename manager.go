@@ -0,0 +1,320 @@
+package hawkeye
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// Manager watches many URLs and sends the changes of all of them on a single
+// channel. URLs are added with their settings, see AddMonitor, and can be
+// organized in groups. Its methods are safe to call from multiple
+// goroutines.
+type Manager struct {
+	mu       sync.Mutex
+	internal *monitor.Manager
+	changes  <-chan Change
+	stopped  bool
+	done     chan struct{}
+	// baggage holds the values of the context given to
+	// NewManagerWithContext, added to every monitor
+	baggage map[string]string
+}
+
+// NewManager creates a new manager without URLs
+func NewManager() *Manager {
+	return &Manager{
+		internal: monitor.NewManager(),
+		done:     make(chan struct{}),
+	}
+}
+
+// NewManagerWithContext creates a new manager that stops when ctx is
+// canceled. The baggage of ctx, see ContextWithBaggage, is added to every
+// monitor.
+func NewManagerWithContext(ctx context.Context) *Manager {
+	m := NewManager()
+	m.baggage = monitor.BaggageFromContext(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.Stop()
+		case <-m.done:
+		}
+	}()
+	return m
+}
+
+// Add watches a URL with the default settings of NewMonitor. If the manager
+// is running, the URL is checked right away.
+func (m *Manager) Add(url string, interval time.Duration) error {
+	return m.AddMonitor(NewMonitor(url, interval))
+}
+
+// AddMonitor watches the URL of a monitor with its settings, for example:
+//
+//	manager.AddMonitor(hawkeye.NewMonitor(url, time.Minute).WithTimeout(5 * time.Second))
+//
+// The monitor only provides the settings and must not be started; if it was,
+// ErrStarted is returned. If the manager is running, the URL is checked
+// right away.
+func (m *Manager) AddMonitor(monitor *Monitor) error {
+	monitor.mu.Lock()
+	if monitor.changes != nil {
+		monitor.mu.Unlock()
+		return ErrStarted
+	}
+	config := monitor.config()
+	monitor.mu.Unlock()
+
+	if len(m.baggage) > 0 {
+		baggage := maps.Clone(m.baggage)
+		maps.Copy(baggage, config.Baggage)
+		config.Baggage = baggage
+	}
+
+	_, err := m.internal.AddMonitorWithConfig(config)
+	return err
+}
+
+// SetStore makes the manager remember its URLs, their settings and groups
+// across restarts, for example with monitor.NewFileStore. The URLs saved in
+// the store are watched again, and every change is saved from then on; see
+// monitor.Manager.SetStore for the settings that can't be saved.
+func (m *Manager) SetStore(store monitor.Store) error {
+	return m.internal.SetStore(store)
+}
+
+// Remove stops watching a URL
+func (m *Manager) Remove(url string) error {
+	return m.internal.RemoveMonitor(url)
+}
+
+// AddToGroup adds a watched URL to a group, creating the group if needed
+func (m *Manager) AddToGroup(url, group string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.internal.GetGroup(group); err != nil {
+		if _, err := m.internal.CreateGroup(group, ""); err != nil {
+			return err
+		}
+	}
+	return m.internal.AddToGroup(url, group)
+}
+
+// RemoveFromGroup removes a URL from a group. The URL is still watched.
+func (m *Manager) RemoveFromGroup(url, group string) error {
+	return m.internal.RemoveFromGroup(url, group)
+}
+
+// MoveToGroup moves a URL from one group to another at once, creating the
+// target group if needed
+func (m *Manager) MoveToGroup(url, from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.internal.GetGroup(to); err != nil {
+		if _, err := m.internal.CreateGroup(to, ""); err != nil {
+			return err
+		}
+	}
+	return m.internal.MoveToGroup(url, from, to)
+}
+
+// GroupsOf returns the names of the groups of a URL in alphabetical order
+func (m *Manager) GroupsOf(url string) ([]string, error) {
+	return m.internal.GroupsOf(url)
+}
+
+// URLs returns the watched URLs in alphabetical order
+func (m *Manager) URLs() []string {
+	urls := m.internal.ListMonitors()
+	sort.Strings(urls)
+	return urls
+}
+
+// Groups returns the names of the groups in alphabetical order
+func (m *Manager) Groups() []string {
+	groups := m.internal.ListGroups()
+	sort.Strings(groups)
+	return groups
+}
+
+// Start starts watching all URLs and returns a channel for the changes of
+// all of them. Calling it again returns the same channel. The channel is
+// closed once the manager is stopped.
+func (m *Manager) Start() <-chan Change {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return m.closedLocked()
+	}
+	return m.forwardLocked(m.internal.Start())
+}
+
+// StartGroup starts watching the URLs of a group only. Their changes are
+// sent on the same channel as returned by Start.
+func (m *Manager) StartGroup(group string) (<-chan Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return m.closedLocked(), nil
+	}
+	internalChanges, err := m.internal.StartGroup(group)
+	if err != nil {
+		return nil, err
+	}
+	return m.forwardLocked(internalChanges), nil
+}
+
+// StartAllExcept starts watching the URLs that aren't in any of the given
+// groups. Their changes are sent on the same channel as returned by Start.
+func (m *Manager) StartAllExcept(groups ...string) (<-chan Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return m.closedLocked(), nil
+	}
+	internalChanges, err := m.internal.StartAllExcept(groups...)
+	if err != nil {
+		return nil, err
+	}
+	return m.forwardLocked(internalChanges), nil
+}
+
+// forwardLocked converts the changes of the internal manager, unless they
+// are already being forwarded. The caller must hold m.mu.
+func (m *Manager) forwardLocked(internalChanges <-chan monitor.Change) <-chan Change {
+	if m.changes != nil {
+		return m.changes
+	}
+
+	changes := make(chan Change)
+	m.changes = changes
+	go func() {
+		// The internal channel is closed when the manager is stopped
+		defer close(changes)
+		for change := range internalChanges {
+			changes <- newChange(change)
+		}
+	}()
+	return changes
+}
+
+// closedLocked returns the channel of a stopped manager. The caller must
+// hold m.mu.
+func (m *Manager) closedLocked() <-chan Change {
+	if m.changes == nil {
+		changes := make(chan Change)
+		close(changes)
+		m.changes = changes
+	}
+	return m.changes
+}
+
+// Stop stops watching all URLs. It is safe to call more than once.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return
+	}
+	m.stopped = true
+	close(m.done)
+
+	// Forwarding blocks on a reader that is gone, so drain what is left
+	// while the internal manager waits for its monitors
+	if m.changes != nil {
+		go func(changes <-chan Change) {
+			for range changes {
+			}
+		}(m.changes)
+	}
+	m.internal.Stop()
+}
+
+// Pause suspends checks of a URL
+func (m *Manager) Pause(url string) error {
+	return m.internal.PauseMonitor(url)
+}
+
+// Resume resumes checks of a URL
+func (m *Manager) Resume(url string) error {
+	return m.internal.ResumeMonitor(url)
+}
+
+// PauseGroup suspends checks of all URLs in a group
+func (m *Manager) PauseGroup(group string) error {
+	return m.internal.PauseGroup(group)
+}
+
+// ResumeGroup resumes checks of all URLs in a group
+func (m *Manager) ResumeGroup(group string) error {
+	return m.internal.ResumeGroup(group)
+}
+
+// PauseByLabel suspends checks of all URLs whose labels match a selector
+// such as "env=staging,team!=web", see monitor.ParseLabelSelector, and
+// returns them
+func (m *Manager) PauseByLabel(selector string) ([]string, error) {
+	parsed, err := monitor.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return m.internal.PauseByLabel(parsed)
+}
+
+// ResumeByLabel resumes checks of all URLs whose labels match a selector and
+// returns them
+func (m *Manager) ResumeByLabel(selector string) ([]string, error) {
+	parsed, err := monitor.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return m.internal.ResumeByLabel(parsed)
+}
+
+// StopByLabel stops watching all URLs whose labels match a selector and
+// returns them
+func (m *Manager) StopByLabel(selector string) ([]string, error) {
+	parsed, err := monitor.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return m.internal.StopByLabel(parsed), nil
+}
+
+// SetMaxConcurrentChecks limits the number of URLs fetched at the same time.
+// Zero, the default, means no limit.
+func (m *Manager) SetMaxConcurrentChecks(n int) {
+	m.internal.SetMaxConcurrentChecks(n)
+}
+
+// SetRateLimit limits the requests per minute sent to any one host. Zero,
+// the default, means no limit.
+func (m *Manager) SetRateLimit(perMinute int) {
+	m.internal.SetRateLimit(perMinute)
+}
+
+// Iterator returns an iterator that yields the changes of all URLs
+func (m *Manager) Iterator() func(yield func(Change) bool) {
+	changes := m.Start()
+
+	return func(yield func(Change) bool) {
+		for change := range changes {
+			if !yield(change) {
+				m.Stop()
+				return
+			}
+		}
+	}
+}
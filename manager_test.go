@@ -0,0 +1,146 @@
+package hawkeye
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/monitortest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	transport := monitortest.NewTransport().
+		Script("https://example.com", monitortest.OK("a1"), monitortest.OK("a2")).
+		Script("https://example.org", monitortest.OK("b1"), monitortest.OK("b1"))
+	clock := monitortest.NewFakeClock(time.Now())
+
+	m := NewManager()
+	defer m.Stop()
+	for _, url := range []string{"https://example.com", "https://example.org"} {
+		require.NoError(t, m.AddMonitor(NewMonitor(url, time.Minute).WithTransport(transport).WithClock(clock).WithRetries(0, 0)))
+	}
+	require.Error(t, m.Add("https://example.com", time.Minute))
+	require.NoError(t, m.AddToGroup("https://example.org", "api"))
+	require.Error(t, m.AddToGroup("https://example.net", "api"))
+	require.Equal(t, []string{"https://example.com", "https://example.org"}, m.URLs())
+	require.Equal(t, []string{"api"}, m.Groups())
+
+	changes := m.Start()
+	require.Equal(t, changes, m.Start())
+
+	clock.BlockUntil(2)
+	clock.Advance(time.Minute)
+	change := <-changes
+	require.True(t, change.HasChanged)
+	require.Equal(t, "https://example.com", change.URL)
+}
+
+func TestManagerGroups(t *testing.T) {
+	transport := monitortest.NewTransport().
+		Script("https://example.com", monitortest.OK("a1"), monitortest.OK("a2"))
+	clock := monitortest.NewFakeClock(time.Now())
+
+	m := NewManager()
+	defer m.Stop()
+	for _, url := range []string{"https://example.com", "https://example.org"} {
+		require.NoError(t, m.AddMonitor(NewMonitor(url, time.Minute).WithTransport(transport).WithClock(clock).WithRetries(0, 0)))
+	}
+	require.NoError(t, m.AddToGroup("https://example.org", "new"))
+	require.NoError(t, m.MoveToGroup("https://example.org", "new", "slow"))
+	groups, err := m.GroupsOf("https://example.org")
+	require.NoError(t, err)
+	require.Equal(t, []string{"slow"}, groups)
+	require.Equal(t, []string{"new", "slow"}, m.Groups())
+
+	// Only the URL outside the slow group is checked
+	changes, err := m.StartAllExcept("slow")
+	require.NoError(t, err)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	require.Equal(t, "https://example.com", (<-changes).URL)
+
+	require.NoError(t, m.RemoveFromGroup("https://example.org", "slow"))
+	groups, _ = m.GroupsOf("https://example.org")
+	require.Empty(t, groups)
+}
+
+func TestManagerAddStartedMonitor(t *testing.T) {
+	monitor, _ := newTestMonitor()
+	monitor.Start()
+	defer monitor.Stop()
+
+	require.ErrorIs(t, NewManager().AddMonitor(monitor), ErrStarted)
+}
+
+func TestManagerStop(t *testing.T) {
+	m := NewManager()
+	m.Stop()
+	m.Stop()
+	require.True(t, drained(m.Start()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m = NewManagerWithContext(ctx)
+	monitor, _ := newTestMonitor()
+	require.NoError(t, m.AddMonitor(monitor))
+	changes := m.Start()
+	cancel()
+	require.True(t, drained(changes))
+}
+
+func TestManagerBaggage(t *testing.T) {
+	ctx := ContextWithBaggage(context.Background(), map[string]string{"tenant": "acme"})
+	m := NewManagerWithContext(ctx)
+	defer m.Stop()
+
+	require.NoError(t, m.AddMonitor(NewMonitor("https://example.com", time.Minute).WithBaggage(map[string]string{"trace": "1"})))
+	monitor, err := m.internal.GetMonitor("https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme", "trace": "1"}, monitor.GetConfig().Baggage)
+}
+
+func TestManagerByLabel(t *testing.T) {
+	m := NewManager()
+	defer m.Stop()
+
+	require.NoError(t, m.AddMonitor(NewMonitor("https://staging.example.com", time.Minute).WithLabels(map[string]string{"env": "staging"})))
+	require.NoError(t, m.AddMonitor(NewMonitor("https://www.example.com", time.Minute).WithLabels(map[string]string{"env": "production"})))
+
+	urls, err := m.PauseByLabel("env=staging")
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://staging.example.com"}, urls)
+	monitor, err := m.internal.GetMonitor("https://staging.example.com")
+	require.NoError(t, err)
+	require.True(t, monitor.IsPaused())
+
+	urls, err = m.ResumeByLabel("env")
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	require.False(t, monitor.IsPaused())
+
+	_, err = m.StopByLabel("=staging")
+	require.Error(t, err)
+}
+
+func TestManagerStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manager.json")
+
+	m := NewManager()
+	require.NoError(t, m.SetStore(monitor.NewFileStore(path)))
+	require.NoError(t, m.Add("https://example.com", time.Minute))
+	require.NoError(t, m.AddToGroup("https://example.com", "news"))
+	require.NoError(t, m.Pause("https://example.com"))
+	m.Stop()
+
+	restored := NewManager()
+	defer restored.Stop()
+	require.NoError(t, restored.SetStore(monitor.NewFileStore(path)))
+	require.Equal(t, []string{"https://example.com"}, restored.URLs())
+	require.Equal(t, []string{"news"}, restored.Groups())
+	internal, err := restored.internal.GetMonitor("https://example.com")
+	require.NoError(t, err)
+	require.True(t, internal.IsPaused())
+	require.Equal(t, time.Minute, internal.GetConfig().Interval)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role is what an API key may do. Each role includes the ones below it.
+type Role string
+
+const (
+	// RoleRead lists monitors and groups and reads the change history
+	RoleRead Role = "read"
+	// RoleWrite also creates, pauses, resumes and triggers monitors
+	RoleWrite Role = "write"
+	// RoleManage also deletes monitors and discards their baselines
+	RoleManage Role = "manage"
+)
+
+// roleRanks orders roles from least to most privileged
+var roleRanks = map[Role]int{
+	RoleRead:   1,
+	RoleWrite:  2,
+	RoleManage: 3,
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(name))
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role '%s' (expected read, write or manage)", name)
+	}
+	return role, nil
+}
+
+// Allows reports whether r includes role
+func (r Role) Allows(role Role) bool {
+	return roleRanks[r] >= roleRanks[role]
+}
+
+// Key is an API key clients send as "Authorization: Bearer <token>"
+type Key struct {
+	// Name identifies the key in the audit log, e.g. "dashboard"
+	Name string `yaml:"name"`
+	// Role is what requests with the key may do
+	Role Role `yaml:"role"`
+	// Token is the secret sent by clients
+	Token string `yaml:"token"`
+}
+
+var (
+	// errUnauthorized is returned for requests without a known key
+	errUnauthorized = errors.New("missing or unknown API key")
+	// errForbidden is returned for keys whose role doesn't allow a request
+	errForbidden = errors.New("API key not allowed to do this")
+)
+
+// keyFile is the format of API key files
+type keyFile struct {
+	Keys []Key `yaml:"keys"`
+}
+
+// LoadKeys reads API keys from a YAML file with a "keys" list of name, role
+// and token entries
+func LoadKeys(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if len(file.Keys) == 0 {
+		return nil, fmt.Errorf("%s defines no keys", path)
+	}
+
+	names := make(map[string]bool)
+	for i, key := range file.Keys {
+		if key.Name == "" || key.Token == "" {
+			return nil, fmt.Errorf("%s: key %d needs a name and a token", path, i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("%s: duplicate key name '%s'", path, key.Name)
+		}
+		names[key.Name] = true
+
+		role, err := ParseRole(string(key.Role))
+		if err != nil {
+			return nil, fmt.Errorf("%s: key '%s': %w", path, key.Name, err)
+		}
+		file.Keys[i].Role = role
+	}
+	return file.Keys, nil
+}
+
+// keyContext is the context key of the API key of a request
+type keyContext struct{}
+
+// authenticate returns the key of a request's bearer token, or nil
+func (s *Server) authenticate(r *http.Request) *Key {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil
+	}
+	// Digests are compared, as their length doesn't depend on the tokens, and
+	// every key is, so the time taken doesn't tell which matched
+	presented := sha256.Sum256([]byte(token))
+	var matched *Key
+	for i := range s.options.Keys {
+		configured := sha256.Sum256([]byte(s.options.Keys[i].Token))
+		if subtle.ConstantTimeCompare(configured[:], presented[:]) == 1 && matched == nil {
+			matched = &s.options.Keys[i]
+		}
+	}
+	return matched
+}
+
+// require wraps a handler so it only serves requests with a key of at least
+// role. Without configured keys every request is served.
+func (s *Server) require(role Role, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.options.Keys) == 0 {
+			handler(w, r)
+			return
+		}
+
+		key := s.authenticate(r)
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="hawkeye"`)
+			writeError(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+		if !key.Role.Allows(role) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%w: requires the %s role, key '%s' has %s", errForbidden, role, key.Name, key.Role))
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), keyContext{}, key)))
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoles(t *testing.T) {
+	log := audit.NewLog(filepath.Join(t.TempDir(), "audit.log"))
+	server := NewServer(monitor.NewManager(), &Options{
+		Audit: log,
+		Keys: []Key{
+			{Name: "dashboard", Role: RoleRead, Token: "read-token"},
+			{Name: "ci", Role: RoleWrite, Token: "write-token"},
+			{Name: "admin", Role: RoleManage, Token: "manage-token"},
+		},
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	do := func(method, path, token, body string) int {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	create := `{"url": "https://example.com", "interval": "1h"}`
+
+	// Requests need a known key, except probes
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/monitors", "", ""))
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/monitors", "wrong", ""))
+	require.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/health", "", ""))
+
+	// Readers can only read
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/monitors", "read-token", ""))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/changes", "read-token", ""))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/monitors", "read-token", create))
+
+	// Writers can modify monitors but not delete them
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/monitors", "write-token", create))
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/monitors/pause?url=https://example.com", "write-token", ""))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/monitors/reset?url=https://example.com", "write-token", ""))
+	require.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/monitors?url=https://example.com", "write-token", ""))
+
+	// Managers can do everything
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/groups", "manage-token", ""))
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/monitors?url=https://example.com", "manage-token", ""))
+
+	// Actions are recorded with the name of the key
+	entries, err := log.Entries(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "ci", entries[0].Actor)
+	require.Equal(t, "ci", entries[1].Actor)
+	require.Equal(t, audit.ActionRemove, entries[2].Action)
+	require.Equal(t, "admin", entries[2].Actor)
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "keys.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	keys, err := LoadKeys(write(`
+keys:
+  - name: dashboard
+    role: read
+    token: abc
+  - name: admin
+    role: Manage
+    token: def
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Key{
+		{Name: "dashboard", Role: RoleRead, Token: "abc"},
+		{Name: "admin", Role: RoleManage, Token: "def"},
+	}, keys)
+
+	_, err = LoadKeys(write("keys: []"))
+	require.ErrorContains(t, err, "defines no keys")
+
+	_, err = LoadKeys(write("keys:\n  - name: ci\n    role: admin\n    token: abc\n"))
+	require.ErrorContains(t, err, "unknown role 'admin'")
+
+	_, err = LoadKeys(write("keys:\n  - name: ci\n    role: read\n"))
+	require.ErrorContains(t, err, "needs a name and a token")
+
+	_, err = LoadKeys(write("keys:\n  - {name: ci, role: read, token: a}\n  - {name: ci, role: write, token: b}\n"))
+	require.ErrorContains(t, err, "duplicate key name 'ci'")
+}
+
+func TestRoleAllows(t *testing.T) {
+	require.True(t, RoleManage.Allows(RoleRead))
+	require.True(t, RoleWrite.Allows(RoleWrite))
+	require.False(t, RoleWrite.Allows(RoleManage))
+	require.False(t, Role("").Allows(RoleRead))
+}
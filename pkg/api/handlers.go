@@ -0,0 +1,1088 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/imap"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// MonitorRequest is the request body used to create a monitor
+type MonitorRequest struct {
+	URL                 string            `json:"url"`
+	Interval            string            `json:"interval"`
+	Schedule            string            `json:"schedule,omitempty"`
+	At                  string            `json:"at,omitempty"`
+	Until               string            `json:"until,omitempty"`
+	Deadline            string            `json:"deadline,omitempty"`
+	Expires             string            `json:"expires,omitempty"`
+	Expect              []string          `json:"expect,omitempty"`
+	Timeout             string            `json:"timeout,omitempty"`
+	FailureThreshold    int               `json:"failure_threshold,omitempty"`
+	ConfirmDelay        string            `json:"confirm_delay,omitempty"`
+	Method              string            `json:"method,omitempty"`
+	ExpectedStatus      []int             `json:"expected_status,omitempty"`
+	Match               []string          `json:"match,omitempty"`
+	MatchAbsent         []string          `json:"match_absent,omitempty"`
+	Count               []string          `json:"count,omitempty"`
+	CSVKeys             []string          `json:"csv_keys,omitempty"`
+	CSVDelimiter        string            `json:"csv_delimiter,omitempty"`
+	TailSeparator       string            `json:"tail_separator,omitempty"`
+	WebhookMatch        []string          `json:"webhook_match,omitempty"`
+	WebhookFields       []string          `json:"webhook_fields,omitempty"`
+	WebhookSecret       string            `json:"webhook_secret,omitempty"`
+	ImageThreshold      float64           `json:"image_threshold,omitempty"`
+	Paginate            string            `json:"paginate,omitempty"`
+	PaginateItems       string            `json:"paginate_items,omitempty"`
+	MaxPages            int               `json:"max_pages,omitempty"`
+	AppendOnly          bool              `json:"append_only,omitempty"`
+	Representations     []string          `json:"representations,omitempty"`
+	Variants            []monitor.Variant `json:"variants,omitempty"`
+	Extract             string            `json:"extract,omitempty"`
+	Below               *float64          `json:"below,omitempty"`
+	Above               *float64          `json:"above,omitempty"`
+	Delta               float64           `json:"delta,omitempty"`
+	DeltaPercent        float64           `json:"delta_percent,omitempty"`
+	Group               string            `json:"group,omitempty"`
+	Headers             map[string]string `json:"headers,omitempty"`
+	Baggage             map[string]string `json:"baggage,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Ignore              []string          `json:"ignore,omitempty"`
+	Select              []string          `json:"select,omitempty"`
+	Alert               []string          `json:"alert,omitempty"`
+	Proxy               string            `json:"proxy,omitempty"`
+	AcceptEncoding      string            `json:"accept_encoding,omitempty"`
+	NoCache             bool              `json:"no_cache,omitempty"`
+	CacheBust           string            `json:"cache_bust,omitempty"`
+	CaptureHAR          bool              `json:"capture_har,omitempty"`
+	NormalizeWhitespace bool              `json:"normalize_whitespace,omitempty"`
+	IgnoreTimestamps    bool              `json:"ignore_timestamps,omitempty"`
+	Fetcher             string            `json:"fetcher,omitempty"`
+	WaitSelector        string            `json:"wait_selector,omitempty"`
+	RequestMethod       string            `json:"request_method,omitempty"`
+	Body                string            `json:"body,omitempty"`
+	ContentType         string            `json:"content_type,omitempty"`
+	KeepCookies         bool              `json:"keep_cookies,omitempty"`
+	Cookies             map[string]string `json:"cookies,omitempty"`
+	BasicAuth           *BasicAuthRequest `json:"basic_auth,omitempty"`
+	BearerToken         string            `json:"bearer_token,omitempty"`
+	Login               *LoginRequest     `json:"login,omitempty"`
+	OAuth2              *OAuth2Request    `json:"oauth2,omitempty"`
+}
+
+// BasicAuthRequest is the basic auth of a monitor, see monitor.BasicAuth
+type BasicAuthRequest struct {
+	User string `json:"user"`
+	Pass string `json:"pass,omitempty"`
+}
+
+// LoginRequest is the login of a monitor, see monitor.Login
+type LoginRequest struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Token       string            `json:"token,omitempty"`
+}
+
+// OAuth2Request is the OAuth2 client credentials of a monitor, see
+// customhttp.OAuth2Options
+type OAuth2Request struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// MonitorInfo describes a monitor in API responses
+type MonitorInfo struct {
+	URL      string `json:"url"`
+	Interval string `json:"interval"`
+	Schedule string `json:"schedule,omitempty"`
+	Method   string `json:"method"`
+	Status   string `json:"status,omitempty"`
+	Paused   bool   `json:"paused"`
+	// Labels select the monitor, see monitor.ParseLabelSelector
+	Labels     map[string]string `json:"labels,omitempty"`
+	LastCheck  time.Time         `json:"last_check"`
+	NextCheck  *time.Time        `json:"next_check,omitempty"`
+	CheckCount int64             `json:"check_count"`
+	Latency    string            `json:"latency,omitempty"`
+	// LastError is the error of the last check, if it failed
+	LastError string `json:"last_error,omitempty"`
+	// Failures counts the checks in a row that failed, and Failing is set
+	// once they reached the failure threshold of the monitor and an error
+	// was reported. Until then Status is "erroring" after a failed check.
+	Failures int64 `json:"failures,omitempty"`
+	Failing  bool  `json:"failing,omitempty"`
+	// DuplicateOf lists the other monitors whose last content was identical
+	// after filters and normalization. It is only set when listing monitors.
+	DuplicateOf []string `json:"duplicate_of,omitempty"`
+}
+
+// ClientRequest changes the HTTP client settings of a running monitor.
+// Settings that are left out keep their value; an empty proxy removes it.
+type ClientRequest struct {
+	Timeout         *string `json:"timeout,omitempty"`
+	FollowRedirects *bool   `json:"follow_redirects,omitempty"`
+	Proxy           *string `json:"proxy,omitempty"`
+}
+
+// TriggerResponse lists the monitors a trigger requested checks of. Skipped
+// monitors are paused and were not checked.
+type TriggerResponse struct {
+	Triggered []string `json:"triggered"`
+	Skipped   []string `json:"skipped,omitempty"`
+}
+
+// GroupInfo describes a monitor group in API responses
+type GroupInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Monitors    []string `json:"monitors"`
+}
+
+// errorResponse is the body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// toConfig converts the request into a monitor configuration
+func (r *MonitorRequest) toConfig() (*monitor.Config, error) {
+	if r.URL == "" {
+		return nil, monitor.ErrURLEmpty
+	}
+
+	config := monitor.DefaultConfig(r.URL)
+
+	if r.Interval != "" {
+		interval, err := time.ParseDuration(r.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		config.Interval = interval
+	}
+
+	if r.Schedule != "" {
+		cron, err := schedule.ParseCron(r.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+		config.Schedule = cron
+	}
+
+	if r.At != "" {
+		at, err := schedule.ParseTime(r.At, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !at.After(time.Now()) {
+			return nil, fmt.Errorf("at %s is in the past", r.At)
+		}
+		config.At = at
+	}
+
+	if r.Until != "" {
+		until, err := monitor.ParseCondition(r.Until)
+		if err != nil {
+			return nil, err
+		}
+		config.Until = until
+	}
+
+	if r.Deadline != "" {
+		deadline, err := schedule.ParseDeadline(r.Deadline, time.Now(), nil)
+		if err != nil {
+			return nil, err
+		}
+		config.Deadline = deadline
+	}
+
+	if r.Expires != "" {
+		expires, err := schedule.ParseDeadline(r.Expires, time.Now(), nil)
+		if err != nil {
+			return nil, err
+		}
+		config.Expires = expires
+	}
+
+	if len(r.Expect) > 0 {
+		expect, err := monitor.ParseExpectation(r.Expect)
+		if err != nil {
+			return nil, err
+		}
+		config.Expect = expect
+	}
+
+	if r.Timeout != "" {
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		config.Timeout = timeout
+	}
+	config.FailureThreshold = r.FailureThreshold
+
+	if r.ConfirmDelay != "" {
+		delay, err := time.ParseDuration(r.ConfirmDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid confirm delay: %w", err)
+		}
+		config.ConfirmDelay = delay
+	}
+
+	if r.Proxy != "" {
+		proxy, err := customhttp.ParseProxyURL(r.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		config.ProxyURL = proxy
+	}
+
+	if r.AcceptEncoding != "" {
+		if err := customhttp.ValidateAcceptEncoding(r.AcceptEncoding); err != nil {
+			return nil, err
+		}
+		config.AcceptEncoding = r.AcceptEncoding
+	}
+
+	if r.RequestMethod != "" {
+		method, err := monitor.ParseRequestMethod(r.RequestMethod)
+		if err != nil {
+			return nil, err
+		}
+		config.RequestMethod = method
+	}
+	config.Body = r.Body
+	config.ContentType = r.ContentType
+	config.KeepCookies = r.KeepCookies
+	config.Cookies = r.Cookies
+	if r.BasicAuth != nil {
+		config.BasicAuth = &monitor.BasicAuth{User: r.BasicAuth.User, Pass: r.BasicAuth.Pass}
+	}
+	config.BearerToken = r.BearerToken
+	if r.Login != nil {
+		config.Login = &monitor.Login{
+			URL:         r.Login.URL,
+			Method:      r.Login.Method,
+			Body:        r.Login.Body,
+			ContentType: r.Login.ContentType,
+			Headers:     r.Login.Headers,
+			Token:       r.Login.Token,
+		}
+	}
+	if r.OAuth2 != nil {
+		config.OAuth2 = &customhttp.OAuth2Options{
+			TokenURL:     r.OAuth2.TokenURL,
+			ClientID:     r.OAuth2.ClientID,
+			ClientSecret: r.OAuth2.ClientSecret,
+			Scopes:       r.OAuth2.Scopes,
+		}
+	}
+
+	fetcher, err := monitor.ParseFetcher(r.Fetcher)
+	if err != nil {
+		return nil, err
+	}
+	config.Fetcher = fetcher
+	config.WaitSelector = r.WaitSelector
+
+	methodName := r.Method
+	if methodName == "" && len(r.Match)+len(r.MatchAbsent) > 0 {
+		methodName = "keyword"
+	}
+	if methodName == "" && r.Extract != "" {
+		methodName = "value"
+	}
+	if methodName == "" && len(r.Count) > 0 {
+		methodName = "count"
+	}
+	if methodName == "" && (len(r.CSVKeys) > 0 || r.CSVDelimiter != "") {
+		methodName = "csv"
+	}
+	if methodName == "" && r.TailSeparator != "" {
+		methodName = "tail"
+	}
+	if methodName == "" && r.ImageThreshold != 0 {
+		methodName = "image"
+	}
+	if methodName == "" && imap.IsURL(r.URL) {
+		methodName = "feed"
+	}
+	method, err := monitor.ParseMethod(methodName)
+	if err != nil {
+		return nil, err
+	}
+	if method == monitor.MethodCustom {
+		return nil, fmt.Errorf("method '%s' is not available through the API", r.Method)
+	}
+	config.Method = method
+	if len(r.ExpectedStatus) > 0 && method != monitor.MethodStatus {
+		return nil, fmt.Errorf("expected_status requires method 'status'")
+	}
+	config.ExpectedStatus = r.ExpectedStatus
+	if len(r.Match)+len(r.MatchAbsent) > 0 && method != monitor.MethodKeyword {
+		return nil, fmt.Errorf("match and match_absent require method 'keyword'")
+	}
+	if config.Matches, err = monitor.ParseMatches(r.Match, r.MatchAbsent); err != nil {
+		return nil, err
+	}
+	if len(r.Count) > 0 && method != monitor.MethodCount {
+		return nil, fmt.Errorf("count requires method 'count'")
+	}
+	if config.Keywords, err = monitor.ParseKeywords(r.Count); err != nil {
+		return nil, err
+	}
+	if (len(r.CSVKeys) > 0 || r.CSVDelimiter != "") && method != monitor.MethodCSV {
+		return nil, fmt.Errorf("csv_keys and csv_delimiter require method 'csv'")
+	}
+	config.CSVKeys = r.CSVKeys
+	if config.CSVDelimiter, err = monitor.ParseDelimiter(r.CSVDelimiter); err != nil {
+		return nil, err
+	}
+	if r.TailSeparator != "" && method != monitor.MethodTail {
+		return nil, fmt.Errorf("tail_separator requires method 'tail'")
+	}
+	if config.TailSeparator, err = monitor.ParseSeparator(r.TailSeparator); err != nil {
+		return nil, err
+	}
+	// Visual diffs aren't available through the API, as they are written to
+	// the server's disk
+	if r.ImageThreshold != 0 && method != monitor.MethodImage {
+		return nil, fmt.Errorf("image_threshold requires method 'image'")
+	}
+	config.ImageThreshold = r.ImageThreshold
+	for _, spec := range r.WebhookMatch {
+		condition, err := monitor.ParseCondition(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook_match: %w", err)
+		}
+		config.WebhookMatch = append(config.WebhookMatch, condition)
+	}
+	config.WebhookFields = r.WebhookFields
+	config.WebhookSecret = r.WebhookSecret
+	config.Representations = r.Representations
+	config.Variants = r.Variants
+
+	if r.Paginate != "" {
+		if config.Pagination, err = monitor.ParsePagination(r.Paginate); err != nil {
+			return nil, err
+		}
+		if r.MaxPages < 0 {
+			return nil, fmt.Errorf("max_pages must not be negative")
+		}
+		config.Pagination.Items = r.PaginateItems
+		config.Pagination.MaxPages = r.MaxPages
+	} else if r.PaginateItems != "" || r.MaxPages != 0 {
+		return nil, fmt.Errorf("paginate_items and max_pages require paginate")
+	}
+
+	if r.Extract != "" {
+		if method != monitor.MethodValue {
+			return nil, fmt.Errorf("extract requires method 'value'")
+		}
+		if config.Extract, err = monitor.ParseExtractor(r.Extract); err != nil {
+			return nil, err
+		}
+	}
+	if r.Below != nil {
+		config.Thresholds = append(config.Thresholds, monitor.Threshold{Value: *r.Below})
+	}
+	if r.Above != nil {
+		config.Thresholds = append(config.Thresholds, monitor.Threshold{Value: *r.Above, Above: true})
+	}
+	if r.Delta < 0 || r.DeltaPercent < 0 {
+		return nil, fmt.Errorf("delta and delta_percent must not be negative")
+	}
+	config.Delta = r.Delta
+	config.DeltaPercent = r.DeltaPercent
+
+	config.Headers = r.Headers
+	config.Baggage = r.Baggage
+	config.Labels = r.Labels
+	config.IgnoreSelectors = r.Ignore
+	config.WatchSelectors = r.Select
+	config.AlertSelectors = r.Alert
+	config.NormalizeWhitespace = r.NormalizeWhitespace
+	config.IgnoreTimestamps = r.IgnoreTimestamps
+	config.NoCache = r.NoCache
+	config.CacheBust = r.CacheBust
+	config.CaptureHAR = r.CaptureHAR
+	config.AppendOnly = r.AppendOnly
+
+	return config, nil
+}
+
+// newMonitorInfo builds the API representation of a monitor
+func newMonitorInfo(m *monitor.Monitor) MonitorInfo {
+	config := m.GetConfig()
+	lastCheck, status, checkCount := m.GetStatus()
+
+	info := MonitorInfo{
+		URL:        config.URL,
+		Interval:   config.Interval.String(),
+		Method:     config.Method.String(),
+		Status:     status,
+		Paused:     m.IsPaused(),
+		Labels:     config.Labels,
+		LastCheck:  lastCheck,
+		CheckCount: checkCount,
+		LastError:  m.LastError(),
+	}
+	info.Failures, info.Failing = m.Failures()
+	if latency := m.Latency(); latency > 0 {
+		info.Latency = latency.String()
+	}
+
+	if config.Schedule != nil {
+		info.Schedule = config.Schedule.String()
+	}
+	if next := m.NextCheck(); !next.IsZero() {
+		info.NextCheck = &next
+	}
+
+	return info
+}
+
+// handleListMonitors handles GET /monitors, optionally with
+// ?selector=... to list the monitors whose labels match it
+func (s *Server) handleListMonitors(w http.ResponseWriter, r *http.Request) {
+	urls := s.manager.ListMonitors()
+	sort.Strings(urls)
+	if r.URL.Query().Has("selector") {
+		selector, err := monitor.ParseLabelSelector(r.URL.Query().Get("selector"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		urls = s.manager.MonitorsByLabel(selector)
+	}
+
+	duplicates := make(map[string][]string)
+	for _, group := range s.manager.Duplicates() {
+		for _, url := range group {
+			duplicates[url] = slices.DeleteFunc(slices.Clone(group), func(other string) bool { return other == url })
+		}
+	}
+
+	monitors := make([]MonitorInfo, 0, len(urls))
+	for _, url := range urls {
+		m, err := s.manager.GetMonitor(url)
+		if err != nil {
+			// Removed while listing
+			continue
+		}
+		info := newMonitorInfo(m)
+		info.DuplicateOf = duplicates[url]
+		monitors = append(monitors, info)
+	}
+
+	writeJSON(w, http.StatusOK, monitors)
+}
+
+// handleCreateMonitor handles POST /monitors
+func (s *Server) handleCreateMonitor(w http.ResponseWriter, r *http.Request) {
+	var req MonitorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	config, err := req.toConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	m, err := s.manager.AddMonitorWithConfig(config)
+	if errors.Is(err, monitor.ErrMonitorExists) {
+		writeError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// A monitor that can't join its group isn't kept
+	if err := s.addToGroup(config.URL, req.Group); err != nil {
+		s.manager.RemoveMonitor(config.URL)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	details := ""
+	if req.Group != "" {
+		details = "group " + req.Group
+	}
+	s.audit(r, audit.ActionAdd, config.URL, details)
+	writeJSON(w, http.StatusCreated, newMonitorInfo(m))
+}
+
+// addToGroup adds a monitor to a group, creating the group if needed. An
+// empty group name adds it to none.
+func (s *Server) addToGroup(url, group string) error {
+	if group == "" {
+		return nil
+	}
+	if _, err := s.manager.GetGroup(group); err != nil {
+		if _, err := s.manager.CreateGroup(group, "Created via API"); err != nil {
+			return err
+		}
+	}
+	return s.manager.AddToGroup(url, group)
+}
+
+// handleDeleteMonitor handles DELETE /monitors?url=...
+func (s *Server) handleDeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, http.StatusBadRequest, monitor.ErrURLEmpty)
+		return
+	}
+
+	if err := s.manager.RemoveMonitor(url); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.audit(r, audit.ActionRemove, url, "")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUpdateMonitor handles PATCH /monitors?url=..., which changes the
+// HTTP client settings of a monitor without resetting its baseline
+func (s *Server) handleUpdateMonitor(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, http.StatusBadRequest, monitor.ErrURLEmpty)
+		return
+	}
+
+	m, err := s.manager.GetMonitor(url)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	var req ClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	settings := m.ClientSettings()
+	var changed []string
+	if req.Timeout != nil {
+		if settings.Timeout, err = time.ParseDuration(*req.Timeout); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout: %w", err))
+			return
+		}
+		changed = append(changed, "timeout "+*req.Timeout)
+	}
+	if req.FollowRedirects != nil {
+		settings.FollowRedirects = *req.FollowRedirects
+		changed = append(changed, fmt.Sprintf("follow_redirects %t", *req.FollowRedirects))
+	}
+	if req.Proxy != nil {
+		settings.ProxyURL = nil
+		if *req.Proxy != "" {
+			if settings.ProxyURL, err = customhttp.ParseProxyURL(*req.Proxy); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		// Proxy URLs may hold credentials, so the audit log only notes the change
+		changed = append(changed, "proxy")
+	}
+
+	if err := s.manager.UpdateMonitor(url, settings); errors.Is(err, monitor.ErrNotSaved) {
+		// The settings apply to the running monitor but weren't saved
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.audit(r, audit.ActionConfig, url, strings.Join(changed, ", "))
+
+	writeJSON(w, http.StatusOK, newMonitorInfo(m))
+}
+
+// handlePauseMonitor handles POST /monitors/pause?url=... and
+// POST /monitors/resume?url=..., or ?selector=... for the monitors whose
+// labels match it
+func (s *Server) handlePauseMonitor(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("selector") {
+			s.pauseByLabel(w, r, pause)
+			return
+		}
+
+		url := r.URL.Query().Get("url")
+		if url == "" {
+			writeError(w, http.StatusBadRequest, monitor.ErrURLEmpty)
+			return
+		}
+
+		m, err := s.manager.GetMonitor(url)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		if pause {
+			m.Pause()
+			s.audit(r, audit.ActionPause, url, "")
+		} else {
+			m.Resume()
+			s.audit(r, audit.ActionResume, url, "")
+		}
+
+		writeJSON(w, http.StatusOK, newMonitorInfo(m))
+	}
+}
+
+// pauseByLabel pauses or resumes the monitors whose labels match the
+// selector of the request, and responds with them
+func (s *Server) pauseByLabel(w http.ResponseWriter, r *http.Request, pause bool) {
+	selector, err := monitor.ParseLabelSelector(r.URL.Query().Get("selector"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var urls []string
+	action := audit.ActionResume
+	if pause {
+		action = audit.ActionPause
+		urls, err = s.manager.PauseByLabel(selector)
+	} else {
+		urls, err = s.manager.ResumeByLabel(selector)
+	}
+	if err != nil {
+		// The monitors were paused or resumed but the state wasn't saved
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	monitors := make([]MonitorInfo, 0, len(urls))
+	for _, url := range urls {
+		s.audit(r, action, url, "selector "+selector.String())
+		if m, err := s.manager.GetMonitor(url); err == nil {
+			monitors = append(monitors, newMonitorInfo(m))
+		}
+	}
+	writeJSON(w, http.StatusOK, monitors)
+}
+
+// handleResetBaseline handles POST /monitors/reset?url=...
+func (s *Server) handleResetBaseline(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, http.StatusBadRequest, monitor.ErrURLEmpty)
+		return
+	}
+
+	if err := s.manager.ResetBaseline(url); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.audit(r, audit.ActionReset, url, "")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListCounts handles GET /monitors/counts?url=..., which returns the
+// keyword counts of a monitor with method count, oldest first
+func (s *Server) handleListCounts(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, http.StatusBadRequest, monitor.ErrURLEmpty)
+		return
+	}
+
+	m, err := s.manager.GetMonitor(url)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m.CountSeries())
+}
+
+// handleTrigger handles POST /trigger?url=...&group=..., which checks the
+// given monitors and groups right away. Both parameters may be repeated. The
+// checks run in the background; their results are recorded like any other.
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if len(query["url"]) == 0 && len(query["group"]) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("url or group is required"))
+		return
+	}
+
+	// Resolve everything first so an unknown name triggers nothing
+	monitors := make(map[string]*monitor.Monitor)
+	for _, url := range query["url"] {
+		m, err := s.manager.GetMonitor(url)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		monitors[url] = m
+	}
+	for _, name := range query["group"] {
+		group, err := s.manager.GetGroup(name)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		for url, m := range group.Monitors {
+			monitors[url] = m
+		}
+	}
+
+	resp := TriggerResponse{Triggered: []string{}}
+	for url, m := range monitors {
+		if err := m.Trigger(); err != nil {
+			resp.Skipped = append(resp.Skipped, url)
+			continue
+		}
+		resp.Triggered = append(resp.Triggered, url)
+	}
+	sort.Strings(resp.Triggered)
+	sort.Strings(resp.Skipped)
+
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// MaxPayloadSize is the largest payload accepted by webhook monitors
+const MaxPayloadSize = 1 << 20
+
+// handleHook handles POST /hooks/{name}, which delivers the body as a
+// payload to the webhook monitor webhook://name, see monitor.IsWebhookURL.
+// Requests for a monitor with a webhook secret must be signed with it as
+// hawkeye signs its webhook notifications, see notify.VerifySignature, or
+// carry it as a bearer token; others need a key with the write role.
+func (s *Server) handleHook(w http.ResponseWriter, r *http.Request) {
+	m, err := s.manager.GetMonitor(monitor.WebhookURL(r.PathValue("name")))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPayloadSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("payload larger than %d bytes", MaxPayloadSize))
+		return
+	}
+
+	receive := func(w http.ResponseWriter, r *http.Request) {
+		err := m.Receive(content, r.Header.Get("Content-Type"))
+		switch {
+		case errors.Is(err, monitor.ErrWebhookBusy):
+			writeError(w, http.StatusServiceUnavailable, err)
+		case err != nil:
+			writeError(w, http.StatusConflict, err)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+
+	if m.GetConfig().WebhookSecret == "" {
+		s.require(RoleWrite, receive)(w, r)
+		return
+	}
+	key, err := secret.Resolve(m.GetConfig().WebhookSecret)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("webhook secret %w", err))
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !(ok && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1) &&
+		notify.VerifySignature(key, r.Header, content, notify.DefaultSignatureTolerance, time.Now()) != nil {
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid signature"))
+		return
+	}
+	receive(w, r)
+}
+
+// handlePauseGroup handles POST /groups/{name}/pause and
+// POST /groups/{name}/resume
+func (s *Server) handlePauseGroup(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+
+		var err error
+		action := audit.ActionResume
+		if pause {
+			action = audit.ActionPause
+			err = s.manager.PauseGroup(name)
+		} else {
+			err = s.manager.ResumeGroup(name)
+		}
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		s.audit(r, action, audit.GroupTarget(name), "")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleListGroups handles GET /groups
+func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	names := s.manager.ListGroups()
+	sort.Strings(names)
+
+	groups := make([]GroupInfo, 0, len(names))
+	for _, name := range names {
+		group, err := s.manager.GetGroup(name)
+		if err != nil {
+			continue
+		}
+
+		urls := make([]string, 0, len(group.Monitors))
+		for url := range group.Monitors {
+			urls = append(urls, url)
+		}
+		sort.Strings(urls)
+
+		groups = append(groups, GroupInfo{
+			Name:        group.Name,
+			Description: group.Description,
+			Monitors:    urls,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// handleListChanges handles GET /changes?url=...&limit=...
+func (s *Server) handleListChanges(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit '%s'", value))
+			return
+		}
+		limit = parsed
+	}
+
+	writeJSON(w, http.StatusOK, s.history.List(r.URL.Query().Get("url"), limit))
+}
+
+// MaxAttachmentSize is the largest attachment accepted through the API
+const MaxAttachmentSize = 32 << 20
+
+// handleListVersions handles GET /archive?url=..., which lists the archived
+// versions of a page and their attachments, oldest first
+func (s *Server) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if !s.checkArchive(w, url) {
+		return
+	}
+
+	versions, err := s.options.Archive.Versions(url)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if versions == nil {
+		versions = []archive.Version{}
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// handleGetAttachment handles GET /archive/attachments?url=...&name=...&at=...,
+// which returns the content of an attachment of the version current at the
+// given time, the latest by default
+func (s *Server) handleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if !s.checkArchive(w, url) {
+		return
+	}
+	version, ok := s.archivedVersion(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	attachment, found := version.Attachment(name)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no attachment '%s' of the version of %s seen at %s", name, url, version.Time.Format(time.RFC3339)))
+		return
+	}
+	content, err := s.options.Archive.ReadAttachment(attachment)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Write(content)
+}
+
+// handleAddAttachment handles POST /archive/attachments?url=...&name=...&at=...,
+// which attaches the request body to the version current at the given time,
+// the latest by default. The Content-Type header of the request is kept.
+func (s *Server) handleAddAttachment(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if !s.checkArchive(w, url) {
+		return
+	}
+	version, ok := s.archivedVersion(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxAttachmentSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("attachment larger than %d bytes", MaxAttachmentSize))
+		return
+	}
+	attachment, err := s.options.Archive.Attach(url, version, r.URL.Query().Get("name"), content, r.Header.Get("Content-Type"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+// checkArchive reports whether the server has an archive and url is set,
+// writing an error response otherwise
+func (s *Server) checkArchive(w http.ResponseWriter, url string) bool {
+	if s.options.Archive == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no archive, start the server with --archive"))
+		return false
+	}
+	if url == "" {
+		writeError(w, http.StatusBadRequest, monitor.ErrURLEmpty)
+		return false
+	}
+	return true
+}
+
+// archivedVersion returns the version of the url parameter that was current
+// at the time of the at parameter, or the latest one, writing an error
+// response if there is none
+func (s *Server) archivedVersion(w http.ResponseWriter, r *http.Request) (archive.Version, bool) {
+	at := time.Now()
+	if value := r.URL.Query().Get("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid at '%s': must be an RFC 3339 time", value))
+			return archive.Version{}, false
+		}
+		at = parsed
+	}
+
+	version, err := s.options.Archive.At(r.URL.Query().Get("url"), at)
+	if errors.Is(err, archive.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return archive.Version{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return archive.Version{}, false
+	}
+	return version, true
+}
+
+// handleStreamChanges handles GET /changes/stream using server-sent events
+func (s *Server) handleStreamChanges(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	filter := r.URL.Query().Get("url")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+
+	for {
+		select {
+		case change := <-ch:
+			if filter != "" && change.URL != filter {
+				continue
+			}
+
+			data, err := json.Marshal(change)
+			if err != nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleHealth handles GET /health. It responds with 503 Service Unavailable
+// when the monitors are not running or some have stalled, so it can be used
+// as a liveness probe.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := s.manager.Health()
+
+	status := http.StatusOK
+	if !health.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// handleReady handles GET /ready. It responds with 503 Service Unavailable
+// until every monitor that is due has done its first check, so it can be used
+// as a readiness probe.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	health := s.manager.Health()
+
+	status := http.StatusOK
+	if !health.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
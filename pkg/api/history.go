@@ -0,0 +1,70 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// DefaultHistorySize is the number of changes kept when no size is configured
+const DefaultHistorySize = 1000
+
+// History is a bounded, in-memory record of recent changes
+type History struct {
+	mu      sync.RWMutex
+	entries []monitor.Change
+	size    int
+}
+
+// NewHistory creates a new History that keeps at most size changes
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+
+	return &History{
+		entries: make([]monitor.Change, 0, size),
+		size:    size,
+	}
+}
+
+// Add records a change, discarding the oldest entry when the history is full
+func (h *History) Add(change monitor.Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.entries) >= h.size {
+		copy(h.entries, h.entries[1:])
+		h.entries = h.entries[:len(h.entries)-1]
+	}
+
+	h.entries = append(h.entries, change)
+}
+
+// List returns recorded changes, newest first. If url is not empty only
+// changes for that URL are returned. A limit of zero or less means no limit.
+func (h *History) List(url string, limit int) []monitor.Change {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make([]monitor.Change, 0)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if url != "" && h.entries[i].URL != url {
+			continue
+		}
+
+		result = append(result, h.entries[i])
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+
+	return result
+}
+
+// Len returns the number of recorded changes
+func (h *History) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.entries)
+}
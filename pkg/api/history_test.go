@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryAddAndList(t *testing.T) {
+	history := NewHistory(3)
+
+	for i := 0; i < 5; i++ {
+		history.Add(monitor.Change{URL: fmt.Sprintf("https://example-%d.com", i%2)})
+	}
+
+	// Only the three newest changes are kept
+	require.Equal(t, 3, history.Len())
+
+	changes := history.List("", 0)
+	require.Len(t, changes, 3)
+	require.Equal(t, "https://example-0.com", changes[0].URL)
+	require.Equal(t, "https://example-1.com", changes[1].URL)
+
+	// Filter by URL
+	changes = history.List("https://example-0.com", 0)
+	require.Len(t, changes, 2)
+
+	// Limit results
+	changes = history.List("", 1)
+	require.Len(t, changes, 1)
+}
+
+func TestNewHistoryDefaultSize(t *testing.T) {
+	history := NewHistory(0)
+	require.Equal(t, DefaultHistorySize, history.size)
+	require.Empty(t, history.List("", 0))
+}
@@ -0,0 +1,230 @@
+// Package api exposes a monitor.Manager over a JSON HTTP API so hawkeye can
+// be embedded into dashboards and automation pipelines.
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// ActorHeader names who requests an action, for the audit log. Requests
+// without it are recorded with the address of the client, and requests with
+// an API key with the name of the key.
+const ActorHeader = "X-Hawkeye-Actor"
+
+// Options configures the API server
+type Options struct {
+	// Addr is the address to listen on, e.g. ":8080"
+	Addr string
+	// HistorySize is the number of changes kept in memory
+	HistorySize int
+	// OnChange, if set, is called with every change after it is recorded,
+	// e.g. to send notifications. It must not block for long.
+	OnChange func(monitor.Change)
+	// Audit, if set, records the monitors added, removed, paused and
+	// resumed through the API
+	Audit *audit.Log
+	// Keys, if set, are required by every endpoint but /health and /ready,
+	// and their roles limit what each key may do
+	Keys []Key
+	// Archive, if set, is the archive of page versions served under
+	// /archive, where attachments can be added to versions
+	Archive *archive.Archive
+	// Composites, if set, raises changes from those of the monitors, which
+	// are recorded and streamed like theirs
+	Composites *monitor.Composites
+}
+
+// DefaultOptions returns default server options
+func DefaultOptions() *Options {
+	return &Options{
+		Addr:        ":8080",
+		HistorySize: DefaultHistorySize,
+	}
+}
+
+// Server serves the hawkeye HTTP API on top of a Manager
+type Server struct {
+	manager     *monitor.Manager
+	history     *History
+	options     Options
+	mux         *http.ServeMux
+	mu          sync.RWMutex
+	running     bool
+	subscribers map[chan monitor.Change]struct{}
+}
+
+// NewServer creates a new API server for the given manager
+func NewServer(manager *monitor.Manager, opts *Options) *Server {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	s := &Server{
+		manager:     manager,
+		history:     NewHistory(opts.HistorySize),
+		options:     *opts,
+		mux:         http.NewServeMux(),
+		subscribers: make(map[chan monitor.Change]struct{}),
+	}
+
+	s.routes()
+	return s
+}
+
+// routes registers the API endpoints
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /monitors", s.require(RoleRead, s.handleListMonitors))
+	s.mux.HandleFunc("POST /monitors", s.require(RoleWrite, s.handleCreateMonitor))
+	s.mux.HandleFunc("DELETE /monitors", s.require(RoleManage, s.handleDeleteMonitor))
+	s.mux.HandleFunc("PATCH /monitors", s.require(RoleWrite, s.handleUpdateMonitor))
+	s.mux.HandleFunc("POST /monitors/pause", s.require(RoleWrite, s.handlePauseMonitor(true)))
+	s.mux.HandleFunc("POST /monitors/resume", s.require(RoleWrite, s.handlePauseMonitor(false)))
+	s.mux.HandleFunc("POST /monitors/reset", s.require(RoleManage, s.handleResetBaseline))
+	s.mux.HandleFunc("GET /monitors/counts", s.require(RoleRead, s.handleListCounts))
+	s.mux.HandleFunc("POST /trigger", s.require(RoleWrite, s.handleTrigger))
+	// Webhook monitors may authenticate with their own secret
+	s.mux.HandleFunc("POST /hooks/{name}", s.handleHook)
+	s.mux.HandleFunc("GET /groups", s.require(RoleRead, s.handleListGroups))
+	s.mux.HandleFunc("POST /groups/{name}/pause", s.require(RoleWrite, s.handlePauseGroup(true)))
+	s.mux.HandleFunc("POST /groups/{name}/resume", s.require(RoleWrite, s.handlePauseGroup(false)))
+	s.mux.HandleFunc("GET /changes", s.require(RoleRead, s.handleListChanges))
+	s.mux.HandleFunc("GET /changes/stream", s.require(RoleRead, s.handleStreamChanges))
+	s.mux.HandleFunc("GET /archive", s.require(RoleRead, s.handleListVersions))
+	s.mux.HandleFunc("GET /archive/attachments", s.require(RoleRead, s.handleGetAttachment))
+	s.mux.HandleFunc("POST /archive/attachments", s.require(RoleWrite, s.handleAddAttachment))
+	// Probes don't carry keys
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /ready", s.handleReady)
+}
+
+// Handler returns the HTTP handler for the API
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// History returns the change history recorded by the server
+func (s *Server) History() *History {
+	return s.history
+}
+
+// Start starts all monitors known to the manager and begins recording changes.
+// Monitors created through the API after Start are started immediately by
+// the manager.
+func (s *Server) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	changes := s.manager.Start()
+	if s.options.Composites != nil {
+		changes = s.options.Composites.Watch(changes)
+	}
+	go s.consume(changes)
+}
+
+// IsRunning reports whether the server has started its monitors
+func (s *Server) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+// ListenAndServe starts the monitors and serves the API until ctx is canceled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.Start()
+
+	httpServer := &http.Server{
+		Addr:              s.options.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		return httpServer.Shutdown(shutdownCtx)
+	}
+}
+
+// audit records an action requested through the API. The action was already
+// done, so a failure to record it doesn't fail the request.
+func (s *Server) audit(r *http.Request, action audit.Action, target, details string) {
+	actor := r.Header.Get(ActorHeader)
+	if key, ok := r.Context().Value(keyContext{}).(*Key); ok {
+		// The key is verified, unlike the header
+		actor = key.Name
+	} else if actor == "" {
+		actor = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			actor = host
+		}
+	}
+	s.options.Audit.Record(audit.Entry{Actor: actor, Source: audit.SourceAPI, Action: action, Target: target, Details: details})
+}
+
+// consume records changes from the manager and fans them out to subscribers
+func (s *Server) consume(changes <-chan monitor.Change) {
+	for change := range changes {
+		s.history.Add(change)
+		s.broadcast(change)
+		if s.options.OnChange != nil {
+			s.options.OnChange(change)
+		}
+	}
+}
+
+// broadcast sends a change to all stream subscribers without blocking
+func (s *Server) broadcast(change monitor.Change) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- change:
+		default:
+			// Drop the change for slow subscribers rather than stalling monitors
+		}
+	}
+}
+
+// subscribe registers a new stream subscriber
+func (s *Server) subscribe() chan monitor.Change {
+	ch := make(chan monitor.Change, 16)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch
+}
+
+// unsubscribe removes a stream subscriber
+func (s *Server) unsubscribe(ch chan monitor.Change) {
+	s.mu.Lock()
+	delete(s.subscribers, ch)
+	s.mu.Unlock()
+}
@@ -0,0 +1,645 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/archive"
+	"github.com/nemuizzz/hawkeye/pkg/audit"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	server := NewServer(monitor.NewManager(), nil)
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+func postMonitor(t *testing.T, ts *httptest.Server, req MonitorRequest) *http.Response {
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	resp, err := http.Post(ts.URL+"/monitors", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	return resp
+}
+
+func TestCreateAndListMonitors(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp := postMonitor(t, ts, MonitorRequest{
+		URL:      "https://example.com",
+		Interval: "1m",
+		Method:   "length",
+		Group:    "docs",
+	})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created MonitorInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	require.Equal(t, "https://example.com", created.URL)
+	require.Equal(t, "1m0s", created.Interval)
+	require.Equal(t, "length", created.Method)
+
+	// Creating the same monitor again conflicts
+	dup := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m"})
+	dup.Body.Close()
+	require.Equal(t, http.StatusConflict, dup.StatusCode)
+
+	// List monitors
+	listResp, err := http.Get(ts.URL + "/monitors")
+	require.NoError(t, err)
+	defer listResp.Body.Close()
+
+	var monitors []MonitorInfo
+	require.NoError(t, json.NewDecoder(listResp.Body).Decode(&monitors))
+	require.Len(t, monitors, 1)
+
+	// List groups
+	groupResp, err := http.Get(ts.URL + "/groups")
+	require.NoError(t, err)
+	defer groupResp.Body.Close()
+
+	var groups []GroupInfo
+	require.NoError(t, json.NewDecoder(groupResp.Body).Decode(&groups))
+	require.Len(t, groups, 1)
+	require.Equal(t, "docs", groups[0].Name)
+	require.Equal(t, []string{"https://example.com"}, groups[0].Monitors)
+}
+
+func TestCreateMonitorValidation(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name string
+		req  MonitorRequest
+	}{
+		{name: "empty URL", req: MonitorRequest{Interval: "1m"}},
+		{name: "bad interval", req: MonitorRequest{URL: "https://example.com", Interval: "soon"}},
+		{name: "zero interval", req: MonitorRequest{URL: "https://example.com", Interval: "0s"}},
+		{name: "unknown method", req: MonitorRequest{URL: "https://example.com", Method: "magic"}},
+		{name: "custom method", req: MonitorRequest{URL: "https://example.com", Method: "custom"}},
+		{name: "bad schedule", req: MonitorRequest{URL: "https://example.com", Schedule: "every day"}},
+		{name: "keyword without matches", req: MonitorRequest{URL: "https://example.com", Method: "keyword"}},
+		{name: "match without keyword", req: MonitorRequest{URL: "https://example.com", Method: "hash", Match: []string{"in stock"}}},
+		{name: "representations with status method", req: MonitorRequest{URL: "https://example.com", Method: "status", Representations: []string{"application/json"}}},
+		{name: "bad proxy", req: MonitorRequest{URL: "https://example.com", Proxy: "ftp://proxy.example.com"}},
+		{name: "image threshold without image", req: MonitorRequest{URL: "https://example.com", Method: "hash", ImageThreshold: 1}},
+		{name: "image threshold over 100", req: MonitorRequest{URL: "https://example.com", ImageThreshold: 150}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := postMonitor(t, ts, tc.req)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+			var body errorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			require.NotEmpty(t, body.Error)
+		})
+	}
+}
+
+// groupFailingStore fails to save states in which a group has monitors
+type groupFailingStore struct{}
+
+func (groupFailingStore) Load() (*monitor.State, error) { return nil, nil }
+
+func (groupFailingStore) Save(state *monitor.State) error {
+	for _, group := range state.Groups {
+		if len(group.URLs) > 0 {
+			return errors.New("disk full")
+		}
+	}
+	return nil
+}
+
+func TestCreateMonitorGroupFails(t *testing.T) {
+	manager := monitor.NewManager()
+	require.NoError(t, manager.SetStore(groupFailingStore{}))
+	ts := httptest.NewServer(NewServer(manager, nil).Handler())
+	defer ts.Close()
+
+	// The monitor isn't kept when it can't be added to its group
+	resp := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m", Group: "shop"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	_, err := manager.GetMonitor("https://example.com")
+	require.Error(t, err)
+
+	resp = postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestCreateScheduledMonitor(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Schedule: "0 9 * * mon-fri"})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created MonitorInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	require.Equal(t, "0 9 * * mon-fri", created.Schedule)
+}
+
+func TestDeleteMonitor(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/monitors?url=https://example.com", nil)
+	require.NoError(t, err)
+
+	delResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	delResp.Body.Close()
+	require.Equal(t, http.StatusNoContent, delResp.StatusCode)
+
+	// Deleting again is not found
+	delResp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	delResp.Body.Close()
+	require.Equal(t, http.StatusNotFound, delResp.StatusCode)
+}
+
+func TestPauseAndResume(t *testing.T) {
+	server, ts := newTestServer(t)
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m", Group: "docs"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	pauseResp, err := http.Post(ts.URL+"/monitors/pause?url=https://example.com", "", nil)
+	require.NoError(t, err)
+	defer pauseResp.Body.Close()
+	require.Equal(t, http.StatusOK, pauseResp.StatusCode)
+
+	var info MonitorInfo
+	require.NoError(t, json.NewDecoder(pauseResp.Body).Decode(&info))
+	require.True(t, info.Paused)
+
+	groupResp, err := http.Post(ts.URL+"/groups/docs/resume", "", nil)
+	require.NoError(t, err)
+	groupResp.Body.Close()
+	require.Equal(t, http.StatusNoContent, groupResp.StatusCode)
+
+	m, err := server.manager.GetMonitor("https://example.com")
+	require.NoError(t, err)
+	require.False(t, m.IsPaused())
+
+	// Unknown monitors and groups are not found
+	missing, err := http.Post(ts.URL+"/monitors/pause?url=https://missing.example.com", "", nil)
+	require.NoError(t, err)
+	missing.Body.Close()
+	require.Equal(t, http.StatusNotFound, missing.StatusCode)
+
+	missing, err = http.Post(ts.URL+"/groups/missing/pause", "", nil)
+	require.NoError(t, err)
+	missing.Body.Close()
+	require.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
+
+func TestPauseByLabel(t *testing.T) {
+	server, ts := newTestServer(t)
+
+	for url, env := range map[string]string{"https://staging.example.com": "staging", "https://www.example.com": "production"} {
+		resp := postMonitor(t, ts, MonitorRequest{URL: url, Interval: "1m", Labels: map[string]string{"env": env}})
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	pauseResp, err := http.Post(ts.URL+"/monitors/pause?selector=env%3Dstaging", "", nil)
+	require.NoError(t, err)
+	defer pauseResp.Body.Close()
+	require.Equal(t, http.StatusOK, pauseResp.StatusCode)
+
+	var paused []MonitorInfo
+	require.NoError(t, json.NewDecoder(pauseResp.Body).Decode(&paused))
+	require.Len(t, paused, 1)
+	require.Equal(t, "https://staging.example.com", paused[0].URL)
+	require.True(t, paused[0].Paused)
+	require.Equal(t, map[string]string{"env": "staging"}, paused[0].Labels)
+
+	m, err := server.manager.GetMonitor("https://www.example.com")
+	require.NoError(t, err)
+	require.False(t, m.IsPaused())
+
+	listResp, err := http.Get(ts.URL + "/monitors?selector=env!%3Dstaging")
+	require.NoError(t, err)
+	defer listResp.Body.Close()
+	var listed []MonitorInfo
+	require.NoError(t, json.NewDecoder(listResp.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	require.Equal(t, "https://www.example.com", listed[0].URL)
+
+	invalid, err := http.Post(ts.URL+"/monitors/resume?selector=%3Dstaging", "", nil)
+	require.NoError(t, err)
+	invalid.Body.Close()
+	require.Equal(t, http.StatusBadRequest, invalid.StatusCode)
+}
+
+func TestUpdateMonitor(t *testing.T) {
+	server, ts := newTestServer(t)
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	patch := func(url, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+"/monitors?url="+url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp = patch("https://example.com", `{"timeout": "5s", "follow_redirects": false, "proxy": "http://proxy.example.com:8080"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	m, err := server.manager.GetMonitor("https://example.com")
+	require.NoError(t, err)
+	settings := m.ClientSettings()
+	require.Equal(t, 5*time.Second, settings.Timeout)
+	require.False(t, settings.FollowRedirects)
+	require.Equal(t, "http://proxy.example.com:8080", settings.ProxyURL.String())
+
+	// Omitted settings are kept and an empty proxy clears it
+	resp = patch("https://example.com", `{"proxy": ""}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	settings = m.ClientSettings()
+	require.Equal(t, 5*time.Second, settings.Timeout)
+	require.Nil(t, settings.ProxyURL)
+
+	for _, body := range []string{`{"timeout": "soon"}`, `{"timeout": "-1s"}`, `{"proxy": "ftp://proxy.example.com"}`, `[`} {
+		require.Equal(t, http.StatusBadRequest, patch("https://example.com", body).StatusCode, body)
+	}
+	require.Equal(t, http.StatusNotFound, patch("https://missing.example.com", `{"timeout": "5s"}`).StatusCode)
+}
+
+func TestAudit(t *testing.T) {
+	log := audit.NewLog(filepath.Join(t.TempDir(), "audit.log"))
+	server := NewServer(monitor.NewManager(), &Options{Audit: log})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1m", Group: "docs"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/groups/docs/pause", nil)
+	require.NoError(t, err)
+	req.Header.Set(ActorHeader, "alice")
+	pauseResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	pauseResp.Body.Close()
+
+	// Failed actions are not recorded
+	missing, err := http.Post(ts.URL+"/monitors/pause?url=https://missing.example.com", "", nil)
+	require.NoError(t, err)
+	missing.Body.Close()
+
+	entries, err := log.Entries(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, audit.ActionAdd, entries[0].Action)
+	require.Equal(t, "https://example.com", entries[0].Target)
+	require.Equal(t, "127.0.0.1", entries[0].Actor)
+	require.Equal(t, audit.SourceAPI, entries[0].Source)
+	require.Equal(t, audit.ActionPause, entries[1].Action)
+	require.Equal(t, "group:docs", entries[1].Target)
+	require.Equal(t, "alice", entries[1].Actor)
+}
+
+func TestListChanges(t *testing.T) {
+	server, ts := newTestServer(t)
+
+	server.History().Add(monitor.Change{URL: "https://a.example.com", HasChanged: true})
+	server.History().Add(monitor.Change{URL: "https://b.example.com", HasChanged: true})
+	server.History().Add(monitor.Change{URL: "https://a.example.com", Error: "boom"})
+
+	resp, err := http.Get(ts.URL + "/changes?url=https://a.example.com&limit=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var changes []monitor.Change
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&changes))
+	require.Len(t, changes, 1)
+	require.Equal(t, "boom", changes[0].Error)
+
+	badResp, err := http.Get(ts.URL + "/changes?limit=abc")
+	require.NoError(t, err)
+	badResp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, badResp.StatusCode)
+}
+
+func TestArchiveAttachments(t *testing.T) {
+	store := archive.New(t.TempDir(), archive.Retention{})
+	seen := time.Now().Add(-time.Hour).UTC()
+	require.NoError(t, store.Save("https://example.com", seen, []byte("v1"), "text/html"))
+	require.NoError(t, store.Save("https://example.com", seen.Add(time.Minute), []byte("v2"), "text/html"))
+	server := NewServer(monitor.NewManager(), &Options{Archive: store})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	page := url.QueryEscape("https://example.com")
+
+	// Attach to the first version
+	resp, err := http.Post(ts.URL+"/archive/attachments?url="+page+"&name=shot.png&at="+url.QueryEscape(seen.Format(time.RFC3339Nano)), "image/png", strings.NewReader("png"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/archive?url=" + page)
+	require.NoError(t, err)
+	var versions []archive.Version
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&versions))
+	resp.Body.Close()
+	require.Len(t, versions, 2)
+	require.Len(t, versions[0].Attachments, 1)
+	require.Empty(t, versions[1].Attachments)
+
+	resp, err = http.Get(ts.URL + "/archive/attachments?url=" + page + "&name=shot.png&at=" + url.QueryEscape(seen.Add(time.Second).Format(time.RFC3339)))
+	require.NoError(t, err)
+	content, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, "png", string(content))
+
+	// The latest version has no attachments
+	resp, err = http.Get(ts.URL + "/archive/attachments?url=" + page + "&name=shot.png")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Without an archive there is nothing to serve
+	_, plain := newTestServer(t)
+	resp, err = http.Get(plain.URL + "/archive?url=" + page)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestListCounts(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Go developer, Go developer, Designer"))
+	}))
+	defer site.Close()
+	server, ts := newTestServer(t)
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: site.URL, Interval: "1h", Count: []string{"Go developer"}})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	m, err := server.manager.GetMonitor(site.URL)
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodCount, m.GetConfig().Method)
+	m.Check()
+
+	countsResp, err := http.Get(ts.URL + "/monitors/counts?url=" + site.URL)
+	require.NoError(t, err)
+	defer countsResp.Body.Close()
+	var series []monitor.CountSample
+	require.NoError(t, json.NewDecoder(countsResp.Body).Decode(&series))
+	require.Len(t, series, 1)
+	require.Equal(t, map[string]int{"Go developer": 2}, series[0].Counts)
+
+	missing, err := http.Get(ts.URL + "/monitors/counts?url=https://unknown.example.com")
+	require.NoError(t, err)
+	missing.Body.Close()
+	require.Equal(t, http.StatusNotFound, missing.StatusCode)
+
+	// Keywords are only counted with method count
+	invalid := postMonitor(t, ts, MonitorRequest{URL: "https://example.com", Interval: "1h", Method: "hash", Count: []string{"Go"}})
+	invalid.Body.Close()
+	require.Equal(t, http.StatusBadRequest, invalid.StatusCode)
+}
+
+func TestStreamChanges(t *testing.T) {
+	server, ts := newTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/changes/stream", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Wait for the subscription to be registered before broadcasting
+	require.Eventually(t, func() bool {
+		server.mu.RLock()
+		defer server.mu.RUnlock()
+		return len(server.subscribers) == 1
+	}, time.Second, time.Millisecond*10)
+
+	server.broadcast(monitor.Change{URL: "https://example.com", HasChanged: true})
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(line, "data: "))
+
+	var change monitor.Change
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change))
+	require.Equal(t, "https://example.com", change.URL)
+	require.True(t, change.HasChanged)
+}
+
+func TestStartMonitorsCreatedAfterStart(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer target.Close()
+
+	server, ts := newTestServer(t)
+	server.Start()
+	require.True(t, server.IsRunning())
+
+	resp := postMonitor(t, ts, MonitorRequest{URL: target.URL, Interval: "50ms"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// The monitor should start checking without an explicit start call
+	m, err := server.manager.GetMonitor(target.URL)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, _, count := m.GetStatus()
+		return count > 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestTrigger(t *testing.T) {
+	var requests atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("content"))
+	}))
+	defer target.Close()
+
+	server, ts := newTestServer(t)
+	server.manager.SetStagger(false)
+	server.Start()
+
+	for _, path := range []string{"/a", "/b"} {
+		resp := postMonitor(t, ts, MonitorRequest{URL: target.URL + path, Interval: "1h", Group: "site"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+	require.Eventually(t, func() bool { return requests.Load() == 2 }, time.Second, time.Millisecond*10)
+
+	require.NoError(t, server.manager.PauseMonitor(target.URL+"/b"))
+
+	resp, err := http.Post(ts.URL+"/trigger?group=site", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var triggered TriggerResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&triggered))
+	require.Equal(t, []string{target.URL + "/a"}, triggered.Triggered)
+	require.Equal(t, []string{target.URL + "/b"}, triggered.Skipped)
+
+	// The hourly monitor is checked again right away
+	require.Eventually(t, func() bool { return requests.Load() == 3 }, time.Second, time.Millisecond*10)
+
+	for path, status := range map[string]int{
+		"/trigger": http.StatusBadRequest,
+		"/trigger?url=https://missing.example.com": http.StatusNotFound,
+		"/trigger?group=missing":                   http.StatusNotFound,
+	} {
+		resp, err := http.Post(ts.URL+path, "", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode, path)
+	}
+}
+
+func TestHook(t *testing.T) {
+	server, ts := newTestServer(t)
+	server.Start()
+
+	resp := postMonitor(t, ts, MonitorRequest{
+		URL:           "webhook://releases",
+		WebhookMatch:  []string{"json:action=published"},
+		WebhookFields: []string{"release.name"},
+		WebhookSecret: "s3cret",
+	})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	hook := func(path, body string, header http.Header) int {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	bearer := http.Header{"Authorization": {"Bearer s3cret"}}
+
+	require.Equal(t, http.StatusUnauthorized, hook("/hooks/releases", `{"action":"published"}`, http.Header{}))
+	require.Equal(t, http.StatusUnauthorized, hook("/hooks/releases", `{"action":"published"}`, http.Header{"Authorization": {"Bearer guess"}}))
+	require.Equal(t, http.StatusNotFound, hook("/hooks/missing", `{}`, bearer))
+
+	// Drafts don't meet the condition
+	require.Equal(t, http.StatusAccepted, hook("/hooks/releases", `{"action":"draft","release":{"name":"v1.1"}}`, bearer))
+	require.Equal(t, http.StatusAccepted, hook("/hooks/releases", `{"action":"published","release":{"name":"v1.2"}}`, bearer))
+
+	// Hawkeye's own webhook notifications are signed with the secret
+	body := `{"action":"published","release":{"name":"v1.3"}}`
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signed := http.Header{
+		notify.TimestampHeader: {timestamp},
+		notify.NonceHeader:     {"abc"},
+		notify.SignatureHeader: {notify.Sign("s3cret", timestamp, "abc", []byte(body))},
+	}
+	require.Equal(t, http.StatusAccepted, hook("/hooks/releases", body, signed))
+
+	require.Eventually(t, func() bool { return len(server.History().List("webhook://releases", 0)) == 2 }, time.Second, time.Millisecond*10)
+	changes := server.History().List("webhook://releases", 0)
+	require.Equal(t, "release.name: v1.3", changes[0].Details)
+	require.Equal(t, "release.name: v1.2", changes[1].Details)
+
+	// Webhook monitors have nothing to check
+	resp, err := http.Post(ts.URL+"/trigger?url=webhook://releases", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var triggered TriggerResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&triggered))
+	require.Equal(t, []string{"webhook://releases"}, triggered.Skipped)
+}
+
+func TestHealth(t *testing.T) {
+	server, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	server.Start()
+
+	resp, err = http.Get(ts.URL + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var health monitor.Health
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	require.True(t, health.Running)
+}
+
+func TestReady(t *testing.T) {
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("content"))
+	}))
+	defer target.Close()
+
+	server, ts := newTestServer(t)
+	resp := postMonitor(t, ts, MonitorRequest{URL: target.URL, Interval: "1h"})
+	resp.Body.Close()
+	server.Start()
+
+	ready := func() int {
+		resp, err := http.Get(ts.URL + "/ready")
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Not ready until the first check is done
+	require.Equal(t, http.StatusServiceUnavailable, ready())
+	close(release)
+	require.Eventually(t, func() bool { return ready() == http.StatusOK }, time.Second*5, time.Millisecond*10)
+}
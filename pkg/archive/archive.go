@@ -0,0 +1,406 @@
+// Package archive keeps versions of the content of monitored pages on disk.
+// Contents are gzip-compressed and addressed by their SHA-256 hash, so a
+// version that comes back, or that several URLs share, is stored once.
+//
+// Versions can carry attachments, e.g. a screenshot, a HAR file or a
+// rendered PDF of the page, stored like contents.
+//
+// An archive directory holds an index of the versions of each URL and the
+// contents:
+//
+//	index/<hash of the URL>.json
+//	objects/<first 2 hex digits>/<SHA-256 hash>.gz
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when no version of a URL was archived at a time
+var ErrNotFound = errors.New("no version archived")
+
+// Version is an archived version of the content of a URL
+type Version struct {
+	// Time is when the version was first seen
+	Time time.Time `json:"time"`
+	// Hash is the hex SHA-256 hash of the content
+	Hash        string `json:"hash"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+	// Attachments are artifacts kept with the version, see Archive.Attach
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is an artifact attached to a version
+type Attachment struct {
+	// Name identifies the attachment among those of its version
+	Name string `json:"name"`
+	// Hash is the hex SHA-256 hash of the content
+	Hash        string `json:"hash"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Attachment returns the attachment of the version with the given name
+func (v Version) Attachment(name string) (Attachment, bool) {
+	for _, attachment := range v.Attachments {
+		if attachment.Name == name {
+			return attachment, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// Retention limits the versions kept of each URL. MaxVersions keeps only
+// that many of the most recent versions, and MaxAge drops versions that
+// were replaced longer ago. The current version is always kept. Zero
+// values keep every version.
+type Retention struct {
+	MaxVersions int
+	MaxAge      time.Duration
+}
+
+// index lists the versions of a URL, oldest first
+type index struct {
+	URL      string    `json:"url"`
+	Versions []Version `json:"versions"`
+}
+
+// Archive stores versions of contents in a directory. It is safe for
+// concurrent use.
+type Archive struct {
+	dir       string
+	retention Retention
+
+	mu sync.Mutex
+}
+
+// New creates an archive in dir, which is created on the first save
+func New(dir string, retention Retention) *Archive {
+	return &Archive{dir: dir, retention: retention}
+}
+
+// Dir returns the directory of the archive
+func (a *Archive) Dir() string {
+	return a.dir
+}
+
+// Save stores content as the version of url seen at the given time, unless
+// it is the same as the current version, and applies the retention limits.
+// It implements monitor.Archive.
+func (a *Archive) Save(url string, at time.Time, content []byte, contentType string) error {
+	sum := sha256.Sum256(content)
+	version := Version{Time: at.UTC(), Hash: hex.EncodeToString(sum[:]), Size: int64(len(content)), ContentType: contentType}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	idx, err := a.readIndex(url)
+	if err != nil {
+		return err
+	}
+	if n := len(idx.Versions); n > 0 && idx.Versions[n-1].Hash == version.Hash {
+		return nil
+	}
+	if err := a.writeObject(version.Hash, content); err != nil {
+		return err
+	}
+
+	idx.Versions = append(idx.Versions, version)
+	removed := a.prune(idx, time.Now())
+	if err := a.writeIndex(idx); err != nil {
+		return err
+	}
+	return a.collect(removed)
+}
+
+// Versions returns the archived versions of url, oldest first
+func (a *Archive) Versions(url string) ([]Version, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	idx, err := a.readIndex(url)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Versions, nil
+}
+
+// At returns the version of url that was current at t, the last one seen
+// at or before it
+func (a *Archive) At(url string, t time.Time) (Version, error) {
+	versions, err := a.Versions(url)
+	if err != nil {
+		return Version{}, err
+	}
+	i, _ := slices.BinarySearchFunc(versions, t, func(v Version, t time.Time) int {
+		if v.Time.After(t) {
+			return 1
+		}
+		return -1
+	})
+	if i == 0 {
+		return Version{}, fmt.Errorf("%w of %s at %s", ErrNotFound, url, t.Format(time.RFC3339))
+	}
+	return versions[i-1], nil
+}
+
+// Read returns the content of a version, checking it against its hash
+func (a *Archive) Read(version Version) ([]byte, error) {
+	content, err := a.readObject(version.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("version %s: %w", version.Hash, err)
+	}
+	return content, nil
+}
+
+// Attach stores content as the attachment with the given name of a version
+// of url, identified by its time, replacing an attachment with the same
+// name. Attachments are dropped with their version.
+func (a *Archive) Attach(url string, version Version, name string, content []byte, contentType string) (Attachment, error) {
+	if name == "" || strings.ContainsAny(name, "/\\") || name == "." || name == ".." {
+		return Attachment{}, fmt.Errorf("invalid attachment name '%s'", name)
+	}
+	sum := sha256.Sum256(content)
+	attachment := Attachment{Name: name, Hash: hex.EncodeToString(sum[:]), Size: int64(len(content)), ContentType: contentType}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	idx, err := a.readIndex(url)
+	if err != nil {
+		return Attachment{}, err
+	}
+	i := slices.IndexFunc(idx.Versions, func(v Version) bool { return v.Time.Equal(version.Time) })
+	if i < 0 {
+		return Attachment{}, fmt.Errorf("%w of %s at %s", ErrNotFound, url, version.Time.Format(time.RFC3339))
+	}
+	if err := a.writeObject(attachment.Hash, content); err != nil {
+		return Attachment{}, err
+	}
+
+	target := &idx.Versions[i]
+	var removed []string
+	if j := slices.IndexFunc(target.Attachments, func(at Attachment) bool { return at.Name == name }); j >= 0 {
+		removed = append(removed, target.Attachments[j].Hash)
+		target.Attachments[j] = attachment
+	} else {
+		target.Attachments = append(target.Attachments, attachment)
+	}
+	if err := a.writeIndex(idx); err != nil {
+		return Attachment{}, err
+	}
+	return attachment, a.collect(removed)
+}
+
+// AttachAt stores content as the attachment with the given name of the
+// version of url current at the given time. It implements
+// monitor.AttachmentArchive.
+func (a *Archive) AttachAt(url string, at time.Time, name string, content []byte, contentType string) error {
+	version, err := a.At(url, at)
+	if err != nil {
+		return err
+	}
+	_, err = a.Attach(url, version, name, content, contentType)
+	return err
+}
+
+// ReadAttachment returns the content of an attachment, checking it against
+// its hash
+func (a *Archive) ReadAttachment(attachment Attachment) ([]byte, error) {
+	content, err := a.readObject(attachment.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s: %w", attachment.Name, err)
+	}
+	return content, nil
+}
+
+// prune drops the versions outside the retention limits and returns their
+// hashes
+func (a *Archive) prune(idx *index, now time.Time) []string {
+	keep := 0
+	if limit := a.retention.MaxVersions; limit > 0 && len(idx.Versions) > limit {
+		keep = len(idx.Versions) - limit
+	}
+	if a.retention.MaxAge > 0 {
+		// A version is replaced when the next one is seen
+		cutoff := now.Add(-a.retention.MaxAge)
+		for keep < len(idx.Versions)-1 && idx.Versions[keep+1].Time.Before(cutoff) {
+			keep++
+		}
+	}
+
+	var removed []string
+	for _, version := range idx.Versions[:keep] {
+		removed = append(removed, version.Hash)
+		for _, attachment := range version.Attachments {
+			removed = append(removed, attachment.Hash)
+		}
+	}
+	idx.Versions = slices.Clone(idx.Versions[keep:])
+	return removed
+}
+
+// collect deletes the contents of hashes that no index refers to anymore.
+// a.mu must be held.
+func (a *Archive) collect(hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	unused := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		unused[hash] = true
+	}
+	entries, err := os.ReadDir(filepath.Join(a.dir, "index"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		idx, err := readIndexFile(filepath.Join(a.dir, "index", entry.Name()))
+		if err != nil {
+			return err
+		}
+		for _, version := range idx.Versions {
+			delete(unused, version.Hash)
+			for _, attachment := range version.Attachments {
+				delete(unused, attachment.Hash)
+			}
+		}
+	}
+
+	for hash := range unused {
+		if err := os.Remove(a.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexPath returns the path of the index of url
+func (a *Archive) indexPath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(a.dir, "index", hex.EncodeToString(sum[:8])+".json")
+}
+
+// objectPath returns the path of the content with the given hash
+func (a *Archive) objectPath(hash string) string {
+	return filepath.Join(a.dir, "objects", hash[:2], hash+".gz")
+}
+
+// readIndex reads the index of url. A URL without one has no versions.
+func (a *Archive) readIndex(url string) (*index, error) {
+	idx, err := readIndexFile(a.indexPath(url))
+	if os.IsNotExist(err) {
+		return &index{URL: url}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// readIndexFile reads an index
+func readIndexFile(path string) (*index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("error parsing archive index %s: %w", path, err)
+	}
+	return &idx, nil
+}
+
+// writeIndex replaces the index of a URL
+func (a *Archive) writeIndex(idx *index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(a.indexPath(idx.URL), data)
+}
+
+// readObject reads the content with the given hash, checking it against
+// the hash
+func (a *Archive) readObject(hash string) ([]byte, error) {
+	file, err := os.Open(a.objectPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != hash {
+		return nil, errors.New("content is corrupted")
+	}
+	return content, nil
+}
+
+// writeObject stores content under its hash, unless it is already stored
+func (a *Archive) writeObject(hash string, content []byte) error {
+	path := a.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(content); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// writeFile writes a file through a temporary file, so readers never see
+// it partially written
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,130 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// objects returns the number of contents stored in the archive at dir
+func objects(t *testing.T, dir string) int {
+	files, err := filepath.Glob(filepath.Join(dir, "objects", "*", "*.gz"))
+	require.NoError(t, err)
+	return len(files)
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := New(dir, Retention{})
+	start := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	require.NoError(t, archive.Save("https://example.com", start, []byte("v1"), "text/html"))
+	require.NoError(t, archive.Save("https://example.com", start.Add(time.Minute), []byte("v1"), "text/html"), "unchanged")
+	require.NoError(t, archive.Save("https://example.com", start.Add(time.Minute*2), []byte("v2"), "text/html"))
+	require.NoError(t, archive.Save("https://example.com", start.Add(time.Minute*3), []byte("v1"), "text/html"))
+	require.NoError(t, archive.Save("https://example.org", start, []byte("v2"), ""))
+
+	versions, err := archive.Versions("https://example.com")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	require.Equal(t, start, versions[0].Time)
+	require.Equal(t, int64(2), versions[0].Size)
+	require.Equal(t, "text/html", versions[0].ContentType)
+	require.Equal(t, versions[0].Hash, versions[2].Hash)
+	require.Equal(t, 2, objects(t, dir), "identical contents are stored once")
+
+	version, err := archive.At("https://example.com", start.Add(time.Minute*2+time.Second))
+	require.NoError(t, err)
+	content, err := archive.Read(version)
+	require.NoError(t, err)
+	require.Equal(t, "v2", string(content))
+
+	version, err = archive.At("https://example.com", start.Add(time.Minute*2))
+	require.NoError(t, err)
+	require.Equal(t, versions[1], version, "a version is current from the time it was seen")
+
+	_, err = archive.At("https://example.com", start.Add(-time.Second))
+	require.ErrorIs(t, err, ErrNotFound)
+	versions, err = archive.Versions("https://example.net")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	// Corrupted contents are refused
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte("v3"))
+	writer.Close()
+	require.NoError(t, os.WriteFile(archive.objectPath(version.Hash), buf.Bytes(), 0644))
+	_, err = archive.Read(version)
+	require.ErrorContains(t, err, "is corrupted")
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	archive := New(dir, Retention{MaxVersions: 3})
+	start := time.Now().Add(-time.Hour)
+	for i, content := range []string{"a", "b", "c", "d", "a"} {
+		require.NoError(t, archive.Save("https://example.com", start.Add(time.Minute*time.Duration(i)), []byte(content), ""))
+	}
+	versions, err := archive.Versions("https://example.com")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	require.Equal(t, start.Add(time.Minute*2).UTC(), versions[0].Time)
+	require.Equal(t, 3, objects(t, dir), "b was dropped, a came back")
+
+	// Versions replaced longer ago than MaxAge are dropped, but the current
+	// one is kept however old it is
+	archive = New(dir, Retention{MaxAge: time.Hour * 24})
+	old := time.Now().Add(-time.Hour * 72)
+	require.NoError(t, archive.Save("https://example.org", old, []byte("x"), ""))
+	require.NoError(t, archive.Save("https://example.org", old.Add(time.Hour), []byte("y"), ""))
+	require.NoError(t, archive.Save("https://example.org", time.Now().Add(-time.Hour), []byte("z"), ""))
+	versions, err = archive.Versions("https://example.org")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	content, err := archive.Read(versions[0])
+	require.NoError(t, err)
+	require.Equal(t, "y", string(content), "y was current until an hour ago")
+	require.Equal(t, 5, objects(t, dir))
+}
+
+func TestAttach(t *testing.T) {
+	dir := t.TempDir()
+	archive := New(dir, Retention{MaxVersions: 1})
+	start := time.Now().Add(-time.Hour).UTC()
+	require.NoError(t, archive.Save("https://example.com", start, []byte("v1"), "text/html"))
+	versions, err := archive.Versions("https://example.com")
+	require.NoError(t, err)
+
+	attachment, err := archive.Attach("https://example.com", versions[0], "screenshot.png", []byte("png"), "image/png")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), attachment.Size)
+	_, err = archive.Attach("https://example.com", versions[0], "page.har", []byte("har"), "application/json")
+	require.NoError(t, err)
+	_, err = archive.Attach("https://example.com", versions[0], "page.har", []byte("har 2"), "application/json")
+	require.NoError(t, err)
+	require.Equal(t, 3, objects(t, dir), "the replaced attachment was deleted")
+
+	versions, err = archive.Versions("https://example.com")
+	require.NoError(t, err)
+	require.Len(t, versions[0].Attachments, 2)
+	har, ok := versions[0].Attachment("page.har")
+	require.True(t, ok)
+	content, err := archive.ReadAttachment(har)
+	require.NoError(t, err)
+	require.Equal(t, "har 2", string(content))
+
+	_, err = archive.Attach("https://example.com", Version{Time: start.Add(time.Second)}, "x", nil, "")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = archive.Attach("https://example.com", versions[0], "../x", nil, "")
+	require.ErrorContains(t, err, "invalid attachment name")
+
+	// Attachments are dropped with their version
+	require.NoError(t, archive.Save("https://example.com", start.Add(time.Minute), []byte("v2"), "text/html"))
+	require.Equal(t, 1, objects(t, dir))
+}
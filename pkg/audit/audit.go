@@ -0,0 +1,191 @@
+// Package audit records administrative actions, such as adding or pausing
+// monitors, in an append-only log, so teams sharing one hawkeye can tell who
+// changed what and when.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Source tells how an action was requested
+type Source string
+
+const (
+	// SourceCLI marks actions done with the hawkeye command
+	SourceCLI Source = "cli"
+	// SourceAPI marks actions requested through the HTTP API
+	SourceAPI Source = "api"
+)
+
+// Action is the kind of an administrative action
+type Action string
+
+const (
+	// ActionAdd records a monitor being added
+	ActionAdd Action = "add"
+	// ActionRemove records a monitor being removed
+	ActionRemove Action = "remove"
+	// ActionPause records a monitor or group being paused
+	ActionPause Action = "pause"
+	// ActionResume records a monitor or group being resumed
+	ActionResume Action = "resume"
+	// ActionReset records the baseline of a monitor being discarded
+	ActionReset Action = "reset"
+	// ActionConfig records the settings of a monitor being changed
+	ActionConfig Action = "config"
+)
+
+// Entry is one recorded action. Target is the URL of the monitor, or
+// "group:" followed by the name for actions on groups.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Source  Source    `json:"source"`
+	Action  Action    `json:"action"`
+	Target  string    `json:"target"`
+	Details string    `json:"details,omitempty"`
+}
+
+// GroupTarget returns the target of an action on a group
+func GroupTarget(name string) string {
+	return "group:" + name
+}
+
+// Log appends entries to a file as JSON lines. Entries are never rewritten
+// or removed, and several processes, e.g. a server and the command line, may
+// record to the same file.
+type Log struct {
+	path string
+	mu   sync.Mutex
+
+	// Now returns the time of new entries. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewLog creates a log recording to the file at path. The file and its
+// directory are created with the first entry.
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the path of the log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry, setting its time unless it has one. It is a no-op
+// on a nil log.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		now := time.Now
+		if l.Now != nil {
+			now = l.Now
+		}
+		entry.Time = now()
+	}
+
+	// A single write in append mode keeps lines of concurrent writers whole
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Query selects entries. Zero fields match everything.
+type Query struct {
+	Since  time.Time
+	Until  time.Time
+	Actor  string
+	Source Source
+	Action Action
+	Target string
+	// Limit keeps only the most recent entries
+	Limit int
+}
+
+// matches reports whether an entry is selected by the query
+func (q Query) matches(entry Entry) bool {
+	return (q.Since.IsZero() || !entry.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Time.Before(q.Until)) &&
+		(q.Actor == "" || entry.Actor == q.Actor) &&
+		(q.Source == "" || entry.Source == q.Source) &&
+		(q.Action == "" || entry.Action == q.Action) &&
+		(q.Target == "" || entry.Target == q.Target)
+}
+
+// Entries returns the entries selected by q, oldest first. A log that
+// doesn't exist yet has no entries.
+func (l *Log) Entries(q Query) ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", l.path, line, err)
+		}
+		if q.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries, nil
+}
+
+// LocalActor returns the name of the user running the process, for actions
+// done on the command line
+func LocalActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
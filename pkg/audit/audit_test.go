@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "audit.log")
+	log := NewLog(path)
+
+	// A log that doesn't exist yet is empty
+	entries, err := log.Entries(Query{})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	log.Now = func() time.Time { return now }
+
+	require.NoError(t, log.Record(Entry{Actor: "alice", Source: SourceCLI, Action: ActionAdd, Target: "https://example.com"}))
+	now = now.Add(time.Hour)
+	require.NoError(t, log.Record(Entry{Actor: "bob", Source: SourceAPI, Action: ActionPause, Target: GroupTarget("news")}))
+	now = now.Add(time.Hour)
+	require.NoError(t, log.Record(Entry{Actor: "alice", Source: SourceAPI, Action: ActionRemove, Target: "https://example.com"}))
+
+	entries, err = log.Entries(Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, start, entries[0].Time)
+	require.Equal(t, ActionRemove, entries[2].Action)
+
+	entries, err = log.Entries(Query{Actor: "alice"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = log.Entries(Query{Target: "group:news"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "bob", entries[0].Actor)
+
+	entries, err = log.Entries(Query{Since: start.Add(time.Hour), Source: SourceAPI})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	// The limit keeps the most recent entries
+	entries, err = log.Entries(Query{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ActionRemove, entries[0].Action)
+
+	// Entries are appended to what is already there
+	require.NoError(t, NewLog(path).Record(Entry{Actor: "carol", Source: SourceCLI, Action: ActionConfig, Target: "https://example.org"}))
+	entries, err = log.Entries(Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.False(t, entries[3].Time.IsZero())
+
+	var nilLog *Log
+	require.NoError(t, nilLog.Record(Entry{}))
+}
+
+func TestLogConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, NewLog(path).Record(Entry{Actor: "alice", Source: SourceAPI, Action: ActionAdd, Target: "https://example.com"}))
+		}()
+	}
+	wg.Wait()
+
+	entries, err := NewLog(path).Entries(Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 20)
+}
+
+func TestLogCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte("{\"actor\":\"alice\"}\nnot json\n"), 0644))
+
+	_, err := NewLog(path).Entries(Query{})
+	require.ErrorContains(t, err, "audit.log:2")
+}
@@ -0,0 +1,452 @@
+// Package browser renders pages in headless Chrome or Chromium over the
+// DevTools protocol, so that pages whose content is built by JavaScript can
+// be compared by what they show rather than by their script shell.
+package browser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+)
+
+// ExecNames are the names of the browser binaries looked up on PATH when
+// Options.ExecPath is empty
+var ExecNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "headless_shell"}
+
+var (
+	// ErrNoBrowser is returned when no browser binary was found
+	ErrNoBrowser = errors.New("no Chrome or Chromium binary found, set its path or a DevTools URL")
+	// ErrClosed is returned by Render after Close
+	ErrClosed = errors.New("browser is closed")
+)
+
+// offlineArgs turn off the update, sync, crash report and safe browsing
+// services of launched browsers in offline mode
+var offlineArgs = []string{
+	"--disable-component-update",
+	"--disable-sync",
+	"--disable-breakpad",
+	"--disable-domain-reliability",
+	"--disable-client-side-phishing-detection",
+	"--no-pings",
+}
+
+// pollInterval is how often a selector waited for is looked up
+const pollInterval = 100 * time.Millisecond
+
+// Options configures a Browser
+type Options struct {
+	// URL is the DevTools WebSocket URL of a running browser, e.g. of a
+	// browser container. If empty, a headless browser is launched.
+	URL string
+	// ExecPath is the browser binary launched. Defaults to the first of
+	// ExecNames found on PATH.
+	ExecPath string
+	// Args are extra command line flags of the launched browser
+	Args []string
+}
+
+// RenderOptions configures how a page is loaded
+type RenderOptions struct {
+	// Headers are sent with every request of the page
+	Headers map[string]string
+	// UserAgent replaces the user agent of the browser if set
+	UserAgent string
+	// WaitSelector is a CSS selector of an element that shows the content
+	// was rendered. Without it, the page is done once the network is almost
+	// idle: at most two requests in flight for half a second after the
+	// load event.
+	WaitSelector string
+	// ProxyURL sends the requests of the page through a proxy
+	ProxyURL string
+	// InsecureSkipVerify accepts any certificate
+	InsecureSkipVerify bool
+}
+
+// Page is a rendered page
+type Page struct {
+	// URL is the address of the document after redirects
+	URL string
+	// StatusCode and ContentType are those of the document response. They
+	// are zero for documents not loaded over the network.
+	StatusCode  int
+	ContentType string
+	// HTML is the serialized DOM after rendering
+	HTML []byte
+}
+
+// Browser renders pages, each in a target of its own, in one browser
+// process shared by all callers. The browser is started by the first Render
+// and started again if it crashes. It is safe for concurrent use.
+type Browser struct {
+	options Options
+
+	mu      sync.Mutex
+	conn    *conn
+	cmd     *exec.Cmd
+	dataDir string
+	closed  bool
+}
+
+// New creates a browser. Nothing is launched until the first page is
+// rendered.
+func New(opts *Options) *Browser {
+	b := &Browser{}
+	if opts != nil {
+		b.options = *opts
+	}
+	return b
+}
+
+// connect returns the connection to the browser, launching or connecting to
+// it if there's none or the previous one failed
+func (b *Browser) connect(ctx context.Context) (*conn, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if b.conn != nil {
+		select {
+		case <-b.conn.done:
+			b.stopLocked()
+		default:
+			return b.conn, nil
+		}
+	}
+
+	endpoint := b.options.URL
+	if endpoint == "" {
+		var err error
+		if endpoint, err = b.launchLocked(ctx); err != nil {
+			b.stopLocked()
+			return nil, err
+		}
+	}
+
+	ws, err := dialWebSocket(ctx, endpoint)
+	if err != nil {
+		b.stopLocked()
+		return nil, fmt.Errorf("error connecting to browser: %w", err)
+	}
+	b.conn = newConn(ws)
+	return b.conn, nil
+}
+
+// launchLocked starts a headless browser and returns its DevTools URL
+func (b *Browser) launchLocked(ctx context.Context) (string, error) {
+	path := b.options.ExecPath
+	if path == "" {
+		for _, name := range ExecNames {
+			if found, err := exec.LookPath(name); err == nil {
+				path = found
+				break
+			}
+		}
+		if path == "" {
+			return "", ErrNoBrowser
+		}
+	}
+
+	dataDir, err := os.MkdirTemp("", "hawkeye-browser-")
+	if err != nil {
+		return "", err
+	}
+	b.dataDir = dataDir
+
+	args := append([]string{
+		"--headless=new",
+		"--remote-debugging-port=0",
+		"--user-data-dir=" + dataDir,
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-gpu",
+		"--disable-extensions",
+		"--disable-background-networking",
+		"--mute-audio",
+	}, b.options.Args...)
+	if offline.Enabled() {
+		// Turn off the services the browser calls by itself
+		args = append(args, offlineArgs...)
+	}
+	args = append(args, "about:blank")
+
+	// The process outlives ctx, which only bounds the startup
+	cmd := exec.Command(path, args...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("error starting browser: %w", err)
+	}
+	b.cmd = cmd
+
+	// The browser prints its DevTools URL once it listens
+	found := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if endpoint, ok := strings.CutPrefix(scanner.Text(), "DevTools listening on "); ok {
+				found <- strings.TrimSpace(endpoint)
+				break
+			}
+		}
+		close(found)
+		io.Copy(io.Discard, stderr)
+	}()
+
+	select {
+	case endpoint, ok := <-found:
+		if !ok {
+			return "", fmt.Errorf("browser %s exited before listening", path)
+		}
+		return endpoint, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// stopLocked closes the connection and stops a launched browser
+func (b *Browser) stopLocked() {
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+	if b.cmd != nil {
+		b.cmd.Process.Kill()
+		b.cmd.Wait()
+		b.cmd = nil
+	}
+	if b.dataDir != "" {
+		os.RemoveAll(b.dataDir)
+		b.dataDir = ""
+	}
+}
+
+// Close stops the browser if it was launched. Renders in progress fail.
+func (b *Browser) Close() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.stopLocked()
+	return nil
+}
+
+// Render loads a page in a new tab and returns its DOM once it was rendered,
+// as described by RenderOptions.WaitSelector. ctx bounds the whole render.
+func (b *Browser) Render(ctx context.Context, url string, opts *RenderOptions) (*Page, error) {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+
+	c, err := b.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages with a proxy get a browser context of their own, as proxies
+	// are set per context
+	create := map[string]any{"url": "about:blank"}
+	if opts.ProxyURL != "" {
+		var browserContext struct {
+			ID string `json:"browserContextId"`
+		}
+		if err := c.call(ctx, "", "Target.createBrowserContext", map[string]any{"proxyServer": opts.ProxyURL}, &browserContext); err != nil {
+			return nil, err
+		}
+		defer cleanup(c, "Target.disposeBrowserContext", map[string]any{"browserContextId": browserContext.ID})
+		create["browserContextId"] = browserContext.ID
+	}
+
+	var target struct {
+		ID string `json:"targetId"`
+	}
+	if err := c.call(ctx, "", "Target.createTarget", create, &target); err != nil {
+		return nil, err
+	}
+	defer cleanup(c, "Target.closeTarget", map[string]any{"targetId": target.ID})
+
+	var session struct {
+		ID string `json:"sessionId"`
+	}
+	if err := c.call(ctx, "", "Target.attachToTarget", map[string]any{"targetId": target.ID, "flatten": true}, &session); err != nil {
+		return nil, err
+	}
+	events := c.subscribe(session.ID)
+	defer c.unsubscribe(session.ID)
+
+	t := &tab{conn: c, session: session.ID}
+	setup := []struct {
+		method string
+		params any
+		skip   bool
+	}{
+		{method: "Page.enable"},
+		{method: "Page.setLifecycleEventsEnabled", params: map[string]any{"enabled": true}},
+		{method: "Network.enable"},
+		{method: "Network.setExtraHTTPHeaders", params: map[string]any{"headers": opts.Headers}, skip: len(opts.Headers) == 0},
+		{method: "Network.setUserAgentOverride", params: map[string]any{"userAgent": opts.UserAgent}, skip: opts.UserAgent == ""},
+		{method: "Security.setIgnoreCertificateErrors", params: map[string]any{"ignore": true}, skip: !opts.InsecureSkipVerify},
+	}
+	for _, step := range setup {
+		if step.skip {
+			continue
+		}
+		if err := t.call(ctx, step.method, step.params, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var navigation struct {
+		FrameID   string `json:"frameId"`
+		ErrorText string `json:"errorText"`
+	}
+	if err := t.call(ctx, "Page.navigate", map[string]any{"url": url}, &navigation); err != nil {
+		return nil, err
+	}
+	if navigation.ErrorText != "" {
+		return nil, fmt.Errorf("error loading %s: %s", url, navigation.ErrorText)
+	}
+
+	page := &Page{URL: url}
+	if err := t.wait(ctx, events, navigation.FrameID, opts.WaitSelector, page); err != nil {
+		return nil, err
+	}
+
+	var html string
+	if err := t.evaluate(ctx, "document.documentElement ? document.documentElement.outerHTML : ''", &html); err != nil {
+		return nil, err
+	}
+	page.HTML = []byte(html)
+	return page, nil
+}
+
+// cleanup sends a browser command that must be sent even if the render was
+// canceled, such as closing its tab
+func cleanup(c *conn, method string, params any) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.call(ctx, "", method, params, nil)
+}
+
+// tab is a page being rendered
+type tab struct {
+	conn    *conn
+	session string
+}
+
+// call sends a command to the tab
+func (t *tab) call(ctx context.Context, method string, params, result any) error {
+	return t.conn.call(ctx, t.session, method, params, result)
+}
+
+// evaluate runs a JavaScript expression in the tab and decodes its value
+func (t *tab) evaluate(ctx context.Context, expression string, value any) error {
+	var result struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		Exception *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	params := map[string]any{"expression": expression, "returnByValue": true}
+	if err := t.call(ctx, "Runtime.evaluate", params, &result); err != nil {
+		return err
+	}
+	if result.Exception != nil {
+		return fmt.Errorf("error evaluating %s: %s", expression, result.Exception.Text)
+	}
+	return json.Unmarshal(result.Result.Value, value)
+}
+
+// wait follows the events of the tab until the page in frameID was rendered,
+// recording the response of its document in page
+func (t *tab) wait(ctx context.Context, events <-chan event, frameID, selector string, page *Page) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	loaded, idle := false, false
+	for {
+		if loaded && idle && (selector == "" || page.StatusCode >= 400) {
+			// Error pages are returned as they are, without waiting for a
+			// selector they're unlikely to have
+			return nil
+		}
+
+		select {
+		case e := <-events:
+			switch e.Method {
+			case "Network.responseReceived":
+				var params struct {
+					Type     string `json:"type"`
+					FrameID  string `json:"frameId"`
+					Response struct {
+						URL      string `json:"url"`
+						Status   int    `json:"status"`
+						MimeType string `json:"mimeType"`
+					} `json:"response"`
+				}
+				if json.Unmarshal(e.Params, &params) == nil && params.Type == "Document" && params.FrameID == frameID {
+					page.URL = params.Response.URL
+					page.StatusCode = params.Response.Status
+					page.ContentType = params.Response.MimeType
+				}
+			case "Page.loadEventFired":
+				loaded = true
+			case "Page.lifecycleEvent":
+				var params struct {
+					FrameID string `json:"frameId"`
+					Name    string `json:"name"`
+				}
+				if json.Unmarshal(e.Params, &params) == nil && params.FrameID == frameID {
+					switch params.Name {
+					case "networkAlmostIdle", "networkIdle":
+						idle = true
+					case "init":
+						// A new document, e.g. after a client-side redirect
+						loaded, idle = false, false
+					}
+				}
+			}
+		case <-ticker.C:
+			if !loaded || selector == "" {
+				continue
+			}
+			var found bool
+			query, _ := json.Marshal(selector)
+			if err := t.evaluate(ctx, "document.querySelector("+string(query)+") !== null", &found); err != nil {
+				if ctx.Err() != nil {
+					// ctx ended during the lookup
+					return fmt.Errorf("'%s' not found on %s: %w", selector, page.URL, ctx.Err())
+				}
+				return err
+			}
+			if found {
+				return nil
+			}
+		case <-t.conn.done:
+			return errClosed
+		case <-ctx.Done():
+			if selector != "" {
+				return fmt.Errorf("'%s' not found on %s: %w", selector, page.URL, ctx.Err())
+			}
+			return fmt.Errorf("%s didn't finish loading: %w", page.URL, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/browser/browsertest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	server := browsertest.NewServer()
+	defer server.Close()
+	server.SetPage("https://example.com/app", browsertest.Page{HTML: `<html><body><div id="app">Rendered</div></body></html>`})
+	server.SetPage("https://example.com/missing", browsertest.Page{Status: 404, HTML: "<html><body>Not found</body></html>"})
+
+	b := New(&Options{URL: server.URL})
+	defer b.Close()
+	ctx := context.Background()
+
+	page, err := b.Render(ctx, "https://example.com/app", &RenderOptions{
+		Headers:   map[string]string{"Authorization": "Bearer token"},
+		UserAgent: "Hawkeye/1.0",
+		ProxyURL:  "http://proxy.example.com:3128",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/app", page.URL)
+	require.Equal(t, 200, page.StatusCode)
+	require.Equal(t, "text/html", page.ContentType)
+	require.Contains(t, string(page.HTML), `<div id="app">Rendered</div>`)
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "Bearer token", requests[0].Headers["Authorization"])
+	require.Equal(t, "Hawkeye/1.0", requests[0].UserAgent)
+	require.Equal(t, "http://proxy.example.com:3128", requests[0].Proxy)
+
+	// The status of the document is reported
+	page, err = b.Render(ctx, "https://example.com/missing", nil)
+	require.NoError(t, err)
+	require.Equal(t, 404, page.StatusCode)
+
+	// Pages that can't be loaded fail
+	_, err = b.Render(ctx, "https://unknown.example.com", nil)
+	require.ErrorContains(t, err, "ERR_NAME_NOT_RESOLVED")
+}
+
+func TestRenderWaitSelector(t *testing.T) {
+	server := browsertest.NewServer()
+	defer server.Close()
+	server.SetPage("https://example.com/app", browsertest.Page{HTML: `<html><body><ul class="results"><li>One</li></ul></body></html>`})
+
+	b := New(&Options{URL: server.URL})
+	defer b.Close()
+
+	page, err := b.Render(context.Background(), "https://example.com/app", &RenderOptions{WaitSelector: "ul.results li"})
+	require.NoError(t, err)
+	require.Contains(t, string(page.HTML), "<li>One</li>")
+
+	// A selector that never matches fails once ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = b.Render(ctx, "https://example.com/app", &RenderOptions{WaitSelector: ".missing"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "'.missing' not found")
+}
+
+func TestBrowserReconnects(t *testing.T) {
+	server := browsertest.NewServer()
+	server.SetPage("https://example.com", browsertest.Page{HTML: "<html><body>Hello</body></html>"})
+
+	b := New(&Options{URL: server.URL})
+	_, err := b.Render(context.Background(), "https://example.com", nil)
+	require.NoError(t, err)
+
+	// A closed browser refuses to render
+	require.NoError(t, b.Close())
+	_, err = b.Render(context.Background(), "https://example.com", nil)
+	require.ErrorIs(t, err, ErrClosed)
+
+	// A lost connection is opened again by the next render
+	b = New(&Options{URL: server.URL})
+	defer b.Close()
+	_, err = b.Render(context.Background(), "https://example.com", nil)
+	require.NoError(t, err)
+	b.conn.Close()
+	require.Eventually(t, func() bool {
+		_, err := b.Render(context.Background(), "https://example.com", nil)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	server.Close()
+}
+
+func TestNoBrowser(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	b := New(nil)
+	defer b.Close()
+
+	_, err := b.Render(context.Background(), "https://example.com", nil)
+	require.ErrorIs(t, err, ErrNoBrowser)
+}
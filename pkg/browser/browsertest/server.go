@@ -0,0 +1,307 @@
+// Package browsertest provides a fake DevTools endpoint for testing code that
+// renders pages with browser.Browser without launching Chrome.
+package browsertest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/nemuizzz/hawkeye/pkg/dom"
+)
+
+// Page is a page served by the fake browser
+type Page struct {
+	// Status is the status code of the document. Defaults to 200.
+	Status int
+	// HTML is returned as the rendered DOM
+	HTML string
+}
+
+// Request records a page the fake browser was asked to render
+type Request struct {
+	URL       string
+	Headers   map[string]string
+	UserAgent string
+	Proxy     string
+}
+
+// Server is a fake DevTools endpoint. Navigating to a URL set with SetPage
+// loads that page at once; other URLs fail to resolve. Selectors are matched
+// against the HTML of the page.
+type Server struct {
+	// URL is the DevTools WebSocket URL, for browser.Options.URL
+	URL string
+
+	server   *httptest.Server
+	mu       sync.Mutex
+	pages    map[string]Page
+	requests []Request
+}
+
+// NewServer starts a fake DevTools endpoint
+func NewServer() *Server {
+	s := &Server{pages: make(map[string]Page)}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = "ws" + strings.TrimPrefix(s.server.URL, "http") + "/devtools/browser/fake"
+	return s
+}
+
+// SetPage sets the page served for a URL
+func (s *Server) SetPage(url string, page Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if page.Status == 0 {
+		page.Status = http.StatusOK
+	}
+	s.pages[url] = page
+}
+
+// Requests returns the pages requested so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Close stops the endpoint
+func (s *Server) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// serve upgrades a request to a WebSocket and answers DevTools commands
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Header.Get("Upgrade") != "websocket" || key == "" {
+		http.Error(w, "not a WebSocket request", http.StatusBadRequest)
+		return
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	rw.Flush()
+
+	c := &session{server: s, conn: conn, reader: rw.Reader, targets: make(map[string]*target), proxies: make(map[string]string)}
+	for {
+		data, err := c.read()
+		if err != nil {
+			return
+		}
+		var msg command
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+		c.handle(msg)
+	}
+}
+
+// command is a DevTools command
+type command struct {
+	ID        int64           `json:"id"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+}
+
+// target is a tab of the fake browser
+type target struct {
+	request Request
+	page    Page
+}
+
+// session is the connection of one client
+type session struct {
+	server  *Server
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	targets map[string]*target
+	proxies map[string]string
+	nextID  int
+}
+
+// selectorPattern extracts the selector of a querySelector expression
+var selectorPattern = regexp.MustCompile(`^document\.querySelector\((".*")\) !== null$`)
+
+// handle answers a command and sends the events it causes
+func (c *session) handle(msg command) {
+	var params map[string]any
+	json.Unmarshal(msg.Params, &params)
+	tab := c.targets[msg.SessionID]
+
+	switch msg.Method {
+	case "Target.createBrowserContext":
+		id := fmt.Sprintf("context-%d", msg.ID)
+		c.proxies[id], _ = params["proxyServer"].(string)
+		c.reply(msg, map[string]any{"browserContextId": id})
+		return
+	case "Target.createTarget":
+		c.nextID++
+		id := fmt.Sprintf("target-%d", c.nextID)
+		context, _ := params["browserContextId"].(string)
+		c.targets[id] = &target{request: Request{Proxy: c.proxies[context]}}
+		c.reply(msg, map[string]any{"targetId": id})
+		return
+	case "Target.attachToTarget":
+		id, _ := params["targetId"].(string)
+		c.reply(msg, map[string]any{"sessionId": id})
+		return
+	case "Target.closeTarget":
+		id, _ := params["targetId"].(string)
+		delete(c.targets, id)
+	}
+	if tab == nil {
+		c.reply(msg, map[string]any{})
+		return
+	}
+
+	switch msg.Method {
+	case "Network.setExtraHTTPHeaders":
+		tab.request.Headers = make(map[string]string)
+		headers, _ := params["headers"].(map[string]any)
+		for key, value := range headers {
+			tab.request.Headers[key], _ = value.(string)
+		}
+	case "Network.setUserAgentOverride":
+		tab.request.UserAgent, _ = params["userAgent"].(string)
+	case "Page.navigate":
+		c.navigate(msg, tab, params)
+		return
+	case "Runtime.evaluate":
+		c.evaluate(msg, tab, params)
+		return
+	}
+	c.reply(msg, map[string]any{})
+}
+
+// navigate loads a page and sends the events of loading it
+func (c *session) navigate(msg command, tab *target, params map[string]any) {
+	url, _ := params["url"].(string)
+	tab.request.URL = url
+
+	c.server.mu.Lock()
+	c.server.requests = append(c.server.requests, tab.request)
+	page, ok := c.server.pages[url]
+	c.server.mu.Unlock()
+
+	frame := msg.SessionID + "-frame"
+	if !ok {
+		c.reply(msg, map[string]any{"frameId": frame, "errorText": "net::ERR_NAME_NOT_RESOLVED"})
+		return
+	}
+	tab.page = page
+	c.reply(msg, map[string]any{"frameId": frame})
+
+	c.event(msg.SessionID, "Page.lifecycleEvent", map[string]any{"frameId": frame, "name": "init"})
+	c.event(msg.SessionID, "Network.responseReceived", map[string]any{
+		"type":     "Document",
+		"frameId":  frame,
+		"response": map[string]any{"url": url, "status": page.Status, "mimeType": "text/html"},
+	})
+	c.event(msg.SessionID, "Page.loadEventFired", map[string]any{})
+	c.event(msg.SessionID, "Page.lifecycleEvent", map[string]any{"frameId": frame, "name": "networkAlmostIdle"})
+}
+
+// evaluate answers the expressions used by browser.Browser
+func (c *session) evaluate(msg command, tab *target, params map[string]any) {
+	expression, _ := params["expression"].(string)
+
+	var value any = tab.page.HTML
+	if match := selectorPattern.FindStringSubmatch(expression); match != nil {
+		var selector string
+		json.Unmarshal([]byte(match[1]), &selector)
+		compiled, err := dom.Compile(selector)
+		if err != nil {
+			c.reply(msg, map[string]any{"exceptionDetails": map[string]any{"text": err.Error()}})
+			return
+		}
+		value = compiled.First(dom.Parse([]byte(tab.page.HTML))) != nil
+	}
+	c.reply(msg, map[string]any{"result": map[string]any{"value": value}})
+}
+
+// reply sends the result of a command
+func (c *session) reply(msg command, result any) {
+	c.write(map[string]any{"id": msg.ID, "sessionId": msg.SessionID, "result": result})
+}
+
+// event sends an event of a tab
+func (c *session) event(sessionID, method string, params any) {
+	c.write(map[string]any{"sessionId": sessionID, "method": method, "params": params})
+}
+
+// write sends a message in an unmasked text frame
+func (c *session) write(msg any) {
+	payload, _ := json.Marshal(msg)
+	header := []byte{0x81}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.Write(append(header, payload...))
+}
+
+// read returns the payload of the next frame of the client, which is masked
+func (c *session) read() ([]byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 0x8 {
+		return nil, io.EOF
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return payload, nil
+}
@@ -0,0 +1,172 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// message is a command, response or event of the DevTools protocol
+type message struct {
+	ID        int64           `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *protocolError  `json:"error,omitempty"`
+}
+
+// protocolError is the error of a failed command
+type protocolError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("DevTools error %d: %s", e.Code, e.Message)
+}
+
+// event is a notification of the browser, such as a finished request
+type event struct {
+	Method string
+	Params json.RawMessage
+}
+
+// conn sends commands to a browser over its DevTools WebSocket and routes
+// responses to their callers and events to the session they belong to
+type conn struct {
+	ws *websocket
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan *message
+	sessions map[string]chan event
+	err      error
+	done     chan struct{}
+}
+
+// newConn starts reading messages from ws
+func newConn(ws *websocket) *conn {
+	c := &conn{
+		ws:       ws,
+		pending:  make(map[int64]chan *message),
+		sessions: make(map[string]chan event),
+		done:     make(chan struct{}),
+	}
+	go c.read()
+	return c
+}
+
+// read dispatches messages until the connection fails
+func (c *conn) read() {
+	var err error
+	for {
+		var data []byte
+		if data, err = c.ws.ReadMessage(); err != nil {
+			break
+		}
+		var msg message
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if msg.ID != 0 {
+			if ch, ok := c.pending[msg.ID]; ok {
+				delete(c.pending, msg.ID)
+				ch <- &msg
+			}
+		} else if ch, ok := c.sessions[msg.SessionID]; ok {
+			// Events of a busy session are dropped rather than stalling
+			// every other session
+			select {
+			case ch <- event{Method: msg.Method, Params: msg.Params}:
+			default:
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// call sends a command, in the session sessionID if it isn't empty, and
+// decodes its result into result if it isn't nil
+func (c *conn) call(ctx context.Context, sessionID, method string, params, result any) error {
+	msg := message{SessionID: sessionID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return errClosed
+	}
+	c.nextID++
+	msg.ID = c.nextID
+	c.pending[msg.ID] = ch
+	c.mu.Unlock()
+
+	data, err := json.Marshal(msg)
+	if err == nil {
+		err = c.ws.WriteMessage(data)
+	}
+	if err != nil {
+		c.forget(msg.ID)
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %w", method, resp.Error)
+		}
+		if result != nil {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return errClosed
+	case <-ctx.Done():
+		c.forget(msg.ID)
+		return ctx.Err()
+	}
+}
+
+// forget drops the pending response of a command
+func (c *conn) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// subscribe returns the events of a session until unsubscribe is called
+func (c *conn) subscribe(sessionID string) <-chan event {
+	ch := make(chan event, 1024)
+	c.mu.Lock()
+	c.sessions[sessionID] = ch
+	c.mu.Unlock()
+	return ch
+}
+
+// unsubscribe stops routing the events of a session
+func (c *conn) unsubscribe(sessionID string) {
+	c.mu.Lock()
+	delete(c.sessions, sessionID)
+	c.mu.Unlock()
+}
+
+// Close closes the connection
+func (c *conn) Close() error {
+	return c.ws.Close()
+}
@@ -0,0 +1,1258 @@
+// Package config loads declarative monitor definition files (monitors.yaml).
+//
+// A definition file declares monitors together with the groups, content
+// filters, notifications and detection methods they use:
+//
+//	defaults:
+//	  interval: 5m
+//	groups:
+//	  - name: news
+//	    description: News sites
+//	notifications:
+//	  - name: ops
+//	    type: webhook
+//	    url: https://hooks.example.com/hawkeye
+//	    secret: change-me
+//	    events: [change, error, recovery]
+//	  - name: pager
+//	    type: ntfy
+//	    url: https://ntfy.sh/oncall
+//	routes:
+//	  - notify: [pager]
+//	    groups: [news]
+//	    min_severity: critical
+//	    quiet_hours: ["Sat,Sun 00:00-24:00"]
+//	domains:
+//	  - domain: example.com
+//	    max_parallel: 1
+//	    delay: 2s
+//	hosts:
+//	  - host: api.example.com
+//	    rate_limit: 10
+//	monitors:
+//	  - url: https://news.example.com
+//	    interval: 1m
+//	    method: hash
+//	    group: news
+//	    filters: ['\d+ comments']
+//	    notify: [ops]
+//	  - url: https://status.example.com
+//	    schedule: "*/10 9-17 * * mon-fri"
+//	    method: status
+//	    expected_status: [200, 204]
+//	    maintenance:
+//	      - window: Sat 02:00-04:00
+//	        mode: skip
+//
+// Files are validated as a whole and every problem is reported with the line
+// it was found on.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/imap"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+)
+
+// File is a parsed monitor definition file
+type File struct {
+	// Environment limits a document of a layered file to an environment,
+	// see ParseLayers
+	Environment   string             `yaml:"environment"`
+	Defaults      Defaults           `yaml:"defaults"`
+	Groups        []GroupSpec        `yaml:"groups"`
+	Notifications []NotificationSpec `yaml:"notifications"`
+	Routes        []RouteSpec        `yaml:"routes"`
+	Composites    []CompositeSpec    `yaml:"composites"`
+	Monitors      []MonitorSpec      `yaml:"monitors"`
+	Domains       []DomainSpec       `yaml:"domains"`
+	Hosts         []HostSpec         `yaml:"hosts"`
+
+	// name and loc locate settings for messages about the file
+	name string
+	loc  locator
+}
+
+// Defaults holds settings applied to every monitor that doesn't set them
+type Defaults struct {
+	Interval            string            `yaml:"interval"`
+	Timeout             string            `yaml:"timeout"`
+	Method              string            `yaml:"method"`
+	Retries             *int              `yaml:"retries"`
+	RetryInterval       string            `yaml:"retry_interval"`
+	FailureThreshold    int               `yaml:"failure_threshold"`
+	ConfirmDelay        string            `yaml:"confirm_delay"`
+	Headers             map[string]string `yaml:"headers"`
+	Baggage             map[string]string `yaml:"baggage"`
+	Labels              map[string]string `yaml:"labels"`
+	NormalizeWhitespace bool              `yaml:"normalize_whitespace"`
+	IgnoreTimestamps    bool              `yaml:"ignore_timestamps"`
+	Maintenance         []MaintenanceSpec `yaml:"maintenance"`
+	Schedule            string            `yaml:"schedule"`
+	Jitter              string            `yaml:"jitter"`
+	Proxy               string            `yaml:"proxy"`
+	AcceptEncoding      string            `yaml:"accept_encoding"`
+	NoCache             bool              `yaml:"no_cache"`
+	CacheBust           string            `yaml:"cache_bust"`
+	CaptureHAR          bool              `yaml:"capture_har"`
+	TLS                 *TLSSpec          `yaml:"tls"`
+	Fetcher             string            `yaml:"fetcher"`
+	WaitSelector        string            `yaml:"wait_selector"`
+	KeepCookies         bool              `yaml:"keep_cookies"`
+	Cookies             map[string]string `yaml:"cookies"`
+	BasicAuth           *BasicAuthSpec    `yaml:"basic_auth"`
+	BearerToken         string            `yaml:"bearer_token"`
+	Login               *LoginSpec        `yaml:"login"`
+	OAuth2              *OAuth2Spec       `yaml:"oauth2"`
+	// RateLimit is the number of requests per minute sent to any one host
+	RateLimit int `yaml:"rate_limit"`
+	// RespectRobotsTxt skips monitors whose URL robots.txt disallows
+	RespectRobotsTxt bool `yaml:"respect_robots_txt"`
+}
+
+// GroupSpec declares a monitor group. ShareCookies makes its monitors keep
+// their cookies in one jar, e.g. to share a session.
+type GroupSpec struct {
+	Name         string `yaml:"name"`
+	Description  string `yaml:"description"`
+	ShareCookies bool   `yaml:"share_cookies"`
+}
+
+// NotificationSpec declares a notification destination. Events limits the
+// event types sent to it; all events are sent if it is empty. Secret, if set,
+// is used to sign webhook payloads. Type is webhook, slack, discord, kafka,
+// nats, sns, pubsub, pushover or ntfy. Kafka notifications publish to Topic
+// on the Brokers, given as host:port, and NATS notifications to the subject
+// Topic on the server at URL. Their Format is json, the default, or avro, see
+// notify.ParseFormat, and SchemaRegistry the URL of a schema registry for
+// Avro messages. SNS notifications publish to the topic ARN Topic and
+// Pub/Sub notifications to the topic projects/<project>/topics/<topic>.
+// Pushover notifications are sent with the application Token to the user or
+// group key User, and ntfy notifications to the topic at URL, with Token as
+// an optional access token. Priorities maps the events of both to min, low,
+// normal, high or urgent; errors are high and other events normal unless
+// it says otherwise. Throttle is the least time between two changes of a
+// URL sent to any notification, Deduplicate drops changes identical to the
+// previous one of their URL, and Digest is a window whose changes are sent
+// in one summary.
+type NotificationSpec struct {
+	Name           string            `yaml:"name"`
+	Type           string            `yaml:"type"`
+	URL            string            `yaml:"url"`
+	Headers        map[string]string `yaml:"headers"`
+	Secret         string            `yaml:"secret"`
+	Events         []string          `yaml:"events"`
+	Brokers        []string          `yaml:"brokers"`
+	Topic          string            `yaml:"topic"`
+	Format         string            `yaml:"format"`
+	SchemaRegistry string            `yaml:"schema_registry"`
+	Token          string            `yaml:"token"`
+	User           string            `yaml:"user"`
+	Priorities     map[string]string `yaml:"priorities"`
+	Throttle       string            `yaml:"throttle"`
+	Deduplicate    bool              `yaml:"deduplicate"`
+	Digest         string            `yaml:"digest"`
+}
+
+// RouteSpec sends the changes of monitors in Groups, or of all monitors if it
+// is empty, to the notifications named in Notify when they meet every
+// condition given: Events limits the event types, e.g. to errors only,
+// Match is a regular expression the details must match, QuietHours are
+// windows in which nothing is sent, see schedule.ParseWindow, and
+// MinSeverity is info, warning or critical, see notify.SeverityOf.
+// Notifications a monitor lists in its own notify are sent all of its
+// changes.
+type RouteSpec struct {
+	Notify      []string `yaml:"notify"`
+	Groups      []string `yaml:"groups"`
+	Events      []string `yaml:"events"`
+	Match       string   `yaml:"match"`
+	QuietHours  []string `yaml:"quiet_hours"`
+	MinSeverity string   `yaml:"min_severity"`
+}
+
+// CompositeSpec declares a composite, which raises a change when the recent
+// events of monitors meet Rule, see monitor.ParseCompositeRule. Window is how
+// far back events are counted, see monitor.DefaultCompositeWindow. Notify
+// lists the notifications its changes are sent to; routes without groups
+// send them theirs too.
+type CompositeSpec struct {
+	Name   string   `yaml:"name"`
+	Rule   string   `yaml:"rule"`
+	Window string   `yaml:"window"`
+	Notify []string `yaml:"notify"`
+}
+
+// MonitorSpec declares a single monitor. Schedule is a cron expression that
+// replaces Interval. At makes the monitor a one-time check at that time.
+// Until is a condition, see monitor.ParseCondition, after which the monitor
+// finishes, and Deadline a time or a duration after which it gives up.
+// Expires is a time or a duration after which the monitor stops with a
+// summary of its checks. Expect is what the first fetch must find for the
+// monitor to be set up, see monitor.ParseExpectation.
+// Match and MatchAbsent are conditions reported when they appear or
+// disappear, and imply method keyword. Count lists keywords whose
+// occurrences are counted, see monitor.ParseKeyword, and implies method
+// count. CSVKeys name the columns identifying a row and CSVDelimiter
+// separates the columns, see monitor.ParseDelimiter; both imply method csv.
+// TailSeparator splits what is appended into segments, see
+// monitor.ParseSeparator, and implies method tail.
+// A webhook://<name> URL makes a monitor that reports the payloads posted to
+// the API at /hooks/<name>; WebhookMatch lists conditions they must meet,
+// WebhookFields the JSON fields shown of them and WebhookSecret signs them,
+// see monitor.Config.
+// ImageThreshold and ImageDiffDir imply method image, see monitor.Config.
+// Paginate follows the pages of an API, see monitor.ParsePagination, and
+// compares the items at PaginateItems of up to MaxPages pages. AppendOnly
+// fetches only what was appended to logs and exports, see monitor.Config.
+// Representations are Accept header values each compared with their own
+// baseline, and Variants sets of request headers that are. Extract finds a
+// number, see monitor.ParseExtractor, and implies method value; Below,
+// Above, Delta and DeltaPercent limit the changes of the number that are
+// reported. Ignore, Select and Alert take CSS selectors or XPath
+// expressions, see monitor.ParseSelector; changes outside of the parts of
+// Alert are informational. Body is sent with RequestMethod, POST by
+// default. AcceptEncoding is sent as the Accept-Encoding of requests;
+// responses are decoded whatever it is. NoCache and CacheBust ask caches for
+// fresh content, and CaptureHAR keeps the requests of changes in the
+// archive, see monitor.Config. FailureThreshold is the number of failed
+// checks in a row before an error is reported, and ConfirmDelay the delay
+// after which changes are confirmed by fetching again. Labels are added to
+// those of Defaults and select monitors, see monitor.ParseLabelSelector.
+type MonitorSpec struct {
+	URL                 string            `yaml:"url"`
+	Interval            string            `yaml:"interval"`
+	Timeout             string            `yaml:"timeout"`
+	Method              string            `yaml:"method"`
+	ExpectedStatus      []int             `yaml:"expected_status"`
+	Match               []string          `yaml:"match"`
+	MatchAbsent         []string          `yaml:"match_absent"`
+	Count               []string          `yaml:"count"`
+	CSVKeys             []string          `yaml:"csv_keys"`
+	CSVDelimiter        string            `yaml:"csv_delimiter"`
+	TailSeparator       string            `yaml:"tail_separator"`
+	WebhookMatch        []string          `yaml:"webhook_match"`
+	WebhookFields       []string          `yaml:"webhook_fields"`
+	WebhookSecret       string            `yaml:"webhook_secret"`
+	ImageThreshold      float64           `yaml:"image_threshold"`
+	ImageDiffDir        string            `yaml:"image_diff_dir"`
+	Paginate            string            `yaml:"paginate"`
+	PaginateItems       string            `yaml:"paginate_items"`
+	MaxPages            int               `yaml:"max_pages"`
+	AppendOnly          bool              `yaml:"append_only"`
+	Representations     []string          `yaml:"representations"`
+	Variants            []VariantSpec     `yaml:"variants"`
+	Extract             string            `yaml:"extract"`
+	Below               *float64          `yaml:"below"`
+	Above               *float64          `yaml:"above"`
+	Delta               float64           `yaml:"delta"`
+	DeltaPercent        float64           `yaml:"delta_percent"`
+	Retries             *int              `yaml:"retries"`
+	RetryInterval       string            `yaml:"retry_interval"`
+	FailureThreshold    int               `yaml:"failure_threshold"`
+	ConfirmDelay        string            `yaml:"confirm_delay"`
+	Group               string            `yaml:"group"`
+	Headers             map[string]string `yaml:"headers"`
+	Baggage             map[string]string `yaml:"baggage"`
+	Labels              map[string]string `yaml:"labels"`
+	Ignore              []string          `yaml:"ignore"`
+	Select              []string          `yaml:"select"`
+	Alert               []string          `yaml:"alert"`
+	Filters             []string          `yaml:"filters"`
+	NormalizeWhitespace *bool             `yaml:"normalize_whitespace"`
+	IgnoreTimestamps    *bool             `yaml:"ignore_timestamps"`
+	Notify              []string          `yaml:"notify"`
+	Maintenance         []MaintenanceSpec `yaml:"maintenance"`
+	Schedule            string            `yaml:"schedule"`
+	At                  string            `yaml:"at"`
+	Until               string            `yaml:"until"`
+	Deadline            string            `yaml:"deadline"`
+	Expires             string            `yaml:"expires"`
+	Expect              []string          `yaml:"expect"`
+	Jitter              string            `yaml:"jitter"`
+	Proxy               string            `yaml:"proxy"`
+	AcceptEncoding      string            `yaml:"accept_encoding"`
+	NoCache             *bool             `yaml:"no_cache"`
+	CacheBust           string            `yaml:"cache_bust"`
+	CaptureHAR          *bool             `yaml:"capture_har"`
+	TLS                 *TLSSpec          `yaml:"tls"`
+	RespectRobotsTxt    *bool             `yaml:"respect_robots_txt"`
+	Fetcher             string            `yaml:"fetcher"`
+	WaitSelector        string            `yaml:"wait_selector"`
+	RequestMethod       string            `yaml:"request_method"`
+	Body                string            `yaml:"body"`
+	ContentType         string            `yaml:"content_type"`
+	KeepCookies         *bool             `yaml:"keep_cookies"`
+	Cookies             map[string]string `yaml:"cookies"`
+	BasicAuth           *BasicAuthSpec    `yaml:"basic_auth"`
+	BearerToken         string            `yaml:"bearer_token"`
+	Login               *LoginSpec        `yaml:"login"`
+	OAuth2              *OAuth2Spec       `yaml:"oauth2"`
+}
+
+// TLSSpec declares how servers are verified and the client certificate
+// presented to them. A monitor's TLS settings replace the defaults.
+type TLSSpec struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CAFile             string `yaml:"ca_file"`
+	MinVersion         string `yaml:"min_version"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+}
+
+// BasicAuthSpec declares the credentials of HTTP Basic authentication. A
+// monitor's basic_auth, bearer_token or oauth2 replaces those of the
+// defaults.
+type BasicAuthSpec struct {
+	User string `yaml:"user"`
+	Pass string `yaml:"pass"`
+}
+
+// LoginSpec declares a request that signs monitors in, see monitor.Login. A
+// monitor's login replaces the default.
+type LoginSpec struct {
+	URL         string            `yaml:"url"`
+	Method      string            `yaml:"method"`
+	Body        string            `yaml:"body"`
+	ContentType string            `yaml:"content_type"`
+	Headers     map[string]string `yaml:"headers"`
+	Token       string            `yaml:"token"`
+}
+
+// OAuth2Spec declares the OAuth2 client credentials monitors authenticate
+// with
+type OAuth2Spec struct {
+	TokenURL     string   `yaml:"token_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// DomainSpec declares a politeness policy shared by all monitors on a domain
+// and its subdomains
+type DomainSpec struct {
+	Domain      string `yaml:"domain"`
+	MinInterval string `yaml:"min_interval"`
+	MaxParallel int    `yaml:"max_parallel"`
+	Delay       string `yaml:"delay"`
+}
+
+// HostSpec declares the number of requests per minute sent to a host,
+// overriding the default rate limit
+type HostSpec struct {
+	Host      string `yaml:"host"`
+	RateLimit int    `yaml:"rate_limit"`
+}
+
+// VariantSpec declares a variant of a page, requested with Headers and
+// compared with its own baseline, e.g. for a language or a logged in user
+type VariantSpec struct {
+	Name    string            `yaml:"name"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// MaintenanceSpec declares a maintenance window, e.g. "Sat 02:00-04:00" or
+// "0 2 * * sat for 2h". Mode is skip (the default) to skip checks or silence
+// to check without notifying.
+type MaintenanceSpec struct {
+	Window string `yaml:"window"`
+	Mode   string `yaml:"mode"`
+}
+
+// Notification types
+const (
+	NotificationWebhook  = "webhook"
+	NotificationSlack    = "slack"
+	NotificationDiscord  = "discord"
+	NotificationKafka    = "kafka"
+	NotificationNATS     = "nats"
+	NotificationSNS      = "sns"
+	NotificationPubSub   = "pubsub"
+	NotificationPushover = "pushover"
+	NotificationNtfy     = "ntfy"
+)
+
+// ErrNoMonitors is reported for files that declare no monitors
+var ErrNoMonitors = errors.New("no monitors defined")
+
+// Load reads and validates a definition file
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(filepath.Base(path), data)
+}
+
+// Parse parses and validates a definition file. name is used in error
+// messages. Files with several documents are merged as by ParseLayers,
+// without an environment.
+func Parse(name string, data []byte) (*File, error) {
+	return ParseLayers([]Layer{{Name: name, Data: data}}, "")
+}
+
+// Position returns the file name and line of a setting of the monitor at
+// index, e.g. "monitors.yaml:12", for messages about it. Settings taken from
+// the defaults point at the monitor.
+func (f *File) Position(index int, field string) string {
+	if line := f.loc.line("monitors", index, field); line > 0 {
+		return fmt.Sprintf("%s:%d", f.loc.file(f.name, "monitors", index, field), line)
+	}
+	return f.name
+}
+
+// Configs builds a monitor configuration for every declared monitor
+func (f *File) Configs() ([]*monitor.Config, error) {
+	configs := make([]*monitor.Config, 0, len(f.Monitors))
+	for i := range f.Monitors {
+		config, err := f.buildConfig(&f.Monitors[i])
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// DomainPolicies builds the politeness policy of every declared domain, keyed
+// by domain
+func (f *File) DomainPolicies() (map[string]monitor.DomainPolicy, error) {
+	policies := make(map[string]monitor.DomainPolicy, len(f.Domains))
+	for _, spec := range f.Domains {
+		policy, err := spec.policy()
+		if err != nil {
+			return nil, err
+		}
+		policies[spec.Domain] = policy
+	}
+	return policies, nil
+}
+
+// options converts the spec into TLS options, checking that the files it
+// names can be loaded
+func (s *TLSSpec) options() (customhttp.TLSOptions, error) {
+	options := customhttp.TLSOptions{
+		InsecureSkipVerify: s.InsecureSkipVerify,
+		CAFile:             s.CAFile,
+		CertFile:           s.CertFile,
+		KeyFile:            s.KeyFile,
+	}
+
+	if s.MinVersion != "" {
+		version, err := customhttp.ParseTLSVersion(s.MinVersion)
+		if err != nil {
+			return options, &fieldError{field: "tls", path: []any{"min_version"}, err: err}
+		}
+		options.MinVersion = version
+	}
+
+	if _, err := options.Config(); err != nil {
+		return options, &fieldError{field: "tls", err: err}
+	}
+
+	return options, nil
+}
+
+// login converts the spec into a monitor login
+func (s *LoginSpec) login() (*monitor.Login, error) {
+	login := &monitor.Login{
+		URL:         s.URL,
+		Method:      s.Method,
+		Body:        s.Body,
+		ContentType: s.ContentType,
+		Headers:     s.Headers,
+		Token:       s.Token,
+	}
+	if err := monitor.ValidateLogin(login); err != nil {
+		return nil, &fieldError{field: "login", err: err}
+	}
+	return login, nil
+}
+
+// options converts the spec into OAuth2 options
+func (s *OAuth2Spec) options() (*customhttp.OAuth2Options, error) {
+	options := &customhttp.OAuth2Options{
+		TokenURL:     s.TokenURL,
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		Scopes:       s.Scopes,
+	}
+	if err := options.Validate(); err != nil {
+		return nil, &fieldError{field: "oauth2", err: err}
+	}
+	return options, nil
+}
+
+// HostRateLimits returns the requests per minute allowed to every declared
+// host, keyed by host
+func (f *File) HostRateLimits() map[string]int {
+	limits := make(map[string]int, len(f.Hosts))
+	for _, spec := range f.Hosts {
+		limits[spec.Host] = spec.RateLimit
+	}
+	return limits
+}
+
+// policy converts the spec into a domain policy
+func (s *DomainSpec) policy() (monitor.DomainPolicy, error) {
+	var policy monitor.DomainPolicy
+	var err error
+
+	if policy.MinInterval, err = duration("min_interval", s.MinInterval, 0); err != nil {
+		return policy, err
+	}
+	if policy.Delay, err = duration("delay", s.Delay, 0); err != nil {
+		return policy, err
+	}
+	if s.MaxParallel < 0 {
+		return policy, &fieldError{field: "max_parallel", err: fmt.Errorf("max_parallel must not be negative")}
+	}
+	policy.MaxParallel = s.MaxParallel
+
+	return policy, nil
+}
+
+// Notifiers builds a notifier for every declared notification, keyed by name
+func (f *File) Notifiers() (map[string]notify.Notifier, error) {
+	notifiers := make(map[string]notify.Notifier, len(f.Notifications))
+	for _, spec := range f.Notifications {
+		notifier, err := spec.notifier()
+		if err != nil {
+			return nil, err
+		}
+		notifiers[spec.Name] = notifier
+	}
+	return notifiers, nil
+}
+
+// MonitorNotifiers returns the notifiers of each monitor, in the order of
+// Monitors: the notifications it lists in notify, and those routes send its
+// changes to, which are only sent the changes their routes allow
+func (f *File) MonitorNotifiers(notifiers map[string]notify.Notifier) ([]notify.NotifierList, error) {
+	rules, err := f.routeRules()
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]notify.NotifierList, len(f.Monitors))
+	for i, spec := range f.Monitors {
+		lists[i] = f.routed(notifiers, rules, spec.Notify, spec.Group)
+	}
+	return lists, nil
+}
+
+// CompositeNotifiers returns the notifiers of each composite, in the order of
+// Composites, like MonitorNotifiers does for monitors
+func (f *File) CompositeNotifiers(notifiers map[string]notify.Notifier) ([]notify.NotifierList, error) {
+	rules, err := f.routeRules()
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]notify.NotifierList, len(f.Composites))
+	for i, spec := range f.Composites {
+		lists[i] = f.routed(notifiers, rules, spec.Notify, "")
+	}
+	return lists, nil
+}
+
+// routeRules builds the conditions of every route
+func (f *File) routeRules() ([]notify.Rule, error) {
+	rules := make([]notify.Rule, len(f.Routes))
+	for i := range f.Routes {
+		rule, err := f.Routes[i].rule()
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// routed returns the notifications named in names, and those the routes
+// send the changes of group to, which are only sent the changes the rules
+// of their routes allow
+func (f *File) routed(notifiers map[string]notify.Notifier, rules []notify.Rule, names []string, group string) notify.NotifierList {
+	var list notify.NotifierList
+	var ordered []string
+	routed := make(map[string][]notify.Rule)
+	for _, name := range names {
+		if !slices.Contains(ordered, name) {
+			ordered = append(ordered, name)
+		}
+	}
+	for j, route := range f.Routes {
+		if len(route.Groups) > 0 && !slices.Contains(route.Groups, group) {
+			continue
+		}
+		for _, name := range route.Notify {
+			if slices.Contains(names, name) {
+				continue
+			}
+			if routed[name] == nil {
+				ordered = append(ordered, name)
+			}
+			routed[name] = append(routed[name], rules[j])
+		}
+	}
+	for _, name := range ordered {
+		list = append(list, notify.Route(notifiers[name], routed[name]...))
+	}
+	return list
+}
+
+// CompositeConfigs builds every declared composite. Errors are returned as
+// *fieldError.
+func (f *File) CompositeConfigs() ([]monitor.Composite, error) {
+	composites := make([]monitor.Composite, 0, len(f.Composites))
+	for i := range f.Composites {
+		composite, err := f.Composites[i].composite()
+		if err != nil {
+			return nil, err
+		}
+		composites = append(composites, composite)
+	}
+	return composites, nil
+}
+
+// composite builds the composite described by the spec. Errors are returned
+// as *fieldError.
+func (s *CompositeSpec) composite() (monitor.Composite, error) {
+	composite := monitor.Composite{Name: s.Name}
+	rule, err := monitor.ParseCompositeRule(s.Rule)
+	if err != nil {
+		return composite, &fieldError{field: "rule", err: err}
+	}
+	composite.Rule = rule
+	if composite.Window, err = duration("window", s.Window, 0); err != nil {
+		return composite, err
+	}
+	if composite.Window < 0 {
+		return composite, &fieldError{field: "window", err: monitor.ErrCompositeWindow}
+	}
+	return composite, nil
+}
+
+// rule builds the conditions of the route. Errors are returned as
+// *fieldError.
+func (s *RouteSpec) rule() (notify.Rule, error) {
+	var rule notify.Rule
+	for i, name := range s.Events {
+		event, err := monitor.ParseEventType(name)
+		if err != nil {
+			return rule, &fieldError{field: "events", path: []any{i}, err: err}
+		}
+		rule.Events = append(rule.Events, event)
+	}
+	if s.Match != "" {
+		match, err := regexp.Compile(s.Match)
+		if err != nil {
+			return rule, &fieldError{field: "match", err: fmt.Errorf("invalid match pattern: %w", err)}
+		}
+		rule.Match = match
+	}
+	for i, spec := range s.QuietHours {
+		window, err := schedule.ParseWindow(spec, schedule.ModeSilence)
+		if err != nil {
+			return rule, &fieldError{field: "quiet_hours", path: []any{i}, err: err}
+		}
+		rule.QuietHours = append(rule.QuietHours, window)
+	}
+	if s.MinSeverity != "" {
+		severity, err := notify.ParseSeverity(s.MinSeverity)
+		if err != nil {
+			return rule, &fieldError{field: "min_severity", err: err}
+		}
+		rule.MinSeverity = severity
+	}
+	return rule, nil
+}
+
+// notifier builds the notifier described by the spec
+func (s NotificationSpec) notifier() (notify.Notifier, error) {
+	if err := offline.Check(fmt.Sprintf("notification '%s'", s.Name)); err != nil {
+		return nil, err
+	}
+
+	var notifier notify.Notifier
+	switch s.Type {
+	case NotificationWebhook:
+		notifier = notify.NewWebhookNotifier(s.Name, s.URL, s.Headers).WithSecret(s.Secret)
+	case NotificationSlack:
+		notifier = notify.NewSlackNotifier(s.Name, s.URL)
+	case NotificationDiscord:
+		notifier = notify.NewDiscordNotifier(s.Name, s.URL)
+	case NotificationKafka:
+		format, err := notify.ParseFormat(s.Format)
+		if err != nil {
+			return nil, err
+		}
+		notifier = notify.NewKafkaNotifier(s.Name, s.Brokers, s.Topic, format).WithSchemaRegistry(s.SchemaRegistry)
+	case NotificationNATS:
+		format, err := notify.ParseFormat(s.Format)
+		if err != nil {
+			return nil, err
+		}
+		notifier = notify.NewNATSNotifier(s.Name, s.URL, s.Topic, format).WithSchemaRegistry(s.SchemaRegistry)
+	case NotificationSNS:
+		notifier = notify.NewSNSNotifier(s.Name, s.Topic)
+	case NotificationPubSub:
+		notifier = notify.NewPubSubNotifier(s.Name, s.Topic)
+	case NotificationPushover:
+		priorities, err := notify.ParsePriorities(s.Priorities)
+		if err != nil {
+			return nil, err
+		}
+		notifier = notify.NewPushoverNotifier(s.Name, s.Token, s.User).WithPriorities(priorities)
+	case NotificationNtfy:
+		priorities, err := notify.ParsePriorities(s.Priorities)
+		if err != nil {
+			return nil, err
+		}
+		notifier = notify.NewNtfyNotifier(s.Name, s.URL).WithToken(s.Token).WithPriorities(priorities)
+	default:
+		return nil, fmt.Errorf("unknown notification type '%s'", s.Type)
+	}
+
+	digest, err := duration("digest", s.Digest, 0)
+	if err != nil {
+		return nil, err
+	}
+	if digest > 0 {
+		notifier = notify.NewDigest(notifier, digest)
+	}
+	throttle, err := duration("throttle", s.Throttle, 0)
+	if err != nil {
+		return nil, err
+	}
+	notifier = notify.Throttle(notifier, throttle)
+	if s.Deduplicate {
+		notifier = notify.Deduplicate(notifier)
+	}
+
+	events := make([]monitor.EventType, 0, len(s.Events))
+	for _, name := range s.Events {
+		event, err := monitor.ParseEventType(name)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return notify.FilterEvents(notifier, events...), nil
+}
+
+// buildConfig converts a monitor spec into a monitor configuration, applying
+// the file's defaults. Errors are returned as *fieldError so validation can
+// point at the offending line.
+func (f *File) buildConfig(spec *MonitorSpec) (*monitor.Config, error) {
+	config := monitor.DefaultConfig(spec.URL)
+	defaults := f.Defaults
+
+	var err error
+	if config.Interval, err = duration("interval", first(spec.Interval, defaults.Interval), config.Interval); err != nil {
+		return nil, err
+	}
+	if config.Interval <= 0 {
+		return nil, &fieldError{field: "interval", err: monitor.ErrInvalidInterval}
+	}
+
+	// A monitor's own interval overrides a default schedule
+	scheduleSpec := first(spec.Schedule, defaults.Schedule)
+	if spec.Interval != "" && spec.Schedule == "" {
+		scheduleSpec = ""
+	}
+	if scheduleSpec != "" {
+		if config.Schedule, err = schedule.ParseCron(scheduleSpec); err != nil {
+			return nil, &fieldError{field: "schedule", err: err}
+		}
+	}
+	if spec.At != "" {
+		if config.At, err = schedule.ParseTime(spec.At, nil); err != nil {
+			return nil, &fieldError{field: "at", err: err}
+		}
+		if !config.At.After(time.Now()) {
+			return nil, &fieldError{field: "at", err: fmt.Errorf("at %s is in the past", spec.At)}
+		}
+	}
+	if spec.Until != "" {
+		if config.Until, err = monitor.ParseCondition(spec.Until); err != nil {
+			return nil, &fieldError{field: "until", err: err}
+		}
+	}
+	if spec.Deadline != "" {
+		if config.Deadline, err = schedule.ParseDeadline(spec.Deadline, time.Now(), nil); err != nil {
+			return nil, &fieldError{field: "deadline", err: err}
+		}
+	}
+	if spec.Expires != "" {
+		if config.Expires, err = schedule.ParseDeadline(spec.Expires, time.Now(), nil); err != nil {
+			return nil, &fieldError{field: "expires", err: err}
+		}
+	}
+	if len(spec.Expect) > 0 {
+		if config.Expect, err = monitor.ParseExpectation(spec.Expect); err != nil {
+			return nil, &fieldError{field: "expect", err: err}
+		}
+	}
+	if config.Jitter, err = duration("jitter", first(spec.Jitter, defaults.Jitter), 0); err != nil {
+		return nil, err
+	}
+	if config.Timeout, err = duration("timeout", first(spec.Timeout, defaults.Timeout), config.Timeout); err != nil {
+		return nil, err
+	}
+	tlsSpec := defaults.TLS
+	if spec.TLS != nil {
+		tlsSpec = spec.TLS
+	}
+	if tlsSpec != nil {
+		if config.TLS, err = tlsSpec.options(); err != nil {
+			return nil, err
+		}
+	}
+	if proxy := first(spec.Proxy, defaults.Proxy); proxy != "" {
+		if config.ProxyURL, err = customhttp.ParseProxyURL(proxy); err != nil {
+			return nil, &fieldError{field: "proxy", err: err}
+		}
+	}
+	if acceptEncoding := first(spec.AcceptEncoding, defaults.AcceptEncoding); acceptEncoding != "" {
+		if err := customhttp.ValidateAcceptEncoding(acceptEncoding); err != nil {
+			return nil, &fieldError{field: "accept_encoding", err: err}
+		}
+		config.AcceptEncoding = acceptEncoding
+	}
+	if config.RetryInterval, err = duration("retry_interval", first(spec.RetryInterval, defaults.RetryInterval), config.RetryInterval); err != nil {
+		return nil, err
+	}
+	if config.ConfirmDelay, err = duration("confirm_delay", first(spec.ConfirmDelay, defaults.ConfirmDelay), 0); err != nil {
+		return nil, err
+	}
+	if config.ConfirmDelay < 0 || (config.ConfirmDelay > 0 && config.Schedule == nil && config.ConfirmDelay >= config.Interval) {
+		return nil, &fieldError{field: "confirm_delay", err: monitor.ErrConfirmDelay}
+	}
+	if spec.RequestMethod != "" {
+		if config.RequestMethod, err = monitor.ParseRequestMethod(spec.RequestMethod); err != nil {
+			return nil, &fieldError{field: "request_method", err: err}
+		}
+		if spec.Body != "" && (config.RequestMethod == http.MethodGet || config.RequestMethod == http.MethodHead) {
+			return nil, &fieldError{field: "body", err: monitor.ErrBodyMethod}
+		}
+	}
+	config.Body = spec.Body
+	config.ContentType = spec.ContentType
+	if config.Fetcher, err = monitor.ParseFetcher(first(spec.Fetcher, defaults.Fetcher)); err != nil {
+		return nil, &fieldError{field: "fetcher", err: err}
+	}
+	if config.WaitSelector = first(spec.WaitSelector, defaults.WaitSelector); config.WaitSelector != "" && config.Fetcher != monitor.FetcherBrowser {
+		return nil, &fieldError{field: "wait_selector", err: fmt.Errorf("wait_selector requires fetcher 'browser'")}
+	}
+
+	methodName := first(spec.Method, defaults.Method)
+	if methodName == "" && len(spec.Match)+len(spec.MatchAbsent) > 0 {
+		methodName = "keyword"
+	}
+	if methodName == "" && spec.Extract != "" {
+		methodName = "value"
+	}
+	if methodName == "" && len(spec.Count) > 0 {
+		methodName = "count"
+	}
+	if methodName == "" && (len(spec.CSVKeys) > 0 || spec.CSVDelimiter != "") {
+		methodName = "csv"
+	}
+	if methodName == "" && spec.TailSeparator != "" {
+		methodName = "tail"
+	}
+	if methodName == "" && (spec.ImageThreshold != 0 || spec.ImageDiffDir != "") {
+		methodName = "image"
+	}
+	if methodName == "" && imap.IsURL(spec.URL) {
+		methodName = "feed"
+	}
+	if config.Method, err = method(methodName); err != nil {
+		return nil, err
+	}
+	if len(spec.Match)+len(spec.MatchAbsent) > 0 {
+		if config.Method != monitor.MethodKeyword {
+			return nil, &fieldError{field: "match", err: fmt.Errorf("match and match_absent require method 'keyword'")}
+		}
+		if config.Matches, err = monitor.ParseMatches(spec.Match, spec.MatchAbsent); err != nil {
+			return nil, &fieldError{field: "match", err: err}
+		}
+	} else if config.Method == monitor.MethodKeyword {
+		return nil, &fieldError{field: "method", err: fmt.Errorf("method 'keyword' requires match or match_absent")}
+	}
+	if len(spec.Count) > 0 {
+		if config.Method != monitor.MethodCount {
+			return nil, &fieldError{field: "count", err: fmt.Errorf("count requires method 'count'")}
+		}
+		if config.Keywords, err = monitor.ParseKeywords(spec.Count); err != nil {
+			return nil, &fieldError{field: "count", err: err}
+		}
+	} else if config.Method == monitor.MethodCount {
+		return nil, &fieldError{field: "method", err: fmt.Errorf("method 'count' requires count")}
+	}
+	if len(spec.CSVKeys) > 0 || spec.CSVDelimiter != "" {
+		if config.Method != monitor.MethodCSV {
+			return nil, &fieldError{field: "csv_keys", err: fmt.Errorf("csv_keys and csv_delimiter require method 'csv'")}
+		}
+		config.CSVKeys = spec.CSVKeys
+		if config.CSVDelimiter, err = monitor.ParseDelimiter(spec.CSVDelimiter); err != nil {
+			return nil, &fieldError{field: "csv_delimiter", err: err}
+		}
+	}
+	if spec.TailSeparator != "" {
+		if config.Method != monitor.MethodTail {
+			return nil, &fieldError{field: "tail_separator", err: fmt.Errorf("tail_separator requires method 'tail'")}
+		}
+		if config.TailSeparator, err = monitor.ParseSeparator(spec.TailSeparator); err != nil {
+			return nil, &fieldError{field: "tail_separator", err: err}
+		}
+	}
+	for _, value := range spec.WebhookMatch {
+		condition, err := monitor.ParseCondition(value)
+		if err != nil {
+			return nil, &fieldError{field: "webhook_match", err: err}
+		}
+		config.WebhookMatch = append(config.WebhookMatch, condition)
+	}
+	config.WebhookFields = spec.WebhookFields
+	config.WebhookSecret = spec.WebhookSecret
+	if spec.ImageThreshold != 0 || spec.ImageDiffDir != "" {
+		field := "image_threshold"
+		if spec.ImageThreshold == 0 {
+			field = "image_diff_dir"
+		}
+		if config.Method != monitor.MethodImage {
+			return nil, &fieldError{field: field, err: fmt.Errorf("image_threshold and image_diff_dir require method 'image'")}
+		}
+		if spec.ImageThreshold < 0 || spec.ImageThreshold > 100 {
+			return nil, &fieldError{field: "image_threshold", err: monitor.ErrImageThreshold}
+		}
+		config.ImageThreshold = spec.ImageThreshold
+		config.ImageDiffDir = spec.ImageDiffDir
+	}
+	if err := valueSpec(spec, config); err != nil {
+		return nil, err
+	}
+	if spec.Paginate != "" {
+		if config.Pagination, err = monitor.ParsePagination(spec.Paginate); err != nil {
+			return nil, &fieldError{field: "paginate", err: err}
+		}
+		if spec.MaxPages < 0 {
+			return nil, &fieldError{field: "max_pages", err: fmt.Errorf("max_pages must not be negative")}
+		}
+		config.Pagination.Items = spec.PaginateItems
+		config.Pagination.MaxPages = spec.MaxPages
+	} else if spec.PaginateItems != "" || spec.MaxPages != 0 {
+		return nil, &fieldError{field: "paginate_items", err: fmt.Errorf("paginate_items and max_pages require paginate")}
+	}
+	if spec.AppendOnly && config.Method != monitor.MethodHash && config.Method != monitor.MethodLength && config.Method != monitor.MethodTail {
+		return nil, &fieldError{field: "append_only", err: fmt.Errorf("append_only requires method 'hash', 'length' or 'tail'")}
+	}
+	config.AppendOnly = spec.AppendOnly
+	if len(spec.Representations) > 0 {
+		if config.Method != monitor.MethodHash && config.Method != monitor.MethodLength {
+			return nil, &fieldError{field: "representations", err: fmt.Errorf("representations require method 'hash' or 'length'")}
+		}
+		config.Representations = spec.Representations
+	}
+	if len(spec.Variants) > 0 {
+		if config.Method != monitor.MethodHash && config.Method != monitor.MethodLength {
+			return nil, &fieldError{field: "variants", err: fmt.Errorf("variants require method 'hash' or 'length'")}
+		}
+		if len(spec.Representations) > 0 {
+			return nil, &fieldError{field: "variants", err: fmt.Errorf("variants can't be combined with representations")}
+		}
+		for _, variant := range spec.Variants {
+			config.Variants = append(config.Variants, monitor.Variant{Name: variant.Name, Headers: variant.Headers})
+		}
+		if err := monitor.ValidateVariants(config.Variants); err != nil {
+			return nil, &fieldError{field: "variants", err: err}
+		}
+	}
+	if len(spec.ExpectedStatus) > 0 {
+		if config.Method != monitor.MethodStatus {
+			return nil, &fieldError{field: "expected_status", err: fmt.Errorf("expected_status requires method 'status'")}
+		}
+		for _, code := range spec.ExpectedStatus {
+			if code < 100 || code > 599 {
+				return nil, &fieldError{field: "expected_status", err: fmt.Errorf("invalid status code %d", code)}
+			}
+		}
+		config.ExpectedStatus = spec.ExpectedStatus
+	}
+
+	retries := defaults.Retries
+	if spec.Retries != nil {
+		retries = spec.Retries
+	}
+	if retries != nil {
+		if *retries < 0 {
+			return nil, &fieldError{field: "retries", err: fmt.Errorf("retries must not be negative")}
+		}
+		config.RetryCount = *retries
+	}
+
+	threshold := spec.FailureThreshold
+	if threshold == 0 {
+		threshold = defaults.FailureThreshold
+	}
+	if threshold < 0 {
+		return nil, &fieldError{field: "failure_threshold", err: monitor.ErrFailureThreshold}
+	}
+	config.FailureThreshold = threshold
+
+	if len(defaults.Headers) > 0 || len(spec.Headers) > 0 {
+		config.Headers = make(map[string]string, len(defaults.Headers)+len(spec.Headers))
+		for key, value := range defaults.Headers {
+			config.Headers[key] = value
+		}
+		for key, value := range spec.Headers {
+			config.Headers[key] = value
+		}
+	}
+
+	if len(defaults.Baggage) > 0 || len(spec.Baggage) > 0 {
+		config.Baggage = make(map[string]string, len(defaults.Baggage)+len(spec.Baggage))
+		for key, value := range defaults.Baggage {
+			config.Baggage[key] = value
+		}
+		for key, value := range spec.Baggage {
+			config.Baggage[key] = value
+		}
+		if err := monitor.ValidateBaggage(config.Baggage); err != nil {
+			return nil, &fieldError{field: "baggage", err: err}
+		}
+	}
+
+	if len(defaults.Labels) > 0 || len(spec.Labels) > 0 {
+		config.Labels = make(map[string]string, len(defaults.Labels)+len(spec.Labels))
+		for key, value := range defaults.Labels {
+			config.Labels[key] = value
+		}
+		for key, value := range spec.Labels {
+			config.Labels[key] = value
+		}
+		if err := monitor.ValidateLabels(config.Labels); err != nil {
+			return nil, &fieldError{field: "labels", err: err}
+		}
+	}
+
+	if len(defaults.Cookies) > 0 || len(spec.Cookies) > 0 {
+		config.Cookies = make(map[string]string, len(defaults.Cookies)+len(spec.Cookies))
+		for name, value := range defaults.Cookies {
+			config.Cookies[name] = value
+		}
+		for name, value := range spec.Cookies {
+			config.Cookies[name] = value
+		}
+		if err := monitor.ValidateCookies(config.Cookies); err != nil {
+			return nil, &fieldError{field: "cookies", err: err}
+		}
+	}
+
+	for i, selector := range spec.Ignore {
+		if _, err := monitor.ParseSelector(selector); err != nil {
+			return nil, &fieldError{field: "ignore", path: []any{i}, err: err}
+		}
+	}
+	for i, selector := range spec.Select {
+		if _, err := monitor.ParseSelector(selector); err != nil {
+			return nil, &fieldError{field: "select", path: []any{i}, err: err}
+		}
+	}
+	for i, selector := range spec.Alert {
+		if _, err := monitor.ParseSelector(selector); err != nil {
+			return nil, &fieldError{field: "alert", path: []any{i}, err: err}
+		}
+	}
+	config.IgnoreSelectors = spec.Ignore
+	config.WatchSelectors = spec.Select
+	config.AlertSelectors = spec.Alert
+
+	for _, pattern := range spec.Filters {
+		filter, err := monitor.NewRegexFilter(pattern, "", "Ignore "+pattern)
+		if err != nil {
+			return nil, &fieldError{field: "filters", err: fmt.Errorf("invalid filter %q: %w", pattern, err)}
+		}
+		config.ContentFilters = append(config.ContentFilters, filter)
+	}
+
+	config.NormalizeWhitespace = defaults.NormalizeWhitespace
+	if spec.NormalizeWhitespace != nil {
+		config.NormalizeWhitespace = *spec.NormalizeWhitespace
+	}
+	config.IgnoreTimestamps = defaults.IgnoreTimestamps
+	if spec.IgnoreTimestamps != nil {
+		config.IgnoreTimestamps = *spec.IgnoreTimestamps
+	}
+	config.NoCache = defaults.NoCache
+	if spec.NoCache != nil {
+		config.NoCache = *spec.NoCache
+	}
+	config.CacheBust = first(spec.CacheBust, defaults.CacheBust)
+	config.CaptureHAR = defaults.CaptureHAR
+	if spec.CaptureHAR != nil {
+		config.CaptureHAR = *spec.CaptureHAR
+	}
+	config.RespectRobotsTxt = defaults.RespectRobotsTxt
+	if spec.RespectRobotsTxt != nil {
+		config.RespectRobotsTxt = *spec.RespectRobotsTxt
+	}
+	config.KeepCookies = defaults.KeepCookies
+	if spec.KeepCookies != nil {
+		config.KeepCookies = *spec.KeepCookies
+	}
+	loginSpec := defaults.Login
+	if spec.Login != nil {
+		loginSpec = spec.Login
+	}
+	if loginSpec != nil {
+		if config.Login, err = loginSpec.login(); err != nil {
+			return nil, err
+		}
+	}
+
+	// A monitor's authentication replaces the default, whatever its kind
+	basicAuth, bearerToken, oauth2Spec := defaults.BasicAuth, defaults.BearerToken, defaults.OAuth2
+	if spec.BasicAuth != nil || spec.BearerToken != "" || spec.OAuth2 != nil {
+		basicAuth, bearerToken, oauth2Spec = spec.BasicAuth, spec.BearerToken, spec.OAuth2
+	}
+	if basicAuth != nil {
+		if basicAuth.User == "" {
+			return nil, &fieldError{field: "basic_auth", err: fmt.Errorf("basic_auth requires a user")}
+		}
+		config.BasicAuth = &monitor.BasicAuth{User: basicAuth.User, Pass: basicAuth.Pass}
+	}
+	config.BearerToken = bearerToken
+	if oauth2Spec != nil {
+		if config.OAuth2, err = oauth2Spec.options(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case config.BasicAuth != nil && (config.BearerToken != "" || config.OAuth2 != nil):
+		return nil, &fieldError{field: "basic_auth", err: monitor.ErrAuthConflict}
+	case config.BearerToken != "" && config.OAuth2 != nil:
+		return nil, &fieldError{field: "bearer_token", err: monitor.ErrAuthConflict}
+	}
+	if err := monitor.ValidateSecrets(config); err != nil {
+		return nil, err
+	}
+
+	// Windows of a monitor replace the default windows
+	maintenance := defaults.Maintenance
+	if spec.Maintenance != nil {
+		maintenance = spec.Maintenance
+	}
+	if config.MaintenanceWindows, err = windows(maintenance); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// windows parses maintenance window specs
+func windows(specs []MaintenanceSpec) (schedule.Windows, error) {
+	var windows schedule.Windows
+	for i, spec := range specs {
+		mode, err := schedule.ParseMode(spec.Mode)
+		if err != nil {
+			return nil, &fieldError{field: "maintenance", path: []any{i, "mode"}, err: err}
+		}
+
+		window, err := schedule.ParseWindow(spec.Window, mode)
+		if err != nil {
+			return nil, &fieldError{field: "maintenance", path: []any{i, "window"}, err: err}
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// first returns the first non-empty value
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// duration parses value, returning fallback if value is empty
+func duration(field, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, &fieldError{field: field, err: fmt.Errorf("invalid %s %q", field, value)}
+	}
+	return d, nil
+}
+
+// valueSpec sets the extractor and limits of method value
+func valueSpec(spec *MonitorSpec, config *monitor.Config) error {
+	if config.Method != monitor.MethodValue {
+		switch {
+		case spec.Extract != "":
+			return &fieldError{field: "extract", err: fmt.Errorf("extract requires method 'value'")}
+		case spec.Below != nil, spec.Above != nil, spec.Delta != 0, spec.DeltaPercent != 0:
+			return &fieldError{field: "method", err: fmt.Errorf("below, above, delta and delta_percent require method 'value'")}
+		}
+		return nil
+	}
+
+	if spec.Extract == "" {
+		return &fieldError{field: "method", err: fmt.Errorf("method 'value' requires extract")}
+	}
+	extractor, err := monitor.ParseExtractor(spec.Extract)
+	if err != nil {
+		return &fieldError{field: "extract", err: err}
+	}
+	config.Extract = extractor
+
+	if spec.Below != nil {
+		config.Thresholds = append(config.Thresholds, monitor.Threshold{Value: *spec.Below})
+	}
+	if spec.Above != nil {
+		config.Thresholds = append(config.Thresholds, monitor.Threshold{Value: *spec.Above, Above: true})
+	}
+	if spec.Delta < 0 {
+		return &fieldError{field: "delta", err: fmt.Errorf("delta must not be negative")}
+	}
+	if spec.DeltaPercent < 0 {
+		return &fieldError{field: "delta_percent", err: fmt.Errorf("delta_percent must not be negative")}
+	}
+	config.Delta = spec.Delta
+	config.DeltaPercent = spec.DeltaPercent
+	return nil
+}
+
+// method parses a detection method name that can be used from a file
+func method(value string) (monitor.ChangeDetectionMethod, error) {
+	m, err := monitor.ParseMethod(value)
+	if err != nil {
+		return m, &fieldError{field: "method", err: err}
+	}
+	if m == monitor.MethodCustom {
+		return m, &fieldError{field: "method", err: fmt.Errorf("method 'custom' cannot be used in a definition file")}
+	}
+	return m, nil
+}
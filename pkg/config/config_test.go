@@ -0,0 +1,1332 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/stretchr/testify/require"
+)
+
+const validFile = `defaults:
+  interval: 10m
+  retries: 1
+  headers:
+    X-Default: yes
+groups:
+  - name: news
+    description: News sites
+notifications:
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com/hawkeye
+monitors:
+  - url: https://news.example.com
+    interval: 1m
+    method: length
+    group: news
+    headers:
+      X-Token: secret
+    filters: ['\d+ comments']
+    notify: [ops]
+  - url: https://example.com
+    normalize_whitespace: true
+`
+
+func TestParseValidFile(t *testing.T) {
+	file, err := Parse("monitors.yaml", []byte(validFile))
+	require.NoError(t, err)
+	require.Len(t, file.Monitors, 2)
+	require.Len(t, file.Groups, 1)
+	require.Equal(t, []string{"ops"}, file.Monitors[0].Notify)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+
+	news := configs[0]
+	require.Equal(t, "https://news.example.com", news.URL)
+	require.Equal(t, time.Minute, news.Interval)
+	require.Equal(t, monitor.MethodLength, news.Method)
+	require.Equal(t, 1, news.RetryCount)
+	require.Equal(t, "secret", news.Headers["X-Token"])
+	require.Equal(t, "yes", news.Headers["X-Default"])
+	require.Len(t, news.ContentFilters, 1)
+	require.Equal(t, "Ignore 3 ", string(news.ContentFilters.Apply([]byte("Ignore 3 42 comments"))))
+
+	// Defaults apply to monitors that don't override them
+	plain := configs[1]
+	require.Equal(t, time.Minute*10, plain.Interval)
+	require.Equal(t, monitor.MethodHash, plain.Method)
+	require.True(t, plain.NormalizeWhitespace)
+
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	require.Contains(t, notifiers, "ops")
+}
+
+func TestParseReportsLineNumbers(t *testing.T) {
+	data := `groups:
+  - name: news
+notifications:
+  - name: ops
+    type: carrier-pigeon
+monitors:
+  - url: https://example.com
+    interval: 5x
+  - url: ftp://example.com
+  - url: https://example.com/news
+    group: sports
+    notify: [ops, pager]
+  - url: https://example.com/filters
+    filters: ['(unclosed']
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.Error(t, err)
+
+	var errs ErrorList
+	require.True(t, errors.As(err, &errs))
+
+	lines := make(map[int]string)
+	for _, e := range errs {
+		lines[e.Line] = e.Message
+	}
+
+	require.Contains(t, lines[5], "unknown notification type")
+	require.Contains(t, lines[8], "invalid interval")
+	require.Contains(t, lines[9], "invalid URL")
+	require.Contains(t, lines[11], "unknown group 'sports'")
+	require.Contains(t, lines[12], "unknown notification 'pager'")
+	require.Contains(t, lines[14], "invalid filter")
+
+	require.Contains(t, err.Error(), "monitors.yaml:8: invalid interval")
+}
+
+func TestNotificationEvents(t *testing.T) {
+	data := `notifications:
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com
+    events: [error, recovery]
+  - name: bad
+    type: webhook
+    url: https://hooks.example.com
+    events: [change, explosion]
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:9: unknown event type 'explosion'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "explosion", "paused", 1)))
+	require.NoError(t, err)
+
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	require.Len(t, notifiers, 2)
+}
+
+func TestSecretPlaceholders(t *testing.T) {
+	data := `notifications:
+  - name: team
+    type: slack
+    url: ${SLACK_WEBHOOK_URL}
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com
+    headers:
+      X-Token: ${OPS_TOKEN
+monitors:
+  - url: https://example.com
+    headers:
+      X-API-Key: file:/run/secrets/api-key
+    bearer_token: ${API_TOKEN}
+  - url: https://example.org
+    basic_auth:
+      user: me
+      pass: ${}
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:9: unterminated placeholder")
+	require.ErrorContains(t, err, "monitors.yaml:15: basic auth password: invalid placeholder")
+	require.NotContains(t, err.Error(), "monitors.yaml:4:", "placeholders are resolved when sending")
+
+	data = strings.Replace(strings.Replace(data, "${OPS_TOKEN", "${OPS_TOKEN}", 1), "${}", "${API_PASSWORD}", 1)
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, "file:/run/secrets/api-key", configs[0].Headers["X-API-Key"])
+	require.Equal(t, "${API_TOKEN}", configs[0].BearerToken)
+}
+
+func TestStreamNotifications(t *testing.T) {
+	data := `notifications:
+  - name: stream
+    type: kafka
+    brokers: [kafka-1:9092, kafka-2]
+    topic: website-changes
+    format: avro
+    schema_registry: http://registry:8081
+  - name: bus
+    type: nats
+    url: http://nats:4222
+    topic: hawkeye changes
+    format: protobuf
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: invalid broker 'kafka-2': must be host:port")
+	require.ErrorContains(t, err, "monitors.yaml:10: invalid URL 'http://nats:4222': must be a nats:// or tls:// URL")
+	require.ErrorContains(t, err, "monitors.yaml:11: invalid topic 'hawkeye changes'")
+	require.ErrorContains(t, err, "monitors.yaml:12: unknown format 'protobuf'")
+
+	data = strings.NewReplacer("kafka-2", "kafka-2:9092", "http://nats", "nats://nats", "hawkeye changes", "hawkeye.changes", "protobuf", "json").Replace(data)
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	require.IsType(t, &notify.KafkaNotifier{}, notifiers["stream"])
+	require.IsType(t, &notify.NATSNotifier{}, notifiers["bus"])
+}
+
+func TestChatNotifications(t *testing.T) {
+	data := `notifications:
+  - name: team
+    type: slack
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+  - name: community
+    type: discord
+    url: not a url
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:7:")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "not a url", "https://discord.com/api/webhooks/1/a", 1)))
+	require.NoError(t, err)
+
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	require.IsType(t, &notify.SlackNotifier{}, notifiers["team"])
+	require.IsType(t, &notify.DiscordNotifier{}, notifiers["community"])
+
+	// Offline, declared notifications are refused rather than dropped
+	offline.Set(true)
+	defer offline.Set(false)
+	_, err = file.Notifiers()
+	require.ErrorIs(t, err, offline.ErrOffline)
+	require.ErrorContains(t, err, "notification 'team'")
+}
+
+func TestPushNotifications(t *testing.T) {
+	data := `notifications:
+  - name: phone
+    type: pushover
+    token: ${PUSHOVER_TOKEN}
+    priorities:
+      change: loud
+  - name: topic
+    type: ntfy
+    url: ntfy.sh/hawkeye
+    priorities:
+      changes: high
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:2: user is required")
+	require.ErrorContains(t, err, "monitors.yaml:6: unknown priority 'loud'")
+	require.ErrorContains(t, err, "monitors.yaml:9: invalid URL 'ntfy.sh/hawkeye'")
+	require.ErrorContains(t, err, "monitors.yaml:11: unknown event type 'changes'")
+
+	data = strings.NewReplacer("    priorities:\n      change: loud", "    user: u123", "ntfy.sh/hawkeye", "https://ntfy.sh/hawkeye", "changes", "change").Replace(data)
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	require.IsType(t, &notify.PushoverNotifier{}, notifiers["phone"])
+	require.IsType(t, &notify.NtfyNotifier{}, notifiers["topic"])
+}
+
+func TestRequestBody(t *testing.T) {
+	data := `monitors:
+  - url: https://api.example.com/graphql
+    request_method: post
+    body: '{"query": "{ status }"}'
+  - url: https://api.example.com/search
+    request_method: GET
+    body: q=status
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:7:")
+	require.ErrorContains(t, err, "request body requires a method")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "GET", "put", 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, "POST", configs[0].RequestMethod)
+	require.Equal(t, `{"query": "{ status }"}`, configs[0].Body)
+	require.Equal(t, "PUT", configs[1].RequestMethod)
+}
+
+func TestCookies(t *testing.T) {
+	data := `defaults:
+  keep_cookies: true
+monitors:
+  - url: https://shop.example.com/cart
+    group: shop
+    cookies:
+      consent: "yes"
+  - url: https://shop.example.com/news
+    keep_cookies: false
+groups:
+  - name: shop
+    share_cookies: true
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.True(t, configs[0].KeepCookies)
+	require.Equal(t, map[string]string{"consent": "yes"}, configs[0].Cookies)
+	require.False(t, configs[1].KeepCookies)
+	require.True(t, file.Groups[0].ShareCookies)
+
+	_, err = Parse("monitors.yaml", []byte(strings.Replace(data, "consent:", "bad name:", 1)))
+	require.ErrorContains(t, err, "monitors.yaml:7:")
+	require.ErrorContains(t, err, "invalid Cookie.Name")
+}
+
+func TestLogin(t *testing.T) {
+	data := `defaults:
+  login:
+    url: https://shop.example.com/login
+    body: user=me&password=secret
+monitors:
+  - url: https://shop.example.com/orders
+  - url: https://api.example.com/orders
+    login:
+      url: https://api.example.com/token
+      body: '{"client_id": "hawkeye"}'
+      token: access_token
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, &monitor.Login{URL: "https://shop.example.com/login", Body: "user=me&password=secret"}, configs[0].Login)
+	require.Equal(t, "access_token", configs[1].Login.Token)
+
+	_, err = Parse("monitors.yaml", []byte(strings.Replace(data, "url: https://api.example.com/token", "url: /token", 1)))
+	require.ErrorContains(t, err, "monitors.yaml:9:")
+	require.ErrorContains(t, err, "login requires an http or https URL")
+}
+
+func TestAuthorization(t *testing.T) {
+	data := `defaults:
+  basic_auth:
+    user: me
+    pass: secret
+monitors:
+  - url: https://intranet.example.com
+  - url: https://api.example.com/status
+    bearer_token: token
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, &monitor.BasicAuth{User: "me", Pass: "secret"}, configs[0].BasicAuth)
+	require.Empty(t, configs[0].BearerToken)
+	// The monitor's bearer token replaces the default basic auth
+	require.Nil(t, configs[1].BasicAuth)
+	require.Equal(t, "token", configs[1].BearerToken)
+
+	_, err = Parse("monitors.yaml", []byte(data+`    basic_auth:
+      user: other
+`))
+	require.ErrorContains(t, err, "monitors.yaml:10:")
+	require.ErrorContains(t, err, "only one of basic auth, a bearer token and OAuth2")
+}
+
+func TestOAuth2(t *testing.T) {
+	data := `defaults:
+  oauth2:
+    token_url: https://auth.example.com/token
+    client_id: hawkeye
+    client_secret: secret
+    scopes: [read]
+monitors:
+  - url: https://api.example.com/orders
+  - url: https://partner.example.com/orders
+    oauth2:
+      token_url: https://partner.example.com/oauth/token
+      client_id: hawkeye
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, &customhttp.OAuth2Options{
+		TokenURL:     "https://auth.example.com/token",
+		ClientID:     "hawkeye",
+		ClientSecret: "secret",
+		Scopes:       []string{"read"},
+	}, configs[0].OAuth2)
+	require.Equal(t, &customhttp.OAuth2Options{TokenURL: "https://partner.example.com/oauth/token", ClientID: "hawkeye"}, configs[1].OAuth2)
+
+	_, err = Parse("monitors.yaml", []byte(strings.Replace(data, "      client_id: hawkeye\n", "", 1)))
+	require.ErrorContains(t, err, "monitors.yaml:11:")
+	require.ErrorContains(t, err, "OAuth2 requires")
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	data := `defaults:
+  maintenance:
+    - window: Sat 02:00-04:00
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    maintenance:
+      - window: 0 3 * * * for 1h
+        mode: silence
+      - window: Someday 02:00-04:00
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:10: invalid window")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "Someday", "Sun", 1)))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Len(t, configs[0].MaintenanceWindows, 1)
+	require.Equal(t, "Sat 02:00-04:00", configs[0].MaintenanceWindows[0].String())
+	require.Len(t, configs[1].MaintenanceWindows, 2)
+	require.Equal(t, schedule.ModeSilence, configs[1].MaintenanceWindows[0].Mode)
+
+	_, err = Parse("monitors.yaml", []byte(strings.Replace(data, "mode: silence", "mode: snooze", 1)))
+	require.ErrorContains(t, err, "monitors.yaml:9: unknown window mode 'snooze'")
+}
+
+func TestSchedule(t *testing.T) {
+	data := `defaults:
+  schedule: "*/10 9-17 * * mon-fri"
+  jitter: 10s
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    interval: 1m
+  - url: https://example.net
+    schedule: "0 25 * * *"
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:9: invalid cron expression")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "0 25", "0 12", 1)))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, "*/10 9-17 * * mon-fri", configs[0].Schedule.String())
+	require.Nil(t, configs[1].Schedule)
+	require.Equal(t, time.Minute, configs[1].Interval)
+	require.Equal(t, "0 12 * * *", configs[2].Schedule.String())
+	require.Equal(t, time.Second*10, configs[2].Jitter)
+}
+
+func TestAt(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    at: 2999-07-01T09:00
+  - url: https://example.org
+    at: 2001-07-01T09:00
+  - url: https://example.net
+    at: soon
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:5: at 2001-07-01T09:00 is in the past")
+	require.ErrorContains(t, err, "monitors.yaml:7: invalid time 'soon'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:3], "\n")))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2999, 7, 1, 9, 0, 0, 0, time.Local), configs[0].At)
+}
+
+func TestUntil(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    until: "json:registration.open=true"
+    deadline: 48h
+  - url: https://example.org
+    until: "regex:("
+  - url: https://example.net
+    deadline: someday
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:6: invalid regex condition")
+	require.ErrorContains(t, err, "monitors.yaml:8: invalid time 'someday'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:4], "\n")))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, "JSON registration.open=true", configs[0].Until.Description())
+	require.WithinDuration(t, time.Now().Add(time.Hour*48), configs[0].Deadline, time.Minute)
+}
+
+func TestPosition(t *testing.T) {
+	data := `defaults:
+  interval: 5s
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    interval: 1s
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+
+	// Settings from the defaults point at the monitor
+	require.Equal(t, "monitors.yaml:4", file.Position(0, "interval"))
+	require.Equal(t, "monitors.yaml:6", file.Position(1, "interval"))
+}
+
+func TestDomains(t *testing.T) {
+	data := `domains:
+  - domain: example.com
+    min_interval: 1m
+    max_parallel: 1
+    delay: 2s
+  - domain: Example.com
+  - domain: example.org
+    delay: soon
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:6: duplicate domain 'Example.com'")
+	require.ErrorContains(t, err, "monitors.yaml:8: invalid delay \"soon\"")
+
+	valid := `domains:
+  - domain: example.com
+    min_interval: 1m
+    max_parallel: 1
+    delay: 2s
+monitors:
+  - url: https://example.com
+`
+	file, err := Parse("monitors.yaml", []byte(valid))
+	require.NoError(t, err)
+
+	policies, err := file.DomainPolicies()
+	require.NoError(t, err)
+	require.Equal(t, map[string]monitor.DomainPolicy{
+		"example.com": {MinInterval: time.Minute, MaxParallel: 1, Delay: time.Second * 2},
+	}, policies)
+}
+
+func TestHostRateLimits(t *testing.T) {
+	data := `defaults:
+  rate_limit: -1
+hosts:
+  - host: api.example.com
+    rate_limit: 10
+  - host: API.example.com
+  - host: example.org
+    rate_limit: -5
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:2: rate_limit must not be negative")
+	require.ErrorContains(t, err, "monitors.yaml:6: duplicate host 'API.example.com'")
+	require.ErrorContains(t, err, "monitors.yaml:8: rate_limit must not be negative")
+
+	valid := `defaults:
+  rate_limit: 30
+hosts:
+  - host: api.example.com
+    rate_limit: 10
+monitors:
+  - url: https://example.com
+`
+	file, err := Parse("monitors.yaml", []byte(valid))
+	require.NoError(t, err)
+	require.Equal(t, 30, file.Defaults.RateLimit)
+	require.Equal(t, map[string]int{"api.example.com": 10}, file.HostRateLimits())
+}
+
+func TestProxy(t *testing.T) {
+	data := `defaults:
+  proxy: http://proxy.example.com:3128
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    proxy: socks5://localhost:1080
+  - url: https://example.net
+    proxy: ftp://proxy.example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:8: unsupported proxy scheme 'ftp'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:6], "\n")))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, "http://proxy.example.com:3128", configs[0].ProxyURL.String())
+	require.Equal(t, "socks5://localhost:1080", configs[1].ProxyURL.String())
+}
+
+func TestAcceptEncoding(t *testing.T) {
+	data := `defaults:
+  accept_encoding: gzip
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    accept_encoding: identity
+  - url: https://example.net
+    accept_encoding: zstd
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:8: unsupported content encoding 'zstd'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:6], "\n")))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, "gzip", configs[0].AcceptEncoding)
+	require.Equal(t, "identity", configs[1].AcceptEncoding)
+}
+
+func TestCacheBusting(t *testing.T) {
+	data := `defaults:
+  no_cache: true
+  cache_bust: _cb
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    no_cache: false
+    cache_bust: nocache
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.True(t, configs[0].NoCache)
+	require.Equal(t, "_cb", configs[0].CacheBust)
+	require.False(t, configs[1].NoCache)
+	require.Equal(t, "nocache", configs[1].CacheBust)
+}
+
+func TestCaptureHAR(t *testing.T) {
+	data := `defaults:
+  capture_har: true
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    capture_har: false
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.True(t, configs[0].CaptureHAR)
+	require.False(t, configs[1].CaptureHAR)
+}
+
+func TestPagination(t *testing.T) {
+	data := `monitors:
+  - url: https://api.example.com/items
+    paginate: cursor:meta.next=after
+    paginate_items: data
+    max_pages: 20
+  - url: https://api.example.org/items
+    paginate: link
+  - url: https://api.example.net/items
+    paginate_items: data
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:9: paginate_items and max_pages require paginate")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:7], "\n")))
+	require.NoError(t, err)
+
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, &monitor.Pagination{Next: monitor.PageCursor, Path: "meta.next", Param: "after", Items: "data", MaxPages: 20}, configs[0].Pagination)
+	require.Equal(t, &monitor.Pagination{Next: monitor.PageLink}, configs[1].Pagination)
+
+	_, err = Parse("monitors.yaml", []byte("monitors:\n  - url: https://example.com\n    paginate: next\n"))
+	require.ErrorContains(t, err, "monitors.yaml:3: invalid pagination 'next'")
+}
+
+func TestAppendOnly(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com/access.log
+    append_only: true
+  - url: https://example.com/export.csv
+    method: csv
+    append_only: true
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:6: append_only requires method 'hash', 'length' or 'tail'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "method: csv", "method: length", 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.True(t, configs[0].AppendOnly)
+	require.Equal(t, monitor.MethodLength, configs[1].Method)
+}
+
+func TestTailSeparator(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com/CHANGELOG.txt
+    tail_separator: paragraph
+  - url: https://example.com/app.log
+    method: hash
+    tail_separator: "---"
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:6: tail_separator requires method 'tail'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "method: hash", "method: tail", 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodTail, configs[0].Method)
+	require.Equal(t, monitor.SeparatorParagraph, configs[0].TailSeparator)
+	require.Equal(t, "---", configs[1].TailSeparator)
+}
+
+func TestFailureThreshold(t *testing.T) {
+	data := `defaults:
+  failure_threshold: 3
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    failure_threshold: 5
+  - url: https://example.net
+    failure_threshold: -1
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:8: failure threshold must not be negative")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "-1", "1", 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, 3, configs[0].FailureThreshold)
+	require.Equal(t, 5, configs[1].FailureThreshold)
+	require.Equal(t, 1, configs[2].FailureThreshold)
+}
+
+func TestConfirmDelay(t *testing.T) {
+	data := `defaults:
+  confirm_delay: 30s
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    interval: 1m
+    confirm_delay: 2m
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:7: confirm delay must not be negative and must be shorter than the interval")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "2m", "10s", 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, configs[0].ConfirmDelay)
+	require.Equal(t, 10*time.Second, configs[1].ConfirmDelay)
+}
+
+func TestExpectedStatus(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    expected_status: [200]
+  - url: https://example.org
+    method: status
+    expected_status: [200, 700]
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:3: expected_status requires method 'status'")
+	require.ErrorContains(t, err, "monitors.yaml:6: invalid status code 700")
+
+	data = `monitors:
+  - url: https://example.com
+    method: status
+    expected_status: [200, 204]
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodStatus, configs[0].Method)
+	require.Equal(t, []int{200, 204}, configs[0].ExpectedStatus)
+}
+
+func TestMatch(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    method: hash
+    match: [in stock]
+  - url: https://example.org
+    method: keyword
+  - url: https://example.net
+    match: ['regex:(']
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: match and match_absent require method 'keyword'")
+	require.ErrorContains(t, err, "monitors.yaml:6: method 'keyword' requires match or match_absent")
+	require.ErrorContains(t, err, "monitors.yaml:8: invalid regex condition")
+
+	data = `monitors:
+  - url: https://example.com
+    match: ['regex:in\s+stock']
+    match_absent: [out of stock]
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodKeyword, configs[0].Method)
+	require.Len(t, configs[0].Matches, 2)
+	require.False(t, configs[0].Matches[0].Absent)
+	require.Equal(t, "pattern /in\\s+stock/", configs[0].Matches[0].Condition.Description())
+	require.True(t, configs[0].Matches[1].Absent)
+}
+
+func TestCount(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    method: hash
+    count: [Go developer]
+  - url: https://example.org
+    method: count
+  - url: https://example.net
+    count: ['regex:(']
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: count requires method 'count'")
+	require.ErrorContains(t, err, "monitors.yaml:6: method 'count' requires count")
+	require.ErrorContains(t, err, "monitors.yaml:8: invalid regex keyword")
+
+	data = `monitors:
+  - url: https://jobs.example.com
+    count: [Go developer, 'regex:(?i)rust (developer|engineer)']
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodCount, configs[0].Method)
+	require.Len(t, configs[0].Keywords, 2)
+	require.Equal(t, "regex:(?i)rust (developer|engineer)", configs[0].Keywords[1].String())
+}
+
+func TestCSV(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    method: hash
+    csv_keys: [id]
+  - url: https://example.org
+    csv_delimiter: ';;'
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: csv_keys and csv_delimiter require method 'csv'")
+	require.ErrorContains(t, err, "monitors.yaml:6: invalid delimiter")
+
+	data = `monitors:
+  - url: https://example.com/prices.tsv
+    csv_keys: [region, sku]
+    csv_delimiter: tab
+  - url: https://example.com/stock.csv
+    method: csv
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodCSV, configs[0].Method)
+	require.Equal(t, []string{"region", "sku"}, configs[0].CSVKeys)
+	require.Equal(t, '\t', configs[0].CSVDelimiter)
+	require.Equal(t, monitor.MethodCSV, configs[1].Method)
+	require.Empty(t, configs[1].CSVKeys)
+}
+
+func TestImage(t *testing.T) {
+	_, err := Parse("monitors.yaml", []byte(`monitors:
+  - url: https://example.com/chart.png
+    method: hash
+    image_diff_dir: diffs
+`))
+	require.ErrorContains(t, err, "monitors.yaml:4: image_threshold and image_diff_dir require method 'image'")
+
+	_, err = Parse("monitors.yaml", []byte(`monitors:
+  - url: https://example.com/chart.png
+    image_threshold: 150
+`))
+	require.ErrorContains(t, err, "monitors.yaml:3: image threshold must be between 0 and 100")
+
+	file, err := Parse("monitors.yaml", []byte(`monitors:
+  - url: https://example.com/chart.png
+    image_threshold: 0.5
+    image_diff_dir: diffs
+  - url: https://example.com/badge.png
+    method: image
+`))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodImage, configs[0].Method)
+	require.Equal(t, 0.5, configs[0].ImageThreshold)
+	require.Equal(t, "diffs", configs[0].ImageDiffDir)
+	require.Equal(t, monitor.MethodImage, configs[1].Method)
+}
+
+func TestValue(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    method: hash
+    extract: css:.price
+  - url: https://example.org
+    extract: xpath://price
+  - url: https://example.net
+    extract: json:price
+    delta: -1
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: extract requires method 'value'")
+	require.ErrorContains(t, err, "monitors.yaml:6: unknown extractor 'xpath'")
+	require.ErrorContains(t, err, "monitors.yaml:9: delta must not be negative")
+
+	data = `monitors:
+  - url: https://example.com
+    extract: css:.price
+    below: 100
+    delta_percent: 5
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodValue, configs[0].Method)
+	require.Equal(t, `selector ".price"`, configs[0].Extract.Description())
+	require.Equal(t, []monitor.Threshold{{Value: 100}}, configs[0].Thresholds)
+	require.Equal(t, 5.0, configs[0].DeltaPercent)
+}
+
+func TestSelectors(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    ignore: ['xpath://div[']
+  - url: https://example.org
+    select: ['div[']
+  - url: https://example.net/feed.xml
+    select: ['xpath://item/title']
+    ignore: [.ad]
+    alert: ['xpath://item[1]/title']
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:3: invalid XPath")
+	require.ErrorContains(t, err, "monitors.yaml:5: invalid selector")
+
+	lines := strings.Split(data, "\n")
+	file, err := Parse("monitors.yaml", []byte(lines[0]+"\n"+strings.Join(lines[5:], "\n")))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, []string{"xpath://item/title"}, configs[0].WatchSelectors)
+	require.Equal(t, []string{".ad"}, configs[0].IgnoreSelectors)
+	require.Equal(t, []string{"xpath://item[1]/title"}, configs[0].AlertSelectors)
+
+	_, err = Parse("monitors.yaml", []byte("monitors:\n  - url: https://example.com\n    alert: ['#price', 'xpath://div[']\n"))
+	require.ErrorContains(t, err, "monitors.yaml:3: invalid XPath")
+}
+
+func TestBaggage(t *testing.T) {
+	data := `defaults:
+  interval: 1m
+  baggage:
+    tenant: acme
+monitors:
+  - url: https://example.com
+    baggage:
+      trace: abc
+  - url: https://example.org
+    baggage:
+      "bad key": x
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:11: invalid baggage key 'bad key'")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:8], "\n")))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme", "trace": "abc"}, configs[0].Baggage)
+}
+
+func TestRepresentations(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    method: status
+    representations: [application/json]
+  - url: https://example.org
+    representations: [text/html, application/json]
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: representations require method 'hash' or 'length'")
+
+	data = `monitors:
+  - url: https://example.org
+    representations: [text/html, application/json]
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, []string{"text/html", "application/json"}, configs[0].Representations)
+}
+
+func TestVariants(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    variants:
+      - name: de
+        headers: {Accept-Language: de}
+      - name: de
+        headers: {Accept-Language: de-AT}
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: duplicate variant 'de'")
+
+	data = `monitors:
+  - url: https://example.com
+    variants:
+      - name: de
+        headers: {Accept-Language: de}
+      - name: mobile
+        headers:
+          User-Agent: Mozilla/5.0 (iPhone)
+          Cookie: "consent=yes"
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, []monitor.Variant{
+		{Name: "de", Headers: map[string]string{"Accept-Language": "de"}},
+		{Name: "mobile", Headers: map[string]string{"User-Agent": "Mozilla/5.0 (iPhone)", "Cookie": "consent=yes"}},
+	}, configs[0].Variants)
+}
+
+func TestTLS(t *testing.T) {
+	data := `defaults:
+  tls:
+    min_version: "1.4"
+monitors:
+  - url: https://example.com
+    tls:
+      ca_file: missing.pem
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:3: unknown TLS version '1.4'")
+
+	data = `defaults:
+  tls:
+    min_version: "1.2"
+monitors:
+  - url: https://example.com
+  - url: https://example.org
+    tls:
+      ca_file: missing.pem
+`
+	_, err = Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:8: error reading CA file")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Join(strings.Split(data, "\n")[:5], "\n")))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), configs[0].TLS.MinVersion)
+}
+
+func TestParseUnknownField(t *testing.T) {
+	data := `monitors:
+  - url: https://example.com
+    intervall: 5m
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "monitors.yaml:3:")
+	require.Contains(t, err.Error(), "intervall")
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Parse("monitors.yaml", []byte("monitors:\n  - url: [unclosed\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "monitors.yaml:")
+}
+
+func TestParseDuplicatesAndEmpty(t *testing.T) {
+	_, err := Parse("monitors.yaml", []byte(""))
+	require.ErrorContains(t, err, ErrNoMonitors.Error())
+
+	data := `groups:
+  - name: news
+  - name: news
+monitors:
+  - url: https://example.com
+  - url: https://example.com
+`
+	_, err = Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:3: duplicate group 'news'")
+	require.ErrorContains(t, err, "monitors.yaml:6: duplicate monitor")
+}
+
+func TestParseInvalidDefaults(t *testing.T) {
+	data := `defaults:
+  interval: never
+  method: magic
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+
+	var errs ErrorList
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	require.Equal(t, 2, errs[0].Line)
+	require.Equal(t, 3, errs[1].Line)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitors.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(validFile), 0644))
+
+	file, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, file.Monitors, 2)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestCloudNotifications(t *testing.T) {
+	data := `notifications:
+  - name: lambda
+    type: sns
+    topic: changes
+  - name: functions
+    type: pubsub
+    topic: projects/acme/changes
+  - name: empty
+    type: pubsub
+monitors:
+  - url: https://example.com
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:4: invalid topic 'changes': must be an ARN like arn:aws:sns:<region>:<account>:<topic>")
+	require.ErrorContains(t, err, "monitors.yaml:7: invalid topic 'projects/acme/changes': must be projects/<project>/topics/<topic>")
+	require.ErrorContains(t, err, "monitors.yaml:8: topic is required")
+
+	data = strings.NewReplacer("topic: changes", "topic: arn:aws:sns:eu-west-1:123456789012:changes", "acme/changes", "acme/topics/changes").Replace(data)
+	data = strings.Replace(data, "    type: pubsub\nmonitors", "    type: pubsub\n    topic: projects/acme/topics/empty\nmonitors", 1)
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	require.IsType(t, &notify.SNSNotifier{}, notifiers["lambda"])
+	require.IsType(t, &notify.PubSubNotifier{}, notifiers["functions"])
+}
+
+func TestRoutes(t *testing.T) {
+	data := `groups:
+  - name: shop
+notifications:
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com/ops
+  - name: pager
+    type: webhook
+    url: https://hooks.example.com/pager
+routes:
+  - notify: [pager]
+    groups: [shop]
+    min_severity: critical
+  - notify: [ops, pager]
+    match: 'price'
+    quiet_hours: ["Sat,Sun 00:00-24:00 UTC"]
+monitors:
+  - url: https://shop.example.com
+    group: shop
+  - url: https://example.com
+    notify: [ops]
+`
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	lists, err := file.MonitorNotifiers(notifiers)
+	require.NoError(t, err)
+	require.Len(t, lists[0], 2)
+	require.Len(t, lists[1], 2)
+	// A notification the monitor lists itself is sent every change
+	require.Same(t, notifiers["ops"], lists[1][0])
+
+	data = strings.NewReplacer("groups: [shop]", "groups: [shop, blog]", "min_severity: critical", "min_severity: fatal",
+		"'price'", "'(price'", "notify: [ops, pager]", "notify: [ops, sms]").Replace(data)
+	_, err = Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:12: unknown group 'blog'")
+	require.ErrorContains(t, err, "monitors.yaml:13: unknown severity 'fatal'")
+	require.ErrorContains(t, err, "monitors.yaml:14: unknown notification 'sms'")
+	require.ErrorContains(t, err, "monitors.yaml:15: invalid match pattern")
+}
+
+func TestMailboxMonitor(t *testing.T) {
+	data := `monitors:
+  - url: imaps://alerts@imap.example.com/INBOX?search=UNSEEN
+    basic_auth:
+      user: alerts@example.com
+      pass: ${IMAP_PASSWORD}
+  - url: imaps://imap.example.com/INBOX?limit=-1
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:6: invalid mailbox URL")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "?limit=-1", "", 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, monitor.MethodFeed, configs[0].Method)
+}
+
+func TestWebhookMonitor(t *testing.T) {
+	data := `monitors:
+  - url: webhook://deploys
+    webhook_match: ["json:status!=failed"]
+    webhook_fields: [service, version]
+    webhook_secret: ${DEPLOY_SECRET}
+  - url: https://example.com
+    webhook_match: ["regex:("]
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:7: invalid regex condition")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, `"regex:("`, `"text:ok"`, 1)))
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Len(t, configs[0].WebhookMatch, 1)
+	require.Equal(t, []string{"service", "version"}, configs[0].WebhookFields)
+	require.Equal(t, "${DEPLOY_SECRET}", configs[0].WebhookSecret)
+	require.Len(t, configs[1].WebhookMatch, 1)
+}
+
+func TestComposites(t *testing.T) {
+	data := `groups:
+  - name: shop
+notifications:
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com/ops
+  - name: pager
+    type: webhook
+    url: https://hooks.example.com/pager
+routes:
+  - notify: [pager]
+    min_severity: warning
+  - notify: [ops]
+    groups: [shop]
+composites:
+  - name: checkout-outage
+    rule: failed(group:shop) >= 3
+    window: 15m
+    notify: [ops]
+  - name: checkout-outage
+    rule: changed(https://shop.example.com) and
+  - name: mirror
+    rule: changed(https://shop.example.com) and unchanged(https://mirror.example.com)
+    window: -1m
+    notify: [team]
+monitors:
+  - url: https://shop.example.com
+    group: shop
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, "monitors.yaml:20: duplicate composite 'checkout-outage'")
+	require.ErrorContains(t, err, "monitors.yaml:21: invalid rule")
+	require.ErrorContains(t, err, "monitors.yaml:24: composite window must not be negative")
+	require.ErrorContains(t, err, "monitors.yaml:25: unknown notification 'team'")
+
+	data = strings.Replace(data, "  - name: checkout-outage\n    rule: changed(https://shop.example.com) and\n", "", 1)
+	data = strings.Replace(data, "    window: -1m\n    notify: [team]\n", "", 1)
+	file, err := Parse("monitors.yaml", []byte(data))
+	require.NoError(t, err)
+	composites, err := file.CompositeConfigs()
+	require.NoError(t, err)
+	require.Len(t, composites, 2)
+	require.Equal(t, 15*time.Minute, composites[0].Window)
+	require.Equal(t, "changed(https://shop.example.com) and unchanged(https://mirror.example.com)", composites[1].Rule.String())
+
+	// Composites have no group, so only routes without groups send theirs
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+	lists, err := file.CompositeNotifiers(notifiers)
+	require.NoError(t, err)
+	require.Len(t, lists[0], 2)
+	require.Len(t, lists[1], 1)
+}
+
+func TestNotificationDelivery(t *testing.T) {
+	data := `notifications:
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com/ops
+    throttle: 15m
+    deduplicate: true
+    digest: 1h
+  - name: team
+    type: slack
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+    throttle: often
+    digest: -1h
+monitors:
+  - url: https://example.com
+    notify: [ops]
+`
+	_, err := Parse("monitors.yaml", []byte(data))
+	require.ErrorContains(t, err, `monitors.yaml:11: invalid throttle "often"`)
+	require.ErrorContains(t, err, "monitors.yaml:12: digest must not be negative")
+
+	file, err := Parse("monitors.yaml", []byte(strings.Replace(data, "    throttle: often\n    digest: -1h\n", "", 1)))
+	require.NoError(t, err)
+	notifiers, err := file.Notifiers()
+	require.NoError(t, err)
+
+	// Changes are held back until the digest is flushed, so nothing is posted
+	// yet
+	require.NoError(t, notifiers["ops"].Notify(context.Background(), monitor.Change{URL: "https://example.com", HasChanged: true}))
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem found in a definition file
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+// ErrorList is a list of problems found in a definition file
+type ErrorList []*Error
+
+// Error implements the error interface
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// fieldError is an error tied to a field of a monitor spec, optionally
+// followed by the path to an element within the field
+type fieldError struct {
+	field string
+	path  []any
+	err   error
+}
+
+// Error implements the error interface
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// yamlLinePattern matches the line prefix of yaml.v3 error messages
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlError converts a yaml.v3 decoding error into an ErrorList
+func yamlError(name string, err error) error {
+	var messages []string
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	errs := make(ErrorList, 0, len(messages))
+	for _, message := range messages {
+		e := &Error{File: name, Message: strings.TrimPrefix(message, "yaml: ")}
+		if match := yamlLinePattern.FindStringSubmatch(message); match != nil {
+			e.Line, _ = strconv.Atoi(match[1])
+			e.Message = match[2]
+		}
+		errs = append(errs, e)
+	}
+
+	return errs
+}
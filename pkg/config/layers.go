@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layer is one definition file of a layered configuration
+type Layer struct {
+	// Name is used in error messages, e.g. the base name of the file
+	Name string
+	Data []byte
+}
+
+// listKeys names the field identifying the items of each top-level list.
+// Overlay items replace or extend the base item with the same key; other
+// lists are replaced as a whole.
+var listKeys = map[string]string{
+	"groups":        "name",
+	"notifications": "name",
+	"composites":    "name",
+	"monitors":      "url",
+	"domains":       "domain",
+	"hosts":         "host",
+}
+
+// LoadLayers reads definition files and merges them in order, see
+// ParseLayers
+func LoadLayers(paths []string, environment string) (*File, error) {
+	layers := make([]Layer, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, Layer{Name: filepath.Base(path), Data: data})
+	}
+
+	return ParseLayers(layers, environment)
+}
+
+// ParseLayers merges the YAML documents of layers, in order, into a single
+// definition file and validates it. Each layer may hold several documents
+// separated by "---". Documents that set environment are only applied when
+// it matches the given environment, so one file can hold a base and its
+// overlays for dev and prod.
+//
+// Later documents override the settings of earlier ones. Mappings are merged
+// key by key; monitors, groups, notifications, domains and hosts are merged
+// item by item, matched by URL, name, domain or host; any other value,
+// including other lists, is replaced.
+func ParseLayers(layers []Layer, environment string) (*File, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("no definition files given")
+	}
+
+	loc := locator{origin: make(map[*yaml.Node]string)}
+	for _, layer := range layers {
+		docs, err := parseDocuments(layer)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			if env := environmentOf(doc); env != "" && env != environment {
+				continue
+			}
+			loc.track(doc, layer.Name)
+			loc.root = mergeNode(loc.root, doc, true)
+		}
+	}
+
+	var file File
+	if loc.root != nil {
+		if err := loc.root.Decode(&file); err != nil {
+			return nil, yamlError(layers[0].Name, err)
+		}
+	}
+
+	file.name = layers[0].Name
+	file.loc = loc
+	if errs := file.validate(file.name, file.loc); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &file, nil
+}
+
+// parseDocuments parses the documents of a layer, rejecting unknown fields
+func parseDocuments(layer Layer) ([]*yaml.Node, error) {
+	// Decoding into File reports unknown fields with their line
+	strict := yaml.NewDecoder(bytes.NewReader(layer.Data))
+	strict.KnownFields(true)
+	for {
+		var file File
+		err := strict.Decode(&file)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, yamlError(layer.Name, err)
+		}
+	}
+
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(layer.Data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, yamlError(layer.Name, err)
+		}
+		if root := documentRoot(&doc); root != nil && root.Kind == yaml.MappingNode {
+			docs = append(docs, root)
+		}
+	}
+
+	return docs, nil
+}
+
+// documentRoot returns the top node of a document
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// environmentOf returns the environment a document applies to, if any
+func environmentOf(doc *yaml.Node) string {
+	if node := mappingValue(doc, "environment"); node != nil {
+		return node.Value
+	}
+	return ""
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mergeNode merges overlay into base and returns the result. top is set for
+// the document roots, whose lists in listKeys are merged item by item.
+func mergeNode(base, overlay *yaml.Node, top bool) *yaml.Node {
+	if base == nil || base.Kind != overlay.Kind || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+
+		j := mappingIndex(base, key.Value)
+		if j < 0 {
+			base.Content = append(base.Content, key, value)
+			continue
+		}
+
+		if itemKey, ok := listKeys[key.Value]; top && ok {
+			base.Content[j+1] = mergeList(base.Content[j+1], value, itemKey)
+		} else {
+			base.Content[j+1] = mergeNode(base.Content[j+1], value, false)
+		}
+	}
+
+	return base
+}
+
+// mergeList merges the items of two lists of mappings, matching them by the
+// value of itemKey. Unmatched overlay items are appended.
+func mergeList(base, overlay *yaml.Node, itemKey string) *yaml.Node {
+	if base.Kind != yaml.SequenceNode || overlay.Kind != yaml.SequenceNode {
+		return overlay
+	}
+
+	for _, item := range overlay.Content {
+		merged := false
+		if id := mappingValue(item, itemKey); id != nil {
+			for k, existing := range base.Content {
+				if other := mappingValue(existing, itemKey); other != nil && other.Value == id.Value {
+					base.Content[k] = mergeNode(existing, item, false)
+					merged = true
+					break
+				}
+			}
+		}
+		if !merged {
+			base.Content = append(base.Content, item)
+		}
+	}
+
+	return base
+}
+
+// mappingIndex returns the index of key in the content of a mapping node, or
+// -1 if it isn't there
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
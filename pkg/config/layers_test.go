@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const baseLayer = `defaults:
+  interval: 10m
+  retries: 1
+notifications:
+  - name: ops
+    type: webhook
+    url: https://hooks.example.com/dev
+monitors:
+  - url: https://example.com
+    filters: ['\d+ visitors']
+    notify: [ops]
+  - url: https://example.com/news
+    interval: 1m
+---
+environment: dev
+defaults:
+  interval: 1h
+---
+environment: prod
+defaults:
+  interval: 2m
+monitors:
+  - url: https://example.com/status
+`
+
+func TestParseLayers(t *testing.T) {
+	prod := Layer{Name: "prod.yaml", Data: []byte(`notifications:
+  - name: ops
+    url: https://hooks.example.com/prod
+monitors:
+  - url: https://example.com/news
+    interval: 30s
+`)}
+
+	file, err := ParseLayers([]Layer{{Name: "monitors.yaml", Data: []byte(baseLayer)}, prod}, "prod")
+	require.NoError(t, err)
+
+	// The prod document of the base file and the prod file both apply
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Len(t, configs, 3)
+	require.Equal(t, time.Minute*2, configs[0].Interval)
+	require.Equal(t, 1, configs[0].RetryCount)
+	require.Len(t, configs[0].ContentFilters, 1)
+	require.Equal(t, time.Second*30, configs[1].Interval)
+	require.Equal(t, "https://example.com/status", configs[2].URL)
+
+	// Merged items keep the settings the overlay doesn't set
+	require.Len(t, file.Notifications, 1)
+	require.Equal(t, "webhook", file.Notifications[0].Type)
+	require.Equal(t, "https://hooks.example.com/prod", file.Notifications[0].URL)
+
+	// Documents of other environments are skipped
+	file, err = Parse("monitors.yaml", []byte(baseLayer))
+	require.NoError(t, err)
+	configs, err = file.Configs()
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	require.Equal(t, time.Minute*10, configs[0].Interval)
+}
+
+func TestParseLayersReportsFile(t *testing.T) {
+	overlay := Layer{Name: "prod.yaml", Data: []byte(`monitors:
+  - url: https://example.com
+    interval: soon
+`)}
+	_, err := ParseLayers([]Layer{{Name: "monitors.yaml", Data: []byte(baseLayer)}, overlay}, "")
+	require.ErrorContains(t, err, "prod.yaml:3: invalid interval")
+
+	unknown := Layer{Name: "prod.yaml", Data: []byte("defaults:\n  intervall: 1m\n")}
+	_, err = ParseLayers([]Layer{{Name: "monitors.yaml", Data: []byte(baseLayer)}, unknown}, "")
+	require.ErrorContains(t, err, "prod.yaml:2:")
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "monitors.yaml")
+	overlay := filepath.Join(dir, "dev.yaml")
+	require.NoError(t, os.WriteFile(base, []byte(baseLayer), 0o644))
+	require.NoError(t, os.WriteFile(overlay, []byte("defaults:\n  retries: 5\n"), 0o644))
+
+	file, err := LoadLayers([]string{base, overlay}, "dev")
+	require.NoError(t, err)
+	configs, err := file.Configs()
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, configs[0].Interval)
+	require.Equal(t, 5, configs[0].RetryCount)
+
+	_, err = LoadLayers(nil, "")
+	require.Error(t, err)
+}
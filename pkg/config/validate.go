@@ -0,0 +1,478 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/imap"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/notify"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// locator finds the file and line numbers of nodes in a parsed, possibly
+// merged, YAML document
+type locator struct {
+	root *yaml.Node
+	// origin is the name of the file each node was parsed from
+	origin map[*yaml.Node]string
+}
+
+// track records name as the origin of node and its descendants
+func (l locator) track(node *yaml.Node, name string) {
+	l.origin[node] = name
+	for _, child := range node.Content {
+		l.track(child, name)
+	}
+}
+
+// find returns the node at path, where each element is either a mapping key
+// or a sequence index. If the path cannot be followed completely the deepest
+// node found is returned.
+func (l locator) find(path ...any) *yaml.Node {
+	node := l.root
+	if node == nil {
+		return nil
+	}
+
+	for _, element := range path {
+		next := l.child(node, element)
+		if next == nil {
+			break
+		}
+		node = next
+	}
+
+	return node
+}
+
+// line returns the line of the node at path, see find
+func (l locator) line(path ...any) int {
+	if node := l.find(path...); node != nil {
+		return node.Line
+	}
+	return 0
+}
+
+// file returns the name of the file the node at path came from, or name if
+// it is unknown
+func (l locator) file(name string, path ...any) string {
+	if origin, ok := l.origin[l.find(path...)]; ok {
+		return origin
+	}
+	return name
+}
+
+// child returns the child of node identified by a key or index
+func (l locator) child(node *yaml.Node, element any) *yaml.Node {
+	switch key := element.(type) {
+	case string:
+		return mappingValue(node, key)
+	case int:
+		if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+			return node.Content[key]
+		}
+	}
+	return nil
+}
+
+// validator collects errors for a file
+type validator struct {
+	name string
+	loc  locator
+	errs ErrorList
+}
+
+// add records an error at the node found at path
+func (v *validator) add(message string, path ...any) {
+	v.errs = append(v.errs, &Error{File: v.loc.file(v.name, path...), Line: v.loc.line(path...), Message: message})
+}
+
+// validate checks the file for problems and returns every problem found
+func (f *File) validate(name string, loc locator) ErrorList {
+	v := &validator{name: name, loc: loc}
+
+	f.validateDefaults(v)
+	defaultsValid := len(v.errs) == 0
+
+	groups := make(map[string]bool)
+	for i, group := range f.Groups {
+		switch {
+		case group.Name == "":
+			v.add("group name is required", "groups", i)
+		case groups[group.Name]:
+			v.add(fmt.Sprintf("duplicate group '%s'", group.Name), "groups", i, "name")
+		}
+		groups[group.Name] = true
+	}
+
+	notifications := make(map[string]bool)
+	for i, spec := range f.Notifications {
+		switch {
+		case spec.Name == "":
+			v.add("notification name is required", "notifications", i)
+		case notifications[spec.Name]:
+			v.add(fmt.Sprintf("duplicate notification '%s'", spec.Name), "notifications", i, "name")
+		}
+		notifications[spec.Name] = true
+
+		// Invalid events and formats are reported individually below
+		hook := spec.Type == NotificationWebhook || spec.Type == NotificationSlack || spec.Type == NotificationDiscord || spec.Type == NotificationNtfy
+		stream := spec.Type == NotificationKafka || spec.Type == NotificationNATS
+		cloud := spec.Type == NotificationSNS || spec.Type == NotificationPubSub
+		push := spec.Type == NotificationPushover || spec.Type == NotificationNtfy
+		if _, err := spec.notifier(); err != nil && !hook && !stream && !cloud && !push {
+			v.add(err.Error(), "notifications", i, "type")
+		}
+		for j, name := range spec.Events {
+			if _, err := monitor.ParseEventType(name); err != nil {
+				v.add(err.Error(), "notifications", i, "events", j)
+			}
+		}
+		for _, entry := range []struct{ field, value string }{{"throttle", spec.Throttle}, {"digest", spec.Digest}} {
+			if d, err := duration(entry.field, entry.value, 0); err != nil {
+				v.add(err.Error(), "notifications", i, entry.field)
+			} else if d < 0 {
+				v.add(fmt.Sprintf("%s must not be negative", entry.field), "notifications", i, entry.field)
+			}
+		}
+		if stream {
+			v.checkStream(spec, i)
+		}
+		if cloud {
+			v.checkCloudTopic(spec, i)
+		}
+		if push {
+			v.checkPush(spec, i)
+		}
+		if hook {
+			// URLs with secret placeholders are checked once resolved
+			check := checkURL
+			if secret.Contains(spec.URL) {
+				check = secret.Validate
+			}
+			if err := check(spec.URL); err != nil {
+				v.add(err.Error(), "notifications", i, "url")
+			}
+		}
+		for key, value := range spec.Headers {
+			if err := secret.Validate(value); err != nil {
+				v.add(err.Error(), "notifications", i, "headers", key)
+			}
+		}
+		if err := secret.Validate(spec.Secret); err != nil {
+			v.add(err.Error(), "notifications", i, "secret")
+		}
+	}
+
+	for i := range f.Routes {
+		spec := &f.Routes[i]
+		if len(spec.Notify) == 0 {
+			v.add("notify is required", "routes", i)
+		}
+		for j, name := range spec.Notify {
+			if !notifications[name] {
+				v.add(fmt.Sprintf("unknown notification '%s'", name), "routes", i, "notify", j)
+			}
+		}
+		for j, name := range spec.Groups {
+			if !groups[name] {
+				v.add(fmt.Sprintf("unknown group '%s'", name), "routes", i, "groups", j)
+			}
+		}
+		if _, err := spec.rule(); err != nil {
+			var fe *fieldError
+			errors.As(err, &fe)
+			v.add(err.Error(), append([]any{"routes", i, fe.field}, fe.path...)...)
+		}
+	}
+
+	composites := make(map[string]bool)
+	for i := range f.Composites {
+		spec := &f.Composites[i]
+		switch {
+		case spec.Name == "":
+			v.add("composite name is required", "composites", i)
+		case composites[spec.Name]:
+			v.add(fmt.Sprintf("duplicate composite '%s'", spec.Name), "composites", i, "name")
+		}
+		composites[spec.Name] = true
+
+		if spec.Rule == "" {
+			v.add("rule is required", "composites", i)
+		} else if composite, err := spec.composite(); err != nil {
+			var fe *fieldError
+			errors.As(err, &fe)
+			v.add(err.Error(), "composites", i, fe.field)
+		} else if _, err := monitor.NewComposites(nil, composite); err != nil && spec.Name != "" {
+			v.add(err.Error(), "composites", i, "name")
+		}
+		for j, name := range spec.Notify {
+			if !notifications[name] {
+				v.add(fmt.Sprintf("unknown notification '%s'", name), "composites", i, "notify", j)
+			}
+		}
+	}
+
+	domains := make(map[string]bool)
+	for i := range f.Domains {
+		spec := &f.Domains[i]
+		switch {
+		case spec.Domain == "":
+			v.add("domain is required", "domains", i)
+		case domains[strings.ToLower(spec.Domain)]:
+			v.add(fmt.Sprintf("duplicate domain '%s'", spec.Domain), "domains", i, "domain")
+		}
+		domains[strings.ToLower(spec.Domain)] = true
+
+		if _, err := spec.policy(); err != nil {
+			var fe *fieldError
+			errors.As(err, &fe)
+			v.add(err.Error(), "domains", i, fe.field)
+		}
+	}
+
+	hosts := make(map[string]bool)
+	for i, spec := range f.Hosts {
+		switch {
+		case spec.Host == "":
+			v.add("host is required", "hosts", i)
+		case hosts[strings.ToLower(spec.Host)]:
+			v.add(fmt.Sprintf("duplicate host '%s'", spec.Host), "hosts", i, "host")
+		}
+		hosts[strings.ToLower(spec.Host)] = true
+
+		if spec.RateLimit < 0 {
+			v.add("rate_limit must not be negative", "hosts", i, "rate_limit")
+		}
+	}
+
+	if len(f.Monitors) == 0 {
+		v.add(ErrNoMonitors.Error())
+	}
+
+	urls := make(map[string]bool)
+	for i := range f.Monitors {
+		spec := &f.Monitors[i]
+
+		if err := checkMonitorURL(spec.URL); err != nil {
+			v.add(err.Error(), "monitors", i, "url")
+		} else if urls[spec.URL] {
+			v.add(fmt.Sprintf("duplicate monitor for URL '%s'", spec.URL), "monitors", i, "url")
+		}
+		urls[spec.URL] = true
+
+		if spec.Group != "" && !groups[spec.Group] {
+			v.add(fmt.Sprintf("unknown group '%s'", spec.Group), "monitors", i, "group")
+		}
+
+		for j, name := range spec.Notify {
+			if !notifications[name] {
+				v.add(fmt.Sprintf("unknown notification '%s'", name), "monitors", i, "notify", j)
+			}
+		}
+
+		// Invalid defaults would be reported again for every monitor
+		if !defaultsValid {
+			continue
+		}
+
+		if _, err := f.buildConfig(spec); err != nil {
+			var fe *fieldError
+			if errors.As(err, &fe) {
+				v.add(err.Error(), append([]any{"monitors", i, fe.field}, fe.path...)...)
+			} else {
+				v.add(err.Error(), "monitors", i)
+			}
+		}
+	}
+
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].Line < v.errs[j].Line
+	})
+
+	return v.errs
+}
+
+// validateDefaults checks the defaults section
+func (f *File) validateDefaults(v *validator) {
+	d := f.Defaults
+
+	durations := []struct{ field, value string }{
+		{"interval", d.Interval},
+		{"timeout", d.Timeout},
+		{"retry_interval", d.RetryInterval},
+		{"jitter", d.Jitter},
+		{"confirm_delay", d.ConfirmDelay},
+	}
+	for _, entry := range durations {
+		if _, err := duration(entry.field, entry.value, 0); err != nil {
+			v.add(err.Error(), "defaults", entry.field)
+		}
+	}
+
+	if d.RateLimit < 0 {
+		v.add("rate_limit must not be negative", "defaults", "rate_limit")
+	}
+
+	if d.TLS != nil {
+		if _, err := d.TLS.options(); err != nil {
+			var fe *fieldError
+			errors.As(err, &fe)
+			v.add(err.Error(), append([]any{"defaults", fe.field}, fe.path...)...)
+		}
+	}
+
+	if d.Proxy != "" {
+		if _, err := customhttp.ParseProxyURL(d.Proxy); err != nil {
+			v.add(err.Error(), "defaults", "proxy")
+		}
+	}
+
+	if d.AcceptEncoding != "" {
+		if err := customhttp.ValidateAcceptEncoding(d.AcceptEncoding); err != nil {
+			v.add(err.Error(), "defaults", "accept_encoding")
+		}
+	}
+
+	if _, err := method(d.Method); err != nil {
+		v.add(err.Error(), "defaults", "method")
+	}
+
+	if d.Retries != nil && *d.Retries < 0 {
+		v.add("retries must not be negative", "defaults", "retries")
+	}
+	if d.FailureThreshold < 0 {
+		v.add(monitor.ErrFailureThreshold.Error(), "defaults", "failure_threshold")
+	}
+
+	if d.Schedule != "" {
+		if _, err := schedule.ParseCron(d.Schedule); err != nil {
+			v.add(err.Error(), "defaults", "schedule")
+		}
+	}
+
+	if _, err := windows(d.Maintenance); err != nil {
+		var fe *fieldError
+		errors.As(err, &fe)
+		v.add(err.Error(), append([]any{"defaults", fe.field}, fe.path...)...)
+	}
+}
+
+// checkStream checks the destination of a kafka or nats notification
+func (v *validator) checkStream(spec NotificationSpec, i int) {
+	if spec.Type == NotificationKafka {
+		if len(spec.Brokers) == 0 {
+			v.add("brokers are required", "notifications", i)
+		}
+		for j, broker := range spec.Brokers {
+			if host, port, err := net.SplitHostPort(broker); err != nil || host == "" || port == "" {
+				v.add(fmt.Sprintf("invalid broker '%s': must be host:port", broker), "notifications", i, "brokers", j)
+			}
+		}
+	} else if err := secret.Validate(spec.URL); err != nil {
+		v.add(err.Error(), "notifications", i, "url")
+	} else if !secret.Contains(spec.URL) {
+		if u, err := url.Parse(spec.URL); err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+			v.add(fmt.Sprintf("invalid URL '%s': must be a nats:// or tls:// URL", spec.URL), "notifications", i, "url")
+		}
+	}
+
+	switch {
+	case spec.Topic == "":
+		v.add("topic is required", "notifications", i)
+	case strings.ContainsAny(spec.Topic, " \t\r\n"):
+		v.add(fmt.Sprintf("invalid topic '%s': must not contain whitespace", spec.Topic), "notifications", i, "topic")
+	}
+	if _, err := notify.ParseFormat(spec.Format); err != nil {
+		v.add(err.Error(), "notifications", i, "format")
+	}
+	if spec.SchemaRegistry != "" {
+		check := checkURL
+		if secret.Contains(spec.SchemaRegistry) {
+			check = secret.Validate
+		}
+		if err := check(spec.SchemaRegistry); err != nil {
+			v.add(err.Error(), "notifications", i, "schema_registry")
+		}
+	}
+}
+
+// checkCloudTopic checks the topic of an sns or pubsub notification
+func (v *validator) checkCloudTopic(spec NotificationSpec, i int) {
+	if spec.Topic == "" {
+		v.add("topic is required", "notifications", i)
+		return
+	}
+
+	if spec.Type == NotificationSNS {
+		parts := strings.Split(spec.Topic, ":")
+		if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" || parts[4] == "" || parts[5] == "" {
+			v.add(fmt.Sprintf("invalid topic '%s': must be an ARN like arn:aws:sns:<region>:<account>:<topic>", spec.Topic), "notifications", i, "topic")
+		}
+		return
+	}
+	parts := strings.Split(spec.Topic, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[1] == "" || parts[2] != "topics" || parts[3] == "" {
+		v.add(fmt.Sprintf("invalid topic '%s': must be projects/<project>/topics/<topic>", spec.Topic), "notifications", i, "topic")
+	}
+}
+
+// checkPush checks the credentials and priorities of a push notification
+func (v *validator) checkPush(spec NotificationSpec, i int) {
+	if spec.Type == NotificationPushover {
+		if spec.Token == "" {
+			v.add("token is required", "notifications", i)
+		}
+		if spec.User == "" {
+			v.add("user is required", "notifications", i)
+		}
+	}
+	if err := secret.Validate(spec.Token); err != nil {
+		v.add(err.Error(), "notifications", i, "token")
+	}
+	if err := secret.Validate(spec.User); err != nil {
+		v.add(err.Error(), "notifications", i, "user")
+	}
+	for event, priority := range spec.Priorities {
+		if _, err := notify.ParsePriorities(map[string]string{event: priority}); err != nil {
+			v.add(err.Error(), "notifications", i, "priorities", event)
+		}
+	}
+}
+
+// checkMonitorURL checks that value is an absolute HTTP(S) URL, the URL of
+// a mailbox, see imap.ParseURL, or of a webhook monitor, see
+// monitor.ParseWebhookURL
+func checkMonitorURL(value string) error {
+	if imap.IsURL(value) {
+		_, err := imap.ParseURL(value)
+		return err
+	}
+	if monitor.IsWebhookURL(value) {
+		_, err := monitor.ParseWebhookURL(value)
+		return err
+	}
+	return checkURL(value)
+}
+
+// checkURL checks that value is an absolute HTTP(S) URL
+func checkURL(value string) error {
+	if value == "" {
+		return errors.New("url is required")
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL '%s': must be an absolute http or https URL", value)
+	}
+
+	return nil
+}
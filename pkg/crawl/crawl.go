@@ -0,0 +1,306 @@
+// Package crawl discovers the pages of a site by following its links from a
+// seed URL, so that a whole small site can be watched without listing its
+// pages.
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/dom"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/robots"
+)
+
+const (
+	// DefaultMaxDepth is the number of links followed from the seed
+	DefaultMaxDepth = 2
+
+	// DefaultMaxPages is the number of pages fetched at most
+	DefaultMaxPages = 100
+
+	// maxPageSize is the largest part of a page searched for links
+	maxPageSize = 10 << 20
+)
+
+var (
+	linkSelector = dom.MustCompile("a[href]")
+	baseSelector = dom.MustCompile("base[href]")
+	metaSelector = dom.MustCompile("meta[name][content]")
+)
+
+// Crawler follows the links of a site from a seed URL. Only links to the
+// origin of the seed, its scheme, host and port, are followed, and the
+// robots.txt of the site is respected.
+type Crawler struct {
+	// Client fetches the pages. Defaults to a client with
+	// customhttp.DefaultClientOptions.
+	Client *http.Client
+	// Headers are sent with every request, e.g. for authentication
+	Headers map[string]string
+	// UserAgent is sent with every request and selects the rules of
+	// robots.txt. Defaults to the user agent of customhttp.
+	UserAgent string
+	// MaxDepth is the number of links followed from the seed; zero only
+	// fetches the seed. Negative values use DefaultMaxDepth.
+	MaxDepth int
+	// MaxPages bounds the pages fetched. Defaults to DefaultMaxPages.
+	MaxPages int
+	// Include limits the pages returned to those matching any of the
+	// patterns. Pages that don't match are still crawled for links, so the
+	// seed doesn't need to match.
+	Include []*regexp.Regexp
+	// Exclude lists patterns of URLs that are neither fetched nor returned
+	Exclude []*regexp.Regexp
+	// Delay is the time between requests. A longer Crawl-delay of
+	// robots.txt takes precedence.
+	Delay time.Duration
+}
+
+// Crawl fetches the seed and the pages it links to, breadth first, and
+// returns the URLs of the HTML pages found, the seed first. Pages that fail
+// to load are skipped; an error is only returned if the seed can't be
+// crawled or ctx is done.
+func (c *Crawler) Crawl(ctx context.Context, seed string) ([]string, error) {
+	start, err := url.Parse(seed)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, fmt.Errorf("invalid seed URL '%s'", seed)
+	}
+	start.Fragment = ""
+
+	options := customhttp.DefaultClientOptions()
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = options.UserAgent
+	}
+	client := c.Client
+	if client == nil {
+		client = customhttp.NewClient(options)
+	}
+	maxDepth := c.MaxDepth
+	if maxDepth < 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	maxPages := c.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	s := &session{crawler: c, client: client, userAgent: userAgent, origin: origin(start)}
+	s.robots, err = s.fetchRobots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !s.robots.Allowed(start) {
+		return nil, fmt.Errorf("robots.txt of %s doesn't allow crawling %s", s.origin, seed)
+	}
+	delay := max(c.Delay, s.robots.CrawlDelay)
+
+	type queued struct {
+		url   *url.URL
+		depth int
+	}
+	queue := []queued{{url: start}}
+	seen := map[string]bool{start.String(): true}
+	var pages []string
+	for fetched := 0; len(queue) > 0 && fetched < maxPages; fetched++ {
+		if fetched > 0 && delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		current := queue[0]
+		queue = queue[1:]
+
+		final, links, err := s.fetch(ctx, current.url)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			if fetched == 0 {
+				return nil, err
+			}
+			continue
+		}
+
+		// A redirect to another page of the site stands for that page
+		if key := final.String(); key != current.url.String() {
+			if seen[key] && fetched > 0 {
+				continue
+			}
+			seen[key] = true
+		}
+		if c.included(final.String()) {
+			pages = append(pages, final.String())
+		}
+
+		if current.depth == maxDepth {
+			continue
+		}
+		for _, link := range links {
+			key := link.String()
+			if seen[key] || origin(link) != s.origin || c.excluded(key) || !s.robots.Allowed(link) {
+				continue
+			}
+			seen[key] = true
+			queue = append(queue, queued{url: link, depth: current.depth + 1})
+		}
+	}
+
+	return pages, nil
+}
+
+// included reports whether a crawled page is returned
+func (c *Crawler) included(u string) bool {
+	if c.excluded(u) {
+		return false
+	}
+	if len(c.Include) == 0 {
+		return true
+	}
+	for _, re := range c.Include {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether a URL matches an exclude pattern
+func (c *Crawler) excluded(u string) bool {
+	for _, re := range c.Exclude {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// origin returns the scheme, host and port of a URL
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// session holds the state of one crawl
+type session struct {
+	crawler   *Crawler
+	client    *http.Client
+	userAgent string
+	origin    string
+	robots    *robots.Robots
+}
+
+// get sends a GET request with the crawler's headers
+func (s *session) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	customhttp.AddHeaders(req, s.crawler.Headers, s.userAgent)
+	return s.client.Do(req)
+}
+
+// fetchRobots fetches the robots.txt of the origin. A missing file allows
+// everything, while a server error is reported, as the site may not want
+// to be crawled at all.
+func (s *session) fetchRobots(ctx context.Context) (*robots.Robots, error) {
+	resp, err := s.get(ctx, s.origin+"/robots.txt")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching robots.txt of %s: %w", s.origin, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		content, err := io.ReadAll(io.LimitReader(resp.Body, 500<<10))
+		if err != nil {
+			return nil, fmt.Errorf("error reading robots.txt of %s: %w", s.origin, err)
+		}
+		return robots.Parse(content, s.userAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &robots.Robots{}, nil
+	default:
+		return nil, fmt.Errorf("error fetching robots.txt of %s: status %d", s.origin, resp.StatusCode)
+	}
+}
+
+// fetch fetches an HTML page and returns its URL after redirects and the
+// links it has, unless it asks robots not to follow them
+func (s *session) fetch(ctx context.Context, u *url.URL) (*url.URL, []*url.URL, error) {
+	resp, err := s.get(ctx, u.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	final := resp.Request.URL
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("error fetching %s: status %d", u, resp.StatusCode)
+	}
+	if origin(final) != s.origin {
+		return nil, nil, fmt.Errorf("%s redirects to another site", u)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, nil, fmt.Errorf("%s is not an HTML page", u)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading %s: %w", u, err)
+	}
+
+	page := *final
+	page.Fragment = ""
+	return &page, links(dom.Parse(content), &page), nil
+}
+
+// links returns the links of a page, resolved against its URL or its base
+// element, without fragments. Links marked rel="nofollow" are left out, as
+// are all links of pages with a robots meta tag saying nofollow.
+func links(doc *dom.Node, page *url.URL) []*url.URL {
+	for _, meta := range metaSelector.Select(doc) {
+		name, _ := meta.Attr("name")
+		content, _ := meta.Attr("content")
+		if strings.EqualFold(name, "robots") && strings.Contains(strings.ToLower(content), "nofollow") {
+			return nil
+		}
+	}
+
+	base := page
+	if element := baseSelector.First(doc); element != nil {
+		href, _ := element.Attr("href")
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = page.ResolveReference(ref)
+		}
+	}
+
+	var result []*url.URL
+	for _, a := range linkSelector.Select(doc) {
+		if rel, _ := a.Attr("rel"); strings.Contains(strings.ToLower(rel), "nofollow") {
+			continue
+		}
+		href, _ := a.Attr("href")
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			continue
+		}
+		link := base.ResolveReference(ref)
+		if link.Scheme != "http" && link.Scheme != "https" {
+			continue
+		}
+		link.Fragment = ""
+		link.RawFragment = ""
+		result = append(result, link)
+	}
+	return result
+}
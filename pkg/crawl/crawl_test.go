@@ -0,0 +1,102 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testSite serves a small site and records the paths requested
+type testSite struct {
+	*httptest.Server
+	mu        sync.Mutex
+	requested []string
+}
+
+func newTestSite(t *testing.T, robots string, pages map[string]string) *testSite {
+	site := &testSite{}
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.requested = append(site.requested, r.URL.RequestURI())
+		site.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/robots.txt" && robots != "":
+			w.Write([]byte(robots))
+		case r.URL.Path == "/old":
+			http.Redirect(w, r, "/about", http.StatusMovedPermanently)
+		case r.URL.Path == "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		case pages[r.URL.Path] != "":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, "<html><body>%s</body></html>", pages[r.URL.Path])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+var testPages = map[string]string{
+	"/": `<a href="/about">About</a> <a href="blog/">Blog</a> <a href="/old#top">Old</a>
+		<a href="https://other.example/">Elsewhere</a> <a href="mailto:me@example.com">Mail</a>
+		<a href="/data.json">Data</a> <a href="/missing">Missing</a> <a href="/private/x">Private</a>`,
+	"/about":        `<a href="/">Home</a>`,
+	"/blog/":        `<a href="post-1">One</a> <a href="/blog/post-2" rel="nofollow">Two</a>`,
+	"/blog/post-1":  `<a href="/blog/archive">Archive</a>`,
+	"/blog/archive": `Archive`,
+	"/private/x":    `Private`,
+}
+
+func TestCrawl(t *testing.T) {
+	site := newTestSite(t, "User-agent: *\nDisallow: /private/\n", testPages)
+
+	crawler := &Crawler{Client: site.Client(), MaxDepth: 2}
+	pages, err := crawler.Crawl(context.Background(), site.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		site.URL + "/",
+		site.URL + "/about",
+		site.URL + "/blog/",
+		site.URL + "/blog/post-1",
+	}, pages)
+	require.NotContains(t, site.requested, "/private/x")
+	require.NotContains(t, site.requested, "/blog/archive")
+
+	crawler.MaxDepth = 5
+	crawler.Include = []*regexp.Regexp{regexp.MustCompile("/blog/.")}
+	crawler.Exclude = []*regexp.Regexp{regexp.MustCompile("archive")}
+	pages, err = crawler.Crawl(context.Background(), site.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, []string{site.URL + "/blog/post-1"}, pages)
+
+	crawler.Include = nil
+	crawler.Exclude = nil
+	crawler.MaxPages = 2
+	pages, err = crawler.Crawl(context.Background(), site.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, []string{site.URL + "/", site.URL + "/about"}, pages)
+}
+
+func TestCrawlErrors(t *testing.T) {
+	site := newTestSite(t, "User-agent: *\nDisallow: /\n", testPages)
+	crawler := &Crawler{Client: site.Client()}
+	_, err := crawler.Crawl(context.Background(), site.URL+"/")
+	require.ErrorContains(t, err, "doesn't allow crawling")
+
+	site = newTestSite(t, "", testPages)
+	crawler = &Crawler{Client: site.Client()}
+	_, err = crawler.Crawl(context.Background(), site.URL+"/missing")
+	require.ErrorContains(t, err, "status 404")
+
+	_, err = crawler.Crawl(context.Background(), "ftp://example.com/")
+	require.ErrorContains(t, err, "invalid seed URL")
+}
@@ -0,0 +1,442 @@
+// Package dom parses HTML and XML into a tree of nodes that can be queried
+// with CSS selectors or XPath expressions. The parser is lenient rather than
+// standards-complete: it is meant to find values in real-world pages and
+// feeds, not to render them.
+package dom
+
+import (
+	"bytes"
+	"html"
+	"slices"
+	"strings"
+)
+
+// NodeType identifies the kind of a Node
+type NodeType int
+
+const (
+	// DocumentNode is the root of a parsed document
+	DocumentNode NodeType = iota
+	// ElementNode is an HTML element
+	ElementNode
+	// TextNode is text between elements, with entities decoded
+	TextNode
+	// AttributeNode is an attribute selected with XPath. It is not part of
+	// the tree: its Parent is the element, Tag its name and Data its value.
+	AttributeNode
+)
+
+// Query finds nodes in a parsed document, see Selector and XPath
+type Query interface {
+	// Select returns the nodes under root matching the query, in document
+	// order
+	Select(root *Node) []*Node
+	// String returns the source of the query
+	String() string
+}
+
+// Node is a document, element or text in a parsed document
+type Node struct {
+	Type NodeType
+	// Tag is the lower-case name of an element or attribute
+	Tag string
+	// Attrs holds the attributes of an element, with lower-case names
+	Attrs map[string]string
+	// Data is the text of a text node or the value of an attribute
+	Data     string
+	Parent   *Node
+	Children []*Node
+}
+
+// voidElements never have content or a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text up to their closing tag, without elements
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+}
+
+// autoClosed lists elements closed by the start of a sibling, e.g. a list
+// item by the next one when the closing tags are left out
+var autoClosed = map[string][]string{
+	"li":     {"li"},
+	"p":      {"p"},
+	"dt":     {"dt", "dd"},
+	"dd":     {"dt", "dd"},
+	"tr":     {"tr"},
+	"td":     {"td", "th"},
+	"th":     {"td", "th"},
+	"option": {"option"},
+}
+
+// Attr returns the value of an attribute of an element
+func (n *Node) Attr(name string) (string, bool) {
+	value, ok := n.Attrs[strings.ToLower(name)]
+	return value, ok
+}
+
+// Text returns the text of a node and its descendants with whitespace
+// collapsed, the way a browser shows it
+func (n *Node) Text() string {
+	var b strings.Builder
+	n.writeText(&b)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func (n *Node) writeText(b *strings.Builder) {
+	if n.Type == TextNode || n.Type == AttributeNode {
+		b.WriteString(n.Data)
+		b.WriteByte(' ')
+		return
+	}
+	if n.Tag == "script" || n.Tag == "style" {
+		return
+	}
+	for _, child := range n.Children {
+		child.writeText(b)
+	}
+}
+
+// Walk calls fn for the node and its descendants in document order
+func (n *Node) Walk(fn func(*Node)) {
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// Remove detaches the node from the document, or removes an attribute
+// from its element
+func (n *Node) Remove() {
+	if n.Parent == nil {
+		return
+	}
+	if n.Type == AttributeNode {
+		delete(n.Parent.Attrs, n.Tag)
+		return
+	}
+	if i := slices.Index(n.Parent.Children, n); i >= 0 {
+		n.Parent.Children = slices.Delete(n.Parent.Children, i, i+1)
+	}
+	n.Parent = nil
+}
+
+// HTML returns the markup of a node and its descendants. Attributes are
+// written in name order, so equal trees always render the same. An
+// attribute renders as its value.
+func (n *Node) HTML() string {
+	var b strings.Builder
+	n.writeHTML(&b)
+	return b.String()
+}
+
+func (n *Node) writeHTML(b *strings.Builder) {
+	switch n.Type {
+	case TextNode:
+		if n.Parent != nil && (n.Parent.Tag == "script" || n.Parent.Tag == "style") {
+			b.WriteString(n.Data)
+		} else {
+			b.WriteString(html.EscapeString(n.Data))
+		}
+		return
+	case AttributeNode:
+		b.WriteString(n.Data)
+		return
+	case ElementNode:
+		b.WriteString("<" + n.Tag)
+		names := make([]string, 0, len(n.Attrs))
+		for name := range n.Attrs {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			b.WriteString(" " + name + `="` + html.EscapeString(n.Attrs[name]) + `"`)
+		}
+		b.WriteString(">")
+		if voidElements[n.Tag] && len(n.Children) == 0 {
+			return
+		}
+	}
+
+	for _, child := range n.Children {
+		child.writeHTML(b)
+	}
+	if n.Type == ElementNode {
+		b.WriteString("</" + n.Tag + ">")
+	}
+}
+
+// appendChild adds child as the last child of n
+func (n *Node) appendChild(child *Node) {
+	child.Parent = n
+	n.Children = append(n.Children, child)
+}
+
+// Parse parses an HTML document. It never fails: unclosed elements are
+// closed at the end of their parent and stray closing tags are ignored.
+func Parse(content []byte) *Node {
+	p := &parser{s: string(content)}
+	p.root = &Node{Type: DocumentNode}
+	p.stack = []*Node{p.root}
+	p.parse()
+	return p.root
+}
+
+// ParseXML parses an XML document such as a feed. Unlike Parse it knows no
+// void or raw text elements, so <link> and <title> hold elements and text
+// like any other, and CDATA sections become text.
+func ParseXML(content []byte) *Node {
+	p := &parser{s: string(content), xml: true}
+	p.root = &Node{Type: DocumentNode}
+	p.stack = []*Node{p.root}
+	p.parse()
+	return p.root
+}
+
+// ParseDocument parses content with ParseXML if it starts with an XML
+// declaration, otherwise with Parse
+func ParseDocument(content []byte) *Node {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<?xml")) {
+		return ParseXML(content)
+	}
+	return Parse(content)
+}
+
+// parser holds the state of Parse and ParseXML
+type parser struct {
+	s     string
+	i     int
+	xml   bool
+	root  *Node
+	stack []*Node
+}
+
+// current returns the element new nodes are added to
+func (p *parser) current() *Node {
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *parser) parse() {
+	for p.i < len(p.s) {
+		if p.s[p.i] != '<' {
+			p.text()
+			continue
+		}
+
+		rest := p.s[p.i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			p.skipPast("-->")
+		case p.xml && strings.HasPrefix(rest, "<![CDATA["):
+			p.cdata()
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			p.skipPast(">")
+		case strings.HasPrefix(rest, "</"):
+			p.endTag()
+		case len(rest) > 1 && isLetter(rest[1]):
+			p.startTag()
+		default:
+			p.addText("<")
+			p.i++
+		}
+	}
+}
+
+// text adds the text up to the next tag
+func (p *parser) text() {
+	end := strings.IndexByte(p.s[p.i:], '<')
+	if end < 0 {
+		end = len(p.s) - p.i
+	}
+	p.addText(html.UnescapeString(p.s[p.i : p.i+end]))
+	p.i += end
+}
+
+// addText adds text to the current element, merging it with a preceding
+// text node
+func (p *parser) addText(text string) {
+	parent := p.current()
+	if n := len(parent.Children); n > 0 && parent.Children[n-1].Type == TextNode {
+		parent.Children[n-1].Data += text
+		return
+	}
+	parent.appendChild(&Node{Type: TextNode, Data: text})
+}
+
+// cdata adds the text of a CDATA section
+func (p *parser) cdata() {
+	p.i += len("<![CDATA[")
+	end := strings.Index(p.s[p.i:], "]]>")
+	if end < 0 {
+		end = len(p.s) - p.i
+	}
+	p.addText(p.s[p.i : p.i+end])
+	p.i += end
+	p.skipPast("]]>")
+}
+
+// skipPast moves past the next occurrence of marker, or to the end
+func (p *parser) skipPast(marker string) {
+	end := strings.Index(p.s[p.i:], marker)
+	if end < 0 {
+		p.i = len(p.s)
+		return
+	}
+	p.i += end + len(marker)
+}
+
+// endTag closes the innermost open element with the tag's name, and any
+// element opened inside it
+func (p *parser) endTag() {
+	p.i += 2
+	name := strings.ToLower(p.name())
+	p.skipPast(">")
+
+	for k := len(p.stack) - 1; k > 0; k-- {
+		if p.stack[k].Tag == name {
+			p.stack = p.stack[:k]
+			return
+		}
+	}
+}
+
+// startTag adds an element and, unless it is void, opens it
+func (p *parser) startTag() {
+	p.i++
+	element := &Node{Type: ElementNode, Tag: strings.ToLower(p.name()), Attrs: make(map[string]string)}
+	selfClosing := p.attributes(element)
+
+	if p.xml {
+		p.current().appendChild(element)
+		if !selfClosing {
+			p.stack = append(p.stack, element)
+		}
+		return
+	}
+
+	if closes, ok := autoClosed[element.Tag]; ok {
+		for _, tag := range closes {
+			if p.current().Tag == tag {
+				p.stack = p.stack[:len(p.stack)-1]
+				break
+			}
+		}
+	}
+
+	p.current().appendChild(element)
+	if selfClosing || voidElements[element.Tag] {
+		return
+	}
+
+	if rawTextElements[element.Tag] {
+		end := strings.Index(strings.ToLower(p.s[p.i:]), "</"+element.Tag)
+		if end < 0 {
+			end = len(p.s) - p.i
+		}
+		if text := p.s[p.i : p.i+end]; text != "" {
+			if element.Tag == "textarea" || element.Tag == "title" {
+				text = html.UnescapeString(text)
+			}
+			element.appendChild(&Node{Type: TextNode, Data: text})
+		}
+		p.i += end
+		p.skipPast(">")
+		return
+	}
+
+	p.stack = append(p.stack, element)
+}
+
+// attributes parses the attributes of a start tag up to its end, and
+// reports whether the tag is self-closing
+func (p *parser) attributes(element *Node) bool {
+	for p.i < len(p.s) {
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			return false
+		}
+
+		switch p.s[p.i] {
+		case '>':
+			p.i++
+			return false
+		case '/':
+			p.i++
+			if p.i < len(p.s) && p.s[p.i] == '>' {
+				p.i++
+				return true
+			}
+			continue
+		}
+
+		name := strings.ToLower(p.name())
+		if name == "" {
+			// Skip a character that can't start an attribute
+			p.i++
+			continue
+		}
+
+		p.skipSpace()
+		value := ""
+		if p.i < len(p.s) && p.s[p.i] == '=' {
+			p.i++
+			p.skipSpace()
+			value = html.UnescapeString(p.value())
+		}
+		if _, ok := element.Attrs[name]; !ok {
+			element.Attrs[name] = value
+		}
+	}
+	return false
+}
+
+// name reads a tag or attribute name
+func (p *parser) name() string {
+	start := p.i
+	for p.i < len(p.s) && !isSpace(p.s[p.i]) && !strings.ContainsRune("/>=", rune(p.s[p.i])) {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// value reads a quoted or unquoted attribute value
+func (p *parser) value() string {
+	if p.i >= len(p.s) {
+		return ""
+	}
+
+	if quote := p.s[p.i]; quote == '"' || quote == '\'' {
+		p.i++
+		end := strings.IndexByte(p.s[p.i:], quote)
+		if end < 0 {
+			end = len(p.s) - p.i
+		}
+		value := p.s[p.i : p.i+end]
+		p.i = min(p.i+end+1, len(p.s))
+		return value
+	}
+
+	start := p.i
+	for p.i < len(p.s) && !isSpace(p.s[p.i]) && p.s[p.i] != '>' {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && isSpace(p.s[p.i]) {
+		p.i++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,106 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Widgets &amp; more</title>
+  <script>if (a < b) { document.write("<p>not a paragraph</p>") }</script>
+</head>
+<body>
+  <!-- <div id="price">commented out</div> -->
+  <div id="product" class="card featured">
+    <h1>Widget</h1>
+    <span class="price" data-currency=EUR>&euro;1,299.00</span>
+    <img src="widget.png">
+    <ul>
+      <li>Red
+      <li>Blue
+      <li>Green
+    </ul>
+  </div>
+  <p>First<p>Second
+</body>
+</html>`
+
+func TestParse(t *testing.T) {
+	doc := Parse([]byte(testPage))
+
+	var tags []string
+	doc.Walk(func(n *Node) {
+		if n.Type == ElementNode {
+			tags = append(tags, n.Tag)
+		}
+	})
+	require.Equal(t, []string{"html", "head", "title", "script", "body", "div", "h1", "span", "img", "ul", "li", "li", "li", "p", "p"}, tags)
+
+	title := MustCompile("title").First(doc)
+	require.Equal(t, "Widgets & more", title.Text())
+
+	price := MustCompile(".price").First(doc)
+	require.Equal(t, "€1,299.00", price.Text())
+	currency, ok := price.Attr("data-currency")
+	require.True(t, ok)
+	require.Equal(t, "EUR", currency)
+
+	// Void and auto-closed elements don't swallow their siblings
+	require.Len(t, MustCompile("ul > li").Select(doc), 3)
+	require.Len(t, MustCompile("body > p").Select(doc), 2)
+
+	require.Equal(t, "Widgets & more Widget €1,299.00 Red Blue Green First Second", doc.Text())
+}
+
+func TestParseMalformed(t *testing.T) {
+	doc := Parse([]byte(`<div><b>bold</i> text</div></span> 1 < 2 <p class='unclosed`))
+	require.Equal(t, "bold text 1 < 2", doc.Text())
+	require.NotNil(t, MustCompile("div > b").First(doc))
+}
+
+func TestSelector(t *testing.T) {
+	doc := Parse([]byte(testPage))
+
+	tests := []struct {
+		selector string
+		text     []string
+	}{
+		{selector: "h1", text: []string{"Widget"}},
+		{selector: "#product h1", text: []string{"Widget"}},
+		{selector: "div.card.featured > span", text: []string{"€1,299.00"}},
+		{selector: "body > span", text: nil},
+		{selector: "span[data-currency=EUR]", text: []string{"€1,299.00"}},
+		{selector: `span[data-currency="USD"]`, text: nil},
+		{selector: "[class~=featured] h1", text: []string{"Widget"}},
+		{selector: "img[src$='.png'], h1", text: []string{"Widget", ""}},
+		{selector: "li:first-child", text: []string{"Red"}},
+		{selector: "li:nth-child(2)", text: []string{"Blue"}},
+		{selector: "ul li:last-child", text: []string{"Green"}},
+		{selector: "*[data-currency]", text: []string{"€1,299.00"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := Compile(tc.selector)
+			require.NoError(t, err)
+
+			var text []string
+			for _, n := range selector.Select(doc) {
+				text = append(text, n.Text())
+			}
+			require.Equal(t, tc.text, text)
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, selector := range []string{"", "div,", "> p", "div >", "div[", "p:hover", "li:nth-child(odd)", "div..x", "a!b"} {
+		t.Run(selector, func(t *testing.T) {
+			_, err := Compile(selector)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package dom
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled CSS selector. The supported subset covers what is
+// needed to point at a value in a page: type, #id, .class and [attribute]
+// selectors with =, ~=, ^=, $= and *=, the :first-child, :last-child and
+// :nth-child(n) pseudo-classes, descendant and child (>) combinators, and
+// comma-separated groups.
+type Selector struct {
+	source string
+	groups [][]step
+}
+
+// step is a compound selector and how it relates to the previous step
+type step struct {
+	// child is set for the > combinator, otherwise any ancestor matches
+	child    bool
+	tag      string
+	id       string
+	classes  []string
+	attrs    []attrMatch
+	position int // 1-based position among siblings, -1 for the last
+}
+
+// attrMatch is an attribute selector
+type attrMatch struct {
+	name, op, value string
+}
+
+// Compile parses a CSS selector
+func Compile(selector string) (*Selector, error) {
+	s := &Selector{source: selector}
+	for _, group := range strings.Split(selector, ",") {
+		steps, err := parseGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector '%s': %w", selector, err)
+		}
+		s.groups = append(s.groups, steps)
+	}
+	return s, nil
+}
+
+// MustCompile is like Compile but panics if the selector is invalid
+func MustCompile(selector string) *Selector {
+	s, err := Compile(selector)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// String returns the source of the selector
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Select returns the elements under root matching the selector, in
+// document order
+func (s *Selector) Select(root *Node) []*Node {
+	var matches []*Node
+	root.Walk(func(n *Node) {
+		if n != root && s.Match(n) {
+			matches = append(matches, n)
+		}
+	})
+	return matches
+}
+
+// First returns the first element under root matching the selector, or nil
+func (s *Selector) First(root *Node) *Node {
+	if matches := s.Select(root); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// Match reports whether an element matches the selector
+func (s *Selector) Match(n *Node) bool {
+	for _, steps := range s.groups {
+		if matchSteps(n, steps, len(steps)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSteps matches n against steps[i] and its ancestors against the
+// steps before it
+func matchSteps(n *Node, steps []step, i int) bool {
+	if !steps[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	if steps[i].child {
+		return n.Parent != nil && matchSteps(n.Parent, steps, i-1)
+	}
+	for ancestor := n.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if matchSteps(ancestor, steps, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// match matches a single element against the compound selector
+func (st *step) match(n *Node) bool {
+	if n.Type != ElementNode {
+		return false
+	}
+	if st.tag != "" && st.tag != "*" && st.tag != n.Tag {
+		return false
+	}
+	if st.id != "" && n.Attrs["id"] != st.id {
+		return false
+	}
+	if len(st.classes) > 0 {
+		classes := strings.Fields(n.Attrs["class"])
+		for _, class := range st.classes {
+			if !slices.Contains(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range st.attrs {
+		if !attr.match(n) {
+			return false
+		}
+	}
+	if st.position != 0 && !matchPosition(n, st.position) {
+		return false
+	}
+	return true
+}
+
+// match matches an attribute selector
+func (a attrMatch) match(n *Node) bool {
+	value, ok := n.Attrs[a.name]
+	if !ok {
+		return false
+	}
+
+	switch a.op {
+	case "=":
+		return value == a.value
+	case "~=":
+		return slices.Contains(strings.Fields(value), a.value)
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	default:
+		return true
+	}
+}
+
+// matchPosition reports whether an element is at a position among its
+// sibling elements, counted from 1, or is the last one for -1
+func matchPosition(n *Node, position int) bool {
+	if n.Parent == nil {
+		return false
+	}
+
+	var siblings []*Node
+	for _, sibling := range n.Parent.Children {
+		if sibling.Type == ElementNode {
+			siblings = append(siblings, sibling)
+		}
+	}
+
+	if position < 0 {
+		return siblings[len(siblings)-1] == n
+	}
+	return position <= len(siblings) && siblings[position-1] == n
+}
+
+// parseGroup parses a selector without commas into its steps
+func parseGroup(group string) ([]step, error) {
+	var steps []step
+	child := false
+	s := strings.TrimSpace(group)
+	if s == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+
+	for s != "" {
+		if s[0] == '>' {
+			if child || len(steps) == 0 {
+				return nil, fmt.Errorf("unexpected '>'")
+			}
+			child = true
+			s = strings.TrimSpace(s[1:])
+			continue
+		}
+
+		end := compoundEnd(s)
+		st, err := parseCompound(s[:end])
+		if err != nil {
+			return nil, err
+		}
+		st.child = child
+		child = false
+		steps = append(steps, st)
+		s = strings.TrimSpace(s[end:])
+	}
+
+	if child {
+		return nil, fmt.Errorf("missing selector after '>'")
+	}
+	return steps, nil
+}
+
+// compoundEnd returns the end of the compound selector at the start of s:
+// the first space or '>' outside of brackets and parentheses
+func compoundEnd(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && (isSpace(c) || c == '>'):
+			return i
+		}
+	}
+	return len(s)
+}
+
+// parseCompound parses a compound selector such as "div.price[data-id]"
+func parseCompound(s string) (step, error) {
+	var st step
+
+	i := 0
+	if i < len(s) && (s[i] == '*' || isLetter(s[i])) {
+		i = identEnd(s, 1)
+		st.tag = strings.ToLower(s[:i])
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			end := identEnd(s, i+1)
+			if end == i+1 {
+				return st, fmt.Errorf("missing name after '%c'", s[i])
+			}
+			if s[i] == '#' {
+				st.id = s[i+1 : end]
+			} else {
+				st.classes = append(st.classes, s[i+1:end])
+			}
+			i = end
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return st, fmt.Errorf("unclosed '['")
+			}
+			attr, err := parseAttr(s[i+1 : i+end])
+			if err != nil {
+				return st, err
+			}
+			st.attrs = append(st.attrs, attr)
+			i += end + 1
+		case ':':
+			end := identEnd(s, i+1)
+			name := s[i+1 : end]
+			i = end
+			switch name {
+			case "first-child":
+				st.position = 1
+			case "last-child":
+				st.position = -1
+			case "nth-child":
+				end := strings.IndexByte(s[i:], ')')
+				if !strings.HasPrefix(s[i:], "(") || end < 0 {
+					return st, fmt.Errorf("expected :nth-child(n)")
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(s[i+1 : i+end]))
+				if err != nil || n < 1 {
+					return st, fmt.Errorf("unsupported :nth-child argument '%s'", s[i+1:i+end])
+				}
+				st.position = n
+				i += end + 1
+			default:
+				return st, fmt.Errorf("unsupported pseudo-class ':%s'", name)
+			}
+		default:
+			return st, fmt.Errorf("unexpected '%c'", s[i])
+		}
+	}
+
+	return st, nil
+}
+
+// parseAttr parses the inside of an attribute selector
+func parseAttr(s string) (attrMatch, error) {
+	name, value, op := s, "", ""
+	if eq := strings.IndexByte(s, '='); eq >= 0 {
+		name, value, op = s[:eq], s[eq+1:], "="
+		if eq > 0 && strings.IndexByte("~^$*", s[eq-1]) >= 0 {
+			name, op = s[:eq-1], s[eq-1:eq+1]
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return attrMatch{}, fmt.Errorf("missing attribute name")
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return attrMatch{name: strings.ToLower(name), op: op, value: value}, nil
+}
+
+// identEnd returns the end of the identifier starting at i
+func identEnd(s string, i int) int {
+	for i < len(s) {
+		c := s[i]
+		if !isLetter(c) && !(c >= '0' && c <= '9') && c != '-' && c != '_' {
+			break
+		}
+		i++
+	}
+	return i
+}
@@ -0,0 +1,685 @@
+package dom
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// XPath is a compiled XPath expression. The supported subset covers
+// location paths: absolute and relative paths with / and //, the . and ..
+// steps, element names, *, text(), node() and @attribute tests, unions
+// with |, and predicates. Predicates may use positions such as [2] and
+// [last()], comparisons with = != < <= > >=, and and or, paths relative to
+// the node, and the functions position(), last(), count(), contains(),
+// starts-with(), normalize-space(), string() and not(). Names are matched
+// case-insensitively, as the parser lower-cases them.
+type XPath struct {
+	source string
+	paths  []*path
+}
+
+// path is a location path
+type path struct {
+	absolute bool
+	steps    []xstep
+}
+
+// axis identifies the nodes a step selects from
+type axis int
+
+const (
+	childAxis axis = iota
+	selfAxis
+	parentAxis
+	attributeAxis
+)
+
+// xstep is a step of a location path
+type xstep struct {
+	// descendants is set for a step after //, which applies it to the
+	// context node and all of its descendants
+	descendants bool
+	axis        axis
+	// test is an element or attribute name, "*", "text()" or "node()"
+	test       string
+	predicates []expr
+}
+
+// CompileXPath parses an XPath expression
+func CompileXPath(expression string) (*XPath, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath '%s': %w", expression, err)
+	}
+
+	p := &xparser{tokens: tokens}
+	x := &XPath{source: expression}
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, fmt.Errorf("invalid XPath '%s': %w", expression, err)
+		}
+		x.paths = append(x.paths, path)
+		if !p.accept("|") {
+			break
+		}
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("invalid XPath '%s': unexpected '%s'", expression, p.peek())
+	}
+	return x, nil
+}
+
+// MustCompileXPath is like CompileXPath but panics if the expression is
+// invalid
+func MustCompileXPath(expression string) *XPath {
+	x, err := CompileXPath(expression)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// String returns the source of the expression
+func (x *XPath) String() string {
+	return x.source
+}
+
+// Select returns the nodes matching the expression with root as the
+// context node, in document order. Attributes are returned as
+// AttributeNode nodes.
+func (x *XPath) Select(root *Node) []*Node {
+	var nodes []*Node
+	for _, path := range x.paths {
+		nodes = append(nodes, path.eval(root)...)
+	}
+	return documentOrder(nodes)
+}
+
+// First returns the first node matching the expression, or nil
+func (x *XPath) First(root *Node) *Node {
+	if nodes := x.Select(root); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// eval evaluates the path with n as the context node
+func (p *path) eval(n *Node) []*Node {
+	nodes := []*Node{n}
+	if p.absolute {
+		for nodes[0].Parent != nil {
+			nodes[0] = nodes[0].Parent
+		}
+	}
+
+	for _, st := range p.steps {
+		var next []*Node
+		for _, context := range nodes {
+			next = append(next, st.eval(context)...)
+		}
+		nodes = documentOrder(next)
+	}
+	return nodes
+}
+
+// eval applies the step to a context node
+func (st *xstep) eval(n *Node) []*Node {
+	if !st.descendants {
+		return st.apply(n)
+	}
+
+	var nodes []*Node
+	n.Walk(func(d *Node) {
+		nodes = append(nodes, st.apply(d)...)
+	})
+	return nodes
+}
+
+// apply selects the nodes of the step's axis that pass its test and
+// predicates
+func (st *xstep) apply(n *Node) []*Node {
+	var candidates []*Node
+	switch st.axis {
+	case selfAxis:
+		candidates = []*Node{n}
+	case parentAxis:
+		if n.Parent != nil {
+			candidates = []*Node{n.Parent}
+		}
+	case attributeAxis:
+		if n.Type == ElementNode {
+			names := make([]string, 0, len(n.Attrs))
+			for name := range n.Attrs {
+				if st.test == "*" || st.test == name {
+					names = append(names, name)
+				}
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				candidates = append(candidates, &Node{Type: AttributeNode, Tag: name, Data: n.Attrs[name], Parent: n})
+			}
+		}
+	default:
+		for _, child := range n.Children {
+			if st.matches(child) {
+				candidates = append(candidates, child)
+			}
+		}
+	}
+
+	for _, predicate := range st.predicates {
+		var kept []*Node
+		for i, candidate := range candidates {
+			value := predicate.eval(evalContext{node: candidate, position: i + 1, size: len(candidates)})
+			if position, ok := value.(float64); ok {
+				if position == float64(i+1) {
+					kept = append(kept, candidate)
+				}
+			} else if truth(value) {
+				kept = append(kept, candidate)
+			}
+		}
+		candidates = kept
+	}
+	return candidates
+}
+
+// matches applies the node test of a child step
+func (st *xstep) matches(n *Node) bool {
+	switch st.test {
+	case "node()":
+		return n.Type == ElementNode || n.Type == TextNode
+	case "text()":
+		return n.Type == TextNode
+	case "*":
+		return n.Type == ElementNode
+	default:
+		return n.Type == ElementNode && n.Tag == st.test
+	}
+}
+
+// documentOrder sorts nodes in document order and removes duplicates.
+// Attributes follow their element, in name order.
+func documentOrder(nodes []*Node) []*Node {
+	if len(nodes) < 2 {
+		return nodes
+	}
+
+	root := nodes[0]
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	order := make(map[*Node]int)
+	root.Walk(func(n *Node) {
+		order[n] = len(order)
+	})
+
+	key := func(n *Node) (int, string) {
+		if n.Type == AttributeNode {
+			return order[n.Parent], n.Tag
+		}
+		return order[n], ""
+	}
+	slices.SortStableFunc(nodes, func(a, b *Node) int {
+		ai, an := key(a)
+		bi, bn := key(b)
+		if ai != bi {
+			return ai - bi
+		}
+		// An element comes before its attributes
+		return strings.Compare(an, bn)
+	})
+
+	return slices.CompactFunc(nodes, func(a, b *Node) bool {
+		if a.Type == AttributeNode && b.Type == AttributeNode {
+			return a.Parent == b.Parent && a.Tag == b.Tag
+		}
+		return a == b
+	})
+}
+
+// evalContext is the node a predicate is evaluated for, and its position
+// among the candidates of the step
+type evalContext struct {
+	node           *Node
+	position, size int
+}
+
+// expr is an expression in a predicate. Its value is a float64, string,
+// bool or []*Node.
+type expr interface {
+	eval(ctx evalContext) any
+}
+
+type literal struct{ value any }
+
+func (l literal) eval(evalContext) any { return l.value }
+
+type pathExpr struct{ path *path }
+
+func (p pathExpr) eval(ctx evalContext) any { return p.path.eval(ctx.node) }
+
+type logical struct {
+	and         bool
+	left, right expr
+}
+
+func (l logical) eval(ctx evalContext) any {
+	if l.and {
+		return truth(l.left.eval(ctx)) && truth(l.right.eval(ctx))
+	}
+	return truth(l.left.eval(ctx)) || truth(l.right.eval(ctx))
+}
+
+type comparison struct {
+	op          string
+	left, right expr
+}
+
+// eval compares the values of both sides. A node set compares true if any
+// of its nodes does. Numbers are compared as numbers, and so is everything
+// for < <= > >=.
+func (c comparison) eval(ctx evalContext) any {
+	left, right := c.left.eval(ctx), c.right.eval(ctx)
+	_, leftNumber := left.(float64)
+	_, rightNumber := right.(float64)
+	numeric := leftNumber || rightNumber || (c.op != "=" && c.op != "!=")
+
+	for _, l := range stringValues(left) {
+		for _, r := range stringValues(right) {
+			var cmp int
+			if numeric {
+				ln, lerr := strconv.ParseFloat(l, 64)
+				rn, rerr := strconv.ParseFloat(r, 64)
+				if lerr != nil || rerr != nil {
+					if c.op == "!=" {
+						return true
+					}
+					continue
+				}
+				cmp = compareNumbers(ln, rn)
+			} else {
+				cmp = strings.Compare(l, r)
+			}
+
+			if (c.op == "=" && cmp == 0) || (c.op == "!=" && cmp != 0) ||
+				(c.op == "<" && cmp < 0) || (c.op == "<=" && cmp <= 0) ||
+				(c.op == ">" && cmp > 0) || (c.op == ">=" && cmp >= 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type call struct {
+	name string
+	args []expr
+}
+
+// functions maps the supported functions to their number of arguments,
+// -1 for an optional one
+var functions = map[string]int{
+	"position": 0, "last": 0, "count": 1, "not": 1, "contains": 2,
+	"starts-with": 2, "normalize-space": -1, "string": -1,
+}
+
+func (c call) eval(ctx evalContext) any {
+	args := make([]any, len(c.args))
+	for i, arg := range c.args {
+		args[i] = arg.eval(ctx)
+	}
+
+	switch c.name {
+	case "position":
+		return float64(ctx.position)
+	case "last":
+		return float64(ctx.size)
+	case "count":
+		nodes, _ := args[0].([]*Node)
+		return float64(len(nodes))
+	case "not":
+		return !truth(args[0])
+	case "contains":
+		return strings.Contains(stringValue(args[0]), stringValue(args[1]))
+	case "starts-with":
+		return strings.HasPrefix(stringValue(args[0]), stringValue(args[1]))
+	}
+
+	// normalize-space and string default to the context node
+	value := any([]*Node{ctx.node})
+	if len(args) > 0 {
+		value = args[0]
+	}
+	if c.name == "normalize-space" {
+		return strings.Join(strings.Fields(stringValue(value)), " ")
+	}
+	return stringValue(value)
+}
+
+// truth converts a value to a boolean
+func truth(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []*Node:
+		return len(v) > 0
+	}
+	return false
+}
+
+// stringValue converts a value to a string, using the first node of a node
+// set
+func stringValue(value any) string {
+	if values := stringValues(value); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// stringValues returns the string value of every node of a node set, or
+// the value itself as a string
+func stringValues(value any) []string {
+	switch v := value.(type) {
+	case []*Node:
+		values := make([]string, len(v))
+		for i, n := range v {
+			values[i] = nodeString(n)
+		}
+		return values
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case string:
+		return []string{v}
+	}
+	return nil
+}
+
+// nodeString returns the string value of a node: the text of a text node,
+// the value of an attribute, or the text of an element
+func nodeString(n *Node) string {
+	if n.Type == TextNode || n.Type == AttributeNode {
+		return n.Data
+	}
+	return n.Text()
+}
+
+// tokenize splits an XPath expression into tokens. Names keep their
+// characters, string literals keep their quotes.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case isSpace(c):
+			i++
+		case strings.HasPrefix(s[i:], "//"), strings.HasPrefix(s[i:], ".."),
+			strings.HasPrefix(s[i:], "!="), strings.HasPrefix(s[i:], "<="),
+			strings.HasPrefix(s[i:], ">="):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, s[i:i+end+2])
+			i += end + 2
+		case c >= '0' && c <= '9', c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			start := i
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		case strings.IndexByte("/[]()@,|=<>.*", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case isLetter(c) || c == '_':
+			start := i
+			for i < len(s) && (isLetter(s[i]) || s[i] >= '0' && s[i] <= '9' || strings.IndexByte("-_:.", s[i]) >= 0) {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		default:
+			return nil, fmt.Errorf("unexpected '%c'", c)
+		}
+	}
+	return tokens, nil
+}
+
+// xparser parses XPath tokens
+type xparser struct {
+	tokens []string
+	i      int
+}
+
+func (p *xparser) done() bool {
+	return p.i >= len(p.tokens)
+}
+
+func (p *xparser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.i]
+}
+
+// accept consumes the next token if it is token
+func (p *xparser) accept(token string) bool {
+	if p.peek() == token && !p.done() {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *xparser) expect(token string) error {
+	if !p.accept(token) {
+		if p.done() {
+			return fmt.Errorf("expected '%s' at the end", token)
+		}
+		return fmt.Errorf("expected '%s', found '%s'", token, p.peek())
+	}
+	return nil
+}
+
+// path parses a location path
+func (p *xparser) path() (*path, error) {
+	result := &path{}
+	descendants := false
+	switch {
+	case p.accept("//"):
+		result.absolute, descendants = true, true
+	case p.accept("/"):
+		result.absolute = true
+		// A lone / selects the document
+		if p.done() || p.peek() == "|" || p.peek() == "]" {
+			return result, nil
+		}
+	}
+
+	for {
+		st, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		st.descendants = descendants
+		result.steps = append(result.steps, st)
+
+		switch {
+		case p.accept("//"):
+			descendants = true
+		case p.accept("/"):
+			descendants = false
+		default:
+			return result, nil
+		}
+	}
+}
+
+// step parses a step with its predicates
+func (p *xparser) step() (xstep, error) {
+	var st xstep
+	switch token := p.peek(); {
+	case p.accept("."):
+		st.axis = selfAxis
+		return st, nil
+	case p.accept(".."):
+		st.axis = parentAxis
+		return st, nil
+	case p.accept("@"):
+		st.axis = attributeAxis
+		name := p.peek()
+		if name != "*" && !isName(name) {
+			return st, fmt.Errorf("expected an attribute name after '@'")
+		}
+		p.i++
+		st.test = strings.ToLower(name)
+	case token == "*":
+		p.i++
+		st.test = "*"
+	case token == "text" || token == "node":
+		p.i++
+		if err := p.expect("("); err != nil {
+			return st, err
+		}
+		if err := p.expect(")"); err != nil {
+			return st, err
+		}
+		st.test = token + "()"
+	case isName(token):
+		p.i++
+		st.test = strings.ToLower(token)
+	case token == "":
+		return st, fmt.Errorf("expected a step at the end")
+	default:
+		return st, fmt.Errorf("expected a step, found '%s'", token)
+	}
+
+	for p.accept("[") {
+		predicate, err := p.or()
+		if err != nil {
+			return st, err
+		}
+		if err := p.expect("]"); err != nil {
+			return st, err
+		}
+		st.predicates = append(st.predicates, predicate)
+	}
+	return st, nil
+}
+
+func (p *xparser) or() (expr, error) {
+	left, err := p.and()
+	for err == nil && p.accept("or") {
+		var right expr
+		right, err = p.and()
+		left = logical{left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *xparser) and() (expr, error) {
+	left, err := p.comparison()
+	for err == nil && p.accept("and") {
+		var right expr
+		right, err = p.comparison()
+		left = logical{and: true, left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *xparser) comparison() (expr, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<", "<=", ">", ">="} {
+		if p.accept(op) {
+			right, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return comparison{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+// primary parses a literal, a function call, a parenthesized expression
+// or a path
+func (p *xparser) primary() (expr, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("expected an expression at the end")
+	case token[0] == '"' || token[0] == '\'':
+		p.i++
+		return literal{token[1 : len(token)-1]}, nil
+	case token[0] >= '0' && token[0] <= '9', len(token) > 1 && token[0] == '.':
+		p.i++
+		number, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", token)
+		}
+		return literal{number}, nil
+	case token == "(":
+		p.i++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+
+	if arity, ok := functions[token]; ok && p.i+1 < len(p.tokens) && p.tokens[p.i+1] == "(" {
+		p.i += 2
+		c := call{name: token}
+		for !p.accept(")") {
+			if len(c.args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+		}
+		if (arity >= 0 && len(c.args) != arity) || (arity < 0 && len(c.args) > 1) {
+			return nil, fmt.Errorf("wrong number of arguments for %s()", token)
+		}
+		return c, nil
+	}
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return pathExpr{path}, nil
+}
+
+// isName reports whether a token is an element or attribute name
+func isName(token string) bool {
+	return token != "" && (isLetter(token[0]) || token[0] == '_')
+}
@@ -0,0 +1,90 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Example News</title>
+    <link>https://example.com/</link>
+    <lastBuildDate>Mon, 01 Jul 2024 09:00:00 GMT</lastBuildDate>
+    <item>
+      <title><![CDATA[First <b>story</b>]]></title>
+      <link>https://example.com/1</link>
+      <category domain="tags">news</category>
+    </item>
+    <item>
+      <title>Second story</title>
+      <link>https://example.com/2</link>
+      <enclosure url="https://example.com/2.mp3" length="1024"/>
+    </item>
+  </channel>
+</rss>`
+
+func texts(nodes []*Node) []string {
+	result := make([]string, len(nodes))
+	for i, n := range nodes {
+		result[i] = n.Text()
+	}
+	return result
+}
+
+func TestXPath(t *testing.T) {
+	doc := Parse([]byte(testPage))
+
+	tests := []struct {
+		expression string
+		expected   []string
+	}{
+		{expression: "/html/head/title", expected: []string{"Widgets & more"}},
+		{expression: "//span[@class='price']", expected: []string{"€1,299.00"}},
+		{expression: "//span/@data-currency", expected: []string{"EUR"}},
+		{expression: "//div[@id='product']/h1/text()", expected: []string{"Widget"}},
+		{expression: "//ul/li[2]", expected: []string{"Blue"}},
+		{expression: "//ul/li[last()]", expected: []string{"Green"}},
+		{expression: "//li[position() > 1 and not(contains(., 'Green'))]", expected: []string{"Blue"}},
+		{expression: "//li[starts-with(normalize-space(), 'R')] | //h1", expected: []string{"Widget", "Red"}},
+		{expression: "//div[contains(@class, 'featured')]//img/..", expected: []string{"Widget €1,299.00 Red Blue Green"}},
+		{expression: "//div[count(ul/li) = 3]/h1", expected: []string{"Widget"}},
+		{expression: "//body/p[. = 'Second']", expected: []string{"Second"}},
+		{expression: "//UL/*", expected: []string{"Red", "Blue", "Green"}},
+		{expression: "//table", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			require.Equal(t, tt.expected, texts(MustCompileXPath(tt.expression).Select(doc)))
+		})
+	}
+}
+
+func TestCompileXPathErrors(t *testing.T) {
+	for _, expression := range []string{"", "//div[", "//div[@id='x]", "//div]", "//@", "//div[contains(@id)]", "//div/", "#price"} {
+		_, err := CompileXPath(expression)
+		require.Error(t, err, expression)
+	}
+}
+
+func TestParseXML(t *testing.T) {
+	doc := ParseDocument([]byte(testFeed))
+
+	// <link> and <title> are ordinary elements in XML
+	require.Equal(t, []string{"https://example.com/1", "https://example.com/2"}, texts(MustCompileXPath("//item/link").Select(doc)))
+	require.Equal(t, []string{"First <b>story</b>", "Second story"}, texts(MustCompileXPath("//item/title").Select(doc)))
+	require.Equal(t, "1024", MustCompileXPath("//enclosure/@length").First(doc).Data)
+	require.Equal(t, "Mon, 01 Jul 2024 09:00:00 GMT", MustCompile("channel > lastbuilddate").First(doc).Text())
+}
+
+func TestRemoveAndHTML(t *testing.T) {
+	doc := Parse([]byte(`<div id="a" class="b"><p>one &amp; two</p><br><script>a < b</script><span>x</span></div>`))
+
+	require.Equal(t, `<div class="b" id="a"><p>one &amp; two</p><br><script>a < b</script><span>x</span></div>`, doc.HTML())
+
+	MustCompile("span").First(doc).Remove()
+	MustCompileXPath("//div/@class").First(doc).Remove()
+	require.Equal(t, `<div id="a"><p>one &amp; two</p><br><script>a < b</script></div>`, doc.HTML())
+}
@@ -0,0 +1,250 @@
+// Package fingerprint hashes the content of a list of URLs once into a
+// manifest and later verifies the URLs against it, a batch complement to
+// watching them continuously.
+package fingerprint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// ManifestVersion is the version of the manifest format
+const ManifestVersion = 1
+
+// DefaultConcurrency is the number of URLs fetched at the same time
+const DefaultConcurrency = 4
+
+// Result statuses
+const (
+	StatusUnchanged = "unchanged"
+	StatusChanged   = "changed"
+	StatusError     = "error"
+)
+
+// Options are the content settings used to compute hashes. They are saved in
+// the manifest so that verification hashes the content the same way.
+type Options struct {
+	NormalizeWhitespace bool `json:"normalize_whitespace,omitempty"`
+	IgnoreTimestamps    bool `json:"ignore_timestamps,omitempty"`
+	// Filters are regular expressions stripped from the content
+	Filters []string `json:"filters,omitempty"`
+}
+
+// apply sets the options on a monitor configuration
+func (o Options) apply(config *monitor.Config) error {
+	config.NormalizeWhitespace = o.NormalizeWhitespace
+	config.IgnoreTimestamps = o.IgnoreTimestamps
+	config.ContentFilters = nil
+	for _, pattern := range o.Filters {
+		filter, err := monitor.NewRegexFilter(pattern, "", "Ignore "+pattern)
+		if err != nil {
+			return fmt.Errorf("invalid filter %q: %w", pattern, err)
+		}
+		config.ContentFilters = append(config.ContentFilters, filter)
+	}
+	return nil
+}
+
+// Entry is the fingerprint of a single URL
+type Entry struct {
+	URL        string `json:"url"`
+	Hash       string `json:"hash,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Manifest holds the fingerprints of a list of URLs
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Options   Options   `json:"options"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// Result is the outcome of verifying a URL against its manifest entry
+type Result struct {
+	URL      string `json:"url"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Fetcher fetches and hashes URLs
+type Fetcher struct {
+	// Config is the template of the monitor configuration used for every
+	// URL, e.g. for timeouts, retries, headers or a proxy. Its content
+	// settings are replaced by the options. Defaults to monitor.DefaultConfig.
+	Config *monitor.Config
+	// Concurrency is the number of URLs fetched at the same time. Defaults
+	// to DefaultConcurrency.
+	Concurrency int
+}
+
+// Build fetches every URL once and returns a manifest of their hashes. URLs
+// that can't be fetched are recorded with their error.
+func (f *Fetcher) Build(ctx context.Context, urls []string, opts Options) (*Manifest, error) {
+	entries, err := f.fetch(ctx, urls, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manifest{
+		Version:   ManifestVersion,
+		CreatedAt: time.Now(),
+		Options:   opts,
+		Entries:   entries,
+	}, nil
+}
+
+// Verify re-fetches the URLs of a manifest, hashing them with the options
+// of the manifest, and reports which differ from it. Results are in the
+// order of the manifest.
+func (f *Fetcher) Verify(ctx context.Context, manifest *Manifest) ([]Result, error) {
+	urls := make([]string, len(manifest.Entries))
+	for i, entry := range manifest.Entries {
+		urls[i] = entry.URL
+	}
+
+	entries, err := f.fetch(ctx, urls, manifest.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(entries))
+	for i, entry := range entries {
+		result := Result{
+			URL:      entry.URL,
+			Expected: manifest.Entries[i].Hash,
+			Actual:   entry.Hash,
+			Error:    entry.Error,
+		}
+		switch {
+		case entry.Error != "":
+			result.Status = StatusError
+		case entry.Hash != result.Expected:
+			result.Status = StatusChanged
+		default:
+			result.Status = StatusUnchanged
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// fetch fingerprints the URLs concurrently, returning entries in the order
+// of urls
+func (f *Fetcher) fetch(ctx context.Context, urls []string, opts Options) ([]Entry, error) {
+	template := f.Config
+	if template == nil {
+		template = monitor.DefaultConfig("")
+	}
+
+	configs := make([]*monitor.Config, len(urls))
+	for i, url := range urls {
+		config := *template
+		config.URL = url
+		if err := opts.apply(&config); err != nil {
+			return nil, err
+		}
+		configs[i] = &config
+	}
+
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	entries := make([]Entry, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(urls)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries[i] = fingerprint(ctx, configs[i])
+			}
+		}()
+	}
+
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return entries, nil
+}
+
+// fingerprint fetches and hashes a single URL, giving up when ctx is done
+func fingerprint(ctx context.Context, config *monitor.Config) Entry {
+	m := monitor.NewMonitorWithConfig(config)
+	stop := context.AfterFunc(ctx, m.Stop)
+	defer func() {
+		// Stop the monitor unless ctx already did
+		if stop() {
+			m.Stop()
+		}
+	}()
+
+	hash, change := m.Fingerprint()
+	return Entry{
+		URL:        config.URL,
+		Hash:       hash,
+		StatusCode: change.StatusCode,
+		Error:      change.Error,
+	}
+}
+
+// Write writes the manifest as indented JSON
+func (m *Manifest) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// Save writes the manifest to a file
+func (m *Manifest) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Read reads a manifest written by Write
+func Read(r io.Reader) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("error parsing manifest: %w", err)
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// Load reads a manifest file
+func Load(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
@@ -0,0 +1,120 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+// testFetcher returns a fetcher that doesn't retry
+func testFetcher() *Fetcher {
+	config := monitor.DefaultConfig("")
+	config.RetryCount = 0
+	config.Timeout = time.Second * 5
+	return &Fetcher{Config: config}
+}
+
+func TestBuildAndVerify(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/static", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/release", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("release " + version.Load().(string)))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	urls := []string{server.URL + "/static", server.URL + "/release", server.URL + "/broken"}
+	fetcher := testFetcher()
+
+	manifest, err := fetcher.Build(context.Background(), urls, Options{})
+	require.NoError(t, err)
+	require.Len(t, manifest.Entries, 3)
+	// sha256("hello")
+	require.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", manifest.Entries[0].Hash)
+	require.Equal(t, http.StatusOK, manifest.Entries[0].StatusCode)
+	require.NotEmpty(t, manifest.Entries[1].Hash)
+	require.Empty(t, manifest.Entries[2].Hash)
+	require.Contains(t, manifest.Entries[2].Error, "500")
+
+	version.Store("v2")
+	results, err := fetcher.Verify(context.Background(), manifest)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, StatusUnchanged, results[0].Status)
+	require.Equal(t, StatusChanged, results[1].Status)
+	require.NotEqual(t, results[1].Expected, results[1].Actual)
+	require.Equal(t, StatusError, results[2].Status)
+}
+
+func TestVerifyUsesManifestOptions(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Write([]byte("Hello   world\n"))
+			return
+		}
+		w.Write([]byte("Hello world"))
+	}))
+	defer server.Close()
+
+	fetcher := testFetcher()
+	manifest, err := fetcher.Build(context.Background(), []string{server.URL}, Options{NormalizeWhitespace: true})
+	require.NoError(t, err)
+
+	results, err := fetcher.Verify(context.Background(), manifest)
+	require.NoError(t, err)
+	require.Equal(t, StatusUnchanged, results[0].Status)
+}
+
+func TestBuildInvalidFilter(t *testing.T) {
+	_, err := testFetcher().Build(context.Background(), []string{"http://example.com"}, Options{Filters: []string{"("}})
+	require.Error(t, err)
+}
+
+func TestBuildCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	manifest, err := testFetcher().Build(ctx, []string{server.URL}, Options{})
+	require.NoError(t, err)
+	require.Empty(t, manifest.Entries[0].Hash)
+	require.NotEmpty(t, manifest.Entries[0].Error)
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	manifest := &Manifest{
+		Version:   ManifestVersion,
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Options:   Options{Filters: []string{"csrf=[a-z0-9]+"}},
+		Entries:   []Entry{{URL: "https://example.com", Hash: "abc", StatusCode: 200}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, manifest.Write(&buf))
+
+	read, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, manifest, read)
+
+	_, err = Read(bytes.NewBufferString(`{"version": 99}`))
+	require.ErrorContains(t, err, "unsupported manifest version")
+}
@@ -0,0 +1,417 @@
+// Package har records HTTP requests as HTTP archives (HAR 1.2), the format
+// browser developer tools import and export. Entries keep the headers,
+// timings and redirects of each request, but not the bodies, and the
+// values of headers carrying credentials are redacted.
+package har
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// ContentType is the content type of HAR files
+const ContentType = "application/json"
+
+// Redacted replaces the values of headers carrying credentials
+const Redacted = "[redacted]"
+
+// redactedHeaders are the canonical names of headers whose values are
+// redacted
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// File is a HAR file
+type File struct {
+	Log Log `json:"log"`
+}
+
+// Log is the log of a HAR file
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator names the application that created the log
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a request and its response. Time is the total time of the
+// request in milliseconds. A request that failed has the error as comment
+// and a response with status 0.
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         Request   `json:"request"`
+	Response        Response  `json:"response"`
+	Cache           struct{}  `json:"cache"`
+	Timings         Timings   `json:"timings"`
+	ServerIPAddress string    `json:"serverIPAddress,omitempty"`
+	Comment         string    `json:"comment,omitempty"`
+}
+
+// Request is the request of an entry
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Response is the response of an entry. RedirectURL is its Location
+// header.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Content describes the body of a response. Size is the decoded size.
+type Content struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// NameValue is a header, cookie or query parameter
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Timings are the phases of a request in milliseconds, -1 for those that
+// didn't happen, e.g. connecting on a reused connection. Connect includes
+// SSL.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// Recorder collects the entries of the requests sent through its
+// transports. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Transport returns a round tripper that sends requests through base, or
+// http.DefaultTransport if nil, and records them
+func (r *Recorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{recorder: r, base: base}
+}
+
+// Reset drops the recorded entries, e.g. before the requests of a check
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Len returns the number of recorded entries
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// File returns the entries recorded since the last reset as a HAR file.
+// Entries whose body is still being read have no receive timing yet.
+func (r *Recorder) File() *File {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]Entry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, *entry)
+	}
+	return &File{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "hawkeye", Version: version.Version},
+		Entries: entries,
+	}}
+}
+
+// add records an entry
+func (r *Recorder) add(entry *Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// transport records the requests sent through base
+type transport struct {
+	recorder *Recorder
+	base     http.RoundTripper
+}
+
+// phases are the times at which the phases of a request started and ended,
+// as reported by httptrace
+type phases struct {
+	mu                  sync.Mutex
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	gotConn, wrote      time.Time
+	firstByte           time.Time
+	remoteAddr          string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	p := &phases{}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { p.set(&p.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { p.set(&p.dnsDone) },
+		ConnectStart:         func(string, string) { p.set(&p.connStart) },
+		ConnectDone:          func(string, string, error) { p.set(&p.connDone) },
+		TLSHandshakeStart:    func() { p.set(&p.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { p.set(&p.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { p.set(&p.wrote) },
+		GotFirstResponseByte: func() { p.set(&p.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.gotConn = time.Now()
+			if info.Conn != nil {
+				p.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	entry := &Entry{
+		StartedDateTime: start,
+		Request: Request{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []NameValue{},
+			Headers:     headers(req.Header),
+			QueryString: queryString(req),
+			HeadersSize: -1,
+			BodySize:    max(req.ContentLength, 0),
+		},
+		Response: Response{
+			Cookies:     []NameValue{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	end := time.Now()
+	if err != nil {
+		entry.Comment = err.Error()
+		t.finish(entry, p, start, end)
+		return nil, err
+	}
+
+	entry.Response.Status = resp.StatusCode
+	entry.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)))
+	entry.Response.HTTPVersion = resp.Proto
+	entry.Response.Headers = headers(resp.Header)
+	entry.Response.Content.MimeType = resp.Header.Get("Content-Type")
+	entry.Response.RedirectURL = resp.Header.Get("Location")
+	t.finish(entry, p, start, end)
+
+	resp.Body = &body{ReadCloser: resp.Body, recorder: t.recorder, entry: entry, phases: p, start: start}
+	return resp, nil
+}
+
+// finish records entry with the timings of its phases. The receive phase
+// is added once the body has been read.
+func (t *transport) finish(entry *Entry, p *phases, start, end time.Time) {
+	p.mu.Lock()
+	entry.Timings = p.timings(start, end)
+	entry.ServerIPAddress = serverIP(p.remoteAddr)
+	p.mu.Unlock()
+	entry.Time = entry.Timings.total()
+	t.recorder.add(entry)
+}
+
+// set records the current time as the time of a phase
+func (p *phases) set(at *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*at = time.Now()
+}
+
+// timings computes the timings of a request that started at start and
+// whose response headers arrived at end. p.mu must be held.
+func (p *phases) timings(start, end time.Time) Timings {
+	timings := Timings{
+		Blocked: -1,
+		DNS:     span(p.dnsStart, p.dnsDone),
+		Connect: -1,
+		SSL:     span(p.tlsStart, p.tlsDone),
+		Send:    span(p.gotConn, p.wrote),
+		Wait:    span(p.wrote, p.firstByte),
+		Receive: -1,
+	}
+	if !p.connStart.IsZero() {
+		connected := p.connDone
+		if p.tlsDone.After(connected) {
+			connected = p.tlsDone
+		}
+		timings.Connect = span(p.connStart, connected)
+	}
+	// Blocked is the time spent waiting for a connection, e.g. in the pool
+	first := p.gotConn
+	for _, t := range []time.Time{p.dnsStart, p.connStart} {
+		if !t.IsZero() && t.Before(first) {
+			first = t
+		}
+	}
+	timings.Blocked = span(start, first)
+	if timings.Wait < 0 && !p.wrote.IsZero() {
+		timings.Wait = span(p.wrote, end)
+	}
+	return timings
+}
+
+// total returns the time of the request, the sum of its phases but SSL,
+// which is part of connect
+func (t Timings) total() float64 {
+	var total float64
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if phase > 0 {
+			total += phase
+		}
+	}
+	return total
+}
+
+// span returns the milliseconds from start to end, or -1 if either is
+// unknown
+func span(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return -1
+	}
+	return float64(end.Sub(start).Microseconds()) / 1000
+}
+
+// body records the receive timing and size of a response body once it has
+// been read or closed
+type body struct {
+	io.ReadCloser
+	recorder *Recorder
+	entry    *Entry
+	phases   *phases
+	start    time.Time
+	size     int64
+	once     sync.Once
+}
+
+// Read implements io.Reader
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (b *body) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+// done completes the entry with the body
+func (b *body) done() {
+	b.once.Do(func() {
+		received := time.Now()
+		b.recorder.mu.Lock()
+		defer b.recorder.mu.Unlock()
+		b.phases.mu.Lock()
+		defer b.phases.mu.Unlock()
+		b.entry.Timings.Receive = span(b.phases.firstByte, received)
+		b.entry.Time = b.entry.Timings.total()
+		b.entry.Response.Content.Size = b.size
+		b.entry.Response.BodySize = b.size
+	})
+}
+
+// headers converts headers to name/value pairs sorted by name, redacting
+// credentials
+func headers(header http.Header) []NameValue {
+	result := []NameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			if redactedHeaders[http.CanonicalHeaderKey(name)] {
+				value = Redacted
+			}
+			result = append(result, NameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// queryString returns the query parameters of a request
+func queryString(req *http.Request) []NameValue {
+	result := []NameValue{}
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range query[name] {
+			result = append(result, NameValue{Name: name, Value: value})
+		}
+	}
+	return result
+}
+
+// serverIP returns the IP address of a remote address
+func serverIP(addr string) string {
+	if i := strings.LastIndexByte(addr, ':'); i > 0 {
+		addr = addr[:i]
+	}
+	return strings.Trim(addr, "[]")
+}
@@ -0,0 +1,69 @@
+package har
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new?page=2", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Cache", "HIT")
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := &http.Client{Transport: recorder.Transport(nil)}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/old", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+
+	file := recorder.File()
+	require.Equal(t, "1.2", file.Log.Version)
+	require.Len(t, file.Log.Entries, 2, "the redirect and the page")
+
+	redirect := file.Log.Entries[0]
+	require.Equal(t, http.StatusMovedPermanently, redirect.Response.Status)
+	require.Equal(t, "Moved Permanently", redirect.Response.StatusText)
+	require.Equal(t, "/new?page=2", redirect.Response.RedirectURL)
+	require.Contains(t, redirect.Request.Headers, NameValue{Name: "Authorization", Value: Redacted})
+	require.Equal(t, "127.0.0.1", redirect.ServerIPAddress)
+	require.Greater(t, redirect.Timings.Connect, -1.0, "the first request connected")
+
+	page := file.Log.Entries[1]
+	require.Equal(t, []NameValue{{Name: "page", Value: "2"}}, page.Request.QueryString)
+	require.Equal(t, http.StatusOK, page.Response.Status)
+	require.Contains(t, page.Response.Headers, NameValue{Name: "X-Cache", Value: "HIT"})
+	require.Contains(t, page.Response.Headers, NameValue{Name: "Set-Cookie", Value: Redacted})
+	require.Equal(t, "text/plain", page.Response.Content.MimeType)
+	require.Equal(t, int64(5), page.Response.Content.Size)
+	require.GreaterOrEqual(t, page.Timings.Receive, 0.0)
+	require.GreaterOrEqual(t, page.Time, page.Timings.Wait)
+
+	recorder.Reset()
+	require.Equal(t, 0, recorder.Len())
+
+	// Failed requests are recorded with their error
+	_, err = client.Get("http://127.0.0.1:1/")
+	require.Error(t, err)
+	file = recorder.File()
+	require.Len(t, file.Log.Entries, 1)
+	require.Equal(t, 0, file.Log.Entries[0].Response.Status)
+	require.NotEmpty(t, file.Log.Entries[0].Comment)
+}
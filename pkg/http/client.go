@@ -1,7 +1,9 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/nemuizzz/hawkeye/pkg/version"
@@ -13,6 +15,39 @@ type ClientOptions struct {
 	FollowRedirects bool
 	Headers         map[string]string
 	UserAgent       string
+	// ProxyURL sends requests through a proxy, e.g. "http://proxy:3128" or
+	// "socks5://localhost:1080". It is ignored if Transport is set.
+	ProxyURL *url.URL
+	// TLS configures server verification and client certificates. It is
+	// ignored if Transport is set.
+	TLS TLSOptions
+	// Transport overrides the underlying round tripper, e.g. for testing
+	Transport http.RoundTripper
+	// Jar, if set, keeps the cookies of responses and sends them with
+	// later requests
+	Jar http.CookieJar
+	// OAuth2, if set, authenticates requests with a client credentials
+	// token
+	OAuth2 *OAuth2Options
+}
+
+// ParseProxyURL parses the URL of an HTTP, HTTPS or SOCKS5 proxy
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s' (expected http, https or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL '%s' has no host", rawURL)
+	}
+
+	return u, nil
 }
 
 // DefaultClientOptions returns default HTTP client options
@@ -24,7 +59,9 @@ func DefaultClientOptions() *ClientOptions {
 	}
 }
 
-// NewClient creates a new HTTP client with the provided options
+// NewClient creates a new HTTP client with the provided options. If the TLS
+// options can't be loaded, every request of the client fails with the error;
+// call TLSOptions.Config first to report it earlier.
 func NewClient(opts *ClientOptions) *http.Client {
 	if opts == nil {
 		opts = DefaultClientOptions()
@@ -32,6 +69,31 @@ func NewClient(opts *ClientOptions) *http.Client {
 
 	client := &http.Client{
 		Timeout: opts.Timeout,
+		Jar:     opts.Jar,
+	}
+
+	if opts.Transport != nil {
+		client.Transport = opts.Transport
+	} else if opts.ProxyURL != nil || !opts.TLS.IsZero() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if opts.ProxyURL != nil {
+			transport.Proxy = http.ProxyURL(opts.ProxyURL)
+		}
+
+		if tlsConfig, err := opts.TLS.Config(); err != nil {
+			client.Transport = errorTransport{err: err}
+		} else {
+			transport.TLSClientConfig = tlsConfig
+			client.Transport = transport
+		}
+	}
+
+	if opts.OAuth2 != nil {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = &oauth2Transport{base: base, source: sharedTokenSource(opts.OAuth2)}
 	}
 
 	if !opts.FollowRedirects {
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultAcceptEncoding is the Accept-Encoding sent by monitors unless
+// another one is configured
+const DefaultAcceptEncoding = "gzip, deflate, br"
+
+// ErrUnsupportedEncoding is returned for responses in a content encoding
+// without a decoder
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// Decoder returns a reader of the decoded content of r
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decodersMu sync.RWMutex
+	// decoders are the content decoders by encoding
+	decoders = map[string]Decoder{
+		"gzip":    decodeGzip,
+		"x-gzip":  decodeGzip,
+		"deflate": decodeDeflate,
+		"br":      decodeBrotli,
+	}
+)
+
+// RegisterDecoder adds a decoder for a content encoding, e.g. "zstd", or
+// replaces a built-in one. Registered encodings are decoded by DecodeBody
+// and may be requested in an Accept-Encoding.
+func RegisterDecoder(encoding string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(encoding)] = decoder
+}
+
+// Encodings returns the content encodings that can be decoded, sorted
+func Encodings() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	encodings := make([]string, 0, len(decoders))
+	for encoding := range decoders {
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+	return encodings
+}
+
+// decoder returns the decoder of a content encoding
+func decoder(encoding string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	decode, ok := decoders[encoding]
+	return decode, ok
+}
+
+// ValidateAcceptEncoding checks an Accept-Encoding value such as
+// "gzip, deflate;q=0.5": every encoding must be decodable, identity or *
+func ValidateAcceptEncoding(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		encoding, _, _ := strings.Cut(part, ";")
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding == "identity" || encoding == "*" {
+			continue
+		}
+		if _, ok := decoder(encoding); !ok {
+			return fmt.Errorf("%w '%s' in Accept-Encoding (expected one of identity, %s)", ErrUnsupportedEncoding, encoding, strings.Join(Encodings(), ", "))
+		}
+	}
+	return nil
+}
+
+// decodeGzip decodes gzip content
+func decodeGzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// decodeDeflate decodes deflate content. HTTP defines it as zlib, but some
+// servers send raw deflate data, so the zlib header is checked first.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decodeBrotli decodes brotli content
+func decodeBrotli(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+// DecodeBody returns a reader of the decoded body of resp, undoing its
+// Content-Encoding, so responses compare by their content whichever encoding
+// the server picks. The body itself is returned when it isn't encoded.
+// Encodings without a decoder fail with ErrUnsupportedEncoding.
+func DecodeBody(resp *http.Response) (io.ReadCloser, error) {
+	var encodings []string
+	for _, value := range resp.Header.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding == "" || encoding == "identity" {
+				continue
+			}
+			if _, ok := decoder(encoding); !ok {
+				return nil, fmt.Errorf("%w '%s'", ErrUnsupportedEncoding, encoding)
+			}
+			encodings = append(encodings, encoding)
+		}
+	}
+	if len(encodings) == 0 {
+		return resp.Body, nil
+	}
+	return &decodedBody{body: resp.Body, encodings: encodings}, nil
+}
+
+// decodedBody decodes a response body in the order its encodings were
+// applied. The decoders are set up on the first read, so empty bodies, e.g.
+// of HEAD requests, aren't an error.
+type decodedBody struct {
+	body      io.ReadCloser
+	encodings []string
+	reader    io.Reader
+	closers   []io.Closer
+	err       error
+}
+
+// Read implements io.Reader
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.open()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+// open sets up the decoders of the body
+func (b *decodedBody) open() (io.Reader, error) {
+	buffered := bufio.NewReader(b.body)
+	if _, err := buffered.Peek(1); err == io.EOF {
+		return buffered, nil
+	}
+
+	var r io.Reader = buffered
+	for i := len(b.encodings) - 1; i >= 0; i-- {
+		decode, _ := decoder(b.encodings[i])
+		decoded, err := decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s response: %w", b.encodings[i], err)
+		}
+		b.closers = append(b.closers, decoded)
+		r = decoded
+	}
+	return r, nil
+}
+
+// Close implements io.Closer
+func (b *decodedBody) Close() error {
+	for _, closer := range b.closers {
+		closer.Close()
+	}
+	return b.body.Close()
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/require"
+)
+
+// encode compresses content in encoding
+func encode(t *testing.T, encoding, content string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		var err error
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "x-base64":
+		w = base64.NewEncoder(base64.StdEncoding, &buf)
+	}
+	_, err := io.WriteString(w, content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	RegisterDecoder("x-base64", func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/identity":
+			w.Write([]byte("hello"))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(encode(t, "gzip", "hello"))
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(encode(t, "deflate", "hello"))
+		case "/raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(encode(t, "raw-deflate", "hello"))
+		case "/chain":
+			// Applied in order: gzip first, then base64
+			w.Header().Set("Content-Encoding", "gzip, x-base64")
+			w.Write(encode(t, "x-base64", string(encode(t, "gzip", "hello"))))
+		case "/empty":
+			w.Header().Set("Content-Encoding", "gzip")
+		case "/br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write(encode(t, "br", "hello"))
+		case "/zstd":
+			w.Header().Set("Content-Encoding", "zstd")
+			w.Write([]byte{0x28, 0xb5, 0x2f, 0xfd})
+		}
+	}))
+	defer server.Close()
+
+	get := func(path string) (string, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", DefaultAcceptEncoding)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := DecodeBody(resp)
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		content, err := io.ReadAll(body)
+		return string(content), err
+	}
+
+	for _, path := range []string{"/identity", "/gzip", "/deflate", "/raw-deflate", "/br", "/chain"} {
+		body, err := get(path)
+		require.NoError(t, err, path)
+		require.Equal(t, "hello", body, path)
+	}
+
+	body, err := get("/empty")
+	require.NoError(t, err)
+	require.Empty(t, body)
+
+	_, err = get("/zstd")
+	require.ErrorIs(t, err, ErrUnsupportedEncoding)
+}
+
+func TestValidateAcceptEncoding(t *testing.T) {
+	require.NoError(t, ValidateAcceptEncoding("gzip, deflate;q=0.5, identity"))
+	require.NoError(t, ValidateAcceptEncoding("*"))
+	require.NoError(t, ValidateAcceptEncoding("gzip, br"))
+	require.ErrorIs(t, ValidateAcceptEncoding("gzip, zstd"), ErrUnsupportedEncoding)
+	require.ErrorContains(t, ValidateAcceptEncoding("zstd"), "'zstd'")
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// tokenExpiryDelta is how long before it expires a token is replaced, so
+// that it doesn't expire in flight
+const tokenExpiryDelta = 10 * time.Second
+
+// maxTokenResponseSize bounds the token responses read
+const maxTokenResponseSize = 1 << 20
+
+// ErrOAuth2Options is returned for OAuth2 options without a token URL or
+// client ID
+var ErrOAuth2Options = errors.New("OAuth2 requires an http or https token URL and a client ID")
+
+// OAuth2Options configures the OAuth2 client credentials grant: a token is
+// requested from TokenURL with the client ID and secret, sent with HTTP
+// Basic authentication, and sent as a bearer token with every request.
+// Tokens are cached until shortly before they expire, shared by all clients
+// with the same options, and requested again when a server answers 401
+// Unauthorized. The client ID and secret may be secret placeholders, see
+// package secret.
+type OAuth2Options struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// Validate checks that the token URL and client ID are set
+func (o *OAuth2Options) Validate() error {
+	u, err := url.Parse(o.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || o.ClientID == "" {
+		return ErrOAuth2Options
+	}
+	return nil
+}
+
+// tokenKey identifies the cached token of a set of options
+type tokenKey struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       string
+}
+
+// tokenSources are the token caches shared by clients, by options
+var tokenSources sync.Map
+
+// tokenSource caches the token of a set of options
+type tokenSource struct {
+	mu      sync.Mutex
+	options OAuth2Options
+	token   string
+	expiry  time.Time
+}
+
+// sharedTokenSource returns the token cache of options
+func sharedTokenSource(options *OAuth2Options) *tokenSource {
+	key := tokenKey{
+		tokenURL:     options.TokenURL,
+		clientID:     options.ClientID,
+		clientSecret: options.ClientSecret,
+		scopes:       strings.Join(options.Scopes, " "),
+	}
+	source, _ := tokenSources.LoadOrStore(key, &tokenSource{options: *options})
+	return source.(*tokenSource)
+}
+
+// get returns a valid token, requested with transport if none is cached. A
+// cached token equal to stale, which a server rejected, is replaced.
+func (s *tokenSource) get(ctx context.Context, transport http.RoundTripper, stale string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.token != stale && (s.expiry.IsZero() || time.Now().Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.options.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.options.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.options.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	clientID, err := secret.Resolve(s.options.ClientID)
+	if err != nil {
+		return "", fmt.Errorf("OAuth2 client ID: %w", err)
+	}
+	clientSecret, err := secret.Resolve(s.options.ClientSecret)
+	if err != nil {
+		return "", fmt.Errorf("OAuth2 client secret: %w", err)
+	}
+	// RFC 6749 section 2.3.1 encodes the credentials before Basic auth
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting OAuth2 token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxTokenResponseSize)).Decode(&body)
+	switch {
+	case body.Error != "":
+		message := body.Error
+		if body.ErrorDescription != "" {
+			message += ": " + body.ErrorDescription
+		}
+		return "", fmt.Errorf("error requesting OAuth2 token: %s", message)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("error requesting OAuth2 token: status code %d", resp.StatusCode)
+	case decodeErr != nil:
+		return "", fmt.Errorf("error requesting OAuth2 token: %w", decodeErr)
+	case body.AccessToken == "":
+		return "", fmt.Errorf("error requesting OAuth2 token: no access_token in the response")
+	}
+
+	s.token = body.AccessToken
+	s.expiry = time.Time{}
+	if body.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - tokenExpiryDelta)
+	}
+	return s.token, nil
+}
+
+// oauth2Transport adds the bearer token of its source to requests
+type oauth2Transport struct {
+	base   http.RoundTripper
+	source *tokenSource
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.get(req.Context(), t.base, "")
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.base.RoundTrip(authorized)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	// The token was revoked before it expired: replace it and retry once
+	fresh, err := t.source.get(req.Context(), t.base, token)
+	if err != nil || fresh == token {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+fresh)
+	return t.base.RoundTrip(retry)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer issues numbered tokens to client "id" with secret "s&cret"
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		require.NoError(t, r.ParseForm())
+		if id != "id" || secret != "s%26cret" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad credentials"}`)
+			return
+		}
+		require.Equal(t, "read write", r.Form.Get("scope"))
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestOAuth2(t *testing.T) {
+	tokens, issued := tokenServer(t, 3600)
+
+	// The API accepts only the current token
+	var current atomic.Value
+	current.Store("token-1")
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+current.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer api.Close()
+
+	options := &OAuth2Options{TokenURL: tokens.URL, ClientID: "id", ClientSecret: "s&cret", Scopes: []string{"read", "write"}}
+	require.NoError(t, options.Validate())
+	client := NewClient(&ClientOptions{OAuth2: options})
+
+	for range 3 {
+		resp, err := client.Get(api.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.Equal(t, int32(1), issued.Load(), "token is cached")
+
+	// Another client with the same options shares the token
+	resp, err := NewClient(&ClientOptions{OAuth2: options}).Get(api.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int32(1), issued.Load())
+
+	// A revoked token is replaced and the request retried
+	current.Store("token-2")
+	resp, err = client.Get(api.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), issued.Load())
+}
+
+func TestOAuth2Expiry(t *testing.T) {
+	// Tokens expiring within tokenExpiryDelta are never reused
+	tokens, issued := tokenServer(t, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+
+	client := NewClient(&ClientOptions{OAuth2: &OAuth2Options{
+		TokenURL: tokens.URL, ClientID: "id", ClientSecret: "s&cret", Scopes: []string{"read", "write"},
+	}})
+	for range 2 {
+		resp, err := client.Get(api.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	require.Equal(t, int32(2), issued.Load())
+}
+
+func TestOAuth2Errors(t *testing.T) {
+	tokens, _ := tokenServer(t, 3600)
+
+	client := NewClient(&ClientOptions{OAuth2: &OAuth2Options{TokenURL: tokens.URL, ClientID: "other"}})
+	_, err := client.Get(tokens.URL)
+	require.ErrorContains(t, err, "invalid_client: bad credentials")
+
+	require.ErrorIs(t, (&OAuth2Options{TokenURL: tokens.URL}).Validate(), ErrOAuth2Options)
+	require.ErrorIs(t, (&OAuth2Options{TokenURL: "ftp://example.com", ClientID: "id"}).Validate(), ErrOAuth2Options)
+}
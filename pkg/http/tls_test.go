@@ -0,0 +1,98 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// clientCertificate creates a self-signed client certificate and returns the
+// paths of its certificate and key files
+func clientCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return writePEM(t, dir, "client.pem", "CERTIFICATE", der), writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestTLSOptions(t *testing.T) {
+	var clientCerts int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	certFile, keyFile := clientCertificate(t, dir)
+
+	get := func(opts TLSOptions) error {
+		resp, err := NewClient(&ClientOptions{Timeout: time.Second * 5, TLS: opts}).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// The test server's certificate is self-signed
+	require.Error(t, get(TLSOptions{MinVersion: tls.VersionTLS12}))
+	require.NoError(t, get(TLSOptions{InsecureSkipVerify: true}))
+	require.NoError(t, get(TLSOptions{CAFile: caFile}))
+	require.Zero(t, clientCerts)
+
+	require.NoError(t, get(TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}))
+	require.Equal(t, 1, clientCerts)
+
+	// Options that can't be loaded fail every request
+	err := get(TLSOptions{CAFile: filepath.Join(dir, "missing.pem")})
+	require.ErrorContains(t, err, "error reading CA file")
+
+	_, err = TLSOptions{CertFile: certFile}.Config()
+	require.ErrorContains(t, err, "must be set together")
+
+	config, err := TLSOptions{}.Config()
+	require.NoError(t, err)
+	require.Nil(t, config)
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	version, err := ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), version)
+
+	_, err = ParseTLSVersion("TLS1.3")
+	require.Error(t, err)
+}
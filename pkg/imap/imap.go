@@ -0,0 +1,427 @@
+// Package imap reads the messages of a mailbox over IMAP4rev1 (RFC 3501), so
+// that updates which arrive by email can be monitored like pages. Mailboxes
+// are only examined, never changed: messages are not marked as seen.
+package imap
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLimit is the number of the most recent matching messages fetched
+// when Query.Limit is zero
+const DefaultLimit = 50
+
+// maxLiteral bounds the size of a string sent by the server, e.g. the
+// headers of a message
+const maxLiteral = 1 << 20
+
+var (
+	// ErrLoginDisabled is returned when a server without TLS refuses
+	// logins and offers no STARTTLS to secure the connection
+	ErrLoginDisabled = errors.New("the server refuses logins without TLS and doesn't offer STARTTLS")
+	// ErrNoCredentials is returned for a query without a user
+	ErrNoCredentials = errors.New("a user is required to log in to the mailbox")
+)
+
+// Query describes the messages of a mailbox to read
+type Query struct {
+	// Addr is the host and port of the server
+	Addr string
+	// TLS connects with implicit TLS, as for imaps URLs. Otherwise the
+	// connection is upgraded with STARTTLS when the server offers it.
+	TLS bool
+	// InsecureSkipVerify accepts any certificate
+	InsecureSkipVerify bool
+	User               string
+	Password           string
+	// Mailbox is the folder examined, INBOX by default
+	Mailbox string
+	// Search holds IMAP search criteria such as `UNSEEN FROM "alerts"`,
+	// ALL by default
+	Search string
+	// Limit is the number of the most recent matching messages read,
+	// DefaultLimit if zero
+	Limit int
+}
+
+// Message describes a message found by Read
+type Message struct {
+	// UID identifies the message within the mailbox, as long as
+	// UIDValidity doesn't change
+	UID         uint32
+	UIDValidity uint32
+	// MessageID is the Message-ID header without its angle brackets
+	MessageID string
+	From      string
+	Subject   string
+	// Date is in UTC, and zero if the message has none that can be parsed
+	Date time.Time
+}
+
+// ParseURL parses a mailbox URL such as
+// imaps://user@imap.example.com/INBOX?search=UNSEEN%20FROM%20%22alerts%22.
+// The path names the mailbox, INBOX if empty, and the search and limit
+// parameters set Query.Search and Query.Limit. The port defaults to 993 for
+// imaps and 143 for imap.
+func ParseURL(raw string) (*Query, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "imap" && u.Scheme != "imaps") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid mailbox URL '%s': must be an imap or imaps URL with a host", raw)
+	}
+
+	q := &Query{TLS: u.Scheme == "imaps", Mailbox: strings.TrimPrefix(u.Path, "/"), Search: u.Query().Get("search")}
+	port := u.Port()
+	if port == "" {
+		port = "143"
+		if q.TLS {
+			port = "993"
+		}
+	}
+	q.Addr = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		q.User = u.User.Username()
+		q.Password, _ = u.User.Password()
+	}
+	if limit := u.Query().Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit <= 0 {
+			return nil, fmt.Errorf("invalid mailbox URL '%s': limit must be a positive number", raw)
+		}
+	}
+	return q, nil
+}
+
+// IsURL reports whether raw is an imap or imaps URL
+func IsURL(raw string) bool {
+	scheme, _, found := strings.Cut(raw, "://")
+	return found && (strings.EqualFold(scheme, "imap") || strings.EqualFold(scheme, "imaps"))
+}
+
+// Read logs in to the server, examines the mailbox and returns the most
+// recent messages matching the search, oldest first
+func Read(ctx context.Context, q *Query) ([]Message, error) {
+	if q.User == "" {
+		return nil, ErrNoCredentials
+	}
+
+	c, err := dial(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	if err := c.login(q); err != nil {
+		return nil, err
+	}
+
+	mailbox := q.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	responses, err := c.command("EXAMINE " + quote(mailbox))
+	if err != nil {
+		return nil, err
+	}
+	var validity uint32
+	for _, r := range responses {
+		if m := uidValidityPattern.FindStringSubmatch(r.text); m != nil {
+			v, _ := strconv.ParseUint(m[1], 10, 32)
+			validity = uint32(v)
+		}
+	}
+
+	search := strings.TrimSpace(q.Search)
+	if search == "" {
+		search = "ALL"
+	}
+	if !isASCII(search) {
+		search = "CHARSET UTF-8 " + search
+	}
+	responses, err = c.command("UID SEARCH " + search)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range responses {
+		fields, found := strings.CutPrefix(r.text, "* SEARCH")
+		if !found {
+			continue
+		}
+		for _, field := range strings.Fields(fields) {
+			if uid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	slices.Sort(uids)
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if len(uids) > limit {
+		uids = uids[len(uids)-limit:]
+	}
+	if len(uids) == 0 {
+		c.logout()
+		return nil, nil
+	}
+
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	responses, err = c.command("UID FETCH " + strings.Join(set, ",") + " (UID BODY.PEEK[HEADER.FIELDS (MESSAGE-ID FROM SUBJECT DATE)])")
+	if err != nil {
+		return nil, err
+	}
+	c.logout()
+
+	var messages []Message
+	for _, r := range responses {
+		if !fetchPattern.MatchString(r.text) || len(r.literals) == 0 {
+			continue
+		}
+		m := uidPattern.FindStringSubmatch(r.text)
+		if m == nil {
+			continue
+		}
+		uid, _ := strconv.ParseUint(m[1], 10, 32)
+		message := parseHeader(r.literals[0])
+		message.UID, message.UIDValidity = uint32(uid), validity
+		messages = append(messages, message)
+	}
+	slices.SortFunc(messages, func(a, b Message) int { return cmp.Compare(a.UID, b.UID) })
+	return messages, nil
+}
+
+var (
+	uidValidityPattern = regexp.MustCompile(`(?i)\[UIDVALIDITY (\d+)\]`)
+	fetchPattern       = regexp.MustCompile(`(?i)^\* \d+ FETCH `)
+	uidPattern         = regexp.MustCompile(`(?i)\bUID (\d+)`)
+	literalPattern     = regexp.MustCompile(`\{(\d+)\}$`)
+)
+
+// parseHeader reads the fields of a message from its header
+func parseHeader(data []byte) Message {
+	var message Message
+	msg, err := mail.ReadMessage(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n")))
+	if err != nil {
+		return message
+	}
+
+	decoder := new(mime.WordDecoder)
+	decode := func(value string) string {
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			return strings.TrimSpace(decoded)
+		}
+		return strings.TrimSpace(value)
+	}
+
+	message.MessageID = strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>")
+	message.Subject = decode(msg.Header.Get("Subject"))
+	message.From = decode(msg.Header.Get("From"))
+	if addresses, err := msg.Header.AddressList("From"); err == nil && len(addresses) > 0 {
+		message.From = addresses[0].Address
+		if addresses[0].Name != "" {
+			message.From = fmt.Sprintf("%s <%s>", addresses[0].Name, addresses[0].Address)
+		}
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		message.Date = date.UTC()
+	}
+	return message
+}
+
+// conn is a connection to an IMAP server
+type conn struct {
+	net  net.Conn
+	r    *bufio.Reader
+	tag  int
+	stop func() bool
+}
+
+// response is an untagged response line with the literal strings it holds
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+// dial connects to the server and reads its greeting. The connection is
+// closed when ctx is done.
+func dial(ctx context.Context, q *Query) (*conn, error) {
+	host, _, err := net.SplitHostPort(q.Addr)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{ServerName: host, InsecureSkipVerify: q.InsecureSkipVerify}
+
+	var nc net.Conn
+	if q.TLS {
+		nc, err = (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", q.Addr)
+	} else {
+		nc, err = (&net.Dialer{}).DialContext(ctx, "tcp", q.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{net: nc, r: bufio.NewReader(nc)}
+	c.stop = context.AfterFunc(ctx, func() { nc.Close() })
+
+	greeting, err := c.read()
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	if !strings.HasPrefix(strings.ToUpper(greeting.text), "* OK") && !strings.HasPrefix(strings.ToUpper(greeting.text), "* PREAUTH") {
+		c.close()
+		return nil, fmt.Errorf("imap: unexpected greeting: %s", greeting.text)
+	}
+
+	if !q.TLS {
+		caps, err := c.capabilities()
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		if slices.Contains(caps, "STARTTLS") {
+			if _, err := c.command("STARTTLS"); err != nil {
+				c.close()
+				return nil, err
+			}
+			tc := tls.Client(nc, config)
+			if err := tc.HandshakeContext(ctx); err != nil {
+				c.close()
+				return nil, err
+			}
+			c.net, c.r = tc, bufio.NewReader(tc)
+		} else if slices.Contains(caps, "LOGINDISABLED") {
+			c.close()
+			return nil, ErrLoginDisabled
+		}
+	}
+	return c, nil
+}
+
+// capabilities returns the capabilities the server announces, upper case
+func (c *conn) capabilities() ([]string, error) {
+	responses, err := c.command("CAPABILITY")
+	if err != nil {
+		return nil, err
+	}
+	var caps []string
+	for _, r := range responses {
+		if rest, found := strings.CutPrefix(strings.ToUpper(r.text), "* CAPABILITY "); found {
+			caps = append(caps, strings.Fields(rest)...)
+		}
+	}
+	return caps, nil
+}
+
+// login authenticates with the user and password of the query
+func (c *conn) login(q *Query) error {
+	if _, err := c.command("LOGIN " + quote(q.User) + " " + quote(q.Password)); err != nil {
+		return fmt.Errorf("login as %s: %w", q.User, err)
+	}
+	return nil
+}
+
+// logout ends the session politely; its result doesn't matter
+func (c *conn) logout() {
+	c.command("LOGOUT")
+}
+
+// close closes the connection
+func (c *conn) close() {
+	c.stop()
+	c.net.Close()
+}
+
+// command sends a command and returns the untagged responses to it, or an
+// error if the server doesn't complete it with OK
+func (c *conn) command(command string) ([]response, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := io.WriteString(c.net, tag+" "+command+"\r\n"); err != nil {
+		return nil, err
+	}
+
+	var responses []response
+	for {
+		r, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		status, found := strings.CutPrefix(r.text, tag+" ")
+		if !found {
+			responses = append(responses, r)
+			continue
+		}
+		if result, _, _ := strings.Cut(status, " "); !strings.EqualFold(result, "OK") {
+			name, _, _ := strings.Cut(command, " ")
+			return nil, fmt.Errorf("imap: %s failed: %s", name, status)
+		}
+		return responses, nil
+	}
+}
+
+// read reads a response line, along with the literal strings it holds
+func (c *conn) read() (response, error) {
+	var r response
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return r, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		r.text += line
+
+		m := literalPattern.FindStringSubmatch(line)
+		if m == nil {
+			return r, nil
+		}
+		size, err := strconv.Atoi(m[1])
+		if err != nil || size > maxLiteral {
+			return r, fmt.Errorf("imap: literal of %s bytes is too large", m[1])
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return r, err
+		}
+		r.literals = append(r.literals, literal)
+	}
+}
+
+// quote returns s as an IMAP quoted string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// isASCII reports whether s holds ASCII characters only
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
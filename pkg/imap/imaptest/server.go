@@ -0,0 +1,198 @@
+// Package imaptest provides a fake IMAP server for testing code that reads
+// mailboxes with package imap.
+package imaptest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is a message in the mailbox of the fake server
+type Message struct {
+	MessageID string
+	From      string
+	Subject   string
+	// Date is the Date header as it is sent
+	Date string
+}
+
+// Server is a fake IMAP server without TLS holding a single mailbox, INBOX.
+// It accepts the user and password it was created with and searches with
+// ALL or with FROM and SUBJECT criteria, matched as substrings.
+type Server struct {
+	// Addr is the host and port the server listens on
+	Addr string
+
+	listener net.Listener
+	user     string
+	password string
+
+	mu       sync.Mutex
+	messages []Message
+	commands []string
+}
+
+// NewServer starts a fake IMAP server
+func NewServer(user, password string) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("imaptest: failed to listen: %v", err))
+	}
+	s := &Server{Addr: listener.Addr().String(), listener: listener, user: user, password: password}
+	go s.accept()
+	return s
+}
+
+// URL returns an imap URL of the mailbox, e.g. for monitor.Config.URL
+func (s *Server) URL() string {
+	return "imap://" + s.user + "@" + s.Addr + "/INBOX"
+}
+
+// Deliver adds a message to the mailbox
+func (s *Server) Deliver(message Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+}
+
+// Commands returns the commands received so far, without their tags
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.listener.Close()
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake IMAP server ready\r\n")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+
+		name, args, _ := strings.Cut(command, " ")
+		switch strings.ToUpper(name) {
+		case "CAPABILITY":
+			fmt.Fprintf(conn, "* CAPABILITY IMAP4rev1\r\n%s OK done\r\n", tag)
+		case "LOGIN":
+			if args != quote(s.user)+" "+quote(s.password) {
+				fmt.Fprintf(conn, "%s NO [AUTHENTICATIONFAILED] invalid credentials\r\n", tag)
+				continue
+			}
+			fmt.Fprintf(conn, "%s OK logged in\r\n", tag)
+		case "EXAMINE":
+			if !strings.EqualFold(args, `"INBOX"`) {
+				fmt.Fprintf(conn, "%s NO no such mailbox\r\n", tag)
+				continue
+			}
+			s.mu.Lock()
+			fmt.Fprintf(conn, "* %d EXISTS\r\n* OK [UIDVALIDITY 7] UIDs valid\r\n%s OK [READ-ONLY] done\r\n", len(s.messages), tag)
+			s.mu.Unlock()
+		case "UID":
+			sub, rest, _ := strings.Cut(args, " ")
+			switch strings.ToUpper(sub) {
+			case "SEARCH":
+				fmt.Fprintf(conn, "* SEARCH%s\r\n%s OK done\r\n", s.search(rest), tag)
+			case "FETCH":
+				s.fetch(conn, rest)
+				fmt.Fprintf(conn, "%s OK done\r\n", tag)
+			default:
+				fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+			}
+		case "LOGOUT":
+			fmt.Fprintf(conn, "* BYE logging out\r\n%s OK done\r\n", tag)
+			return
+		default:
+			fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+		}
+	}
+}
+
+// search returns the UIDs of the messages matching the criteria, each
+// preceded by a space
+func (s *Server) search(criteria string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var uids string
+	for i, message := range s.messages {
+		if matches(message, criteria) {
+			uids += " " + strconv.Itoa(i+1)
+		}
+	}
+	return uids
+}
+
+// matches reports whether a message matches ALL or FROM and SUBJECT
+// criteria
+func matches(message Message, criteria string) bool {
+	fields := strings.Fields(criteria)
+	for i := 0; i < len(fields); i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "ALL":
+		case "FROM", "SUBJECT":
+			if i+1 == len(fields) {
+				return false
+			}
+			value := message.From
+			if strings.ToUpper(fields[i]) == "SUBJECT" {
+				value = message.Subject
+			}
+			i++
+			if !strings.Contains(strings.ToLower(value), strings.ToLower(strings.Trim(fields[i], `"`))) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// fetch sends the headers of the messages in a set of UIDs such as 1,3
+func (s *Server) fetch(conn net.Conn, args string) {
+	set, _, _ := strings.Cut(args, " ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, value := range strings.Split(set, ",") {
+		uid, err := strconv.Atoi(value)
+		if err != nil || uid < 1 || uid > len(s.messages) {
+			continue
+		}
+		m := s.messages[uid-1]
+		header := fmt.Sprintf("Message-ID: <%s>\r\nFrom: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n", m.MessageID, m.From, m.Subject, m.Date)
+		fmt.Fprintf(conn, "* %d FETCH (UID %d BODY[HEADER.FIELDS (MESSAGE-ID FROM SUBJECT DATE)] {%d}\r\n%s)\r\n", uid, uid, len(header), header)
+	}
+}
+
+// quote returns s as an IMAP quoted string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
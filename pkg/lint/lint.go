@@ -0,0 +1,172 @@
+// Package lint checks monitor configurations for setups that work but are
+// likely to cause trouble, such as hammering a third-party site every few
+// seconds or filtering away everything a monitor could detect.
+package lint
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// MinThirdPartyInterval is the shortest interval considered polite for sites
+// that aren't on the local machine or network
+const MinThirdPartyInterval = time.Second * 10
+
+// Rules
+const (
+	RuleShortInterval  = "short-interval"
+	RuleNoTimeout      = "no-timeout"
+	RuleErasingFilter  = "erasing-filter"
+	RuleDuplicateQuery = "duplicate-query"
+)
+
+// Warning is a problem found in the configuration of a monitor
+type Warning struct {
+	// Index is the position of the monitor's configuration in the checked list
+	Index int
+	URL   string
+	// Field is the setting the warning is about, e.g. "interval"
+	Field   string
+	Rule    string
+	Message string
+}
+
+// String formats the warning for display
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", w.URL, w.Message, w.Rule)
+}
+
+// sampleContent is a page used to detect filters that leave nothing to compare
+var sampleContent = []byte(`<!DOCTYPE html>
+<html>
+<head><title>Example Domain</title></head>
+<body>
+<h1>Latest news</h1>
+<p>Published 2024-01-10 10:30 by Jane Doe. 42 comments, 7 shares.</p>
+<ul><li>Price: $19.99</li><li>Status: in stock</li></ul>
+</body>
+</html>
+`)
+
+// erasingSelectors are selectors that match the whole page
+var erasingSelectors = map[string]bool{
+	"*": true, "html": true, "body": true, ":root": true,
+	"xpath:/": true, "xpath:/html": true, "xpath://html": true, "xpath:/html/body": true, "xpath://body": true,
+}
+
+// Check lints a list of monitor configurations and returns the warnings
+// ordered by the position of the configuration
+func Check(configs []*monitor.Config) []Warning {
+	var warnings []Warning
+	for i, config := range configs {
+		warnings = append(warnings, checkConfig(i, config)...)
+	}
+	warnings = append(warnings, checkDuplicates(configs)...)
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Index < warnings[j].Index
+	})
+	return warnings
+}
+
+// checkConfig lints a single configuration
+func checkConfig(index int, config *monitor.Config) []Warning {
+	var warnings []Warning
+	warn := func(field, rule, format string, args ...any) {
+		warnings = append(warnings, Warning{
+			Index:   index,
+			URL:     config.URL,
+			Field:   field,
+			Rule:    rule,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if config.Schedule == nil && config.Interval > 0 && config.Interval < MinThirdPartyInterval && !isLocal(config.URL) {
+		warn("interval", RuleShortInterval,
+			"checking a third-party site every %s may get you rate limited or blocked; use an interval of at least %s",
+			config.Interval, MinThirdPartyInterval)
+	}
+
+	if config.Timeout <= 0 {
+		warn("timeout", RuleNoTimeout,
+			"no timeout is set, so a server that never responds stalls the monitor; set a timeout such as 30s")
+	}
+
+	for _, selector := range config.IgnoreSelectors {
+		if erasingSelectors[strings.ToLower(strings.TrimSpace(selector))] {
+			warn("ignore", RuleErasingFilter,
+				"ignoring '%s' removes the whole page, so no change can ever be detected", selector)
+		}
+	}
+
+	if len(config.ContentFilters) > 0 && len(strings.TrimSpace(string(config.ContentFilters.Apply(sampleContent)))) == 0 {
+		warn("filters", RuleErasingFilter,
+			"the filters remove all content from a typical page, so no change can ever be detected; make them more specific")
+	}
+
+	return warnings
+}
+
+// checkDuplicates warns about monitors of the same page whose URLs differ
+// only by the query string, which usually means a tracking or cache-busting
+// parameter was copied along with the URL
+func checkDuplicates(configs []*monitor.Config) []Warning {
+	first := make(map[string]int)
+	var warnings []Warning
+
+	for i, config := range configs {
+		u, err := url.Parse(config.URL)
+		if err != nil {
+			continue
+		}
+
+		key := pageKey(u)
+		j, seen := first[key]
+		if !seen {
+			first[key] = i
+			continue
+		}
+		if configs[j].URL == config.URL {
+			continue
+		}
+
+		warnings = append(warnings, Warning{
+			Index:   i,
+			URL:     config.URL,
+			Field:   "url",
+			Rule:    RuleDuplicateQuery,
+			Message: fmt.Sprintf("differs from %s only by the query string; both monitors likely watch the same page", configs[j].URL),
+		})
+	}
+
+	return warnings
+}
+
+// pageKey identifies a URL without its query string and fragment
+func pageKey(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + u.EscapedPath()
+}
+
+// isLocal reports whether a URL points at the local machine or a private
+// network, where short intervals don't burden anyone else
+func isLocal(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := u.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
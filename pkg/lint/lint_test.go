@@ -0,0 +1,77 @@
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(warnings []Warning) []string {
+	var names []string
+	for _, w := range warnings {
+		names = append(names, w.Rule)
+	}
+	return names
+}
+
+func TestCheckInterval(t *testing.T) {
+	remote := monitor.DefaultConfig("https://example.com")
+	remote.Interval = time.Second * 5
+
+	local := monitor.DefaultConfig("http://localhost:8080/status")
+	local.Interval = time.Second
+
+	private := monitor.DefaultConfig("http://192.168.1.10/")
+	private.Interval = time.Second
+
+	warnings := Check([]*monitor.Config{remote, local, private})
+	require.Equal(t, []string{RuleShortInterval}, rules(warnings))
+	require.Equal(t, "interval", warnings[0].Field)
+	require.Contains(t, warnings[0].String(), "https://example.com: checking a third-party site every 5s")
+}
+
+func TestCheckTimeout(t *testing.T) {
+	config := monitor.DefaultConfig("https://example.com")
+	config.Timeout = 0
+
+	warnings := Check([]*monitor.Config{config})
+	require.Equal(t, []string{RuleNoTimeout}, rules(warnings))
+}
+
+func TestCheckErasingFilters(t *testing.T) {
+	everything, err := monitor.NewRegexFilter(`.*`, "", "")
+	require.NoError(t, err)
+	comments, err := monitor.NewRegexFilter(`\d+ comments`, "", "")
+	require.NoError(t, err)
+
+	erasing := monitor.DefaultConfig("https://example.com/a")
+	erasing.ContentFilters = monitor.ContentFilterList{everything}
+
+	specific := monitor.DefaultConfig("https://example.com/b")
+	specific.ContentFilters = monitor.ContentFilterList{comments}
+
+	selector := monitor.DefaultConfig("https://example.com/c")
+	selector.IgnoreSelectors = []string{".ads", "body"}
+
+	warnings := Check([]*monitor.Config{erasing, specific, selector})
+	require.Equal(t, []string{RuleErasingFilter, RuleErasingFilter}, rules(warnings))
+	require.Equal(t, 0, warnings[0].Index)
+	require.Equal(t, 2, warnings[1].Index)
+	require.Equal(t, "ignore", warnings[1].Field)
+}
+
+func TestCheckDuplicates(t *testing.T) {
+	warnings := Check([]*monitor.Config{
+		monitor.DefaultConfig("https://example.com/news?utm_source=mail"),
+		monitor.DefaultConfig("https://example.com/about"),
+		monitor.DefaultConfig("https://example.com/news"),
+		monitor.DefaultConfig("https://EXAMPLE.com/news?page=1"),
+	})
+
+	require.Equal(t, []string{RuleDuplicateQuery, RuleDuplicateQuery}, rules(warnings))
+	require.Equal(t, 2, warnings[0].Index)
+	require.Contains(t, warnings[0].Message, "https://example.com/news?utm_source=mail")
+	require.Equal(t, 3, warnings[1].Index)
+}
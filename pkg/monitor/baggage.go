@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// BaggageHeader is the W3C header that carries Config.Baggage on requests
+const BaggageHeader = "Baggage"
+
+// baggageKey is the context key of the values set with ContextWithBaggage
+type baggageKey struct{}
+
+// ContextWithBaggage returns a copy of ctx carrying baggage values, such as
+// a trace or tenant ID, in addition to those already in ctx. Monitors of
+// the top-level hawkeye package created with such a context send them with
+// every request, see Config.Baggage.
+func ContextWithBaggage(ctx context.Context, values map[string]string) context.Context {
+	merged := BaggageFromContext(ctx)
+	if merged == nil {
+		merged = make(map[string]string, len(values))
+	}
+	maps.Copy(merged, values)
+	return context.WithValue(ctx, baggageKey{}, merged)
+}
+
+// BaggageFromContext returns a copy of the baggage values of ctx, or nil if
+// it has none
+func BaggageFromContext(ctx context.Context) map[string]string {
+	values, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return maps.Clone(values)
+}
+
+// ValidateBaggage checks that baggage keys can be sent in a header: they
+// must not be empty or contain whitespace or the separators = , ;
+func ValidateBaggage(values map[string]string) error {
+	for key := range values {
+		if key == "" || strings.ContainsAny(key, " \t=,;\"") {
+			return fmt.Errorf("invalid baggage key '%s'", key)
+		}
+	}
+	return nil
+}
+
+// FormatBaggage formats values as the value of a W3C baggage header, e.g.
+// "tenant=acme,trace=abc123", with keys in alphabetical order and values
+// percent-encoded
+func FormatBaggage(values map[string]string) string {
+	members := make([]string, 0, len(values))
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		members = append(members, key+"="+url.PathEscape(values[key]))
+	}
+	return strings.Join(members, ",")
+}
+
+// addBaggage adds the monitor's baggage to a request, after any baggage
+// set with Config.Headers
+func (m *Monitor) addBaggage(req *http.Request) {
+	if len(m.config.Baggage) == 0 {
+		return
+	}
+
+	baggage := FormatBaggage(m.config.Baggage)
+	if existing := req.Header.Get(BaggageHeader); existing != "" {
+		baggage = existing + "," + baggage
+	}
+	req.Header.Set(BaggageHeader, baggage)
+}
@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatBaggage(t *testing.T) {
+	require.Equal(t, "", FormatBaggage(nil))
+	require.Equal(t, "tenant=acme,trace=a%20b%2Cc", FormatBaggage(map[string]string{"trace": "a b,c", "tenant": "acme"}))
+}
+
+func TestContextWithBaggage(t *testing.T) {
+	require.Nil(t, BaggageFromContext(context.Background()))
+
+	ctx := ContextWithBaggage(context.Background(), map[string]string{"tenant": "acme", "trace": "1"})
+	ctx = ContextWithBaggage(ctx, map[string]string{"trace": "2"})
+	require.Equal(t, map[string]string{"tenant": "acme", "trace": "2"}, BaggageFromContext(ctx))
+
+	// The returned values are a copy
+	BaggageFromContext(ctx)["tenant"] = "other"
+	require.Equal(t, "acme", BaggageFromContext(ctx)["tenant"])
+}
+
+func TestValidateBaggage(t *testing.T) {
+	require.NoError(t, ValidateBaggage(map[string]string{"tenant-id": "a=b; c"}))
+	for _, key := range []string{"", "a b", "a=b", "a,b", "a;b"} {
+		require.Error(t, ValidateBaggage(map[string]string{key: "x"}), key)
+	}
+
+	config := DefaultConfig("https://example.com")
+	config.Baggage = map[string]string{"bad key": "x"}
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorContains(t, err, "invalid baggage key 'bad key'")
+}
+
+func TestMonitorBaggage(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(BaggageHeader))
+		w.Write([]byte("content"))
+	}))
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig(server.URL)
+	config.Headers = map[string]string{"Baggage": "upstream=1"}
+	config.Baggage = map[string]string{"tenant": "acme"}
+	m := NewMonitorWithConfig(config)
+
+	// The monitor keeps its own copy
+	config.Baggage["tenant"] = "other"
+
+	change := m.Check()
+	require.Equal(t, map[string]string{"tenant": "acme"}, change.Baggage)
+	require.Equal(t, []string{"upstream=1,tenant=acme"}, received)
+}
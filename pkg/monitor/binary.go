@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// binaryKinds name common binary content types in change details
+var binaryKinds = map[string]string{
+	"application/pdf":    "PDF",
+	"application/zip":    "ZIP archive",
+	"application/gzip":   "Gzip archive",
+	"application/x-gzip": "Gzip archive",
+	"application/x-tar":  "Tar archive",
+	"image/png":          "PNG image",
+	"image/jpeg":         "JPEG image",
+	"image/gif":          "GIF image",
+	"image/webp":         "WebP image",
+	"image/avif":         "AVIF image",
+}
+
+// binaryTypes are binary content types without a prefix of binaryPrefixes
+var binaryTypes = map[string]bool{
+	"application/octet-stream":      true,
+	"application/x-7z-compressed":   true,
+	"application/x-bzip2":           true,
+	"application/x-rar-compressed":  true,
+	"application/vnd.rar":           true,
+	"application/wasm":              true,
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+}
+
+// binaryPrefixes start binary content types, except XML ones such as
+// image/svg+xml, and name them
+var binaryPrefixes = []struct{ prefix, kind string }{
+	{"image/", "Image"},
+	{"audio/", "Audio file"},
+	{"video/", "Video"},
+	{"font/", "Font"},
+	{"application/vnd.openxmlformats-officedocument.", "Office document"},
+}
+
+// binaryKind returns a name for content that isn't text, such as "PDF", or
+// "" for text. The content type is trusted unless it is missing or
+// application/octet-stream, in which case the content is sniffed.
+func binaryKind(contentType string, content []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	mediaType = strings.ToLower(mediaType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(content))
+	}
+
+	if kind, ok := binaryKinds[mediaType]; ok {
+		return kind
+	}
+	if strings.HasSuffix(mediaType, "+xml") {
+		return ""
+	}
+	for _, binary := range binaryPrefixes {
+		if strings.HasPrefix(mediaType, binary.prefix) {
+			return binary.kind
+		}
+	}
+	if binaryTypes[mediaType] {
+		return "Binary content"
+	}
+	return ""
+}
+
+// detectBinaryChange compares binary content, or content that was binary on
+// the previous check, by the hash, or with MethodLength the size, of the
+// complete body. A line diff of binary content would be unreadable, so the
+// details give the sizes and hashes instead.
+func (m *Monitor) detectBinaryChange(content []byte, kind string, digest bodyDigest) (bool, string) {
+	if digest.hash == nil {
+		hash := sha256.Sum256(content)
+		digest = bodyDigest{hash: hash[:], size: int64(len(content))}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last, lastKind := m.lastDigest, m.lastKind
+	m.lastDigest, m.lastKind, m.lastContent = digest, kind, content
+	if last.hash == nil {
+		return false, ""
+	}
+
+	var changed bool
+	if m.config.Method == MethodLength {
+		changed = last.size != digest.size
+	} else {
+		changed = !bytes.Equal(last.hash, digest.hash)
+	}
+	if !changed {
+		return false, ""
+	}
+
+	var details string
+	switch {
+	case lastKind == kind:
+		details = kind + " changed"
+	case lastKind == "":
+		details = "Text changed to " + kind
+	case kind == "":
+		details = lastKind + " changed to text"
+	default:
+		details = lastKind + " changed to " + kind
+	}
+	details += ": size " + formatSizes(last.size, digest.size)
+	if m.config.Method != MethodLength {
+		details += fmt.Sprintf(", sha256 %s… → %s…", hex.EncodeToString(last.hash[:6]), hex.EncodeToString(digest.hash[:6]))
+	}
+	return true, details
+}
+
+// formatSizes formats a size change such as "1.2 MB → 1.3 MB", in bytes when
+// the rounded sizes are the same
+func formatSizes(from, to int64) string {
+	if from == to {
+		return formatSize(to)
+	}
+	if formatSize(from) == formatSize(to) {
+		return fmt.Sprintf("%d → %d bytes", from, to)
+	}
+	return formatSize(from) + " → " + formatSize(to)
+}
+
+// formatSize formats a size in bytes with a decimal unit, e.g. "1.2 MB"
+func formatSize(size int64) string {
+	if size < 1000 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	for _, unit := range []string{"KB", "MB", "GB"} {
+		value /= 1000
+		if value < 999.95 || unit == "GB" {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryKind(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	for _, test := range []struct {
+		contentType string
+		content     []byte
+		kind        string
+	}{
+		{"application/pdf", []byte("%PDF-1.7"), "PDF"},
+		{"image/png", png, "PNG image"},
+		{"image/x-icon", nil, "Image"},
+		{"image/svg+xml", []byte("<svg/>"), ""},
+		{"video/mp4", nil, "Video"},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", nil, "Office document"},
+		{"Application/ZIP; name=export.zip", nil, "ZIP archive"},
+		// Missing and generic content types are sniffed
+		{"", png, "PNG image"},
+		{"application/octet-stream", []byte("%PDF-1.4\n"), "PDF"},
+		{"application/octet-stream", []byte{0x00, 0x01, 0x02, 0xff}, "Binary content"},
+		{"application/octet-stream", []byte("plain text"), ""},
+		{"text/html", []byte("<html>"), ""},
+		{"application/json", []byte(`{"a": 1}`), ""},
+	} {
+		require.Equal(t, test.kind, binaryKind(test.contentType, test.content), test.contentType)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	require.Equal(t, "512 B", formatSize(512))
+	require.Equal(t, "1.2 KB", formatSize(1234))
+	require.Equal(t, "1.2 MB", formatSize(1_200_000))
+	require.Equal(t, "1.0 MB", formatSize(999_999))
+	require.Equal(t, "2048.0 GB", formatSize(2_048_000_000_000))
+
+	require.Equal(t, "1.2 MB → 1.3 MB", formatSizes(1_200_000, 1_300_000))
+	require.Equal(t, "1200000 → 1200001 bytes", formatSizes(1_200_000, 1_200_001))
+	require.Equal(t, "1.2 MB", formatSizes(1_200_000, 1_200_000))
+}
+
+func TestBinaryChange(t *testing.T) {
+	pages := []struct{ contentType, body string }{
+		{"application/pdf", "%PDF-1.7\n" + strings.Repeat("a", 1_200_000)},
+		{"application/pdf", "%PDF-1.7\n" + strings.Repeat("a", 1_200_000)},
+		{"application/pdf", "%PDF-1.7\n" + strings.Repeat("b", 1_300_000)},
+		{"text/html", "<html>Not found</html>"},
+		{"text/html", "<html>Moved</html>"},
+	}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := pages[calls.Add(1)-1]
+		w.Header().Set("Content-Type", page.contentType)
+		w.Write([]byte(page.body))
+	}))
+	defer server.Close()
+
+	m := NewMonitorWithConfig(DefaultConfig(server.URL))
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged)
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Regexp(t, `^PDF changed: size 1\.2 MB → 1\.3 MB, sha256 [0-9a-f]{12}… → [0-9a-f]{12}…$`, change.Details)
+	require.Empty(t, change.Diff)
+	require.Equal(t, int64(1_200_009), change.OldLength)
+	require.Equal(t, int64(1_300_009), change.NewLength)
+	newHash := sha256.Sum256([]byte(pages[2].body))
+	require.Equal(t, hex.EncodeToString(newHash[:]), change.NewHash)
+	require.Contains(t, change.Details, "→ "+change.NewHash[:12])
+
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Regexp(t, `^PDF changed to text: size 1\.3 MB → 22 B, sha256 `, change.Details)
+
+	// Text is diffed again
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Contains(t, change.Diff, "+<html>Moved</html>")
+}
+
+func TestBinaryLength(t *testing.T) {
+	bodies := []string{"\x00\x01\x02", "\x00\x01\x03", "\x00\x01\x03\x04"}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte(bodies[calls.Add(1)-1]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodLength
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "the size is the same")
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Binary content changed: size 3 B → 4 B", change.Details)
+}
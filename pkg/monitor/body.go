@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxBodySize is the largest response body read by default, 64 MiB
+const DefaultMaxBodySize = 64 << 20
+
+// ErrBodyTooLarge is returned for responses larger than Config.MaxBodySize
+var ErrBodyTooLarge = errors.New("response body is too large")
+
+// bodyDigest describes a complete response body, including the part beyond
+// Config.MaxSnapshotSize that isn't kept
+type bodyDigest struct {
+	hash      []byte
+	size      int64
+	truncated bool
+}
+
+// snapshotWriter keeps the first limit bytes written to it and discards the
+// rest. A limit of zero keeps everything.
+type snapshotWriter struct {
+	buf   bytes.Buffer
+	limit int64
+}
+
+// Write implements io.Writer
+func (w *snapshotWriter) Write(p []byte) (int, error) {
+	kept := p
+	if w.limit > 0 {
+		room := max(w.limit-int64(w.buf.Len()), 0)
+		kept = p[:min(int64(len(p)), room)]
+	}
+	w.buf.Write(kept)
+	return len(p), nil
+}
+
+// readBody streams a response body through its SHA-256 hash, keeping the
+// first keep bytes of it, or all of it if keep is zero. Bodies larger than
+// maxSize, unless it is zero, fail with ErrBodyTooLarge without being read
+// any further.
+func readBody(r io.Reader, maxSize, keep int64) ([]byte, bodyDigest, error) {
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+
+	hash := sha256.New()
+	snapshot := &snapshotWriter{limit: keep}
+	size, err := io.Copy(io.MultiWriter(hash, snapshot), r)
+	if err != nil {
+		return nil, bodyDigest{}, err
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, bodyDigest{}, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, maxSize)
+	}
+
+	digest := bodyDigest{hash: hash.Sum(nil), size: size, truncated: int64(snapshot.buf.Len()) < size}
+	return snapshot.buf.Bytes(), digest, nil
+}
+
+// detectTruncatedChange compares a body cut at Config.MaxSnapshotSize with
+// the previous check by the hash, or with MethodLength the size, of the
+// complete body. The diff only covers the part that was kept.
+func (m *Monitor) detectTruncatedChange(content []byte, digest bodyDigest) (bool, string, []DiffHunk) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := m.lastDigest
+	m.lastDigest = digest
+	if m.lastContent == nil {
+		m.lastContent = content
+		return false, "", nil
+	}
+
+	var changed bool
+	var details string
+	if m.config.Method == MethodLength {
+		changed = last.size != digest.size
+		details = fmt.Sprintf("Length changed from %d to %d bytes", last.size, digest.size)
+	} else {
+		changed = !bytes.Equal(last.hash, digest.hash)
+		details = "Content changed"
+	}
+	if !changed {
+		return false, "", nil
+	}
+
+	compareLast, compareContent := m.prepare(m.lastContent), m.prepare(content)
+	m.lastContent = content
+	hunks := LineDiff(compareLast, compareContent, m.config.DiffContextLines)
+	if len(hunks) > 0 {
+		details += fmt.Sprintf(" (diff of the first %d bytes)\n", m.config.MaxSnapshotSize) + FormatHunks(hunks)
+	} else {
+		details += fmt.Sprintf(" after the first %d bytes", m.config.MaxSnapshotSize)
+	}
+	return true, truncateDetails(details, m.config.MaxDetailsLines, m.config.MaxDetailsBytes), hunks
+}
@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBody(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	hash := sha256.Sum256([]byte(body))
+
+	content, digest, err := readBody(strings.NewReader(body), 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, body, string(content))
+	require.Equal(t, hash[:], digest.hash)
+	require.False(t, digest.truncated)
+
+	content, digest, err = readBody(strings.NewReader(body), 100, 10)
+	require.NoError(t, err)
+	require.Equal(t, body[:10], string(content))
+	require.Equal(t, hash[:], digest.hash, "the complete body is hashed")
+	require.Equal(t, int64(100), digest.size)
+	require.True(t, digest.truncated)
+
+	_, _, err = readBody(strings.NewReader(body), 99, 0)
+	require.ErrorIs(t, err, ErrBodyTooLarge)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.MaxBodySize = 1024
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	change := m.Check()
+	require.Equal(t, EventError, change.Event)
+	require.Contains(t, change.Error, "response body is too large")
+}
+
+func TestMaxSnapshotSize(t *testing.T) {
+	pages := []string{
+		"header\n" + strings.Repeat("x", 100) + "1",
+		"header\n" + strings.Repeat("x", 100) + "1",
+		"header\n" + strings.Repeat("x", 100) + "2",
+		"HEADER\n" + strings.Repeat("x", 100) + "2",
+	}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[calls.Add(1)-1]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.MaxSnapshotSize = 20
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged)
+
+	// The change is past the kept bytes
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Content changed after the first 20 bytes", change.Details)
+	require.Empty(t, change.Hunks)
+
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.True(t, strings.HasPrefix(change.Details, "Content changed (diff of the first 20 bytes)\n"), change.Details)
+	require.NotEmpty(t, change.Hunks)
+}
+
+func TestCompressedResponses(t *testing.T) {
+	var calls atomic.Int64
+	var accepted atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted.Store(r.Header.Get("Accept-Encoding"))
+		// The server alternates encodings of the same content
+		var body io.WriteCloser
+		switch calls.Add(1) % 4 {
+		case 0:
+			w.Header().Set("Content-Encoding", "gzip")
+			body = gzip.NewWriter(w)
+		case 1:
+			w.Header().Set("Content-Encoding", "deflate")
+			body = zlib.NewWriter(w)
+		case 2:
+			w.Header().Set("Content-Encoding", "br")
+			body = brotli.NewWriter(w)
+		default:
+			w.Write([]byte("same content"))
+			return
+		}
+		body.Write([]byte("same content"))
+		body.Close()
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	for range 4 {
+		change := m.Check()
+		require.Empty(t, change.Error)
+		require.False(t, change.HasChanged)
+	}
+	require.Equal(t, "gzip, deflate, br", accepted.Load())
+	require.Equal(t, "same content", string(m.lastContent))
+
+	config.AcceptEncoding = "identity"
+	m = NewMonitorWithConfig(config)
+	defer m.Stop()
+	m.Check()
+	require.Equal(t, "identity", accepted.Load())
+}
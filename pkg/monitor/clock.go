@@ -0,0 +1,55 @@
+package monitor
+
+import "time"
+
+// Clock abstracts the passage of time so monitors can be driven
+// deterministically in tests
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a ticker that fires every d
+	NewTicker(d time.Duration) Ticker
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks at intervals
+type Ticker interface {
+	// C returns the channel on which ticks are delivered
+	C() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+}
+
+// RealClock is a Clock backed by the time package
+type RealClock struct{}
+
+// Now implements Clock.Now
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker implements Clock.NewTicker
+func (RealClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+// After implements Clock.After
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// realTicker wraps a time.Ticker
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+// C implements Ticker.C
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop implements Ticker.Stop
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
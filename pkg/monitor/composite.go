@@ -0,0 +1,586 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// CompositeScheme is the scheme of the URLs of the changes raised by
+// composites, e.g. composite://shop-outage
+const CompositeScheme = "composite"
+
+// DefaultCompositeWindow is how far back the rule of a composite counts
+// events unless Composite.Window says otherwise
+const DefaultCompositeWindow = time.Minute * 10
+
+// ErrCompositeWindow is returned for a composite with a negative window
+var ErrCompositeWindow = errors.New("composite window must not be negative")
+
+// Composite raises a change with EventComposite when the recent events of
+// other monitors meet its Rule, e.g. when a page changed while its mirror
+// didn't, or when 3 monitors of a group changed. The rule counts the events
+// of the last Window, DefaultCompositeWindow if zero, and the change is
+// raised once a Window passed if the rule is still met, see
+// Composites.Observe. Names are made of
+// letters, digits, dots, dashes and underscores, like those of webhook
+// monitors.
+type Composite struct {
+	Name   string
+	Rule   CompositeRule
+	Window time.Duration
+}
+
+// CompositeURL returns the URL of the changes of the composite with the
+// given name
+func CompositeURL(name string) string {
+	return CompositeScheme + "://" + name
+}
+
+// window returns the window of the composite
+func (c Composite) window() time.Duration {
+	if c.Window == 0 {
+		return DefaultCompositeWindow
+	}
+	return c.Window
+}
+
+// CompositeRule is a boolean expression over the recent events of monitors,
+// see ParseCompositeRule
+type CompositeRule interface {
+	// eval evaluates the rule with count, which returns the number of
+	// monitors of a target that had an event in the window
+	eval(count func(event EventType, target string) int) bool
+	// terms appends the counted terms of the rule to terms
+	terms(terms []ruleTerm) []ruleTerm
+	// String returns the rule as ParseCompositeRule parses it
+	String() string
+}
+
+// ruleFunctions are the names of the terms of rules that aren't event
+// types, with the event they count
+var ruleFunctions = map[string]EventType{
+	"changed":   EventChange,
+	"unchanged": EventChange,
+	"failed":    EventError,
+	"recovered": EventRecovery,
+}
+
+// ruleTerm counts the monitors of target that had event in the window of a
+// composite and compares the count with n. Without a comparison it is met
+// if any monitor had the event, or none for unchanged.
+type ruleTerm struct {
+	function string
+	event    EventType
+	target   string
+	op       string
+	n        int
+}
+
+func (t ruleTerm) eval(count func(event EventType, target string) int) bool {
+	c := count(t.event, t.target)
+	switch t.op {
+	case "":
+		if t.function == "unchanged" {
+			return c == 0
+		}
+		return c > 0
+	case ">=":
+		return c >= t.n
+	case ">":
+		return c > t.n
+	case "<=":
+		return c <= t.n
+	case "<":
+		return c < t.n
+	case "!=":
+		return c != t.n
+	default:
+		return c == t.n
+	}
+}
+
+func (t ruleTerm) terms(terms []ruleTerm) []ruleTerm {
+	return append(terms, t)
+}
+
+func (t ruleTerm) String() string {
+	s := fmt.Sprintf("%s(%s)", t.function, t.target)
+	if t.op != "" {
+		s += fmt.Sprintf(" %s %d", t.op, t.n)
+	}
+	return s
+}
+
+// ruleNot is met when its rule isn't
+type ruleNot struct {
+	rule CompositeRule
+}
+
+func (r ruleNot) eval(count func(event EventType, target string) int) bool {
+	return !r.rule.eval(count)
+}
+
+func (r ruleNot) terms(terms []ruleTerm) []ruleTerm {
+	return r.rule.terms(terms)
+}
+
+func (r ruleNot) String() string {
+	if _, ok := r.rule.(ruleTerm); ok {
+		return "not " + r.rule.String()
+	}
+	return "not (" + r.rule.String() + ")"
+}
+
+// ruleList is met when all of its rules are met, or any one of them if
+// or is set
+type ruleList struct {
+	rules []CompositeRule
+	or    bool
+}
+
+func (r ruleList) eval(count func(event EventType, target string) int) bool {
+	for _, rule := range r.rules {
+		if rule.eval(count) == r.or {
+			return r.or
+		}
+	}
+	return !r.or
+}
+
+func (r ruleList) terms(terms []ruleTerm) []ruleTerm {
+	for _, rule := range r.rules {
+		terms = rule.terms(terms)
+	}
+	return terms
+}
+
+func (r ruleList) String() string {
+	parts := make([]string, len(r.rules))
+	for i, rule := range r.rules {
+		parts[i] = rule.String()
+		if list, ok := rule.(ruleList); ok && list.or && !r.or {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	if r.or {
+		return strings.Join(parts, " or ")
+	}
+	return strings.Join(parts, " and ")
+}
+
+// ParseCompositeRule parses the rule of a composite, such as
+// "changed(https://example.com) and unchanged(https://mirror.example.com)"
+// or "changed(group:shop) >= 3". A term counts the monitors that had an
+// event in the window: changed, unchanged, failed, recovered or the name
+// of an event type, followed by a monitor URL or group:<name> in
+// parentheses. The count is compared with a number by >=, >, <=, <, = or
+// !=; a term without a comparison is met if the count is at least 1, or 0
+// for unchanged. Terms are combined with not, and and or, in this order of
+// precedence, and parentheses.
+func ParseCompositeRule(spec string) (CompositeRule, error) {
+	p := &ruleParser{spec: spec}
+	rule, err := p.or()
+	if err == nil && p.skipSpace() < len(spec) {
+		err = p.errorf("unexpected '%s'", spec[p.pos:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ruleParser parses composite rules by recursive descent
+type ruleParser struct {
+	spec string
+	pos  int
+}
+
+// errorf returns an error about the rule at the current position
+func (p *ruleParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid rule '%s': %s at position %d", p.spec, fmt.Sprintf(format, args...), p.pos+1)
+}
+
+// skipSpace skips whitespace and returns the new position
+func (p *ruleParser) skipSpace() int {
+	for p.pos < len(p.spec) && unicode.IsSpace(rune(p.spec[p.pos])) {
+		p.pos++
+	}
+	return p.pos
+}
+
+// word returns the word at the current position without consuming it
+func (p *ruleParser) word() string {
+	end := p.skipSpace()
+	for end < len(p.spec) && (p.spec[end] == '_' || unicode.IsLetter(rune(p.spec[end])) || unicode.IsDigit(rune(p.spec[end]))) {
+		end++
+	}
+	return p.spec[p.pos:end]
+}
+
+// keyword consumes the keyword if it is at the current position
+func (p *ruleParser) keyword(keyword string) bool {
+	if word := p.word(); strings.EqualFold(word, keyword) {
+		p.pos += len(word)
+		return true
+	}
+	return false
+}
+
+// symbol consumes symbol if it is at the current position
+func (p *ruleParser) symbol(symbol string) bool {
+	if strings.HasPrefix(p.spec[p.skipSpace():], symbol) {
+		p.pos += len(symbol)
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) or() (CompositeRule, error) {
+	return p.list(true, p.and, "or")
+}
+
+func (p *ruleParser) and() (CompositeRule, error) {
+	return p.list(false, p.not, "and")
+}
+
+// list parses operands separated by the keyword
+func (p *ruleParser) list(or bool, operand func() (CompositeRule, error), keyword string) (CompositeRule, error) {
+	rule, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	rules := []CompositeRule{rule}
+	for p.keyword(keyword) {
+		if rule, err = operand(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 1 {
+		return rules[0], nil
+	}
+	return ruleList{rules: rules, or: or}, nil
+}
+
+func (p *ruleParser) not() (CompositeRule, error) {
+	if p.keyword("not") {
+		rule, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return ruleNot{rule: rule}, nil
+	}
+	if p.symbol("(") {
+		rule, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, p.errorf("missing ')'")
+		}
+		return rule, nil
+	}
+	return p.term()
+}
+
+// term parses a term such as changed(group:shop) >= 3
+func (p *ruleParser) term() (CompositeRule, error) {
+	word := p.word()
+	if word == "" {
+		if p.pos == len(p.spec) {
+			return nil, p.errorf("missing term")
+		}
+		return nil, p.errorf("unexpected '%s'", p.spec[p.pos:])
+	}
+	function := strings.ToLower(word)
+	event, ok := ruleFunctions[function]
+	if !ok {
+		var err error
+		if event, err = ParseEventType(function); err != nil {
+			return nil, p.errorf("unknown term '%s'", word)
+		}
+	}
+	p.pos += len(word)
+
+	if !p.symbol("(") {
+		return nil, p.errorf("missing '(' after '%s'", word)
+	}
+	end := strings.IndexByte(p.spec[p.pos:], ')')
+	if end < 0 {
+		return nil, p.errorf("missing ')'")
+	}
+	target := strings.TrimSpace(p.spec[p.pos : p.pos+end])
+	if target == "" || target == "group:" {
+		return nil, p.errorf("missing monitor URL or group")
+	}
+	p.pos += end + 1
+
+	term := ruleTerm{function: function, event: event, target: target}
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if p.symbol(op) {
+			term.op = op
+			break
+		}
+	}
+	if term.op != "" {
+		number := p.word()
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 0 {
+			return nil, p.errorf("expected a count after '%s'", term.op)
+		}
+		p.pos += len(number)
+		term.n = n
+	}
+	return term, nil
+}
+
+// compositeEvent is an event of a monitor counted by composites
+type compositeEvent struct {
+	url   string
+	event EventType
+	at    time.Time
+}
+
+// compositeHold is a composite whose rule became met at since, held until
+// its window has passed
+type compositeHold struct {
+	composite Composite
+	since     time.Time
+}
+
+// due returns when the hold ends
+func (h compositeHold) due() time.Time {
+	return h.since.Add(h.composite.window())
+}
+
+// Composites raises the changes of composites from those of the monitors of
+// a manager, see Watch
+type Composites struct {
+	manager    *Manager
+	composites []Composite
+	// window is the longest window of the composites
+	window time.Duration
+	clock  Clock
+
+	mu     sync.Mutex
+	events []compositeEvent
+	met    map[string]bool
+	held   map[string]compositeHold
+}
+
+// NewComposites returns the composites evaluated over the changes of the
+// monitors of manager, which tells the groups of the monitors
+func NewComposites(manager *Manager, composites ...Composite) (*Composites, error) {
+	c := &Composites{
+		manager:    manager,
+		composites: composites,
+		clock:      RealClock{},
+		met:        make(map[string]bool),
+		held:       make(map[string]compositeHold),
+	}
+	names := make(map[string]bool)
+	for _, composite := range composites {
+		switch {
+		case !webhookName.MatchString(composite.Name):
+			return nil, fmt.Errorf("invalid composite name '%s': must be made of letters, digits, dots, dashes and underscores", composite.Name)
+		case names[composite.Name]:
+			return nil, fmt.Errorf("duplicate composite '%s'", composite.Name)
+		case composite.Rule == nil:
+			return nil, fmt.Errorf("composite '%s' has no rule", composite.Name)
+		case composite.Window < 0:
+			return nil, ErrCompositeWindow
+		}
+		names[composite.Name] = true
+		c.window = max(c.window, composite.window())
+	}
+	return c, nil
+}
+
+// Watch passes on the changes of a channel, such as the one returned by
+// Manager.Start, each followed by the changes of the composites it raises,
+// see Observe. Held composites are released by a timer once their window
+// has passed, see Release. The returned channel is closed once changes is,
+// and composites still held then aren't raised.
+func (c *Composites) Watch(changes <-chan Change) <-chan Change {
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		var due <-chan time.Time
+		var dueAt time.Time
+		for {
+			if next, ok := c.nextDue(); ok && (due == nil || !next.Equal(dueAt)) {
+				dueAt = next
+				due = c.clock.After(next.Sub(c.clock.Now()))
+			}
+
+			var raised []Change
+			select {
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				out <- change
+				raised = c.Observe(change)
+			case now := <-due:
+				due = nil
+				raised = c.Release(now)
+			}
+			for _, change := range raised {
+				out <- change
+			}
+		}
+	}()
+	return out
+}
+
+// nextDue returns when the first held composite is due for release
+func (c *Composites) nextDue() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var next time.Time
+	for _, hold := range c.held {
+		if next.IsZero() || hold.due().Before(next) {
+			next = hold.due()
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Observe counts a change of a monitor. A composite whose rule it makes met
+// is held until its window has passed rather than raised right away, so
+// that later events can still unmeet the rule, e.g. a change of the mirror
+// of a page shortly after that of the page. Observe returns the changes of
+// the held composites due by the time of the change, see Release. A
+// composite is raised when its rule becomes met and still is at the end of
+// the hold, and only again once an event made it unmet.
+func (c *Composites) Observe(change Change) []Change {
+	if strings.HasPrefix(change.URL, CompositeScheme+"://") {
+		return nil
+	}
+	at := change.Timestamp
+	if at.IsZero() {
+		at = c.clock.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	raised := c.release(at)
+	c.events = append(c.events, compositeEvent{url: change.URL, event: change.Event, at: at})
+	// Held composites count the events of the window before their rule
+	// became met, which is at most a window before now
+	kept := 0
+	for kept < len(c.events) && c.events[kept].at.Before(at.Add(-2*c.window)) {
+		kept++
+	}
+	c.events = c.events[kept:]
+
+	matches := c.matcher()
+	for _, composite := range c.composites {
+		if _, held := c.held[composite.Name]; held {
+			continue
+		}
+		met := composite.Rule.eval(c.counter(at.Add(-composite.window()), at, matches))
+		if met && !c.met[composite.Name] {
+			c.held[composite.Name] = compositeHold{composite: composite, since: at}
+		}
+		c.met[composite.Name] = met
+	}
+	return raised
+}
+
+// Release ends the holds of the composites due by now and returns the
+// changes of those whose rule is still met, counting the events of the
+// window before the rule became met and of the hold
+func (c *Composites) Release(now time.Time) []Change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.release(now)
+}
+
+// release implements Release. The caller must hold c.mu.
+func (c *Composites) release(now time.Time) []Change {
+	matches := c.matcher()
+	var raised []Change
+	for _, composite := range c.composites {
+		hold, held := c.held[composite.Name]
+		if !held || hold.due().After(now) {
+			continue
+		}
+		delete(c.held, composite.Name)
+
+		since := hold.since.Add(-composite.window())
+		met := composite.Rule.eval(c.counter(since, hold.due(), matches))
+		if met {
+			raised = append(raised, c.raise(composite, since, hold.due(), matches))
+		}
+		c.met[composite.Name] = met
+	}
+	return raised
+}
+
+// matcher returns a function reporting whether an event is of a monitor
+// given by URL or as group:<name>. Groups are looked up once per call of
+// matcher.
+func (c *Composites) matcher() func(e compositeEvent, target string) bool {
+	groups := make(map[string][]string)
+	return func(e compositeEvent, target string) bool {
+		name, ok := strings.CutPrefix(target, "group:")
+		if !ok {
+			return e.url == target
+		}
+		if _, looked := groups[e.url]; !looked && c.manager != nil {
+			groups[e.url], _ = c.manager.GroupsOf(e.url)
+		}
+		return slices.Contains(groups[e.url], name)
+	}
+}
+
+// counter returns the count of the terms of rules: the number of monitors
+// of a target that had an event from since until until. The caller must
+// hold c.mu.
+func (c *Composites) counter(since, until time.Time, matches func(compositeEvent, string) bool) func(event EventType, target string) int {
+	return func(event EventType, target string) int {
+		urls := make(map[string]bool)
+		for _, e := range c.events {
+			if e.event == event && !e.at.Before(since) && !e.at.After(until) && matches(e, target) {
+				urls[e.url] = true
+			}
+		}
+		return len(urls)
+	}
+}
+
+// raise returns the change of a composite whose rule was still met at the
+// end of its hold, which lists the events its terms counted from since
+// until then
+func (c *Composites) raise(composite Composite, since, at time.Time, matches func(compositeEvent, string) bool) Change {
+	lines := []string{composite.Rule.String()}
+	terms := composite.Rule.terms(nil)
+	for _, e := range c.events {
+		if e.at.Before(since) || e.at.After(at) {
+			continue
+		}
+		for _, term := range terms {
+			if e.event == term.event && matches(e, term.target) {
+				lines = append(lines, fmt.Sprintf("- %s %s %s", e.at.Format(time.TimeOnly), e.event, e.url))
+				break
+			}
+		}
+	}
+
+	return Change{
+		URL:        CompositeURL(composite.Name),
+		Event:      EventComposite,
+		Timestamp:  at,
+		HasChanged: true,
+		Details:    truncateDetails(strings.Join(lines, "\n"), DefaultMaxDetailsLines, DefaultMaxDetailsBytes),
+	}
+}
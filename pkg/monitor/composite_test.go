@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCompositeRule(t *testing.T) {
+	for spec, want := range map[string]string{
+		"changed(https://a.example.com)":                                         "changed(https://a.example.com)",
+		"CHANGED( group:shop )>=3":                                               "changed(group:shop) >= 3",
+		"changed(https://a) and not changed(https://b) or failed(group:api) > 1": "changed(https://a) and not changed(https://b) or failed(group:api) > 1",
+		"changed(https://a) and (unchanged(https://b) or error(https://c) = 2)":  "changed(https://a) and (unchanged(https://b) or error(https://c) = 2)",
+		"not (recovered(https://a) or condition_met(https://b))":                 "not (recovered(https://a) or condition_met(https://b))",
+	} {
+		rule, err := ParseCompositeRule(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, want, rule.String())
+	}
+
+	for spec, message := range map[string]string{
+		"":                           "missing term at position 1",
+		"changed":                    "missing '(' after 'changed'",
+		"changed(https://a":          "missing ')'",
+		"changed()":                  "missing monitor URL or group",
+		"flapped(https://a)":         "unknown term 'flapped'",
+		"changed(https://a) >= many": "expected a count after '>='",
+		"changed(https://a) and":     "missing term",
+		"(changed(https://a)":        "missing ')'",
+		"changed(https://a) then":    "unexpected 'then'",
+	} {
+		_, err := ParseCompositeRule(spec)
+		require.ErrorContains(t, err, message, spec)
+	}
+}
+
+func TestComposites(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+	for _, url := range []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"} {
+		_, err := manager.AddMonitorWithConfig(DefaultConfig(url))
+		require.NoError(t, err)
+	}
+	_, err := manager.CreateGroup("shop", "")
+	require.NoError(t, err)
+	for _, url := range []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"} {
+		require.NoError(t, manager.AddToGroup(url, "shop"))
+	}
+
+	mirror, err := ParseCompositeRule("changed(https://a.example.com) and unchanged(https://b.example.com)")
+	require.NoError(t, err)
+	outage, err := ParseCompositeRule("changed(group:shop) >= 3")
+	require.NoError(t, err)
+	composites, err := NewComposites(manager,
+		Composite{Name: "mirror", Rule: mirror},
+		Composite{Name: "outage", Rule: outage, Window: time.Hour})
+	require.NoError(t, err)
+
+	start := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	observe := func(url string, event EventType, after time.Duration) []Change {
+		return composites.Observe(Change{URL: url, Event: event, Timestamp: start.Add(after)})
+	}
+
+	// B changed recently, so A changing doesn't hold mirror
+	require.Empty(t, observe("https://b.example.com", EventChange, 0))
+	require.Empty(t, observe("https://a.example.com", EventChange, time.Minute*5))
+
+	// Once the change of B left the window mirror is held, and B changing
+	// before the window passed keeps it quiet
+	require.Empty(t, observe("https://a.example.com", EventChange, time.Minute*15))
+	require.Empty(t, composites.Release(start.Add(time.Minute*24)))
+	require.Empty(t, observe("https://b.example.com", EventChange, time.Minute*20))
+	require.Empty(t, composites.Release(start.Add(time.Minute*25)))
+
+	// Without a change of B it is raised once the window passed, and only
+	// once
+	require.Empty(t, observe("https://a.example.com", EventChange, time.Minute*40))
+	require.Empty(t, observe("https://a.example.com", EventError, time.Minute*45))
+	raised := observe("https://a.example.com", EventChange, time.Minute*50)
+	require.Len(t, raised, 1)
+	require.Equal(t, "composite://mirror", raised[0].URL)
+	require.Equal(t, EventComposite, raised[0].Event)
+	require.Equal(t, start.Add(time.Minute*50), raised[0].Timestamp)
+	require.True(t, raised[0].HasChanged)
+	require.Equal(t, "changed(https://a.example.com) and unchanged(https://b.example.com)\n"+
+		"- 10:40:00 change https://a.example.com", raised[0].Details)
+	require.Empty(t, observe("https://a.example.com", EventChange, time.Minute*55))
+	require.Empty(t, composites.Release(start.Add(time.Hour*2)))
+
+	// Errors don't count as changes, and every monitor counts once
+	require.Empty(t, observe("https://c.example.com", EventError, time.Hour*3))
+	require.Empty(t, observe("https://a.example.com", EventChange, time.Hour*3+time.Minute))
+	require.Empty(t, observe("https://a.example.com", EventChange, time.Hour*3+time.Minute*2))
+	require.Empty(t, observe("https://b.example.com", EventChange, time.Hour*3+time.Minute*3))
+	require.Empty(t, observe("https://c.example.com", EventChange, time.Hour*3+time.Minute*4))
+	require.Empty(t, composites.Release(start.Add(time.Hour*4)))
+	raised = composites.Release(start.Add(time.Hour*4 + time.Minute*4))
+	require.Len(t, raised, 1)
+	require.Equal(t, "composite://outage", raised[0].URL)
+
+	// Changes of composites aren't observed
+	require.Empty(t, composites.Observe(raised[0]))
+
+	_, err = NewComposites(manager, Composite{Name: "a/b", Rule: mirror})
+	require.ErrorContains(t, err, "invalid composite name")
+	_, err = NewComposites(manager, Composite{Name: "a", Rule: mirror}, Composite{Name: "a", Rule: outage})
+	require.ErrorContains(t, err, "duplicate composite 'a'")
+	_, err = NewComposites(manager, Composite{Name: "a", Rule: mirror, Window: -time.Minute})
+	require.ErrorIs(t, err, ErrCompositeWindow)
+}
+
+func TestCompositesWatch(t *testing.T) {
+	rule, err := ParseCompositeRule("failed(https://a.example.com)")
+	require.NoError(t, err)
+	composites, err := NewComposites(nil, Composite{Name: "down", Rule: rule, Window: time.Millisecond * 10})
+	require.NoError(t, err)
+
+	changes := make(chan Change)
+	out := composites.Watch(changes)
+	changes <- Change{URL: "https://a.example.com", Event: EventError}
+	require.Equal(t, EventError, (<-out).Event)
+	changes <- Change{URL: "https://a.example.com", Event: EventRecovery}
+	require.Equal(t, EventRecovery, (<-out).Event)
+
+	// The composite is raised by a timer once its window passed
+	require.Equal(t, EventComposite, (<-out).Event)
+	close(changes)
+	_, ok := <-out
+	require.False(t, ok)
+}
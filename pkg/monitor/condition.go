@@ -0,0 +1,265 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition is a state of the content, see Config.Until and Match
+type Condition interface {
+	// Met reports whether the content meets the condition, and describes
+	// what was found if it does
+	Met(content []byte) (bool, string)
+	// Description returns a human-readable description of the condition
+	Description() string
+}
+
+// snippetContext is the number of bytes shown around a match in details
+const snippetContext = 40
+
+// TextCondition is met when the content contains a text
+type TextCondition struct {
+	text string
+}
+
+// NewTextCondition creates a condition met when the content contains text
+func NewTextCondition(text string) *TextCondition {
+	return &TextCondition{text: text}
+}
+
+// Met implements Condition.Met
+func (c *TextCondition) Met(content []byte) (bool, string) {
+	i := bytes.Index(content, []byte(c.text))
+	if i < 0 {
+		return false, ""
+	}
+	return true, fmt.Sprintf("Found %q: %s", c.text, snippet(content, i, i+len(c.text)))
+}
+
+// Description implements Condition.Description
+func (c *TextCondition) Description() string {
+	return fmt.Sprintf("text %q", c.text)
+}
+
+// RegexCondition is met when the content matches a regular expression
+type RegexCondition struct {
+	pattern *regexp.Regexp
+}
+
+// NewRegexCondition creates a condition met when the content matches pattern
+func NewRegexCondition(pattern string) (*RegexCondition, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexCondition{pattern: re}, nil
+}
+
+// Met implements Condition.Met
+func (c *RegexCondition) Met(content []byte) (bool, string) {
+	loc := c.pattern.FindIndex(content)
+	if loc == nil {
+		return false, ""
+	}
+	return true, fmt.Sprintf("Matched /%s/: %s", c.pattern, snippet(content, loc[0], loc[1]))
+}
+
+// Description implements Condition.Description
+func (c *RegexCondition) Description() string {
+	return fmt.Sprintf("pattern /%s/", c.pattern)
+}
+
+// JSONCondition is met when a field of a JSON document has, or no longer
+// has, a value
+type JSONCondition struct {
+	path   string
+	value  string
+	negate bool
+}
+
+// NewJSONCondition creates a condition met when the field at path, such as
+// "registration.open" or "items.0.status", equals value, or differs from it
+// if negate is set. Values are compared as written in JSON, with strings
+// unquoted, e.g. "true", "42" or "open".
+func NewJSONCondition(path, value string, negate bool) *JSONCondition {
+	return &JSONCondition{path: path, value: value, negate: negate}
+}
+
+// Met implements Condition.Met. Content that isn't JSON or lacks the field
+// doesn't meet the condition.
+func (c *JSONCondition) Met(content []byte) (bool, string) {
+	var doc any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return false, ""
+	}
+
+	field, ok := lookupJSON(doc, c.path)
+	if !ok {
+		return false, ""
+	}
+
+	value := jsonValue(field)
+	if (value == c.value) == c.negate {
+		return false, ""
+	}
+	return true, fmt.Sprintf("%s is %s", c.path, value)
+}
+
+// Description implements Condition.Description
+func (c *JSONCondition) Description() string {
+	op := "="
+	if c.negate {
+		op = "!="
+	}
+	return fmt.Sprintf("JSON %s%s%s", c.path, op, c.value)
+}
+
+// Match is a keyword or pattern watched with MethodKeyword. A change is
+// reported when its condition becomes met, or when it stops being met if
+// Absent is set, e.g. for "out of stock" disappearing from a page.
+type Match struct {
+	Condition Condition
+	Absent    bool
+}
+
+// ParseMatches parses the conditions, see ParseCondition, of matches reported
+// when they appear and of matches reported when they disappear
+func ParseMatches(present, absent []string) ([]Match, error) {
+	matches := make([]Match, 0, len(present)+len(absent))
+	for i, specs := range [][]string{present, absent} {
+		for _, spec := range specs {
+			condition, err := ParseCondition(spec)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, Match{Condition: condition, Absent: i == 1})
+		}
+	}
+	return matches, nil
+}
+
+// detectMatchChange checks if a watched keyword appeared or disappeared
+func (m *Monitor) detectMatchChange(content []byte) (bool, string) {
+	content = m.prepare(content)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matched := make([]bool, len(m.config.Matches))
+	var details []string
+
+	for i, match := range m.config.Matches {
+		met, found := match.Condition.Met(content)
+		matched[i] = met
+		if m.lastMatched == nil || m.lastMatched[i] == met {
+			continue
+		}
+
+		switch {
+		case met && !match.Absent:
+			details = append(details, found)
+		case !met && match.Absent:
+			details = append(details, "No longer found: "+match.Condition.Description())
+		}
+	}
+	m.lastMatched = matched
+
+	if len(details) == 0 {
+		return false, ""
+	}
+	return true, strings.Join(details, "\n")
+}
+
+// ParseCondition parses a condition written as "text:registration open",
+// "regex:tickets? available" or "json:registration.open=true", with "!=" to
+// wait for a JSON field to change from a value. Without a prefix the
+// condition is a text.
+func ParseCondition(spec string) (Condition, error) {
+	kind, value, found := strings.Cut(spec, ":")
+	if !found {
+		kind, value = "text", spec
+	}
+
+	switch kind {
+	case "text":
+		if value == "" {
+			return nil, fmt.Errorf("empty text condition")
+		}
+		return NewTextCondition(value), nil
+	case "regex":
+		condition, err := NewRegexCondition(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex condition: %w", err)
+		}
+		return condition, nil
+	case "json":
+		negate := false
+		path, expected, found := strings.Cut(value, "!=")
+		if found {
+			negate = true
+		} else {
+			path, expected, found = strings.Cut(value, "=")
+		}
+		if !found || path == "" {
+			return nil, fmt.Errorf("invalid JSON condition '%s' (expected path=value or path!=value)", value)
+		}
+		return NewJSONCondition(path, expected, negate), nil
+	default:
+		// A colon in a plain text, e.g. "Status: open"
+		return NewTextCondition(spec), nil
+	}
+}
+
+// lookupJSON returns the value at a dotted path in a decoded JSON document.
+// Numeric parts index arrays.
+func lookupJSON(doc any, path string) (any, bool) {
+	for _, part := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			doc = value
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// jsonValue formats a decoded JSON value for comparison: strings as is and
+// anything else as JSON
+func jsonValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// snippet returns the content from start to end with some context around
+// it, on a single line
+func snippet(content []byte, start, end int) string {
+	from := max(start-snippetContext, 0)
+	to := min(end+snippetContext, len(content))
+
+	text := strings.Join(strings.Fields(string(content[from:to])), " ")
+	if from > 0 {
+		text = "..." + text
+	}
+	if to < len(content) {
+		text += "..."
+	}
+	return text
+}
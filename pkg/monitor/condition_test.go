@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		spec        string
+		description string
+		wantErr     bool
+	}{
+		{spec: "registration open", description: `text "registration open"`},
+		{spec: "text:in stock", description: `text "in stock"`},
+		{spec: "Status: open", description: `text "Status: open"`},
+		{spec: "regex:tickets? available", description: "pattern /tickets? available/"},
+		{spec: "json:registration.open=true", description: "JSON registration.open=true"},
+		{spec: "json:items.0.status!=sold out", description: "JSON items.0.status!=sold out"},
+		{spec: "regex:(", wantErr: true},
+		{spec: "json:open", wantErr: true},
+		{spec: "text:", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			condition, err := ParseCondition(tc.spec)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.description, condition.Description())
+		})
+	}
+}
+
+func TestConditionMet(t *testing.T) {
+	page := []byte("<p>Registration\n   opens soon</p>")
+
+	met, _ := NewTextCondition("Registration open").Met(page)
+	require.False(t, met)
+	met, details := NewTextCondition("opens soon").Met(page)
+	require.True(t, met)
+	require.Equal(t, `Found "opens soon": <p>Registration opens soon</p>`, details)
+
+	regex, err := NewRegexCondition(`opens? (soon|now)`)
+	require.NoError(t, err)
+	met, details = regex.Met(page)
+	require.True(t, met)
+	require.Contains(t, details, "Matched /opens? (soon|now)/")
+
+	doc := []byte(`{"registration": {"open": false}, "items": [{"status": "sold out"}]}`)
+	met, _ = NewJSONCondition("registration.open", "true", false).Met(doc)
+	require.False(t, met)
+	met, details = NewJSONCondition("registration.open", "false", false).Met(doc)
+	require.True(t, met)
+	require.Equal(t, "registration.open is false", details)
+	met, _ = NewJSONCondition("items.0.status", "sold out", true).Met(doc)
+	require.False(t, met)
+	met, _ = NewJSONCondition("items.0.status", "available", true).Met(doc)
+	require.True(t, met)
+
+	// Missing fields and other content never meet JSON conditions
+	met, _ = NewJSONCondition("items.3.status", "x", true).Met(doc)
+	require.False(t, met)
+	met, _ = NewJSONCondition("open", "x", true).Met(page)
+	require.False(t, met)
+}
+
+func TestSnippet(t *testing.T) {
+	content := []byte(strings.Repeat("a", 50) + "MATCH" + strings.Repeat("b", 50))
+	require.Equal(t, "..."+strings.Repeat("a", 40)+"MATCH"+strings.Repeat("b", 40)+"...", snippet(content, 50, 55))
+	require.Equal(t, "MATCH", snippet([]byte("MATCH"), 0, 5))
+}
+
+func TestKeywordMethod(t *testing.T) {
+	pages := []string{
+		"Widget: out of stock",
+		"Widget: out of stock, more soon",
+		"Widget: in stock",
+		"Widget: out of stock",
+	}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[calls.Add(1)-1]))
+	}))
+	defer server.Close()
+
+	inStock, err := NewRegexCondition(`in\s+stock`)
+	require.NoError(t, err)
+	config := DefaultConfig(server.URL)
+	config.Method = MethodKeyword
+	config.RetryCount = 0
+	config.Matches = []Match{
+		{Condition: inStock},
+		{Condition: NewTextCondition("out of stock"), Absent: true},
+	}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "other changes are ignored")
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Matched /in\\s+stock/: Widget: in stock\nNo longer found: text \"out of stock\"", change.Details)
+
+	// Neither match reports the reverse direction
+	require.False(t, m.Check().HasChanged)
+}
+
+func TestKeywordMethodRequiresMatches(t *testing.T) {
+	config := DefaultConfig("https://example.com")
+	config.Method = MethodKeyword
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrNoMatches)
+}
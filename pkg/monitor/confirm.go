@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"image"
+	"maps"
+)
+
+// baselineState is the state a check compares content with, saved before a
+// change is compared so that it can be undone until the change is confirmed
+type baselineState struct {
+	lastContent  []byte
+	lastDigest   bodyDigest
+	lastKind     string
+	lastVariants map[string][]byte
+	lastStatus   int
+	lastMatched  []bool
+	lastValue    *float64
+	lastCounts   []int
+	countSeries  []CountSample
+	lastTable    *table
+	lastImage    image.Image
+	tail         tailState
+	seenEntries  map[string]bool
+	met          string
+}
+
+// saveBaseline returns the baseline of the monitor. Maps updated in place by
+// checks are copied.
+func (m *Monitor) saveBaseline() baselineState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return baselineState{
+		lastContent:  m.lastContent,
+		lastDigest:   m.lastDigest,
+		lastKind:     m.lastKind,
+		lastVariants: maps.Clone(m.lastVariants),
+		lastStatus:   m.lastStatus,
+		lastMatched:  m.lastMatched,
+		lastValue:    m.lastValue,
+		lastCounts:   m.lastCounts,
+		countSeries:  m.countSeries,
+		lastTable:    m.lastTable,
+		lastImage:    m.lastImage,
+		tail:         m.tail,
+		seenEntries:  maps.Clone(m.seenEntries),
+		met:          m.met,
+	}
+}
+
+// restoreBaseline sets the baseline saved by saveBaseline back
+func (m *Monitor) restoreBaseline(saved baselineState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastContent = saved.lastContent
+	m.lastDigest = saved.lastDigest
+	m.lastKind = saved.lastKind
+	m.lastVariants = saved.lastVariants
+	m.lastStatus = saved.lastStatus
+	m.lastMatched = saved.lastMatched
+	m.lastValue = saved.lastValue
+	m.lastCounts = saved.lastCounts
+	m.countSeries = saved.countSeries
+	m.lastTable = saved.lastTable
+	m.lastImage = saved.lastImage
+	m.tail = saved.tail
+	m.seenEntries = saved.seenEntries
+	m.met = saved.met
+}
+
+// confirm confirms a change found by a check, see Config.ConfirmDelay: it
+// sets the baseline saved before the change back and checks again after the
+// delay, so that the change is only reported if the content fetched again
+// differs from that baseline too. A change that isn't confirmed leaves the
+// baseline as it was.
+func (m *Monitor) confirm(saved baselineState) (Change, bool) {
+	m.restoreBaseline(saved)
+	m.setStatus("confirming")
+
+	select {
+	case <-m.clock.After(m.config.ConfirmDelay):
+	case <-m.ctx.Done():
+		return Change{}, false
+	}
+	return m.checkContent(false)
+}
@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// cookieJar is the jar of a monitor's client. It forwards to the jar the
+// monitor currently uses: none for monitors that don't keep cookies, their
+// own, or the jar of a group that shares cookies. Switching jars is safe
+// while checks run.
+type cookieJar struct {
+	mu  sync.RWMutex
+	jar http.CookieJar
+}
+
+// SetCookies implements http.CookieJar.SetCookies
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.jar != nil {
+		j.jar.SetCookies(u, cookies)
+	}
+}
+
+// Cookies implements http.CookieJar.Cookies
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.jar == nil {
+		return nil
+	}
+	return j.jar.Cookies(u)
+}
+
+// use switches to jar
+func (j *cookieJar) use(jar http.CookieJar) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar = jar
+}
+
+// ValidateCookies checks that cookies to seed have valid names and values
+func ValidateCookies(cookies map[string]string) error {
+	for name, value := range cookies {
+		if err := (&http.Cookie{Name: name, Value: value}).Valid(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newJar creates an empty cookie jar
+func newJar() http.CookieJar {
+	// Without options cookiejar.New doesn't fail
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+// seedCookies sets the cookies of the config in jar, for the whole site of
+// its URL
+func seedCookies(jar http.CookieJar, config *Config) {
+	if len(config.Cookies) == 0 {
+		return
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return
+	}
+
+	cookies := make([]*http.Cookie, 0, len(config.Cookies))
+	for name, value := range config.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: value, Path: "/"})
+	}
+	jar.SetCookies(u, cookies)
+}
+
+// useJar makes the monitor keep its cookies in jar, seeding it with the
+// configured cookies. A nil jar goes back to the monitor's own jar.
+func (m *Monitor) useJar(jar http.CookieJar) {
+	if jar == nil {
+		jar = m.ownJar
+	} else {
+		seedCookies(jar, &m.config)
+	}
+	m.jar.use(jar)
+}
+
+// ShareCookies makes the monitors of a group keep their cookies in a jar
+// shared by the group, e.g. so that a session started by one page is used by
+// all of them, or stops sharing. A monitor in several sharing groups uses the
+// jar of the first one by name.
+func (m *Manager) ShareCookies(groupName string, share bool) (err error) {
+	defer m.persist(&err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", groupName)
+	}
+
+	group.ShareCookies = share
+	group.jar = nil
+	if share {
+		group.jar = newJar()
+	}
+	for url, monitor := range group.Monitors {
+		m.updateJarLocked(url, monitor)
+	}
+	return nil
+}
+
+// updateJarLocked points a monitor at the jar of the first group sharing
+// cookies that it is in, or at its own jar. m.mu must be held.
+func (m *Manager) updateJarLocked(url string, monitor *Monitor) {
+	names := make([]string, 0, len(m.groups))
+	for name, group := range m.groups {
+		if _, exists := group.Monitors[url]; exists && group.ShareCookies {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		monitor.useJar(nil)
+		return
+	}
+	sort.Strings(names)
+	monitor.useJar(m.groups[names[0]].jar)
+}
@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cookieServer sets a session cookie on /login and records the cookies sent
+// to every path
+func cookieServer(t *testing.T) (*httptest.Server, func(path string) string) {
+	var mu sync.Mutex
+	received := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Get("Cookie")
+		mu.Unlock()
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		}
+		w.Write([]byte("content"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(path string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return received[path]
+	}
+}
+
+func TestKeepCookies(t *testing.T) {
+	server, cookie := cookieServer(t)
+
+	// Cookies are dropped by default
+	m := NewMonitorWithConfig(DefaultConfig(server.URL + "/login"))
+	defer m.Stop()
+	m.Check()
+	m.Check()
+	require.Empty(t, cookie("/login"))
+
+	// and kept between checks with KeepCookies
+	config := DefaultConfig(server.URL + "/login")
+	config.KeepCookies = true
+	m = NewMonitorWithConfig(config)
+	defer m.Stop()
+	m.Check()
+	require.Empty(t, cookie("/login"))
+	m.Check()
+	require.Equal(t, "session=abc", cookie("/login"))
+
+	// Seeded cookies are sent from the first check
+	config = DefaultConfig(server.URL + "/page")
+	config.Cookies = map[string]string{"consent": "yes"}
+	m = NewMonitorWithConfig(config)
+	defer m.Stop()
+	m.Check()
+	require.Equal(t, "consent=yes", cookie("/page"))
+
+	_, err := NewManager().AddMonitorWithConfig(&Config{URL: server.URL, Interval: config.Interval, Cookies: map[string]string{"bad name": "x"}})
+	require.ErrorContains(t, err, "invalid Cookie.Name")
+}
+
+func TestShareCookies(t *testing.T) {
+	server, cookie := cookieServer(t)
+	path := filepath.Join(t.TempDir(), "manager.json")
+
+	manager := NewManager()
+	require.NoError(t, manager.SetStore(NewFileStore(path)))
+	login, err := manager.AddMonitorWithConfig(DefaultConfig(server.URL + "/login"))
+	require.NoError(t, err)
+	config := DefaultConfig(server.URL + "/account")
+	config.Cookies = map[string]string{"consent": "yes"}
+	account, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	_, err = manager.CreateGroup("shop", "")
+	require.NoError(t, err)
+	require.NoError(t, manager.ShareCookies("shop", true))
+	require.NoError(t, manager.AddToGroup(login.GetConfig().URL, "shop"))
+	require.NoError(t, manager.AddToGroup(account.GetConfig().URL, "shop"))
+
+	// The session of one monitor is used by the others of the group
+	login.Check()
+	account.Check()
+	require.Equal(t, "consent=yes; session=abc", cookie("/account"))
+
+	// A monitor that leaves the group goes back to its own cookies
+	require.NoError(t, manager.RemoveFromGroup(account.GetConfig().URL, "shop"))
+	account.Check()
+	require.Equal(t, "consent=yes", cookie("/account"))
+
+	// Sharing is saved with the group
+	restored := NewManager()
+	require.NoError(t, restored.SetStore(NewFileStore(path)))
+	group, err := restored.GetGroup("shop")
+	require.NoError(t, err)
+	require.True(t, group.ShareCookies)
+
+	require.Error(t, manager.ShareCookies("missing", true))
+}
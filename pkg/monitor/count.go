@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxCountSamples is the number of samples of keyword counts a monitor keeps
+// with MethodCount; older samples are dropped
+const MaxCountSamples = 1000
+
+// Keyword is a text or pattern whose occurrences are counted with
+// MethodCount, e.g. the job postings matching "Go developer"
+type Keyword struct {
+	spec    string
+	pattern *regexp.Regexp
+}
+
+// ParseKeyword parses a keyword written as "text:Go developer" or
+// "regex:(?i)go (developer|engineer)". Without a prefix the keyword is a
+// text.
+func ParseKeyword(spec string) (*Keyword, error) {
+	kind, value, found := strings.Cut(spec, ":")
+	switch {
+	case found && kind == "regex":
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex keyword: %w", err)
+		}
+		if pattern.MatchString("") {
+			return nil, fmt.Errorf("regex keyword /%s/ matches empty text", value)
+		}
+		return &Keyword{spec: spec, pattern: pattern}, nil
+	case found && kind == "text":
+	default:
+		// A colon in a plain text, e.g. "Status: open"
+		value = spec
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("empty keyword")
+	}
+	return &Keyword{spec: spec, pattern: regexp.MustCompile(regexp.QuoteMeta(value))}, nil
+}
+
+// ParseKeywords parses keywords, see ParseKeyword
+func ParseKeywords(specs []string) ([]*Keyword, error) {
+	keywords := make([]*Keyword, 0, len(specs))
+	for _, spec := range specs {
+		keyword, err := ParseKeyword(spec)
+		if err != nil {
+			return nil, err
+		}
+		keywords = append(keywords, keyword)
+	}
+	return keywords, nil
+}
+
+// Count returns the number of non-overlapping occurrences of the keyword
+func (k *Keyword) Count(content []byte) int {
+	return len(k.pattern.FindAllIndex(content, -1))
+}
+
+// String returns the keyword as it was written
+func (k *Keyword) String() string {
+	return k.spec
+}
+
+// CountChange is the number of occurrences of a keyword before and after a
+// change found with MethodCount
+type CountChange struct {
+	Keyword string `json:"keyword"`
+	Old     int    `json:"old"`
+	New     int    `json:"new"`
+}
+
+// CountSample is the number of occurrences of every keyword found by a check
+type CountSample struct {
+	Time   time.Time      `json:"time"`
+	Counts map[string]int `json:"counts"`
+}
+
+// detectCountChange counts the keywords in the content, records the counts
+// and compares them with those of the previous check
+func (m *Monitor) detectCountChange(content []byte) (bool, string, []CountChange) {
+	content = m.prepare(content)
+
+	counts := make([]int, len(m.config.Keywords))
+	sample := CountSample{Time: m.clock.Now(), Counts: make(map[string]int, len(counts))}
+	for i, keyword := range m.config.Keywords {
+		counts[i] = keyword.Count(content)
+		sample.Counts[keyword.String()] = counts[i]
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.countSeries = append(m.countSeries, sample)
+	if len(m.countSeries) > MaxCountSamples {
+		m.countSeries = append([]CountSample(nil), m.countSeries[len(m.countSeries)-MaxCountSamples:]...)
+	}
+
+	last := m.lastCounts
+	m.lastCounts = counts
+	if last == nil {
+		return false, "", nil
+	}
+
+	var changes []CountChange
+	var details []string
+	for i, keyword := range m.config.Keywords {
+		if counts[i] == last[i] {
+			continue
+		}
+		changes = append(changes, CountChange{Keyword: keyword.String(), Old: last[i], New: counts[i]})
+		details = append(details, fmt.Sprintf("%q: %d → %d", keyword.String(), last[i], counts[i]))
+	}
+	if len(changes) == 0 {
+		return false, "", nil
+	}
+	return true, "Count changed: " + strings.Join(details, ", "), changes
+}
+
+// CountSeries returns the keyword counts of the checks with MethodCount,
+// oldest first
+func (m *Monitor) CountSeries() []CountSample {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]CountSample(nil), m.countSeries...)
+}
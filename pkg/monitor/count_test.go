@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyword(t *testing.T) {
+	content := []byte("Go developer, Senior Go Developer, go developer (remote), Status: open")
+
+	tests := []struct {
+		spec  string
+		count int
+	}{
+		{"Go developer", 1},
+		{"text:Go developer", 1},
+		{"regex:(?i)go developer", 3},
+		{"Status: open", 1},
+		{"Rust developer", 0},
+	}
+	for _, tt := range tests {
+		keyword, err := ParseKeyword(tt.spec)
+		require.NoError(t, err)
+		require.Equal(t, tt.count, keyword.Count(content), tt.spec)
+		require.Equal(t, tt.spec, keyword.String())
+	}
+
+	_, err := ParseKeyword("regex:(")
+	require.ErrorContains(t, err, "invalid regex keyword")
+	_, err = ParseKeyword("regex:a*")
+	require.ErrorContains(t, err, "matches empty text")
+	_, err = ParseKeyword("text:")
+	require.ErrorContains(t, err, "empty keyword")
+}
+
+func TestCountMethod(t *testing.T) {
+	pages := []string{
+		"<li>Go developer</li><li>Designer</li>",
+		"<li>Go developer</li><li>Designer</li><li>Product manager</li>",
+		"<li>Go developer</li><li>Go developer</li><li>Designer</li><li>Designer</li>",
+	}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[calls.Add(1)-1]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodCount
+	config.RetryCount = 0
+	config.Keywords, _ = ParseKeywords([]string{"Go developer", "Designer"})
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "other changes are ignored")
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, `Count changed: "Go developer": 1 → 2, "Designer": 1 → 2`, change.Details)
+	require.Equal(t, []CountChange{{Keyword: "Go developer", Old: 1, New: 2}, {Keyword: "Designer", Old: 1, New: 2}}, change.Counts)
+
+	// Every check is a sample of the series
+	series := m.CountSeries()
+	require.Len(t, series, 3)
+	require.Equal(t, map[string]int{"Go developer": 1, "Designer": 1}, series[0].Counts)
+	require.Equal(t, map[string]int{"Go developer": 2, "Designer": 2}, series[2].Counts)
+	require.False(t, series[2].Time.Before(series[0].Time))
+}
+
+func TestCountMethodRequiresKeywords(t *testing.T) {
+	config := DefaultConfig("https://example.com")
+	config.Method = MethodCount
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrNoKeywords)
+}
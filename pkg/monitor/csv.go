@@ -0,0 +1,242 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Row change types, besides DiffAdded and DiffRemoved
+const RowChanged = "changed"
+
+// RowChange is a row of a CSV or TSV document that was added, removed or
+// changed, found with MethodCSV. Old and New map column names to values.
+type RowChange struct {
+	Key  string            `json:"key"`
+	Type string            `json:"type"`
+	Old  map[string]string `json:"old,omitempty"`
+	New  map[string]string `json:"new,omitempty"`
+}
+
+// ParseDelimiter parses the name of a CSV delimiter: "comma", "tab",
+// "semicolon", "pipe" or a single character. An empty name detects the
+// delimiter from the content.
+func ParseDelimiter(name string) (rune, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return 0, nil
+	case "comma":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "semicolon":
+		return ';', nil
+	case "pipe":
+		return '|', nil
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	if size != len(name) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter '%s' (expected comma, tab, semicolon, pipe or a single character)", name)
+	}
+	return r, nil
+}
+
+// formatDelimiter returns the name of a delimiter, see ParseDelimiter
+func formatDelimiter(delimiter rune) string {
+	switch delimiter {
+	case 0:
+		return ""
+	case '\t':
+		return "tab"
+	default:
+		return string(delimiter)
+	}
+}
+
+// table is a parsed CSV or TSV document with its rows by key
+type table struct {
+	header []string
+	rows   map[string]map[string]string
+	order  []string
+}
+
+// detectDelimiter picks the delimiter of a document: tab for TSV content
+// types, otherwise the most frequent of comma, tab and semicolon in its
+// first line
+func detectDelimiter(content []byte, contentType string) rune {
+	if strings.Contains(contentType, "tab-separated") {
+		return '\t'
+	}
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	delimiter, most := ',', bytes.Count(line, []byte(","))
+	for _, candidate := range []rune{'\t', ';'} {
+		if n := bytes.Count(line, []byte(string(candidate))); n > most {
+			delimiter, most = candidate, n
+		}
+	}
+	return delimiter
+}
+
+// parseTable parses a CSV or TSV document whose first line names the
+// columns. Rows are keyed by the values of the key columns, or of the first
+// column without any; repeated keys are numbered.
+func parseTable(content []byte, delimiter rune, keys []string) (*table, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return &table{}, nil
+	}
+
+	t := &table{header: records[0], rows: make(map[string]map[string]string, len(records)-1)}
+	keyColumns := keys
+	if len(keyColumns) == 0 {
+		keyColumns = t.header[:1]
+	}
+	for _, key := range keyColumns {
+		if !slices.Contains(t.header, key) {
+			return nil, fmt.Errorf("key column '%s' is not in the CSV header", key)
+		}
+	}
+
+	seen := make(map[string]int)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(t.header))
+		for i, column := range t.header {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+
+		parts := make([]string, len(keyColumns))
+		for i, column := range keyColumns {
+			parts[i] = column + "=" + row[column]
+		}
+		key := strings.Join(parts, ", ")
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s (%d)", key, seen[key])
+		}
+
+		t.rows[key] = row
+		t.order = append(t.order, key)
+	}
+	return t, nil
+}
+
+// parseTable parses content as a CSV document with the monitor's settings
+func (m *Monitor) parseTable(content []byte, contentType string) (*table, error) {
+	content = m.prepare(content)
+	delimiter := m.config.CSVDelimiter
+	if delimiter == 0 {
+		delimiter = detectDelimiter(content, contentType)
+	}
+	return parseTable(content, delimiter, m.config.CSVKeys)
+}
+
+// detectCSVChange compares the rows of a document with those of the
+// previous check. Rows are matched by key, so reordered rows aren't changes.
+func (m *Monitor) detectCSVChange(t *table) (bool, string, []RowChange) {
+	m.mu.Lock()
+	last := m.lastTable
+	m.lastTable = t
+	m.mu.Unlock()
+	if last == nil {
+		return false, "", nil
+	}
+
+	// Values are only compared in the columns both documents have
+	var columns, addedColumns, removedColumns []string
+	for _, column := range t.header {
+		if slices.Contains(last.header, column) {
+			columns = append(columns, column)
+		} else {
+			addedColumns = append(addedColumns, column)
+		}
+	}
+	for _, column := range last.header {
+		if !slices.Contains(t.header, column) {
+			removedColumns = append(removedColumns, column)
+		}
+	}
+
+	var added, removed, changed []RowChange
+	for _, key := range t.order {
+		row := t.rows[key]
+		old, found := last.rows[key]
+		switch {
+		case !found:
+			added = append(added, RowChange{Key: key, Type: DiffAdded, New: row})
+		case slices.ContainsFunc(columns, func(column string) bool { return old[column] != row[column] }):
+			changed = append(changed, RowChange{Key: key, Type: RowChanged, Old: old, New: row})
+		}
+	}
+	for _, key := range last.order {
+		if _, found := t.rows[key]; !found {
+			removed = append(removed, RowChange{Key: key, Type: DiffRemoved, Old: last.rows[key]})
+		}
+	}
+
+	if len(added)+len(removed)+len(changed)+len(addedColumns)+len(removedColumns) == 0 {
+		return false, "", nil
+	}
+
+	var summary []string
+	if len(added) > 0 {
+		summary = append(summary, fmt.Sprintf("%d added", len(added)))
+	}
+	if len(removed) > 0 {
+		summary = append(summary, fmt.Sprintf("%d removed", len(removed)))
+	}
+	if len(changed) > 0 {
+		summary = append(summary, fmt.Sprintf("%d changed", len(changed)))
+	}
+	var details []string
+	if len(summary) > 0 {
+		details = append(details, "Rows "+strings.Join(summary, ", "))
+	} else {
+		details = append(details, "Columns changed")
+	}
+	if len(addedColumns) > 0 {
+		details = append(details, "Columns added: "+strings.Join(addedColumns, ", "))
+	}
+	if len(removedColumns) > 0 {
+		details = append(details, "Columns removed: "+strings.Join(removedColumns, ", "))
+	}
+
+	for _, row := range added {
+		details = append(details, "+ "+row.Key+": "+formatRow(t.header, row.New))
+	}
+	for _, row := range removed {
+		details = append(details, "- "+row.Key+": "+formatRow(last.header, row.Old))
+	}
+	for _, row := range changed {
+		var values []string
+		for _, column := range columns {
+			if row.Old[column] != row.New[column] {
+				values = append(values, fmt.Sprintf("%s %q → %q", column, row.Old[column], row.New[column]))
+			}
+		}
+		details = append(details, "~ "+row.Key+": "+strings.Join(values, ", "))
+	}
+
+	rows := slices.Concat(added, removed, changed)
+	return true, strings.Join(details, "\n"), rows
+}
+
+// formatRow formats the values of a row in the order of the header
+func formatRow(header []string, row map[string]string) string {
+	values := make([]string, 0, len(header))
+	for _, column := range header {
+		values = append(values, column+"="+row[column])
+	}
+	return strings.Join(values, ", ")
+}
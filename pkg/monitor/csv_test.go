@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	content := []byte("region,sku,price\neu,1,10\nus,1,12\neu,1,11\n")
+
+	table, err := parseTable(content, ',', nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"region=eu", "region=us", "region=eu (2)"}, table.order)
+
+	table, err = parseTable(content, ',', []string{"region", "sku"})
+	require.NoError(t, err)
+	require.Equal(t, "11", table.rows["region=eu, sku=1 (2)"]["price"])
+
+	_, err = parseTable(content, ',', []string{"id"})
+	require.ErrorContains(t, err, "key column 'id'")
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	require.Equal(t, ',', detectDelimiter([]byte("id,name\n1,a"), "text/csv"))
+	require.Equal(t, '\t', detectDelimiter([]byte("id\tname\tnote, full\n"), "text/plain"))
+	require.Equal(t, ';', detectDelimiter([]byte("id;name\n"), ""))
+	require.Equal(t, '\t', detectDelimiter([]byte("id"), "text/tab-separated-values"))
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for name, delimiter := range map[string]rune{"": 0, "comma": ',', "TAB": '\t', "semicolon": ';', "|": '|'} {
+		parsed, err := ParseDelimiter(name)
+		require.NoError(t, err)
+		require.Equal(t, delimiter, parsed, name)
+	}
+	_, err := ParseDelimiter(";;")
+	require.ErrorContains(t, err, "invalid delimiter")
+}
+
+func TestCSVMethod(t *testing.T) {
+	pages := []string{
+		"id,name,price\n1,Widget,10\n2,Gadget,20\n3,Gizmo,30\n",
+		"id,name,price\n3,Gizmo,30\n1,Widget,10\n2,Gadget,20\n",
+		"id,name,price\n1,Widget,12\n3,Gizmo,30\n4,Doohickey,5\n",
+		"id,name,price\n1,Widget,12\n3,Gizmo,30\n4,Doohickey,5\n5,\"Thing, large\"\n",
+		"sku,name\n1,Widget\n",
+	}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte(pages[calls.Add(1)-1]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodCSV
+	config.CSVKeys = []string{"id"}
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "reordered rows aren't a change")
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Rows 1 added, 1 removed, 1 changed\n"+
+		"+ id=4: id=4, name=Doohickey, price=5\n"+
+		"- id=2: id=2, name=Gadget, price=20\n"+
+		`~ id=1: price "10" → "12"`, change.Details)
+	require.Len(t, change.Rows, 3)
+	require.Equal(t, RowChange{Key: "id=2", Type: DiffRemoved, Old: map[string]string{"id": "2", "name": "Gadget", "price": "20"}}, change.Rows[1])
+	require.Equal(t, RowChanged, change.Rows[2].Type)
+	require.Empty(t, change.Hunks)
+
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Rows 1 added\n+ id=5: id=5, name=Thing, large, price=", change.Details)
+
+	// The key column is missing
+	change = m.Check()
+	require.Equal(t, EventError, change.Event)
+	require.Contains(t, change.Error, "key column 'id'")
+}
+
+func TestCSVColumns(t *testing.T) {
+	pages := []string{
+		"sku\tname\n1\tWidget\n",
+		"sku\tname\tstock\n1\tWidget\t3\n",
+	}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[calls.Add(1)-1]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodCSV
+	config.CSVKeys = []string{"sku"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Columns changed\nColumns added: stock", change.Details)
+	require.Empty(t, change.Rows, "values are compared in the columns both documents have")
+}
+
+func TestCSVKeysRequireMethod(t *testing.T) {
+	config := DefaultConfig("https://example.com")
+	config.CSVKeys = []string{"id"}
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrCSVKeys)
+}
@@ -0,0 +1,315 @@
+package monitor
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default limits for the diff included in Change.Details
+const (
+	// DefaultDiffContextLines is the number of unchanged lines shown around
+	// each changed line
+	DefaultDiffContextLines = 3
+	// DefaultMaxDetailsLines is the maximum number of lines in Change.Details
+	DefaultMaxDetailsLines = 40
+	// DefaultMaxDetailsBytes is the maximum size of Change.Details in bytes
+	DefaultMaxDetailsBytes = 4096
+)
+
+// maxDiffCells bounds the size of the line comparison table. Larger inputs
+// are reported as a complete replacement of the differing region.
+const maxDiffCells = 4 * 1024 * 1024
+
+// Diff line types
+const (
+	DiffContext = "context"
+	DiffRemoved = "removed"
+	DiffAdded   = "added"
+)
+
+// DiffLine is a single line of a diff hunk
+type DiffLine struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// DiffHunk is a contiguous region of changed lines with surrounding context.
+// Line numbers are 1-based; a range with zero lines starts at the line after
+// which lines were added or removed.
+type DiffHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// String formats the hunk in unified diff format
+func (h DiffHunk) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	for _, line := range h.Lines {
+		switch line.Type {
+		case DiffRemoved:
+			b.WriteByte('-')
+		case DiffAdded:
+			b.WriteByte('+')
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(line.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FormatHunks formats hunks as a unified diff
+func FormatHunks(hunks []DiffHunk) string {
+	var b strings.Builder
+	for _, hunk := range hunks {
+		b.WriteString(hunk.String())
+	}
+	return b.String()
+}
+
+// FormatHunksHTML formats hunks as an HTML table with the old and new
+// number of each line. Rows have the class of their line type, or hunk for
+// hunk headers, so they can be styled.
+func FormatHunksHTML(hunks []DiffHunk) string {
+	var b strings.Builder
+	b.WriteString("<table class=\"diff\">\n")
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "<tr class=\"hunk\"><td></td><td></td><td>@@ -%d,%d +%d,%d @@</td></tr>\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		oldLine, newLine := hunk.OldStart, hunk.NewStart
+		for _, line := range hunk.Lines {
+			var oldNumber, newNumber string
+			if line.Type != DiffAdded {
+				oldNumber = strconv.Itoa(oldLine)
+				oldLine++
+			}
+			if line.Type != DiffRemoved {
+				newNumber = strconv.Itoa(newLine)
+				newLine++
+			}
+			fmt.Fprintf(&b, "<tr class=\"%s\"><td>%s</td><td>%s</td><td>%s</td></tr>\n", line.Type, oldNumber, newNumber, html.EscapeString(line.Text))
+		}
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// diffOp is a line in a diff
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// LineDiff returns the hunks of a line diff of old and new with the given
+// number of context lines around each change. It returns nil if both are
+// identical.
+func LineDiff(oldContent, newContent []byte, context int) []DiffHunk {
+	oldLines := splitLines(string(oldContent))
+	newLines := splitLines(string(newContent))
+
+	ops := diffLines(oldLines, newLines)
+	if context < 0 {
+		context = 0
+	}
+
+	var hunks []DiffHunk
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*context {
+				break
+			}
+		}
+
+		from := max(start-context, 0)
+		to := min(end+context, len(ops))
+		hunks = append(hunks, newHunk(ops, from, to))
+		start = to
+	}
+
+	return hunks
+}
+
+// newHunk builds the hunk covering ops[from:to]
+func newHunk(ops []diffOp, from, to int) DiffHunk {
+	hunk := DiffHunk{OldStart: 1, NewStart: 1}
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			hunk.OldStart++
+		}
+		if op.kind != '-' {
+			hunk.NewStart++
+		}
+	}
+
+	for _, op := range ops[from:to] {
+		line := DiffLine{Type: DiffContext, Text: op.text}
+		switch op.kind {
+		case '-':
+			line.Type = DiffRemoved
+			hunk.OldLines++
+		case '+':
+			line.Type = DiffAdded
+			hunk.NewLines++
+		default:
+			hunk.OldLines++
+			hunk.NewLines++
+		}
+		hunk.Lines = append(hunk.Lines, line)
+	}
+
+	// Empty ranges start at the line before the change, as in unified diffs
+	if hunk.OldLines == 0 {
+		hunk.OldStart--
+	}
+	if hunk.NewLines == 0 {
+		hunk.NewStart--
+	}
+
+	return hunk
+}
+
+// diffLines computes the line operations turning old into new using the
+// longest common subsequence of the lines that differ
+func diffLines(oldLines, newLines []string) []diffOp {
+	// Strip the common prefix and suffix
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(oldLines)+len(newLines))
+	for _, line := range oldLines[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	a := oldLines[prefix : len(oldLines)-suffix]
+	c := newLines[prefix : len(newLines)-suffix]
+
+	if (len(a)+1)*(len(c)+1) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range c {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of a[i:] and c[j:]
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(c)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(c) - 1; j >= 0; j-- {
+				if a[i] == c[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(a) && j < len(c) {
+			switch {
+			case a[i] == c[j]:
+				ops = append(ops, diffOp{' ', a[i]})
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				ops = append(ops, diffOp{'-', a[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', c[j]})
+				j++
+			}
+		}
+		for ; i < len(a); i++ {
+			ops = append(ops, diffOp{'-', a[i]})
+		}
+		for ; j < len(c); j++ {
+			ops = append(ops, diffOp{'+', c[j]})
+		}
+	}
+
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	return ops
+}
+
+// splitLines splits content into lines without their line endings
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	return strings.Split(content, "\n")
+}
+
+// truncateDetails caps details at maxLines lines and maxBytes bytes, ending
+// it with a marker that says how much was left out. A limit of zero or less
+// means no limit.
+func truncateDetails(details string, maxLines, maxBytes int) string {
+	kept := details
+
+	if maxLines > 0 {
+		lines := strings.SplitAfter(kept, "\n")
+		if len(lines) > maxLines {
+			kept = strings.Join(lines[:maxLines], "")
+		}
+	}
+
+	if maxBytes > 0 && len(kept) > maxBytes {
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(kept[cut]) {
+			cut--
+		}
+		// Prefer to end on a whole line
+		if nl := strings.LastIndexByte(kept[:cut], '\n'); nl > 0 {
+			cut = nl + 1
+		}
+		kept = kept[:cut]
+	}
+
+	if len(kept) == len(details) {
+		return details
+	}
+
+	omitted := details[len(kept):]
+	omittedLines := strings.Count(omitted, "\n")
+	if !strings.HasSuffix(omitted, "\n") {
+		omittedLines++
+	}
+	if !strings.HasSuffix(kept, "\n") {
+		kept += "\n"
+	}
+
+	return kept + fmt.Sprintf("[... truncated: %d more lines (%d bytes), see the full diff ...]",
+		omittedLines, len(omitted))
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineDiff(t *testing.T) {
+	old := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	new := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	require.Empty(t, LineDiff([]byte(old), []byte(old), 3))
+
+	// Changes far apart get their own hunks
+	hunks := LineDiff([]byte(old), []byte(new), 1)
+	require.Equal(t, []DiffHunk{
+		{
+			OldStart: 2, OldLines: 3, NewStart: 2, NewLines: 3,
+			Lines: []DiffLine{
+				{Type: DiffContext, Text: "two"},
+				{Type: DiffRemoved, Text: "three"},
+				{Type: DiffAdded, Text: "THREE"},
+				{Type: DiffContext, Text: "four"},
+			},
+		},
+		{
+			OldStart: 10, OldLines: 1, NewStart: 10, NewLines: 2,
+			Lines: []DiffLine{
+				{Type: DiffContext, Text: "ten"},
+				{Type: DiffAdded, Text: "eleven"},
+			},
+		},
+	}, hunks)
+	require.Equal(t, "@@ -2,3 +2,3 @@\n two\n-three\n+THREE\n four\n@@ -10,1 +10,2 @@\n ten\n+eleven\n", FormatHunks(hunks))
+
+	// With enough context they are merged into one
+	hunks = LineDiff([]byte(old), []byte(new), 4)
+	require.Len(t, hunks, 1)
+	require.Contains(t, FormatHunks(hunks), "-three\n+THREE\n")
+	require.Contains(t, FormatHunks(hunks), "+eleven\n")
+
+	// Without context an insertion has an empty old range
+	hunks = LineDiff([]byte("a\nb\n"), []byte("a\nx\nb\n"), 0)
+	require.Len(t, hunks, 1)
+	require.Equal(t, 1, hunks[0].OldStart)
+	require.Equal(t, 0, hunks[0].OldLines)
+	require.Equal(t, 2, hunks[0].NewStart)
+	require.Equal(t, 1, hunks[0].NewLines)
+}
+
+func TestFormatHunksHTML(t *testing.T) {
+	hunks := LineDiff([]byte("a\n<b>\nc\n"), []byte("a\n<i>\nc\n"), 1)
+	require.Equal(t, `<table class="diff">
+<tr class="hunk"><td></td><td></td><td>@@ -1,3 +1,3 @@</td></tr>
+<tr class="context"><td>1</td><td>1</td><td>a</td></tr>
+<tr class="removed"><td>2</td><td></td><td>&lt;b&gt;</td></tr>
+<tr class="added"><td></td><td>2</td><td>&lt;i&gt;</td></tr>
+<tr class="context"><td>3</td><td>3</td><td>c</td></tr>
+</table>
+`, FormatHunksHTML(hunks))
+}
+
+func TestTruncateDetails(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	details := strings.Join(lines, "\n")
+
+	tests := []struct {
+		name     string
+		maxLines int
+		maxBytes int
+		expected string
+	}{
+		{
+			name:     "no limits",
+			expected: details,
+		},
+		{
+			name:     "within limits",
+			maxLines: 10,
+			maxBytes: 1000,
+			expected: details,
+		},
+		{
+			name:     "line limit",
+			maxLines: 3,
+			expected: "line 0\nline 1\nline 2\n[... truncated: 7 more lines (48 bytes), see the full diff ...]",
+		},
+		{
+			name:     "byte limit ends on a whole line",
+			maxBytes: 10,
+			expected: "line 0\n[... truncated: 9 more lines (62 bytes), see the full diff ...]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, truncateDetails(details, tc.maxLines, tc.maxBytes))
+		})
+	}
+}
+
+func TestDetectChangeCapsDetails(t *testing.T) {
+	var old, new strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&old, "line %d\n", i)
+		fmt.Fprintf(&new, "changed line %d\n", i)
+	}
+
+	config := DefaultConfig("http://example.com")
+	config.MaxDetailsLines = 10
+	m := NewMonitorWithConfig(config)
+
+	m.detectChange([]byte(old.String()))
+	changed, details, hunks := m.detectChange([]byte(new.String()))
+	require.True(t, changed)
+	require.Equal(t, 11, strings.Count(details, "\n")+1)
+	require.Contains(t, details, "[... truncated:")
+
+	// The complete diff is kept separately
+	diff := FormatHunks(hunks)
+	require.Equal(t, 100, strings.Count(diff, "\n-line"))
+	require.Equal(t, 100, strings.Count(diff, "\n+changed line"))
+	require.NotContains(t, diff, "truncated")
+}
+
+func TestChangeStats(t *testing.T) {
+	var body atomic.Value
+	body.Store("<p>Price: 10</p>\n<p>Updated 2024-01-02T10:00:00Z</p>\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.IgnoreTimestamps = true
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	body.Store("<p>Price: 12</p>\n<p>Updated 2024-01-03T11:00:00Z</p>\n")
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Positive(t, change.Latency)
+	require.Len(t, change.Hunks, 1)
+
+	// Hashes and lengths describe the content as compared, without the
+	// timestamps
+	filtered := m.prepare([]byte(body.Load().(string)))
+	require.NotContains(t, string(filtered), "2024")
+	hash := sha256.Sum256(filtered)
+	require.Equal(t, hex.EncodeToString(hash[:]), change.NewHash)
+	require.NotEqual(t, change.OldHash, change.NewHash)
+	require.Equal(t, int64(len(filtered)), change.NewLength)
+	require.Equal(t, change.OldLength, change.NewLength)
+	require.Equal(t, []string{"Ignore timestamps"}, change.FilteredBy)
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainPolicy limits how often the monitors of a domain, together, send
+// requests to it. It applies to the domain and all of its subdomains.
+type DomainPolicy struct {
+	// MinInterval is the shortest interval at which a monitor on the domain
+	// is checked. Monitors with a shorter interval are checked less often.
+	MinInterval time.Duration
+	// MaxParallel is the number of requests sent to the domain at the same
+	// time. Zero means no limit.
+	MaxParallel int
+	// Delay is the time between the starts of two requests to the domain
+	Delay time.Duration
+}
+
+// domainGate applies a domain policy to the requests of every monitor on the
+// domain
+type domainGate struct {
+	policy   DomainPolicy
+	parallel *checkLimiter
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newDomainGate creates the gate of a policy
+func newDomainGate(policy DomainPolicy) *domainGate {
+	parallel := newCheckLimiter()
+	parallel.setLimit(policy.MaxParallel)
+	return &domainGate{policy: policy, parallel: parallel}
+}
+
+// reserve returns how long a request starting at now must wait for the
+// previous request to the domain to be Delay ago, and reserves its start time
+func (g *domainGate) reserve(now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	start := now
+	if g.next.After(now) {
+		start = g.next
+	}
+	g.next = start.Add(g.policy.Delay)
+
+	return start.Sub(now)
+}
+
+// matchDomain returns the gate of the most specific domain that the host of
+// rawURL is or is a subdomain of, or nil if there is none
+func matchDomain(gates map[string]*domainGate, rawURL string) *domainGate {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for host != "" {
+		if gate, ok := gates[host]; ok {
+			return gate
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+
+	return nil
+}
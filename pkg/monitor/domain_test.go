@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchDomain(t *testing.T) {
+	example := newDomainGate(DomainPolicy{})
+	news := newDomainGate(DomainPolicy{})
+	gates := map[string]*domainGate{"example.com": example, "news.example.com": news}
+
+	require.Same(t, example, matchDomain(gates, "https://example.com/a"))
+	require.Same(t, example, matchDomain(gates, "https://WWW.Example.com:8443/a"))
+	require.Same(t, news, matchDomain(gates, "https://eu.news.example.com/"))
+	require.Nil(t, matchDomain(gates, "https://example.org/"))
+	require.Nil(t, matchDomain(gates, "https://notexample.com/"))
+}
+
+func TestDomainGateReserve(t *testing.T) {
+	gate := newDomainGate(DomainPolicy{Delay: time.Second * 5})
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	require.Zero(t, gate.reserve(now))
+	require.Equal(t, time.Second*5, gate.reserve(now))
+	require.Equal(t, time.Second*8, gate.reserve(now.Add(time.Second*2)))
+	require.Zero(t, gate.reserve(now.Add(time.Minute)))
+}
+
+func TestManagerDomainPolicy(t *testing.T) {
+	var mu sync.Mutex
+	active, peak, requests := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		requests++
+		peak = max(peak, active)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond * 10)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	manager.SetDomainPolicy("127.0.0.1", DomainPolicy{MinInterval: time.Hour, MaxParallel: 1})
+
+	var monitors []*Monitor
+	for i := 0; i < 3; i++ {
+		config := DefaultConfig(fmt.Sprintf("%s/page/%d", server.URL, i))
+		config.Interval = time.Millisecond * 5
+		m, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+		monitors = append(monitors, m)
+	}
+
+	changes := manager.Start()
+	go func() {
+		for range changes {
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests == 3
+	}, time.Second*5, time.Millisecond)
+
+	// Requests were sent one at a time, and the short interval was raised
+	time.Sleep(time.Millisecond * 50)
+	mu.Lock()
+	require.Equal(t, 1, peak)
+	require.Equal(t, 3, requests)
+	mu.Unlock()
+	require.Equal(t, time.Hour, monitors[0].GetConfig().Interval)
+
+	manager.Stop()
+}
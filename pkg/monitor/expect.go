@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrExpectation is returned when the first fetch of a monitor doesn't meet
+// Config.Expect, so the monitor isn't added
+var ErrExpectation = errors.New("first check doesn't meet the expectation")
+
+// Expectation is what the first fetch of a monitor must find for
+// Manager.AddMonitorWithConfig to add it, see Config.Expect
+type Expectation struct {
+	// Status lists the accepted status codes. Empty accepts any status that
+	// isn't an error, see Config.ExpectedStatus.
+	Status []int
+	// Content are conditions the content must all meet
+	Content []Condition
+}
+
+// ParseExpectation parses an expectation written as a list of
+// "status:200,204" for the accepted status codes and conditions the content
+// must meet, see ParseCondition
+func ParseExpectation(specs []string) (*Expectation, error) {
+	expect := &Expectation{}
+	for _, spec := range specs {
+		if codes, found := strings.CutPrefix(spec, "status:"); found {
+			for _, code := range strings.Split(codes, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(code))
+				if err != nil {
+					return nil, fmt.Errorf("invalid expected status code '%s'", code)
+				}
+				expect.Status = append(expect.Status, n)
+			}
+			continue
+		}
+		condition, err := ParseCondition(spec)
+		if err != nil {
+			return nil, err
+		}
+		expect.Content = append(expect.Content, condition)
+	}
+	if err := expect.Validate(); err != nil {
+		return nil, err
+	}
+	return expect, nil
+}
+
+// Validate checks that the status codes are valid HTTP status codes
+func (e *Expectation) Validate() error {
+	for _, code := range e.Status {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid expected status code %d", code)
+		}
+	}
+	return nil
+}
+
+// check returns an error wrapping ErrExpectation if a response with the
+// status code and content doesn't meet the expectation
+func (e *Expectation) check(statusCode int, content []byte) error {
+	if len(e.Status) > 0 && !slices.Contains(e.Status, statusCode) {
+		return fmt.Errorf("%w: status %d, expected %s", ErrExpectation, statusCode, joinCodes(e.Status))
+	}
+	for _, condition := range e.Content {
+		if met, _ := condition.Met(content); !met {
+			return fmt.Errorf("%w: content doesn't have %s", ErrExpectation, condition.Description())
+		}
+	}
+	return nil
+}
+
+// joinCodes lists status codes, e.g. "200 or 204"
+func joinCodes(codes []int) string {
+	s := fmt.Sprint(codes[0])
+	for i, code := range codes[1:] {
+		if i == len(codes)-2 {
+			s += fmt.Sprintf(" or %d", code)
+		} else {
+			s += fmt.Sprintf(", %d", code)
+		}
+	}
+	return s
+}
+
+// Verify fetches the URL once, retrying on failure, and checks the response
+// against Config.Expect. It doesn't change the baseline of the monitor, so
+// that an error page isn't taken for the content to compare with. An error
+// wrapping ErrExpectation is returned if the fetch failed or the response
+// doesn't meet the expectation.
+func (m *Monitor) Verify() error {
+	content, change, err := m.fetch(nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExpectation, err)
+	}
+	if m.config.Expect == nil {
+		return nil
+	}
+	return m.config.Expect.check(change.StatusCode, content)
+}
+
+// verify checks the first fetch of a monitor that isn't added yet against
+// Config.Expect, within the manager's limits. A monitor that fails it is
+// stopped.
+func (m *Manager) verify(monitor *Monitor) error {
+	m.mu.RLock()
+	m.share(monitor)
+	m.mu.RUnlock()
+
+	err := monitor.Verify()
+	if err != nil {
+		monitor.Stop()
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerExpect(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/moved":
+			w.WriteHeader(http.StatusNonAuthoritativeInfo)
+			w.Write([]byte("Registration is open"))
+		default:
+			w.Write([]byte("Registration is open"))
+		}
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	defer manager.Stop()
+
+	add := func(path string, expect *Expectation) error {
+		config := DefaultConfig(server.URL + path)
+		config.RetryCount = 0
+		config.Expect = expect
+		_, err := manager.AddMonitorWithConfig(config)
+		return err
+	}
+
+	err := add("/down", &Expectation{})
+	require.ErrorIs(t, err, ErrExpectation)
+	require.ErrorContains(t, err, "unexpected status code: 503")
+
+	err = add("/moved", &Expectation{Status: []int{200, 204}})
+	require.ErrorIs(t, err, ErrExpectation)
+	require.ErrorContains(t, err, "status 203, expected 200 or 204")
+
+	err = add("/other", &Expectation{Content: []Condition{NewTextCondition("Sold out")}})
+	require.ErrorIs(t, err, ErrExpectation)
+	require.ErrorContains(t, err, `content doesn't have text "Sold out"`)
+
+	require.Error(t, add("/", &Expectation{Status: []int{42}}))
+	require.Empty(t, manager.ListMonitors())
+
+	// The fetch of the expectation doesn't set the baseline
+	require.NoError(t, add("/", &Expectation{Status: []int{200}, Content: []Condition{NewTextCondition("Registration")}}))
+	m, err := manager.GetMonitor(server.URL + "/")
+	require.NoError(t, err)
+	require.Empty(t, m.ContentHash())
+	require.Equal(t, int64(4), calls.Load())
+}
+
+func TestParseExpectation(t *testing.T) {
+	expect, err := ParseExpectation([]string{"status:200, 204", "Registration", "regex:open|closed"})
+	require.NoError(t, err)
+	require.Equal(t, []int{200, 204}, expect.Status)
+	require.Len(t, expect.Content, 2)
+	require.Equal(t, `text "Registration"`, expect.Content[0].Description())
+
+	_, err = ParseExpectation([]string{"status:ok"})
+	require.ErrorContains(t, err, "invalid expected status code 'ok'")
+	_, err = ParseExpectation([]string{"status:1000"})
+	require.ErrorContains(t, err, "invalid expected status code 1000")
+	_, err = ParseExpectation([]string{"regex:("})
+	require.Error(t, err)
+}
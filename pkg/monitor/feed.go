@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FeedEntry is an entry of an RSS or Atom feed found with MethodFeed
+type FeedEntry struct {
+	// ID is the GUID or Atom ID of the entry, or its link or title if it
+	// has none
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	Link  string `json:"link,omitempty"`
+	// Author is the author of the entry, or the sender of a message
+	Author string `json:"author,omitempty"`
+	// Published is nil if the feed gives no date or one that can't be
+	// parsed
+	Published *time.Time `json:"published,omitempty"`
+}
+
+// ErrNotFeed is returned for content that is neither an RSS nor an Atom
+// feed
+var ErrNotFeed = errors.New("content is not an RSS or Atom feed")
+
+// maxSeenEntries bounds the entry IDs a monitor remembers. Once exceeded,
+// the IDs of entries no longer in the feed are forgotten.
+const maxSeenEntries = 1000
+
+// feedDocument holds the parts of RSS 2.0, RSS 1.0 and Atom documents
+// that describe entries. Elements are matched by local name, so namespaced
+// elements such as dc:date are found too.
+type feedDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// Items are the entries of RSS 1.0, which are siblings of the channel
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID    string `xml:"guid"`
+	About   string `xml:"about,attr"`
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"`
+	Author  string `xml:"author"`
+	Creator string `xml:"creator"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Author    struct {
+		Name  string `xml:"name"`
+		Email string `xml:"email"`
+	} `xml:"author"`
+}
+
+// feedDateLayouts are the date formats found in feeds, RFC 822 variants
+// for RSS and RFC 3339 for Atom and Dublin Core dates
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// ParseFeed parses an RSS or Atom feed and returns its entries in the
+// order of the document
+func ParseFeed(content []byte) ([]FeedEntry, error) {
+	var doc feedDocument
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	// Feeds declare all kinds of encodings; the entry fields are compared,
+	// not shown byte for byte, so read them as they are
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFeed, err)
+	}
+
+	var entries []FeedEntry
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		for _, item := range append(doc.Channel.Items, doc.Items...) {
+			entries = append(entries, item.entry())
+		}
+	case "feed":
+		for _, entry := range doc.Entries {
+			entries = append(entries, entry.entry())
+		}
+	default:
+		return nil, ErrNotFeed
+	}
+	return entries, nil
+}
+
+func (item rssItem) entry() FeedEntry {
+	return FeedEntry{
+		ID:        firstNonEmpty(item.GUID, item.About, item.Link, item.Title),
+		Title:     strings.TrimSpace(item.Title),
+		Link:      strings.TrimSpace(item.Link),
+		Author:    firstNonEmpty(item.Creator, item.Author),
+		Published: parseFeedDate(firstNonEmpty(item.PubDate, item.Date)),
+	}
+}
+
+func (entry atomEntry) entry() FeedEntry {
+	var link string
+	for _, l := range entry.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			link = l.Href
+			break
+		}
+	}
+	if link == "" && len(entry.Links) > 0 {
+		link = entry.Links[0].Href
+	}
+
+	return FeedEntry{
+		ID:        firstNonEmpty(entry.ID, link, entry.Title),
+		Title:     strings.TrimSpace(entry.Title),
+		Link:      strings.TrimSpace(link),
+		Author:    firstNonEmpty(entry.Author.Name, entry.Author.Email),
+		Published: parseFeedDate(firstNonEmpty(entry.Published, entry.Updated)),
+	}
+}
+
+// firstNonEmpty returns the first value that isn't blank, trimmed
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseFeedDate parses a date in one of feedDateLayouts, or returns nil
+func parseFeedDate(value string) *time.Time {
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// describe returns the details of a change reporting the entry
+func (e FeedEntry) describe() string {
+	details := "New entry: " + firstNonEmpty(e.Title, e.ID)
+	if e.Author != "" {
+		details += "\nBy " + e.Author
+	}
+	if e.Link != "" {
+		details += "\n" + e.Link
+	}
+	if e.Published != nil {
+		details += "\nPublished " + e.Published.Format(time.RFC3339)
+	}
+	return details
+}
+
+// detectFeedChange finds the entries that weren't in the feed at previous
+// checks, see MethodFeed. They are returned oldest first, assuming the feed
+// lists the newest entries first as feeds do.
+func (m *Monitor) detectFeedChange(entries []FeedEntry) (bool, string, []FeedEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	first := m.seenEntries == nil
+	if first {
+		m.seenEntries = make(map[string]bool, len(entries))
+	} else if len(m.seenEntries) > maxSeenEntries {
+		// Forget the entries that left the feed
+		kept := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			if m.seenEntries[entry.ID] {
+				kept[entry.ID] = true
+			}
+		}
+		m.seenEntries = kept
+	}
+
+	var added []FeedEntry
+	for _, entry := range entries {
+		if !m.seenEntries[entry.ID] {
+			m.seenEntries[entry.ID] = true
+			added = append(added, entry)
+		}
+	}
+	if first || len(added) == 0 {
+		return false, "", nil
+	}
+	slices.Reverse(added)
+
+	details := fmt.Sprintf("%d new entries", len(added))
+	if len(added) == 1 {
+		details = "1 new entry"
+	}
+	for _, entry := range added {
+		details += "\n- " + firstNonEmpty(entry.Title, entry.ID)
+	}
+	return true, details, added
+}
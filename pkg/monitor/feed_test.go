@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRSS = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Example</title>
+    <atom:link href="https://example.com/feed.xml" rel="self"/>
+    <lastBuildDate>%s</lastBuildDate>
+    %s
+  </channel>
+</rss>`
+
+func rssItem1(n string) string {
+	return `<item><guid>item-` + n + `</guid><title>Story ` + n + `</title><link>https://example.com/` + n + `</link><pubDate>Mon, 01 Jul 2024 09:0` + n + `:00 GMT</pubDate></item>`
+}
+
+func TestParseFeed(t *testing.T) {
+	entries, err := ParseFeed([]byte(strings.Replace(strings.Replace(testRSS, "%s", "Mon, 01 Jul 2024 09:00:00 GMT", 1), "%s", rssItem1("2")+rssItem1("1"), 1)))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "item-2", entries[0].ID)
+	require.Equal(t, "Story 2", entries[0].Title)
+	require.Equal(t, "https://example.com/2", entries[0].Link)
+	require.Equal(t, time.Date(2024, 7, 1, 9, 2, 0, 0, time.UTC), entries[0].Published.UTC())
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom">
+  <updated>2024-07-01T10:00:00Z</updated>
+  <entry>
+    <id>tag:example.com,2024:1</id>
+    <title>Release 1.0</title>
+    <link rel="enclosure" href="https://example.com/1.0.tar.gz"/>
+    <link href="https://example.com/releases/1.0"/>
+    <published>2024-07-01T09:00:00+02:00</published>
+  </entry>
+</feed>`
+	entries, err = ParseFeed([]byte(atom))
+	require.NoError(t, err)
+	require.Equal(t, "tag:example.com,2024:1", entries[0].ID)
+	require.Equal(t, "https://example.com/releases/1.0", entries[0].Link)
+	require.Equal(t, time.Date(2024, 7, 1, 7, 0, 0, 0, time.UTC), entries[0].Published.UTC())
+
+	rdf := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <item rdf:about="https://example.com/a"><title>A</title><dc:date>bogus</dc:date></item>
+</rdf:RDF>`
+	entries, err = ParseFeed([]byte(rdf))
+	require.NoError(t, err)
+	require.Equal(t, []FeedEntry{{ID: "https://example.com/a", Title: "A"}}, entries)
+
+	_, err = ParseFeed([]byte(`<html><body>not a feed</body></html>`))
+	require.ErrorIs(t, err, ErrNotFeed)
+	_, err = ParseFeed([]byte(`{"items": []}`))
+	require.ErrorIs(t, err, ErrNotFeed)
+}
+
+// feedServer serves the given item lists in turn, each with a new build
+// date, and the last one from then on
+func feedServer(t *testing.T, items ...string) *httptest.Server {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(calls.Add(1)) - 1
+		buildDate := time.Date(2024, 7, 1, 9, i, 0, 0, time.UTC).Format(time.RFC1123)
+		feed := strings.Replace(testRSS, "%s", buildDate, 1)
+		w.Write([]byte(strings.Replace(feed, "%s", items[min(i, len(items)-1)], 1)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFeedMethod(t *testing.T) {
+	server := feedServer(t, rssItem1("1"), rssItem1("1"), rssItem1("3")+rssItem1("2")+rssItem1("1"))
+	config := DefaultConfig(server.URL)
+	config.Method = MethodFeed
+	m := NewMonitorWithConfig(config)
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "a rebuilt feed without new entries is not a change")
+
+	go m.performCheck()
+	for _, n := range []string{"2", "3"} {
+		change := <-m.changes
+		require.True(t, change.HasChanged)
+		require.Equal(t, "item-"+n, change.Entry.ID)
+		require.Equal(t, "Story "+n, change.Entry.Title)
+		require.Equal(t, "New entry: Story "+n+"\nhttps://example.com/"+n+"\nPublished 2024-07-01T09:0"+n+":00Z", change.Details)
+	}
+}
+
+func TestFeedMethodNotFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html></html>`))
+	}))
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodFeed
+	config.RetryCount = 0
+	change := NewMonitorWithConfig(config).Check()
+	require.Equal(t, EventError, change.Event)
+	require.Contains(t, change.Error, ErrNotFeed.Error())
+}
+
+func TestFeedSeenEntriesBounded(t *testing.T) {
+	m := NewMonitorWithConfig(DefaultConfig("https://example.com/feed.xml"))
+
+	entries := make([]FeedEntry, maxSeenEntries+1)
+	for i := range entries {
+		entries[i] = FeedEntry{ID: fmt.Sprintf("item-%d", i)}
+	}
+	m.detectFeedChange(entries)
+
+	// Entries still in the feed are remembered, the others forgotten
+	changed, _, added := m.detectFeedChange([]FeedEntry{{ID: "new"}, {ID: "item-0"}})
+	require.True(t, changed)
+	require.Equal(t, []FeedEntry{{ID: "new"}}, added)
+	require.Len(t, m.seenEntries, 2)
+}
+
+func TestParseFeedAuthor(t *testing.T) {
+	entries, err := ParseFeed([]byte(`<rss xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+<item><guid>a</guid><dc:creator>Alice</dc:creator></item>
+<item><guid>b</guid><author>bob@example.com (Bob)</author></item>
+</channel></rss>`))
+	require.NoError(t, err)
+	require.Equal(t, "Alice", entries[0].Author)
+	require.Equal(t, "bob@example.com (Bob)", entries[1].Author)
+
+	entries, err = ParseFeed([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>c</id><author><name>Carol</name></author></entry></feed>`))
+	require.NoError(t, err)
+	require.Equal(t, "Carol", entries[0].Author)
+}
@@ -0,0 +1,202 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	// Decoders of the other formats of MethodImage
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// pixelTolerance is the squared YIQ distance under which two pixels look the
+// same, as in pixelmatch with a threshold of 0.1. It hides JPEG artifacts and
+// anti-aliasing noise.
+const pixelTolerance = 35215 * 0.1 * 0.1
+
+// ImageChange describes a change found with MethodImage
+type ImageChange struct {
+	// Difference is the percentage of pixels that look different
+	Difference float64 `json:"difference"`
+	// OldSize and NewSize are the dimensions of the images, e.g. "640x480"
+	OldSize string `json:"old_size"`
+	NewSize string `json:"new_size"`
+	// DiffFile is the path of the visual diff written to
+	// Config.ImageDiffDir
+	DiffFile string `json:"diff_file,omitempty"`
+}
+
+// decodeImage decodes a PNG, JPEG or GIF response
+func decodeImage(content []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("not a PNG, JPEG or GIF image: %w", err)
+	}
+	return img, nil
+}
+
+// detectImageChange compares an image with the last reported one and reports
+// a change when more than Config.ImageThreshold percent of its pixels look
+// different. Smaller differences don't replace the baseline, so changes that
+// build up slowly are reported once they pass the threshold. With
+// Config.ImageDiffDir the changed pixels are drawn into a visual diff. A
+// change of the dimensions is always reported.
+func (m *Monitor) detectImageChange(img image.Image) (bool, string, *ImageChange) {
+	m.mu.Lock()
+	last := m.lastImage
+	if last == nil {
+		m.lastImage = img
+	}
+	m.mu.Unlock()
+	if last == nil {
+		return false, "", nil
+	}
+
+	difference, diff := compareImages(last, img, m.config.ImageDiffDir != "")
+	resized := last.Bounds().Size() != img.Bounds().Size()
+	if !resized && difference <= m.config.ImageThreshold {
+		return false, "", nil
+	}
+	m.mu.Lock()
+	m.lastImage = img
+	m.mu.Unlock()
+
+	change := &ImageChange{
+		Difference: difference,
+		OldSize:    formatImageSize(last),
+		NewSize:    formatImageSize(img),
+	}
+	details := fmt.Sprintf("Image changed: %.2f%% of pixels differ", difference)
+	if resized {
+		details += fmt.Sprintf(", size %s → %s", change.OldSize, change.NewSize)
+	}
+
+	if diff != nil {
+		// The change is reported even if its diff can't be written
+		if path, err := writeImageDiff(m.config.ImageDiffDir, m.config.URL, m.clock.Now(), diff); err != nil {
+			details += "\nDiff not written: " + err.Error()
+		} else {
+			change.DiffFile = path
+			details += "\nDiff: " + path
+		}
+	}
+	return true, details, change
+}
+
+// compareImages returns the percentage of pixels of b that look different
+// from a, and with draw an image of b, faded to gray, with those pixels in
+// red. Images of different sizes are compared on the larger one's grid,
+// each scaled to it.
+func compareImages(a, b image.Image, draw bool) (float64, *image.RGBA) {
+	ab, bb := a.Bounds(), b.Bounds()
+	width, height := max(ab.Dx(), bb.Dx()), max(ab.Dy(), bb.Dy())
+	if width == 0 || height == 0 {
+		return 0, nil
+	}
+
+	var diff *image.RGBA
+	if draw {
+		diff = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	sample := func(img image.Image, bounds image.Rectangle, x, y int) color.Color {
+		return img.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height)
+	}
+
+	changed := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pa, pb := sample(a, ab, x, y), sample(b, bb, x, y)
+			different := colorDistance(pa, pb) > pixelTolerance
+			if different {
+				changed++
+			}
+			if diff != nil {
+				if different {
+					diff.Set(x, y, color.RGBA{R: 255, A: 255})
+				} else {
+					// Unchanged pixels are kept as context, faded towards white
+					gray := uint8(255 - (255-luma(pb))/10)
+					diff.Set(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 255})
+				}
+			}
+		}
+	}
+	return float64(changed) * 100 / float64(width*height), diff
+}
+
+// colorDistance returns the squared perceptual distance of two colors in
+// YIQ space, after blending them onto white so transparent pixels compare
+// by how they look
+func colorDistance(a, b color.Color) float64 {
+	ar, ag, ab := blendWhite(a)
+	br, bg, bb := blendWhite(b)
+	y := rgbToY(ar, ag, ab) - rgbToY(br, bg, bb)
+	i := rgbToI(ar, ag, ab) - rgbToI(br, bg, bb)
+	q := rgbToQ(ar, ag, ab) - rgbToQ(br, bg, bb)
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+// blendWhite returns the 8-bit channels of c drawn onto white
+func blendWhite(c color.Color) (float64, float64, float64) {
+	r, g, b, a := c.RGBA()
+	blend := func(v uint32) float64 {
+		// RGBA returns alpha-premultiplied 16-bit channels
+		return float64(v+0xffff-a) / 257
+	}
+	return blend(r), blend(g), blend(b)
+}
+
+func rgbToY(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func rgbToI(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func rgbToQ(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+// luma returns the brightness of c drawn onto white
+func luma(c color.Color) float64 {
+	return rgbToY(blendWhite(c))
+}
+
+// formatImageSize formats the dimensions of an image, e.g. "640x480"
+func formatImageSize(img image.Image) string {
+	size := img.Bounds().Size()
+	return fmt.Sprintf("%dx%d", size.X, size.Y)
+}
+
+// unsafeFileChars matches characters that are replaced in diff file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeImageDiff writes a visual diff as a PNG into dir, named after the URL,
+// with a hash of it for uniqueness, and the time of the check. It returns
+// the path of the file.
+func writeImageDiff(dir, rawURL string, now time.Time, diff image.Image) (string, error) {
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+	if len(name) > 60 {
+		name = name[:60]
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%s.png", name, hex.EncodeToString(sum[:4]), now.UTC().Format("20060102T150405.000")))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, diff); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, buf.Bytes(), 0644)
+}
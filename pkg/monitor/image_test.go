@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// chart returns a white image of width x height with the first bars columns
+// filled in blue
+func chart(width, height, bars int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			if x < bars {
+				c = color.RGBA{B: 200, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// encodeImage encodes img as a PNG or JPEG
+func encodeImage(t *testing.T, img image.Image, format string) []byte {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	} else {
+		require.NoError(t, png.Encode(&buf, img))
+	}
+	return buf.Bytes()
+}
+
+func TestCompareImages(t *testing.T) {
+	difference, diff := compareImages(chart(10, 10, 2), chart(10, 10, 2), false)
+	require.Zero(t, difference)
+	require.Nil(t, diff)
+
+	difference, diff = compareImages(chart(10, 10, 2), chart(10, 10, 3), true)
+	require.Equal(t, 10.0, difference)
+	require.Equal(t, color.RGBA{R: 255, A: 255}, diff.At(2, 5))
+	require.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, diff.At(9, 5))
+
+	// Slight color shifts, e.g. from anti-aliasing, look the same
+	shifted := chart(10, 10, 2)
+	shifted.Set(5, 5, color.RGBA{R: 250, G: 252, B: 255, A: 255})
+	difference, _ = compareImages(chart(10, 10, 2), shifted, false)
+	require.Zero(t, difference)
+
+	// Transparent pixels look like the white page they are drawn on
+	transparent := chart(10, 10, 2)
+	for x := 2; x < 10; x++ {
+		transparent.Set(x, 0, color.RGBA{})
+	}
+	difference, _ = compareImages(chart(10, 10, 2), transparent, false)
+	require.Zero(t, difference)
+
+	// Images of other sizes are scaled to the larger one
+	difference, _ = compareImages(chart(10, 10, 2), chart(20, 20, 4), false)
+	require.Zero(t, difference)
+}
+
+func TestImageChange(t *testing.T) {
+	var body atomic.Value
+	body.Store(encodeImage(t, chart(100, 50, 40), "png"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body.Load().([]byte))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "diffs")
+	config := DefaultConfig(server.URL + "/chart.png")
+	config.Method = MethodImage
+	config.RetryCount = 0
+	config.ImageThreshold = 1
+	config.ImageDiffDir = dir
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+
+	// A JPEG of the same chart only differs by compression artifacts
+	body.Store(encodeImage(t, chart(100, 50, 40), "jpeg"))
+	require.False(t, m.Check().HasChanged)
+
+	// Half a percent of the pixels is under the threshold and keeps the
+	// baseline
+	img := chart(100, 50, 40)
+	for y := 0; y < 25; y++ {
+		img.Set(99, y, color.Black)
+	}
+	body.Store(encodeImage(t, img, "png"))
+	require.False(t, m.Check().HasChanged)
+
+	body.Store(encodeImage(t, chart(100, 50, 42), "png"))
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, 2.0, change.Image.Difference)
+
+	body.Store(encodeImage(t, chart(100, 50, 47), "png"))
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, 5.0, change.Image.Difference)
+	require.Equal(t, "100x50", change.Image.NewSize)
+	require.Contains(t, change.Details, "Image changed: 5.00% of pixels differ\nDiff: "+dir)
+
+	file, err := os.Open(change.Image.DiffFile)
+	require.NoError(t, err)
+	defer file.Close()
+	diff, err := png.Decode(file)
+	require.NoError(t, err)
+	require.Equal(t, image.Pt(100, 50), diff.Bounds().Size())
+
+	// A new size is always a change
+	body.Store(encodeImage(t, chart(200, 100, 90), "png"))
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Contains(t, change.Details, "size 100x50 → 200x100")
+
+	// Content that isn't an image fails the check
+	body.Store([]byte("<html></html>"))
+	change = m.Check()
+	require.Contains(t, change.Error, "not a PNG, JPEG or GIF image")
+}
+
+func TestImageOptions(t *testing.T) {
+	manager := NewManager()
+	config := DefaultConfig("https://example.com/chart.png")
+	config.ImageDiffDir = "diffs"
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrImageOptions)
+
+	config.Method = MethodImage
+	config.ImageThreshold = 101
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrImageThreshold)
+}
@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ValidateLabels checks that label keys can be selected: they must not be
+// empty or contain whitespace or the separators = ! ,
+func ValidateLabels(labels map[string]string) error {
+	for key := range labels {
+		if key == "" || strings.ContainsAny(key, " \t=!,") {
+			return fmt.Errorf("invalid label key '%s'", key)
+		}
+	}
+	return nil
+}
+
+// labelRequirement is one requirement of a LabelSelector
+type labelRequirement struct {
+	key   string
+	value string
+	// exists is set for a requirement on the key alone
+	exists bool
+	negate bool
+}
+
+// LabelSelector selects monitors by their Config.Labels. All of its
+// requirements must be met; a selector without requirements matches every
+// monitor.
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+// ParseLabelSelector parses a comma-separated list of requirements:
+// "env=staging" for a label with a value, "env!=staging" for a label that
+// is missing or has another value, "env" for a label that is set and "!env"
+// for one that isn't
+func ParseLabelSelector(spec string) (LabelSelector, error) {
+	var selector LabelSelector
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var req labelRequirement
+		if key, value, found := strings.Cut(part, "!="); found {
+			req = labelRequirement{key: key, value: value, negate: true}
+		} else if key, value, found := strings.Cut(part, "="); found {
+			req = labelRequirement{key: key, value: value}
+		} else if key, found := strings.CutPrefix(part, "!"); found {
+			req = labelRequirement{key: key, exists: true, negate: true}
+		} else {
+			req = labelRequirement{key: part, exists: true}
+		}
+
+		req.key, req.value = strings.TrimSpace(req.key), strings.TrimSpace(req.value)
+		if err := ValidateLabels(map[string]string{req.key: ""}); err != nil {
+			return LabelSelector{}, fmt.Errorf("invalid label selector '%s': %w", spec, err)
+		}
+		selector.requirements = append(selector.requirements, req)
+	}
+	return selector, nil
+}
+
+// Matches reports whether labels meet all requirements of the selector
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, req := range s.requirements {
+		value, set := labels[req.key]
+		met := set
+		if !req.exists {
+			met = set && value == req.value
+		}
+		if met == req.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in the syntax of ParseLabelSelector
+func (s LabelSelector) String() string {
+	parts := make([]string, 0, len(s.requirements))
+	for _, req := range s.requirements {
+		switch {
+		case req.exists && req.negate:
+			parts = append(parts, "!"+req.key)
+		case req.exists:
+			parts = append(parts, req.key)
+		case req.negate:
+			parts = append(parts, req.key+"!="+req.value)
+		default:
+			parts = append(parts, req.key+"="+req.value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// MonitorsByLabel returns the URLs of the monitors whose labels match the
+// selector, in alphabetical order
+func (m *Manager) MonitorsByLabel(selector LabelSelector) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.byLabelLocked(selector)
+}
+
+// byLabelLocked returns the URLs of the monitors matching the selector, in
+// alphabetical order. m.mu must be held.
+func (m *Manager) byLabelLocked(selector LabelSelector) []string {
+	var urls []string
+	for url, monitor := range m.monitors {
+		if selector.Matches(monitor.config.Labels) {
+			urls = append(urls, url)
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// StopByLabel stops all monitors whose labels match the selector and
+// returns their URLs
+func (m *Manager) StopByLabel(selector LabelSelector) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	urls := m.byLabelLocked(selector)
+	for _, url := range urls {
+		m.monitors[url].Stop()
+	}
+	return urls
+}
+
+// PauseByLabel suspends checks of all monitors whose labels match the
+// selector and returns their URLs
+func (m *Manager) PauseByLabel(selector LabelSelector) (urls []string, err error) {
+	defer m.persist(&err)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	urls = m.byLabelLocked(selector)
+	for _, url := range urls {
+		m.monitors[url].Pause()
+	}
+	return urls, nil
+}
+
+// ResumeByLabel resumes checks of all monitors whose labels match the
+// selector and returns their URLs
+func (m *Manager) ResumeByLabel(selector LabelSelector) (urls []string, err error) {
+	defer m.persist(&err)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	urls = m.byLabelLocked(selector)
+	for _, url := range urls {
+		m.monitors[url].Resume()
+	}
+	return urls, nil
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "staging", "team": "web"}
+
+	for spec, matches := range map[string]bool{
+		"":                     true,
+		"env=staging":          true,
+		"env=production":       false,
+		"env=staging,team=web": true,
+		"env=staging,team=api": false,
+		"env!=production":      true,
+		"region!=eu":           true,
+		"team":                 true,
+		"region":               false,
+		"!region":              true,
+		"!team":                false,
+	} {
+		selector, err := ParseLabelSelector(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, matches, selector.Matches(labels), spec)
+		require.Equal(t, spec, selector.String())
+	}
+
+	_, err := ParseLabelSelector("=staging")
+	require.ErrorContains(t, err, "invalid label key ''")
+	_, err = ParseLabelSelector("!")
+	require.Error(t, err)
+}
+
+func TestManagerByLabel(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	for url, env := range map[string]string{
+		"https://staging.example.com":   "staging",
+		"https://staging.example.org":   "staging",
+		"https://www.example.com":       "production",
+		"https://unlabeled.example.com": "",
+	} {
+		config := DefaultConfig(url)
+		if env != "" {
+			config.Labels = map[string]string{"env": env}
+		}
+		_, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+	}
+
+	config := DefaultConfig("https://example.net")
+	config.Labels = map[string]string{"env=prod": "x"}
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorContains(t, err, "invalid label key 'env=prod'")
+
+	staging, err := ParseLabelSelector("env=staging")
+	require.NoError(t, err)
+	want := []string{"https://staging.example.com", "https://staging.example.org"}
+	require.Equal(t, want, manager.MonitorsByLabel(staging))
+
+	urls, err := manager.PauseByLabel(staging)
+	require.NoError(t, err)
+	require.Equal(t, want, urls)
+	for _, url := range manager.ListMonitors() {
+		m, err := manager.GetMonitor(url)
+		require.NoError(t, err)
+		require.Equal(t, m.GetConfig().Labels["env"] == "staging", m.IsPaused(), url)
+	}
+
+	urls, err = manager.ResumeByLabel(staging)
+	require.NoError(t, err)
+	require.Equal(t, want, urls)
+
+	require.Equal(t, want, manager.StopByLabel(staging))
+	for _, url := range manager.ListMonitors() {
+		m, err := manager.GetMonitor(url)
+		require.NoError(t, err)
+		require.Equal(t, m.GetConfig().Labels["env"] == "staging", m.ctx.Err() != nil, url)
+	}
+}
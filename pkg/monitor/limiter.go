@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+)
+
+// checkLimiter limits the number of checks fetching at the same time. Checks
+// over the limit wait in line until a running check finishes.
+type checkLimiter struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	active  int
+	waiting int
+}
+
+// newCheckLimiter creates a limiter with no limit
+func newCheckLimiter() *checkLimiter {
+	l := &checkLimiter{}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// setLimit changes the number of concurrent checks. Zero means no limit.
+func (l *checkLimiter) setLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+// tryAcquire takes a slot if one is free without waiting
+func (l *checkLimiter) tryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.full() {
+		return false
+	}
+	l.active++
+	return true
+}
+
+// acquire waits for a free slot. It returns false if ctx is canceled first.
+func (l *checkLimiter) acquire(ctx context.Context) bool {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.cond.Broadcast()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.waiting++
+	for l.full() && ctx.Err() == nil {
+		l.cond.Wait()
+	}
+	l.waiting--
+
+	if ctx.Err() != nil {
+		return false
+	}
+	l.active++
+	return true
+}
+
+// release frees a slot taken by tryAcquire or acquire
+func (l *checkLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	l.cond.Broadcast()
+}
+
+// stats returns the number of running and waiting checks
+func (l *checkLimiter) stats() (active, waiting int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active, l.waiting
+}
+
+// full reports whether every slot is taken. The caller must hold l.mu.
+func (l *checkLimiter) full() bool {
+	return l.limit > 0 && l.active >= l.limit
+}
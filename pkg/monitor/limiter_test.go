@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLimiter(t *testing.T) {
+	l := newCheckLimiter()
+	l.setLimit(1)
+
+	require.True(t, l.tryAcquire())
+	require.False(t, l.tryAcquire())
+
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		_, waiting := l.stats()
+		return waiting == 1
+	}, time.Second, time.Millisecond)
+
+	l.release()
+	require.True(t, <-acquired)
+	active, waiting := l.stats()
+	require.Equal(t, 1, active)
+	require.Zero(t, waiting)
+
+	// Waiting stops when the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { acquired <- l.acquire(ctx) }()
+	cancel()
+	require.False(t, <-acquired)
+
+	// Raising the limit lets waiting checks through
+	go func() { acquired <- l.acquire(context.Background()) }()
+	l.setLimit(2)
+	require.True(t, <-acquired)
+}
+
+func TestCheckLimiterUnlimited(t *testing.T) {
+	l := newCheckLimiter()
+	for i := 0; i < 100; i++ {
+		require.True(t, l.tryAcquire())
+	}
+	active, _ := l.stats()
+	require.Equal(t, 100, active)
+}
@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// maxLoginResponseSize bounds the part of a login response searched for a
+// token
+const maxLoginResponseSize = 1 << 20
+
+// ErrLoginURL is returned for logins without an http or https URL
+var ErrLoginURL = errors.New("login requires an http or https URL")
+
+// Login is a request that signs a monitor in before its checks, e.g. a form
+// or JSON POST of credentials. The session it starts is kept in the cookies
+// it sets or, with Token, in a bearer token read from its JSON response and
+// sent in the Authorization header of the checks. A check answered with 401
+// Unauthorized logs in again and is retried once.
+type Login struct {
+	URL string
+	// Method defaults to POST. Body is sent with ContentType or, without it,
+	// as JSON if it is valid JSON and as a form otherwise.
+	Method      string
+	Body        string
+	ContentType string
+	Headers     map[string]string
+	// Token is the dotted path of the token in the response, e.g.
+	// "access_token" or "data.token"
+	Token string
+}
+
+// method returns the HTTP method of the login request
+func (l *Login) method() string {
+	if l.Method == "" {
+		return http.MethodPost
+	}
+	method, _ := ParseRequestMethod(l.Method)
+	return method
+}
+
+// ValidateLogin checks the URL and method of a login, if any
+func ValidateLogin(login *Login) error {
+	if login == nil {
+		return nil
+	}
+
+	u, err := url.Parse(login.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrLoginURL
+	}
+	method, err := ParseRequestMethod(login.Method)
+	if err != nil {
+		return err
+	}
+	if login.Body != "" && login.Method != "" && (method == http.MethodGet || method == http.MethodHead) {
+		return ErrBodyMethod
+	}
+	return nil
+}
+
+// session is the state of a monitor's login
+type session struct {
+	mu       sync.Mutex
+	loggedIn bool
+	token    string
+}
+
+// ensureLogin logs the monitor in if it has a login and isn't logged in
+func (m *Monitor) ensureLogin() error {
+	if m.config.Login == nil {
+		return nil
+	}
+	m.session.mu.Lock()
+	defer m.session.mu.Unlock()
+	if m.session.loggedIn {
+		return nil
+	}
+	return m.loginLocked()
+}
+
+// relogin logs the monitor in again after its session expired
+func (m *Monitor) relogin() error {
+	m.session.mu.Lock()
+	defer m.session.mu.Unlock()
+	m.session.loggedIn = false
+	return m.loginLocked()
+}
+
+// logout makes the next check log in again
+func (m *Monitor) logout() {
+	m.session.mu.Lock()
+	defer m.session.mu.Unlock()
+	m.session.loggedIn = false
+	m.session.token = ""
+}
+
+// loginLocked sends the login request. Its cookies are kept in the
+// monitor's jar by the client. m.session.mu must be held.
+func (m *Monitor) loginLocked() error {
+	login := m.config.Login
+	loginBody, err := secret.Resolve(login.Body)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	headers, err := secret.ResolveMap(login.Headers)
+	if err != nil {
+		return fmt.Errorf("login failed: header %w", err)
+	}
+
+	var body io.Reader
+	if loginBody != "" {
+		body = strings.NewReader(loginBody)
+	}
+	req, err := http.NewRequestWithContext(m.ctx, login.method(), login.URL, body)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	customhttp.AddHeaders(req, headers, version.UserAgent())
+	if body != nil && (login.ContentType != "" || req.Header.Get("Content-Type") == "") {
+		req.Header.Set("Content-Type", bodyContentType(loginBody, login.ContentType))
+	}
+	m.addBaggage(req)
+
+	resp, err := m.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("login failed: status code %d", resp.StatusCode)
+	}
+
+	token := ""
+	if login.Token != "" {
+		var doc any
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxLoginResponseSize)).Decode(&doc); err != nil {
+			return fmt.Errorf("login failed: response is not JSON: %w", err)
+		}
+		value, found := lookupJSON(doc, login.Token)
+		if token, _ = value.(string); !found || token == "" {
+			return fmt.Errorf("login failed: no token at '%s' in the response", login.Token)
+		}
+	}
+
+	m.session.loggedIn = true
+	m.session.token = token
+	return nil
+}
+
+// authorize adds the credentials of the monitor, if any, to req: its basic
+// auth or bearer token, replaced by the token of its login. Their secret
+// placeholders are resolved.
+func (m *Monitor) authorize(req *http.Request) error {
+	if auth := m.config.BasicAuth; auth != nil {
+		user, err := secret.Resolve(auth.User)
+		if err != nil {
+			return fmt.Errorf("basic auth: %w", err)
+		}
+		pass, err := secret.Resolve(auth.Pass)
+		if err != nil {
+			return fmt.Errorf("basic auth: %w", err)
+		}
+		req.SetBasicAuth(user, pass)
+	}
+	if m.config.BearerToken != "" {
+		token, err := secret.Resolve(m.config.BearerToken)
+		if err != nil {
+			return fmt.Errorf("bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if m.config.Login == nil {
+		return nil
+	}
+	m.session.mu.Lock()
+	token := m.session.token
+	m.session.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
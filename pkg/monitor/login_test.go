@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loginServer signs clients in on /login with a session cookie, or on /token
+// with a bearer token, and serves /page only to signed in clients. Sessions
+// expire when the server's generation changes.
+type loginServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	generation int
+	logins     int
+}
+
+func newLoginServer(t *testing.T) *loginServer {
+	s := &loginServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		session := fmt.Sprintf("s%d", s.generation)
+
+		switch r.URL.Path {
+		case "/login":
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || r.FormValue("password") != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			s.logins++
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+		case "/token":
+			var credentials map[string]string
+			if json.NewDecoder(r.Body).Decode(&credentials) != nil || credentials["password"] != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			s.logins++
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"token": session}})
+		case "/page":
+			cookie, err := r.Cookie("session")
+			if r.Header.Get("Authorization") != "Bearer "+session && (err != nil || cookie.Value != session) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("members only"))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// expire ends every session
+func (s *loginServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+}
+
+func TestLoginCookies(t *testing.T) {
+	server := newLoginServer(t)
+
+	config := DefaultConfig(server.URL + "/page")
+	config.Login = &Login{URL: server.URL + "/login", Body: "user=me&password=secret"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.Empty(t, m.Check().Error)
+	require.Empty(t, m.Check().Error)
+	require.Equal(t, 1, server.logins)
+
+	// An expired session logs in again and the check is retried
+	server.expire()
+	change := m.Check()
+	require.Empty(t, change.Error)
+	require.Equal(t, http.StatusOK, change.StatusCode)
+	require.Equal(t, 2, server.logins)
+}
+
+func TestLoginToken(t *testing.T) {
+	server := newLoginServer(t)
+
+	config := DefaultConfig(server.URL + "/page")
+	config.Login = &Login{URL: server.URL + "/token", Body: `{"user": "me", "password": "secret"}`, Token: "data.token"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.Empty(t, m.Check().Error)
+	server.expire()
+	require.Empty(t, m.Check().Error)
+	require.Equal(t, 2, server.logins)
+
+	// A token that isn't in the response fails the check
+	config.Login = &Login{URL: server.URL + "/token", Body: `{"password": "secret"}`, Token: "access_token"}
+	config.RetryCount = 0
+	m = NewMonitorWithConfig(config)
+	defer m.Stop()
+	require.Equal(t, "login failed: no token at 'access_token' in the response", m.Check().Error)
+}
+
+func TestLoginFailed(t *testing.T) {
+	server := newLoginServer(t)
+
+	config := DefaultConfig(server.URL + "/page")
+	config.Login = &Login{URL: server.URL + "/login", Body: "user=me&password=wrong"}
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.Equal(t, "login failed: status code 403", m.Check().Error)
+	require.Zero(t, server.logins)
+}
+
+func TestValidateLogin(t *testing.T) {
+	require.NoError(t, ValidateLogin(nil))
+	require.NoError(t, ValidateLogin(&Login{URL: "https://example.com/login", Body: "a=b"}))
+	require.ErrorIs(t, ValidateLogin(&Login{URL: "/login"}), ErrLoginURL)
+	require.ErrorIs(t, ValidateLogin(&Login{URL: "https://example.com/login", Method: "GET", Body: "a=b"}), ErrBodyMethod)
+	require.ErrorContains(t, ValidateLogin(&Login{URL: "https://example.com/login", Method: "FETCH"}), "unknown request method")
+
+	_, err := NewManager().AddMonitorWithConfig(&Config{URL: "https://example.com", Interval: DefaultConfig("").Interval, Login: &Login{URL: "ftp://example.com"}})
+	require.ErrorIs(t, err, ErrLoginURL)
+}
@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/imap"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// ErrMailbox is returned for settings a mailbox monitor can't honor
+var ErrMailbox = errors.New("mailbox monitors don't support the browser, representations, variants, pagination, append-only fetching, the status or tail method, request bodies, methods other than GET, login flows, bearer tokens or OAuth2")
+
+// validateMailbox checks that the settings of a monitor of an imap or imaps
+// URL can be applied
+func validateMailbox(config *Config) error {
+	if !imap.IsURL(config.URL) {
+		return nil
+	}
+	if _, err := imap.ParseURL(config.URL); err != nil {
+		return err
+	}
+	if config.Fetcher == FetcherBrowser || len(config.Representations) > 0 || len(config.Variants) > 0 || config.Pagination != nil ||
+		config.AppendOnly || config.Method == MethodStatus || config.Method == MethodTail || config.requestMethod() != http.MethodGet ||
+		config.Login != nil || config.BearerToken != "" || config.OAuth2 != nil {
+		return ErrMailbox
+	}
+	return nil
+}
+
+// mailboxFeed is the Atom feed a mailbox is presented as
+type mailboxFeed struct {
+	XMLName xml.Name       `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string         `xml:"title"`
+	ID      string         `xml:"id"`
+	Updated string         `xml:"updated"`
+	Entries []mailboxEntry `xml:"entry"`
+}
+
+type mailboxEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Link  struct {
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Author struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Published string `xml:"published,omitempty"`
+}
+
+// mailboxSource reads the messages of the mailbox at an imap or imaps URL
+type mailboxSource struct{}
+
+// fetch implements source.fetch
+func (mailboxSource) fetch(m *Monitor, variant map[string]string) ([]byte, Change, error) {
+	return m.fetchMailbox()
+}
+
+// close implements source.close
+func (mailboxSource) close() {}
+
+// fetchMailbox reads the messages of the mailbox at the monitor's URL, see
+// package imap, and returns them as an Atom feed, newest first, so that
+// MethodFeed reports every new message as a change of its own. The
+// credentials are those of the URL or of Config.BasicAuth.
+func (m *Monitor) fetchMailbox() ([]byte, Change, error) {
+	query, err := imap.ParseURL(m.config.URL)
+	if err != nil {
+		return nil, Change{}, err
+	}
+	if auth := m.config.BasicAuth; auth != nil {
+		if query.User, err = secret.Resolve(auth.User); err != nil {
+			return nil, Change{}, fmt.Errorf("basic auth user %w", err)
+		}
+		if query.Password, err = secret.Resolve(auth.Pass); err != nil {
+			return nil, Change{}, fmt.Errorf("basic auth password %w", err)
+		}
+	} else if query.Password, err = secret.Resolve(query.Password); err != nil {
+		return nil, Change{}, fmt.Errorf("password %w", err)
+	}
+	query.InsecureSkipVerify = m.config.TLS.InsecureSkipVerify
+
+	ctx := m.ctx
+	if timeout := m.ClientSettings().Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := m.clock.Now()
+	messages, err := imap.Read(ctx, query)
+	if err != nil {
+		return nil, Change{}, err
+	}
+
+	change := Change{
+		URL:         m.config.URL,
+		Timestamp:   m.clock.Now(),
+		ContentType: "application/atom+xml",
+		Latency:     m.clock.Now().Sub(start),
+		Baggage:     m.config.Baggage,
+	}
+	m.mu.Lock()
+	m.latency = change.Latency
+	m.mu.Unlock()
+
+	content, err := mailboxAtom(m.config.URL, messages)
+	return content, change, err
+}
+
+// mailboxAtom renders messages as an Atom feed. Entries are identified by
+// their Message-ID, or their UID if they have none, and link to the message
+// with an IMAP URL (RFC 5092).
+func mailboxAtom(rawURL string, messages []imap.Message) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	mailbox := strings.TrimPrefix(u.Path, "/")
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	base := u.Scheme + "://" + u.Host + "/" + mailbox
+
+	feed := mailboxFeed{Title: mailbox + " on " + u.Hostname(), ID: base, Updated: time.Time{}.Format(time.RFC3339)}
+	for _, message := range slices.Backward(messages) {
+		var entry mailboxEntry
+		entry.Link.Href = fmt.Sprintf("%s;UIDVALIDITY=%d/;UID=%d", base, message.UIDValidity, message.UID)
+		entry.ID = message.MessageID
+		if entry.ID == "" {
+			entry.ID = entry.Link.Href
+		}
+		entry.Title = message.Subject
+		if entry.Title == "" {
+			entry.Title = "(no subject)"
+		}
+		entry.Author.Name = message.From
+		if !message.Date.IsZero() {
+			entry.Published = message.Date.Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if len(messages) > 0 && !messages[len(messages)-1].Date.IsZero() {
+		feed.Updated = messages[len(messages)-1].Date.Format(time.RFC3339)
+	}
+
+	content, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
@@ -0,0 +1,55 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nemuizzz/hawkeye/pkg/imap/imaptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailbox(t *testing.T) {
+	server := imaptest.NewServer("me", "secret")
+	defer server.Close()
+	server.Deliver(imaptest.Message{MessageID: "1@shop", From: "orders@shop.example", Subject: "Order placed", Date: "Wed, 15 Jan 2025 10:30:00 +0000"})
+
+	config := DefaultConfig(server.URL() + "?search=" + `FROM%20%22shop%22`)
+	config.Method = MethodFeed
+	config.RetryCount = 0
+	config.BasicAuth = &BasicAuth{User: "me", Pass: "secret"}
+	config.RespectRobotsTxt = true
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+	require.Equal(t, mailboxSource{}, m.source)
+	// Mailboxes have no robots.txt
+	require.Nil(t, m.robots)
+
+	require.False(t, m.Check().HasChanged)
+
+	server.Deliver(imaptest.Message{MessageID: "2@news", From: "news@example.com", Subject: "Digest", Date: "Thu, 16 Jan 2025 08:00:00 +0000"})
+	server.Deliver(imaptest.Message{MessageID: "3@shop", From: "Shop <orders@shop.example>", Subject: "Order shipped", Date: "Thu, 16 Jan 2025 09:00:00 +0000"})
+	go m.performCheck()
+	change := <-m.changes
+	require.True(t, change.HasChanged)
+	require.Equal(t, "3@shop", change.Entry.ID)
+	require.Equal(t, "New entry: Order shipped\nBy Shop <orders@shop.example>\nimap://"+server.Addr+"/INBOX;UIDVALIDITY=7/;UID=3\nPublished 2025-01-16T09:00:00Z", change.Details)
+
+	// Messages are only examined
+	for _, command := range server.Commands() {
+		require.False(t, strings.HasPrefix(command, "SELECT") || strings.HasPrefix(command, "STORE"), command)
+	}
+}
+
+func TestMailboxValidation(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig("imaps://me@imap.example.com/INBOX")
+	config.Method = MethodStatus
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrMailbox)
+
+	config = DefaultConfig("imaps://me@imap.example.com/INBOX?limit=none")
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorContains(t, err, "limit must be a positive number")
+}
@@ -2,8 +2,17 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/browser"
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/robots"
 )
 
 // MonitorMap represents a map of URLs to Monitors
@@ -14,6 +23,40 @@ type MonitorGroup struct {
 	Name        string
 	Description string
 	Monitors    MonitorMap
+	// ShareCookies is set by Manager.ShareCookies
+	ShareCookies bool
+
+	jar http.CookieJar
+}
+
+// Health summarizes whether the monitors of a manager are running as
+// scheduled
+type Health struct {
+	Running  bool `json:"running"`
+	Monitors int  `json:"monitors"`
+	Paused   int  `json:"paused"`
+	// ActiveChecks is the number of checks fetching right now and
+	// QueuedChecks the number waiting for MaxConcurrentChecks to allow them
+	ActiveChecks        int `json:"active_checks"`
+	QueuedChecks        int `json:"queued_checks"`
+	MaxConcurrentChecks int `json:"max_concurrent_checks,omitempty"`
+	// Starting is the number of monitors whose first check is due but
+	// hasn't been done yet
+	Starting int `json:"starting"`
+	// Stalled lists the URLs of monitors whose run loop stopped waking up,
+	// e.g. because the consumer of the changes channel is stuck
+	Stalled []string `json:"stalled,omitempty"`
+}
+
+// OK reports whether the manager is running and no monitor is stalled
+func (h Health) OK() bool {
+	return h.Running && len(h.Stalled) == 0
+}
+
+// Ready reports whether the manager is healthy and every monitor that is due
+// has done its first check, so changes from now on are detected
+func (h Health) Ready() bool {
+	return h.OK() && h.Starting == 0
 }
 
 // Manager handles multiple monitors
@@ -24,6 +67,22 @@ type Manager struct {
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
+	running       bool
+	started       map[string]bool
+	noStagger     bool
+	maxChecks     int
+	limiter       *checkLimiter
+	domains       map[string]*domainGate
+	rates         *hostRateLimiter
+	robots        *robots.Cache
+	browser       *browser.Browser
+	archive       Archive
+	onCheck       func(Change)
+	forwarders    sync.WaitGroup
+	// store saves the state after every change, see SetStore. saveMu
+	// guards it and serializes saves.
+	store  Store
+	saveMu sync.Mutex
 }
 
 // NewManager creates a new Manager
@@ -35,11 +94,20 @@ func NewManager() *Manager {
 		changeChannel: make(chan Change),
 		ctx:           ctx,
 		cancel:        cancel,
+		started:       make(map[string]bool),
+		limiter:       newCheckLimiter(),
+		domains:       make(map[string]*domainGate),
+		rates:         newHostRateLimiter(),
+		robots:        robots.NewCache(),
+		browser:       browser.New(nil),
 	}
 }
 
-// AddMonitor adds a new monitor to the manager
-func (m *Manager) AddMonitor(monitor *Monitor) error {
+// AddMonitor adds a new monitor to the manager. If the manager is running the
+// monitor is started immediately and its changes are sent on the channel
+// returned by Start.
+func (m *Manager) AddMonitor(monitor *Monitor) (err error) {
+	defer m.persist(&err)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -49,34 +117,186 @@ func (m *Manager) AddMonitor(monitor *Monitor) error {
 	}
 
 	if _, exists := m.monitors[url]; exists {
-		return fmt.Errorf("monitor for URL '%s' already exists", url)
+		return fmt.Errorf("%w for URL '%s'", ErrMonitorExists, url)
+	}
+
+	m.share(monitor)
+	if monitor.config.Archive == nil {
+		monitor.config.Archive = m.archive
+	}
+	if monitor.config.OnCheck == nil {
+		monitor.config.OnCheck = m.onCheck
 	}
 
 	m.monitors[url] = monitor
+	if m.running {
+		m.startLocked(url, monitor)
+	}
+
 	return nil
 }
 
-// AddMonitorWithConfig creates and adds a new monitor with the given configuration
+// share makes a monitor use the manager's limits, robots.txt cache and
+// browser. m.mu must be held.
+func (m *Manager) share(monitor *Monitor) {
+	// Checks of all monitors share the manager's concurrency and rate
+	// limits, and those respecting robots.txt share its cache
+	monitor.limiter = m.limiter
+	monitor.rates = m.rates
+	if monitor.robots != nil {
+		monitor.robots = m.robots
+	}
+	// Monitors rendering pages share one browser unless given their own
+	if s, ok := monitor.source.(*browserSource); ok && s.own {
+		s.browser, s.own = m.browser, false
+	}
+}
+
+// AddMonitorWithConfig creates and adds a new monitor with the given
+// configuration. With Config.Expect the URL is fetched first, and an error
+// wrapping ErrExpectation is returned if the response doesn't meet it. If
+// the manager's state couldn't be saved, the monitor is returned along with
+// an ErrNotSaved error.
 func (m *Manager) AddMonitorWithConfig(config *Config) (*Monitor, error) {
 	if config.URL == "" {
 		return nil, ErrURLEmpty
 	}
 
-	if config.Interval <= 0 {
+	if config.Interval <= 0 && config.Schedule == nil {
 		return nil, ErrInvalidInterval
 	}
 
+	if config.Method == MethodKeyword && len(config.Matches) == 0 {
+		return nil, ErrNoMatches
+	}
+
+	if config.Method == MethodCount && len(config.Keywords) == 0 {
+		return nil, ErrNoKeywords
+	}
+
+	if config.Method == MethodValue && config.Extract == nil {
+		return nil, ErrNoExtractor
+	}
+
+	if (len(config.CSVKeys) > 0 || config.CSVDelimiter != 0) && config.Method != MethodCSV {
+		return nil, ErrCSVKeys
+	}
+
+	if config.TailSeparator != "" && config.Method != MethodTail {
+		return nil, ErrTailSeparator
+	}
+
+	if (config.ImageThreshold != 0 || config.ImageDiffDir != "") && config.Method != MethodImage {
+		return nil, ErrImageOptions
+	}
+	if config.ImageThreshold < 0 || config.ImageThreshold > 100 {
+		return nil, ErrImageThreshold
+	}
+
+	if config.FailureThreshold < 0 {
+		return nil, ErrFailureThreshold
+	}
+
+	if config.ConfirmDelay < 0 || (config.ConfirmDelay > 0 && config.Schedule == nil && config.ConfirmDelay >= config.Interval) {
+		return nil, ErrConfirmDelay
+	}
+
+	if config.AcceptEncoding != "" {
+		if err := customhttp.ValidateAcceptEncoding(config.AcceptEncoding); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := NewSelectorFilter(config.WatchSelectors, config.IgnoreSelectors); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateBaggage(config.Baggage); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateLabels(config.Labels); err != nil {
+		return nil, err
+	}
+
+	if len(config.Representations) > 0 && config.Method != MethodHash && config.Method != MethodLength {
+		return nil, ErrRepresentations
+	}
+
+	if len(config.Variants) > 0 {
+		if (config.Method != MethodHash && config.Method != MethodLength) || len(config.Representations) > 0 {
+			return nil, ErrVariants
+		}
+		if err := ValidateVariants(config.Variants); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.AlertSelectors) > 0 {
+		if (config.Method != MethodHash && config.Method != MethodLength) || len(config.Representations)+len(config.Variants) > 0 {
+			return nil, ErrAlertSelectors
+		}
+		if _, err := NewSelectorFilter(config.AlertSelectors, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateRequest(config); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateCookies(config.Cookies); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateLogin(config.Login); err != nil {
+		return nil, err
+	}
+
+	if config.OAuth2 != nil {
+		if err := config.OAuth2.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateFetcher(config); err != nil {
+		return nil, err
+	}
+
+	if err := validateMailbox(config); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(config); err != nil {
+		return nil, err
+	}
+	if err := validateAppendOnly(config); err != nil {
+		return nil, err
+	}
+
+	if config.Expect != nil {
+		if err := config.Expect.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	monitor := NewMonitorWithConfig(config)
+	if config.Expect != nil {
+		if err := m.verify(monitor); err != nil {
+			return nil, err
+		}
+	}
 	err := m.AddMonitor(monitor)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNotSaved) {
 		return nil, err
 	}
 
-	return monitor, nil
+	// A monitor that was added but not saved is still returned
+	return monitor, err
 }
 
 // CreateGroup creates a new monitor group
-func (m *Manager) CreateGroup(name, description string) (*MonitorGroup, error) {
+func (m *Manager) CreateGroup(name, description string) (group *MonitorGroup, err error) {
+	defer m.persist(&err)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -84,7 +304,7 @@ func (m *Manager) CreateGroup(name, description string) (*MonitorGroup, error) {
 		return nil, fmt.Errorf("group '%s' already exists", name)
 	}
 
-	group := &MonitorGroup{
+	group = &MonitorGroup{
 		Name:        name,
 		Description: description,
 		Monitors:    make(MonitorMap),
@@ -95,7 +315,8 @@ func (m *Manager) CreateGroup(name, description string) (*MonitorGroup, error) {
 }
 
 // AddToGroup adds a monitor to a group
-func (m *Manager) AddToGroup(url, groupName string) error {
+func (m *Manager) AddToGroup(url, groupName string) (err error) {
+	defer m.persist(&err)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -110,11 +331,104 @@ func (m *Manager) AddToGroup(url, groupName string) error {
 	}
 
 	group.Monitors[url] = monitor
+	m.updateJarLocked(url, monitor)
+	return nil
+}
+
+// RemoveFromGroup removes a monitor from a group. The monitor keeps running
+// and stays in its other groups.
+func (m *Manager) RemoveFromGroup(url, groupName string) (err error) {
+	defer m.persist(&err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", groupName)
+	}
+
+	if _, exists := group.Monitors[url]; !exists {
+		return fmt.Errorf("monitor for URL '%s' is not in group '%s'", url, groupName)
+	}
+
+	monitor := group.Monitors[url]
+	delete(group.Monitors, url)
+	m.updateJarLocked(url, monitor)
+	return nil
+}
+
+// MoveToGroup moves a monitor from one group to another in a single step, so
+// that no one sees it in both groups or in neither
+func (m *Manager) MoveToGroup(url, from, to string) (err error) {
+	defer m.persist(&err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source, exists := m.groups[from]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", from)
+	}
+
+	target, exists := m.groups[to]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", to)
+	}
+
+	monitor, exists := source.Monitors[url]
+	if !exists {
+		return fmt.Errorf("monitor for URL '%s' is not in group '%s'", url, from)
+	}
+
+	delete(source.Monitors, url)
+	target.Monitors[url] = monitor
+	m.updateJarLocked(url, monitor)
+	return nil
+}
+
+// DeleteGroup deletes a group. Its monitors keep running and stay in their
+// other groups.
+func (m *Manager) DeleteGroup(name string) (err error) {
+	defer m.persist(&err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[name]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", name)
+	}
+
+	delete(m.groups, name)
+	if group.ShareCookies {
+		for url, monitor := range group.Monitors {
+			m.updateJarLocked(url, monitor)
+		}
+	}
 	return nil
 }
 
+// GroupsOf returns the names of the groups a monitor is in, sorted
+func (m *Manager) GroupsOf(url string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.monitors[url]; !exists {
+		return nil, fmt.Errorf("no monitor found for URL '%s'", url)
+	}
+
+	groups := []string{}
+	for name, group := range m.groups {
+		if _, exists := group.Monitors[url]; exists {
+			groups = append(groups, name)
+		}
+	}
+	sort.Strings(groups)
+
+	return groups, nil
+}
+
 // RemoveMonitor removes a monitor
-func (m *Manager) RemoveMonitor(url string) error {
+func (m *Manager) RemoveMonitor(url string) (err error) {
+	defer m.persist(&err)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -123,6 +437,13 @@ func (m *Manager) RemoveMonitor(url string) error {
 		return fmt.Errorf("no monitor found for URL '%s'", url)
 	}
 
+	m.removeLocked(url, monitor)
+	return nil
+}
+
+// removeLocked stops a monitor and removes it from the manager and all
+// groups. The caller must hold m.mu.
+func (m *Manager) removeLocked(url string, monitor *Monitor) {
 	// Stop the monitor
 	monitor.Stop()
 
@@ -133,7 +454,23 @@ func (m *Manager) RemoveMonitor(url string) error {
 
 	// Remove from manager
 	delete(m.monitors, url)
-	return nil
+	delete(m.started, url)
+}
+
+// removeFinished removes a one-time monitor once it has done its check,
+// unless it was already removed or replaced
+func (m *Manager) removeFinished(url string, monitor *Monitor) {
+	m.mu.Lock()
+	removed := m.monitors[url] == monitor
+	if removed {
+		m.removeLocked(url, monitor)
+	}
+	m.mu.Unlock()
+
+	// Nobody waits for the result, so a failed save is left to the next one
+	if removed {
+		m.save()
+	}
 }
 
 // GetMonitor returns a monitor by URL
@@ -188,21 +525,200 @@ func (m *Manager) ListGroups() []string {
 	return groups
 }
 
-// Start starts all monitors and returns a channel for all changes
+// Start starts all monitors and returns a channel for all changes. Monitors
+// added while the manager is running are started automatically. Calling Start
+// again returns the same channel without restarting monitors.
 func (m *Manager) Start() <-chan Change {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for _, monitor := range m.monitors {
-		changes := monitor.Start()
-		go m.forwardChanges(changes)
+	m.running = true
+	m.staggerLocked(m.monitors)
+	for url, monitor := range m.monitors {
+		m.startLocked(url, monitor)
 	}
 
 	return m.changeChannel
 }
 
-// forwardChanges forwards changes from a monitor to the manager's change channel
-func (m *Manager) forwardChanges(changes <-chan Change) {
+// SetMaxConcurrentChecks limits the number of checks of the manager's
+// monitors that fetch at the same time, so hundreds of monitors don't exhaust
+// file descriptors or overload their targets. Checks over the limit wait in
+// line with the status "queued". Zero, the default, means no limit.
+func (m *Manager) SetMaxConcurrentChecks(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxChecks = n
+	m.limiter.setLimit(n)
+}
+
+// SetDomainPolicy sets the politeness policy of a domain and its subdomains,
+// shared by all monitors on them. The most specific domain's policy applies.
+// Monitors whose interval is shorter than the policy's MinInterval are checked
+// every MinInterval instead, and scheduled monitors skip checks that would
+// come too soon. Policies apply to monitors started after they are set.
+func (m *Manager) SetDomainPolicy(domain string, policy DomainPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.domains[strings.ToLower(domain)] = newDomainGate(policy)
+}
+
+// SetRateLimit limits the requests per minute that the manager's monitors
+// together send to any one host, so monitors pointed at the same site don't
+// hammer it. Requests over the limit wait with the status "queued". Zero, the
+// default, means no limit. SetHostRateLimit overrides it for a single host.
+func (m *Manager) SetRateLimit(perMinute int) {
+	m.rates.setDefault(perMinute)
+}
+
+// SetHostRateLimit limits the requests per minute sent to a host, e.g.
+// "api.example.com", overriding the limit set with SetRateLimit. Zero means
+// no limit for the host.
+func (m *Manager) SetHostRateLimit(host string, perMinute int) {
+	m.rates.setLimit(host, perMinute)
+}
+
+// SetBrowser replaces the browser shared by monitors with FetcherBrowser
+// that have no Config.Browser, e.g. with one connected to a browser
+// container. Call it before adding monitors, as it applies to monitors added
+// afterwards. The manager closes the browser when it stops.
+func (m *Manager) SetBrowser(b *browser.Browser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.browser = b
+}
+
+// SetArchive sets the archive of monitors without Config.Archive. Call it
+// before adding monitors, as it applies to monitors added afterwards.
+func (m *Manager) SetArchive(a Archive) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.archive = a
+}
+
+// SetOnCheck sets the Config.OnCheck of monitors without one. Call it before
+// adding monitors, as it applies to monitors added afterwards.
+func (m *Manager) SetOnCheck(fn func(Change)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onCheck = fn
+}
+
+// SetStagger controls whether Start and StartGroup spread the first checks of
+// monitors with the same interval evenly across the interval, rather than
+// checking all of them at once. Staggering is enabled by default.
+func (m *Manager) SetStagger(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.noStagger = !enabled
+}
+
+// staggerLocked delays the start of monitors that are about to be started
+// together. Monitors with the same interval are spread evenly across it, in
+// the order of their URLs; monitors with a cron schedule are left alone. The
+// caller must hold m.mu.
+func (m *Manager) staggerLocked(monitors MonitorMap) {
+	if m.noStagger {
+		return
+	}
+
+	byInterval := make(map[time.Duration][]string)
+	for url, monitor := range monitors {
+		if m.started[url] || monitor.config.Schedule != nil {
+			continue
+		}
+		byInterval[monitor.config.Interval] = append(byInterval[monitor.config.Interval], url)
+	}
+
+	for interval, urls := range byInterval {
+		sort.Strings(urls)
+		for i, url := range urls {
+			monitors[url].delayStart(interval * time.Duration(i) / time.Duration(len(urls)))
+		}
+	}
+}
+
+// IsRunning reports whether the manager has been started and not yet stopped
+func (m *Manager) IsRunning() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.running
+}
+
+// Health reports whether the manager is running and which of its monitors
+// have stalled
+func (m *Manager) Health() Health {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	health := Health{Running: m.running, Monitors: len(m.monitors), MaxConcurrentChecks: m.maxChecks}
+	health.ActiveChecks, health.QueuedChecks = m.limiter.stats()
+	for url, monitor := range m.monitors {
+		if monitor.IsPaused() {
+			health.Paused++
+		}
+		if monitor.Starting() {
+			health.Starting++
+		}
+		if monitor.Stalled() {
+			health.Stalled = append(health.Stalled, url)
+		}
+	}
+	sort.Strings(health.Stalled)
+
+	return health
+}
+
+// Duplicates groups the URLs of monitors whose last content is identical
+// after filters and normalization, e.g. the www and apex domains of a site,
+// so that redundant monitors can be removed. Each group is sorted and the
+// groups are sorted by their first URL.
+func (m *Manager) Duplicates() [][]string {
+	m.mu.RLock()
+	byHash := make(map[string][]string)
+	for url, monitor := range m.monitors {
+		if hash := monitor.ContentHash(); hash != "" {
+			byHash[hash] = append(byHash[hash], url)
+		}
+	}
+	m.mu.RUnlock()
+
+	var groups [][]string
+	for _, urls := range byHash {
+		if len(urls) > 1 {
+			sort.Strings(urls)
+			groups = append(groups, urls)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// startLocked starts a monitor unless it has already been started. The caller
+// must hold m.mu.
+func (m *Manager) startLocked(url string, monitor *Monitor) {
+	if m.started[url] {
+		return
+	}
+	m.started[url] = true
+
+	if gate := matchDomain(m.domains, url); gate != nil {
+		monitor.domain = gate
+		if monitor.config.Interval < gate.policy.MinInterval {
+			monitor.config.Interval = gate.policy.MinInterval
+		}
+	}
+
+	changes := monitor.Start()
+	m.forwarders.Add(1)
+	go m.forwardChanges(url, monitor, changes)
+}
+
+// forwardChanges forwards changes from a monitor to the manager's change
+// channel. One-time monitors are removed once they finish.
+func (m *Manager) forwardChanges(url string, monitor *Monitor, changes <-chan Change) {
+	defer m.forwarders.Done()
 	for change := range changes {
 		select {
 		case m.changeChannel <- change:
@@ -210,6 +726,11 @@ func (m *Manager) forwardChanges(changes <-chan Change) {
 			return
 		}
 	}
+
+	// Stop holds m.mu while waiting for forwarders, so remove asynchronously
+	if monitor.Finished() {
+		go m.removeFinished(url, monitor)
+	}
 }
 
 // StartMonitor starts a specific monitor
@@ -222,9 +743,7 @@ func (m *Manager) StartMonitor(url string) (<-chan Change, error) {
 		return nil, fmt.Errorf("no monitor found for URL '%s'", url)
 	}
 
-	changes := monitor.Start()
-	go m.forwardChanges(changes)
-
+	m.startLocked(url, monitor)
 	return m.changeChannel, nil
 }
 
@@ -238,14 +757,54 @@ func (m *Manager) StartGroup(groupName string) (<-chan Change, error) {
 		return nil, fmt.Errorf("group '%s' does not exist", groupName)
 	}
 
-	for _, monitor := range group.Monitors {
-		changes := monitor.Start()
-		go m.forwardChanges(changes)
+	m.staggerLocked(group.Monitors)
+	for url, monitor := range group.Monitors {
+		m.startLocked(url, monitor)
 	}
 
 	return m.changeChannel, nil
 }
 
+// StartAllExcept starts all monitors that aren't in any of the given groups.
+// Monitors added later aren't started automatically, unlike with Start.
+func (m *Manager) StartAllExcept(groupNames ...string) (<-chan Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	monitors, err := m.exceptLocked(groupNames)
+	if err != nil {
+		return nil, err
+	}
+
+	m.staggerLocked(monitors)
+	for url, monitor := range monitors {
+		m.startLocked(url, monitor)
+	}
+
+	return m.changeChannel, nil
+}
+
+// exceptLocked returns the monitors that aren't in any of the given groups.
+// The caller must hold m.mu.
+func (m *Manager) exceptLocked(groupNames []string) (MonitorMap, error) {
+	monitors := make(MonitorMap, len(m.monitors))
+	for url, monitor := range m.monitors {
+		monitors[url] = monitor
+	}
+
+	for _, name := range groupNames {
+		group, exists := m.groups[name]
+		if !exists {
+			return nil, fmt.Errorf("group '%s' does not exist", name)
+		}
+		for url := range group.Monitors {
+			delete(monitors, url)
+		}
+	}
+
+	return monitors, nil
+}
+
 // Stop stops all monitors
 func (m *Manager) Stop() {
 	m.cancel()
@@ -253,11 +812,15 @@ func (m *Manager) Stop() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.running = false
 	for _, monitor := range m.monitors {
 		monitor.Stop()
 	}
 
+	// Forwarders return once canceled; wait so none sends on a closed channel
+	m.forwarders.Wait()
 	close(m.changeChannel)
+	m.browser.Close()
 }
 
 // StopMonitor stops a specific monitor
@@ -290,3 +853,144 @@ func (m *Manager) StopGroup(groupName string) error {
 
 	return nil
 }
+
+// StopAllExcept stops all monitors that aren't in any of the given groups
+func (m *Manager) StopAllExcept(groupNames ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	monitors, err := m.exceptLocked(groupNames)
+	if err != nil {
+		return err
+	}
+
+	for _, monitor := range monitors {
+		monitor.Stop()
+	}
+
+	return nil
+}
+
+// PauseMonitor suspends checks of a specific monitor
+func (m *Manager) PauseMonitor(url string) (err error) {
+	defer m.persist(&err)
+	monitor, err := m.GetMonitor(url)
+	if err != nil {
+		return err
+	}
+
+	monitor.Pause()
+	return nil
+}
+
+// ResumeMonitor resumes checks of a specific monitor
+func (m *Manager) ResumeMonitor(url string) (err error) {
+	defer m.persist(&err)
+	monitor, err := m.GetMonitor(url)
+	if err != nil {
+		return err
+	}
+
+	monitor.Resume()
+	return nil
+}
+
+// PauseGroup suspends checks of all monitors in a group
+func (m *Manager) PauseGroup(groupName string) (err error) {
+	defer m.persist(&err)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, exists := m.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", groupName)
+	}
+
+	for _, monitor := range group.Monitors {
+		monitor.Pause()
+	}
+
+	return nil
+}
+
+// ResumeGroup resumes checks of all monitors in a group
+func (m *Manager) ResumeGroup(groupName string) (err error) {
+	defer m.persist(&err)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, exists := m.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", groupName)
+	}
+
+	for _, monitor := range group.Monitors {
+		monitor.Resume()
+	}
+
+	return nil
+}
+
+// TriggerMonitor requests an immediate check of a specific monitor
+func (m *Manager) TriggerMonitor(url string) error {
+	monitor, err := m.GetMonitor(url)
+	if err != nil {
+		return err
+	}
+
+	return monitor.Trigger()
+}
+
+// TriggerGroup requests an immediate check of all monitors in a group. Paused
+// monitors are skipped.
+func (m *Manager) TriggerGroup(groupName string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, exists := m.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group '%s' does not exist", groupName)
+	}
+
+	for _, monitor := range group.Monitors {
+		if err := monitor.Trigger(); err != nil && !errors.Is(err, ErrMonitorPaused) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateMonitor changes the HTTP client settings of a specific monitor
+// while it runs, keeping its baseline, see Monitor.UpdateClient
+func (m *Manager) UpdateMonitor(url string, settings ClientSettings) (err error) {
+	if settings.Timeout < 0 {
+		return ErrInvalidTimeout
+	}
+	if settings.ProxyURL != nil {
+		if _, err := customhttp.ParseProxyURL(settings.ProxyURL.String()); err != nil {
+			return err
+		}
+	}
+
+	defer m.persist(&err)
+	monitor, err := m.GetMonitor(url)
+	if err != nil {
+		return err
+	}
+
+	monitor.UpdateClient(settings)
+	return nil
+}
+
+// ResetBaseline discards the stored content of a specific monitor so its next
+// check sets a new baseline
+func (m *Manager) ResetBaseline(url string) error {
+	monitor, err := m.GetMonitor(url)
+	if err != nil {
+		return err
+	}
+
+	monitor.ResetBaseline()
+	return nil
+}
@@ -2,10 +2,15 @@ package monitor
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
 	"github.com/stretchr/testify/require"
 )
 
@@ -126,6 +131,89 @@ func TestAddToGroup(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestGroupOperations(t *testing.T) {
+	manager := NewManager()
+	for _, url := range []string{"https://example.com", "https://example.org"} {
+		_, err := manager.AddMonitorWithConfig(DefaultConfig(url))
+		require.NoError(t, err)
+	}
+	for _, name := range []string{"news", "shops", "archive"} {
+		_, err := manager.CreateGroup(name, "")
+		require.NoError(t, err)
+	}
+	require.NoError(t, manager.AddToGroup("https://example.com", "news"))
+	require.NoError(t, manager.AddToGroup("https://example.com", "shops"))
+
+	groups, err := manager.GroupsOf("https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"news", "shops"}, groups)
+	groups, err = manager.GroupsOf("https://example.org")
+	require.NoError(t, err)
+	require.Empty(t, groups)
+	_, err = manager.GroupsOf("https://non-existent.com")
+	require.Error(t, err)
+
+	require.NoError(t, manager.MoveToGroup("https://example.com", "news", "archive"))
+	groups, _ = manager.GroupsOf("https://example.com")
+	require.Equal(t, []string{"archive", "shops"}, groups)
+	require.Error(t, manager.MoveToGroup("https://example.com", "news", "archive"))
+	require.Error(t, manager.MoveToGroup("https://example.com", "archive", "non-existent-group"))
+	groups, _ = manager.GroupsOf("https://example.com")
+	require.Equal(t, []string{"archive", "shops"}, groups)
+
+	require.NoError(t, manager.RemoveFromGroup("https://example.com", "shops"))
+	require.Error(t, manager.RemoveFromGroup("https://example.com", "shops"))
+	require.NoError(t, manager.DeleteGroup("archive"))
+	require.Error(t, manager.DeleteGroup("archive"))
+	groups, _ = manager.GroupsOf("https://example.com")
+	require.Empty(t, groups)
+	require.ElementsMatch(t, []string{"news", "shops"}, manager.ListGroups())
+	require.Len(t, manager.ListMonitors(), 2)
+}
+
+func TestStartAllExcept(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	for _, path := range []string{"/a", "/b", "/c"} {
+		config := DefaultConfig(server.URL + path)
+		config.Interval = time.Hour
+		_, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+	}
+	_, err := manager.CreateGroup("slow", "")
+	require.NoError(t, err)
+	require.NoError(t, manager.AddToGroup(server.URL+"/b", "slow"))
+
+	_, err = manager.StartAllExcept("slow", "non-existent-group")
+	require.Error(t, err)
+
+	changes, err := manager.StartAllExcept("slow")
+	require.NoError(t, err)
+	require.NotNil(t, changes)
+	manager.mu.RLock()
+	require.Equal(t, map[string]bool{server.URL + "/a": true, server.URL + "/c": true}, manager.started)
+	manager.mu.RUnlock()
+
+	stopped := func(url string) bool {
+		monitor, err := manager.GetMonitor(url)
+		require.NoError(t, err)
+		select {
+		case <-monitor.stop:
+			return true
+		default:
+			return false
+		}
+	}
+	require.NoError(t, manager.StopAllExcept("slow"))
+	require.True(t, stopped(server.URL+"/a"))
+	require.False(t, stopped(server.URL+"/b"))
+	manager.Stop()
+}
+
 func TestRemoveMonitor(t *testing.T) {
 	manager := NewManager()
 	monitor := NewMonitor("https://example.com", time.Second*5)
@@ -277,3 +365,609 @@ func TestConcurrentManagerOperations(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, group.Monitors)
 }
+
+func TestManagerStartsMonitorsAddedAfterStart(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		fmt.Fprintf(w, "content %d", count)
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	require.False(t, manager.IsRunning())
+
+	changes := manager.Start()
+	require.True(t, manager.IsRunning())
+
+	config := DefaultConfig(server.URL)
+	config.Interval = time.Millisecond * 20
+	_, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	// Starting it again explicitly is a no-op
+	_, err = manager.StartMonitor(server.URL)
+	require.NoError(t, err)
+
+	select {
+	case change := <-changes:
+		require.Equal(t, server.URL, change.URL)
+		require.True(t, change.HasChanged)
+	case <-time.After(time.Second * 5):
+		t.Fatal("monitor added after Start was not started")
+	}
+
+	manager.Stop()
+	require.False(t, manager.IsRunning())
+}
+
+func TestPauseAndResume(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		fmt.Fprintf(w, "content %d", count)
+	}))
+	defer server.Close()
+
+	requests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+
+	manager := NewManager()
+	config := DefaultConfig(server.URL)
+	config.Interval = time.Millisecond * 10
+	monitor, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	_, err = manager.CreateGroup("docs", "")
+	require.NoError(t, err)
+	require.NoError(t, manager.AddToGroup(server.URL, "docs"))
+
+	// Paused monitors don't check, not even on start
+	require.NoError(t, manager.PauseGroup("docs"))
+	require.True(t, monitor.IsPaused())
+	_, status, _ := monitor.GetStatus()
+	require.Equal(t, "paused", status)
+
+	changes := manager.Start()
+	time.Sleep(time.Millisecond * 50)
+	require.Equal(t, 0, requests())
+
+	// Resuming picks up where it left off
+	require.NoError(t, manager.ResumeMonitor(server.URL))
+	require.False(t, monitor.IsPaused())
+
+	for _, expected := range []EventType{EventPaused, EventResumed, EventChange} {
+		select {
+		case change := <-changes:
+			require.Equal(t, expected, change.Event)
+			require.Equal(t, expected == EventChange, change.HasChanged)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("no %s event", expected)
+		}
+	}
+
+	require.Error(t, manager.PauseMonitor("https://missing.example.com"))
+	require.Error(t, manager.ResumeGroup("missing"))
+
+	manager.Stop()
+}
+
+func TestManagerHealth(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)}
+	manager := NewManager()
+
+	config := DefaultConfig("https://example.com")
+	config.Interval = time.Minute
+	config.Timeout = time.Second * 10
+	config.RetryCount = 1
+	config.RetryInterval = time.Second * 5
+	config.Clock = clock
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	health := manager.Health()
+	require.False(t, health.OK())
+	require.Equal(t, 1, health.Monitors)
+
+	// Simulate a run loop that woke up and then got stuck
+	manager.running = true
+	m.setCycle(clock.now)
+	require.True(t, manager.Health().OK())
+
+	// Interval plus two timeouts and a retry interval is allowed
+	clock.now = clock.now.Add(time.Minute + time.Second*25)
+	require.False(t, m.Stalled())
+	clock.now = clock.now.Add(time.Second)
+	require.True(t, m.Stalled())
+	require.Equal(t, []string{"https://example.com"}, manager.Health().Stalled)
+
+	m.Pause()
+	require.Equal(t, 1, manager.Health().Paused)
+
+	// Stopped monitors are not stalled
+	m.setCycle(time.Time{})
+	require.True(t, manager.Health().OK())
+}
+
+func TestManagerDuplicates(t *testing.T) {
+	manager := NewManager()
+	filter, err := NewRegexFilter("version: [0-9.]+", "version: X.Y.Z", "Ignore version numbers")
+	require.NoError(t, err)
+
+	contents := map[string]string{
+		"https://example.com":      "Software version: 1.2.3",
+		"https://www.example.com":  "Software version: 1.2.4",
+		"https://example.com/docs": "Documentation",
+		"https://example.org":      "Documentation",
+		"https://example.net":      "Something else",
+	}
+	for url, content := range contents {
+		config := DefaultConfig(url)
+		config.ContentFilters = ContentFilterList{filter}
+		m, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+		m.lastContent = []byte(content)
+	}
+	// Not checked yet
+	_, err = manager.AddMonitorWithConfig(DefaultConfig("https://example.edu"))
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"https://example.com", "https://www.example.com"},
+		{"https://example.com/docs", "https://example.org"},
+	}, manager.Duplicates())
+
+	m, err := manager.GetMonitor("https://example.org")
+	require.NoError(t, err)
+	m.ResetBaseline()
+	require.Equal(t, [][]string{{"https://example.com", "https://www.example.com"}}, manager.Duplicates())
+}
+
+func TestManagerReady(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)}
+	manager := NewManager()
+
+	config := DefaultConfig("https://example.com")
+	config.Clock = clock
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	scheduled := DefaultConfig("https://example.org")
+	scheduled.Schedule, err = schedule.ParseCron("0 9 * * *")
+	require.NoError(t, err)
+	_, err = manager.AddMonitorWithConfig(scheduled)
+	require.NoError(t, err)
+
+	// Report as running without starting the monitors, whose state is set
+	// directly below
+	manager.running = true
+
+	// Scheduled monitors don't hold up readiness
+	require.Equal(t, 1, manager.Health().Starting)
+	require.False(t, manager.Health().Ready())
+
+	// Nor do monitors waiting for a staggered start
+	m.firstCheck = clock.now.Add(time.Minute)
+	require.True(t, manager.Health().Ready())
+
+	clock.now = clock.now.Add(time.Minute)
+	require.False(t, manager.Health().Ready())
+
+	m.settle()
+	require.True(t, manager.Health().Ready())
+}
+
+func TestManagerOneTimeCheck(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Write([]byte("tickets: coming soon"))
+			return
+		}
+		w.Write([]byte("tickets: on sale"))
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	defer manager.Stop()
+	config := DefaultConfig(server.URL)
+	config.At = time.Now().Add(time.Millisecond * 200)
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+	require.Equal(t, config.At, m.NextCheck())
+	_, err = manager.CreateGroup("releases", "")
+	require.NoError(t, err)
+	require.NoError(t, manager.AddToGroup(server.URL, "releases"))
+
+	changes := manager.Start()
+
+	change := <-changes
+	require.Equal(t, EventChange, change.Event)
+	require.True(t, change.HasChanged)
+	require.False(t, change.Timestamp.Before(config.At))
+
+	change = <-changes
+	require.Equal(t, EventCompleted, change.Event)
+	require.True(t, m.Finished())
+	require.Equal(t, int64(2), calls.Load())
+
+	// The monitor removes itself once done
+	require.Eventually(t, func() bool {
+		return len(manager.ListMonitors()) == 0
+	}, time.Second, time.Millisecond*10)
+	group, err := manager.GetGroup("releases")
+	require.NoError(t, err)
+	require.Empty(t, group.Monitors)
+}
+
+func TestManagerUntilCondition(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Write([]byte(`{"registration": {"open": false}}`))
+			return
+		}
+		w.Write([]byte(`{"registration": {"open": true}}`))
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	defer manager.Stop()
+
+	config := DefaultConfig(server.URL)
+	config.Interval = time.Millisecond * 50
+	config.Until = NewJSONCondition("registration.open", "true", false)
+	config.Deadline = time.Now().Add(time.Minute)
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	changes := manager.Start()
+	var events []EventType
+	for change := range changes {
+		events = append(events, change.Event)
+		if change.Event == EventConditionMet {
+			require.Equal(t, "registration.open is true", change.Details)
+			break
+		}
+	}
+	require.Equal(t, []EventType{EventChange, EventConditionMet}, events)
+	require.True(t, m.Finished())
+	require.Eventually(t, func() bool {
+		return len(manager.ListMonitors()) == 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestManagerDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Registration opens soon"))
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig(server.URL)
+	config.Until = NewTextCondition("Registration is open")
+	config.Deadline = time.Now().Add(time.Millisecond * 100)
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	change := <-manager.Start()
+	require.Equal(t, EventDeadlinePassed, change.Event)
+	require.Contains(t, change.Details, `before text "Registration is open" was found`)
+	require.True(t, m.Finished())
+}
+
+func TestManagerExpires(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Write([]byte("Sale ends soon"))
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("Sale ended"))
+		}
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	defer manager.Stop()
+
+	config := DefaultConfig(server.URL)
+	config.Interval = time.Millisecond * 50
+	config.RetryCount = 0
+	config.Expires = time.Now().Add(time.Millisecond * 130)
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	var change Change
+	for change = range manager.Start() {
+		if change.Event == EventExpired {
+			break
+		}
+	}
+	require.Equal(t, EventExpired, change.Event)
+	require.Equal(t, "Expired after 3 checks with 1 change and 1 error", change.Details)
+	require.True(t, m.Finished())
+	require.Eventually(t, func() bool {
+		return len(manager.ListMonitors()) == 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestManagerMaxChecks(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "version %d", calls.Add(1))
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	defer manager.Stop()
+
+	config := DefaultConfig(server.URL)
+	config.Interval = time.Millisecond * 20
+	config.MaxChecks = 3
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	var events []EventType
+	for change := range manager.Start() {
+		events = append(events, change.Event)
+		if change.Event == EventCompleted {
+			require.Equal(t, "3 checks done", change.Details)
+			break
+		}
+	}
+	// The first check sets the baseline
+	require.Equal(t, []EventType{EventChange, EventChange, EventCompleted}, events)
+	require.True(t, m.Finished())
+	require.Equal(t, int64(3), calls.Load())
+}
+
+func TestManagerStaggersStart(t *testing.T) {
+	manager := NewManager()
+	add := func(url string, interval time.Duration) *Monitor {
+		config := DefaultConfig(url)
+		config.Interval = interval
+		m, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+		return m
+	}
+
+	a := add("https://a.example.com", time.Minute)
+	b := add("https://b.example.com", time.Minute)
+	c := add("https://c.example.com", time.Minute)
+	alone := add("https://d.example.com", time.Hour)
+
+	manager.mu.Lock()
+	manager.staggerLocked(manager.monitors)
+	manager.mu.Unlock()
+
+	require.Zero(t, a.startDelay)
+	require.Equal(t, time.Second*20, b.startDelay)
+	require.Equal(t, time.Second*40, c.startDelay)
+	require.Zero(t, alone.startDelay)
+
+	// Staggering can be turned off
+	b.delayStart(0)
+	manager.SetStagger(false)
+	manager.mu.Lock()
+	manager.staggerLocked(manager.monitors)
+	manager.mu.Unlock()
+	require.Zero(t, b.startDelay)
+}
+
+func TestManagerMaxConcurrentChecks(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetMaxConcurrentChecks(2)
+	manager.SetStagger(false)
+	for i := 0; i < 5; i++ {
+		config := DefaultConfig(fmt.Sprintf("%s/page/%d", server.URL, i))
+		config.Interval = time.Hour
+		_, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+	}
+
+	changes := manager.Start()
+	go func() {
+		for range changes {
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		health := manager.Health()
+		return active == 2 && health.ActiveChecks == 2 && health.QueuedChecks == 3
+	}, time.Second*5, time.Millisecond)
+
+	queued := 0
+	for _, url := range manager.ListMonitors() {
+		monitor, err := manager.GetMonitor(url)
+		require.NoError(t, err)
+		if _, status, _ := monitor.GetStatus(); status == "queued" {
+			queued++
+		}
+	}
+	require.Equal(t, 3, queued)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		health := manager.Health()
+		return health.ActiveChecks == 0 && health.QueuedChecks == 0
+	}, time.Second*5, time.Millisecond)
+
+	mu.Lock()
+	require.Equal(t, 2, peak)
+	mu.Unlock()
+
+	manager.Stop()
+}
+
+func TestManagerTrigger(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	_, err := manager.CreateGroup("site", "")
+	require.NoError(t, err)
+
+	for _, path := range []string{"/a", "/b"} {
+		config := DefaultConfig(server.URL + path)
+		config.Interval = time.Hour
+		_, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+		require.NoError(t, manager.AddToGroup(server.URL+path, "site"))
+	}
+
+	changes := manager.Start()
+	go func() {
+		for range changes {
+		}
+	}()
+	defer manager.Stop()
+	require.Eventually(t, func() bool { return requests.Load() == 2 }, time.Second*5, time.Millisecond)
+
+	require.NoError(t, manager.TriggerMonitor(server.URL+"/a"))
+	require.Eventually(t, func() bool { return requests.Load() == 3 }, time.Second*5, time.Millisecond)
+
+	// Paused monitors refuse triggers and are skipped in groups
+	require.NoError(t, manager.PauseMonitor(server.URL+"/b"))
+	require.ErrorIs(t, manager.TriggerMonitor(server.URL+"/b"), ErrMonitorPaused)
+	require.NoError(t, manager.TriggerGroup("site"))
+	require.Eventually(t, func() bool { return requests.Load() == 4 }, time.Second*5, time.Millisecond)
+
+	require.Error(t, manager.TriggerGroup("missing"))
+	require.Error(t, manager.TriggerMonitor("https://missing.example.com"))
+}
+
+func TestManagerRespectRobotsTxt(t *testing.T) {
+	var pages, robotsFetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			robotsFetches.Add(1)
+			fmt.Fprint(w, "User-agent: hawkeye\nDisallow: /private\n")
+			return
+		}
+		pages.Add(1)
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	for _, path := range []string{"/public", "/private"} {
+		config := DefaultConfig(server.URL + path)
+		config.Interval = time.Hour
+		config.RespectRobotsTxt = true
+		_, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+	}
+
+	changes := manager.Start()
+	defer manager.Stop()
+
+	change := <-changes
+	require.Equal(t, EventDisallowed, change.Event)
+	require.Equal(t, server.URL+"/private", change.URL)
+	require.Contains(t, change.Details, "disallows /private")
+	_, status, _ := manager.monitors[server.URL+"/private"].GetStatus()
+	require.Equal(t, "disallowed", status)
+
+	// The allowed monitor is checked, and robots.txt is fetched once for both
+	require.NoError(t, manager.TriggerMonitor(server.URL+"/private"))
+	require.Eventually(t, func() bool { return pages.Load() == 1 }, time.Second*5, time.Millisecond)
+	require.Equal(t, int32(1), robotsFetches.Load())
+}
+
+func TestManagerUpdateMonitor(t *testing.T) {
+	var content atomic.Value
+	content.Store("v1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		case "/moved":
+			http.Redirect(w, r, "/slow", http.StatusFound)
+			return
+		}
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+	proxied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxy receives the absolute URL of the target
+		require.Equal(t, server.URL+"/moved", r.URL.String())
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer proxied.Close()
+
+	manager := NewManager()
+	config := DefaultConfig(server.URL + "/moved")
+	config.RetryCount = 0
+	config.Timeout = 20 * time.Millisecond
+	config.FollowRedirects = false
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+
+	require.Equal(t, "unexpected status code: 302", m.Check().Error)
+
+	settings := m.ClientSettings()
+	settings.FollowRedirects = true
+	require.NoError(t, manager.UpdateMonitor(config.URL, settings))
+	require.Contains(t, m.Check().Error, "Client.Timeout exceeded")
+
+	settings.Timeout = time.Second
+	require.NoError(t, manager.UpdateMonitor(config.URL, settings))
+	change := m.Check()
+	require.Empty(t, change.Error)
+
+	// The baseline is kept across updates
+	settings.ProxyURL, _ = url.Parse(proxied.URL)
+	require.NoError(t, manager.UpdateMonitor(config.URL, settings))
+	require.False(t, m.Check().HasChanged)
+	content.Store("v2")
+	require.True(t, m.Check().HasChanged)
+	require.Equal(t, settings, m.ClientSettings())
+	require.Equal(t, time.Second, m.GetConfig().Timeout)
+
+	settings.Timeout = -time.Second
+	require.ErrorIs(t, manager.UpdateMonitor(config.URL, settings), ErrInvalidTimeout)
+	require.Error(t, manager.UpdateMonitor("https://missing.example.com", m.ClientSettings()))
+}
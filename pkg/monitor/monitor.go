@@ -1,17 +1,28 @@
 package monitor
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"image"
+	"maps"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/nemuizzz/hawkeye/pkg/browser"
+	"github.com/nemuizzz/hawkeye/pkg/har"
 	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/robots"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
 	"github.com/nemuizzz/hawkeye/pkg/utils"
 	"github.com/nemuizzz/hawkeye/pkg/version"
 )
@@ -26,42 +37,545 @@ const (
 	MethodLength
 	// MethodCustom uses a custom comparison function
 	MethodCustom
+	// MethodStatus ignores the content and reports a change when the class
+	// of the status code changes, e.g. from 2xx to 5xx, for uptime checks
+	MethodStatus
+	// MethodKeyword reports a change when a keyword or pattern of
+	// Config.Matches appears in or disappears from the content
+	MethodKeyword
+	// MethodValue extracts a number from the content with Config.Extract and
+	// reports a change when it changes, crosses one of Config.Thresholds or
+	// moves by more than Config.Delta or Config.DeltaPercent
+	MethodValue
+	// MethodFeed parses the content as an RSS or Atom feed and reports a
+	// change for every entry that wasn't in the feed before, rather than
+	// for every rebuild of the feed
+	MethodFeed
+	// MethodCount counts the occurrences of each of Config.Keywords and
+	// reports a change when a count changes. The counts of every check are
+	// kept as a time series, see Monitor.CountSeries.
+	MethodCount
+	// MethodCSV parses the content as a CSV or TSV document and reports the
+	// rows added, removed or changed, matched by Config.CSVKeys, rather than
+	// the changed lines
+	MethodCSV
+	// MethodImage decodes PNG, JPEG and GIF images and reports a change when
+	// more than Config.ImageThreshold percent of their pixels look
+	// different, e.g. for charts, badges and screenshots
+	MethodImage
+	// MethodTail follows content that only grows, such as a log or a
+	// changelog, from the end seen by the previous check, and reports every
+	// segment appended since, a line or a part between Config.TailSeparator,
+	// as a change of its own. It fetches as Config.AppendOnly does.
+	MethodTail
 )
 
+// String returns the name of the change detection method
+func (c ChangeDetectionMethod) String() string {
+	switch c {
+	case MethodHash:
+		return "hash"
+	case MethodLength:
+		return "length"
+	case MethodCustom:
+		return "custom"
+	case MethodStatus:
+		return "status"
+	case MethodKeyword:
+		return "keyword"
+	case MethodValue:
+		return "value"
+	case MethodFeed:
+		return "feed"
+	case MethodCount:
+		return "count"
+	case MethodCSV:
+		return "csv"
+	case MethodImage:
+		return "image"
+	case MethodTail:
+		return "tail"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseMethod parses a change detection method name
+func ParseMethod(name string) (ChangeDetectionMethod, error) {
+	switch strings.ToLower(name) {
+	case "", "hash":
+		return MethodHash, nil
+	case "length":
+		return MethodLength, nil
+	case "custom":
+		return MethodCustom, nil
+	case "status":
+		return MethodStatus, nil
+	case "keyword":
+		return MethodKeyword, nil
+	case "value":
+		return MethodValue, nil
+	case "feed":
+		return MethodFeed, nil
+	case "count":
+		return MethodCount, nil
+	case "csv":
+		return MethodCSV, nil
+	case "image":
+		return MethodImage, nil
+	case "tail":
+		return MethodTail, nil
+	default:
+		return MethodHash, fmt.Errorf("unknown change detection method '%s'", name)
+	}
+}
+
 // Error definitions
 var (
 	ErrURLEmpty        = errors.New("URL cannot be empty")
 	ErrInvalidInterval = errors.New("interval must be greater than zero")
+	ErrInvalidTimeout  = errors.New("timeout must not be negative")
 	ErrMonitorStopped  = errors.New("monitor has been stopped")
+	ErrMonitorPaused   = errors.New("monitor is paused")
+	// ErrMonitorExists is returned, wrapped, when a monitor is added for a
+	// URL that already has one
+	ErrMonitorExists   = errors.New("monitor already exists")
+	ErrNoMatches       = errors.New("keyword method requires at least one match")
+	ErrRepresentations = errors.New("representations require the hash or length method")
+	// ErrVariants is returned when variants are set with a method other
+	// than hash or length, or together with representations
+	ErrVariants = errors.New("variants require the hash or length method and can't be combined with representations")
+	// ErrAlertSelectors is returned when alert selectors are set with a
+	// method other than hash or length, or together with representations
+	// or variants
+	ErrAlertSelectors = errors.New("alert selectors require the hash or length method and can't be combined with representations or variants")
+	ErrNoExtractor    = errors.New("value method requires an extractor")
+	ErrNoKeywords     = errors.New("count method requires at least one keyword")
+	ErrCSVKeys        = errors.New("CSV key columns and delimiter require the csv method")
+	ErrImageOptions   = errors.New("image threshold and diff directory require the image method")
+	ErrImageThreshold = errors.New("image threshold must be between 0 and 100")
+	// ErrFailureThreshold is returned for a negative failure threshold
+	ErrFailureThreshold = errors.New("failure threshold must not be negative")
+	// ErrConfirmDelay is returned for a negative confirm delay, or one that
+	// isn't shorter than the interval
+	ErrConfirmDelay = errors.New("confirm delay must not be negative and must be shorter than the interval")
+)
+
+// EventType identifies what a Change reports
+type EventType string
+
+const (
+	// EventChange reports that the content changed
+	EventChange EventType = "change"
+	// EventError reports a failed check
+	EventError EventType = "error"
+	// EventRecovery reports the first successful check after failed ones
+	EventRecovery EventType = "recovery"
+	// EventPaused reports that checks were suspended
+	EventPaused EventType = "paused"
+	// EventResumed reports that checks were resumed
+	EventResumed EventType = "resumed"
+	// EventBaselineReset reports that the stored content was discarded and
+	// the next check sets a new baseline
+	EventBaselineReset EventType = "baseline_reset"
+	// EventCompleted reports that the check of a one-time monitor, or the
+	// Config.MaxChecks checks of a monitor, were done and the monitor
+	// finished
+	EventCompleted EventType = "completed"
+	// EventConditionMet reports that the content met the condition of the
+	// monitor and the monitor finished
+	EventConditionMet EventType = "condition_met"
+	// EventDeadlinePassed reports that the deadline of the monitor passed
+	// before its condition was met and the monitor finished
+	EventDeadlinePassed EventType = "deadline_passed"
+	// EventDisallowed reports that checks are skipped because robots.txt
+	// disallows the URL, see Config.RespectRobotsTxt
+	EventDisallowed EventType = "disallowed"
+	// EventExpired reports that the monitor reached Config.Expires and
+	// finished, with a summary of its checks in the details
+	EventExpired EventType = "expired"
+	// EventDigest sums up the events of any number of monitors in its
+	// details. It is sent by notification digests rather than monitors.
+	EventDigest EventType = "digest"
+	// EventComposite reports that the events of monitors met the rule of a
+	// composite, which its details give along with the events. It is sent
+	// by Composites rather than monitors.
+	EventComposite EventType = "composite"
 )
 
-// Change represents a detected change in a monitored URL
+// EventTypes lists all event types
+var EventTypes = []EventType{EventChange, EventError, EventRecovery, EventPaused, EventResumed, EventBaselineReset, EventCompleted, EventConditionMet, EventDeadlinePassed, EventDisallowed, EventExpired, EventDigest, EventComposite}
+
+// ParseEventType parses an event type name
+func ParseEventType(name string) (EventType, error) {
+	for _, event := range EventTypes {
+		if string(event) == strings.ToLower(name) {
+			return event, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type '%s'", name)
+}
+
+// Change represents a detected change in a monitored URL, or another event
+// in the life of its monitor as given by Event
 type Change struct {
 	URL         string    `json:"url"`
+	Event       EventType `json:"event_type"`
 	Timestamp   time.Time `json:"timestamp"`
 	HasChanged  bool      `json:"has_changed"`
 	StatusCode  int       `json:"status_code,omitempty"`
 	ContentType string    `json:"content_type,omitempty"`
 	Error       string    `json:"error,omitempty"`
 	Details     string    `json:"details,omitempty"`
+	// Summary describes a content change in one line, if a summarizer was
+	// applied to it
+	Summary string `json:"summary,omitempty"`
+	// Latency is the time until the response headers were received, in
+	// nanoseconds in JSON
+	Latency time.Duration `json:"latency,omitempty"`
+	// Value holds the old and new number of a change found with
+	// MethodValue
+	Value *ValueChange `json:"value,omitempty"`
+	// Entry is the new feed entry reported with MethodFeed
+	Entry *FeedEntry `json:"entry,omitempty"`
+	// Segment is the appended segment reported with MethodTail
+	Segment *Segment `json:"segment,omitempty"`
+	// Counts are the keyword counts that changed with MethodCount
+	Counts []CountChange `json:"counts,omitempty"`
+	// Rows are the rows added, removed or changed with MethodCSV
+	Rows []RowChange `json:"rows,omitempty"`
+	// Image describes the difference of the images compared with
+	// MethodImage
+	Image *ImageChange `json:"image,omitempty"`
+	// Baggage echoes Config.Baggage, e.g. to route a change to its tenant.
+	// It must not be modified.
+	Baggage map[string]string `json:"baggage,omitempty"`
+	// Hunks is the complete line diff of the change as structured data.
+	// Details holds a summary of it capped to the configured size.
+	Hunks []DiffHunk `json:"hunks,omitempty"`
+	// Variants names the variants or representations that changed, see
+	// Config.Variants
+	Variants []string `json:"variants,omitempty"`
+	// Diff is Hunks formatted as a unified diff
+	Diff string `json:"-"`
+	// OldHash and NewHash are the hex SHA-256 hashes of the content before
+	// and after a change found with MethodHash, MethodLength or
+	// MethodCustom, and OldLength and NewLength its sizes in bytes. For text
+	// they describe the compared content, after filters and normalization,
+	// and for binary or truncated bodies the complete body.
+	OldHash   string `json:"old_hash,omitempty"`
+	NewHash   string `json:"new_hash,omitempty"`
+	OldLength int64  `json:"old_length,omitempty"`
+	NewLength int64  `json:"new_length,omitempty"`
+	// FilteredBy describes the content filters, such as IgnoreTimestamps,
+	// that removed or replaced parts of the new content before it was
+	// compared
+	FilteredBy []string `json:"filtered_by,omitempty"`
+	// Silenced is set for changes found during a maintenance window in
+	// silence mode; they are reported but should not be notified
+	Silenced bool `json:"silenced,omitempty"`
+	// Informational is set for changes outside of Config.AlertSelectors;
+	// like silenced changes they are reported but should not be notified
+	Informational bool `json:"informational,omitempty"`
+
+	// entries are the new feed entries found by a check, each sent as a
+	// change of its own
+	entries []FeedEntry
+	// segments are the segments appended since the previous check with
+	// MethodTail, each sent as a change of its own
+	segments []Segment
+	// digest describes the complete response body of the check
+	digest bodyDigest
+	// etag is the strong ETag of the content of an append-only monitor
+	etag string
+}
+
+// CheckError is a failure seen by a check, passed to Config.OnError. Err is
+// the cause, e.g. a *url.Error wrapping a *net.DNSError.
+type CheckError struct {
+	URL  string
+	Time time.Time
+	// Attempt numbers the attempts to fetch the URL in a check from 1. It
+	// is zero for errors in content that was fetched, such as a value that
+	// can't be extracted.
+	Attempt int
+	// Final is set when the check fails with the error, as it isn't retried
+	Final bool
+	// StatusCode is the status of the response, if there was one
+	StatusCode int
+	Err        error
 }
 
+// Error implements error
+func (e *CheckError) Error() string {
+	if e.Attempt == 0 {
+		return fmt.Sprintf("%s: %s", e.URL, e.Err)
+	}
+	return fmt.Sprintf("%s: attempt %d: %s", e.URL, e.Attempt, e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// Archive stores versions of the content of monitors, see Config.Archive
+type Archive interface {
+	// Save stores content as the version of url seen at the given time
+	Save(url string, at time.Time, content []byte, contentType string) error
+}
+
+// AttachmentArchive is an Archive that keeps attachments with versions,
+// e.g. an *archive.Archive
+type AttachmentArchive interface {
+	Archive
+	// AttachAt stores content as the attachment with the given name of the
+	// version of url current at the given time
+	AttachAt(url string, at time.Time, name string, content []byte, contentType string) error
+}
+
+// HARAttachment is the name of the HTTP archive attached to the archived
+// version of a change, see Config.CaptureHAR
+const HARAttachment = "fetch.har"
+
 // Config holds the configuration for a monitor
 type Config struct {
-	URL                 string
-	Interval            time.Duration
-	Timeout             time.Duration
-	Headers             map[string]string
-	IgnoreSelectors     []string
-	Method              ChangeDetectionMethod
-	CustomCompareFn     func([]byte, []byte) (bool, string)
-	RetryCount          int
-	RetryInterval       time.Duration
+	URL      string
+	Interval time.Duration
+	Timeout  time.Duration
+	Headers  map[string]string
+	// RequestMethod is the HTTP method of requests, GET by default or POST
+	// when Body is set. Body is sent with every request, with ContentType
+	// or, without it, as JSON if it is valid JSON and as a form otherwise.
+	RequestMethod string
+	Body          string
+	ContentType   string
+	// KeepCookies keeps the cookies set by the site between checks, like a
+	// browser, so sites with session or consent cookies behave the same on
+	// every check. Cookies, name to value, are set for the whole site
+	// before the first check and imply KeepCookies.
+	KeepCookies bool
+	Cookies     map[string]string
+	// BasicAuth and BearerToken set the Authorization header of requests.
+	// Only one of them and OAuth2 can be set.
+	BasicAuth   *BasicAuth
+	BearerToken string
+	// Login signs the monitor in before its first check and again when a
+	// check is answered with 401 Unauthorized. It implies KeepCookies.
+	Login *Login
+	// OAuth2 authenticates requests with an OAuth2 client credentials token
+	OAuth2 *customhttp.OAuth2Options
+	// Baggage holds values such as trace or tenant IDs that are sent with
+	// every request in the W3C Baggage header and echoed on every Change
+	Baggage map[string]string
+	// Labels are key-value pairs, such as env=staging, that select monitors
+	// for operations on many of them, see LabelSelector. Unlike Baggage
+	// they aren't sent with requests.
+	Labels map[string]string
+	// IgnoreSelectors remove parts of a page before it is compared, and
+	// WatchSelectors limit the comparison to the parts they match. Both
+	// take CSS selectors or XPath expressions, see ParseSelector.
+	IgnoreSelectors []string
+	WatchSelectors  []string
+	// AlertSelectors, if set, split changes in two tiers: a change of the
+	// parts they match is reported as usual, while a change elsewhere in the
+	// compared content is reported with Change.Informational set. They take
+	// the same selectors as WatchSelectors and require MethodHash or
+	// MethodLength.
+	AlertSelectors  []string
+	Method          ChangeDetectionMethod
+	CustomCompareFn func([]byte, []byte) (bool, string)
+	// OnError, if set, is called with a *CheckError for every failed
+	// attempt to fetch the URL, including those followed by a retry, and
+	// for content that can't be compared, such as an invalid feed. It is
+	// called on the monitor's goroutine and must not block.
+	OnError func(err error)
+	// OnCheck, if set, is called with the result of every check, whether it
+	// found a change, an error or nothing, e.g. to log each check. Like
+	// OnError it is called on the monitor's goroutine and must not block.
+	OnCheck       func(change Change)
+	RetryCount    int
+	RetryInterval time.Duration
+	// RetryPolicy, if set, decides which failed attempts are retried and
+	// when, instead of RetryCount and RetryInterval. Those are still used
+	// to tell when a monitor has stalled, see Monitor.Stalled, so set them
+	// to about the most the policy retries and waits.
+	RetryPolicy RetryPolicy
+	// FailureThreshold is the number of checks in a row that must fail
+	// before an error is reported, so that a single failed fetch doesn't
+	// alert. A recovery is reported when a check succeeds after an error
+	// was reported. Zero and 1 report every error.
+	FailureThreshold int
+	// ConfirmDelay, if set, confirms every change by fetching the URL again
+	// after this delay. The change is only reported if the content fetched
+	// again differs from the baseline too, so that content that flaps, e.g.
+	// between A/B test buckets, servers behind a load balancer or a
+	// transient error page, isn't reported. The change reported is that of
+	// the second fetch.
+	ConfirmDelay        time.Duration
 	FollowRedirects     bool
 	IncludeResponseBody bool
 	NormalizeWhitespace bool
 	ContentFilters      ContentFilterList
 	IgnoreTimestamps    bool
+	// Schedule runs checks at the times matching a cron expression instead
+	// of every Interval, e.g. only during business hours
+	Schedule *schedule.Cron
+	// At makes the monitor a one-time check: the content is recorded when
+	// the monitor starts and checked once more at At, after which the
+	// monitor finishes and a Manager removes it. Interval and Schedule are
+	// ignored.
+	At time.Time
+	// Until makes the monitor watch for a condition, such as a text that
+	// appears once registration opens. When a check meets it the monitor
+	// reports EventConditionMet and finishes.
+	Until Condition
+	// Deadline finishes the monitor with EventDeadlinePassed if it is still
+	// running at that time, e.g. because its condition was never met
+	Deadline time.Time
+	// Expect makes Manager.AddMonitorWithConfig fetch the URL before adding
+	// the monitor and fail if the response doesn't meet it, so that an
+	// error page doesn't become the baseline. It isn't saved with the
+	// state of the manager, as it only applies when the monitor is added.
+	Expect *Expectation
+	// Expires finishes the monitor with EventExpired at that time, e.g. two
+	// weeks after it was set up, so that monitors of a passing interest don't
+	// run forever. The event sums up the checks done until then.
+	Expires time.Time
+	// MaxChecks finishes the monitor with EventCompleted after that many
+	// checks, e.g. so a script or CI job watches a page a set number of
+	// times. Zero means no limit; one-time monitors ignore it.
+	MaxChecks int
+	// Jitter delays each check by a random duration up to Jitter, so that
+	// monitors with the same interval don't send their requests together
+	Jitter time.Duration
+	// DiffContextLines is the number of unchanged lines shown around each
+	// changed line of the diff
+	DiffContextLines int
+	// MaxDetailsLines and MaxDetailsBytes cap the size of Change.Details so
+	// notifications stay small. The complete diff is always in Change.Diff.
+	// Zero means no limit.
+	MaxDetailsLines int
+	MaxDetailsBytes int
+	// NoCache sends Cache-Control: no-cache and Pragma: no-cache, so
+	// caches and CDNs revalidate responses with the origin
+	NoCache bool
+	// CacheBust names a query parameter added to every request with a
+	// random value, so caches that ignore NoCache miss. Empty adds none.
+	CacheBust string
+	// AcceptEncoding replaces the Accept-Encoding of requests, e.g.
+	// "identity" to ask for uncompressed responses. Without it, and without
+	// an Accept-Encoding in Headers, customhttp.DefaultAcceptEncoding is
+	// sent. Responses are decoded whatever their encoding, so they compare
+	// by their content.
+	AcceptEncoding string
+	// MaxBodySize fails checks whose response body is larger, so huge
+	// responses can't exhaust memory. Zero means no limit.
+	MaxBodySize int64
+	// MaxSnapshotSize keeps only the first bytes of a response body for
+	// comparisons and diffs. The complete body is still hashed, so the hash
+	// and length methods notice changes beyond it. Zero keeps everything.
+	MaxSnapshotSize int64
+	// ExpectedStatus lists the status codes that count as up with
+	// MethodStatus. When set, a change is reported when the status moves
+	// between expected and unexpected codes instead of between classes.
+	ExpectedStatus []int
+	// Matches are the keywords and patterns watched with MethodKeyword
+	Matches []Match
+	// Keywords are the texts and patterns counted with MethodCount
+	Keywords []*Keyword
+	// CSVKeys are the columns identifying a row with MethodCSV, by default
+	// the first column
+	CSVKeys []string
+	// CSVDelimiter separates the columns with MethodCSV. When zero it is
+	// detected from the content type and the header line.
+	CSVDelimiter rune
+	// ImageThreshold is the percentage of pixels that must look different
+	// for MethodImage to report a change. Zero reports any difference.
+	ImageThreshold float64
+	// ImageDiffDir, if set, receives a PNG of every change found with
+	// MethodImage, with the changed pixels in red
+	ImageDiffDir string
+	// TailSeparator separates the segments of MethodTail, see
+	// ParseSeparator. Empty splits the content into lines.
+	TailSeparator string
+	// WebhookMatch lists conditions, see ParseCondition, that the payloads
+	// received by a webhook monitor must all meet to be reported, e.g.
+	// "json:action=published"; other payloads are dropped. WebhookFields
+	// are dotted JSON paths whose values make the details of a change
+	// instead of the whole payload. WebhookSecret, if set, must sign the
+	// requests delivering payloads, see api.Server. See IsWebhookURL.
+	WebhookMatch  []Condition
+	WebhookFields []string
+	WebhookSecret string
+	// Pagination follows the pages of an API and compares their combined
+	// items, see fetchPages
+	Pagination *Pagination
+	// Extract finds the number watched with MethodValue
+	Extract Extractor
+	// Thresholds report a change with MethodValue when the value crosses
+	// them, e.g. a price dropping below 100
+	Thresholds []Threshold
+	// Delta and DeltaPercent report a change with MethodValue only when the
+	// value moves by more than this amount or percentage since the previous
+	// check. Without them and without thresholds, any change is reported.
+	Delta        float64
+	DeltaPercent float64
+	// Representations are Accept header values, such as "text/html" and
+	// "application/json", each requested on every check and compared with
+	// its own baseline, e.g. to catch an API and its HTML page diverging.
+	// They replace any Accept header in Headers. Until is checked against
+	// the first representation.
+	Representations []string
+	// Variants are versions of the page served for different request
+	// headers, such as Accept-Language, Cookie or User-Agent, each
+	// requested on every check and compared with its own baseline. They
+	// require MethodHash or MethodLength and can't be combined with
+	// Representations. Until is checked against the first variant.
+	Variants []Variant
+	// MaintenanceWindows are quiet periods during which checks are skipped
+	// or changes are not notified, depending on the mode of each window
+	MaintenanceWindows schedule.Windows
+	// ProxyURL sends requests through an HTTP, HTTPS or SOCKS5 proxy
+	ProxyURL *url.URL
+	// TLS configures server verification and client certificates, e.g. for
+	// internal services with self-signed certificates or mutual TLS
+	TLS customhttp.TLSOptions
+	// RespectRobotsTxt skips checks of URLs that the robots.txt of their
+	// site disallows for the user agent of the monitor. The file is fetched
+	// and cached for a day.
+	RespectRobotsTxt bool
+	// Fetcher selects how the URL is loaded. FetcherBrowser renders it in a
+	// headless browser, waiting for WaitSelector, a CSS selector, to match
+	// or, without one, for the network to go idle.
+	Fetcher      Fetcher
+	WaitSelector string
+	// Browser renders pages with FetcherBrowser. Defaults to a headless
+	// browser launched on first use and shared by the monitors of a
+	// Manager.
+	Browser *browser.Browser
+	// Transport overrides the HTTP transport used for fetching, e.g. to
+	// inject a mock fetcher in tests and simulations
+	Transport http.RoundTripper
+	// Clock overrides the time source used for scheduling and timestamps.
+	// Defaults to RealClock.
+	Clock Clock
+	// Archive, if set, keeps the content of the first check and of every
+	// change, e.g. an *archive.Archive
+	Archive Archive
+	// AppendOnly treats the content as only ever appended to, like a log or
+	// a CSV export. When the server sent a strong ETag, checks request only
+	// the bytes after the content of the previous check with a Range
+	// request, checking only the end of that content. Appended content is
+	// reported as "N new lines appended" with a diff of the appended lines
+	// alone, while content that was rewritten or shrank is compared in full.
+	// It requires MethodHash or MethodLength.
+	AppendOnly bool
+	// CaptureHAR records the requests of each check, with their headers,
+	// timings and redirects, and attaches them as an HTTP archive named
+	// HARAttachment to the archived version of every change, e.g. to see
+	// which CDN node or cache answered. It requires an AttachmentArchive.
+	CaptureHAR bool
 }
 
 // Monitor watches a URL for changes
@@ -69,16 +583,62 @@ type Monitor struct {
 	config       Config
 	client       *http.Client
 	lastContent  []byte
+	lastDigest   bodyDigest
+	lastKind     string
+	lastVariants map[string][]byte
+	lastStatus   int
+	lastMatched  []bool
+	lastValue    *float64
+	lastCounts   []int
+	countSeries  []CountSample
+	lastTable    *table
+	lastImage    image.Image
+	tail         tailState
+	seenEntries  map[string]bool
+	latency      time.Duration
 	lastCheck    time.Time
+	nextCheck    time.Time
+	lastCycle    time.Time
+	firstCheck   time.Time
+	settled      bool
+	finished     bool
+	met          string
+	expired      <-chan time.Time
+	ends         <-chan time.Time
+	startDelay   time.Duration
+	limiter      *checkLimiter
+	domain       *domainGate
+	rates        *hostRateLimiter
+	robots       *robots.Cache
+	disallowed   bool
+	source       source
+	jar          *cookieJar
+	ownJar       http.CookieJar
+	har          *har.Recorder
+	session      session
 	changes      chan Change
 	stop         chan struct{}
+	stopOnce     sync.Once
 	ctx          context.Context
 	cancel       context.CancelFunc
 	mu           sync.RWMutex
 	checkCount   int64
+	changeCount  int64
+	errorCount   int64
 	status       string
 	isFirstCheck bool
+	paused       bool
+	failing      bool
+	failures     int64
+	lastError    string
+	events       chan Change
+	trigger      chan struct{}
+	received     chan payload
 	filters      ContentFilterList
+	// alertFilters select the parts of Config.AlertSelectors instead of
+	// those of Config.WatchSelectors, followed by the other filters
+	alertFilters ContentFilterList
+	clock        Clock
 }
 
 // DefaultConfig returns a default configuration
@@ -93,6 +653,10 @@ func DefaultConfig(url string) *Config {
 		FollowRedirects:     true,
 		NormalizeWhitespace: false,
 		IgnoreTimestamps:    false,
+		DiffContextLines:    DefaultDiffContextLines,
+		MaxDetailsLines:     DefaultMaxDetailsLines,
+		MaxDetailsBytes:     DefaultMaxDetailsBytes,
+		MaxBodySize:         DefaultMaxBodySize,
 	}
 }
 
@@ -103,43 +667,125 @@ func NewMonitor(url string, interval time.Duration) *Monitor {
 	return NewMonitorWithConfig(config)
 }
 
+// ClientSettings are the settings of a monitor's HTTP client that can be
+// changed while it runs, see Monitor.UpdateClient
+type ClientSettings struct {
+	Timeout         time.Duration
+	FollowRedirects bool
+	ProxyURL        *url.URL
+}
+
+// newClient creates the HTTP client of a monitor. With a recorder its
+// requests are recorded.
+func newClient(config *Config, jar http.CookieJar, recorder *har.Recorder) *http.Client {
+	client := customhttp.NewClient(&customhttp.ClientOptions{
+		Timeout:         config.Timeout,
+		FollowRedirects: config.FollowRedirects,
+		ProxyURL:        config.ProxyURL,
+		TLS:             config.TLS,
+		Transport:       config.Transport,
+		Jar:             jar,
+		OAuth2:          config.OAuth2,
+	})
+	if recorder != nil {
+		client.Transport = recorder.Transport(client.Transport)
+	}
+	return client
+}
+
+// eventBufferSize is the number of pause, resume and similar events a monitor
+// holds until they are sent on its changes channel
+const eventBufferSize = 16
+
 // NewMonitorWithConfig creates a new monitor with the given configuration
 func NewMonitorWithConfig(config *Config) *Monitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	clientOpts := &customhttp.ClientOptions{
-		Timeout:         config.Timeout,
-		FollowRedirects: config.FollowRedirects,
+	// Every monitor has a jar so it can join a group sharing cookies, but
+	// only monitors that keep cookies have one of their own
+	jar := &cookieJar{}
+	var ownJar http.CookieJar
+	if config.KeepCookies || len(config.Cookies) > 0 || config.Login != nil {
+		ownJar = newJar()
+		seedCookies(ownJar, config)
+		jar.use(ownJar)
 	}
 
-	client := customhttp.NewClient(clientOpts)
+	var recorder *har.Recorder
+	if config.CaptureHAR {
+		recorder = har.NewRecorder()
+	}
+	client := newClient(config, jar, recorder)
 
 	// Set up filters
-	var filters ContentFilterList
+	var filters, others ContentFilterList
 
 	// Add the provided filters
 	if config.ContentFilters != nil {
-		filters = append(filters, config.ContentFilters...)
+		others = append(others, config.ContentFilters...)
 	}
 
 	// Add default timestamp filter if configured
 	if config.IgnoreTimestamps {
 		tsFilter, _ := NewTimestampFilter()
 		if tsFilter != nil {
-			filters = append(filters, tsFilter)
+			others = append(others, tsFilter)
+		}
+	}
+
+	// Selecting the watched parts of the page comes first, as the other
+	// filters may break its markup. Invalid selectors are rejected by
+	// Manager.AddMonitorWithConfig.
+	if len(config.WatchSelectors)+len(config.IgnoreSelectors) > 0 {
+		if selectors, err := NewSelectorFilter(config.WatchSelectors, config.IgnoreSelectors); err == nil {
+			filters = append(filters, selectors)
+		}
+	}
+	filters = append(filters, others...)
+
+	// The parts of alert selectors are compared with the same filters
+	var alertFilters ContentFilterList
+	if len(config.AlertSelectors) > 0 {
+		if selectors, err := NewSelectorFilter(config.AlertSelectors, config.IgnoreSelectors); err == nil {
+			alertFilters = append(ContentFilterList{selectors}, others...)
 		}
 	}
 
-	return &Monitor{
+	clock := config.Clock
+	if clock == nil {
+		clock = RealClock{}
+	}
+
+	m := &Monitor{
 		config:       *config,
 		client:       client,
+		nextCheck:    config.At,
 		changes:      make(chan Change),
 		stop:         make(chan struct{}),
 		ctx:          ctx,
 		cancel:       cancel,
 		isFirstCheck: true,
+		events:       make(chan Change, eventBufferSize),
+		trigger:      make(chan struct{}, 1),
+		received:     make(chan payload, webhookBufferSize),
 		filters:      filters,
+		alertFilters: alertFilters,
+		clock:        clock,
+		jar:          jar,
+		ownJar:       ownJar,
+		har:          recorder,
+		source:       newSource(config),
+	}
+	// Mailboxes have no robots.txt
+	if _, mailbox := m.source.(mailboxSource); config.RespectRobotsTxt && !mailbox {
+		m.robots = robots.NewCache()
+		m.robots.Now = clock.Now
 	}
+	// Changes share the baggage and selectors read the labels, so keep them
+	// from being modified by the caller
+	m.config.Baggage = maps.Clone(config.Baggage)
+	m.config.Labels = maps.Clone(config.Labels)
+	return m
 }
 
 // Start begins monitoring the URL for changes
@@ -148,71 +794,679 @@ func (m *Monitor) Start() <-chan Change {
 	return m.changes
 }
 
-// Stop stops the monitoring
+// Stop stops the monitoring. It is safe to call more than once.
 func (m *Monitor) Stop() {
-	m.cancel()
-	close(m.stop)
+	m.stopOnce.Do(func() {
+		m.cancel()
+		close(m.stop)
+		m.source.close()
+	})
 }
 
 // run is the main monitoring loop
 func (m *Monitor) run() {
-	ticker := time.NewTicker(m.config.Interval)
-	defer ticker.Stop()
 	defer close(m.changes)
+	defer m.setCycle(time.Time{})
+
+	m.setCycle(m.clock.Now())
+	if !m.config.Deadline.IsZero() {
+		m.expired = m.clock.After(m.config.Deadline.Sub(m.clock.Now()))
+	}
+	if !m.config.Expires.IsZero() {
+		m.ends = m.clock.After(m.config.Expires.Sub(m.clock.Now()))
+	}
+	if IsWebhookURL(m.config.URL) {
+		m.runWebhook()
+		return
+	}
+	if !m.config.At.IsZero() {
+		m.runOnce()
+		return
+	}
+	if m.config.Schedule != nil {
+		m.runScheduled()
+		return
+	}
+
+	m.mu.Lock()
+	m.firstCheck = m.clock.Now().Add(m.startDelay)
+	m.mu.Unlock()
+
+	// Wait for the staggered start, if any, so the ticker is offset too
+	if m.startDelay > 0 {
+		if !m.wait(m.clock.After(m.startDelay)) {
+			return
+		}
+		m.setCycle(m.clock.Now())
+	}
+
+	ticker := m.clock.NewTicker(m.config.Interval)
+	defer ticker.Stop()
 
 	// Perform first check immediately
-	m.performCheck()
+	m.scheduledCheck()
 
-	for {
+	for !m.Finished() {
 		select {
-		case <-ticker.C:
-			m.performCheck()
+		case <-ticker.C():
+			m.setCycle(m.clock.Now())
+			m.scheduledCheck()
+		case event := <-m.events:
+			m.changes <- event
+		case <-m.trigger:
+			m.triggeredCheck()
+		case <-m.expired:
+			m.expire()
+		case <-m.ends:
+			m.end()
 		case <-m.ctx.Done():
 			return
 		}
 	}
 }
 
-// performCheck checks the URL for changes
-func (m *Monitor) performCheck() {
+// runScheduled is the run loop of monitors with a cron schedule. Rather than
+// checking immediately, it waits for the next time matching the schedule,
+// computed after every check so slow checks don't cause missed runs to pile
+// up.
+func (m *Monitor) runScheduled() {
+	for !m.Finished() {
+		now := m.clock.Now()
+		next, err := m.config.Schedule.Next(now)
+
+		m.mu.Lock()
+		m.nextCheck = next
+		m.mu.Unlock()
+
+		// A schedule that never matches again leaves only events to send
+		var due <-chan time.Time
+		if err == nil {
+			due = m.clock.After(next.Sub(now))
+		}
+
+		if !m.wait(due) {
+			return
+		}
+		m.setCycle(m.clock.Now())
+		m.scheduledCheck()
+	}
+}
+
+// runOnce is the run loop of one-time monitors. It records the content right
+// away, checks it again at Config.At and reports that it completed. A
+// baseline that can't be fetched, e.g. because the page isn't published yet,
+// is reported as an error and the check at At as a recovery.
+func (m *Monitor) runOnce() {
+	m.scheduledCheck()
+
+	if m.Finished() || !m.wait(m.clock.After(m.config.At.Sub(m.clock.Now()))) {
+		return
+	}
+	m.setCycle(m.clock.Now())
+
 	m.mu.Lock()
-	m.checkCount++
-	m.status = "checking"
+	m.nextCheck = time.Time{}
 	m.mu.Unlock()
 
-	var change Change
-	var content []byte
-	var err error
+	// The time was chosen on purpose, so windows and jitter don't apply
+	if !m.IsPaused() {
+		m.performCheck()
+	}
+
+	if !m.Finished() {
+		m.complete(EventCompleted, "")
+	}
+}
+
+// complete finishes the monitor, sending pending events followed by a final
+// event. The run loop returns once the monitor is finished.
+func (m *Monitor) complete(event EventType, details string) {
+	m.mu.Lock()
+	m.finished = true
+	m.nextCheck = time.Time{}
+	m.mu.Unlock()
+
+	for len(m.events) > 0 {
+		m.changes <- <-m.events
+	}
+	m.changes <- Change{URL: m.config.URL, Event: event, Timestamp: m.clock.Now(), Details: details, Baggage: m.config.Baggage}
+}
+
+// expire finishes a monitor whose deadline passed
+func (m *Monitor) expire() {
+	details := fmt.Sprintf("Deadline %s passed", m.config.Deadline.Format(time.RFC3339))
+	if m.config.Until != nil {
+		details += " before " + m.config.Until.Description() + " was found"
+	}
+	m.complete(EventDeadlinePassed, details)
+}
+
+// end finishes a monitor that reached Config.Expires, summing up its checks
+func (m *Monitor) end() {
+	m.mu.Lock()
+	details := fmt.Sprintf("Expired after %s with %s and %s", countOf(m.checkCount, "check"), countOf(m.changeCount, "change"), countOf(m.errorCount, "error"))
+	m.mu.Unlock()
+	m.complete(EventExpired, details)
+}
+
+// countOf formats a number of things, e.g. "1 check" or "3 checks"
+func countOf(n int64, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Finished reports whether a monitor has finished: a one-time monitor that
+// has done its check, a monitor that has done its MaxChecks checks, or a
+// monitor whose condition was met, whose deadline passed or that expired
+func (m *Monitor) Finished() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.finished
+}
 
-	for i := 0; i <= m.config.RetryCount; i++ {
-		if i > 0 {
-			time.Sleep(m.config.RetryInterval)
+// scheduledCheck performs a check the schedule is due for, unless the monitor
+// is paused or in a maintenance window. The check is delayed by a random
+// amount up to Config.Jitter.
+func (m *Monitor) scheduledCheck() {
+	defer m.settle()
+
+	if m.IsPaused() || m.inWindow(schedule.ModeSkip) || m.tooSoon() {
+		return
+	}
+
+	if m.config.Jitter > 0 {
+		if !m.wait(m.clock.After(rand.N(m.config.Jitter))) {
+			return
 		}
+	}
 
-		content, change, err = m.fetchContent()
-		if err == nil {
-			break
+	m.performCheck()
+}
+
+// settle records that the first check of the monitor was done or skipped
+func (m *Monitor) settle() {
+	m.mu.Lock()
+	m.settled = true
+	m.mu.Unlock()
+}
+
+// Starting reports whether the first check of an interval monitor is due but
+// hasn't been done yet. Monitors waiting for a staggered start, paused and
+// scheduled monitors are not starting.
+func (m *Monitor) Starting() bool {
+	if m.config.Schedule != nil {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.paused || m.settled || m.finished {
+		return false
+	}
+	return m.firstCheck.IsZero() || !m.clock.Now().Before(m.firstCheck)
+}
+
+// tooSoon reports whether a scheduled monitor was checked more recently than
+// its domain policy's MinInterval allows. Monitors checked every Interval
+// have their interval raised instead.
+func (m *Monitor) tooSoon() bool {
+	if m.config.Schedule == nil || m.domain == nil || m.domain.policy.MinInterval <= 0 {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.lastCheck.IsZero() && m.clock.Now().Sub(m.lastCheck) < m.domain.policy.MinInterval
+}
+
+// triggeredCheck performs a check requested with Trigger. Unlike scheduled
+// checks it ignores maintenance windows and jitter, since someone asked for it.
+func (m *Monitor) triggeredCheck() {
+	if m.IsPaused() {
+		return
+	}
+	m.performCheck()
+	m.settle()
+}
+
+// wait blocks until ch fires, sending queued events, performing triggered
+// checks and reporting received payloads in the meantime. It returns false
+// if the monitor was stopped or finished.
+func (m *Monitor) wait(ch <-chan time.Time) bool {
+	for !m.Finished() {
+		select {
+		case <-ch:
+			return true
+		case event := <-m.events:
+			m.changes <- event
+		case <-m.trigger:
+			m.triggeredCheck()
+		case p := <-m.received:
+			m.receive(p)
+		case <-m.expired:
+			m.expire()
+		case <-m.ends:
+			m.end()
+		case <-m.ctx.Done():
+			return false
 		}
+	}
+	return false
+}
 
-		// Last attempt, report the error
-		if i == m.config.RetryCount {
-			change = Change{
-				URL:       m.config.URL,
-				Timestamp: time.Now(),
-				Error:     err.Error(),
+// acquire waits until the monitor may send a request: until its domain
+// policy and the rate limit of its host allow another request and the
+// manager's concurrent check limit has a free slot. While waiting the status of the monitor is
+// "queued". It returns false if the monitor was stopped while waiting.
+func (m *Monitor) acquire() bool {
+	queued := false
+	queue := func() {
+		if !queued {
+			queued = true
+			m.setStatus("queued")
+		}
+	}
+	take := func(l *checkLimiter) bool {
+		if l.tryAcquire() {
+			return true
+		}
+		queue()
+		return l.acquire(m.ctx)
+	}
+	defer func() {
+		if queued {
+			m.setStatus("checking")
+		}
+	}()
+
+	if m.domain != nil {
+		if !take(m.domain.parallel) {
+			return false
+		}
+
+		if delay := m.domain.reserve(m.clock.Now()); delay > 0 {
+			queue()
+			select {
+			case <-m.clock.After(delay):
+			case <-m.ctx.Done():
+				m.domain.parallel.release()
+				return false
 			}
 		}
 	}
 
-	if err != nil {
+	if m.rates != nil {
+		if delay := m.rates.reserve(m.config.URL, m.clock.Now()); delay > 0 {
+			queue()
+			select {
+			case <-m.clock.After(delay):
+			case <-m.ctx.Done():
+				if m.domain != nil {
+					m.domain.parallel.release()
+				}
+				return false
+			}
+		}
+	}
+
+	if m.limiter != nil && !take(m.limiter) {
+		if m.domain != nil {
+			m.domain.parallel.release()
+		}
+		return false
+	}
+
+	return true
+}
+
+// release frees the slots taken by acquire
+func (m *Monitor) release() {
+	if m.limiter != nil {
+		m.limiter.release()
+	}
+	if m.domain != nil {
+		m.domain.parallel.release()
+	}
+}
+
+// setStatus sets the status reported by GetStatus
+func (m *Monitor) setStatus(status string) {
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+}
+
+// delayStart delays the first check of a monitor that hasn't been started
+// yet, so monitors started together don't all fire at once
+func (m *Monitor) delayStart(d time.Duration) {
+	m.startDelay = d
+}
+
+// NextCheck returns the time of the next scheduled check of a monitor with a
+// cron schedule, or of the check of a one-time monitor. It is zero for
+// monitors checked every Interval, for schedules that never match again and
+// for one-time monitors that are done.
+func (m *Monitor) NextCheck() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.nextCheck
+}
+
+// setCycle records the time the run loop last woke up to check, whether or
+// not the check was skipped
+func (m *Monitor) setCycle(t time.Time) {
+	m.mu.Lock()
+	m.lastCycle = t
+	m.mu.Unlock()
+}
+
+// Stalled reports whether the run loop of a started monitor has not woken up
+// for longer than its interval or schedule allows, including the time a slow
+// check with all of its retries may take. Stopped monitors are not stalled.
+func (m *Monitor) Stalled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.lastCycle.IsZero() {
+		return false
+	}
+
+	fetch := m.config.Timeout*time.Duration(m.config.RetryCount+1) +
+		m.config.RetryInterval*time.Duration(m.config.RetryCount)
+	budget := m.config.Jitter + fetch
+	if m.config.ConfirmDelay > 0 {
+		budget += m.config.ConfirmDelay + fetch
+	}
+
+	deadline := m.lastCycle.Add(m.config.Interval + budget)
+	if m.config.Schedule != nil || !m.config.At.IsZero() {
+		// Schedules that never match again never wake up
+		if m.nextCheck.IsZero() {
+			return false
+		}
+		deadline = m.nextCheck.Add(budget)
+	}
+
+	return m.clock.Now().After(deadline)
+}
+
+// performCheck checks the URL for changes and reports the result, unless
+// robots.txt disallows it
+func (m *Monitor) performCheck() {
+	if m.disallowedByRobots() {
+		return
+	}
+
+	change, report := m.check()
+	m.checked(change)
+
+	m.mu.Lock()
+	if change.Error != "" {
+		m.errorCount++
+	} else if report && change.HasChanged {
+		m.changeCount++
+	}
+	m.mu.Unlock()
+
+	// Report a recovery before the change found by the same check
+	for len(m.events) > 0 {
+		m.changes <- <-m.events
+	}
+
+	if report && len(change.entries) > 0 {
+		for _, entry := range change.entries {
+			c := change
+			c.Entry = &entry
+			c.Details = entry.describe()
+			c.entries = nil
+			m.changes <- c
+		}
+	} else if report && len(change.segments) > 0 {
+		for _, segment := range change.segments {
+			c := change
+			c.Segment = &segment
+			c.Details = segment.Text
+			c.segments = nil
+			m.changes <- c
+		}
+	} else if report {
 		m.changes <- change
+	}
+
+	m.mu.Lock()
+	met := m.met
+	done := m.config.MaxChecks > 0 && m.config.At.IsZero() && m.checkCount >= int64(m.config.MaxChecks)
+	m.mu.Unlock()
+	switch {
+	case met != "":
+		m.complete(EventConditionMet, met)
+	case done:
+		m.complete(EventCompleted, fmt.Sprintf("%d checks done", m.config.MaxChecks))
+	}
+}
+
+// inWindow reports whether the monitor is in a maintenance window of the
+// given mode
+func (m *Monitor) inWindow(mode schedule.Mode) bool {
+	return m.config.MaintenanceWindows.Active(m.clock.Now(), mode) != nil
+}
+
+// disallowedByRobots reports whether the robots.txt of the site disallows
+// checking the URL of a monitor that respects it. The first check skipped
+// in a row reports EventDisallowed, and the status of the monitor is
+// "disallowed" until robots.txt allows the URL again.
+func (m *Monitor) disallowedByRobots() bool {
+	if m.robots == nil {
+		return false
+	}
+	u, err := url.Parse(m.config.URL)
+	if err != nil {
+		return false
+	}
+
+	userAgent := version.UserAgent()
+	for key, value := range m.config.Headers {
+		if strings.EqualFold(key, "User-Agent") {
+			userAgent = value
+		}
+	}
+	allowed := m.robots.Get(m.ctx, m.httpClient(), u, userAgent).Allowed(u)
+
+	m.mu.Lock()
+	first := !allowed && !m.disallowed
+	m.disallowed = !allowed
+	if !allowed {
+		m.status = "disallowed"
+	}
+	m.mu.Unlock()
+
+	if first {
+		details := fmt.Sprintf("robots.txt of %s disallows %s for %s; checks are skipped", u.Host, u.RequestURI(), userAgent)
+		select {
+		case m.events <- Change{URL: m.config.URL, Event: EventDisallowed, Timestamp: m.clock.Now(), Details: details, Baggage: m.config.Baggage}:
+		default:
+		}
+	}
+	return !allowed
+}
+
+// queueEvent queues an event to be sent on the changes channel. Events are
+// dropped if the buffer is full.
+func (m *Monitor) queueEvent(event EventType) {
+	select {
+	case m.events <- Change{URL: m.config.URL, Event: event, Timestamp: m.clock.Now(), Baggage: m.config.Baggage}:
+	default:
+	}
+}
+
+// Check performs a single check synchronously and returns its result without
+// sending it on the changes channel. HasChanged is set if the content differs
+// from the previous check and Error is set if the URL could not be fetched.
+// It is useful for one-off checks and for replaying recorded sessions. With
+// MethodFeed and MethodTail, the new entries or segments of a check are
+// listed in a single change.
+func (m *Monitor) Check() Change {
+	change, _ := m.check()
+	m.checked(change)
+	return change
+}
+
+// checked passes the result of a check to Config.OnCheck, if set
+func (m *Monitor) checked(change Change) {
+	if m.config.OnCheck == nil || m.ctx.Err() != nil {
 		return
 	}
+	change.entries = nil
+	change.segments = nil
+	m.config.OnCheck(change)
+}
+
+// check fetches the URL, retrying on failure, and compares the content with
+// the previous check. It returns the resulting change and whether it should
+// be reported.
+func (m *Monitor) check() (Change, bool) {
+	m.mu.Lock()
+	m.checkCount++
+	m.status = "checking"
+	m.mu.Unlock()
+	return m.checkContent(m.config.ConfirmDelay > 0)
+}
+
+// checkContent fetches the URL and compares the content for check. With
+// confirm, a change is only reported once confirmed, see Monitor.confirm.
+func (m *Monitor) checkContent(confirm bool) (Change, bool) {
+	if m.har != nil {
+		m.har.Reset()
+	}
 
-	changed, details := m.detectChange(content)
+	var content []byte
+	var variants [][]byte
+	var change Change
+	var err error
+	if len(m.variants()) > 0 {
+		variants, change, err = m.fetchVariants()
+		if err == nil {
+			content = variants[0]
+		}
+	} else {
+		content, change, err = m.fetch(nil)
+	}
+	if errors.Is(err, ErrMonitorStopped) {
+		return change, false
+	}
+
+	if err != nil {
+		return m.fail(change)
+	}
+
+	decoded, err := m.decode(content, change.ContentType)
+	if err != nil {
+		m.reportError(0, true, change.StatusCode, err)
+		change.Error = err.Error()
+		return m.fail(change)
+	}
 
 	m.mu.Lock()
-	m.lastCheck = time.Now()
+	recovered := m.failing
+	m.failing = false
+	m.failures = 0
+	m.lastError = ""
+	m.mu.Unlock()
+	if recovered {
+		m.queueEvent(EventRecovery)
+	}
+
+	var saved baselineState
+	if confirm {
+		saved = m.saveBaseline()
+	}
+
+	if m.config.Until != nil {
+		if met, details := m.config.Until.Met(content); met {
+			m.mu.Lock()
+			m.met = details
+			m.mu.Unlock()
+		}
+	}
+
+	var changed bool
+	var details string
+	var hunks []DiffHunk
+	var values *ValueChange
+	var added []FeedEntry
+	var counts []CountChange
+	var rows []RowChange
+	var imageChange *ImageChange
+	var segments []Segment
+	var stats contentStats
+	// Set for changes outside of Config.AlertSelectors
+	var informational bool
+	switch m.config.Method {
+	case MethodStatus:
+		changed, details = m.detectStatusChange(change.StatusCode)
+	case MethodKeyword:
+		changed, details = m.detectMatchChange(content)
+	case MethodValue:
+		changed, details, values = m.detectValueChange(decoded.value)
+	case MethodFeed:
+		changed, details, added = m.detectFeedChange(decoded.entries)
+	case MethodCount:
+		changed, details, counts = m.detectCountChange(content)
+	case MethodCSV:
+		changed, details, rows = m.detectCSVChange(decoded.table)
+	case MethodImage:
+		changed, details, imageChange = m.detectImageChange(decoded.image)
+	case MethodTail:
+		changed, details, segments = m.detectTailChange(content, change)
+	case MethodHash, MethodLength:
+		if variants != nil {
+			changed, details, hunks, change.Variants = m.detectVariantChange(variants)
+			break
+		}
+		m.mu.Lock()
+		wasBinary := m.lastKind != ""
+		last := m.lastDigest
+		m.mu.Unlock()
+		if kind := binaryKind(change.ContentType, content); kind != "" || wasBinary {
+			if changed, details = m.detectBinaryChange(content, kind, change.digest); changed {
+				stats = m.digestStats(last)
+			}
+			break
+		}
+		if change.digest.truncated {
+			if changed, details, hunks = m.detectTruncatedChange(content, change.digest); changed {
+				stats = m.digestStats(last)
+			}
+			break
+		}
+		old := m.baseline()
+		if m.config.AppendOnly {
+			var appended bool
+			if changed, details, hunks, appended = m.detectAppend(content, change); appended {
+				if changed {
+					stats = m.contentStats(old, content)
+				}
+				break
+			}
+		}
+		if changed, details, hunks = m.detectChange(content); changed {
+			stats = m.contentStats(old, content)
+			informational = m.alertFilters != nil && !m.alertChanged(old, content)
+		}
+		m.mu.Lock()
+		m.lastDigest = change.digest
+		m.mu.Unlock()
+	default:
+		old := m.baseline()
+		if changed, details, hunks = m.detectChange(content); changed {
+			stats = m.contentStats(old, content)
+		}
+	}
+
+	m.mu.Lock()
+	m.lastCheck = m.clock.Now()
 	m.status = "idle"
 	isFirst := m.isFirstCheck
 	m.isFirstCheck = false
@@ -220,112 +1474,398 @@ func (m *Monitor) performCheck() {
 
 	// Don't report a change on the first check
 	if isFirst {
-		return
+		// Only errors archiving changes are reported; they have details
+		m.archive(content, change.ContentType)
+		return change, false
+	}
+
+	if changed && confirm {
+		return m.confirm(saved)
 	}
 
 	if changed {
+		if err := m.archive(content, change.ContentType); err != nil {
+			details += "\nSnapshot not archived: " + err.Error()
+		} else if err := m.attachHAR(); err != nil {
+			details += "\nHAR not archived: " + err.Error()
+		}
 		change.HasChanged = true
+		change.Event = EventChange
+		change.Silenced = m.inWindow(schedule.ModeSilence)
+		change.Informational = informational
 		change.Details = details
-		m.changes <- change
+		change.Value = values
+		change.Counts = counts
+		change.Rows = rows
+		change.Image = imageChange
+		change.entries = added
+		change.segments = segments
+		change.Hunks = hunks
+		change.Diff = FormatHunks(hunks)
+		change.OldHash, change.NewHash = stats.oldHash, stats.newHash
+		change.OldLength, change.NewLength = stats.oldLength, stats.newLength
+		change.FilteredBy = stats.filteredBy
+		return change, true
 	}
+
+	return change, false
 }
 
-// fetchContent retrieves the content from the URL
-func (m *Monitor) fetchContent() ([]byte, Change, error) {
-	req, err := http.NewRequestWithContext(m.ctx, "GET", m.config.URL, nil)
+// decodedContent is the content as read by the methods that compare more
+// than its bytes
+type decodedContent struct {
+	value   float64
+	entries []FeedEntry
+	table   *table
+	image   image.Image
+}
+
+// decode reads content the way the monitor's method compares it. Content
+// compared as is needs no decoding.
+func (m *Monitor) decode(content []byte, contentType string) (decodedContent, error) {
+	var decoded decodedContent
+	var err error
+	switch m.config.Method {
+	case MethodValue:
+		decoded.value, err = m.extractValue(content)
+	case MethodFeed:
+		decoded.entries, err = ParseFeed(content)
+	case MethodCSV:
+		decoded.table, err = m.parseTable(content, contentType)
+	case MethodImage:
+		decoded.image, err = decodeImage(content)
+	}
+	return decoded, err
+}
+
+// archive saves content in Config.Archive, if set
+func (m *Monitor) archive(content []byte, contentType string) error {
+	if m.config.Archive == nil {
+		return nil
+	}
+	return m.config.Archive.Save(m.config.URL, m.clock.Now(), content, contentType)
+}
+
+// attachHAR attaches the requests of the check to the version archived
+// last, if Config.CaptureHAR is set
+func (m *Monitor) attachHAR() error {
+	if m.har == nil || m.har.Len() == 0 {
+		return nil
+	}
+	archive, ok := m.config.Archive.(AttachmentArchive)
+	if !ok {
+		return errors.New("the archive doesn't keep attachments")
+	}
+	data, err := json.MarshalIndent(m.har.File(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return archive.AttachAt(m.config.URL, m.clock.Now(), HARAttachment, data, har.ContentType)
+}
+
+// fail counts a failed check and turns change, whose Error is set, into an
+// error report, which is only reported once Config.FailureThreshold checks in
+// a row failed. The monitor is marked as failing then; until then its status
+// is "erroring".
+func (m *Monitor) fail(change Change) (Change, bool) {
+	change.Event = EventError
+	change.Silenced = m.inWindow(schedule.ModeSilence)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures++
+	m.failing = m.failures >= int64(m.config.FailureThreshold)
+	m.lastError = change.Error
+	m.status = "erroring"
+	if m.failing {
+		m.status = "failing"
+	}
+	return change, m.failing
+}
+
+// reportError passes a failure of a check to Config.OnError, if set
+func (m *Monitor) reportError(attempt int, final bool, statusCode int, err error) {
+	if m.config.OnError == nil || m.ctx.Err() != nil {
+		return
+	}
+	m.config.OnError(&CheckError{
+		URL:        m.config.URL,
+		Time:       m.clock.Now(),
+		Attempt:    attempt,
+		Final:      final,
+		StatusCode: statusCode,
+		Err:        err,
+	})
+}
+
+// fetch fetches the URL, retrying on failure. The headers of a variant,
+// if any, replace those of the monitor. On failure the returned change holds the error of the
+// last attempt. It returns ErrMonitorStopped if the monitor was stopped
+// before the URL could be fetched.
+func (m *Monitor) fetch(variant map[string]string) ([]byte, Change, error) {
+	policy := m.retryPolicy()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !m.acquire() {
+			return nil, Change{}, ErrMonitorStopped
+		}
+		var content []byte
+		var change Change
+		content, change, err = m.fetchContent(variant)
+		m.release()
+		if err == nil {
+			return content, change, nil
+		}
+		retry := policy.ShouldRetry(err, failedResponse(change, err), attempt)
+		m.reportError(attempt, !retry, change.StatusCode, err)
+		if !retry {
+			break
+		}
+
+		select {
+		case <-m.clock.After(policy.NextDelay(attempt)):
+		case <-m.ctx.Done():
+			return nil, Change{}, ErrMonitorStopped
+		}
+	}
+
+	// Report the error of the last attempt
+	change := Change{
+		URL:       m.config.URL,
+		Timestamp: m.clock.Now(),
+		Error:     err.Error(),
+		Baggage:   m.config.Baggage,
+	}
+	return nil, change, err
+}
+
+// Fingerprint fetches the URL once, retrying on failure, and returns the
+// hex-encoded SHA-256 hash of its content after filters and normalization,
+// i.e. of the content compared between checks. It doesn't change the
+// baseline of the monitor. For a body cut at Config.MaxSnapshotSize it is
+// the hash of the complete body as received. On failure the hash is empty
+// and the error is set in the returned change.
+func (m *Monitor) Fingerprint() (string, Change) {
+	content, change, err := m.fetch(nil)
+	if errors.Is(err, ErrMonitorStopped) {
+		change = Change{
+			URL:       m.config.URL,
+			Timestamp: m.clock.Now(),
+			Error:     err.Error(),
+			Baggage:   m.config.Baggage,
+		}
+	}
 	if err != nil {
-		return nil, Change{}, err
+		change.Event = EventError
+		return "", change
+	}
+
+	// Filters can't be applied to the part of the body that wasn't kept
+	if change.digest.truncated {
+		return hex.EncodeToString(change.digest.hash), change
 	}
+	return hex.EncodeToString(m.calculateHash(m.prepare(content))), change
+}
+
+// ContentHash returns the hash of the baseline the way Fingerprint returns
+// it for a fetched page, without fetching it, so that monitors serving the
+// same content can be found. It is empty before the first successful check
+// and for methods that don't keep the content.
+func (m *Monitor) ContentHash() string {
+	m.mu.RLock()
+	content, digest := m.lastContent, m.lastDigest
+	m.mu.RUnlock()
+
+	if digest.truncated {
+		return hex.EncodeToString(digest.hash)
+	}
+	if content == nil {
+		return ""
+	}
+	return hex.EncodeToString(m.calculateHash(m.prepare(content)))
+}
+
+// fetchContent retrieves the content from the URL
+func (m *Monitor) fetchContent(variant map[string]string) ([]byte, Change, error) {
+	return m.source.fetch(m, variant)
+}
 
-	// Add custom headers
-	customhttp.AddHeaders(req, m.config.Headers, version.UserAgent())
+// fetchPage retrieves the content of pageURL, the monitor's URL or one of
+// its pages, along with the response headers. header, if set, is added to
+// the request as is, e.g. for a range request.
+func (m *Monitor) fetchPage(pageURL string, variant map[string]string, header http.Header) ([]byte, Change, http.Header, error) {
+	req, err := m.newRequest(pageURL, variant)
+	if err != nil {
+		return nil, Change{}, nil, err
+	}
+	maps.Copy(req.Header, header)
 
-	resp, err := m.client.Do(req)
+	start := m.clock.Now()
+	resp, err := m.httpClient().Do(req)
 	if err != nil {
-		return nil, Change{}, err
+		return nil, Change{}, nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && m.config.Login != nil {
+		// The session expired: log in again and retry once
+		resp.Body.Close()
+		if err := m.relogin(); err != nil {
+			return nil, Change{}, nil, err
+		}
+		if req, err = m.newRequest(pageURL, variant); err != nil {
+			return nil, Change{}, nil, err
+		}
+		maps.Copy(req.Header, header)
+		start = m.clock.Now()
+		if resp, err = m.httpClient().Do(req); err != nil {
+			return nil, Change{}, nil, err
+		}
 	}
 	defer resp.Body.Close()
 
 	change := Change{
 		URL:         m.config.URL,
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		StatusCode:  resp.StatusCode,
 		ContentType: resp.Header.Get("Content-Type"),
+		Latency:     m.clock.Now().Sub(start),
+		Baggage:     m.config.Baggage,
+	}
+
+	m.mu.Lock()
+	m.latency = change.Latency
+	m.mu.Unlock()
+
+	// Any status is a valid result for status checks; the body is not needed
+	if m.config.Method == MethodStatus {
+		return nil, change, resp.Header, nil
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, change, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, change, nil, &StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
 	}
 
-	content, err := io.ReadAll(resp.Body)
+	body, err := customhttp.DecodeBody(resp)
 	if err != nil {
-		return nil, change, err
+		return nil, change, nil, err
 	}
+	defer body.Close()
 
-	return content, change, nil
+	content, digest, err := readBody(body, m.config.MaxBodySize, m.config.MaxSnapshotSize)
+	if err != nil {
+		return nil, change, nil, err
+	}
+	change.digest = digest
+
+	return content, change, resp.Header, nil
 }
 
-// detectChange checks if the content has changed
-func (m *Monitor) detectChange(content []byte) (bool, string) {
+// detectChange checks if the content has changed. It returns a summary of
+// the change, capped to the configured size, and the complete diff.
+func (m *Monitor) detectChange(content []byte) (bool, string, []DiffHunk) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// If this is the first check, just store the content
-	if m.lastContent == nil {
-		m.lastContent = content
-		return false, ""
+	changed, details, hunks := m.compare(&m.lastContent, content)
+	if !changed {
+		return false, "", nil
 	}
+	return true, truncateDetails(details, m.config.MaxDetailsLines, m.config.MaxDetailsBytes), hunks
+}
 
-	// Apply filters to content if any are defined
-	compareContent := content
-	compareLast := m.lastContent
-
-	// Apply content filters
-	if len(m.filters) > 0 {
-		compareContent = m.filters.Apply(compareContent)
-		compareLast = m.filters.Apply(compareLast)
+// compare compares content with the baseline in last, replacing the
+// baseline if it changed. The details are not capped. m.mu must be held.
+func (m *Monitor) compare(last *[]byte, content []byte) (bool, string, []DiffHunk) {
+	// If this is the first check, just store the content
+	if *last == nil {
+		*last = content
+		return false, "", nil
 	}
 
-	// Normalize content if configured
-	if m.config.NormalizeWhitespace {
-		compareContent = m.normalizeContent(compareContent)
-		compareLast = m.normalizeContent(compareLast)
-	}
+	compareContent := m.prepare(content)
+	compareLast := m.prepare(*last)
+
+	var changed bool
+	var details string
 
 	switch m.config.Method {
 	case MethodHash:
 		currentHash := m.calculateHash(compareContent)
 		lastHash := m.calculateHash(compareLast)
-		changed := !byteSliceEqual(currentHash, lastHash)
-
+		changed = !byteSliceEqual(currentHash, lastHash)
 		if changed {
-			details := m.findDifference(compareLast, compareContent)
-			m.lastContent = content // Store the original content
-			return true, details
+			details = m.findDifference(compareLast, compareContent)
 		}
 
 	case MethodLength:
-		oldLen := len(compareLast)
-		newLen := len(compareContent)
-
-		if oldLen != newLen {
-			details := m.findDifference(compareLast, compareContent)
-			m.lastContent = content // Store the original content
-			return true, details
+		changed = len(compareLast) != len(compareContent)
+		if changed {
+			details = m.findDifference(compareLast, compareContent)
 		}
 
 	case MethodCustom:
 		if m.config.CustomCompareFn != nil {
-			changed, details := m.config.CustomCompareFn(compareLast, compareContent)
-
-			if changed {
-				m.lastContent = content // Store the original content
-				return true, details
-			}
+			changed, details = m.config.CustomCompareFn(compareLast, compareContent)
 		}
 	}
 
-	return false, ""
+	if !changed {
+		return false, "", nil
+	}
+
+	*last = content // Store the original content
+
+	// Custom comparisons describe changes themselves
+	hunks := LineDiff(compareLast, compareContent, m.config.DiffContextLines)
+	if len(hunks) > 0 && m.config.Method != MethodCustom {
+		details += "\n" + FormatHunks(hunks)
+	}
+
+	return true, details, hunks
+}
+
+// detectStatusChange checks if the status of the URL has changed, see
+// MethodStatus
+func (m *Monitor) detectStatusChange(code int) (bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := m.lastStatus
+	m.lastStatus = code
+	if last == 0 {
+		return false, ""
+	}
+
+	before, after := m.statusState(last), m.statusState(code)
+	if before == after {
+		return false, ""
+	}
+
+	return true, fmt.Sprintf("Status changed from %d (%s) to %d (%s)", last, before, code, after)
+}
+
+// statusState describes a status code for status checks: "up" or "down" if
+// expected codes are configured, and its class such as "2xx" otherwise
+func (m *Monitor) statusState(code int) string {
+	if len(m.config.ExpectedStatus) == 0 {
+		return fmt.Sprintf("%dxx", code/100)
+	}
+	if slices.Contains(m.config.ExpectedStatus, code) {
+		return "up"
+	}
+	return "down"
+}
+
+// prepare applies the content filters and normalization to content before
+// it is compared
+func (m *Monitor) prepare(content []byte) []byte {
+	if len(m.filters) > 0 {
+		content = m.filters.Apply(content)
+	}
+
+	if m.config.NormalizeWhitespace {
+		content = m.normalizeContent(content)
+	}
+
+	return content
 }
 
 // calculateHash calculates the SHA-256 hash of the content
@@ -334,6 +1874,70 @@ func (m *Monitor) calculateHash(content []byte) []byte {
 	return hash[:]
 }
 
+// contentStats are the hashes, lengths and filters of a content change, see
+// Change.OldHash
+type contentStats struct {
+	oldHash, newHash     string
+	oldLength, newLength int64
+	filteredBy           []string
+}
+
+// baseline returns the content the next check is compared with
+func (m *Monitor) baseline() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastContent
+}
+
+// contentStats describes a change of the content from old, after filters
+// and normalization
+func (m *Monitor) contentStats(old, content []byte) contentStats {
+	compareLast, compareContent := m.prepare(old), m.prepare(content)
+	stats := contentStats{
+		oldHash:   hex.EncodeToString(m.calculateHash(compareLast)),
+		newHash:   hex.EncodeToString(m.calculateHash(compareContent)),
+		oldLength: int64(len(compareLast)),
+		newLength: int64(len(compareContent)),
+	}
+
+	filtered := content
+	for _, filter := range m.filters {
+		next := filter.Apply(filtered)
+		if !bytes.Equal(next, filtered) {
+			stats.filteredBy = append(stats.filteredBy, filter.Description())
+		}
+		filtered = next
+	}
+	return stats
+}
+
+// alertChanged reports whether the parts of Config.AlertSelectors differ
+// between old and content, after the other filters and normalization
+func (m *Monitor) alertChanged(old, content []byte) bool {
+	alerted := func(content []byte) []byte {
+		content = m.alertFilters.Apply(content)
+		if m.config.NormalizeWhitespace {
+			content = m.normalizeContent(content)
+		}
+		return content
+	}
+	return !bytes.Equal(alerted(old), alerted(content))
+}
+
+// digestStats describes a change of the complete body from the digest last,
+// after detectBinaryChange or detectTruncatedChange stored the new one
+func (m *Monitor) digestStats(last bodyDigest) contentStats {
+	m.mu.RLock()
+	digest := m.lastDigest
+	m.mu.RUnlock()
+	return contentStats{
+		oldHash:   hex.EncodeToString(last.hash),
+		newHash:   hex.EncodeToString(digest.hash),
+		oldLength: last.size,
+		newLength: digest.size,
+	}
+}
+
 // findDifference finds the difference between old and new content
 // It returns a description of what changed
 func (m *Monitor) findDifference(oldContent, newContent []byte) string {
@@ -414,11 +2018,160 @@ func (m *Monitor) GetStatus() (time.Time, string, int64) {
 	return m.lastCheck, m.status, m.checkCount
 }
 
+// LastError returns the error of the last check if it failed, or "" if it
+// succeeded. GetStatus keeps the time of the last successful check.
+func (m *Monitor) LastError() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastError
+}
+
+// Failures returns the number of checks in a row that failed, and whether
+// they reached Config.FailureThreshold so that an error was reported
+func (m *Monitor) Failures() (int64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failures, m.failing
+}
+
+// Latency returns the time until the response headers of the last
+// successful request were received
+func (m *Monitor) Latency() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latency
+}
+
+// Pause suspends checks until Resume is called. The content of the last
+// check is kept, so changes made while paused are reported after resuming.
+func (m *Monitor) Pause() {
+	m.mu.Lock()
+	wasPaused := m.paused
+	m.paused = true
+	m.status = "paused"
+	m.mu.Unlock()
+
+	if !wasPaused {
+		m.queueEvent(EventPaused)
+	}
+}
+
+// Resume resumes checks suspended by Pause, starting with the next scheduled
+// check
+func (m *Monitor) Resume() {
+	m.mu.Lock()
+	wasPaused := m.paused
+	if m.paused {
+		m.paused = false
+		m.status = "idle"
+	}
+	m.mu.Unlock()
+
+	if wasPaused {
+		m.queueEvent(EventResumed)
+	}
+}
+
+// Trigger requests a check right away, outside the monitor's schedule, e.g.
+// to verify a page after a deploy. The check runs as soon as the monitor is
+// free and its result is sent on the changes channel like that of any other
+// check. Triggers made while one is pending are merged.
+func (m *Monitor) Trigger() error {
+	if IsWebhookURL(m.config.URL) {
+		return ErrNotWebhook
+	}
+	if m.ctx.Err() != nil {
+		return ErrMonitorStopped
+	}
+	if m.IsPaused() {
+		return ErrMonitorPaused
+	}
+
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ResetBaseline discards the stored content. The next check sets a new
+// baseline instead of reporting a change.
+func (m *Monitor) ResetBaseline() {
+	m.mu.Lock()
+	m.lastContent = nil
+	m.lastDigest = bodyDigest{}
+	m.lastKind = ""
+	m.lastVariants = nil
+	m.lastStatus = 0
+	m.lastMatched = nil
+	m.lastValue = nil
+	m.lastCounts = nil
+	m.lastTable = nil
+	m.lastImage = nil
+	m.tail = tailState{}
+	m.seenEntries = nil
+	m.isFirstCheck = true
+	m.mu.Unlock()
+
+	m.queueEvent(EventBaselineReset)
+}
+
+// IsPaused reports whether checks are suspended
+func (m *Monitor) IsPaused() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paused
+}
+
 // GetURL returns the URL being monitored
 func (m *Monitor) GetURL() string {
 	return m.config.URL
 }
 
+// GetConfig returns a copy of the monitor's configuration
+func (m *Monitor) GetConfig() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config
+}
+
+// ClientSettings returns the current settings of the monitor's HTTP client
+func (m *Monitor) ClientSettings() ClientSettings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return ClientSettings{
+		Timeout:         m.config.Timeout,
+		FollowRedirects: m.config.FollowRedirects,
+		ProxyURL:        m.config.ProxyURL,
+	}
+}
+
+// UpdateClient changes the settings of the monitor's HTTP client while it
+// runs. Only the client is rebuilt, so the baseline, cookies and schedule
+// are kept; a check in progress finishes with the previous client.
+func (m *Monitor) UpdateClient(settings ClientSettings) {
+	m.mu.Lock()
+	m.config.Timeout = settings.Timeout
+	m.config.FollowRedirects = settings.FollowRedirects
+	m.config.ProxyURL = settings.ProxyURL
+	previous := m.client
+	// The client is built from a copy, like the one GetConfig returns, so
+	// nothing it keeps points into the config guarded by mu
+	config := m.config
+	m.client = newClient(&config, m.jar, m.har)
+	m.mu.Unlock()
+
+	previous.CloseIdleConnections()
+}
+
+// httpClient returns the monitor's HTTP client, which UpdateClient may
+// replace
+func (m *Monitor) httpClient() *http.Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.client
+}
+
 // byteSliceEqual compares two byte slices for equality
 func byteSliceEqual(a, b []byte) bool {
 	return utils.ByteSliceEqual(a, b)
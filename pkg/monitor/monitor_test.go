@@ -1,11 +1,16 @@
 package monitor
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/nemuizzz/hawkeye/pkg/har"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
 	"github.com/stretchr/testify/require"
 )
 
@@ -63,7 +68,7 @@ func TestMonitorFetchContent(t *testing.T) {
 	m := NewMonitorWithConfig(config)
 
 	// Fetch content
-	fetchedContent, change, err := m.fetchContent()
+	fetchedContent, change, err := m.fetchContent(nil)
 	require.NoError(t, err)
 	require.Equal(t, content, string(fetchedContent))
 	require.Equal(t, server.URL, change.URL)
@@ -79,16 +84,16 @@ func TestMonitorDetectChange(t *testing.T) {
 
 		// First check, no change expected
 		content1 := []byte("Initial content")
-		changed, _ := m.detectChange(content1)
+		changed, _, _ := m.detectChange(content1)
 		require.False(t, changed)
 
 		// Second check with same content, no change expected
-		changed, _ = m.detectChange(content1)
+		changed, _, _ = m.detectChange(content1)
 		require.False(t, changed)
 
 		// Third check with different content, change expected
 		content2 := []byte("Changed content")
-		changed, details := m.detectChange(content2)
+		changed, details, _ := m.detectChange(content2)
 		require.True(t, changed)
 		require.Contains(t, details, "differs at position")
 	})
@@ -100,12 +105,12 @@ func TestMonitorDetectChange(t *testing.T) {
 
 		// First check, no change expected
 		content1 := []byte("Initial content")
-		changed, _ := m.detectChange(content1)
+		changed, _, _ := m.detectChange(content1)
 		require.False(t, changed)
 
 		// Second check with different length, change expected
 		content2 := []byte("Different length content string")
-		changed, details := m.detectChange(content2)
+		changed, details, _ := m.detectChange(content2)
 		require.True(t, changed)
 		require.Contains(t, details, "length")
 	})
@@ -124,12 +129,12 @@ func TestMonitorDetectChange(t *testing.T) {
 
 		// First check, no change expected
 		content1 := []byte("Same first letter")
-		changed, _ := m.detectChange(content1)
+		changed, _, _ := m.detectChange(content1)
 		require.False(t, changed)
 
 		// Second check with different first letter, change expected
 		content2 := []byte("Different first letter")
-		changed, details := m.detectChange(content2)
+		changed, details, _ := m.detectChange(content2)
 		require.True(t, changed)
 		require.Equal(t, "First byte changed", details)
 	})
@@ -178,7 +183,7 @@ func TestMonitorTimeout(t *testing.T) {
 	m := NewMonitorWithConfig(config)
 
 	// Fetch should fail with timeout
-	_, _, err := m.fetchContent()
+	_, _, err := m.fetchContent(nil)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "deadline exceeded")
 }
@@ -281,7 +286,7 @@ func TestDetectChangeWithWhitespaceNormalization(t *testing.T) {
 	monitor1.mu.Unlock()
 
 	// Test with whitespace difference
-	changed, _ := monitor1.detectChange([]byte("hello  world"))
+	changed, _, _ := monitor1.detectChange([]byte("hello  world"))
 	require.True(t, changed, "Should detect change when whitespace normalization is disabled")
 
 	// Test when NormalizeWhitespace is true
@@ -294,11 +299,11 @@ func TestDetectChangeWithWhitespaceNormalization(t *testing.T) {
 	monitor2.mu.Unlock()
 
 	// Test with whitespace difference
-	changed, _ = monitor2.detectChange([]byte("hello  world"))
+	changed, _, _ = monitor2.detectChange([]byte("hello  world"))
 	require.False(t, changed, "Should not detect change when whitespace normalization is enabled")
 
 	// Test with actual content difference
-	changed, details := monitor2.detectChange([]byte("hello universe"))
+	changed, details, _ := monitor2.detectChange([]byte("hello universe"))
 	require.True(t, changed, "Should detect change with different content")
 	require.Contains(t, details, "differs at position")
 }
@@ -319,14 +324,14 @@ func TestMonitorWithTimestampFiltering(t *testing.T) {
 	updatedContent := []byte("Last updated: 2023-05-01T13:00:00Z")
 
 	// Should not detect a change since we're ignoring timestamps
-	changed, _ := monitor.detectChange(updatedContent)
+	changed, _, _ := monitor.detectChange(updatedContent)
 	require.False(t, changed, "Should not detect a change when only timestamps differ and filtering is enabled")
 
 	// New content with other changes
 	otherContent := []byte("Last updated: 2023-05-01T13:00:00Z and new content")
 
 	// Should detect a change since other content changed
-	changed, details := monitor.detectChange(otherContent)
+	changed, details, _ := monitor.detectChange(otherContent)
 	require.True(t, changed, "Should detect changes in non-timestamp content")
 	require.Contains(t, details, "differs at position")
 }
@@ -351,18 +356,53 @@ func TestMonitorWithCustomFilters(t *testing.T) {
 	updatedContent := []byte("Software version: 1.2.4")
 
 	// Should not detect a change since we're filtering out version numbers
-	changed, _ := monitor.detectChange(updatedContent)
+	changed, _, _ := monitor.detectChange(updatedContent)
 	require.False(t, changed, "Should not detect a change when only version numbers differ")
 
 	// New content with other changes
 	otherContent := []byte("Software version: 1.2.4 with new features")
 
 	// Should detect a change since other content changed
-	changed, details := monitor.detectChange(otherContent)
+	changed, details, _ := monitor.detectChange(otherContent)
 	require.True(t, changed, "Should detect changes in non-filtered content")
 	require.Contains(t, details, "differs at position")
 }
 
+func TestFingerprint(t *testing.T) {
+	version := "1.2.3"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Software version: " + version))
+	}))
+	defer server.Close()
+
+	filter, err := NewRegexFilter("version: [0-9.]+", "version: X.Y.Z", "Ignore version numbers")
+	require.NoError(t, err)
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.ContentFilters = ContentFilterList{filter}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	hash, change := m.Fingerprint()
+	require.Empty(t, change.Error)
+	require.Equal(t, 200, change.StatusCode)
+	require.Len(t, hash, 64)
+
+	// Filtered content doesn't change the fingerprint
+	version = "1.2.4"
+	again, _ := m.Fingerprint()
+	require.Equal(t, hash, again)
+
+	// Fingerprinting leaves the baseline alone
+	require.Nil(t, m.lastContent)
+
+	server.Close()
+	hash, change = m.Fingerprint()
+	require.Empty(t, hash)
+	require.NotEmpty(t, change.Error)
+	require.Equal(t, EventError, change.Event)
+}
+
 func TestMonitorWithMultipleFilters(t *testing.T) {
 	// Create multiple filters
 	tsFilter, err := NewTimestampFilter()
@@ -386,14 +426,473 @@ func TestMonitorWithMultipleFilters(t *testing.T) {
 	updatedContent := []byte("Updated: 2023-05-01T13:00:00Z, version: 1.2.4")
 
 	// Should not detect a change since we're filtering both timestamps and versions
-	changed, _ := monitor.detectChange(updatedContent)
+	changed, _, _ := monitor.detectChange(updatedContent)
 	require.False(t, changed, "Should not detect a change when only filtered elements differ")
 
 	// New content with other changes
 	otherContent := []byte("Updated: 2023-05-01T13:00:00Z, version: 1.2.4, new feature added")
 
 	// Should detect a change
-	changed, details := monitor.detectChange(otherContent)
+	changed, details, _ := monitor.detectChange(otherContent)
 	require.True(t, changed, "Should detect changes in non-filtered content")
 	require.Contains(t, details, "differs at position")
 }
+
+func TestStatusMethod(t *testing.T) {
+	statuses := []int{200, 201, 503, 503, 200}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls.Add(1)-1])
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodStatus
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	// The first check sets the baseline and 2xx to 2xx is no change
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged)
+
+	// Unsuccessful statuses are results, not errors
+	change := m.Check()
+	require.Empty(t, change.Error)
+	require.True(t, change.HasChanged)
+	require.Equal(t, 503, change.StatusCode)
+	require.Equal(t, "Status changed from 201 (2xx) to 503 (5xx)", change.Details)
+	require.Greater(t, change.Latency, time.Duration(0))
+	require.Equal(t, change.Latency, m.Latency())
+
+	require.False(t, m.Check().HasChanged)
+	require.True(t, m.Check().HasChanged)
+}
+
+func TestStatusMethodExpectedStatus(t *testing.T) {
+	statuses := []int{200, 301, 404}
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls.Add(1)-1])
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodStatus
+	config.ExpectedStatus = []int{200, 301}
+	config.FollowRedirects = false
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "both statuses are expected")
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Status changed from 301 (up) to 404 (down)", change.Details)
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ChangeDetectionMethod
+		wantErr  bool
+	}{
+		{input: "", expected: MethodHash},
+		{input: "hash", expected: MethodHash},
+		{input: "Length", expected: MethodLength},
+		{input: "custom", expected: MethodCustom},
+		{input: "status", expected: MethodStatus},
+		{input: "keyword", expected: MethodKeyword},
+		{input: "value", expected: MethodValue},
+		{input: "feed", expected: MethodFeed},
+		{input: "bogus", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			method, err := ParseMethod(tc.input)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, method)
+
+			// String should round-trip through ParseMethod
+			roundTrip, err := ParseMethod(method.String())
+			require.NoError(t, err)
+			require.Equal(t, method, roundTrip)
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	statuses := []int{http.StatusOK, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK}
+	bodies := []string{"v1", "", "", "v2"}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(calls, len(statuses)-1)
+		calls++
+		w.WriteHeader(statuses[i])
+		w.Write([]byte(bodies[i]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+
+	var events []EventType
+	report := func() {
+		change, reported := m.check()
+		for len(m.events) > 0 {
+			events = append(events, (<-m.events).Event)
+		}
+		if reported {
+			events = append(events, change.Event)
+		}
+	}
+
+	for i := range statuses {
+		report()
+		_, status, _ := m.GetStatus()
+		if statuses[i] == http.StatusOK {
+			require.Empty(t, m.LastError())
+			require.Equal(t, "idle", status)
+		} else {
+			require.Equal(t, "unexpected status code: 500", m.LastError())
+			require.Equal(t, "failing", status)
+		}
+	}
+	require.Equal(t, []EventType{EventError, EventError, EventRecovery, EventChange}, events)
+
+	// After a reset the next check sets a new baseline
+	events = nil
+	m.ResetBaseline()
+	report()
+	require.Equal(t, []EventType{EventBaselineReset}, events)
+}
+
+func TestFailureThreshold(t *testing.T) {
+	statuses := []int{http.StatusOK, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK,
+		http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(calls, len(statuses)-1)
+		calls++
+		w.WriteHeader(statuses[i])
+		w.Write([]byte("v1"))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.FailureThreshold = 3
+	m := NewMonitorWithConfig(config)
+
+	var events []EventType
+	var states []string
+	for range statuses {
+		change, reported := m.check()
+		for len(m.events) > 0 {
+			events = append(events, (<-m.events).Event)
+		}
+		if reported {
+			events = append(events, change.Event)
+		}
+		failures, failing := m.Failures()
+		_, status, _ := m.GetStatus()
+		states = append(states, fmt.Sprintf("%s %d %t", status, failures, failing))
+	}
+
+	// Two failed checks in a row stay below the threshold, four report
+	// errors from the third on and a recovery
+	require.Equal(t, []EventType{EventError, EventError, EventRecovery}, events)
+	require.Equal(t, []string{
+		"idle 0 false", "erroring 1 false", "erroring 2 false", "idle 0 false",
+		"erroring 1 false", "erroring 2 false", "failing 3 true", "failing 4 true", "idle 0 false",
+	}, states)
+
+	config.FailureThreshold = -1
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrFailureThreshold)
+}
+
+func TestConfirmDelay(t *testing.T) {
+	bodies := []string{"v1", "v2", "v1", "v3", "v3", "v4", "v5"}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(calls, len(bodies)-1)
+		calls++
+		w.Write([]byte(bodies[i]))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.ConfirmDelay = time.Millisecond * 10
+	m := NewMonitorWithConfig(config)
+
+	_, reported := m.check()
+	require.False(t, reported)
+
+	// v2 flaps back to v1, so it isn't reported and v1 stays the baseline
+	_, reported = m.check()
+	require.False(t, reported)
+	require.Equal(t, "v1", string(m.baseline()))
+
+	change, reported := m.check()
+	require.True(t, reported)
+	require.Contains(t, change.Diff, "-v1\n+v3")
+
+	// v4 is followed by v5, which is reported as it differs from v3 too
+	change, reported = m.check()
+	require.True(t, reported)
+	require.Contains(t, change.Diff, "-v3\n+v5")
+	require.Equal(t, 7, calls)
+	_, _, checks := m.GetStatus()
+	require.EqualValues(t, 4, checks)
+
+	config.ConfirmDelay = config.Interval
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrConfirmDelay)
+}
+
+func TestOnError(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("no number here"))
+	}))
+	defer server.Close()
+
+	var errs []*CheckError
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 2
+	config.RetryInterval = time.Millisecond
+	config.Method = MethodValue
+	config.Extract, _ = ParseExtractor("regex:[0-9]+")
+	config.OnError = func(err error) {
+		var checkErr *CheckError
+		require.ErrorAs(t, err, &checkErr)
+		errs = append(errs, checkErr)
+	}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	// Every attempt is reported, the last one as final
+	change := m.Check()
+	require.Equal(t, EventError, change.Event)
+	require.Len(t, errs, 3)
+	for i, err := range errs {
+		require.Equal(t, server.URL, err.URL)
+		require.Equal(t, i+1, err.Attempt)
+		require.Equal(t, i == 2, err.Final)
+		require.Equal(t, http.StatusBadGateway, err.StatusCode)
+	}
+	require.Equal(t, server.URL+": attempt 3: unexpected status code: 502", errs[2].Error())
+
+	// Content that can't be compared
+	errs = nil
+	fail.Store(false)
+	m.Check()
+	require.Len(t, errs, 1)
+	require.Zero(t, errs[0].Attempt)
+	require.True(t, errs[0].Final)
+	require.Equal(t, http.StatusOK, errs[0].StatusCode)
+}
+
+func TestOnCheck(t *testing.T) {
+	content := "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	var checks []Change
+	manager := NewManager()
+	manager.SetOnCheck(func(change Change) {
+		checks = append(checks, change)
+	})
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	// Checks without changes are passed too
+	m.Check()
+	m.Check()
+	content = "second"
+	m.Check()
+	require.Len(t, checks, 3)
+	require.False(t, checks[1].HasChanged)
+	require.Equal(t, http.StatusOK, checks[1].StatusCode)
+	require.True(t, checks[2].HasChanged)
+}
+
+func TestParseEventType(t *testing.T) {
+	for _, event := range EventTypes {
+		parsed, err := ParseEventType(string(event))
+		require.NoError(t, err)
+		require.Equal(t, event, parsed)
+	}
+
+	_, err := ParseEventType("explosion")
+	require.Error(t, err)
+}
+
+// fixedClock is a clock whose time is set by the test
+type fixedClock struct {
+	RealClock
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, "content %d", calls)
+	}))
+	defer server.Close()
+
+	skip, err := schedule.ParseWindow("Sat 02:00-04:00 UTC", schedule.ModeSkip)
+	require.NoError(t, err)
+	silence, err := schedule.ParseWindow("Sun 02:00-04:00 UTC", schedule.ModeSilence)
+	require.NoError(t, err)
+
+	// 2024-01-13 is a Saturday
+	clock := &fixedClock{now: time.Date(2024, time.January, 13, 3, 0, 0, 0, time.UTC)}
+	config := DefaultConfig(server.URL)
+	config.MaintenanceWindows = schedule.Windows{skip, silence}
+	config.Clock = clock
+	m := NewMonitorWithConfig(config)
+
+	require.True(t, m.inWindow(schedule.ModeSkip))
+	require.False(t, m.inWindow(schedule.ModeSilence))
+
+	// Changes in a silence window are reported but marked silenced
+	clock.now = clock.now.AddDate(0, 0, 1)
+	require.False(t, m.inWindow(schedule.ModeSkip))
+	m.Check()
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.True(t, change.Silenced)
+
+	clock.now = clock.now.Add(time.Hour * 2)
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.False(t, change.Silenced)
+}
+
+// recordingArchive keeps the contents saved to it
+type recordingArchive struct {
+	contents []string
+	err      error
+}
+
+func (a *recordingArchive) Save(url string, at time.Time, content []byte, contentType string) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.contents = append(a.contents, string(content))
+	return nil
+}
+
+// attachingArchive also keeps the attachments added to it
+type attachingArchive struct {
+	recordingArchive
+	attachments map[string][]byte
+}
+
+func (a *attachingArchive) AttachAt(url string, at time.Time, name string, content []byte, contentType string) error {
+	a.attachments[name] = content
+	return nil
+}
+
+func TestCaptureHAR(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		w.Header().Set("X-Cache", fmt.Sprintf("node-%d", calls))
+		fmt.Fprintf(w, "content %d", calls)
+	}))
+	defer server.Close()
+
+	archive := &attachingArchive{attachments: map[string][]byte{}}
+	config := DefaultConfig(server.URL)
+	config.Archive = archive
+	config.CaptureHAR = true
+	m := NewMonitorWithConfig(config)
+
+	m.Check()
+	require.Empty(t, archive.attachments, "the baseline isn't a change")
+	change := m.Check()
+	require.True(t, change.HasChanged)
+
+	var file har.File
+	require.NoError(t, json.Unmarshal(archive.attachments[HARAttachment], &file))
+	require.Len(t, file.Log.Entries, 2, "only the requests of the check with the change")
+	require.Equal(t, http.StatusFound, file.Log.Entries[0].Response.Status)
+	require.Contains(t, file.Log.Entries[1].Response.Headers, har.NameValue{Name: "X-Cache", Value: "node-4"})
+
+	// Archives without attachments can't keep it
+	config.Archive = &recordingArchive{}
+	m = NewMonitorWithConfig(config)
+	m.Check()
+	change = m.Check()
+	require.Contains(t, change.Details, "\nHAR not archived: the archive doesn't keep attachments")
+}
+
+func TestArchive(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, "content %d", min(calls, 2))
+	}))
+	defer server.Close()
+
+	archive := &recordingArchive{}
+	config := DefaultConfig(server.URL)
+	config.Archive = archive
+	m := NewMonitorWithConfig(config)
+
+	m.Check()
+	m.Check()
+	m.Check()
+	require.Equal(t, []string{"content 1", "content 2"}, archive.contents, "the baseline and the change")
+
+	archive.err = fmt.Errorf("disk full")
+	calls = 5
+	m.ResetBaseline()
+	m.Check()
+	calls = 0
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Contains(t, change.Details, "\nSnapshot not archived: disk full")
+
+	// Monitors of a manager use its archive unless they have their own
+	manager := NewManager()
+	manager.SetArchive(archive)
+	shared, err := manager.AddMonitorWithConfig(DefaultConfig("https://example.com"))
+	require.NoError(t, err)
+	require.Same(t, archive, shared.GetConfig().Archive)
+	config = DefaultConfig("https://example.org")
+	config.Archive = &recordingArchive{}
+	own, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+	require.NotSame(t, archive, own.GetConfig().Archive)
+}
@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Variant is a version of a page served for some request headers, such as
+// Accept-Language, Cookie or User-Agent, see Config.Variants
+type Variant struct {
+	// Name identifies the variant in changes, e.g. "de" or "mobile"
+	Name string `json:"name"`
+	// Headers are set on the requests of the variant, replacing those of
+	// the monitor. Values may hold secret placeholders.
+	Headers map[string]string `json:"headers"`
+}
+
+// ValidateVariants checks that variants have unique names and valid
+// headers
+func ValidateVariants(variants []Variant) error {
+	names := make(map[string]bool, len(variants))
+	for _, variant := range variants {
+		if variant.Name == "" {
+			return errors.New("variants need a name")
+		}
+		if names[variant.Name] {
+			return fmt.Errorf("duplicate variant '%s'", variant.Name)
+		}
+		names[variant.Name] = true
+		if len(variant.Headers) == 0 {
+			return fmt.Errorf("variant '%s' has no headers", variant.Name)
+		}
+		for name, value := range variant.Headers {
+			if name == "" || strings.ContainsFunc(name, invalidHeaderNameRune) || strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("variant '%s' has an invalid header '%s'", variant.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// variants returns the variants fetched by each check: Config.Variants, or
+// one for each representation of Config.Representations
+func (m *Monitor) variants() []Variant {
+	if len(m.config.Representations) == 0 {
+		return m.config.Variants
+	}
+	variants := make([]Variant, len(m.config.Representations))
+	for i, accept := range m.config.Representations {
+		variants[i] = Variant{Name: accept, Headers: map[string]string{"Accept": accept}}
+	}
+	return variants
+}
+
+// variantKind names the variants of the monitor in details
+func (m *Monitor) variantKind() string {
+	if len(m.config.Representations) > 0 {
+		return "Representation"
+	}
+	return "Variant"
+}
+
+// fetchVariants fetches every variant of the monitor, in order. The
+// returned change is the one of the first variant, or the failure of the
+// first one that couldn't be fetched with the name of the variant
+// prepended to its error.
+func (m *Monitor) fetchVariants() ([][]byte, Change, error) {
+	var first Change
+	variants := m.variants()
+	contents := make([][]byte, len(variants))
+	for i, variant := range variants {
+		content, change, err := m.fetch(variant.Headers)
+		if err != nil {
+			if change.Error != "" {
+				change.Error = variant.Name + ": " + change.Error
+			}
+			return nil, change, err
+		}
+		if i == 0 {
+			first = change
+		}
+		contents[i] = content
+	}
+	return contents, first, nil
+}
+
+// detectVariantChange compares each variant with its own baseline and
+// returns the names of the variants that changed. When only some of them
+// changed, the details say so first, as the variants no longer agree.
+func (m *Monitor) detectVariantChange(contents [][]byte) (bool, string, []DiffHunk, []string) {
+	variants := m.variants()
+	kind := m.variantKind()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lastVariants == nil {
+		m.lastVariants = make(map[string][]byte, len(contents))
+	}
+
+	var changed []string
+	var sections []string
+	var hunks []DiffHunk
+	for i, variant := range variants {
+		last := m.lastVariants[variant.Name]
+		ok, details, diff := m.compare(&last, contents[i])
+		m.lastVariants[variant.Name] = last
+		if !ok {
+			continue
+		}
+
+		changed = append(changed, variant.Name)
+		sections = append(sections, fmt.Sprintf("%s %s changed: %s", kind, variant.Name, details))
+		// Hunks hold a single diff, so keep the first variant's
+		if hunks == nil {
+			hunks = diff
+		}
+	}
+
+	if len(changed) == 0 {
+		return false, "", nil, nil
+	}
+
+	if len(changed) < len(variants) {
+		sections = append([]string{fmt.Sprintf("%ss diverged: only %s changed", kind, strings.Join(changed, ", "))}, sections...)
+	}
+	details := strings.Join(sections, "\n")
+	return true, truncateDetails(details, m.config.MaxDetailsLines, m.config.MaxDetailsBytes), hunks, changed
+}
+
+// invalidHeaderNameRune reports whether r can't be part of a header name
+func invalidHeaderNameRune(r rune) bool {
+	return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+}
@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepresentations(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	var htmlVersion atomic.Value
+	htmlVersion.Store("v1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"version": "` + version.Load().(string) + `"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>Version " + htmlVersion.Load().(string) + "</p>"))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.Headers = map[string]string{"Accept": "*/*"}
+	config.Representations = []string{"text/html", "application/json"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	change := m.Check()
+	require.False(t, change.HasChanged)
+	require.Equal(t, "text/html", change.ContentType)
+	require.False(t, m.Check().HasChanged)
+
+	// The API moves on but the page doesn't
+	version.Store("v2")
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Contains(t, change.Details, "Representations diverged: only application/json changed")
+	require.Contains(t, change.Details, "Representation application/json changed")
+	require.Contains(t, change.Details, `+{"version": "v2"}`)
+	require.NotContains(t, change.Details, "Representation text/html changed")
+
+	// Both catch up: no divergence
+	version.Store("v3")
+	htmlVersion.Store("v3")
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.NotContains(t, change.Details, "diverged")
+	require.Contains(t, change.Details, "Representation text/html changed")
+	require.Contains(t, change.Details, "Representation application/json changed")
+}
+
+func TestRepresentationsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/json" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.Representations = []string{"text/html", "application/json"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	change := m.Check()
+	require.Equal(t, EventError, change.Event)
+	require.Equal(t, "application/json: unexpected status code: 406", change.Error)
+}
+
+func TestRepresentationsRequireContentMethod(t *testing.T) {
+	config := DefaultConfig("https://example.com")
+	config.Method = MethodStatus
+	config.Representations = []string{"application/json"}
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrRepresentations)
+}
+
+func TestVariants(t *testing.T) {
+	var german atomic.Value
+	german.Store("Hallo")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		greeting := "Hello"
+		if r.Header.Get("Accept-Language") == "de" {
+			greeting = german.Load().(string)
+		}
+		if cookie, err := r.Cookie("plan"); err == nil {
+			greeting += " " + cookie.Value
+		}
+		w.Write([]byte(greeting))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.Variants = []Variant{
+		{Name: "en", Headers: map[string]string{"Accept-Language": "en"}},
+		{Name: "de", Headers: map[string]string{"Accept-Language": "de"}},
+		{Name: "pro", Headers: map[string]string{"Cookie": "plan=pro"}},
+	}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged)
+
+	german.Store("Guten Tag")
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, []string{"de"}, change.Variants)
+	require.Contains(t, change.Details, "Variants diverged: only de changed")
+	require.Contains(t, change.Details, "Variant de changed")
+	require.Contains(t, change.Details, "+Guten Tag")
+	require.NotContains(t, change.Details, "Variant en changed")
+}
+
+func TestValidateVariants(t *testing.T) {
+	require.NoError(t, ValidateVariants([]Variant{{Name: "mobile", Headers: map[string]string{"User-Agent": "Mobile"}}}))
+	require.ErrorContains(t, ValidateVariants([]Variant{{Headers: map[string]string{"User-Agent": "Mobile"}}}), "need a name")
+	require.ErrorContains(t, ValidateVariants([]Variant{{Name: "mobile"}}), "no headers")
+	require.ErrorContains(t, ValidateVariants([]Variant{
+		{Name: "a", Headers: map[string]string{"Cookie": "a=1"}},
+		{Name: "a", Headers: map[string]string{"Cookie": "a=2"}},
+	}), "duplicate variant 'a'")
+	require.ErrorContains(t, ValidateVariants([]Variant{{Name: "a", Headers: map[string]string{"Bad Name": "x"}}}), "invalid header")
+	require.ErrorContains(t, ValidateVariants([]Variant{{Name: "a", Headers: map[string]string{"Cookie": "a\r\nb"}}}), "invalid header")
+}
+
+func TestVariantsRequireContentMethod(t *testing.T) {
+	config := DefaultConfig("https://example.com")
+	config.Method = MethodStatus
+	config.Variants = []Variant{{Name: "de", Headers: map[string]string{"Accept-Language": "de"}}}
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrVariants)
+
+	config.Method = MethodHash
+	config.Representations = []string{"application/json"}
+	_, err = NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrVariants)
+}
@@ -0,0 +1,273 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultMaxPages is the number of pages followed at most by default
+const DefaultMaxPages = 100
+
+// ErrTooManyPages is returned when an API has more pages than
+// Pagination.MaxPages
+var ErrTooManyPages = errors.New("too many pages")
+
+// PageNext is how the next page of a paginated API is found
+type PageNext int
+
+const (
+	// PageLink follows the URL of the Link header with rel="next"
+	PageLink PageNext = iota
+	// PageURL follows the URL in a field of the JSON document
+	PageURL
+	// PageCursor sends the cursor in a field of the JSON document as a
+	// query parameter of the monitor's URL
+	PageCursor
+)
+
+// Pagination follows the pages of an API and combines their items into a
+// single JSON array, sorted so items moving between pages aren't changes
+type Pagination struct {
+	Next PageNext
+	// Path is the dotted path of the next URL or cursor, see ParseCondition
+	Path string
+	// Param is the query parameter the cursor is sent in
+	Param string
+	// Items is the dotted path of the array of items of a page. Empty means
+	// pages are arrays.
+	Items string
+	// MaxPages fails checks of APIs with more pages. Zero means
+	// DefaultMaxPages.
+	MaxPages int
+}
+
+// ParsePagination parses how the next page is found: "link" for Link
+// headers, "json:links.next" for the URL in a JSON field, or
+// "cursor:meta.next_cursor=cursor" for a cursor in a JSON field sent as the
+// query parameter after the "="
+func ParsePagination(spec string) (*Pagination, error) {
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "link":
+		if value != "" {
+			break
+		}
+		return &Pagination{Next: PageLink}, nil
+	case "json":
+		if value == "" {
+			return nil, fmt.Errorf("invalid pagination '%s' (expected json:path)", spec)
+		}
+		return &Pagination{Next: PageURL, Path: value}, nil
+	case "cursor":
+		path, param, found := strings.Cut(value, "=")
+		if !found || path == "" || param == "" {
+			return nil, fmt.Errorf("invalid pagination '%s' (expected cursor:path=param)", spec)
+		}
+		return &Pagination{Next: PageCursor, Path: path, Param: param}, nil
+	}
+	return nil, fmt.Errorf("invalid pagination '%s' (expected link, json:path or cursor:path=param)", spec)
+}
+
+// String returns the spec of how the next page is found, see
+// ParsePagination
+func (p *Pagination) String() string {
+	switch p.Next {
+	case PageURL:
+		return "json:" + p.Path
+	case PageCursor:
+		return "cursor:" + p.Path + "=" + p.Param
+	default:
+		return "link"
+	}
+}
+
+// maxPages returns the number of pages followed at most
+func (p *Pagination) maxPages() int {
+	if p.MaxPages > 0 {
+		return p.MaxPages
+	}
+	return DefaultMaxPages
+}
+
+// fetchPages fetches every page of the monitor's URL and combines their
+// items. The change is the one of the first page, with the latency of all
+// of them. A URL seen before ends the pages, so cursors that don't advance
+// can't loop.
+func (m *Monitor) fetchPages(variant map[string]string) ([]byte, Change, error) {
+	pagination := m.config.Pagination
+	var first Change
+	var latency time.Duration
+	var items []any
+	seen := make(map[string]bool)
+	for pageURL := m.config.URL; pageURL != "" && !seen[pageURL]; {
+		if len(seen) == pagination.maxPages() {
+			return nil, first, fmt.Errorf("%w: more than %d", ErrTooManyPages, pagination.maxPages())
+		}
+		seen[pageURL] = true
+
+		content, change, header, err := m.fetchPage(pageURL, variant, nil)
+		if len(seen) == 1 {
+			first = change
+		}
+		if err != nil {
+			if len(seen) > 1 {
+				err = fmt.Errorf("page %d: %w", len(seen), err)
+			}
+			return nil, first, err
+		}
+		latency += change.Latency
+
+		doc, err := decodeJSON(content)
+		if err != nil {
+			return nil, first, fmt.Errorf("page %d is not JSON: %w", len(seen), err)
+		}
+		pageItems, err := pagination.items(doc)
+		if err != nil {
+			return nil, first, fmt.Errorf("page %d: %w", len(seen), err)
+		}
+		items = append(items, pageItems...)
+
+		if pageURL, err = pagination.next(pageURL, m.config.URL, doc, header.Values("Link")); err != nil {
+			return nil, first, fmt.Errorf("page %d: %w", len(seen), err)
+		}
+	}
+
+	// Items are sorted by their encoding, whose object keys are sorted
+	encoded := make([][]byte, len(items))
+	for i, item := range items {
+		encoded[i], _ = json.Marshal(item)
+	}
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return bytes.Compare(encoded[a], encoded[b]) })
+	sorted := make([]any, len(items))
+	for i, j := range order {
+		sorted[i] = items[j]
+	}
+
+	content, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return nil, first, err
+	}
+
+	first.Latency = latency
+	first.ContentType = "application/json"
+	first.digest = bodyDigest{}
+	m.mu.Lock()
+	m.latency = latency
+	m.mu.Unlock()
+	return content, first, nil
+}
+
+// decodeJSON decodes a JSON document, keeping numbers as written
+func decodeJSON(content []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// items returns the items of a page. A missing or null array has no items.
+func (p *Pagination) items(doc any) ([]any, error) {
+	if p.Items != "" {
+		value, ok := lookupJSON(doc, p.Items)
+		if !ok || value == nil {
+			return nil, nil
+		}
+		doc = value
+	}
+	items, ok := doc.([]any)
+	if !ok {
+		if p.Items == "" {
+			return nil, errors.New("page is not an array (set the path of its items)")
+		}
+		return nil, fmt.Errorf("'%s' is not an array", p.Items)
+	}
+	return items, nil
+}
+
+// next returns the URL of the page after pageURL, or "" after the last
+// page. Relative URLs are resolved against pageURL.
+func (p *Pagination) next(pageURL, baseURL string, doc any, links []string) (string, error) {
+	var target string
+	switch p.Next {
+	case PageLink:
+		target = nextLink(links)
+	case PageURL:
+		if value, ok := lookupJSON(doc, p.Path); ok && value != nil {
+			target = jsonValue(value)
+		}
+	case PageCursor:
+		value, ok := lookupJSON(doc, p.Path)
+		if !ok || value == nil || jsonValue(value) == "" {
+			return "", nil
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return "", err
+		}
+		query := u.Query()
+		query.Set(p.Param, jsonValue(value))
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+	if target == "" {
+		return "", nil
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	u, err := base.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page URL '%s': %w", target, err)
+	}
+	return u.String(), nil
+}
+
+// nextLink returns the target of the link with rel="next" in Link header
+// values such as `<https://api.example.com/items?page=2>; rel="next"`
+func nextLink(values []string) string {
+	for _, value := range values {
+		for {
+			start := strings.IndexByte(value, '<')
+			if start < 0 {
+				break
+			}
+			end := strings.IndexByte(value[start:], '>')
+			if end < 0 {
+				break
+			}
+			target := value[start+1 : start+end]
+			value = value[start+end+1:]
+
+			params := value
+			if i := strings.IndexByte(value, '<'); i >= 0 {
+				params = value[:i]
+			}
+			for _, param := range strings.Split(params, ";") {
+				key, rel, _ := strings.Cut(param, "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				rel = strings.Trim(strings.TrimSpace(strings.TrimRight(strings.TrimSpace(rel), ",")), `"`)
+				if slices.Contains(strings.Fields(strings.ToLower(rel)), "next") {
+					return target
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePagination(t *testing.T) {
+	for _, spec := range []string{"link", "json:links.next", "cursor:meta.next_cursor=cursor"} {
+		pagination, err := ParsePagination(spec)
+		require.NoError(t, err)
+		require.Equal(t, spec, pagination.String())
+	}
+
+	pagination, err := ParsePagination("cursor:meta.next=after")
+	require.NoError(t, err)
+	require.Equal(t, &Pagination{Next: PageCursor, Path: "meta.next", Param: "after"}, pagination)
+
+	for _, spec := range []string{"", "links", "link:next", "json:", "cursor:meta.next", "cursor:=after"} {
+		_, err := ParsePagination(spec)
+		require.ErrorContains(t, err, "invalid pagination", spec)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	require.Equal(t, "https://api.example.com/items?page=3", nextLink([]string{
+		`<https://api.example.com/items?page=1>; rel="prev", <https://api.example.com/items?page=3>; rel="next"`,
+	}))
+	require.Equal(t, "/items?page=2", nextLink([]string{`</items?page=1>; rel=first`, `</items?page=2>; title="a, b"; rel="next last"`}))
+	require.Empty(t, nextLink([]string{`<https://api.example.com/items?page=1>; rel="prev"`}))
+	require.Empty(t, nextLink(nil))
+}
+
+func TestPaginationLink(t *testing.T) {
+	// Pages of two items, shifted by one item on the second check
+	var pages atomic.Value
+	pages.Store([][]string{{`{"id":1}`, `{"id":2}`}, {`{"id":3}`, `{"id":4}`}, {`{"id":5}`}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		all := pages.Load().([][]string)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < len(all)-1 {
+			w.Header().Set("Link", fmt.Sprintf(`</items?page=%d>; rel="next"`, page+1))
+		}
+		items := all[page]
+		fmt.Fprint(w, "[")
+		for i, item := range items {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprint(w, item)
+		}
+		fmt.Fprint(w, "]")
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL + "/items")
+	config.Pagination = &Pagination{Next: PageLink}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	change := m.Check()
+	require.Empty(t, change.Error)
+	require.Equal(t, "application/json", change.ContentType)
+	require.JSONEq(t, `[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5}]`, string(m.lastContent))
+
+	// Items moving across page boundaries aren't a change
+	pages.Store([][]string{{`{"id":2}`}, {`{"id":1}`, `{"id":4}`}, {`{"id":3}`, `{"id":5}`}})
+	change = m.Check()
+	require.Empty(t, change.Error)
+	require.False(t, change.HasChanged)
+
+	pages.Store([][]string{{`{"id":2}`}, {`{"id":1}`, `{"id":4}`}, {`{"id":3}`, `{"id":6}`}})
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Contains(t, change.Details, `"id": 6`)
+}
+
+func TestPaginationCursor(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		require.Equal(t, "active", r.URL.Query().Get("status"))
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, `{"data":[{"id":12345678901234567890}],"meta":{"next":"b"}}`)
+		case "b":
+			fmt.Fprint(w, `{"data":[{"id":2}],"meta":{"next":2}}`)
+		case "2":
+			fmt.Fprint(w, `{"data":null,"meta":{"next":null}}`)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL + "/items?status=active")
+	config.Pagination = &Pagination{Next: PageCursor, Path: "meta.next", Param: "after", Items: "data"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	change := m.Check()
+	require.Empty(t, change.Error)
+	require.Equal(t, int32(3), requests.Load())
+	require.Equal(t, "[\n  {\n    \"id\": 12345678901234567890\n  },\n  {\n    \"id\": 2\n  }\n]", string(m.lastContent), "numbers are kept as written")
+}
+
+func TestPaginationErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/endless":
+			// Every page links to a new one
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			fmt.Fprintf(w, `{"items":[%d],"next":"/endless?page=%d"}`, page, page+1)
+		case "/loop":
+			fmt.Fprint(w, `{"items":[1],"next":"/loop"}`)
+		case "/object":
+			fmt.Fprint(w, `{"items":[1]}`)
+		case "/html":
+			fmt.Fprint(w, `<html></html>`)
+		}
+	}))
+	defer server.Close()
+
+	check := func(path string, pagination *Pagination) Change {
+		config := DefaultConfig(server.URL + path)
+		config.RetryCount = 0
+		config.Pagination = pagination
+		m := NewMonitorWithConfig(config)
+		defer m.Stop()
+		return m.Check()
+	}
+
+	change := check("/endless", &Pagination{Next: PageURL, Path: "next", Items: "items", MaxPages: 5})
+	require.Equal(t, "too many pages: more than 5", change.Error)
+
+	// A page linking to itself is the last one
+	change = check("/loop", &Pagination{Next: PageURL, Path: "next", Items: "items"})
+	require.Empty(t, change.Error)
+
+	change = check("/object", &Pagination{Next: PageLink})
+	require.Equal(t, "page 1: page is not an array (set the path of its items)", change.Error)
+
+	change = check("/html", &Pagination{Next: PageLink})
+	require.Contains(t, change.Error, "page 1 is not JSON")
+}
@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateWindow is the window over which host rate limits are counted
+const rateWindow = time.Minute
+
+// hostRateLimiter limits the number of requests per minute sent to each host
+// by all monitors of a manager. Hosts without a limit of their own use the
+// default limit; zero means no limit.
+type hostRateLimiter struct {
+	mu       sync.Mutex
+	perHost  int
+	limits   map[string]int
+	requests map[string][]time.Time
+}
+
+// newHostRateLimiter creates a rate limiter with no limits
+func newHostRateLimiter() *hostRateLimiter {
+	return &hostRateLimiter{
+		limits:   make(map[string]int),
+		requests: make(map[string][]time.Time),
+	}
+}
+
+// setDefault sets the limit of hosts that have no limit of their own
+func (l *hostRateLimiter) setDefault(perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perHost = perMinute
+}
+
+// setLimit sets the limit of a single host, overriding the default
+func (l *hostRateLimiter) setLimit(host string, perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[strings.ToLower(host)] = perMinute
+}
+
+// limit returns the requests per minute allowed to a host. The caller must
+// hold l.mu.
+func (l *hostRateLimiter) limit(host string) int {
+	if limit, ok := l.limits[host]; ok {
+		return limit
+	}
+	return l.perHost
+}
+
+// reserve returns how long a request to the host of rawURL starting at now
+// must wait so that no more than the host's limit of requests start within
+// any minute, and reserves its start time
+func (l *hostRateLimiter) reserve(rawURL string, now time.Time) time.Duration {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	host := strings.ToLower(u.Hostname())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limit(host)
+	if limit <= 0 {
+		return 0
+	}
+
+	// Only the last limit start times matter, and they are kept in order
+	starts := l.requests[host]
+	start := now
+	if len(starts) > 0 && starts[len(starts)-1].After(start) {
+		start = starts[len(starts)-1]
+	}
+	if len(starts) >= limit {
+		if earliest := starts[len(starts)-limit].Add(rateWindow); earliest.After(start) {
+			start = earliest
+		}
+	}
+
+	starts = append(starts, start)
+	if len(starts) > limit {
+		starts = starts[len(starts)-limit:]
+	}
+	l.requests[host] = starts
+
+	return start.Sub(now)
+}
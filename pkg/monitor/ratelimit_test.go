@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostRateLimiterReserve(t *testing.T) {
+	limiter := newHostRateLimiter()
+	limiter.setDefault(2)
+	limiter.setLimit("API.example.com", 1)
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	// Two requests per minute to most hosts
+	require.Zero(t, limiter.reserve("https://example.com/a", now))
+	require.Zero(t, limiter.reserve("https://example.com/b", now.Add(time.Second*10)))
+	require.Equal(t, time.Minute, limiter.reserve("https://example.com/c", now))
+	require.Equal(t, time.Second*10, limiter.reserve("https://example.com/d", now.Add(time.Minute)))
+
+	// Hosts are limited separately, and the port doesn't matter
+	require.Zero(t, limiter.reserve("https://www.example.com/", now))
+	require.Zero(t, limiter.reserve("https://api.example.com:8443/", now))
+	require.Equal(t, time.Second*30, limiter.reserve("https://api.example.com/", now.Add(time.Second*30)))
+
+	// A zero limit disables limiting
+	limiter.setLimit("free.example.com", 0)
+	for i := 0; i < 5; i++ {
+		require.Zero(t, limiter.reserve("https://free.example.com/", now))
+	}
+}
+
+func TestManagerRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	manager := NewManager()
+	manager.SetStagger(false)
+	manager.SetRateLimit(1000)
+	manager.SetHostRateLimit("127.0.0.1", 2)
+
+	var monitors []*Monitor
+	for i := 0; i < 3; i++ {
+		config := DefaultConfig(fmt.Sprintf("%s/page/%d", server.URL, i))
+		config.Interval = time.Hour
+		m, err := manager.AddMonitorWithConfig(config)
+		require.NoError(t, err)
+		monitors = append(monitors, m)
+	}
+
+	changes := manager.Start()
+	go func() {
+		for range changes {
+		}
+	}()
+	defer manager.Stop()
+
+	// Two monitors check right away while the third waits for the next minute
+	require.Eventually(t, func() bool {
+		queued := 0
+		for _, m := range monitors {
+			if _, status, _ := m.GetStatus(); status == "queued" {
+				queued++
+			}
+		}
+		return requests.Load() == 2 && queued == 1
+	}, time.Second*5, time.Millisecond)
+}
@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nemuizzz/hawkeye/pkg/browser"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// Fetcher selects how a monitor loads its URL
+type Fetcher string
+
+const (
+	// FetcherHTTP sends a plain HTTP request, the default
+	FetcherHTTP Fetcher = "http"
+	// FetcherBrowser renders the page in a headless browser and compares
+	// its DOM, for pages whose content is built by JavaScript
+	FetcherBrowser Fetcher = "browser"
+)
+
+var (
+	// ErrBrowserFetcher is returned for settings the browser fetcher can't
+	// honor
+	ErrBrowserFetcher = errors.New("the browser fetcher doesn't support representations, variants, pagination, request bodies, methods other than GET, CA files, client certificates or OAuth2")
+	// ErrWaitSelector is returned when a wait selector is set without the
+	// browser fetcher
+	ErrWaitSelector = errors.New("a wait selector requires the browser fetcher")
+)
+
+// ParseFetcher parses a fetcher name. An empty name is FetcherHTTP.
+func ParseFetcher(name string) (Fetcher, error) {
+	switch strings.ToLower(name) {
+	case "", string(FetcherHTTP):
+		return FetcherHTTP, nil
+	case string(FetcherBrowser):
+		return FetcherBrowser, nil
+	}
+	return "", fmt.Errorf("unknown fetcher '%s' (expected http or browser)", name)
+}
+
+// validateFetcher checks that the settings of a monitor work with its fetcher
+func validateFetcher(config *Config) error {
+	if _, err := ParseFetcher(string(config.Fetcher)); err != nil {
+		return err
+	}
+	if config.Fetcher != FetcherBrowser {
+		if config.WaitSelector != "" {
+			return ErrWaitSelector
+		}
+		return nil
+	}
+	if len(config.Representations) > 0 || len(config.Variants) > 0 || config.Pagination != nil || config.requestMethod() != http.MethodGet || config.TLS.CAFile != "" || config.TLS.CertFile != "" || config.OAuth2 != nil {
+		return ErrBrowserFetcher
+	}
+	return nil
+}
+
+// browserSource renders the URL in a headless browser, see Monitor.render
+type browserSource struct {
+	browser *browser.Browser
+	// own is set for a browser launched for the monitor alone, which is
+	// closed with it
+	own bool
+}
+
+// newBrowserSource creates a source rendering pages with b, or with a
+// browser of its own if b is nil
+func newBrowserSource(b *browser.Browser) *browserSource {
+	if b == nil {
+		return &browserSource{browser: browser.New(nil), own: true}
+	}
+	return &browserSource{browser: b}
+}
+
+// fetch implements source.fetch
+func (s *browserSource) fetch(m *Monitor, variant map[string]string) ([]byte, Change, error) {
+	return m.render(s.browser)
+}
+
+// close implements source.close
+func (s *browserSource) close() {
+	if s.own {
+		s.browser.Close()
+	}
+}
+
+// render loads the URL in b, waiting for Config.WaitSelector or for the
+// network to go idle, and returns the rendered DOM
+func (m *Monitor) render(b *browser.Browser) ([]byte, Change, error) {
+	settings := m.ClientSettings()
+	ctx := m.ctx
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+
+	if err := m.ensureLogin(); err != nil {
+		return nil, Change{}, err
+	}
+
+	// Headers are collected as for requests, then handed to the browser
+	req, err := http.NewRequest(http.MethodGet, m.config.URL, nil)
+	if err != nil {
+		return nil, Change{}, err
+	}
+	headers, err := secret.ResolveMap(m.config.Headers)
+	if err != nil {
+		return nil, Change{}, fmt.Errorf("header %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	m.addBaggage(req)
+	m.bustCache(req)
+	if err := m.authorize(req); err != nil {
+		return nil, Change{}, err
+	}
+	// Cookies kept between checks are sent, but cookies set while rendering
+	// stay in the browser
+	for _, cookie := range m.jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+
+	opts := &browser.RenderOptions{
+		Headers:            make(map[string]string),
+		UserAgent:          req.Header.Get("User-Agent"),
+		WaitSelector:       m.config.WaitSelector,
+		InsecureSkipVerify: m.config.TLS.InsecureSkipVerify,
+	}
+	req.Header.Del("User-Agent")
+	for key := range req.Header {
+		opts.Headers[key] = req.Header.Get(key)
+	}
+	if settings.ProxyURL != nil {
+		opts.ProxyURL = settings.ProxyURL.String()
+	}
+
+	start := m.clock.Now()
+	page, err := b.Render(ctx, req.URL.String(), opts)
+	if err != nil {
+		return nil, Change{}, err
+	}
+
+	change := Change{
+		URL:         m.config.URL,
+		Timestamp:   m.clock.Now(),
+		StatusCode:  page.StatusCode,
+		ContentType: page.ContentType,
+		Latency:     m.clock.Now().Sub(start),
+		Baggage:     m.config.Baggage,
+	}
+
+	m.mu.Lock()
+	m.latency = change.Latency
+	m.mu.Unlock()
+
+	if page.StatusCode == http.StatusUnauthorized && m.config.Login != nil {
+		// Rendering is too slow to retry; the next check logs in again
+		m.logout()
+	}
+
+	if m.config.Method == MethodStatus {
+		return nil, change, nil
+	}
+	if page.StatusCode != 0 && (page.StatusCode < 200 || page.StatusCode >= 300) {
+		return nil, change, &StatusError{StatusCode: page.StatusCode}
+	}
+
+	return page.HTML, change, nil
+}
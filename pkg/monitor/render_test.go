@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/nemuizzz/hawkeye/pkg/browser"
+	"github.com/nemuizzz/hawkeye/pkg/browser/browsertest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFetcher(t *testing.T) {
+	fetcher, err := ParseFetcher("")
+	require.NoError(t, err)
+	require.Equal(t, FetcherHTTP, fetcher)
+
+	fetcher, err = ParseFetcher("Browser")
+	require.NoError(t, err)
+	require.Equal(t, FetcherBrowser, fetcher)
+
+	_, err = ParseFetcher("curl")
+	require.ErrorContains(t, err, "unknown fetcher 'curl'")
+}
+
+func TestBrowserFetcher(t *testing.T) {
+	server := browsertest.NewServer()
+	defer server.Close()
+	server.SetPage("https://example.com/app", browsertest.Page{HTML: `<html><body><p id="price">10</p></body></html>`})
+
+	b := browser.New(&browser.Options{URL: server.URL})
+	defer b.Close()
+
+	config := DefaultConfig("https://example.com/app")
+	config.Fetcher = FetcherBrowser
+	config.WaitSelector = "#price"
+	config.Headers = map[string]string{"User-Agent": "Hawkeye-Test", "X-Token": "secret"}
+	config.Browser = b
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	// The rendered DOM is compared between checks
+	change := m.Check()
+	require.Empty(t, change.Error)
+	require.False(t, change.HasChanged)
+	require.Equal(t, 200, change.StatusCode)
+
+	server.SetPage("https://example.com/app", browsertest.Page{HTML: `<html><body><p id="price">12</p></body></html>`})
+	change = m.Check()
+	require.Empty(t, change.Error)
+	require.True(t, change.HasChanged)
+
+	requests := server.Requests()
+	require.Equal(t, "Hawkeye-Test", requests[0].UserAgent)
+	require.Equal(t, "secret", requests[0].Headers["X-Token"])
+	require.NotContains(t, requests[0].Headers, "User-Agent")
+
+	// Unsuccessful documents are errors
+	server.SetPage("https://example.com/app", browsertest.Page{Status: 500, HTML: "<html></html>"})
+	change = m.Check()
+	require.Contains(t, change.Error, "unexpected status code: 500")
+}
+
+func TestNewSource(t *testing.T) {
+	require.Equal(t, httpSource{}, newSource(DefaultConfig("https://example.com")))
+
+	// Without a browser of their own, monitors share the manager's
+	config := DefaultConfig("https://example.com/app")
+	config.Fetcher = FetcherBrowser
+	own, ok := newSource(config).(*browserSource)
+	require.True(t, ok)
+	require.True(t, own.own)
+
+	b := browser.New(nil)
+	manager := NewManager()
+	manager.SetBrowser(b)
+	m, err := manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+	require.Equal(t, &browserSource{browser: b}, m.source)
+}
+
+func TestValidateFetcher(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig("https://example.com/a")
+	config.WaitSelector = "#app"
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrWaitSelector)
+
+	config = DefaultConfig("https://example.com/b")
+	config.Fetcher = FetcherBrowser
+	config.Method = MethodHash
+	config.Representations = []string{"application/json"}
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrBrowserFetcher)
+
+	config = DefaultConfig("https://example.com/c")
+	config.Fetcher = "curl"
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorContains(t, err, "unknown fetcher")
+
+	config = DefaultConfig("https://example.com/d")
+	config.Fetcher = FetcherBrowser
+	config.WaitSelector = "#app"
+	_, err = manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+}
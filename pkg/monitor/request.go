@@ -0,0 +1,209 @@
+package monitor
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// RequestMethods are the HTTP methods a monitor can send
+var RequestMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+var (
+	// ErrBodyMethod is returned for request bodies sent with GET or HEAD
+	ErrBodyMethod = errors.New("a request body requires a method such as POST or PUT")
+	// ErrAuthConflict is returned for configs with more than one way to
+	// set the Authorization header
+	ErrAuthConflict = errors.New("only one of basic auth, a bearer token and OAuth2 can be set")
+)
+
+// BasicAuth holds the credentials of HTTP Basic authentication
+type BasicAuth struct {
+	User string
+	Pass string
+}
+
+// ParseBasicAuth parses credentials given as "user:pass". The password may
+// contain colons.
+func ParseBasicAuth(value string) (*BasicAuth, error) {
+	user, pass, ok := strings.Cut(value, ":")
+	if !ok || user == "" {
+		return nil, fmt.Errorf("invalid basic auth credentials (expected user:pass)")
+	}
+	return &BasicAuth{User: user, Pass: pass}, nil
+}
+
+// ParseRequestMethod parses an HTTP method name, case-insensitively. An
+// empty name is GET.
+func ParseRequestMethod(name string) (string, error) {
+	if name == "" {
+		return http.MethodGet, nil
+	}
+	method := strings.ToUpper(name)
+	for _, known := range RequestMethods {
+		if method == known {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unknown request method '%s' (expected one of %s)", name, strings.Join(RequestMethods, ", "))
+}
+
+// validateRequest checks the request method, body, authentication and
+// secret placeholders of a config
+func validateRequest(config *Config) error {
+	method, err := ParseRequestMethod(config.RequestMethod)
+	if err != nil {
+		return err
+	}
+	if config.Body != "" && config.RequestMethod != "" && (method == http.MethodGet || method == http.MethodHead) {
+		return ErrBodyMethod
+	}
+
+	auths := 0
+	for _, set := range []bool{config.BasicAuth != nil, config.BearerToken != "", config.OAuth2 != nil} {
+		if set {
+			auths++
+		}
+	}
+	if auths > 1 {
+		return ErrAuthConflict
+	}
+	return ValidateSecrets(config)
+}
+
+// ValidateSecrets checks the secret placeholders, see package secret, of the
+// headers and credentials of config. They are resolved with every request.
+func ValidateSecrets(config *Config) error {
+	values := map[string]string{"bearer token": config.BearerToken, "webhook secret": config.WebhookSecret}
+	for key, value := range config.Headers {
+		values["header "+key] = value
+	}
+	if auth := config.BasicAuth; auth != nil {
+		values["basic auth user"] = auth.User
+		values["basic auth password"] = auth.Pass
+	}
+	if login := config.Login; login != nil {
+		values["login body"] = login.Body
+		for key, value := range login.Headers {
+			values["login header "+key] = value
+		}
+	}
+	if oauth2 := config.OAuth2; oauth2 != nil {
+		values["OAuth2 client ID"] = oauth2.ClientID
+		values["OAuth2 client secret"] = oauth2.ClientSecret
+	}
+	for name, value := range values {
+		if err := secret.Validate(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// requestMethod returns the HTTP method of the monitor's requests. A body
+// without a method is POSTed, as with curl --data.
+func (c *Config) requestMethod() string {
+	if c.RequestMethod == "" && c.Body != "" {
+		return http.MethodPost
+	}
+	method, _ := ParseRequestMethod(c.RequestMethod)
+	return method
+}
+
+// bodyContentType returns the Content-Type of a request body: contentType if
+// set, otherwise JSON for bodies that are valid JSON and a form otherwise
+func bodyContentType(body, contentType string) string {
+	switch {
+	case contentType != "":
+		return contentType
+	case json.Valid([]byte(body)):
+		return "application/json"
+	default:
+		return "application/x-www-form-urlencoded"
+	}
+}
+
+// newRequest builds a request for rawURL, the monitor's URL or one of its
+// pages, with the monitor's method, body and headers. The headers of a
+// variant, if any, replace those of the monitor. Setting Accept-Encoding keeps the transport
+// from decoding gzip itself, so fetchContent decodes every encoding the same
+// way.
+func (m *Monitor) newRequest(rawURL string, variant map[string]string) (*http.Request, error) {
+	var body io.Reader
+	if m.config.Body != "" {
+		body = strings.NewReader(m.config.Body)
+	}
+
+	req, err := http.NewRequestWithContext(m.ctx, m.config.requestMethod(), rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := secret.ResolveMap(m.config.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("header %w", err)
+	}
+	customhttp.AddHeaders(req, headers, version.UserAgent())
+	if body != nil && (m.config.ContentType != "" || req.Header.Get("Content-Type") == "") {
+		req.Header.Set("Content-Type", bodyContentType(m.config.Body, m.config.ContentType))
+	}
+	m.addBaggage(req)
+	if err := m.authorize(req); err != nil {
+		return nil, err
+	}
+	variantHeaders, err := secret.ResolveMap(variant)
+	if err != nil {
+		return nil, fmt.Errorf("variant header %w", err)
+	}
+	for name, value := range variantHeaders {
+		req.Header.Set(name, value)
+	}
+	if m.config.AcceptEncoding != "" || req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", cmp.Or(m.config.AcceptEncoding, customhttp.DefaultAcceptEncoding))
+	}
+	m.bustCache(req)
+	return req, nil
+}
+
+// bustCache asks caches between the monitor and the origin for fresh
+// content: with Config.NoCache by the Cache-Control and Pragma headers, unless
+// Headers set them, and with Config.CacheBust by a query parameter with a
+// random value, for caches that ignore the headers
+func (m *Monitor) bustCache(req *http.Request) {
+	if m.config.NoCache {
+		if req.Header.Get("Cache-Control") == "" {
+			req.Header.Set("Cache-Control", "no-cache")
+		}
+		if req.Header.Get("Pragma") == "" {
+			req.Header.Set("Pragma", "no-cache")
+		}
+	}
+	if m.config.CacheBust != "" {
+		// The parameter is appended, so the rest of the query is sent as is
+		param := url.QueryEscape(m.config.CacheBust) + "=" + strconv.FormatUint(rand.Uint64(), 36)
+		if req.URL.RawQuery == "" {
+			req.URL.RawQuery = param
+		} else {
+			req.URL.RawQuery += "&" + param
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package monitor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBody(t *testing.T) {
+	type request struct {
+		method, contentType, body string
+	}
+	var mu sync.Mutex
+	var requests []request
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request{r.Method, r.Header.Get("Content-Type"), string(body)})
+		calls++
+		if calls == 1 {
+			// The body is sent again when the request is retried
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RequestMethod = http.MethodPut
+	config.Body = `{"query": "{ status }"}`
+	config.RetryInterval = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+	require.Empty(t, m.Check().Error)
+
+	// A body without a method is POSTed, as a form unless it is JSON
+	config = DefaultConfig(server.URL)
+	config.Body = "q=status"
+	m2 := NewMonitorWithConfig(config)
+	defer m2.Stop()
+	require.Empty(t, m2.Check().Error)
+
+	// ContentType replaces the Content-Type header
+	config = DefaultConfig(server.URL)
+	config.Body = "<query/>"
+	config.ContentType = "application/xml"
+	config.Headers = map[string]string{"Content-Type": "text/plain"}
+	m3 := NewMonitorWithConfig(config)
+	defer m3.Stop()
+	require.Empty(t, m3.Check().Error)
+
+	require.Equal(t, []request{
+		{http.MethodPut, "application/json", `{"query": "{ status }"}`},
+		{http.MethodPut, "application/json", `{"query": "{ status }"}`},
+		{http.MethodPost, "application/x-www-form-urlencoded", "q=status"},
+		{http.MethodPost, "application/xml", "<query/>"},
+	}, requests)
+}
+
+func TestParseRequestMethod(t *testing.T) {
+	method, err := ParseRequestMethod("")
+	require.NoError(t, err)
+	require.Equal(t, http.MethodGet, method)
+
+	method, err = ParseRequestMethod("patch")
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPatch, method)
+
+	_, err = ParseRequestMethod("FETCH")
+	require.ErrorContains(t, err, "unknown request method 'FETCH'")
+}
+
+func TestValidateRequest(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig("https://example.com/a")
+	config.RequestMethod = http.MethodGet
+	config.Body = "q=1"
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrBodyMethod)
+
+	config = DefaultConfig("https://example.com/b")
+	config.Body = "q=1"
+	config.Fetcher = FetcherBrowser
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrBrowserFetcher)
+
+	config = DefaultConfig("https://example.com/c")
+	config.BasicAuth = &BasicAuth{User: "me", Pass: "secret"}
+	config.BearerToken = "token"
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrAuthConflict)
+}
+
+func TestAuthorization(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.BasicAuth = &BasicAuth{User: "me", Pass: "pa:ss"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+	require.Empty(t, m.Check().Error)
+	require.Equal(t, "Basic bWU6cGE6c3M=", header)
+
+	config = DefaultConfig(server.URL)
+	config.BearerToken = "token"
+	m2 := NewMonitorWithConfig(config)
+	defer m2.Stop()
+	require.Empty(t, m2.Check().Error)
+	require.Equal(t, "Bearer token", header)
+}
+
+func TestSecretPlaceholders(t *testing.T) {
+	t.Setenv("HAWKEYE_TEST_TOKEN", "s3cret")
+	var header, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		apiKey = r.Header.Get("X-API-Key")
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.BearerToken = "${HAWKEYE_TEST_TOKEN}"
+	config.Headers = map[string]string{"X-API-Key": "key-${HAWKEYE_TEST_TOKEN}"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+	require.Empty(t, m.Check().Error)
+	require.Equal(t, "Bearer s3cret", header)
+	require.Equal(t, "key-s3cret", apiKey)
+
+	// The placeholders are kept, so they aren't saved resolved
+	require.Equal(t, "${HAWKEYE_TEST_TOKEN}", newMonitorState(m.GetConfig(), false).BearerToken)
+
+	config.BearerToken = "${HAWKEYE_TEST_UNSET}"
+	m2 := NewMonitorWithConfig(config)
+	defer m2.Stop()
+	require.Contains(t, m2.Check().Error, "HAWKEYE_TEST_UNSET")
+
+	config.BearerToken = "${HAWKEYE_TEST_TOKEN"
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorContains(t, err, "bearer token: unterminated placeholder")
+}
+
+func TestCacheBusting(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL + "/page?b=2&a=1")
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+	m.Check()
+	require.Empty(t, requests[0].Header.Get("Cache-Control"))
+	require.Equal(t, "b=2&a=1", requests[0].URL.RawQuery)
+
+	config.NoCache = true
+	config.CacheBust = "_cb"
+	config.Headers = map[string]string{"Pragma": "x-custom"}
+	m = NewMonitorWithConfig(config)
+	defer m.Stop()
+	m.Check()
+	m.Check()
+
+	require.Len(t, requests, 3)
+	for _, r := range requests[1:] {
+		require.Equal(t, "no-cache", r.Header.Get("Cache-Control"))
+		require.Equal(t, "x-custom", r.Header.Get("Pragma"), "headers win")
+		require.Regexp(t, `^b=2&a=1&_cb=[0-9a-z]+$`, r.URL.RawQuery)
+	}
+	require.NotEqual(t, requests[1].URL.RawQuery, requests[2].URL.RawQuery)
+}
+
+func TestParseBasicAuth(t *testing.T) {
+	auth, err := ParseBasicAuth("me:pa:ss")
+	require.NoError(t, err)
+	require.Equal(t, &BasicAuth{User: "me", Pass: "pa:ss"}, auth)
+
+	_, err = ParseBasicAuth("me")
+	require.ErrorContains(t, err, "expected user:pass")
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides whether a failed attempt to fetch a URL is retried and
+// how long to wait before the next attempt, see Config.RetryPolicy. It is
+// called on the monitor's goroutine.
+type RetryPolicy interface {
+	// ShouldRetry reports whether to retry after the attempt numbered from
+	// 1 failed with err. resp has the status code and headers of the
+	// response, without its body, or is nil if no response was received,
+	// e.g. for a DNS error or a timeout.
+	ShouldRetry(err error, resp *http.Response, attempt int) bool
+	// NextDelay returns the time to wait after the failed attempt before
+	// the next one
+	NextDelay(attempt int) time.Duration
+}
+
+// StatusError is the error of a response whose status code isn't a success.
+// Header holds the headers of the response, e.g. its Retry-After.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// Error implements error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// fixedRetry is the policy of Config.RetryCount and Config.RetryInterval:
+// every error is retried count times, waiting interval in between
+type fixedRetry struct {
+	count    int
+	interval time.Duration
+}
+
+// ShouldRetry implements RetryPolicy.ShouldRetry
+func (r fixedRetry) ShouldRetry(err error, resp *http.Response, attempt int) bool {
+	return attempt <= r.count
+}
+
+// NextDelay implements RetryPolicy.NextDelay
+func (r fixedRetry) NextDelay(attempt int) time.Duration {
+	return r.interval
+}
+
+// retryPolicy returns Config.RetryPolicy, or the policy of Config.RetryCount
+// and Config.RetryInterval
+func (m *Monitor) retryPolicy() RetryPolicy {
+	if m.config.RetryPolicy != nil {
+		return m.config.RetryPolicy
+	}
+	return fixedRetry{count: m.config.RetryCount, interval: m.config.RetryInterval}
+}
+
+// failedResponse describes the response of a failed attempt for a
+// RetryPolicy, or returns nil if none was received
+func failedResponse(change Change, err error) *http.Response {
+	if change.StatusCode == 0 {
+		return nil
+	}
+	resp := &http.Response{
+		StatusCode: change.StatusCode,
+		Status:     fmt.Sprintf("%d %s", change.StatusCode, http.StatusText(change.StatusCode)),
+		Header:     http.Header{},
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Header != nil {
+		resp.Header = statusErr.Header
+	}
+	return resp
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// retryAfterPolicy retries responses with a Retry-After header up to three
+// times and nothing else
+type retryAfterPolicy struct {
+	delays []time.Duration
+	next   time.Duration
+}
+
+func (p *retryAfterPolicy) ShouldRetry(err error, resp *http.Response, attempt int) bool {
+	if resp == nil || attempt > 3 {
+		return false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil {
+		return false
+	}
+	p.next = time.Duration(seconds) * time.Millisecond
+	return true
+}
+
+func (p *retryAfterPolicy) NextDelay(attempt int) time.Duration {
+	p.delays = append(p.delays, p.next)
+	return p.next
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	policy := &retryAfterPolicy{}
+	var errs []*CheckError
+	config := DefaultConfig(server.URL)
+	config.RetryPolicy = policy
+	config.OnError = func(err error) {
+		var checkErr *CheckError
+		require.ErrorAs(t, err, &checkErr)
+		errs = append(errs, checkErr)
+	}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	// The error without Retry-After isn't retried
+	change := m.Check()
+	require.Equal(t, EventError, change.Event)
+	require.EqualValues(t, 3, requests.Load())
+	require.Equal(t, []time.Duration{2 * time.Millisecond, time.Millisecond}, policy.delays)
+	require.Len(t, errs, 3)
+	require.False(t, errs[1].Final)
+	require.True(t, errs[2].Final)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, errs[0], &statusErr)
+	require.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	require.Equal(t, "2", statusErr.Header.Get("Retry-After"))
+
+	change = m.Check()
+	require.Empty(t, change.Error)
+}
+
+func TestFailedResponse(t *testing.T) {
+	require.Nil(t, failedResponse(Change{}, errors.New("connection refused")))
+
+	err := &StatusError{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"60"}}}
+	resp := failedResponse(Change{StatusCode: http.StatusTooManyRequests}, err)
+	require.Equal(t, "429 Too Many Requests", resp.Status)
+	require.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	// Responses that failed for another reason have no headers
+	resp = failedResponse(Change{StatusCode: http.StatusOK}, errors.New("body too large"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header)
+}
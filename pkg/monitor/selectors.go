@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"strings"
+
+	"github.com/nemuizzz/hawkeye/pkg/dom"
+)
+
+// ParseSelector parses a selector that picks parts of a page: an XPath
+// expression prefixed with "xpath:", such as "xpath://item/title", or a CSS
+// selector, optionally prefixed with "css:"
+func ParseSelector(spec string) (dom.Query, error) {
+	if expression, ok := strings.CutPrefix(spec, "xpath:"); ok {
+		return dom.CompileXPath(expression)
+	}
+	return dom.Compile(strings.TrimPrefix(spec, "css:"))
+}
+
+// SelectorFilter reduces a page to the parts being watched. It removes the
+// nodes matching its ignore selectors and, if it has watch selectors, keeps
+// only the nodes they match, one per line. Documents starting with an XML
+// declaration, such as feeds, are parsed as XML.
+type SelectorFilter struct {
+	watch  []dom.Query
+	ignore []dom.Query
+}
+
+// NewSelectorFilter creates a filter from watch and ignore selectors, see
+// ParseSelector
+func NewSelectorFilter(watch, ignore []string) (*SelectorFilter, error) {
+	f := &SelectorFilter{}
+	for _, spec := range watch {
+		query, err := ParseSelector(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.watch = append(f.watch, query)
+	}
+	for _, spec := range ignore {
+		query, err := ParseSelector(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.ignore = append(f.ignore, query)
+	}
+	return f, nil
+}
+
+// Apply implements ContentFilter.Apply
+func (f *SelectorFilter) Apply(content []byte) []byte {
+	doc := dom.ParseDocument(content)
+	for _, query := range f.ignore {
+		for _, n := range query.Select(doc) {
+			n.Remove()
+		}
+	}
+
+	if len(f.watch) == 0 {
+		return []byte(doc.HTML())
+	}
+
+	var parts []string
+	for _, query := range f.watch {
+		for _, n := range query.Select(doc) {
+			parts = append(parts, n.HTML())
+		}
+	}
+	return []byte(strings.Join(parts, "\n"))
+}
+
+// Description implements ContentFilter.Description
+func (f *SelectorFilter) Description() string {
+	var parts []string
+	if len(f.watch) > 0 {
+		parts = append(parts, "Watch "+joinQueries(f.watch))
+	}
+	if len(f.ignore) > 0 {
+		parts = append(parts, "Ignore "+joinQueries(f.ignore))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinQueries(queries []dom.Query) string {
+	sources := make([]string, len(queries))
+	for i, query := range queries {
+		sources[i] = "'" + query.String() + "'"
+	}
+	return strings.Join(sources, ", ")
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelector(t *testing.T) {
+	for _, spec := range []string{"#price", "css:.price", "xpath://item/title"} {
+		_, err := ParseSelector(spec)
+		require.NoError(t, err, spec)
+	}
+
+	_, err := ParseSelector("xpath://div[")
+	require.ErrorContains(t, err, "invalid XPath")
+	_, err = ParseSelector("css:div[")
+	require.ErrorContains(t, err, "invalid selector")
+}
+
+func TestSelectorFilter(t *testing.T) {
+	page := []byte(`<div id="main"><h1>Title</h1><p class="ad">Buy now</p><span data-ts="1">Price: 10</span></div>`)
+
+	tests := []struct {
+		name     string
+		watch    []string
+		ignore   []string
+		expected string
+	}{
+		{
+			name:     "ignore css",
+			ignore:   []string{".ad"},
+			expected: `<div id="main"><h1>Title</h1><span data-ts="1">Price: 10</span></div>`,
+		},
+		{
+			name:     "ignore xpath attribute",
+			ignore:   []string{"xpath://span/@data-ts", "xpath://p"},
+			expected: `<div id="main"><h1>Title</h1><span>Price: 10</span></div>`,
+		},
+		{
+			name:     "watch",
+			watch:    []string{"h1", "xpath://span/text()"},
+			expected: "<h1>Title</h1>\nPrice: 10",
+		},
+		{
+			name:     "watch and ignore",
+			watch:    []string{"xpath://div"},
+			ignore:   []string{"h1"},
+			expected: `<div id="main"><p class="ad">Buy now</p><span data-ts="1">Price: 10</span></div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewSelectorFilter(tt.watch, tt.ignore)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(filter.Apply(page)))
+		})
+	}
+}
+
+func TestMonitorWithXPathSelectors(t *testing.T) {
+	feed := `<?xml version="1.0"?><rss><channel><lastBuildDate>%s</lastBuildDate><item><title>%s</title></item></channel></rss>`
+
+	config := DefaultConfig("https://example.com/feed.xml")
+	config.WatchSelectors = []string{"xpath://item/title"}
+	monitor := NewMonitorWithConfig(config)
+
+	changed, _, _ := monitor.detectChange([]byte(fmt.Sprintf(feed, "Mon", "First")))
+	require.False(t, changed)
+
+	// A rebuilt feed with the same items is not a change
+	changed, _, _ = monitor.detectChange([]byte(fmt.Sprintf(feed, "Tue", "First")))
+	require.False(t, changed)
+
+	changed, details, _ := monitor.detectChange([]byte(fmt.Sprintf(feed, "Wed", "Second")))
+	require.True(t, changed)
+	require.Contains(t, details, "+<title>Second</title>")
+
+	_, err := NewManager().AddMonitorWithConfig(&Config{URL: "https://example.com", Interval: 1, IgnoreSelectors: []string{"xpath:["}})
+	require.ErrorContains(t, err, "invalid XPath")
+}
+
+func TestAlertSelectors(t *testing.T) {
+	page := `<html><body><div id="price">%s</div><div id="news">%s</div><p>Updated %s</p></body></html>`
+	price, news, updated := "10 EUR", "Nothing new", "2024-07-01T09:00:00Z"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, page, price, news, updated)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.IgnoreTimestamps = true
+	config.AlertSelectors = []string{"#price"}
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	_, reported := m.check()
+	require.False(t, reported)
+
+	// Changes elsewhere are informational
+	news = "Summer sale"
+	change, reported := m.check()
+	require.True(t, reported)
+	require.True(t, change.HasChanged)
+	require.True(t, change.Informational)
+
+	// Filtered parts are not changes
+	updated = "2024-07-02T09:00:00Z"
+	_, reported = m.check()
+	require.False(t, reported)
+
+	price = "8 EUR"
+	change, reported = m.check()
+	require.True(t, reported)
+	require.False(t, change.Informational)
+
+	_, err := NewManager().AddMonitorWithConfig(&Config{URL: "https://example.com", Interval: 1, Method: MethodStatus, AlertSelectors: []string{"#price"}})
+	require.ErrorIs(t, err, ErrAlertSelectors)
+	_, err = NewManager().AddMonitorWithConfig(&Config{URL: "https://example.com", Interval: 1, AlertSelectors: []string{"xpath:["}})
+	require.ErrorContains(t, err, "invalid XPath")
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import "github.com/nemuizzz/hawkeye/pkg/imap"
+
+// source fetches the content of a monitor's URL. A monitor picks its source
+// when it is created, from its URL and Config.Fetcher.
+type source interface {
+	// fetch loads the content, with the headers of variant, if any
+	fetch(m *Monitor, variant map[string]string) ([]byte, Change, error)
+	// close releases what the source holds once the monitor is stopped
+	close()
+}
+
+// newSource returns the source of a monitor with config
+func newSource(config *Config) source {
+	if imap.IsURL(config.URL) {
+		return mailboxSource{}
+	}
+	if config.Fetcher == FetcherBrowser {
+		return newBrowserSource(config.Browser)
+	}
+	return httpSource{}
+}
+
+// httpSource sends HTTP requests, logging in first if needed, and follows
+// the pages of Config.Pagination or reads what was appended to the content
+type httpSource struct{}
+
+// fetch implements source.fetch
+func (httpSource) fetch(m *Monitor, variant map[string]string) ([]byte, Change, error) {
+	if err := m.ensureLogin(); err != nil {
+		return nil, Change{}, err
+	}
+
+	if m.config.Pagination != nil && m.config.Method != MethodStatus {
+		return m.fetchPages(variant)
+	}
+	if m.config.AppendOnly || m.config.Method == MethodTail {
+		return m.fetchAppended()
+	}
+	content, change, _, err := m.fetchPage(m.config.URL, variant, nil)
+	return content, change, err
+}
+
+// close implements source.close
+func (httpSource) close() {}
@@ -0,0 +1,742 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+)
+
+// StateVersion is the version of the State format
+const StateVersion = 1
+
+// ErrNotSaved is returned, wrapped, by Manager methods whose change was made
+// but couldn't be saved to the manager's store
+var ErrNotSaved = errors.New("manager state not saved")
+
+// Store persists the monitors and groups of a manager, see Manager.SetStore
+type Store interface {
+	// Load returns the saved state, or nil if nothing was saved yet
+	Load() (*State, error)
+	// Save replaces the saved state
+	Save(state *State) error
+}
+
+// State is the registry of a manager in a form that can be saved: its
+// monitors and groups
+type State struct {
+	Version  int            `json:"version"`
+	Monitors []MonitorState `json:"monitors"`
+	Groups   []GroupState   `json:"groups,omitempty"`
+}
+
+// GroupState is the saved form of a monitor group
+type GroupState struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	URLs        []string `json:"urls"`
+	// ShareCookies is saved, but not the shared cookies
+	ShareCookies bool `json:"share_cookies,omitempty"`
+}
+
+// MonitorState is the saved form of a monitor. Settings that can't be
+// written down aren't saved: transports, clocks, custom compare functions,
+// error callbacks, content filters other than RegexFilter, and conditions or
+// extractors other than those of ParseCondition and ParseExtractor.
+type MonitorState struct {
+	URL                 string            `json:"url"`
+	Paused              bool              `json:"paused,omitempty"`
+	Interval            string            `json:"interval,omitempty"`
+	Schedule            string            `json:"schedule,omitempty"`
+	Timeout             string            `json:"timeout,omitempty"`
+	Jitter              string            `json:"jitter,omitempty"`
+	At                  *time.Time        `json:"at,omitempty"`
+	Deadline            *time.Time        `json:"deadline,omitempty"`
+	Expires             *time.Time        `json:"expires,omitempty"`
+	Until               string            `json:"until,omitempty"`
+	Method              string            `json:"method"`
+	ExpectedStatus      []int             `json:"expected_status,omitempty"`
+	Match               []string          `json:"match,omitempty"`
+	MatchAbsent         []string          `json:"match_absent,omitempty"`
+	Count               []string          `json:"count,omitempty"`
+	CSVKeys             []string          `json:"csv_keys,omitempty"`
+	CSVDelimiter        string            `json:"csv_delimiter,omitempty"`
+	TailSeparator       string            `json:"tail_separator,omitempty"`
+	WebhookMatch        []string          `json:"webhook_match,omitempty"`
+	WebhookFields       []string          `json:"webhook_fields,omitempty"`
+	WebhookSecret       string            `json:"webhook_secret,omitempty"`
+	ImageThreshold      float64           `json:"image_threshold,omitempty"`
+	ImageDiffDir        string            `json:"image_diff_dir,omitempty"`
+	Paginate            string            `json:"paginate,omitempty"`
+	PaginateItems       string            `json:"paginate_items,omitempty"`
+	MaxPages            int               `json:"max_pages,omitempty"`
+	Extract             string            `json:"extract,omitempty"`
+	Thresholds          []ThresholdState  `json:"thresholds,omitempty"`
+	Delta               float64           `json:"delta,omitempty"`
+	DeltaPercent        float64           `json:"delta_percent,omitempty"`
+	Representations     []string          `json:"representations,omitempty"`
+	Variants            []Variant         `json:"variants,omitempty"`
+	Headers             map[string]string `json:"headers,omitempty"`
+	Baggage             map[string]string `json:"baggage,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Ignore              []string          `json:"ignore,omitempty"`
+	Select              []string          `json:"select,omitempty"`
+	Alert               []string          `json:"alert,omitempty"`
+	Filters             []FilterState     `json:"filters,omitempty"`
+	NormalizeWhitespace bool              `json:"normalize_whitespace,omitempty"`
+	IgnoreTimestamps    bool              `json:"ignore_timestamps,omitempty"`
+	RetryCount          int               `json:"retries"`
+	RetryInterval       string            `json:"retry_interval,omitempty"`
+	FailureThreshold    int               `json:"failure_threshold,omitempty"`
+	ConfirmDelay        string            `json:"confirm_delay,omitempty"`
+	FollowRedirects     bool              `json:"follow_redirects"`
+	IncludeResponseBody bool              `json:"include_body,omitempty"`
+	DiffContextLines    int               `json:"diff_context"`
+	MaxDetailsLines     int               `json:"max_details_lines"`
+	MaxDetailsBytes     int               `json:"max_details_bytes"`
+	MaxBodySize         int64             `json:"max_body_size"`
+	MaxSnapshotSize     int64             `json:"max_snapshot_size,omitempty"`
+	Windows             []WindowState     `json:"windows,omitempty"`
+	Proxy               string            `json:"proxy,omitempty"`
+	AcceptEncoding      string            `json:"accept_encoding,omitempty"`
+	NoCache             bool              `json:"no_cache,omitempty"`
+	CacheBust           string            `json:"cache_bust,omitempty"`
+	CaptureHAR          bool              `json:"capture_har,omitempty"`
+	AppendOnly          bool              `json:"append_only,omitempty"`
+	TLS                 *TLSState         `json:"tls,omitempty"`
+	RespectRobotsTxt    bool              `json:"respect_robots_txt,omitempty"`
+	Fetcher             Fetcher           `json:"fetcher,omitempty"`
+	RequestMethod       string            `json:"request_method,omitempty"`
+	Body                string            `json:"body,omitempty"`
+	ContentType         string            `json:"content_type,omitempty"`
+	KeepCookies         bool              `json:"keep_cookies,omitempty"`
+	Cookies             map[string]string `json:"cookies,omitempty"`
+	BasicAuth           *BasicAuthState   `json:"basic_auth,omitempty"`
+	BearerToken         string            `json:"bearer_token,omitempty"`
+	Login               *LoginState       `json:"login,omitempty"`
+	OAuth2              *OAuth2State      `json:"oauth2,omitempty"`
+	WaitSelector        string            `json:"wait_selector,omitempty"`
+}
+
+// ThresholdState is the saved form of a Threshold
+type ThresholdState struct {
+	Value float64 `json:"value"`
+	Above bool    `json:"above,omitempty"`
+}
+
+// FilterState is the saved form of a RegexFilter
+type FilterState struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// WindowState is the saved form of a maintenance window
+type WindowState struct {
+	Spec string `json:"spec"`
+	Mode string `json:"mode"`
+}
+
+// TLSState is the saved form of the TLS options
+type TLSState struct {
+	InsecureSkipVerify bool   `json:"insecure,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
+	MinVersion         uint16 `json:"min_version,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+}
+
+// BasicAuthState is the saved form of a BasicAuth
+type BasicAuthState struct {
+	User string `json:"user"`
+	Pass string `json:"pass,omitempty"`
+}
+
+// LoginState is the saved form of a Login
+type LoginState struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Token       string            `json:"token,omitempty"`
+}
+
+// OAuth2State is the saved form of the OAuth2 options
+type OAuth2State struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// newMonitorState returns the saved form of a monitor configuration
+func newMonitorState(config Config, paused bool) MonitorState {
+	s := MonitorState{
+		URL:                 config.URL,
+		Paused:              paused,
+		Method:              config.Method.String(),
+		ExpectedStatus:      config.ExpectedStatus,
+		CSVKeys:             config.CSVKeys,
+		CSVDelimiter:        formatDelimiter(config.CSVDelimiter),
+		TailSeparator:       formatSeparator(config.TailSeparator),
+		WebhookFields:       config.WebhookFields,
+		WebhookSecret:       config.WebhookSecret,
+		ImageThreshold:      config.ImageThreshold,
+		ImageDiffDir:        config.ImageDiffDir,
+		Delta:               config.Delta,
+		DeltaPercent:        config.DeltaPercent,
+		Representations:     config.Representations,
+		Variants:            config.Variants,
+		Headers:             config.Headers,
+		Baggage:             config.Baggage,
+		Labels:              config.Labels,
+		Ignore:              config.IgnoreSelectors,
+		Select:              config.WatchSelectors,
+		Alert:               config.AlertSelectors,
+		NormalizeWhitespace: config.NormalizeWhitespace,
+		IgnoreTimestamps:    config.IgnoreTimestamps,
+		RetryCount:          config.RetryCount,
+		FailureThreshold:    config.FailureThreshold,
+		FollowRedirects:     config.FollowRedirects,
+		IncludeResponseBody: config.IncludeResponseBody,
+		DiffContextLines:    config.DiffContextLines,
+		MaxDetailsLines:     config.MaxDetailsLines,
+		MaxDetailsBytes:     config.MaxDetailsBytes,
+		MaxBodySize:         config.MaxBodySize,
+		MaxSnapshotSize:     config.MaxSnapshotSize,
+		RespectRobotsTxt:    config.RespectRobotsTxt,
+		Fetcher:             config.Fetcher,
+		RequestMethod:       config.RequestMethod,
+		Body:                config.Body,
+		ContentType:         config.ContentType,
+		KeepCookies:         config.KeepCookies,
+		Cookies:             config.Cookies,
+		BearerToken:         config.BearerToken,
+		WaitSelector:        config.WaitSelector,
+	}
+
+	s.Interval = formatDuration(config.Interval)
+	s.Timeout = formatDuration(config.Timeout)
+	s.Jitter = formatDuration(config.Jitter)
+	s.RetryInterval = formatDuration(config.RetryInterval)
+	s.ConfirmDelay = formatDuration(config.ConfirmDelay)
+	if config.Schedule != nil {
+		s.Schedule = config.Schedule.String()
+	}
+	if !config.At.IsZero() {
+		s.At = &config.At
+	}
+	if !config.Deadline.IsZero() {
+		s.Deadline = &config.Deadline
+	}
+	if !config.Expires.IsZero() {
+		s.Expires = &config.Expires
+	}
+
+	if config.Until != nil {
+		s.Until, _ = conditionSpec(config.Until)
+	}
+	for _, match := range config.Matches {
+		spec, ok := conditionSpec(match.Condition)
+		switch {
+		case !ok:
+		case match.Absent:
+			s.MatchAbsent = append(s.MatchAbsent, spec)
+		default:
+			s.Match = append(s.Match, spec)
+		}
+	}
+	for _, condition := range config.WebhookMatch {
+		if spec, ok := conditionSpec(condition); ok {
+			s.WebhookMatch = append(s.WebhookMatch, spec)
+		}
+	}
+	for _, keyword := range config.Keywords {
+		s.Count = append(s.Count, keyword.String())
+	}
+	if config.Pagination != nil {
+		s.Paginate = config.Pagination.String()
+		s.PaginateItems = config.Pagination.Items
+		s.MaxPages = config.Pagination.MaxPages
+	}
+	if config.Extract != nil {
+		s.Extract, _ = extractorSpec(config.Extract)
+	}
+	for _, threshold := range config.Thresholds {
+		s.Thresholds = append(s.Thresholds, ThresholdState{Value: threshold.Value, Above: threshold.Above})
+	}
+
+	for _, filter := range config.ContentFilters {
+		if f, ok := filter.(*RegexFilter); ok {
+			s.Filters = append(s.Filters, FilterState{
+				Pattern:     f.pattern.String(),
+				Replacement: string(f.replacement),
+				Description: f.description,
+			})
+		}
+	}
+	for _, window := range config.MaintenanceWindows {
+		s.Windows = append(s.Windows, WindowState{Spec: window.String(), Mode: window.Mode.String()})
+	}
+
+	if config.ProxyURL != nil {
+		s.Proxy = config.ProxyURL.String()
+	}
+	s.AcceptEncoding = config.AcceptEncoding
+	s.NoCache = config.NoCache
+	s.CacheBust = config.CacheBust
+	s.CaptureHAR = config.CaptureHAR
+	s.AppendOnly = config.AppendOnly
+	if !config.TLS.IsZero() {
+		s.TLS = &TLSState{
+			InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+			CAFile:             config.TLS.CAFile,
+			MinVersion:         config.TLS.MinVersion,
+			CertFile:           config.TLS.CertFile,
+			KeyFile:            config.TLS.KeyFile,
+		}
+	}
+	if auth := config.BasicAuth; auth != nil {
+		s.BasicAuth = &BasicAuthState{User: auth.User, Pass: auth.Pass}
+	}
+	if login := config.Login; login != nil {
+		s.Login = &LoginState{
+			URL:         login.URL,
+			Method:      login.Method,
+			Body:        login.Body,
+			ContentType: login.ContentType,
+			Headers:     login.Headers,
+			Token:       login.Token,
+		}
+	}
+	if oauth2 := config.OAuth2; oauth2 != nil {
+		s.OAuth2 = &OAuth2State{
+			TokenURL:     oauth2.TokenURL,
+			ClientID:     oauth2.ClientID,
+			ClientSecret: oauth2.ClientSecret,
+			Scopes:       oauth2.Scopes,
+		}
+	}
+
+	return s
+}
+
+// Config returns the monitor configuration of the saved state
+func (s MonitorState) Config() (*Config, error) {
+	config := &Config{
+		URL:                 s.URL,
+		ExpectedStatus:      s.ExpectedStatus,
+		CSVKeys:             s.CSVKeys,
+		ImageThreshold:      s.ImageThreshold,
+		ImageDiffDir:        s.ImageDiffDir,
+		Delta:               s.Delta,
+		DeltaPercent:        s.DeltaPercent,
+		Representations:     s.Representations,
+		Variants:            s.Variants,
+		Headers:             s.Headers,
+		Baggage:             s.Baggage,
+		Labels:              s.Labels,
+		IgnoreSelectors:     s.Ignore,
+		WatchSelectors:      s.Select,
+		AlertSelectors:      s.Alert,
+		NormalizeWhitespace: s.NormalizeWhitespace,
+		IgnoreTimestamps:    s.IgnoreTimestamps,
+		RetryCount:          s.RetryCount,
+		FailureThreshold:    s.FailureThreshold,
+		FollowRedirects:     s.FollowRedirects,
+		IncludeResponseBody: s.IncludeResponseBody,
+		DiffContextLines:    s.DiffContextLines,
+		MaxDetailsLines:     s.MaxDetailsLines,
+		MaxDetailsBytes:     s.MaxDetailsBytes,
+		MaxBodySize:         s.MaxBodySize,
+		MaxSnapshotSize:     s.MaxSnapshotSize,
+		RespectRobotsTxt:    s.RespectRobotsTxt,
+		Fetcher:             s.Fetcher,
+		RequestMethod:       s.RequestMethod,
+		Body:                s.Body,
+		ContentType:         s.ContentType,
+		KeepCookies:         s.KeepCookies,
+		Cookies:             s.Cookies,
+		BearerToken:         s.BearerToken,
+		WebhookFields:       s.WebhookFields,
+		WebhookSecret:       s.WebhookSecret,
+		WaitSelector:        s.WaitSelector,
+	}
+
+	var err error
+	for _, d := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"interval", s.Interval, &config.Interval},
+		{"timeout", s.Timeout, &config.Timeout},
+		{"jitter", s.Jitter, &config.Jitter},
+		{"retry interval", s.RetryInterval, &config.RetryInterval},
+		{"confirm delay", s.ConfirmDelay, &config.ConfirmDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if *d.field, err = time.ParseDuration(d.value); err != nil {
+			return nil, fmt.Errorf("invalid %s for %s: %w", d.name, s.URL, err)
+		}
+	}
+
+	if s.Schedule != "" {
+		if config.Schedule, err = schedule.ParseCron(s.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid schedule for %s: %w", s.URL, err)
+		}
+	}
+	if s.At != nil {
+		config.At = *s.At
+	}
+	if s.Deadline != nil {
+		config.Deadline = *s.Deadline
+	}
+	if s.Expires != nil {
+		config.Expires = *s.Expires
+	}
+
+	if config.Method, err = ParseMethod(s.Method); err != nil {
+		return nil, fmt.Errorf("invalid method for %s: %w", s.URL, err)
+	}
+	if s.Until != "" {
+		if config.Until, err = ParseCondition(s.Until); err != nil {
+			return nil, fmt.Errorf("invalid condition for %s: %w", s.URL, err)
+		}
+	}
+	if len(s.Match)+len(s.MatchAbsent) > 0 {
+		if config.Matches, err = ParseMatches(s.Match, s.MatchAbsent); err != nil {
+			return nil, fmt.Errorf("invalid match for %s: %w", s.URL, err)
+		}
+	}
+	if len(s.Count) > 0 {
+		if config.Keywords, err = ParseKeywords(s.Count); err != nil {
+			return nil, fmt.Errorf("invalid count for %s: %w", s.URL, err)
+		}
+	}
+	if config.CSVDelimiter, err = ParseDelimiter(s.CSVDelimiter); err != nil {
+		return nil, fmt.Errorf("invalid CSV delimiter for %s: %w", s.URL, err)
+	}
+	if config.TailSeparator, err = ParseSeparator(s.TailSeparator); err != nil {
+		return nil, fmt.Errorf("invalid tail separator for %s: %w", s.URL, err)
+	}
+	for _, spec := range s.WebhookMatch {
+		condition, err := ParseCondition(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook match for %s: %w", s.URL, err)
+		}
+		config.WebhookMatch = append(config.WebhookMatch, condition)
+	}
+	if s.Paginate != "" {
+		if config.Pagination, err = ParsePagination(s.Paginate); err != nil {
+			return nil, fmt.Errorf("invalid pagination for %s: %w", s.URL, err)
+		}
+		config.Pagination.Items = s.PaginateItems
+		config.Pagination.MaxPages = s.MaxPages
+	}
+	if s.Extract != "" {
+		if config.Extract, err = ParseExtractor(s.Extract); err != nil {
+			return nil, fmt.Errorf("invalid extract for %s: %w", s.URL, err)
+		}
+	}
+	for _, threshold := range s.Thresholds {
+		config.Thresholds = append(config.Thresholds, Threshold{Value: threshold.Value, Above: threshold.Above})
+	}
+
+	for _, f := range s.Filters {
+		filter, err := NewRegexFilter(f.Pattern, f.Replacement, f.Description)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter for %s: %w", s.URL, err)
+		}
+		config.ContentFilters = append(config.ContentFilters, filter)
+	}
+	for _, w := range s.Windows {
+		mode, err := schedule.ParseMode(w.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window for %s: %w", s.URL, err)
+		}
+		window, err := schedule.ParseWindow(w.Spec, mode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window for %s: %w", s.URL, err)
+		}
+		config.MaintenanceWindows = append(config.MaintenanceWindows, window)
+	}
+
+	if s.Proxy != "" {
+		if config.ProxyURL, err = customhttp.ParseProxyURL(s.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy for %s: %w", s.URL, err)
+		}
+	}
+	config.AcceptEncoding = s.AcceptEncoding
+	config.NoCache = s.NoCache
+	config.CacheBust = s.CacheBust
+	config.CaptureHAR = s.CaptureHAR
+	config.AppendOnly = s.AppendOnly
+	if s.TLS != nil {
+		config.TLS = customhttp.TLSOptions{
+			InsecureSkipVerify: s.TLS.InsecureSkipVerify,
+			CAFile:             s.TLS.CAFile,
+			MinVersion:         s.TLS.MinVersion,
+			CertFile:           s.TLS.CertFile,
+			KeyFile:            s.TLS.KeyFile,
+		}
+	}
+	if s.BasicAuth != nil {
+		config.BasicAuth = &BasicAuth{User: s.BasicAuth.User, Pass: s.BasicAuth.Pass}
+	}
+	if s.Login != nil {
+		config.Login = &Login{
+			URL:         s.Login.URL,
+			Method:      s.Login.Method,
+			Body:        s.Login.Body,
+			ContentType: s.Login.ContentType,
+			Headers:     s.Login.Headers,
+			Token:       s.Login.Token,
+		}
+	}
+	if s.OAuth2 != nil {
+		config.OAuth2 = &customhttp.OAuth2Options{
+			TokenURL:     s.OAuth2.TokenURL,
+			ClientID:     s.OAuth2.ClientID,
+			ClientSecret: s.OAuth2.ClientSecret,
+			Scopes:       s.OAuth2.Scopes,
+		}
+	}
+
+	return config, nil
+}
+
+// formatDuration formats a duration, leaving zero durations out
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// conditionSpec returns the spec that ParseCondition parses into the
+// condition, or false for conditions of other types
+func conditionSpec(condition Condition) (string, bool) {
+	switch c := condition.(type) {
+	case *TextCondition:
+		return "text:" + c.text, true
+	case *RegexCondition:
+		return "regex:" + c.pattern.String(), true
+	case *JSONCondition:
+		op := "="
+		if c.negate {
+			op = "!="
+		}
+		return "json:" + c.path + op + c.value, true
+	default:
+		return "", false
+	}
+}
+
+// extractorSpec returns the spec that ParseExtractor parses into the
+// extractor, or false for extractors of other types
+func extractorSpec(extractor Extractor) (string, bool) {
+	switch e := extractor.(type) {
+	case *SelectorExtractor:
+		return "css:" + e.selector.String(), true
+	case *RegexExtractor:
+		return "regex:" + e.pattern.String(), true
+	case *JSONExtractor:
+		return "json:" + e.path, true
+	default:
+		return "", false
+	}
+}
+
+// FileStore saves the state of a manager as JSON in a file
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store saving to the file at path. The file and its
+// directory are created on the first save.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load implements Store.Load
+func (s *FileStore) Load() (*State, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", s.path, err)
+	}
+	if state.Version > StateVersion {
+		return nil, fmt.Errorf("state file %s has version %d, newer than the supported %d", s.path, state.Version, StateVersion)
+	}
+	return &state, nil
+}
+
+// Save implements Store.Save. The file is replaced at once, so a crash
+// while saving leaves the previous state.
+func (s *FileStore) Save(state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path)
+}
+
+// SetStore makes the manager persistent. The monitors and groups saved in
+// the store are added to the manager, paused monitors staying paused, and
+// from then on the manager saves its state to the store after every change
+// of its monitors, groups or paused monitors. Monitors the manager already
+// has take precedence over saved ones for the same URL. Saved monitors that
+// can't be restored are reported in the error, while the others are still
+// added.
+func (m *Manager) SetStore(store Store) error {
+	state, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("error loading manager state: %w", err)
+	}
+
+	var errs []error
+	if state != nil {
+		for _, saved := range state.Monitors {
+			if _, err := m.GetMonitor(saved.URL); err == nil {
+				continue
+			}
+			config, err := saved.Config()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			monitor, err := m.AddMonitorWithConfig(config)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error restoring monitor for %s: %w", saved.URL, err))
+				continue
+			}
+			if saved.Paused {
+				monitor.Pause()
+			}
+		}
+
+		for _, saved := range state.Groups {
+			if _, err := m.GetGroup(saved.Name); err != nil {
+				if _, err := m.CreateGroup(saved.Name, saved.Description); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
+			for _, url := range saved.URLs {
+				if err := m.AddToGroup(url, saved.Name); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if saved.ShareCookies {
+				if err := m.ShareCookies(saved.Name, true); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	m.saveMu.Lock()
+	m.store = store
+	m.saveMu.Unlock()
+
+	// Save the state merged with the monitors the manager already had
+	if err := m.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// State returns the monitors and groups of the manager in the form saved to
+// its store
+func (m *Manager) State() *State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state := &State{Version: StateVersion, Monitors: []MonitorState{}}
+
+	urls := make([]string, 0, len(m.monitors))
+	for url := range m.monitors {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		monitor := m.monitors[url]
+		state.Monitors = append(state.Monitors, newMonitorState(monitor.GetConfig(), monitor.IsPaused()))
+	}
+
+	names := make([]string, 0, len(m.groups))
+	for name := range m.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		group := m.groups[name]
+		saved := GroupState{Name: name, Description: group.Description, URLs: []string{}, ShareCookies: group.ShareCookies}
+		for url := range group.Monitors {
+			saved.URLs = append(saved.URLs, url)
+		}
+		sort.Strings(saved.URLs)
+		state.Groups = append(state.Groups, saved)
+	}
+
+	return state
+}
+
+// save saves the state of the manager to its store, if it has one. Saves
+// are serialized so that an older state never replaces a newer one.
+func (m *Manager) save() error {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.Save(m.State()); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotSaved, err)
+	}
+	return nil
+}
+
+// persist saves the state of the manager after a change, unless the change
+// failed. It is deferred by the methods that change the state, before they
+// lock m.mu, with a pointer to their error result.
+func (m *Manager) persist(err *error) {
+	if *err == nil {
+		*err = m.save()
+	}
+}
@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitorStateRoundTrip(t *testing.T) {
+	config := DefaultConfig("https://example.com/price")
+	config.Interval = 0
+	config.Schedule, _ = schedule.ParseCron("*/10 9-17 * * mon-fri")
+	config.Method = MethodValue
+	config.Extract, _ = ParseExtractor("css:#product .price")
+	config.Thresholds = []Threshold{{Value: 100}, {Value: 200, Above: true}}
+	config.Until, _ = ParseCondition("json:status!=open")
+	config.Matches, _ = ParseMatches([]string{"regex:[0-9]+ left"}, []string{"out of stock"})
+	config.Deadline = time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	config.Headers = map[string]string{"Authorization": "Bearer x"}
+	config.WatchSelectors = []string{"xpath://div[@id='product']"}
+	filter, _ := NewRegexFilter(`id=\d+`, "id=", "Ignore IDs")
+	config.ContentFilters = ContentFilterList{filter}
+	window, _ := schedule.ParseWindow("Sat 02:00-04:00", schedule.ModeSilence)
+	config.MaintenanceWindows = schedule.Windows{window}
+	config.ProxyURL, _ = customhttp.ParseProxyURL("socks5://localhost:1080")
+	config.TLS.CAFile = "ca.pem"
+	config.RequestMethod = "PUT"
+	config.Body = `{"query": "price"}`
+	config.KeepCookies = true
+	config.Cookies = map[string]string{"consent": "yes"}
+	config.Login = &Login{URL: "https://example.com/login", Body: "user=me", Token: "access_token"}
+	config.BasicAuth = &BasicAuth{User: "me", Pass: "secret"}
+	config.Keywords, _ = ParseKeywords([]string{"regex:(?i)go developer"})
+	config.CSVKeys = []string{"region", "sku"}
+	config.CSVDelimiter = '\t'
+	config.ImageThreshold = 0.5
+	config.ImageDiffDir = "diffs"
+	config.MaxSnapshotSize = 1 << 20
+	config.AcceptEncoding = "identity"
+	config.NoCache = true
+	config.CacheBust = "_cb"
+	config.CaptureHAR = true
+	config.Pagination = &Pagination{Next: PageCursor, Path: "meta.next", Param: "after", Items: "data", MaxPages: 10}
+	config.OAuth2 = &customhttp.OAuth2Options{TokenURL: "https://example.com/token", ClientID: "hawkeye", ClientSecret: "secret", Scopes: []string{"read"}}
+
+	state := newMonitorState(*config, true)
+	require.True(t, state.Paused)
+	require.Equal(t, "css:#product .price", state.Extract)
+	require.Equal(t, "json:status!=open", state.Until)
+	require.Equal(t, []string{"regex:[0-9]+ left"}, state.Match)
+	require.Equal(t, []string{"text:out of stock"}, state.MatchAbsent)
+	require.Empty(t, state.Interval)
+
+	restored, err := state.Config()
+	require.NoError(t, err)
+	require.Equal(t, state, newMonitorState(*restored, true))
+	require.Equal(t, schedule.ModeSilence, restored.MaintenanceWindows[0].Mode)
+	require.Equal(t, "Ignore IDs", restored.ContentFilters[0].Description())
+	require.Equal(t, `{"query": "price"}`, restored.Body)
+	require.True(t, restored.KeepCookies)
+	require.Equal(t, "yes", restored.Cookies["consent"])
+	require.Equal(t, config.Login, restored.Login)
+	require.Equal(t, config.BasicAuth, restored.BasicAuth)
+	require.Equal(t, config.OAuth2, restored.OAuth2)
+	require.Equal(t, '\t', restored.CSVDelimiter)
+	require.Equal(t, "diffs", restored.ImageDiffDir)
+	require.Equal(t, "identity", restored.AcceptEncoding)
+	require.Equal(t, config.Pagination, restored.Pagination)
+	require.True(t, restored.CaptureHAR)
+
+	state.Interval = "soon"
+	_, err = state.Config()
+	require.ErrorContains(t, err, "invalid interval")
+}
+
+func TestManagerStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "manager.json")
+
+	manager := NewManager()
+	require.NoError(t, manager.SetStore(NewFileStore(path)))
+	_, err := manager.AddMonitorWithConfig(DefaultConfig("https://example.com/a"))
+	require.NoError(t, err)
+	config := DefaultConfig("https://example.com/b")
+	config.Method = MethodKeyword
+	config.Matches, _ = ParseMatches([]string{"in stock"}, nil)
+	_, err = manager.AddMonitorWithConfig(config)
+	require.NoError(t, err)
+	_, err = manager.CreateGroup("shop", "Shop pages")
+	require.NoError(t, err)
+	require.NoError(t, manager.AddToGroup("https://example.com/b", "shop"))
+	require.NoError(t, manager.PauseMonitor("https://example.com/b"))
+
+	restored := NewManager()
+	require.NoError(t, restored.SetStore(NewFileStore(path)))
+	require.Equal(t, manager.State(), restored.State())
+	m, err := restored.GetMonitor("https://example.com/b")
+	require.NoError(t, err)
+	require.True(t, m.IsPaused())
+	require.Equal(t, MethodKeyword, m.GetConfig().Method)
+	group, err := restored.GetGroup("shop")
+	require.NoError(t, err)
+	require.Equal(t, "Shop pages", group.Description)
+	require.Contains(t, group.Monitors, "https://example.com/b")
+
+	// Removing a monitor is saved too
+	require.NoError(t, restored.RemoveMonitor("https://example.com/a"))
+	state, err := NewFileStore(path).Load()
+	require.NoError(t, err)
+	require.Len(t, state.Monitors, 1)
+	require.Equal(t, "https://example.com/b", state.Monitors[0].URL)
+}
+
+func TestManagerStoreKeepsExistingMonitors(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "manager.json"))
+	require.NoError(t, store.Save(&State{Version: StateVersion, Monitors: []MonitorState{
+		{URL: "https://example.com", Interval: "1h", Method: "hash"},
+		{URL: "https://example.org", Interval: "1h", Method: "keyword"},
+	}}))
+
+	manager := NewManager()
+	_, err := manager.AddMonitorWithConfig(DefaultConfig("https://example.com"))
+	require.NoError(t, err)
+
+	// The keyword monitor has no matches
+	err = manager.SetStore(store)
+	require.ErrorIs(t, err, ErrNoMatches)
+	m, err := manager.GetMonitor("https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, m.GetConfig().Interval)
+	require.Equal(t, []string{"https://example.com"}, manager.ListMonitors())
+}
+
+// failingStore fails every save
+type failingStore struct{}
+
+func (failingStore) Load() (*State, error) { return nil, nil }
+func (failingStore) Save(*State) error     { return errors.New("disk full") }
+
+func TestManagerStoreErrors(t *testing.T) {
+	manager := NewManager()
+	require.ErrorIs(t, manager.SetStore(failingStore{}), ErrNotSaved)
+
+	// The monitor is added even though it isn't saved
+	m, err := manager.AddMonitorWithConfig(DefaultConfig("https://example.com"))
+	require.ErrorIs(t, err, ErrNotSaved)
+	require.NotNil(t, m)
+	require.Len(t, manager.ListMonitors(), 1)
+
+	path := filepath.Join(t.TempDir(), "manager.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	require.ErrorContains(t, NewManager().SetStore(NewFileStore(path)), "invalid state file")
+}
@@ -0,0 +1,347 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrAppendOnly is returned when Config.AppendOnly is set with a method
+	// other than hash, length or tail, or together with options that fetch
+	// or compare the content in parts
+	ErrAppendOnly = errors.New("append-only fetching requires the hash, length or tail method and can't be combined with representations, variants, selectors, pagination or the browser")
+	// ErrTail is returned when MethodTail is combined with options that
+	// fetch or compare the content in parts
+	ErrTail = errors.New("the tail method can't be combined with representations, variants, selectors, pagination or the browser")
+	// ErrTailSeparator is returned when Config.TailSeparator is set with a
+	// method other than tail
+	ErrTailSeparator = errors.New("tail separator requires the tail method")
+)
+
+// tailOverlap is the number of bytes at the end of the previous content that
+// are requested again with the appended ones, to check that the content was
+// only appended to
+const tailOverlap = 64
+
+// maxTailSegments bounds the changes sent for one check with MethodTail; the
+// segments beyond are summed up in the last one
+const maxTailSegments = 100
+
+// Separators of the segments of MethodTail, see ParseSeparator
+const (
+	SeparatorLine      = ""
+	SeparatorParagraph = "\n\n"
+)
+
+// tailState is the complete content of the previous check of an append-only
+// or tail monitor, as it was sent, and its strong ETag, see
+// Config.AppendOnly. tracked is set once the content was seen.
+type tailState struct {
+	content     []byte
+	contentType string
+	etag        string
+	tracked     bool
+}
+
+// Segment is a part of the content appended since the previous check,
+// reported with MethodTail. Offset is the position of its first byte in the
+// content.
+type Segment struct {
+	Text   string `json:"text"`
+	Offset int64  `json:"offset"`
+}
+
+// ParseSeparator parses the separator of the segments of MethodTail: "line"
+// or an empty name for lines, "paragraph" for parts between blank lines, or
+// any other text that separates them, such as "---"
+func ParseSeparator(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "line":
+		return SeparatorLine, nil
+	case "paragraph":
+		return SeparatorParagraph, nil
+	}
+	if strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("invalid separator '%s' (expected line, paragraph or a text)", name)
+	}
+	return name, nil
+}
+
+// formatSeparator returns the name of a separator, see ParseSeparator
+func formatSeparator(separator string) string {
+	if separator == SeparatorParagraph {
+		return "paragraph"
+	}
+	return separator
+}
+
+// validateAppendOnly checks that Config.AppendOnly and MethodTail can be
+// applied
+func validateAppendOnly(config *Config) error {
+	if !config.AppendOnly && config.Method != MethodTail {
+		return nil
+	}
+	err := ErrAppendOnly
+	if config.Method == MethodTail {
+		err = ErrTail
+	} else if config.Method != MethodHash && config.Method != MethodLength {
+		return err
+	}
+	if len(config.Representations)+len(config.Variants)+len(config.WatchSelectors)+len(config.IgnoreSelectors)+len(config.AlertSelectors) > 0 {
+		return err
+	}
+	if config.Pagination != nil || config.Fetcher == FetcherBrowser {
+		return err
+	}
+	return nil
+}
+
+// strongETag returns the ETag of a response if it is a strong one. Weak ETags
+// don't promise the same bytes, so ranges can't be requested with them.
+func strongETag(header http.Header) string {
+	etag := header.Get("ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		return ""
+	}
+	return etag
+}
+
+// digestOf describes a complete body that was kept whole
+func digestOf(content []byte) bodyDigest {
+	hash := sha256.Sum256(content)
+	return bodyDigest{hash: hash[:], size: int64(len(content))}
+}
+
+// fetchAppended fetches the content of an append-only monitor. With a strong
+// ETag from the previous check only the bytes after that content are
+// requested, from a little before its end to check that it didn't change: a
+// 304 means nothing was appended and a 206 carries the appended bytes.
+// Servers that ignore ranges send the complete content, and content that
+// shrank or was rewritten is fetched again in full.
+func (m *Monitor) fetchAppended() ([]byte, Change, error) {
+	m.mu.RLock()
+	tail := m.tail
+	m.mu.RUnlock()
+
+	if tail.etag == "" {
+		return m.fetchFull()
+	}
+
+	offset := max(len(tail.content)-tailOverlap, 0)
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	header.Set("If-None-Match", tail.etag)
+	// Ranges count the bytes as sent, so they must not be encoded
+	header.Set("Accept-Encoding", "identity")
+
+	content, change, respHeader, err := m.fetchPage(m.config.URL, nil, header)
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotModified:
+		change.ContentType = tail.contentType
+		change.digest = digestOf(tail.content)
+		change.etag = tail.etag
+		return tail.content, change, nil
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The content shrank, e.g. a log that was rotated
+		return m.fetchFull()
+	case err != nil:
+		return nil, change, err
+	case change.StatusCode != http.StatusPartialContent:
+		// The server sent the complete content
+		if !change.digest.truncated {
+			change.etag = strongETag(respHeader)
+		}
+		return content, change, nil
+	}
+
+	start, ok := contentRangeStart(respHeader.Get("Content-Range"))
+	if !ok || start != offset || change.digest.truncated || !bytes.HasPrefix(content, tail.content[offset:]) {
+		return m.fetchFull()
+	}
+	full := append(tail.content[:offset:offset], content...)
+	if m.config.MaxBodySize > 0 && int64(len(full)) > m.config.MaxBodySize {
+		return nil, change, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, m.config.MaxBodySize)
+	}
+	change.digest = digestOf(full)
+	change.etag = strongETag(respHeader)
+	return full, change, nil
+}
+
+// fetchFull fetches the complete content of an append-only monitor along
+// with its strong ETag, if any
+func (m *Monitor) fetchFull() ([]byte, Change, error) {
+	content, change, header, err := m.fetchPage(m.config.URL, nil, nil)
+	if err == nil && !change.digest.truncated {
+		change.etag = strongETag(header)
+	}
+	return content, change, err
+}
+
+// contentRangeStart returns the first byte of a Content-Range such as
+// "bytes 100-199/200"
+func contentRangeStart(value string) (int, bool) {
+	spec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, false
+	}
+	first, _, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, false
+	}
+	start, err := strconv.Atoi(first)
+	return start, err == nil
+}
+
+// detectAppend compares content with the previous content of an append-only
+// monitor. If content starts with it, the appended lines are reported
+// without comparing the rest, and ok is true; otherwise the content must be
+// compared in full. The content becomes the new baseline either way.
+func (m *Monitor) detectAppend(content []byte, change Change) (changed bool, details string, hunks []DiffHunk, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := m.tail.content
+	m.tail = tailState{content: content, contentType: change.ContentType, etag: change.etag, tracked: true}
+	if m.lastContent == nil || last == nil || !bytes.HasPrefix(content, last) {
+		return false, "", nil, false
+	}
+	m.lastContent = content
+	m.lastDigest = change.digest
+
+	if len(content) == len(last) {
+		return false, "", nil, true
+	}
+	switch m.config.Method {
+	case MethodLength:
+		changed = len(m.prepare(last)) != len(m.prepare(content))
+	default:
+		changed = !bytes.Equal(m.prepare(last), m.prepare(content))
+	}
+	if !changed {
+		return false, "", nil, true
+	}
+
+	hunk, appended := appendHunk(last, content, m.config.DiffContextLines)
+	details = "Text appended to the last line"
+	if appended > 0 {
+		details = countOf(int64(appended), "new line") + " appended"
+	}
+	hunks = []DiffHunk{hunk}
+	details += "\n" + FormatHunks(hunks)
+	return true, truncateDetails(details, m.config.MaxDetailsLines, m.config.MaxDetailsBytes), hunks, true
+}
+
+// appendHunk returns the hunk of the lines appended to last in content, which
+// starts with last, with up to context lines before them, and the number of
+// new lines. A last line without a line break is replaced by the line it
+// became.
+func appendHunk(last, content []byte, context int) (DiffHunk, int) {
+	start := bytes.LastIndexByte(last, '\n') + 1
+	from := start
+	for n := 0; n < context && from > 0; n++ {
+		from = bytes.LastIndexByte(last[:from-1], '\n') + 1
+	}
+
+	var ops []diffOp
+	for _, line := range splitLines(string(last[from:start])) {
+		ops = append(ops, diffOp{kind: ' ', text: line})
+	}
+	partial := start < len(last)
+	if partial {
+		ops = append(ops, diffOp{kind: '-', text: strings.TrimSuffix(string(last[start:]), "\r")})
+	}
+	added := splitLines(string(content[start:]))
+	for _, line := range added {
+		ops = append(ops, diffOp{kind: '+', text: line})
+	}
+
+	hunk := newHunk(ops, 0, len(ops))
+	before := bytes.Count(last[:from], []byte("\n"))
+	hunk.OldStart += before
+	hunk.NewStart += before
+
+	appended := len(added)
+	if partial {
+		appended--
+	}
+	return hunk, appended
+}
+
+// detectTailChange finds the segments appended to the content since the
+// previous check, see MethodTail. Content that shrank, e.g. a rotated log,
+// is followed from its start, so all of it is new, while content that was
+// rewritten is reported once and followed from its new end.
+func (m *Monitor) detectTailChange(content []byte, change Change) (bool, string, []Segment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last, tracked := m.tail.content, m.tail.tracked
+	m.tail = tailState{content: content, contentType: change.ContentType, etag: change.etag, tracked: true}
+	if !tracked {
+		return false, "", nil
+	}
+
+	offset := len(last)
+	details := ""
+	switch {
+	case len(content) < len(last):
+		offset, details = 0, "Content shrank and is followed from its start\n"
+	case !bytes.HasPrefix(content, last):
+		return true, "Content was rewritten rather than appended to and is followed from its new end", nil
+	}
+
+	segments := splitSegments(content[offset:], offset, m.config.TailSeparator)
+	if len(segments) == 0 {
+		return false, "", nil
+	}
+
+	noun := "new segment"
+	if m.config.TailSeparator == SeparatorLine {
+		noun = "new line"
+	}
+	details += countOf(int64(len(segments)), noun) + " appended"
+	if len(segments) > maxTailSegments {
+		rest := segments[maxTailSegments-1:]
+		segments = append(segments[:maxTailSegments-1:maxTailSegments-1], Segment{
+			Text:   "[... " + countOf(int64(len(rest)), "more "+strings.TrimPrefix(noun, "new ")) + " ...]",
+			Offset: rest[0].Offset,
+		})
+	}
+	for _, segment := range segments {
+		details += "\n" + segment.Text
+	}
+	return true, truncateDetails(details, m.config.MaxDetailsLines, m.config.MaxDetailsBytes), segments
+}
+
+// splitSegments splits text, found at offset in the content, at separator,
+// see ParseSeparator. Line breaks around segments are left out, and blank
+// segments are skipped.
+func splitSegments(text []byte, offset int, separator string) []Segment {
+	sep := []byte(separator)
+	if separator == SeparatorLine {
+		sep = []byte("\n")
+	}
+
+	var segments []Segment
+	for len(text) > 0 {
+		end, next := len(text), len(text)
+		if i := bytes.Index(text, sep); i >= 0 {
+			end, next = i, i+len(sep)
+		}
+		segment := bytes.TrimLeft(text[:end], "\r\n")
+		start := offset + end - len(segment)
+		segment = bytes.TrimRight(segment, "\r\n")
+		if len(bytes.TrimSpace(segment)) > 0 {
+			segments = append(segments, Segment{Text: string(segment), Offset: int64(start)})
+		}
+		text = text[next:]
+		offset += next
+	}
+	return segments
+}
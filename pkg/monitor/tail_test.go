@@ -0,0 +1,239 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendOnly(t *testing.T) {
+	var mu sync.Mutex
+	log := "GET /a 200\nGET /b 404\n"
+	var ranges []string
+	etag := func(content string) string { return fmt.Sprintf(`"%d"`, len(content)) }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		content := log
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", etag(content))
+		http.ServeContent(w, r, "access.log", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	setLog := func(content string) {
+		mu.Lock()
+		log = content
+		mu.Unlock()
+	}
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.AppendOnly = true
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+
+	// Only the appended bytes are requested
+	setLog(log + "GET /c 200\nGET /d 500\n")
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, http.StatusPartialContent, change.StatusCode)
+	require.Equal(t, "bytes=0-", ranges[1])
+	require.True(t, strings.HasPrefix(change.Details, "2 new lines appended\n"))
+	require.Equal(t, "@@ -1,2 +1,4 @@\n GET /a 200\n GET /b 404\n+GET /c 200\n+GET /d 500\n", change.Diff)
+
+	// Nothing is sent for unchanged content
+	change = m.Check()
+	require.False(t, change.HasChanged)
+	require.Equal(t, http.StatusNotModified, change.StatusCode)
+
+	long := log + strings.Repeat("GET /e 200\n", 20)
+	setLog(long)
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.True(t, strings.HasPrefix(change.Details, "20 new lines appended\n"))
+	require.Equal(t, int64(len(long)), change.NewLength)
+
+	// The range starts a little before the end to check the content
+	m.Check()
+	require.Equal(t, fmt.Sprintf("bytes=%d-", len(long)-tailOverlap), ranges[4])
+
+	// Content rewritten at its end is fetched and compared in full
+	setLog(strings.TrimSuffix(long, "GET /e 200\n") + "GET /z 200\nGET /f 200\n")
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, http.StatusOK, change.StatusCode)
+	require.False(t, strings.Contains(change.Details, "appended"))
+	require.Contains(t, change.Diff, "-GET /e 200\n+GET /z 200")
+
+	// Content that shrank is fetched in full
+	setLog("GET /g 200\n")
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, http.StatusOK, change.StatusCode)
+	require.Contains(t, change.Diff, "+GET /g 200")
+}
+
+func TestAppendOnlyWeakETag(t *testing.T) {
+	log := "line 1\n"
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `W/"1"`)
+		w.Write([]byte(log))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.AppendOnly = true
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	m.Check()
+	log += "line 2\n"
+	change := m.Check()
+	require.Equal(t, []string{"", ""}, ranges)
+	require.True(t, strings.HasPrefix(change.Details, "1 new line appended\n"))
+}
+
+func TestAppendHunk(t *testing.T) {
+	hunk, appended := appendHunk([]byte("a\nb\nc\nd\npart"), []byte("a\nb\nc\nd\npartial\ne\n"), 2)
+	require.Equal(t, 1, appended)
+	require.Equal(t, "@@ -3,3 +3,4 @@\n c\n d\n-part\n+partial\n+e\n", hunk.String())
+
+	hunk, appended = appendHunk([]byte(""), []byte("a\n"), 3)
+	require.Equal(t, 1, appended)
+	require.Equal(t, "@@ -0,0 +1,1 @@\n+a\n", hunk.String())
+}
+
+func TestAppendOnlyValidation(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig("https://example.com/export.csv")
+	config.AppendOnly = true
+	config.Method = MethodCSV
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrAppendOnly)
+
+	config.Method = MethodHash
+	config.Variants = []Variant{{Name: "de", Headers: map[string]string{"Accept-Language": "de"}}}
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrAppendOnly)
+}
+
+func TestTailMethod(t *testing.T) {
+	var mu sync.Mutex
+	log := "started\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		content := log
+		mu.Unlock()
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, len(content)))
+		http.ServeContent(w, r, "app.log", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	setLog := func(content string) {
+		mu.Lock()
+		log = content
+		mu.Unlock()
+	}
+
+	config := DefaultConfig(server.URL)
+	config.Method = MethodTail
+	config.RetryCount = 0
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged)
+
+	// Each appended line is its own change
+	setLog("started\nERROR disk full\nWARN retrying\n")
+	go m.performCheck()
+	for _, segment := range []Segment{{Text: "ERROR disk full", Offset: 8}, {Text: "WARN retrying", Offset: 24}} {
+		change := <-m.changes
+		require.True(t, change.HasChanged)
+		require.Equal(t, http.StatusPartialContent, change.StatusCode)
+		require.Equal(t, segment, *change.Segment)
+		require.Equal(t, segment.Text, change.Details)
+	}
+
+	// A rotated log is followed from its start
+	setLog("rotated\n")
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Content shrank and is followed from its start\n1 new line appended\nrotated", change.Details)
+
+	// A rewritten one is reported once and followed from its new end
+	setLog("replaced\n")
+	change = m.Check()
+	require.True(t, change.HasChanged)
+	require.Contains(t, change.Details, "rewritten")
+	setLog("replaced\nnext\n")
+	change = m.Check()
+	require.Equal(t, "1 new line appended\nnext", change.Details)
+}
+
+func TestTailMethodTooManySegments(t *testing.T) {
+	m := NewMonitorWithConfig(DefaultConfig("https://example.com/app.log"))
+	m.config.Method = MethodTail
+	m.config.MaxDetailsLines = 0
+	m.tail.tracked = true
+
+	changed, details, segments := m.detectTailChange([]byte(strings.Repeat("line\n", maxTailSegments+5)), Change{})
+	require.True(t, changed)
+	require.True(t, strings.HasPrefix(details, "105 new lines appended\n"))
+	require.Len(t, segments, maxTailSegments)
+	require.Equal(t, Segment{Text: "[... 6 more lines ...]", Offset: int64(5 * (maxTailSegments - 1))}, segments[maxTailSegments-1])
+}
+
+func TestSplitSegments(t *testing.T) {
+	require.Equal(t, []Segment{{Text: "a", Offset: 10}, {Text: "b", Offset: 14}}, splitSegments([]byte("a\r\n\nb"), 10, SeparatorLine))
+	require.Equal(t, []Segment{
+		{Text: "## 1.1\n- fix", Offset: 0},
+		{Text: "## 1.0", Offset: 14},
+	}, splitSegments([]byte("## 1.1\n- fix\n\n## 1.0\n"), 0, SeparatorParagraph))
+	require.Equal(t, []Segment{{Text: "one", Offset: 0}, {Text: "two", Offset: 8}}, splitSegments([]byte("one\n---\ntwo\n---\n"), 0, "---"))
+}
+
+func TestParseSeparator(t *testing.T) {
+	for name, want := range map[string]string{"": SeparatorLine, "line": SeparatorLine, "Paragraph": SeparatorParagraph, "---": "---"} {
+		separator, err := ParseSeparator(name)
+		require.NoError(t, err)
+		require.Equal(t, want, separator)
+		if name != "Paragraph" {
+			parsed, err := ParseSeparator(formatSeparator(separator))
+			require.NoError(t, err)
+			require.Equal(t, separator, parsed)
+		}
+	}
+	_, err := ParseSeparator("  ")
+	require.Error(t, err)
+}
+
+func TestTailValidation(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig("https://example.com/app.log")
+	config.Method = MethodTail
+	config.WatchSelectors = []string{"#log"}
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrTail)
+
+	config = DefaultConfig("https://example.com/app.log")
+	config.TailSeparator = "---"
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrTailSeparator)
+}
@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nemuizzz/hawkeye/pkg/dom"
+)
+
+// Extractor finds the number watched with MethodValue in the content
+type Extractor interface {
+	// Extract returns the number found in the content
+	Extract(content []byte) (float64, error)
+	// Description returns a human-readable description of where the number
+	// is taken from
+	Description() string
+}
+
+// ErrValueNotFound is returned by extractors when the content has no value
+// where they look for it
+var ErrValueNotFound = errors.New("value not found")
+
+// numberPattern finds a number in text such as "$1,299.00" or "-3.5 °C"
+var numberPattern = regexp.MustCompile(`[-+]?(?:\d[\d,]*(?:\.\d+)?|\.\d+)`)
+
+// ValueChange is the number before and after a change found with
+// MethodValue
+type ValueChange struct {
+	Old float64 `json:"old"`
+	New float64 `json:"new"`
+}
+
+// Threshold is a value whose crossing is reported with MethodValue
+type Threshold struct {
+	Value float64
+	// Above is set for values rising above Value, otherwise values
+	// dropping below it are reported
+	Above bool
+}
+
+// Beyond reports whether a value is past the threshold
+func (t Threshold) Beyond(value float64) bool {
+	if t.Above {
+		return value > t.Value
+	}
+	return value < t.Value
+}
+
+// String describes the threshold, e.g. "below 100"
+func (t Threshold) String() string {
+	if t.Above {
+		return "above " + formatNumber(t.Value)
+	}
+	return "below " + formatNumber(t.Value)
+}
+
+// RegexExtractor takes the number from the first match of a regular
+// expression, or from its first group if it has one
+type RegexExtractor struct {
+	pattern *regexp.Regexp
+}
+
+// NewRegexExtractor creates an extractor taking the number from pattern
+func NewRegexExtractor(pattern string) (*RegexExtractor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexExtractor{pattern: re}, nil
+}
+
+// Extract implements Extractor.Extract
+func (e *RegexExtractor) Extract(content []byte) (float64, error) {
+	match := e.pattern.FindSubmatch(content)
+	if match == nil {
+		return 0, ErrValueNotFound
+	}
+	if len(match) > 1 {
+		return parseNumber(string(match[1]))
+	}
+	return parseNumber(string(match[0]))
+}
+
+// Description implements Extractor.Description
+func (e *RegexExtractor) Description() string {
+	return fmt.Sprintf("pattern /%s/", e.pattern)
+}
+
+// SelectorExtractor takes the number from the text of the first element
+// matching a CSS selector
+type SelectorExtractor struct {
+	selector *dom.Selector
+}
+
+// NewSelectorExtractor creates an extractor taking the number from the
+// element matching selector, e.g. "#product .price"
+func NewSelectorExtractor(selector string) (*SelectorExtractor, error) {
+	s, err := dom.Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &SelectorExtractor{selector: s}, nil
+}
+
+// Extract implements Extractor.Extract
+func (e *SelectorExtractor) Extract(content []byte) (float64, error) {
+	element := e.selector.First(dom.Parse(content))
+	if element == nil {
+		return 0, ErrValueNotFound
+	}
+	return parseNumber(element.Text())
+}
+
+// Description implements Extractor.Description
+func (e *SelectorExtractor) Description() string {
+	return fmt.Sprintf("selector %q", e.selector)
+}
+
+// JSONExtractor takes the number from a field of a JSON document
+type JSONExtractor struct {
+	path string
+}
+
+// NewJSONExtractor creates an extractor taking the number from the field at
+// a dotted path such as "data.price" or "items.0.stock". A leading "$." as
+// in JSONPath is accepted.
+func NewJSONExtractor(path string) *JSONExtractor {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	return &JSONExtractor{path: path}
+}
+
+// Extract implements Extractor.Extract. Numbers written as strings, such as
+// "12.50", are accepted.
+func (e *JSONExtractor) Extract(content []byte) (float64, error) {
+	var doc any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return 0, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	field, ok := lookupJSON(doc, e.path)
+	if !ok {
+		return 0, ErrValueNotFound
+	}
+
+	switch value := field.(type) {
+	case float64:
+		return value, nil
+	case string:
+		return parseNumber(value)
+	default:
+		return 0, fmt.Errorf("%s is not a number", jsonValue(field))
+	}
+}
+
+// Description implements Extractor.Description
+func (e *JSONExtractor) Description() string {
+	return "JSON " + e.path
+}
+
+// ParseExtractor parses an extractor written as "css:.price",
+// "regex:Total: ([0-9.]+)" or "json:data.price"
+func ParseExtractor(spec string) (Extractor, error) {
+	kind, value, _ := strings.Cut(spec, ":")
+	if value == "" {
+		return nil, fmt.Errorf("invalid extractor '%s' (expected css:, regex: or json: followed by a selector, pattern or path)", spec)
+	}
+
+	switch kind {
+	case "css":
+		return NewSelectorExtractor(value)
+	case "regex":
+		extractor, err := NewRegexExtractor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex extractor: %w", err)
+		}
+		return extractor, nil
+	case "json":
+		return NewJSONExtractor(value), nil
+	default:
+		return nil, fmt.Errorf("unknown extractor '%s' (expected css, regex or json)", kind)
+	}
+}
+
+// parseNumber parses the first number in text, ignoring thousands
+// separators and anything around it such as a currency
+func parseNumber(text string) (float64, error) {
+	number := numberPattern.FindString(text)
+	if number == "" {
+		return 0, fmt.Errorf("no number in %q", text)
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+}
+
+// formatNumber formats a number without trailing zeros
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// extractValue extracts the number watched with MethodValue
+func (m *Monitor) extractValue(content []byte) (float64, error) {
+	value, err := m.config.Extract.Extract(m.prepare(content))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", m.config.Extract.Description(), err)
+	}
+	return value, nil
+}
+
+// detectValueChange compares a value with the one of the previous check,
+// see MethodValue
+func (m *Monitor) detectValueChange(value float64) (bool, string, *ValueChange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := m.lastValue
+	m.lastValue = &value
+	if last == nil || *last == value {
+		return false, "", nil
+	}
+	old := *last
+
+	var reasons []string
+	for _, threshold := range m.config.Thresholds {
+		before, after := threshold.Beyond(old), threshold.Beyond(value)
+		switch {
+		case after && !before && threshold.Above:
+			reasons = append(reasons, "Rose "+threshold.String())
+		case after && !before:
+			reasons = append(reasons, "Dropped "+threshold.String())
+		case before && !after:
+			reasons = append(reasons, "No longer "+threshold.String())
+		}
+	}
+
+	delta := value - old
+	if m.config.Delta > 0 && math.Abs(delta) > m.config.Delta {
+		reasons = append(reasons, "Changed by more than "+formatNumber(m.config.Delta))
+	}
+	if m.config.DeltaPercent > 0 && (old == 0 || math.Abs(delta/old)*100 > m.config.DeltaPercent) {
+		reasons = append(reasons, "Changed by more than "+formatNumber(m.config.DeltaPercent)+"%")
+	}
+
+	limited := len(m.config.Thresholds) > 0 || m.config.Delta > 0 || m.config.DeltaPercent > 0
+	if limited && len(reasons) == 0 {
+		return false, "", nil
+	}
+
+	// Round away floating point noise such as 0.30000000000000004
+	sign := ""
+	if delta > 0 {
+		sign = "+"
+	}
+	details := fmt.Sprintf("Value changed from %s to %s (%s%s", formatNumber(old), formatNumber(value), sign, formatNumber(math.Round(delta*1e9)/1e9))
+	if old != 0 {
+		details += fmt.Sprintf(", %+.1f%%", delta/old*100)
+	}
+	details += ")"
+	for _, reason := range reasons {
+		details += "\n" + reason
+	}
+
+	return true, details, &ValueChange{Old: old, New: value}
+}
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected float64
+		wantErr  bool
+	}{
+		{text: "42", expected: 42},
+		{text: "$1,299.00", expected: 1299},
+		{text: "Temperature: -3.5 °C", expected: -3.5},
+		{text: "only .5 left", expected: 0.5},
+		{text: "sold out", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			value, err := parseNumber(tc.text)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value)
+		})
+	}
+}
+
+func TestExtractors(t *testing.T) {
+	page := []byte(`<div class="product"><span class="price">€ 1.499,00</span>
+<span id="total">Total: 87.50 EUR</span></div>`)
+	data := []byte(`{"data": {"price": 19.99, "stock": "12 left", "name": "Widget"}, "items": [{"qty": 3}]}`)
+
+	tests := []struct {
+		spec     string
+		content  []byte
+		expected float64
+		err      string
+	}{
+		{spec: "css:#total", content: page, expected: 87.5},
+		{spec: "css:.missing", content: page, err: "value not found"},
+		{spec: "regex:Total: ([0-9.]+)", content: page, expected: 87.5},
+		{spec: `regex:\d+\.\d+ EUR`, content: page, expected: 87.5},
+		{spec: "json:data.price", content: data, expected: 19.99},
+		{spec: "json:$.items.0.qty", content: data, expected: 3},
+		{spec: "json:data.stock", content: data, expected: 12},
+		{spec: "json:data.name", content: data, err: `no number in "Widget"`},
+		{spec: "json:data.missing", content: data, err: "value not found"},
+		{spec: "json:data", content: data, err: "is not a number"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			extractor, err := ParseExtractor(tc.spec)
+			require.NoError(t, err)
+
+			value, err := extractor.Extract(tc.content)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value)
+		})
+	}
+}
+
+func TestParseExtractorInvalid(t *testing.T) {
+	for _, spec := range []string{"", ".price", "xpath://span", "css:div[", "regex:("} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseExtractor(spec)
+			require.Error(t, err)
+		})
+	}
+}
+
+// valueServer serves the prices in turn, repeating the last one
+func valueServer(t *testing.T, prices ...string) *httptest.Server {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(int(calls.Add(1))-1, len(prices)-1)
+		w.Write([]byte(`<p>Widget <span class="price">$` + prices[i] + `</span></p>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func valueMonitor(t *testing.T, url string, configure func(*Config)) *Monitor {
+	extractor, err := NewSelectorExtractor(".price")
+	require.NoError(t, err)
+	config := DefaultConfig(url)
+	config.RetryCount = 0
+	config.Method = MethodValue
+	config.Extract = extractor
+	configure(config)
+	m := NewMonitorWithConfig(config)
+	t.Cleanup(m.Stop)
+	return m
+}
+
+func TestValueMethod(t *testing.T) {
+	server := valueServer(t, "120", "120", "121.10")
+	m := valueMonitor(t, server.URL, func(*Config) {})
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged)
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Value changed from 120 to 121.1 (+1.1, +0.9%)", change.Details)
+	require.Equal(t, &ValueChange{Old: 120, New: 121.1}, change.Value)
+}
+
+func TestValueThresholds(t *testing.T) {
+	server := valueServer(t, "120", "110", "95", "90", "105", "250")
+	m := valueMonitor(t, server.URL, func(config *Config) {
+		config.Thresholds = []Threshold{{Value: 100}, {Value: 200, Above: true}}
+	})
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "110 is above the threshold")
+
+	change := m.Check()
+	require.True(t, change.HasChanged)
+	require.Equal(t, "Value changed from 110 to 95 (-15, -13.6%)\nDropped below 100", change.Details)
+
+	require.False(t, m.Check().HasChanged, "90 is still below")
+	require.Equal(t, "Value changed from 90 to 105 (+15, +16.7%)\nNo longer below 100", m.Check().Details)
+	require.Equal(t, "Value changed from 105 to 250 (+145, +138.1%)\nRose above 200", m.Check().Details)
+}
+
+func TestValueDelta(t *testing.T) {
+	server := valueServer(t, "100", "104", "110", "98")
+	m := valueMonitor(t, server.URL, func(config *Config) {
+		config.Delta = 5
+		config.DeltaPercent = 10
+	})
+
+	require.False(t, m.Check().HasChanged)
+	require.False(t, m.Check().HasChanged, "a change of 4 is within the delta")
+	require.Equal(t, "Value changed from 104 to 110 (+6, +5.8%)\nChanged by more than 5", m.Check().Details)
+	require.Equal(t, "Value changed from 110 to 98 (-12, -10.9%)\nChanged by more than 5\nChanged by more than 10%", m.Check().Details)
+}
+
+func TestValueNotFound(t *testing.T) {
+	server := valueServer(t, "sold out", "80")
+	m := valueMonitor(t, server.URL, func(*Config) {})
+
+	change := m.Check()
+	require.Equal(t, EventError, change.Event)
+	require.Equal(t, `selector ".price": no number in "$sold out"`, change.Error)
+
+	// The first value found is the baseline
+	require.False(t, m.Check().HasChanged)
+}
+
+func TestValueRequiresExtractor(t *testing.T) {
+	config := DefaultConfig("https://example.com")
+	config.Method = MethodValue
+	_, err := NewManager().AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrNoExtractor)
+}
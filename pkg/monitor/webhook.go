@@ -0,0 +1,207 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+)
+
+// WebhookScheme is the scheme of the URLs of webhook monitors, which receive
+// their content instead of fetching it, e.g. webhook://deploys
+const WebhookScheme = "webhook"
+
+// webhookBufferSize bounds the payloads received by a webhook monitor that
+// wait for its run loop
+const webhookBufferSize = 64
+
+var (
+	// ErrWebhook is returned for settings a webhook monitor can't honor
+	ErrWebhook = errors.New("webhook monitors receive their content and don't support methods other than hash, the browser, representations, variants, pagination, append-only fetching, change confirmation, schedules, one-time checks, expectations, request bodies, methods other than GET, login flows, bearer tokens or OAuth2")
+	// ErrWebhookOptions is returned when the webhook settings of Config are
+	// set for a monitor that isn't a webhook monitor
+	ErrWebhookOptions = errors.New("webhook match, fields and secret require a webhook:// URL")
+	// ErrNotWebhook is returned by Monitor.Receive for monitors that fetch
+	// their URL, and by Monitor.Trigger for webhook monitors, which can't
+	// fetch theirs
+	ErrNotWebhook = errors.New("only webhook monitors receive payloads, and they can't be checked")
+	// ErrWebhookBusy is returned by Monitor.Receive when too many payloads
+	// wait for the monitor
+	ErrWebhookBusy = errors.New("too many payloads waiting for the monitor")
+)
+
+// webhookName is the pattern of the names of webhook monitors, which are
+// part of the path of their endpoint
+var webhookName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// payload is the content received by a webhook monitor
+type payload struct {
+	content     []byte
+	contentType string
+	at          time.Time
+}
+
+// IsWebhookURL reports whether rawURL is the URL of a webhook monitor
+func IsWebhookURL(rawURL string) bool {
+	scheme, _, ok := strings.Cut(rawURL, "://")
+	return ok && strings.EqualFold(scheme, WebhookScheme)
+}
+
+// WebhookURL returns the URL of the webhook monitor with the given name
+func WebhookURL(name string) string {
+	return WebhookScheme + "://" + name
+}
+
+// ParseWebhookURL returns the name of the webhook monitor of a URL such as
+// webhook://deploys. Names are made of letters, digits, dots, dashes and
+// underscores.
+func ParseWebhookURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, WebhookScheme) {
+		return "", fmt.Errorf("invalid webhook URL '%s': must be webhook://<name>", rawURL)
+	}
+	if !webhookName.MatchString(u.Host) || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.User != nil {
+		return "", fmt.Errorf("invalid webhook URL '%s': the name must be made of letters, digits, dots, dashes and underscores", rawURL)
+	}
+	return u.Host, nil
+}
+
+// validateWebhook checks that the settings of a webhook monitor can be
+// applied, and that monitors that fetch their URL have no webhook settings
+func validateWebhook(config *Config) error {
+	if !IsWebhookURL(config.URL) {
+		if len(config.WebhookMatch)+len(config.WebhookFields) > 0 || config.WebhookSecret != "" {
+			return ErrWebhookOptions
+		}
+		return nil
+	}
+	if _, err := ParseWebhookURL(config.URL); err != nil {
+		return err
+	}
+	if config.Method != MethodHash || config.Fetcher == FetcherBrowser || len(config.Representations) > 0 || len(config.Variants) > 0 ||
+		config.Pagination != nil || config.AppendOnly || config.ConfirmDelay != 0 || config.Schedule != nil || !config.At.IsZero() || config.Expect != nil ||
+		config.requestMethod() != http.MethodGet || config.Login != nil || config.BearerToken != "" || config.OAuth2 != nil {
+		return ErrWebhook
+	}
+	return nil
+}
+
+// Receive passes a payload received for a webhook monitor, see
+// IsWebhookURL, to its run loop, which reports it as a change unless it
+// doesn't meet Config.WebhookMatch. Payloads are reported in the order
+// they are received.
+func (m *Monitor) Receive(content []byte, contentType string) error {
+	if !IsWebhookURL(m.config.URL) {
+		return ErrNotWebhook
+	}
+	if m.ctx.Err() != nil {
+		return ErrMonitorStopped
+	}
+	if m.IsPaused() {
+		return ErrMonitorPaused
+	}
+
+	select {
+	case m.received <- payload{content: content, contentType: contentType, at: m.clock.Now()}:
+		return nil
+	default:
+		return ErrWebhookBusy
+	}
+}
+
+// runWebhook is the run loop of webhook monitors. They have nothing to
+// check, so they wait for payloads and don't stall between them.
+func (m *Monitor) runWebhook() {
+	m.setCycle(time.Time{})
+	m.settle()
+	m.wait(nil)
+}
+
+// receive reports a payload received by a webhook monitor as a change,
+// unless it doesn't meet Config.WebhookMatch
+func (m *Monitor) receive(p payload) {
+	if m.IsPaused() {
+		return
+	}
+
+	m.mu.Lock()
+	m.checkCount++
+	m.lastCheck = p.at
+	m.mu.Unlock()
+
+	change := Change{
+		URL:         m.config.URL,
+		Timestamp:   p.at,
+		ContentType: p.contentType,
+		Baggage:     m.config.Baggage,
+	}
+	for _, condition := range m.config.WebhookMatch {
+		if met, _ := condition.Met(p.content); !met {
+			m.checked(change)
+			return
+		}
+	}
+
+	details := describePayload(p.content, p.contentType, m.config.WebhookFields)
+	if err := m.archive(p.content, p.contentType); err != nil {
+		details += "\nSnapshot not archived: " + err.Error()
+	}
+	change.HasChanged = true
+	change.Event = EventChange
+	change.Silenced = m.inWindow(schedule.ModeSilence)
+	change.Details = truncateDetails(details, m.config.MaxDetailsLines, m.config.MaxDetailsBytes)
+	m.checked(change)
+
+	m.mu.Lock()
+	m.changeCount++
+	m.mu.Unlock()
+	m.changes <- change
+
+	if m.config.Until != nil {
+		if met, details := m.config.Until.Met(p.content); met {
+			m.complete(EventConditionMet, details)
+		}
+	}
+}
+
+// describePayload returns the details of a change reporting a payload: the
+// values of fields, dotted JSON paths as in JSONCondition, if the payload is
+// JSON and has any of them, or else the payload itself, indented if it is
+// JSON
+func describePayload(content []byte, contentType string, fields []string) string {
+	if !utf8.Valid(content) {
+		if contentType == "" {
+			contentType = "unknown type"
+		}
+		return fmt.Sprintf("Received %d bytes of %s", len(content), contentType)
+	}
+
+	var doc any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return strings.TrimSpace(string(content))
+	}
+
+	var lines []string
+	for _, path := range fields {
+		if value, ok := lookupJSON(doc, path); ok {
+			lines = append(lines, path+": "+jsonValue(value))
+		}
+	}
+	if len(lines) > 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(content), "", "  "); err != nil {
+		return strings.TrimSpace(string(content))
+	}
+	return indented.String()
+}
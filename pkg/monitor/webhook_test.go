@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookMonitor(t *testing.T) {
+	config := DefaultConfig(WebhookURL("deploys"))
+	config.WebhookMatch = []Condition{NewJSONCondition("status", "failed", true)}
+	config.WebhookFields = []string{"service", "version"}
+	config.Until = NewJSONCondition("status", "retired", false)
+	m := NewMonitorWithConfig(config)
+	defer m.Stop()
+
+	// Payloads received before the start wait for it
+	require.NoError(t, m.Receive([]byte(`{"service":"api","version":"1.2","status":"ok"}`), "application/json"))
+	require.NoError(t, m.Receive([]byte(`{"service":"api","version":"1.3","status":"failed"}`), "application/json"))
+	require.NoError(t, m.Receive([]byte(`{"service":"web","status":"ok"}`), "application/json"))
+	changes := m.Start()
+
+	change := <-changes
+	require.True(t, change.HasChanged)
+	require.Equal(t, EventChange, change.Event)
+	require.Equal(t, "service: api\nversion: 1.2", change.Details)
+	require.Equal(t, "application/json", change.ContentType)
+
+	// The failed deploy doesn't meet the condition
+	change = <-changes
+	require.Equal(t, "service: web", change.Details)
+	require.False(t, m.Stalled())
+	require.False(t, m.Starting())
+	require.ErrorIs(t, m.Trigger(), ErrNotWebhook)
+
+	m.Pause()
+	require.ErrorIs(t, m.Receive([]byte("ignored"), "text/plain"), ErrMonitorPaused)
+	m.Resume()
+	require.Equal(t, EventPaused, (<-changes).Event)
+	require.Equal(t, EventResumed, (<-changes).Event)
+
+	require.NoError(t, m.Receive([]byte(`{"service":"web","status":"retired"}`), "application/json"))
+	change = <-changes
+	require.Equal(t, "service: web", change.Details)
+	change = <-changes
+	require.Equal(t, EventConditionMet, change.Event)
+	require.Equal(t, "status is retired", change.Details)
+	_, _, checks := m.GetStatus()
+	require.EqualValues(t, 4, checks)
+}
+
+func TestParseWebhookURL(t *testing.T) {
+	name, err := ParseWebhookURL("webhook://github-releases")
+	require.NoError(t, err)
+	require.Equal(t, "github-releases", name)
+	require.True(t, IsWebhookURL("WEBHOOK://deploys"))
+	require.False(t, IsWebhookURL("https://example.com/webhook"))
+
+	for _, url := range []string{"webhook://", "webhook://a/b", "webhook://me@deploys", "webhook://deploys?x=1", "https://deploys"} {
+		_, err := ParseWebhookURL(url)
+		require.Error(t, err, url)
+	}
+}
+
+func TestWebhookValidation(t *testing.T) {
+	manager := NewManager()
+	defer manager.Stop()
+
+	config := DefaultConfig(WebhookURL("deploys"))
+	config.Method = MethodLength
+	_, err := manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrWebhook)
+
+	config = DefaultConfig("https://example.com")
+	config.WebhookFields = []string{"service"}
+	_, err = manager.AddMonitorWithConfig(config)
+	require.ErrorIs(t, err, ErrWebhookOptions)
+
+	_, err = manager.AddMonitorWithConfig(DefaultConfig("webhook://a/b"))
+	require.ErrorContains(t, err, "invalid webhook URL")
+}
+
+func TestDescribePayload(t *testing.T) {
+	require.Equal(t, "{\n  \"a\": [\n    1,\n    2\n  ]\n}", describePayload([]byte(` {"a":[1,2]}`), "application/json", nil))
+	require.Equal(t, "items.0.id: 7\nok: true", describePayload([]byte(`{"items":[{"id":7}],"ok":true}`), "", []string{"items.0.id", "missing", "ok"}))
+	require.Equal(t, "{}", describePayload([]byte(`{}`), "", []string{"missing"}))
+	require.Equal(t, "plain text", describePayload([]byte("plain text\n"), "text/plain", []string{"missing"}))
+	require.Equal(t, "Received 2 bytes of application/octet-stream", describePayload([]byte{0xff, 0xfe}, "application/octet-stream", nil))
+}
@@ -0,0 +1,150 @@
+package monitortest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// FakeClock is a monitor.Clock whose time only moves when Advance is called
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending After channel or ticker
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+}
+
+// NewFakeClock creates a fake clock set to start
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now implements monitor.Clock.Now
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After implements monitor.Clock.After
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.addWaiter(&fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// NewTicker implements monitor.Clock.NewTicker
+func (c *FakeClock) NewTicker(d time.Duration) monitor.Ticker {
+	if d <= 0 {
+		panic("monitortest: non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{deadline: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.addWaiter(w)
+
+	return &fakeTicker{clock: c, waiter: w}
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker whose
+// deadline has been reached along the way. Like time.Ticker, a ticker whose
+// previous tick has not been received drops the new tick.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	for len(c.waiters) > 0 && !c.waiters[0].deadline.After(target) {
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		c.now = w.deadline
+
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+			c.addWaiter(w)
+		}
+	}
+
+	c.now = target
+}
+
+// Waiters returns the number of pending timers and tickers
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until at least n timers or tickers are pending. It is
+// used to make sure a monitor is waiting on the clock before advancing it.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// addWaiter inserts a waiter ordered by deadline. Callers must hold c.mu.
+func (c *FakeClock) addWaiter(w *fakeWaiter) {
+	c.waiters = append(c.waiters, w)
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+	})
+	c.cond.Broadcast()
+}
+
+// removeWaiter removes a waiter. Callers must hold c.mu.
+func (c *FakeClock) removeWaiter(w *fakeWaiter) {
+	for i, existing := range c.waiters {
+		if existing == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// fakeTicker is a monitor.Ticker driven by a FakeClock
+type fakeTicker struct {
+	clock  *FakeClock
+	waiter *fakeWaiter
+}
+
+// C implements monitor.Ticker.C
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+// Stop implements monitor.Ticker.Stop
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.removeWaiter(t.waiter)
+}
@@ -0,0 +1,178 @@
+package monitortest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, transport *Transport, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	return transport.RoundTrip(req)
+}
+
+func TestTransportScript(t *testing.T) {
+	transport := NewTransport(OK("fallback")).
+		Script("https://example.com", OK("first"), Status(http.StatusTeapot, "second"))
+
+	resp, err := get(t, transport, "https://example.com")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	require.Equal(t, "first", string(body))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = get(t, transport, "https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	// Last response repeats once the script is exhausted
+	resp, err = get(t, transport, "https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	// Unscripted URLs use the fallback
+	resp, err = get(t, transport, "https://other.example.com")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	require.Equal(t, "fallback", string(body))
+
+	require.Equal(t, 3, transport.RequestCount("https://example.com"))
+	require.Len(t, transport.Requests(), 4)
+}
+
+func TestTransportErrors(t *testing.T) {
+	boom := errors.New("boom")
+	transport := NewTransport(Error(boom))
+
+	_, err := get(t, transport, "https://example.com")
+	require.ErrorIs(t, err, boom)
+
+	_, err = get(t, NewTransport(), "https://example.com")
+	require.Error(t, err)
+}
+
+func TestTransportLatency(t *testing.T) {
+	transport := NewTransport(Response{Body: "slow", Latency: time.Millisecond * 50})
+
+	start := time.Now()
+	_, err := get(t, transport, "https://example.com")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), time.Millisecond*50)
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	after := clock.After(time.Minute)
+	ticker := clock.NewTicker(time.Second * 30)
+	require.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(time.Second * 30)
+	require.Equal(t, start.Add(time.Second*30), <-ticker.C())
+
+	select {
+	case <-after:
+		t.Fatal("After fired early")
+	default:
+	}
+
+	clock.Advance(time.Second * 30)
+	require.Equal(t, start.Add(time.Minute), <-after)
+	require.Equal(t, start.Add(time.Minute), <-ticker.C())
+	require.Equal(t, start.Add(time.Minute), clock.Now())
+
+	ticker.Stop()
+	require.Zero(t, clock.Waiters())
+}
+
+func TestMonitorWithScriptedTransport(t *testing.T) {
+	url := "https://example.com"
+	transport := NewTransport().Script(url,
+		OK("version 1"),
+		OK("version 1"),
+		OK("version 2"),
+		Status(http.StatusInternalServerError, "down"),
+	)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	m := monitor.NewMonitorWithConfig(NewConfig(url, time.Minute, transport, clock))
+	changes := m.Start()
+	defer m.Stop()
+
+	// Wait for the monitor to schedule its ticker, then step through the script
+	clock.BlockUntil(1)
+
+	// Identical content: no change reported
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool { return transport.RequestCount(url) == 2 }, time.Second, time.Millisecond)
+
+	// Changed content
+	clock.Advance(time.Minute)
+	change := <-changes
+	require.True(t, change.HasChanged)
+	require.Equal(t, clock.Now(), change.Timestamp)
+
+	// Server error
+	clock.Advance(time.Minute)
+	change = <-changes
+	require.False(t, change.HasChanged)
+	require.Contains(t, change.Error, "500")
+}
+
+func TestMonitorWithCronSchedule(t *testing.T) {
+	url := "https://example.com"
+	transport := NewTransport(OK("content"))
+	// 2024-01-12 is a Friday
+	clock := NewFakeClock(time.Date(2024, 1, 12, 17, 45, 0, 0, time.UTC))
+
+	config := NewConfig(url, time.Minute, transport, clock)
+	config.Schedule = schedule.MustParseCron("*/10 9-17 * * mon-fri")
+	m := monitor.NewMonitorWithConfig(config)
+	m.Start()
+	defer m.Stop()
+
+	// Nothing is checked until the first scheduled time
+	clock.BlockUntil(1)
+	require.Zero(t, transport.RequestCount(url))
+	require.Equal(t, time.Date(2024, 1, 12, 17, 50, 0, 0, time.UTC), m.NextCheck())
+
+	clock.Advance(time.Minute * 5)
+	require.Eventually(t, func() bool { return transport.RequestCount(url) == 1 }, time.Second, time.Millisecond)
+
+	// After business hours the next check is on Monday morning
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	next := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	require.Equal(t, next, m.NextCheck())
+
+	clock.Advance(next.Sub(clock.Now()) - time.Minute)
+	require.Equal(t, 1, transport.RequestCount(url))
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool { return transport.RequestCount(url) == 2 }, time.Second, time.Millisecond)
+}
+
+func TestMonitorWithJitter(t *testing.T) {
+	url := "https://example.com"
+	transport := NewTransport(OK("content"))
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	config := NewConfig(url, time.Minute, transport, clock)
+	config.Jitter = time.Second * 30
+	m := monitor.NewMonitorWithConfig(config)
+	m.Start()
+	defer m.Stop()
+
+	// The ticker and the jitter delay of the first check
+	clock.BlockUntil(2)
+	require.Zero(t, transport.RequestCount(url))
+
+	clock.Advance(time.Second * 30)
+	require.Eventually(t, func() bool { return transport.RequestCount(url) == 1 }, time.Second, time.Millisecond)
+}
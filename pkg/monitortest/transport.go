@@ -0,0 +1,189 @@
+// Package monitortest provides a scripted HTTP transport and a fake clock for
+// testing code that embeds hawkeye monitors, without touching the network or
+// waiting on real time.
+package monitortest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// Response is a scripted response returned by a Transport
+type Response struct {
+	// StatusCode is the HTTP status code, defaulting to 200
+	StatusCode int
+	// Body is the response body
+	Body string
+	// Header holds response headers
+	Header http.Header
+	// Latency delays the response by the given duration
+	Latency time.Duration
+	// Err makes the request fail with the given error instead of responding
+	Err error
+}
+
+// OK returns a 200 response with the given body
+func OK(body string) Response {
+	return Response{StatusCode: http.StatusOK, Body: body}
+}
+
+// Status returns a response with the given status code and body
+func Status(code int, body string) Response {
+	return Response{StatusCode: code, Body: body}
+}
+
+// Error returns a response that fails the request with err
+func Error(err error) Response {
+	return Response{Err: err}
+}
+
+// Transport is an http.RoundTripper that replays scripted responses. Each
+// URL has its own sequence of responses; once a sequence is exhausted its
+// last response is repeated.
+type Transport struct {
+	mu       sync.Mutex
+	scripts  map[string][]Response
+	fallback []Response
+	requests []*http.Request
+}
+
+// NewTransport creates a transport that serves responses, in order, for any
+// URL that has no script of its own
+func NewTransport(responses ...Response) *Transport {
+	return &Transport{
+		scripts:  make(map[string][]Response),
+		fallback: responses,
+	}
+}
+
+// Script sets the sequence of responses served for url
+func (t *Transport) Script(url string, responses ...Response) *Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scripts[url] = responses
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, err := t.next(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Latency > 0 {
+		timer := time.NewTimer(response.Latency)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+
+	if response.Err != nil {
+		return nil, response.Err
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next records the request and pops the next scripted response for it
+func (t *Transport) next(req *http.Request) (Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.requests = append(t.requests, req)
+
+	url := req.URL.String()
+	script, ok := t.scripts[url]
+	if !ok {
+		script = t.fallback
+	}
+
+	if len(script) == 0 {
+		return Response{}, fmt.Errorf("monitortest: no scripted response for '%s'", url)
+	}
+
+	response := script[0]
+	if len(script) > 1 {
+		script = script[1:]
+	}
+
+	if ok {
+		t.scripts[url] = script
+	} else {
+		t.fallback = script
+	}
+
+	return response, nil
+}
+
+// Requests returns all requests received so far
+func (t *Transport) Requests() []*http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests := make([]*http.Request, len(t.requests))
+	copy(requests, t.requests)
+	return requests
+}
+
+// RequestCount returns the number of requests received for url
+func (t *Transport) RequestCount(url string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := 0
+	for _, req := range t.requests {
+		if req.URL.String() == url {
+			count++
+		}
+	}
+	return count
+}
+
+// NewConfig returns a monitor configuration for url that fetches through
+// transport and schedules on clock, with retries disabled so every tick
+// consumes exactly one scripted response
+func NewConfig(url string, interval time.Duration, transport *Transport, clock *FakeClock) *monitor.Config {
+	config := monitor.DefaultConfig(url)
+	config.Interval = interval
+	config.RetryCount = 0
+
+	// Avoid storing typed nil pointers in the interface fields
+	if transport != nil {
+		config.Transport = transport
+	}
+	if clock != nil {
+		config.Clock = clock
+	}
+
+	return config
+}
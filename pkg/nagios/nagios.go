@@ -0,0 +1,122 @@
+// Package nagios formats check results following the Nagios plugin
+// guidelines, so hawkeye can run as a plugin of Nagios, Icinga or NRPE.
+package nagios
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State is the result of a check. Its value is the exit code of the plugin.
+type State int
+
+// States of a check
+const (
+	OK State = iota
+	Warning
+	Critical
+	Unknown
+)
+
+// String returns the name of the state as printed in plugin output
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Worst returns the more severe of two states: Critical, then Warning, then
+// Unknown, then OK
+func Worst(a, b State) State {
+	rank := func(s State) int {
+		if s == Unknown {
+			return 1
+		}
+		return int(s) * 2
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// Perf is a performance data value, such as the response time, that the
+// monitoring system records and graphs. Warn, Crit, Min and Max are left out
+// when zero.
+type Perf struct {
+	Label string
+	Value float64
+	// Unit is one of "", "s", "ms", "us", "%", "B", "KB", "MB", "TB" or "c"
+	Unit string
+	Warn float64
+	Crit float64
+	Min  float64
+	Max  float64
+}
+
+// Seconds returns the performance data of a duration in seconds, to the
+// microsecond
+func Seconds(label string, d, warn, crit time.Duration) Perf {
+	return Perf{Label: label, Value: d.Round(time.Microsecond).Seconds(), Unit: "s", Warn: warn.Seconds(), Crit: crit.Seconds()}
+}
+
+// String formats the value as 'label'=value[unit];[warn];[crit];[min];[max],
+// with trailing empty fields left out
+func (p Perf) String() string {
+	label := p.Label
+	if strings.ContainsAny(label, " '=") {
+		label = "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+
+	fields := []string{formatNumber(p.Value) + p.Unit}
+	for _, value := range []float64{p.Warn, p.Crit, p.Min, p.Max} {
+		if value == 0 {
+			fields = append(fields, "")
+		} else {
+			fields = append(fields, formatNumber(value))
+		}
+	}
+	return label + "=" + strings.TrimRight(strings.Join(fields, ";"), ";")
+}
+
+// formatNumber formats a number without an exponent, which plugins can't use
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Result is the output of a plugin
+type Result struct {
+	// Service names the check, e.g. "HAWKEYE"
+	Service string
+	State   State
+	// Summary is the single line shown in the status overview
+	Summary  string
+	Perfdata []Perf
+}
+
+// String formats the result as a line of plugin output, such as
+// "HAWKEYE OK - https://example.com: 200 in 0.12s | time=0.12s;1;5"
+func (r Result) String() string {
+	// A pipe would start the performance data
+	summary := strings.ReplaceAll(strings.TrimSpace(r.Summary), "|", "/")
+	summary = strings.Join(strings.Fields(summary), " ")
+
+	line := fmt.Sprintf("%s %s - %s", r.Service, r.State, summary)
+	if len(r.Perfdata) > 0 {
+		perfdata := make([]string, len(r.Perfdata))
+		for i, perf := range r.Perfdata {
+			perfdata[i] = perf.String()
+		}
+		line += " | " + strings.Join(perfdata, " ")
+	}
+	return line
+}
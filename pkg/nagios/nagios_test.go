@@ -0,0 +1,40 @@
+package nagios
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorst(t *testing.T) {
+	require.Equal(t, Warning, Worst(OK, Warning))
+	require.Equal(t, Critical, Worst(Critical, Warning))
+	require.Equal(t, Warning, Worst(Unknown, Warning))
+	require.Equal(t, Unknown, Worst(OK, Unknown))
+	require.Equal(t, Critical, Worst(Unknown, Critical))
+	require.Equal(t, OK, Worst(OK, OK))
+}
+
+func TestPerf(t *testing.T) {
+	require.Equal(t, "time=0.125s;1;5", Seconds("time", 125*time.Millisecond, time.Second, 5*time.Second).String())
+	require.Equal(t, "time=2s", Seconds("time", 2*time.Second, 0, 0).String())
+	require.Equal(t, "size=1048576B", Perf{Label: "size", Value: 1 << 20, Unit: "B"}.String())
+	require.Equal(t, "'pct used'=50%;;;;100", Perf{Label: "pct used", Value: 50, Unit: "%", Max: 100}.String())
+	require.Equal(t, "'it''s'=1", Perf{Label: "it's", Value: 1}.String())
+	require.Equal(t, "tiny=0.0000001s", Perf{Label: "tiny", Value: 1e-7, Unit: "s"}.String())
+}
+
+func TestResult(t *testing.T) {
+	result := Result{
+		Service:  "HAWKEYE",
+		State:    Critical,
+		Summary:  "https://example.com: unexpected status code: 503 | retrying\n",
+		Perfdata: []Perf{Seconds("time", 1500*time.Millisecond, time.Second, 0)},
+	}
+	require.Equal(t, "HAWKEYE CRITICAL - https://example.com: unexpected status code: 503 / retrying | time=1.5s;1", result.String())
+
+	result = Result{Service: "HAWKEYE", State: OK, Summary: "fine"}
+	require.Equal(t, "HAWKEYE OK - fine", result.String())
+	require.Equal(t, 3, int(Unknown))
+}
@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// ChangeSchema is the Avro schema of changes published with FormatAvro
+const ChangeSchema = `{
+  "type": "record",
+  "name": "Change",
+  "namespace": "com.github.nemuizzz.hawkeye",
+  "fields": [
+    {"name": "url", "type": "string"},
+    {"name": "event_type", "type": "string"},
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "has_changed", "type": "boolean"},
+    {"name": "status_code", "type": "int"},
+    {"name": "content_type", "type": "string"},
+    {"name": "error", "type": "string"},
+    {"name": "details", "type": "string"},
+    {"name": "summary", "type": "string"},
+    {"name": "latency_ms", "type": "double"},
+    {"name": "old_hash", "type": "string"},
+    {"name": "new_hash", "type": "string"},
+    {"name": "old_length", "type": "long"},
+    {"name": "new_length", "type": "long"},
+    {"name": "silenced", "type": "boolean"},
+    {"name": "baggage", "type": {"type": "map", "values": "string"}},
+    {"name": "hunks", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "DiffHunk",
+      "fields": [
+        {"name": "old_start", "type": "int"},
+        {"name": "old_lines", "type": "int"},
+        {"name": "new_start", "type": "int"},
+        {"name": "new_lines", "type": "int"},
+        {"name": "lines", "type": {"type": "array", "items": {
+          "type": "record",
+          "name": "DiffLine",
+          "fields": [
+            {"name": "type", "type": "string"},
+            {"name": "text", "type": "string"}
+          ]
+        }}}
+      ]
+    }}}
+  ]
+}`
+
+// encodeAvro encodes a change in the Avro binary encoding of ChangeSchema
+func encodeAvro(change monitor.Change) []byte {
+	var e avroEncoder
+	e.string(change.URL)
+	e.string(string(change.Event))
+	e.long(change.Timestamp.UnixMilli())
+	e.boolean(change.HasChanged)
+	e.long(int64(change.StatusCode))
+	e.string(change.ContentType)
+	e.string(change.Error)
+	e.string(change.Details)
+	e.string(change.Summary)
+	e.double(float64(change.Latency.Microseconds()) / 1000)
+	e.string(change.OldHash)
+	e.string(change.NewHash)
+	e.long(change.OldLength)
+	e.long(change.NewLength)
+	e.boolean(change.Silenced)
+
+	// Maps and arrays are written as a block of items and an empty block
+	e.long(int64(len(change.Baggage)))
+	keys := make([]string, 0, len(change.Baggage))
+	for key := range change.Baggage {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		e.string(key)
+		e.string(change.Baggage[key])
+	}
+	e.end(len(keys))
+
+	e.long(int64(len(change.Hunks)))
+	for _, hunk := range change.Hunks {
+		e.long(int64(hunk.OldStart))
+		e.long(int64(hunk.OldLines))
+		e.long(int64(hunk.NewStart))
+		e.long(int64(hunk.NewLines))
+		e.long(int64(len(hunk.Lines)))
+		for _, line := range hunk.Lines {
+			e.string(line.Type)
+			e.string(line.Text)
+		}
+		e.end(len(hunk.Lines))
+	}
+	e.end(len(change.Hunks))
+
+	return e.buf
+}
+
+// avroEncoder writes values in the Avro binary encoding
+type avroEncoder struct {
+	buf []byte
+}
+
+// long writes an int or long as a zig-zag varint
+func (e *avroEncoder) long(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *avroEncoder) boolean(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *avroEncoder) double(v float64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *avroEncoder) string(v string) {
+	e.long(int64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// end ends a map or array of n items. An empty one is a single empty block.
+func (e *avroEncoder) end(n int) {
+	if n > 0 {
+		e.long(0)
+	}
+}
@@ -0,0 +1,491 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+)
+
+// awsCredentials are the access keys requests to AWS are signed with
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expires is zero for keys that don't expire
+	Expires time.Time
+}
+
+// awsCredentialChain finds credentials like the AWS SDKs do, trying in
+// order:
+//
+//   - the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//     environment variables
+//   - the web identity token of AWS_WEB_IDENTITY_TOKEN_FILE exchanged for
+//     AWS_ROLE_ARN, e.g. on EKS
+//   - the AWS_PROFILE profile of the shared credentials file
+//   - the ECS and EKS Pod Identity container endpoint
+//   - the role of the EC2 instance
+//
+// Temporary credentials are kept until shortly before they expire.
+type awsCredentialChain struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	cached *awsCredentials
+}
+
+// newAWSCredentialChain creates a credential chain
+func newAWSCredentialChain() *awsCredentialChain {
+	return &awsCredentialChain{
+		client: customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10}),
+	}
+}
+
+// retrieve returns valid credentials
+func (c *awsCredentialChain) retrieve(ctx context.Context) (*awsCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached != nil && (c.cached.Expires.IsZero() || time.Until(c.cached.Expires) > time.Minute*5) {
+		return c.cached, nil
+	}
+
+	creds, err := c.find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AWS credentials: %w", err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("AWS credentials: access key ID or secret access key missing")
+	}
+	c.cached = creds
+	return creds, nil
+}
+
+// reset forgets the cached credentials, e.g. after they were rejected
+func (c *awsCredentialChain) reset() {
+	c.mu.Lock()
+	c.cached = nil
+	c.mu.Unlock()
+}
+
+// find goes through the sources of credentials
+func (c *awsCredentialChain) find(ctx context.Context) (*awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" {
+		return c.webIdentity(ctx, tokenFile)
+	}
+	if creds, err := sharedAWSCredentials(); creds != nil || err != nil {
+		return creds, err
+	}
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return c.container(ctx)
+	}
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, errors.New("none found in the environment or the shared credentials file")
+	}
+	creds, err := c.instance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("none found in the environment, the shared credentials file or the instance metadata: %w", err)
+	}
+	return creds, nil
+}
+
+// sharedAWSCredentials reads the profile of the shared credentials file. It
+// returns no credentials if the file or the default profile doesn't exist.
+func sharedAWSCredentials() (*awsCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	explicit := profile != ""
+	if !explicit {
+		profile = "default"
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var creds *awsCredentials
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if creds != nil {
+				break
+			}
+			if strings.TrimSpace(line[1:len(line)-1]) == profile {
+				creds = &awsCredentials{}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || creds == nil {
+			continue
+		}
+		switch value = strings.TrimSpace(value); strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if creds == nil && explicit {
+		return nil, fmt.Errorf("profile '%s' not found in %s", profile, path)
+	}
+	return creds, nil
+}
+
+// webIdentity exchanges the web identity token in tokenFile for temporary
+// credentials of AWS_ROLE_ARN
+func (c *awsCredentialChain) webIdentity(ctx context.Context, tokenFile string) (*awsCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = fmt.Sprintf("hawkeye-%d", time.Now().Unix())
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {os.Getenv("AWS_ROLE_ARN")},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+
+	// The request is authenticated by the token rather than signed
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, awsEndpoint("sts", region), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := awsQuery(c.client, req, &result); err != nil {
+		return nil, fmt.Errorf("assuming role with web identity: %w", err)
+	}
+	return &awsCredentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		Expires:         result.Credentials.Expiration,
+	}, nil
+}
+
+// awsRoleCredentials are the credentials returned by the container and
+// instance metadata endpoints
+type awsRoleCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// awsContainerHosts are the addresses of the ECS and EKS Pod Identity
+// container credentials endpoints
+var awsContainerHosts = map[string]bool{
+	"169.254.170.2":  true,
+	"169.254.170.23": true,
+	"fd00:ec2::23":   true,
+}
+
+// isLoopback reports whether host is localhost or a loopback address
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.IsLoopback()
+}
+
+// allowedContainerEndpoint reports whether the container credentials may be
+// requested from endpoint. Like the AWS SDKs, only loopback and the
+// container credentials endpoints are trusted with the authorization token.
+func allowedContainerEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if isLoopback(host) {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && awsContainerHosts[addr.Unmap().String()]
+}
+
+// container gets the credentials of an ECS task or EKS pod from the
+// container credentials endpoint
+func (c *awsCredentialChain) container(ctx context.Context) (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = "http://169.254.170.2" + relative
+	} else if !allowedContainerEndpoint(endpoint) {
+		return nil, fmt.Errorf("container credentials: AWS_CONTAINER_CREDENTIALS_FULL_URI '%s' is neither loopback nor a container credentials endpoint", endpoint)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	var role awsRoleCredentials
+	if err := getJSON(c.client, req, &role); err != nil {
+		return nil, fmt.Errorf("container credentials: %w", err)
+	}
+	return &awsCredentials{AccessKeyID: role.AccessKeyID, SecretAccessKey: role.SecretAccessKey, SessionToken: role.Token, Expires: role.Expiration}, nil
+}
+
+// instance gets the credentials of the role of the EC2 instance from the
+// instance metadata service, with a session token as IMDSv2 requires
+func (c *awsCredentialChain) instance(ctx context.Context) (*awsCredentials, error) {
+	endpoint := strings.TrimSuffix(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), "/")
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	// Off EC2 nothing answers; don't wait long for it
+	ctx, cancel := context.WithTimeout(ctx, time.Second*2)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := getText(c.client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	path := endpoint + "/latest/meta-data/iam/security-credentials/"
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, path, nil); err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	roles, err := getText(c.client, req)
+	if err != nil {
+		return nil, err
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+	if role == "" {
+		return nil, errors.New("the instance has no IAM role")
+	}
+
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, path+role, nil); err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	var creds awsRoleCredentials
+	if err := getJSON(c.client, req, &creds); err != nil {
+		return nil, err
+	}
+	return &awsCredentials{AccessKeyID: creds.AccessKeyID, SecretAccessKey: creds.SecretAccessKey, SessionToken: creds.Token, Expires: creds.Expiration}, nil
+}
+
+// awsEndpoint returns the URL of a service in a region. Like the AWS SDKs,
+// AWS_ENDPOINT_URL_<SERVICE> or AWS_ENDPOINT_URL replace it, e.g. for
+// LocalStack.
+func awsEndpoint(service, region string) string {
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL_" + strings.ToUpper(service)); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		return endpoint
+	}
+	domain := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("https://%s.%s.%s/", service, region, domain)
+}
+
+// awsQuery sends a request to an AWS query API and decodes the XML
+// response into result. Errors of the API are returned with their code.
+func awsQuery(client *http.Client, req *http.Request, result any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		if xml.Unmarshal(body, &failure) == nil && failure.Code != "" {
+			return fmt.Errorf("%s: %s", failure.Code, failure.Message)
+		}
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return xml.Unmarshal(body, result)
+}
+
+// signAWS signs a request with AWS Signature Version 4. body is the
+// payload of the request.
+func signAWS(req *http.Request, body []byte, creds *awsCredentials, service, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Host, the content type and the AWS headers are signed
+	headers := map[string]string{"host": req.URL.Host}
+	if req.Host != "" {
+		headers["host"] = req.Host
+	}
+	for key, values := range req.Header {
+		key = strings.ToLower(key)
+		if key == "content-type" || strings.HasPrefix(key, "x-amz-") {
+			headers[key] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := req.URL.Query()
+	for key := range query {
+		sort.Strings(query[key])
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data keyed by key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// getJSON sends a request and decodes its JSON response into result
+func getJSON(client *http.Client, req *http.Request, result any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(result)
+}
+
+// getText sends a request and returns its response as text
+func getText(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return string(body), err
+}
@@ -0,0 +1,309 @@
+package notify
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAWS(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	creds := &awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWS(req, nil, creds, "service", "us-east-1", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	require.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	require.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+// isolateAWS clears the AWS settings of the environment, so tests don't
+// pick up real credentials or wait for the instance metadata service
+func isolateAWS(t *testing.T) {
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_REGION",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_ROLE_SESSION_NAME", "AWS_ENDPOINT_URL",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestSNSNotifier(t *testing.T) {
+	isolateAWS(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+
+	requests := make(chan url.Values, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Contains(t, r.Header.Get("Authorization"), "Credential=AKIDEXAMPLE/")
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/sns/aws4_request")
+		require.Equal(t, "session", r.Header.Get("X-Amz-Security-Token"))
+		if strings.HasSuffix(r.PostForm.Get("TopicArn"), ":denied") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AuthorizationError</Code><Message>User is not authorized</Message></Error></ErrorResponse>`))
+			return
+		}
+		requests <- r.PostForm
+		w.Write([]byte(`<PublishResponse><PublishResult><MessageId>1</MessageId></PublishResult></PublishResponse>`))
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_SNS", server.URL)
+
+	notifier := NewSNSNotifier("sns", "arn:aws:sns:eu-west-1:123456789012:changes")
+	require.Equal(t, "sns", notifier.Name())
+	change := monitor.Change{
+		URL:        "https://example.com",
+		Event:      monitor.EventChange,
+		HasChanged: true,
+		Summary:    "Price changed\nto €12",
+		Baggage:    map[string]string{"tenant": "acme"},
+		Hunks:      []monitor.DiffHunk{{OldStart: 1}},
+	}
+	require.NoError(t, notifier.Notify(context.Background(), change))
+
+	form := <-requests
+	require.Equal(t, "Publish", form.Get("Action"))
+	require.Equal(t, "arn:aws:sns:eu-west-1:123456789012:changes", form.Get("TopicArn"))
+	require.Equal(t, "Change detected on https://example.com: Price changed to ?12", form.Get("Subject"))
+	var received monitor.Change
+	require.NoError(t, json.Unmarshal([]byte(form.Get("Message")), &received))
+	require.Equal(t, "https://example.com", received.URL)
+	require.Empty(t, received.Hunks)
+	require.Equal(t, "event_type", form.Get("MessageAttributes.entry.1.Name"))
+	require.Equal(t, "change", form.Get("MessageAttributes.entry.1.Value.StringValue"))
+	require.Equal(t, "https://example.com", form.Get("MessageAttributes.entry.2.Value.StringValue"))
+	require.Equal(t, "tenant=acme", form.Get("MessageAttributes.entry.3.Value.StringValue"))
+	require.Empty(t, form.Get("MessageGroupId"))
+
+	// FIFO topics receive the changes of a URL in one message group
+	notifier = NewSNSNotifier("sns", "arn:aws:sns:eu-west-1:123456789012:changes.fifo")
+	require.NoError(t, notifier.Notify(context.Background(), change))
+	form = <-requests
+	require.Len(t, form.Get("MessageGroupId"), 64)
+	require.Len(t, form.Get("MessageDeduplicationId"), 64)
+
+	notifier = NewSNSNotifier("sns", "arn:aws:sns:eu-west-1:123456789012:denied")
+	err := notifier.Notify(context.Background(), change)
+	require.EqualError(t, err, "sns 'sns': AuthorizationError: User is not authorized")
+}
+
+func TestAWSCredentials(t *testing.T) {
+	isolateAWS(t)
+	ctx := context.Background()
+
+	_, err := newAWSCredentialChain().retrieve(ctx)
+	require.EqualError(t, err, "AWS credentials: none found in the environment or the shared credentials file")
+
+	// Shared credentials file
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	require.NoError(t, os.WriteFile(path, []byte("[default]\naws_access_key_id = AKID1\naws_secret_access_key = secret1\n\n[ci]\naws_access_key_id=AKID2\naws_secret_access_key=secret2\n"), 0600))
+	creds, err := newAWSCredentialChain().retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "AKID1", creds.AccessKeyID)
+	t.Setenv("AWS_PROFILE", "ci")
+	creds, err = newAWSCredentialChain().retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "secret2", creds.SecretAccessKey)
+	t.Setenv("AWS_PROFILE", "missing")
+	_, err = newAWSCredentialChain().retrieve(ctx)
+	require.ErrorContains(t, err, "profile 'missing' not found")
+	t.Setenv("AWS_PROFILE", "")
+	require.NoError(t, os.Remove(path))
+
+	// Container credentials
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/credentials":
+			require.Equal(t, "pod-token", r.Header.Get("Authorization"))
+			json.NewEncoder(w).Encode(map[string]any{"AccessKeyId": "AKID3", "SecretAccessKey": "secret3", "Token": "token3", "Expiration": expires})
+		case "/":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "AssumeRoleWithWebIdentity", r.PostForm.Get("Action"))
+			require.Equal(t, "arn:aws:iam::123456789012:role/hawkeye", r.PostForm.Get("RoleArn"))
+			require.Equal(t, "jwt", r.PostForm.Get("WebIdentityToken"))
+			w.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>AKID4</AccessKeyId><SecretAccessKey>secret4</SecretAccessKey><SessionToken>token4</SessionToken>
+<Expiration>` + expires.Format(time.RFC3339) + `</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/credentials")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "pod-token")
+	chain := newAWSCredentialChain()
+	creds, err = chain.retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, awsCredentials{AccessKeyID: "AKID3", SecretAccessKey: "secret3", SessionToken: "token3", Expires: expires}, *creds)
+	// Credentials are kept until they are about to expire
+	_, err = chain.retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), requests.Load())
+
+	// Web identity
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("jwt\n"), 0600))
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/hawkeye")
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	creds, err = newAWSCredentialChain().retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "token4", creds.SessionToken)
+	require.True(t, creds.Expires.Equal(expires))
+}
+
+func TestAWSContainerEndpoint(t *testing.T) {
+	for _, endpoint := range []string{
+		"http://127.0.0.1:8080/credentials",
+		"http://localhost/credentials",
+		"http://[::1]/credentials",
+		"http://169.254.170.2/v2/credentials",
+		"http://169.254.170.23/v1/credentials",
+		"http://[fd00:ec2::23]/v1/credentials",
+	} {
+		require.True(t, allowedContainerEndpoint(endpoint), endpoint)
+	}
+	for _, endpoint := range []string{
+		"http://attacker.example/credentials",
+		"https://attacker.example/credentials",
+		"http://169.254.169.254/credentials",
+		"http://10.0.0.1/credentials",
+	} {
+		require.False(t, allowedContainerEndpoint(endpoint), endpoint)
+	}
+
+	isolateAWS(t)
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "http://attacker.example/credentials")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "pod-token")
+	_, err := newAWSCredentialChain().retrieve(context.Background())
+	require.ErrorContains(t, err, "neither loopback nor a container credentials endpoint")
+}
+
+func TestPubSubNotifier(t *testing.T) {
+	messages := make(chan map[string]any, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("Authorization"))
+		if r.URL.Path == "/v1/projects/acme/topics/missing:publish" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"Topic not found","status":"NOT_FOUND"}}`))
+			return
+		}
+		require.Equal(t, "/v1/projects/acme/topics/changes:publish", r.URL.Path)
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Messages, 1)
+		messages <- body.Messages[0]
+		w.Write([]byte(`{"messageIds":["1"]}`))
+	}))
+	defer server.Close()
+	t.Setenv("PUBSUB_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	notifier := NewPubSubNotifier("pubsub", "projects/acme/topics/changes")
+	require.Equal(t, "pubsub", notifier.Name())
+	change := monitor.Change{URL: "https://example.com", Error: "timeout"}
+	require.NoError(t, notifier.Notify(context.Background(), change))
+
+	message := <-messages
+	data, err := base64.StdEncoding.DecodeString(message["data"].(string))
+	require.NoError(t, err)
+	var received monitor.Change
+	require.NoError(t, json.Unmarshal(data, &received))
+	require.Equal(t, "timeout", received.Error)
+	require.Equal(t, map[string]any{"content-type": "application/json", "event_type": "error", "url": "https://example.com"}, message["attributes"])
+
+	notifier = NewPubSubNotifier("pubsub", "projects/acme/topics/missing")
+	err = notifier.Notify(context.Background(), change)
+	require.EqualError(t, err, "pubsub 'pubsub': NOT_FOUND: Topic not found")
+}
+
+func TestGCPTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature))
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(claims, &decoded))
+		require.Equal(t, "hawkeye@acme.iam.gserviceaccount.com", decoded["iss"])
+		require.Equal(t, pubsubScope, decoded["scope"])
+		require.Equal(t, "http://"+r.Host+"/token", decoded["aud"])
+
+		w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	keyFile, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "hawkeye@acme.iam.gserviceaccount.com",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      server.URL + "/token",
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, keyFile, 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	tokens := newGCPTokenSource(pubsubScope)
+	for range 2 {
+		token, err := tokens.accessToken(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "ya29.token", token)
+	}
+	require.Equal(t, int64(1), requests.Load())
+
+	// Credentials are only sent to Google's token endpoints
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"authorized_user","refresh_token":"r","token_uri":"https://attacker.example/token"}`), 0600))
+	_, err = newGCPTokenSource(pubsubScope).accessToken(context.Background())
+	require.ErrorContains(t, err, "is not a Google token endpoint")
+	require.True(t, allowedGCPTokenURL(gcpTokenURL))
+	require.False(t, allowedGCPTokenURL("http://oauth2.googleapis.com/token"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"external_account"}`), 0600))
+	_, err = newGCPTokenSource(pubsubScope).accessToken(context.Background())
+	require.ErrorContains(t, err, "unsupported credentials type 'external_account'")
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// maxDigestLines bounds the changes listed in a digest; the rest are
+// counted
+const maxDigestLines = 50
+
+// Flusher is implemented by notifiers that hold changes back, such as a
+// Digest
+type Flusher interface {
+	// Flush sends the changes held back at once
+	Flush(ctx context.Context) error
+}
+
+// Digest batches the changes sent to a notifier: the first change starts a
+// window, and when it ends, every change of the window is sent in a single
+// change with EventDigest that lists them in its details.
+type Digest struct {
+	Notifier
+	window time.Duration
+
+	mu      sync.Mutex
+	changes []monitor.Change
+	timer   *time.Timer
+	since   time.Time
+}
+
+// NewDigest creates a digest that sends the changes of each window to
+// notifier in one summary
+func NewDigest(notifier Notifier, window time.Duration) *Digest {
+	return &Digest{Notifier: notifier, window: window}
+}
+
+// Notify implements Notifier.Notify. The change is held back until the
+// window ends.
+func (d *Digest) Notify(ctx context.Context, change monitor.Change) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.changes) == 0 {
+		d.since = time.Now()
+		d.timer = time.AfterFunc(d.window, func() {
+			// Nobody waits for a digest sent by the timer
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+			defer cancel()
+			d.Flush(ctx)
+		})
+	}
+	d.changes = append(d.changes, change)
+	return nil
+}
+
+// Flush implements Flusher. It sends the changes of the current window
+// without waiting for its end.
+func (d *Digest) Flush(ctx context.Context) error {
+	d.mu.Lock()
+	changes, since := d.changes, d.since
+	d.changes = nil
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.mu.Unlock()
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return d.Notifier.Notify(ctx, digestOf(changes, since))
+}
+
+// Unwrap returns the notifier digests are sent to
+func (d *Digest) Unwrap() Notifier {
+	return d.Notifier
+}
+
+// digestOf sums up changes in a single change. It has the URL of the
+// changes if they are all of one URL.
+func digestOf(changes []monitor.Change, since time.Time) monitor.Change {
+	urls := make(map[string]bool)
+	digest := monitor.Change{Event: monitor.EventDigest, Timestamp: time.Now(), URL: changes[0].URL}
+	var lines []string
+	for i, change := range changes {
+		urls[change.URL] = true
+		digest.HasChanged = digest.HasChanged || change.HasChanged
+		if i == maxDigestLines {
+			lines = append(lines, fmt.Sprintf("- ... and %d more", len(changes)-maxDigestLines))
+			continue
+		}
+		if i > maxDigestLines {
+			continue
+		}
+
+		summary, _ := message(change)
+		lines = append(lines, fmt.Sprintf("- %s %s", change.Timestamp.Format(time.TimeOnly), summary))
+	}
+	if len(urls) > 1 {
+		digest.URL = ""
+	}
+
+	header := countOf(len(changes), "change")
+	if len(urls) > 1 {
+		header += fmt.Sprintf(" of %d URLs", len(urls))
+	}
+	digest.Details = header + " since " + since.Format(time.TimeOnly) + "\n" + strings.Join(lines, "\n")
+	return digest
+}
+
+// countOf returns n with noun, made plural unless n is 1
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// unwrapper is implemented by notifiers that wrap another one
+type unwrapper interface {
+	Unwrap() Notifier
+}
+
+// Flush flushes every Flusher among notifiers and the notifiers they wrap,
+// and returns the errors of those that failed
+func Flush(ctx context.Context, notifiers ...Notifier) error {
+	var errs []error
+	for _, notifier := range notifiers {
+		for notifier != nil {
+			if flusher, ok := notifier.(Flusher); ok {
+				errs = append(errs, flusher.Flush(ctx))
+			}
+			u, ok := notifier.(unwrapper)
+			if !ok {
+				break
+			}
+			notifier = u.Unwrap()
+		}
+	}
+	return errors.Join(errs...)
+}
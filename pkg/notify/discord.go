@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+const (
+	// discordMessageLimit is the most characters Discord accepts in the
+	// content of a message
+	discordMessageLimit = 2000
+	// discordMaxFileSize bounds attached diffs below the smallest upload
+	// limit of Discord servers
+	discordMaxFileSize = 8 << 20
+)
+
+// DiscordNotifier posts changes to a Discord webhook. Diffs too long for a
+// message are attached as a file instead.
+type DiscordNotifier struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewDiscordNotifier creates a notifier that posts each change to the Discord
+// webhook url
+func NewDiscordNotifier(name, url string) *DiscordNotifier {
+	return &DiscordNotifier{
+		name:   name,
+		url:    url,
+		client: customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// Name implements Notifier.Name
+func (n *DiscordNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify
+func (n *DiscordNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	summary, diff := message(change)
+
+	content := summary
+	if diff != "" {
+		content = summary + "\n```diff\n" + diff + "\n```"
+	}
+	if textLength(content) <= discordMessageLimit {
+		body, err := json.Marshal(map[string]string{"content": content})
+		if err != nil {
+			return err
+		}
+		return post(ctx, n.client, n.name, n.url, "application/json", body)
+	}
+
+	if diff == "" {
+		body, err := json.Marshal(map[string]string{"content": truncateText(summary, discordMessageLimit)})
+		if err != nil {
+			return err
+		}
+		return post(ctx, n.client, n.name, n.url, "application/json", body)
+	}
+
+	note := "\nThe complete diff is attached."
+	content = truncateText(summary, discordMessageLimit-textLength(note)) + note
+	body, contentType, err := discordAttachment(content, "diff.txt", capFile(diff+"\n", discordMaxFileSize))
+	if err != nil {
+		return err
+	}
+	return post(ctx, n.client, n.name, n.url, contentType, body)
+}
+
+// discordAttachment builds a multipart message with content and one file
+func discordAttachment(content, filename string, file []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	payload, err := json.Marshal(map[string]any{
+		"content":     content,
+		"attachments": []map[string]any{{"id": 0, "filename": filename}},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := w.WriteField("payload_json", string(payload)); err != nil {
+		return nil, "", err
+	}
+
+	part, err := w.CreateFormFile("files[0]", filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(file); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// capFile cuts text to at most limit bytes at a line break, noting how much
+// was left out
+func capFile(text string, limit int) []byte {
+	if len(text) <= limit {
+		return []byte(text)
+	}
+
+	note := "[... %d more bytes left out ...]\n"
+	end := limit - len(note) - 20
+	cut := strings.LastIndexByte(text[:end], '\n') + 1
+	if cut == 0 {
+		// A single huge line is cut between characters
+		for cut = end; cut > 0 && !utf8.RuneStart(text[cut]); cut-- {
+		}
+	}
+	return []byte(text[:cut] + fmt.Sprintf(note, len(text)-cut))
+}
@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// DefaultExecTimeout is the time a command run by an ExecNotifier gets
+// before it is killed
+const DefaultExecTimeout = time.Second * 30
+
+// ErrThrottled is returned, wrapped, by ExecNotifier.Notify for a change
+// that came too soon after the last command run for its URL
+var ErrThrottled = errors.New("throttled")
+
+// ExecNotifier runs a command for each change. The command's words are
+// templates executed with the change, e.g. "notify-send {{.URL}}", and the
+// change is written to its standard input as JSON. The words are split
+// before templating and the command is run without a shell, so the content
+// of pages can't inject commands.
+type ExecNotifier struct {
+	name     string
+	args     []*template.Template
+	timeout  time.Duration
+	throttle time.Duration
+	output   io.Writer
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+}
+
+// NewExecNotifier creates a notifier that runs command for each change.
+// Words are separated by spaces and may be quoted with single or double
+// quotes.
+func NewExecNotifier(name, command string) (*ExecNotifier, error) {
+	words, err := splitCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("exec '%s': %w", name, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("exec '%s': empty command", name)
+	}
+
+	args := make([]*template.Template, len(words))
+	for i, word := range words {
+		if args[i], err = template.New(name).Option("missingkey=error").Parse(word); err != nil {
+			return nil, fmt.Errorf("exec '%s': %w", name, err)
+		}
+	}
+
+	return &ExecNotifier{
+		name:    name,
+		args:    args,
+		timeout: DefaultExecTimeout,
+		lastRun: make(map[string]time.Time),
+	}, nil
+}
+
+// WithTimeout kills commands that run longer than timeout
+func (n *ExecNotifier) WithTimeout(timeout time.Duration) *ExecNotifier {
+	n.timeout = timeout
+	return n
+}
+
+// WithThrottle runs the command at most once every interval for each URL.
+// Changes in between are dropped with an error wrapping ErrThrottled.
+func (n *ExecNotifier) WithThrottle(interval time.Duration) *ExecNotifier {
+	n.throttle = interval
+	return n
+}
+
+// WithOutput passes the standard output and error of commands to w. They
+// are discarded by default.
+func (n *ExecNotifier) WithOutput(w io.Writer) *ExecNotifier {
+	n.output = w
+	return n
+}
+
+// Name implements Notifier.Name
+func (n *ExecNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify. The complete diff is written to the
+// command with the change.
+func (n *ExecNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	if !n.allow(change.URL) {
+		return fmt.Errorf("exec '%s': %w, the command ran for %s less than %s ago", n.name, ErrThrottled, change.URL, n.throttle)
+	}
+
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		var b strings.Builder
+		if err := arg.Execute(&b, change); err != nil {
+			return fmt.Errorf("exec '%s': %w", n.name, err)
+		}
+		args[i] = b.String()
+	}
+
+	stdin, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	if n.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = n.output
+	cmd.Stderr = n.output
+	// Don't wait for children of the command that keep its output open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("exec '%s': %w", n.name, err)
+	}
+	return nil
+}
+
+// allow reports whether the command may run for url, and records the run
+func (n *ExecNotifier) allow(url string) bool {
+	if n.throttle <= 0 {
+		return true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	if last, ok := n.lastRun[url]; ok && now.Sub(last) < n.throttle {
+		return false
+	}
+	n.lastRun[url] = now
+	return true
+}
+
+// splitCommand splits a command into words at spaces outside of quotes.
+// Backslashes escape the next character outside of single quotes.
+func splitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
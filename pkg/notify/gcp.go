@@ -0,0 +1,281 @@
+package notify
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+)
+
+// gcpTokenURL is where Google OAuth2 access tokens are requested
+const gcpTokenURL = "https://oauth2.googleapis.com/token"
+
+// allowedGCPTokenURL reports whether the credentials of a key file may be
+// sent to its token_uri: Google's token endpoints over HTTPS, or loopback,
+// e.g. for an emulator
+func allowedGCPTokenURL(tokenURL string) bool {
+	u, err := url.Parse(tokenURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if isLoopback(host) {
+		return true
+	}
+	return u.Scheme == "https" && (host == "accounts.google.com" || strings.HasSuffix(host, ".googleapis.com"))
+}
+
+// gcpTokenSource gets access tokens from Application Default Credentials
+// like the Google Cloud client libraries, trying in order:
+//
+//   - the key file of GOOGLE_APPLICATION_CREDENTIALS
+//   - the credentials of 'gcloud auth application-default login'
+//   - the service account of the metadata server, e.g. on Compute Engine,
+//     GKE, Cloud Run and Cloud Functions
+//
+// Tokens are kept until shortly before they expire.
+type gcpTokenSource struct {
+	scope  string
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newGCPTokenSource creates a token source for the OAuth2 scope
+func newGCPTokenSource(scope string) *gcpTokenSource {
+	return &gcpTokenSource{
+		scope:  scope,
+		client: customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10}),
+	}
+}
+
+// gcpToken is the response of a token request
+type gcpToken struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// accessToken returns a valid access token
+func (s *gcpTokenSource) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.expires) > time.Minute*5 {
+		return s.token, nil
+	}
+
+	token, err := s.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Google credentials: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("Google credentials: no access token returned")
+	}
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// reset forgets the cached token, e.g. after it was rejected
+func (s *gcpTokenSource) reset() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
+
+// fetch requests a new token from the first source of credentials found
+func (s *gcpTokenSource) fetch(ctx context.Context) (*gcpToken, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if wellKnown := gcloudCredentialsPath(); wellKnown != "" {
+			if _, err := os.Stat(wellKnown); err == nil {
+				path = wellKnown
+			}
+		}
+	}
+	if path != "" {
+		return s.fromFile(ctx, path)
+	}
+
+	token, err := s.fromMetadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("none found in GOOGLE_APPLICATION_CREDENTIALS, the gcloud configuration or the metadata server: %w", err)
+	}
+	return token, nil
+}
+
+// gcloudCredentialsPath returns where gcloud keeps application default
+// credentials
+func gcloudCredentialsPath() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// fromFile requests a token with a service account key or the refresh
+// token of a user
+func (s *gcpTokenSource) fromFile(ctx context.Context, path string) (*gcpToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	tokenURL := file.TokenURI
+	if tokenURL == "" {
+		tokenURL = gcpTokenURL
+	}
+	if !allowedGCPTokenURL(tokenURL) {
+		return nil, fmt.Errorf("%s: token_uri '%s' is not a Google token endpoint", path, tokenURL)
+	}
+
+	var form url.Values
+	switch file.Type {
+	case "service_account":
+		assertion, err := gcpAssertion(file.ClientEmail, file.PrivateKeyID, file.PrivateKey, s.scope, tokenURL, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		form = url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+	case "authorized_user":
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {file.ClientID},
+			"client_secret": {file.ClientSecret},
+			"refresh_token": {file.RefreshToken},
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported credentials type '%s' (expected service_account or authorized_user)", path, file.Type)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.requestToken(req)
+}
+
+// fromMetadata requests a token of the service account the workload runs
+// as from the metadata server
+func (s *gcpTokenSource) fromMetadata(ctx context.Context) (*gcpToken, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	// Off Google Cloud nothing answers; don't wait long for it
+	ctx, cancel := context.WithTimeout(ctx, time.Second*2)
+	defer cancel()
+
+	endpoint := fmt.Sprintf("http://%s/computeMetadata/v1/instance/service-accounts/default/token?scopes=%s", host, url.QueryEscape(s.scope))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return s.requestToken(req)
+}
+
+// requestToken sends a token request and decodes the token
+func (s *gcpTokenSource) requestToken(req *http.Request) (*gcpToken, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token gcpToken
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token)
+	if resp.StatusCode != http.StatusOK {
+		if token.Error != "" {
+			return nil, fmt.Errorf("%s: %s", token.Error, token.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request returned status code %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return &token, nil
+}
+
+// gcpAssertion returns the JWT a service account requests a token with,
+// signed with its private key
+func gcpAssertion(email, keyID, privateKey, scope, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", errors.New("invalid private key")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if key, _ = parsed.(*rsa.PrivateKey); key == nil {
+			return "", errors.New("invalid private key: not an RSA key")
+		}
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   email,
+		"scope": scope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
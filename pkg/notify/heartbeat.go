@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// DefaultHeartbeatInterval is the time between heartbeat pings
+const DefaultHeartbeatInterval = time.Minute
+
+// Heartbeat monitors the monitor: it pings a URL at a regular interval so a
+// dead man's switch service such as healthchecks.io raises an alert once the
+// pings stop because hawkeye died. When the manager is unhealthy, the ping
+// goes to URL/fail instead, following the healthchecks.io convention.
+type Heartbeat struct {
+	url      string
+	interval time.Duration
+	health   func() monitor.Health
+	client   *http.Client
+}
+
+// NewHeartbeat creates a heartbeat that pings url every interval with the
+// result of health, typically Manager.Health
+func NewHeartbeat(url string, interval time.Duration, health func() monitor.Health) *Heartbeat {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	return &Heartbeat{
+		url:      url,
+		interval: interval,
+		health:   health,
+		client:   customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// Run pings immediately and then every interval until ctx is canceled.
+// Failed pings are passed to onError, which may be nil.
+func (h *Heartbeat) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.Ping(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Ping sends a single heartbeat. The health of the manager is sent as the
+// JSON body.
+func (h *Heartbeat) Ping(ctx context.Context) error {
+	if err := offline.Check("heartbeat"); err != nil {
+		return err
+	}
+
+	health := h.health()
+	body, err := json.Marshal(health)
+	if err != nil {
+		return err
+	}
+
+	url := h.url
+	if !health.OK() {
+		url = strings.TrimSuffix(url, "/") + "/fail"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	customhttp.AddHeaders(req, nil, version.UserAgent())
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat returned status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,442 @@
+package notify
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+)
+
+// Kafka API keys and the versions hawkeye speaks, supported by brokers
+// since Kafka 0.11
+const (
+	kafkaProduce         int16 = 0
+	kafkaMetadata        int16 = 3
+	kafkaProduceVersion  int16 = 3
+	kafkaMetadataVersion int16 = 1
+)
+
+// kafkaErrors names the broker error codes a producer may get
+var kafkaErrors = map[int16]string{
+	2:  "CORRUPT_MESSAGE",
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	10: "MESSAGE_TOO_LARGE",
+	17: "INVALID_TOPIC_EXCEPTION",
+	19: "NOT_ENOUGH_REPLICAS",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+}
+
+// kafkaError describes a broker error code
+func kafkaError(code int16) error {
+	if name, ok := kafkaErrors[code]; ok {
+		return fmt.Errorf("broker error %d (%s)", code, name)
+	}
+	return fmt.Errorf("broker error %d", code)
+}
+
+// KafkaNotifier publishes changes to a Kafka topic. Messages are keyed by
+// URL, so the changes of a monitor stay in order on one partition, and are
+// acknowledged by the partition leader. Connections are plaintext and kept
+// between changes.
+type KafkaNotifier struct {
+	name    string
+	brokers []string
+	topic   string
+	format  Format
+	encoder *streamEncoder
+	timeout time.Duration
+
+	mu          sync.Mutex
+	addrs       map[int32]string
+	leaders     []int32
+	conns       map[int32]*kafkaConn
+	correlation int32
+}
+
+// NewKafkaNotifier creates a notifier that publishes each change to topic,
+// looking up its partitions on the first of brokers, given as host:port,
+// that answers
+func NewKafkaNotifier(name string, brokers []string, topic string, format Format) *KafkaNotifier {
+	return &KafkaNotifier{
+		name:    name,
+		brokers: brokers,
+		topic:   topic,
+		format:  format,
+		encoder: newStreamEncoder(format, topic, ""),
+		timeout: time.Second * 10,
+		conns:   make(map[int32]*kafkaConn),
+	}
+}
+
+// WithSchemaRegistry registers ChangeSchema with the Confluent-compatible
+// schema registry at url and prefixes Avro messages with its ID
+func (n *KafkaNotifier) WithSchemaRegistry(url string) *KafkaNotifier {
+	n.encoder = newStreamEncoder(n.format, n.topic, url)
+	return n
+}
+
+// Name implements Notifier.Name
+func (n *KafkaNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify. The baggage of the change is passed on
+// in a Baggage record header. After an error, e.g. because the partition
+// moved to another leader, the partitions are looked up again on the next
+// change.
+func (n *KafkaNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	if err := offline.Check(fmt.Sprintf("kafka '%s'", n.name)); err != nil {
+		return err
+	}
+
+	value, err := n.encoder.encode(ctx, change)
+	if err != nil {
+		return fmt.Errorf("kafka '%s': %w", n.name, err)
+	}
+	headers := [][2]string{{"content-type", n.format.ContentType()}}
+	if len(change.Baggage) > 0 {
+		headers = append(headers, [2]string{monitor.BaggageHeader, monitor.FormatBaggage(change.Baggage)})
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.produce(ctx, []byte(change.URL), value, headers); err != nil {
+		n.reset()
+		return fmt.Errorf("kafka '%s': %w", n.name, err)
+	}
+	return nil
+}
+
+// produce sends a message to the leader of its partition. n.mu must be
+// held.
+func (n *KafkaNotifier) produce(ctx context.Context, key, value []byte, headers [][2]string) error {
+	if n.leaders == nil {
+		if err := n.lookup(ctx); err != nil {
+			return err
+		}
+	}
+
+	hash := fnv.New32a()
+	hash.Write(key)
+	partition := int32(hash.Sum32() % uint32(len(n.leaders)))
+	conn, err := n.conn(ctx, n.leaders[partition])
+	if err != nil {
+		return err
+	}
+
+	var w kafkaWriter
+	w.int16(-1) // No transactional ID
+	w.int16(1)  // Acknowledged by the leader
+	w.int32(int32(n.timeout / time.Millisecond))
+	w.int32(1)
+	w.string(n.topic)
+	w.int32(1)
+	w.int32(partition)
+	w.bytes(kafkaRecordBatch(key, value, headers, time.Now()))
+
+	r, err := conn.roundTrip(ctx, n.nextCorrelation(), kafkaProduce, kafkaProduceVersion, w.buf)
+	if err != nil {
+		return err
+	}
+	for topics := r.int32(); topics > 0 && r.err == nil; topics-- {
+		r.string()
+		for partitions := r.int32(); partitions > 0 && r.err == nil; partitions-- {
+			r.int32()
+			if code := r.int16(); code != 0 && r.err == nil {
+				return kafkaError(code)
+			}
+			r.int64()
+			r.int64()
+		}
+	}
+	return r.err
+}
+
+// lookup finds the leaders of the partitions of the topic on the first
+// broker that answers. n.mu must be held.
+func (n *KafkaNotifier) lookup(ctx context.Context) error {
+	var errs []error
+	for _, broker := range n.brokers {
+		err := n.lookupOn(ctx, broker)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return errors.New("no brokers")
+	}
+	return errors.Join(errs...)
+}
+
+// lookupOn reads the metadata of the topic from broker
+func (n *KafkaNotifier) lookupOn(ctx context.Context, broker string) error {
+	conn, err := dialKafka(ctx, broker, n.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var w kafkaWriter
+	w.int32(1)
+	w.string(n.topic)
+	r, err := conn.roundTrip(ctx, n.nextCorrelation(), kafkaMetadata, kafkaMetadataVersion, w.buf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", broker, err)
+	}
+
+	addrs := make(map[int32]string)
+	for brokers := r.int32(); brokers > 0 && r.err == nil; brokers-- {
+		id, host, port := r.int32(), r.string(), r.int32()
+		r.string() // Rack
+		addrs[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.int32() // Controller
+
+	type partition struct{ index, leader int32 }
+	var partitions []partition
+	var topicErr int16
+	for topics := r.int32(); topics > 0 && r.err == nil; topics-- {
+		code, name := r.int16(), r.string()
+		r.bool() // Internal
+		for count := r.int32(); count > 0 && r.err == nil; count-- {
+			r.int16() // Errors of partitions show when producing to them
+			p := partition{index: r.int32(), leader: r.int32()}
+			for replicas := r.int32(); replicas > 0 && r.err == nil; replicas-- {
+				r.int32()
+			}
+			for isr := r.int32(); isr > 0 && r.err == nil; isr-- {
+				r.int32()
+			}
+			if name == n.topic {
+				partitions = append(partitions, p)
+			}
+		}
+		if name == n.topic {
+			topicErr = code
+		}
+	}
+	switch {
+	case r.err != nil:
+		return fmt.Errorf("%s: invalid metadata: %w", broker, r.err)
+	case topicErr != 0:
+		return fmt.Errorf("topic '%s': %w", n.topic, kafkaError(topicErr))
+	case len(partitions) == 0:
+		return fmt.Errorf("topic '%s' has no partitions", n.topic)
+	}
+
+	slices.SortFunc(partitions, func(a, b partition) int { return int(a.index - b.index) })
+	leaders := make([]int32, len(partitions))
+	for i, p := range partitions {
+		if p.leader < 0 {
+			return fmt.Errorf("partition %d of topic '%s': %w", p.index, n.topic, kafkaError(5))
+		}
+		leaders[i] = p.leader
+	}
+	n.addrs, n.leaders = addrs, leaders
+	return nil
+}
+
+// conn returns the connection to a broker, connecting to it if needed.
+// n.mu must be held.
+func (n *KafkaNotifier) conn(ctx context.Context, id int32) (*kafkaConn, error) {
+	if conn := n.conns[id]; conn != nil {
+		return conn, nil
+	}
+	addr, ok := n.addrs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown broker %d", id)
+	}
+	conn, err := dialKafka(ctx, addr, n.timeout)
+	if err != nil {
+		return nil, err
+	}
+	n.conns[id] = conn
+	return conn, nil
+}
+
+// reset closes the connections and forgets the partitions. n.mu must be
+// held.
+func (n *KafkaNotifier) reset() {
+	for id, conn := range n.conns {
+		conn.Close()
+		delete(n.conns, id)
+	}
+	n.addrs, n.leaders = nil, nil
+}
+
+// nextCorrelation returns the ID of the next request. n.mu must be held.
+func (n *KafkaNotifier) nextCorrelation() int32 {
+	n.correlation++
+	return n.correlation
+}
+
+// kafkaConn is a connection to a Kafka broker
+type kafkaConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+// dialKafka connects to a broker
+func dialKafka(ctx context.Context, addr string, timeout time.Duration) (*kafkaConn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaConn{Conn: conn, timeout: timeout}, nil
+}
+
+// roundTrip sends a request and returns a reader of the body of its
+// response
+func (c *kafkaConn) roundTrip(ctx context.Context, correlation int32, apiKey, version int16, body []byte) (*kafkaReader, error) {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.SetDeadline(deadline)
+
+	var w kafkaWriter
+	w.int32(0) // Size, set below
+	w.int16(apiKey)
+	w.int16(version)
+	w.int32(correlation)
+	w.string("hawkeye")
+	w.buf = append(w.buf, body...)
+	binary.BigEndian.PutUint32(w.buf, uint32(len(w.buf)-4))
+	if _, err := c.Write(w.buf); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(c, size[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(c, response); err != nil {
+		return nil, err
+	}
+
+	r := &kafkaReader{buf: response}
+	if id := r.int32(); r.err == nil && id != correlation {
+		return nil, fmt.Errorf("response to request %d instead of %d", id, correlation)
+	}
+	return r, r.err
+}
+
+// kafkaRecordBatch returns a record batch, the message format of Kafka
+// 0.11 and later, holding a single record
+func kafkaRecordBatch(key, value []byte, headers [][2]string, now time.Time) []byte {
+	record := []byte{0}                     // Attributes
+	record = binary.AppendVarint(record, 0) // Timestamp delta
+	record = binary.AppendVarint(record, 0) // Offset delta
+	record = binary.AppendVarint(record, int64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendVarint(record, int64(len(value)))
+	record = append(record, value...)
+	record = binary.AppendVarint(record, int64(len(headers)))
+	for _, header := range headers {
+		record = binary.AppendVarint(record, int64(len(header[0])))
+		record = append(record, header[0]...)
+		record = binary.AppendVarint(record, int64(len(header[1])))
+		record = append(record, header[1]...)
+	}
+
+	// The checksum covers the batch from its attributes on
+	var batch kafkaWriter
+	batch.int16(0) // Attributes: no compression, create time
+	batch.int32(0) // Last offset delta
+	batch.int64(now.UnixMilli())
+	batch.int64(now.UnixMilli())
+	batch.int64(-1) // No producer ID, epoch or sequence
+	batch.int16(-1)
+	batch.int32(-1)
+	batch.int32(1)
+	batch.buf = binary.AppendVarint(batch.buf, int64(len(record)))
+	batch.buf = append(batch.buf, record...)
+
+	var w kafkaWriter
+	w.int64(0) // Base offset, set by the broker
+	w.int32(int32(4 + 1 + 4 + len(batch.buf)))
+	w.int32(-1) // Partition leader epoch
+	w.buf = append(w.buf, 2)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, crc32.Checksum(batch.buf, crc32.MakeTable(crc32.Castagnoli)))
+	w.buf = append(w.buf, batch.buf...)
+	return w.buf
+}
+
+// kafkaWriter encodes the fields of Kafka requests
+type kafkaWriter struct {
+	buf []byte
+}
+
+func (w *kafkaWriter) int16(v int16) { w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v)) }
+func (w *kafkaWriter) int32(v int32) { w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v)) }
+func (w *kafkaWriter) int64(v int64) { w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(v)) }
+
+func (w *kafkaWriter) string(v string) {
+	w.int16(int16(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *kafkaWriter) bytes(v []byte) {
+	w.int32(int32(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// kafkaReader decodes the fields of Kafka responses. The first error is
+// kept and later reads return zero values.
+type kafkaReader struct {
+	buf []byte
+	err error
+}
+
+// next returns the next n bytes
+func (r *kafkaReader) next(n int) []byte {
+	if r.err == nil && (n < 0 || n > len(r.buf)) {
+		r.err = io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return make([]byte, max(n, 0))
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *kafkaReader) bool() bool   { return r.next(1)[0] != 0 }
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.next(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+// string reads a string; a null string is empty
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+// bytes reads a byte array; a null one is nil
+func (r *kafkaReader) bytes() []byte {
+	n := r.int32()
+	if n < 0 {
+		return nil
+	}
+	return r.next(int(n))
+}
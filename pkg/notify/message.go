@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// message formats a change for chat services as a short summary and, for
+// content changes, the complete diff
+func message(change monitor.Change) (summary, diff string) {
+	event := eventType(change)
+	switch event {
+	case monitor.EventChange:
+		// Details starts with a description of the change, followed by a
+		// capped diff that the complete one replaces. A summary describes
+		// the change better.
+		description, capped, _ := strings.Cut(change.Details, "\n")
+		if change.Summary != "" {
+			description = change.Summary
+		}
+		summary = fmt.Sprintf("Change detected on %s", change.URL)
+		if description != "" {
+			summary += ": " + description
+		}
+		diff = change.Diff
+		if diff == "" {
+			diff = capped
+		}
+	case monitor.EventError:
+		summary = fmt.Sprintf("Error checking %s: %s", change.URL, change.Error)
+	case monitor.EventDigest:
+		// Details lists the changes of the digest below its first line
+		header, list, _ := strings.Cut(change.Details, "\n")
+		summary, diff = "Digest: "+header, list
+	case monitor.EventComposite:
+		// Details lists the events that met the rule below it
+		rule, list, _ := strings.Cut(change.Details, "\n")
+		summary, diff = fmt.Sprintf("Composite %s met: %s", change.URL, rule), list
+	default:
+		summary = fmt.Sprintf("[%s] %s", strings.ToUpper(string(event)), change.URL)
+		if change.Details != "" {
+			summary += ": " + change.Details
+		}
+	}
+	return summary, strings.TrimSuffix(diff, "\n")
+}
+
+// textLength returns the length of text as chat services count it, in UTF-16
+// code units
+func textLength(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// truncateText cuts text to at most limit UTF-16 code units, ending it with an
+// ellipsis if anything was cut. Characters are never split.
+func truncateText(text string, limit int) string {
+	if textLength(text) <= limit {
+		return text
+	}
+	n := 0
+	for i, r := range text {
+		if n+utf16.RuneLen(r) > limit-1 {
+			return text[:i] + "…"
+		}
+		n += utf16.RuneLen(r)
+	}
+	return text
+}
+
+// splitText splits text into parts of at most limit UTF-16 code units. Parts
+// end at line breaks where possible; longer lines are split between
+// characters.
+func splitText(text string, limit int) []string {
+	var parts []string
+	var part strings.Builder
+	partLength := 0
+
+	flush := func() {
+		if part.Len() > 0 {
+			parts = append(parts, part.String())
+			part.Reset()
+			partLength = 0
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		length := textLength(line)
+		if partLength+length > limit {
+			flush()
+		}
+		for length > limit {
+			// A line that fills whole parts on its own
+			n, cut := 0, 0
+			for cut < len(line) {
+				r, size := utf8.DecodeRuneInString(line[cut:])
+				if n+utf16.RuneLen(r) > limit {
+					break
+				}
+				n += utf16.RuneLen(r)
+				cut += size
+			}
+			parts = append(parts, line[:cut])
+			line = line[cut:]
+			length -= n
+		}
+		part.WriteString(line)
+		partLength += length
+	}
+	flush()
+
+	return parts
+}
+
+// post sends a request to a chat service and checks its status
+func post(ctx context.Context, client *http.Client, name, url, contentType string, body []byte) error {
+	return postWithHeader(ctx, client, name, url, http.Header{"Content-Type": {contentType}}, body)
+}
+
+// postWithHeader sends a request with the header to a chat or push service
+// and checks its status
+func postWithHeader(ctx context.Context, client *http.Client, name, url string, header http.Header, body []byte) error {
+	if err := offline.Check(fmt.Sprintf("notification '%s'", name)); err != nil {
+		return err
+	}
+	url, err := secret.Resolve(url)
+	if err != nil {
+		return fmt.Errorf("notification '%s': %w", name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification '%s' returned status code %d", name, resp.StatusCode)
+	}
+	return nil
+}
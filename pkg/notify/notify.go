@@ -0,0 +1,111 @@
+// Package notify delivers detected changes and other monitor events, such as
+// errors and recoveries, to external services.
+package notify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// Notifier delivers a change to an external destination
+type Notifier interface {
+	// Notify sends the change
+	Notify(ctx context.Context, change monitor.Change) error
+	// Name returns the name of the notifier
+	Name() string
+}
+
+// NotifierList is a collection of notifiers that are all sent every change
+type NotifierList []Notifier
+
+// Notify sends the change to every notifier in the list and returns the
+// first error encountered, after trying all notifiers
+func (l NotifierList) Notify(ctx context.Context, change monitor.Change) error {
+	var firstErr error
+	for _, notifier := range l {
+		if err := notifier.Notify(ctx, change); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// FilterEvents wraps a notifier so it is only sent the given event types. With
+// no event types every event is sent.
+func FilterEvents(notifier Notifier, events ...monitor.EventType) Notifier {
+	if len(events) == 0 {
+		return notifier
+	}
+
+	allowed := make(map[monitor.EventType]bool, len(events))
+	for _, event := range events {
+		allowed[event] = true
+	}
+
+	return &eventFilter{Notifier: notifier, events: allowed}
+}
+
+// eventFilter drops events a notifier is not interested in
+type eventFilter struct {
+	Notifier
+	events map[monitor.EventType]bool
+}
+
+// Notify implements Notifier.Notify
+func (f *eventFilter) Notify(ctx context.Context, change monitor.Change) error {
+	if !f.events[eventType(change)] {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, change)
+}
+
+// Unwrap returns the filtered notifier
+func (f *eventFilter) Unwrap() Notifier {
+	return f.Notifier
+}
+
+// ErrorOnset tracks the health of monitors so that only the first error of a
+// streak of failed checks is notified, rather than every failed check
+type ErrorOnset struct {
+	mu      sync.Mutex
+	failing map[string]bool
+}
+
+// NewErrorOnset creates an error onset tracker
+func NewErrorOnset() *ErrorOnset {
+	return &ErrorOnset{failing: make(map[string]bool)}
+}
+
+// Allow reports whether a change should be notified. Content changes and
+// monitor events always are; errors only when a monitor starts failing.
+func (o *ErrorOnset) Allow(change monitor.Change) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch eventType(change) {
+	case monitor.EventError:
+		if o.failing[change.URL] {
+			return false
+		}
+		o.failing[change.URL] = true
+	case monitor.EventRecovery, monitor.EventBaselineReset:
+		delete(o.failing, change.URL)
+	}
+
+	return true
+}
+
+// eventType returns the event type of a change, inferring it for changes
+// that don't set one
+func eventType(change monitor.Change) monitor.EventType {
+	switch {
+	case change.Event != "":
+		return change.Event
+	case change.Error != "":
+		return monitor.EventError
+	default:
+		return monitor.EventChange
+	}
+}
@@ -0,0 +1,606 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	changes []monitor.Change
+	err     error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	n.changes = append(n.changes, change)
+	return n.err
+}
+
+func (n *recordingNotifier) Name() string {
+	return "recording"
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received monitor.Change
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "secret", r.Header.Get("X-Token"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier("ops", server.URL, map[string]string{"X-Token": "secret"})
+	require.Equal(t, "ops", notifier.Name())
+
+	err := notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com", HasChanged: true, Details: "summary", Hunks: []monitor.DiffHunk{{OldStart: 1, OldLines: 1}}})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", received.URL)
+	require.True(t, received.HasChanged)
+	require.Equal(t, "summary", received.Details)
+	require.Empty(t, received.Hunks)
+}
+
+func TestWebhookNotifierErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier("ops", server.URL, nil)
+	err := notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "500")
+}
+
+func TestWebhookNotifierSecrets(t *testing.T) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Token")
+	}))
+	defer server.Close()
+	t.Setenv("HAWKEYE_TEST_WEBHOOK", server.URL)
+	t.Setenv("HAWKEYE_TEST_TOKEN", "s3cret")
+
+	notifier := NewWebhookNotifier("ops", "${HAWKEYE_TEST_WEBHOOK}", map[string]string{"X-Token": "${HAWKEYE_TEST_TOKEN}"})
+	require.NoError(t, notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com"}))
+	require.Equal(t, "s3cret", token)
+
+	notifier = NewWebhookNotifier("ops", "${HAWKEYE_TEST_UNSET}", nil)
+	err := notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com"})
+	require.ErrorContains(t, err, "HAWKEYE_TEST_UNSET")
+}
+
+func TestNotifierList(t *testing.T) {
+	boom := errors.New("boom")
+	first := &recordingNotifier{err: boom}
+	second := &recordingNotifier{}
+
+	list := NotifierList{first, second}
+	err := list.Notify(context.Background(), monitor.Change{URL: "https://example.com"})
+	require.ErrorIs(t, err, boom)
+
+	// Every notifier is tried even if an earlier one fails
+	require.Len(t, first.changes, 1)
+	require.Len(t, second.changes, 1)
+}
+
+func TestFilterEvents(t *testing.T) {
+	recorder := &recordingNotifier{}
+	notifier := FilterEvents(recorder, monitor.EventError, monitor.EventRecovery)
+	require.Equal(t, "recording", notifier.Name())
+
+	ctx := context.Background()
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, HasChanged: true}))
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventError, Error: "boom"}))
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventRecovery}))
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Error: "no event type"}))
+
+	require.Len(t, recorder.changes, 3)
+	require.Equal(t, monitor.EventError, recorder.changes[0].Event)
+	require.Equal(t, monitor.EventRecovery, recorder.changes[1].Event)
+
+	// Without event types everything is sent
+	require.Same(t, recorder, FilterEvents(recorder))
+}
+
+func TestRoute(t *testing.T) {
+	quiet, err := schedule.ParseWindow("Sat,Sun 00:00-24:00 UTC", schedule.ModeSilence)
+	require.NoError(t, err)
+	weekday := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	saturday := time.Date(2025, 1, 18, 10, 0, 0, 0, time.UTC)
+
+	recorder := &recordingNotifier{}
+	notifier := Route(recorder,
+		Rule{Events: []monitor.EventType{monitor.EventError}},
+		Rule{Match: regexp.MustCompile(`price`), QuietHours: schedule.Windows{quiet}, MinSeverity: SeverityWarning},
+	)
+	require.Equal(t, "recording", notifier.Name())
+
+	ctx := context.Background()
+	for _, change := range []monitor.Change{
+		{URL: "https://example.com", Error: "boom", Timestamp: saturday},
+		{URL: "https://example.com", Event: monitor.EventChange, Details: "price went up", Timestamp: weekday},
+		{URL: "https://example.com", Event: monitor.EventChange, Details: "price went up", Timestamp: saturday},
+		{URL: "https://example.com", Event: monitor.EventChange, Details: "new comment", Timestamp: weekday},
+		{URL: "https://example.com", Event: monitor.EventExpired, Details: "price checks", Timestamp: weekday},
+	} {
+		require.NoError(t, notifier.Notify(ctx, change))
+	}
+
+	require.Len(t, recorder.changes, 2)
+	require.Equal(t, "boom", recorder.changes[0].Error)
+	require.Equal(t, weekday, recorder.changes[1].Timestamp)
+
+	// Without rules everything is sent
+	require.Same(t, recorder, Route(recorder))
+}
+
+func TestSeverity(t *testing.T) {
+	require.Equal(t, SeverityCritical, SeverityOf(monitor.Change{Error: "boom"}))
+	require.Equal(t, SeverityWarning, SeverityOf(monitor.Change{HasChanged: true}))
+	require.Equal(t, SeverityInfo, SeverityOf(monitor.Change{Event: monitor.EventRecovery}))
+
+	severity, err := ParseSeverity("Critical")
+	require.NoError(t, err)
+	require.Equal(t, "critical", severity.String())
+	_, err = ParseSeverity("fatal")
+	require.Error(t, err)
+}
+
+func TestThrottle(t *testing.T) {
+	recorder := &recordingNotifier{}
+	notifier := Throttle(recorder, time.Minute)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	notifier.(*throttler).now = func() time.Time { return now }
+
+	ctx := context.Background()
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", HasChanged: true}))
+	err := notifier.Notify(ctx, monitor.Change{URL: "https://example.com", HasChanged: true})
+	require.ErrorIs(t, err, ErrThrottled)
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.org", HasChanged: true}))
+
+	now = now.Add(time.Minute)
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", HasChanged: true}))
+	require.Len(t, recorder.changes, 3)
+
+	require.Same(t, recorder, Throttle(recorder, 0))
+}
+
+func TestDeduplicate(t *testing.T) {
+	recorder := &recordingNotifier{}
+	notifier := Deduplicate(recorder)
+
+	ctx := context.Background()
+	banner := monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Details: "Sale banner"}
+	for _, change := range []monitor.Change{
+		banner,
+		banner,
+		{URL: "https://example.org", Event: monitor.EventChange, Details: "Sale banner"},
+		{URL: "https://example.com", Event: monitor.EventChange, Details: "No banner"},
+		banner,
+	} {
+		require.NoError(t, notifier.Notify(ctx, change))
+	}
+	require.Len(t, recorder.changes, 4)
+}
+
+func TestDigest(t *testing.T) {
+	recorder := &recordingNotifier{}
+	digest := NewDigest(recorder, time.Hour)
+	notifier := FilterEvents(digest, monitor.EventChange, monitor.EventError)
+
+	ctx := context.Background()
+	at := time.Date(2025, 1, 15, 10, 0, 0, 0, time.Local)
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, HasChanged: true, Details: "Price changed\n-10\n+12", Timestamp: at}))
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.org", Event: monitor.EventError, Error: "timeout", Timestamp: at.Add(time.Minute)}))
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.org", Event: monitor.EventPaused}))
+	require.Empty(t, recorder.changes)
+
+	// Flush finds the digest behind the filter
+	require.NoError(t, Flush(ctx, notifier, &recordingNotifier{}))
+	require.Len(t, recorder.changes, 1)
+	change := recorder.changes[0]
+	require.Equal(t, monitor.EventDigest, change.Event)
+	require.Empty(t, change.URL)
+	require.True(t, change.HasChanged)
+	header, list, _ := strings.Cut(change.Details, "\n")
+	require.True(t, strings.HasPrefix(header, "2 changes of 2 URLs since "))
+	require.Equal(t, "- 10:00:00 Change detected on https://example.com: Price changed\n- 10:01:00 Error checking https://example.org: timeout", list)
+	require.Equal(t, "Digest", pushTitle(change))
+
+	// Nothing is left to flush
+	require.NoError(t, Flush(ctx, notifier))
+	require.Len(t, recorder.changes, 1)
+}
+
+func TestDigestWindow(t *testing.T) {
+	sent := make(chan monitor.Change, 1)
+	digest := NewDigest(notifierFunc(func(change monitor.Change) { sent <- change }), 10*time.Millisecond)
+
+	for range 3 {
+		require.NoError(t, digest.Notify(context.Background(), monitor.Change{URL: "https://example.com", HasChanged: true}))
+	}
+	change := <-sent
+	require.Equal(t, "https://example.com", change.URL)
+	require.True(t, strings.HasPrefix(change.Details, "3 changes since "))
+}
+
+// notifierFunc is a notifier calling a function
+type notifierFunc func(monitor.Change)
+
+func (f notifierFunc) Notify(ctx context.Context, change monitor.Change) error {
+	f(change)
+	return nil
+}
+
+func (f notifierFunc) Name() string {
+	return "func"
+}
+
+func TestErrorOnset(t *testing.T) {
+	onset := NewErrorOnset()
+
+	failure := monitor.Change{URL: "https://example.com", Event: monitor.EventError, Error: "boom"}
+	require.True(t, onset.Allow(failure))
+	require.False(t, onset.Allow(failure))
+
+	// Other monitors are tracked separately
+	require.True(t, onset.Allow(monitor.Change{URL: "https://other.example.com", Event: monitor.EventError}))
+
+	// Recovery ends the streak
+	require.True(t, onset.Allow(monitor.Change{URL: "https://example.com", Event: monitor.EventRecovery}))
+	require.True(t, onset.Allow(failure))
+
+	require.True(t, onset.Allow(monitor.Change{URL: "https://example.com", Event: monitor.EventChange, HasChanged: true}))
+}
+
+func TestHeartbeat(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var health monitor.Health
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&health))
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	health := monitor.Health{Running: true, Monitors: 2}
+	heartbeat := NewHeartbeat(server.URL+"/ping/abc", time.Minute, func() monitor.Health { return health })
+
+	require.NoError(t, heartbeat.Ping(context.Background()))
+
+	health.Stalled = []string{"https://example.com"}
+	require.NoError(t, heartbeat.Ping(context.Background()))
+
+	require.Equal(t, []string{"/ping/abc", "/ping/abc/fail"}, paths)
+}
+
+func TestHeartbeatRun(t *testing.T) {
+	pings := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings <- struct{}{}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	heartbeat := NewHeartbeat(server.URL, time.Hour, func() monitor.Health { return monitor.Health{Running: true} })
+	go func() {
+		heartbeat.Run(ctx, func(err error) { t.Error(err) })
+		close(done)
+	}()
+
+	// The first ping is sent right away
+	<-pings
+	cancel()
+	<-done
+}
+
+func TestWebhookNotifierSignature(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier("ops", server.URL, nil).WithSecret("s3cret")
+	require.NoError(t, notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com"}))
+
+	require.True(t, strings.HasPrefix(header.Get(SignatureHeader), "sha256="))
+	require.NotEmpty(t, header.Get(NonceHeader))
+	require.NoError(t, VerifySignature("s3cret", header, body, DefaultSignatureTolerance, time.Now()))
+
+	require.ErrorIs(t, VerifySignature("wrong", header, body, DefaultSignatureTolerance, time.Now()), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySignature("s3cret", header, append(body, ' '), DefaultSignatureTolerance, time.Now()), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySignature("s3cret", header, body, DefaultSignatureTolerance, time.Now().Add(time.Hour)), ErrSignatureExpired)
+	require.ErrorIs(t, VerifySignature("s3cret", http.Header{}, body, DefaultSignatureTolerance, time.Now()), ErrMissingSignature)
+}
+
+func TestSign(t *testing.T) {
+	// Computed with: printf '1700000000.abc.{}' | openssl dgst -sha256 -hmac key
+	require.Equal(t, "sha256=5b0ed6a1c8da1ca2b44c3493245ec6b1f8b9cf1dc9cfc6cac3f44b488f289e52", Sign("key", "1700000000", "abc", []byte("{}")))
+}
+
+func TestSplitText(t *testing.T) {
+	require.Equal(t, []string{"one\ntwo\n", "three"}, splitText("one\ntwo\nthree", 9))
+
+	// Long lines are split between characters, counting UTF-16 code units
+	parts := splitText("ééééé\n😀😀😀", 4)
+	require.Equal(t, []string{"éééé", "é\n", "😀😀", "😀"}, parts)
+	for _, part := range parts {
+		require.True(t, utf8.ValidString(part))
+	}
+
+	require.Equal(t, "abc…", truncateText("abcdef", 4))
+	require.Equal(t, "ab…", truncateText("ab😀😀", 4))
+	require.Equal(t, "abc", truncateText("abc", 4))
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		messages = append(messages, body.Text)
+	}))
+	defer server.Close()
+
+	notifier := NewSlackNotifier("team", server.URL)
+	require.Equal(t, "team", notifier.Name())
+	ctx := context.Background()
+
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Details: "Content changed\n@@ capped", Diff: "@@ -1 +1 @@\n-<b>old</b>\n+new\n"}))
+	require.Equal(t, []string{"Change detected on https://example.com: Content changed\n```\n@@ -1 +1 @@\n-&lt;b&gt;old&lt;/b&gt;\n+new\n```"}, messages)
+
+	// A summary replaces the description in the details
+	messages = nil
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Details: "Content differs at position 3\n@@ capped", Summary: `"old" changed to "new"`}))
+	require.Equal(t, []string{"Change detected on https://example.com: \"old\" changed to \"new\"\n```\n@@ capped\n```"}, messages)
+
+	// Long diffs are split into numbered messages, up to a limit
+	messages = nil
+	line := strings.Repeat("x", 99) + "\n"
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Diff: strings.Repeat(line, 60)}))
+	require.Len(t, messages, 2)
+	require.True(t, strings.HasPrefix(messages[0], "Change detected on https://example.com (1/2)\n```\n"))
+	require.True(t, strings.HasPrefix(messages[1], "(2/2)\n```\n"))
+	for _, message := range messages {
+		require.LessOrEqual(t, textLength(message), slackMessageLimit)
+	}
+
+	messages = nil
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Diff: strings.Repeat(line, 1000)}))
+	require.Len(t, messages, slackMaxMessages)
+	require.Regexp(t, `… \d+ more lines of the diff were left out$`, messages[slackMaxMessages-1])
+
+	messages = nil
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventError, Error: "timeout"}))
+	require.Equal(t, []string{"Error checking https://example.com: timeout"}, messages)
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	var content, file string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, file = "", ""
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			var payload struct {
+				Content string `json:"content"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(r.FormValue("payload_json")), &payload))
+			content = payload.Content
+			f, header, err := r.FormFile("files[0]")
+			require.NoError(t, err)
+			require.Equal(t, "diff.txt", header.Filename)
+			data, _ := io.ReadAll(f)
+			file = string(data)
+			return
+		}
+		var body struct {
+			Content string `json:"content"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		content = body.Content
+	}))
+	defer server.Close()
+
+	notifier := NewDiscordNotifier("community", server.URL)
+	ctx := context.Background()
+
+	// Short diffs are sent in the message
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Diff: "-old\n+new\n"}))
+	require.Equal(t, "Change detected on https://example.com\n```diff\n-old\n+new\n```", content)
+	require.Empty(t, file)
+
+	// Long diffs are attached
+	diff := strings.Repeat("+ünïcödé line\n", 500)
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventChange, Diff: diff}))
+	require.Equal(t, "Change detected on https://example.com\nThe complete diff is attached.", content)
+	require.Equal(t, diff, file)
+
+	// Huge files are cut at a line break
+	capped := string(capFile(diff, 100))
+	require.LessOrEqual(t, len(capped), 100)
+	require.Contains(t, capped, "more bytes left out")
+	require.True(t, utf8.ValidString(capped))
+}
+
+func TestPushoverNotifier(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+	}))
+	defer server.Close()
+
+	t.Setenv("PUSHOVER_TOKEN", "app-token")
+	notifier := NewPushoverNotifier("phone", "${PUSHOVER_TOKEN}", "user-key").WithPriorities(Priorities{monitor.EventRecovery: PriorityLow})
+	notifier.url = server.URL
+	ctx := context.Background()
+
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com/pricing", Event: monitor.EventChange, Diff: "-old\n+new\n"}))
+	require.Equal(t, "app-token", form.Get("token"))
+	require.Equal(t, "user-key", form.Get("user"))
+	require.Equal(t, "Change on example.com", form.Get("title"))
+	require.Equal(t, "Change detected on https://example.com/pricing\n\n-old\n+new", form.Get("message"))
+	require.Equal(t, "https://example.com/pricing", form.Get("url"))
+	require.Equal(t, "0", form.Get("priority"))
+
+	// Errors are high by default and long messages are cut
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Error: strings.Repeat("ü", 2000)}))
+	require.Equal(t, "1", form.Get("priority"))
+	require.Equal(t, pushoverMessageLimit, textLength(form.Get("message")))
+
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Event: monitor.EventRecovery}))
+	require.Equal(t, "-1", form.Get("priority"))
+	require.Equal(t, "Recovery on example.com", form.Get("title"))
+
+	// Emergencies are repeated until acknowledged
+	notifier.WithPriorities(Priorities{monitor.EventError: PriorityUrgent})
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Error: "timeout"}))
+	require.Equal(t, "2", form.Get("priority"))
+	require.Equal(t, "60", form.Get("retry"))
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	var header http.Header
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	notifier := NewNtfyNotifier("phone", server.URL+"/hawkeye").WithToken("tk_secret")
+	ctx := context.Background()
+
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com/pricing", Event: monitor.EventBaselineReset, Details: "by ops"}))
+	require.Equal(t, "Baseline reset on example.com", header.Get("Title"))
+	require.Equal(t, "3", header.Get("Priority"))
+	require.Equal(t, "hawkeye,baseline_reset", header.Get("Tags"))
+	require.Equal(t, "https://example.com/pricing", header.Get("Click"))
+	require.Equal(t, "Bearer tk_secret", header.Get("Authorization"))
+	require.Equal(t, "[BASELINE_RESET] https://example.com/pricing: by ops", body)
+
+	// The message is cut to the byte limit of ntfy
+	diff := strings.Repeat("+ünïcödé line\n", 500)
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Error: "timeout", Diff: diff, Event: monitor.EventChange}))
+	require.Equal(t, "3", header.Get("Priority"))
+	require.LessOrEqual(t, len(body), ntfyMessageLimit)
+	require.True(t, utf8.ValidString(body))
+	require.True(t, strings.HasSuffix(body, "…"))
+
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com", Error: "timeout"}))
+	require.Equal(t, "4", header.Get("Priority"))
+}
+
+func TestParsePriorities(t *testing.T) {
+	priorities, err := ParsePriorities(map[string]string{"change": "High", "recovery": "min"})
+	require.NoError(t, err)
+	require.Equal(t, Priorities{monitor.EventError: PriorityHigh, monitor.EventChange: PriorityHigh, monitor.EventRecovery: PriorityMin}, priorities)
+
+	_, err = ParsePriorities(map[string]string{"change": "loud"})
+	require.ErrorContains(t, err, "unknown priority 'loud'")
+	_, err = ParsePriorities(map[string]string{"changes": "high"})
+	require.Error(t, err)
+}
+
+func TestOffline(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	offline.Set(true)
+	defer offline.Set(false)
+
+	change := monitor.Change{URL: "https://example.com", Details: "Content changed"}
+	for _, notifier := range []Notifier{
+		NewWebhookNotifier("ops", server.URL, nil),
+		NewSlackNotifier("team", server.URL),
+		NewDiscordNotifier("community", server.URL),
+		NewNtfyNotifier("phone", server.URL),
+	} {
+		err := notifier.Notify(context.Background(), change)
+		require.ErrorIs(t, err, offline.ErrOffline)
+		require.ErrorContains(t, err, "'"+notifier.Name()+"'")
+	}
+
+	heartbeat := NewHeartbeat(server.URL, time.Minute, func() monitor.Health { return monitor.Health{Running: true} })
+	require.ErrorIs(t, heartbeat.Ping(context.Background()), offline.ErrOffline)
+	require.Zero(t, requests)
+}
+
+func TestExecNotifier(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	notifier, err := NewExecNotifier("script", `sh -c 'printf "%s|%s\n" "$0" "$1" > "$2"; cat >> "$2"' {{.URL}} "{{.Event}} $(reboot)" `+out)
+	require.NoError(t, err)
+
+	change := monitor.Change{URL: "https://example.com/a b", Event: monitor.EventChange, HasChanged: true, Details: "v2"}
+	require.NoError(t, notifier.Notify(context.Background(), change))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	first, payload, _ := strings.Cut(string(data), "\n")
+	require.Equal(t, "https://example.com/a b|change $(reboot)", first)
+	var sent monitor.Change
+	require.NoError(t, json.Unmarshal([]byte(payload), &sent))
+	require.Equal(t, "v2", sent.Details)
+}
+
+func TestExecNotifierFailures(t *testing.T) {
+	_, err := NewExecNotifier("bad", `echo "unterminated`)
+	require.ErrorContains(t, err, "unterminated \" quote")
+	_, err = NewExecNotifier("bad", " ")
+	require.ErrorContains(t, err, "empty command")
+	_, err = NewExecNotifier("bad", "echo {{.URL")
+	require.Error(t, err)
+
+	notifier, err := NewExecNotifier("fail", "sh -c 'exit 3'")
+	require.NoError(t, err)
+	require.ErrorContains(t, notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com"}), "exec 'fail': exit status 3")
+
+	notifier, err = NewExecNotifier("slow", "sleep 5")
+	require.NoError(t, err)
+	notifier.WithTimeout(time.Millisecond * 50)
+	require.ErrorIs(t, notifier.Notify(context.Background(), monitor.Change{URL: "https://example.com"}), context.DeadlineExceeded)
+}
+
+func TestExecNotifierThrottle(t *testing.T) {
+	notifier, err := NewExecNotifier("throttled", "true")
+	require.NoError(t, err)
+	notifier.WithThrottle(time.Hour)
+
+	ctx := context.Background()
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com"}))
+	require.ErrorIs(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.com"}), ErrThrottled)
+	require.NoError(t, notifier.Notify(ctx, monitor.Change{URL: "https://example.org"}))
+}
+
+func TestSplitCommand(t *testing.T) {
+	words, err := splitCommand(`a  "b c" 'd "e"' f\ g "h\"i" ''`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b c", `d "e"`, "f g", `h"i`, ""}, words)
+}
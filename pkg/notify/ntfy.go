@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// ntfyMessageLimit is the most bytes ntfy servers accept in a message by
+// default; longer ones are turned into attachments or refused
+const ntfyMessageLimit = 4096
+
+// NtfyNotifier publishes changes to an ntfy topic, on ntfy.sh or a
+// self-hosted server, as push notifications
+type NtfyNotifier struct {
+	name       string
+	url        string
+	token      string
+	priorities Priorities
+	client     *http.Client
+}
+
+// NewNtfyNotifier creates a notifier that publishes each change to the topic
+// at url, e.g. https://ntfy.sh/my-topic
+func NewNtfyNotifier(name, url string) *NtfyNotifier {
+	return &NtfyNotifier{
+		name:       name,
+		url:        url,
+		priorities: DefaultPriorities(),
+		client:     customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// WithToken authenticates to the server with an access token, which may be a
+// secret placeholder
+func (n *NtfyNotifier) WithToken(token string) *NtfyNotifier {
+	n.token = token
+	return n
+}
+
+// WithPriorities sets the priorities of events, which are added to the
+// default ones
+func (n *NtfyNotifier) WithPriorities(priorities Priorities) *NtfyNotifier {
+	n.priorities = priorities.withDefaults()
+	return n
+}
+
+// Name implements Notifier.Name
+func (n *NtfyNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify
+func (n *NtfyNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	// ntfy priorities go from 1 for the lowest to 5 for the most urgent,
+	// like Priority
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Title", pushTitle(change))
+	header.Set("Priority", strconv.Itoa(int(n.priorities.of(change))))
+	header.Set("Tags", "hawkeye,"+string(eventType(change)))
+	header.Set("Click", change.URL)
+	if n.token != "" {
+		token, err := secret.Resolve(n.token)
+		if err != nil {
+			return fmt.Errorf("notification '%s': token: %w", n.name, err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	// The limit is in bytes rather than characters, so the message is cut
+	// further until it fits
+	message := pushMessage(change, ntfyMessageLimit)
+	for limit := ntfyMessageLimit; len(message) > ntfyMessageLimit; {
+		limit -= len(message) - ntfyMessageLimit
+		message = pushMessage(change, limit)
+	}
+
+	return postWithHeader(ctx, n.client, n.name, n.url, header, []byte(message))
+}
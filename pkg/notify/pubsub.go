@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// pubsubScope is the OAuth2 scope of Pub/Sub requests
+const pubsubScope = "https://www.googleapis.com/auth/pubsub"
+
+// PubSubNotifier publishes changes as JSON to a Google Cloud Pub/Sub topic,
+// e.g. for Cloud Functions or Cloud Run services subscribed to it. The event
+// type, the URL and the baggage of each change are message attributes, so
+// subscriptions can filter on them.
+type PubSubNotifier struct {
+	name   string
+	topic  string
+	tokens *gcpTokenSource
+	client *http.Client
+}
+
+// NewPubSubNotifier creates a notifier that publishes each change to the
+// topic, given as projects/<project>/topics/<topic>. Application Default
+// Credentials are used, like the Google Cloud client libraries do. With
+// PUBSUB_EMULATOR_HOST set, changes go to the emulator without credentials.
+func NewPubSubNotifier(name, topic string) *PubSubNotifier {
+	return &PubSubNotifier{
+		name:   name,
+		topic:  topic,
+		tokens: newGCPTokenSource(pubsubScope),
+		client: customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10}),
+	}
+}
+
+// Name implements Notifier.Name
+func (n *PubSubNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify
+func (n *PubSubNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	if err := offline.Check(fmt.Sprintf("pubsub '%s'", n.name)); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	attributes := map[string]string{"content-type": FormatJSON.ContentType()}
+	for _, attribute := range messageAttributes(change) {
+		attributes[attribute[0]] = attribute[1]
+	}
+	// Data is encoded in base64 by encoding/json
+	body, err := json.Marshal(map[string]any{
+		"messages": []map[string]any{{"data": data, "attributes": attributes}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := "https://pubsub.googleapis.com"
+	emulator := os.Getenv("PUBSUB_EMULATOR_HOST")
+	if emulator != "" {
+		endpoint = "http://" + emulator
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1/%s:publish", endpoint, n.topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.UserAgent())
+	if emulator == "" {
+		token, err := n.tokens.accessToken(ctx)
+		if err != nil {
+			return fmt.Errorf("pubsub '%s': %w", n.name, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pubsub '%s': %w", n.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		n.tokens.reset()
+	}
+	var failure struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&failure) == nil && failure.Error.Status != "" {
+		return fmt.Errorf("pubsub '%s': %s: %s", n.name, failure.Error.Status, failure.Error.Message)
+	}
+	return fmt.Errorf("pubsub '%s' returned status code %d", n.name, resp.StatusCode)
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// Priority is how urgently a push notification is delivered. Each service
+// maps it onto its own scale.
+type Priority int
+
+// Priorities of push notifications, from the least to the most urgent
+const (
+	PriorityMin Priority = iota + 1
+	PriorityLow
+	PriorityNormal
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = map[Priority]string{
+	PriorityMin:    "min",
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// ParsePriority parses min, low, normal, high or urgent
+func ParsePriority(name string) (Priority, error) {
+	for priority, n := range priorityNames {
+		if strings.EqualFold(name, n) {
+			return priority, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority '%s': must be min, low, normal, high or urgent", name)
+}
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	return priorityNames[p]
+}
+
+// Priorities maps event types to the priority of their push notifications.
+// Events it doesn't list are sent with normal priority.
+type Priorities map[monitor.EventType]Priority
+
+// DefaultPriorities sends errors with high priority and other events with
+// normal priority
+func DefaultPriorities() Priorities {
+	return Priorities{monitor.EventError: PriorityHigh}
+}
+
+// ParsePriorities parses a map of event type names to priority names and
+// adds it to the default priorities
+func ParsePriorities(names map[string]string) (Priorities, error) {
+	priorities := DefaultPriorities()
+	for event, name := range names {
+		eventType, err := monitor.ParseEventType(event)
+		if err != nil {
+			return nil, err
+		}
+		if priorities[eventType], err = ParsePriority(name); err != nil {
+			return nil, err
+		}
+	}
+	return priorities, nil
+}
+
+// of returns the priority of the notification of a change
+func (p Priorities) of(change monitor.Change) Priority {
+	if priority, ok := p[eventType(change)]; ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// withDefaults returns p added to the default priorities
+func (p Priorities) withDefaults() Priorities {
+	priorities := DefaultPriorities()
+	maps.Copy(priorities, p)
+	return priorities
+}
+
+// pushTitle returns the title of a push notification, e.g. "Change on
+// example.com"
+func pushTitle(change monitor.Change) string {
+	event := strings.ReplaceAll(string(eventType(change)), "_", " ")
+	if event != "" {
+		event = strings.ToUpper(event[:1]) + event[1:]
+	}
+	host := change.URL
+	if u, err := url.Parse(change.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if host == "" {
+		return event
+	}
+	return fmt.Sprintf("%s on %s", event, host)
+}
+
+// pushMessage returns the text of a push notification: the summary of the
+// change and its diff, cut to limit UTF-16 code units
+func pushMessage(change monitor.Change, limit int) string {
+	summary, diff := message(change)
+	if diff != "" {
+		summary += "\n\n" + diff
+	}
+	return truncateText(summary, limit)
+}
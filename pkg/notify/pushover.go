@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+const (
+	// pushoverURL is the endpoint of the Pushover message API
+	pushoverURL = "https://api.pushover.net/1/messages.json"
+	// pushoverMessageLimit and pushoverTitleLimit are the most characters
+	// Pushover accepts in a message and its title
+	pushoverMessageLimit = 1024
+	pushoverTitleLimit   = 250
+	// pushoverURLLimit is the longest supplementary URL Pushover accepts
+	pushoverURLLimit = 512
+)
+
+// PushoverNotifier sends changes as Pushover push notifications. Urgent ones
+// are emergency notifications, repeated every minute for an hour until they
+// are acknowledged.
+type PushoverNotifier struct {
+	name       string
+	token      string
+	user       string
+	url        string
+	priorities Priorities
+	client     *http.Client
+}
+
+// NewPushoverNotifier creates a notifier that sends each change with the
+// application token to the user or group key user. Both may be secret
+// placeholders.
+func NewPushoverNotifier(name, token, user string) *PushoverNotifier {
+	return &PushoverNotifier{
+		name:       name,
+		token:      token,
+		user:       user,
+		url:        pushoverURL,
+		priorities: DefaultPriorities(),
+		client:     customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// WithPriorities sets the priorities of events, which are added to the
+// default ones
+func (n *PushoverNotifier) WithPriorities(priorities Priorities) *PushoverNotifier {
+	n.priorities = priorities.withDefaults()
+	return n
+}
+
+// Name implements Notifier.Name
+func (n *PushoverNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify
+func (n *PushoverNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	token, err := secret.Resolve(n.token)
+	if err != nil {
+		return fmt.Errorf("notification '%s': token: %w", n.name, err)
+	}
+	user, err := secret.Resolve(n.user)
+	if err != nil {
+		return fmt.Errorf("notification '%s': user: %w", n.name, err)
+	}
+
+	// Pushover priorities go from -2 for the lowest to 2 for emergencies
+	priority := n.priorities.of(change) - PriorityNormal
+	form := url.Values{
+		"token":    {token},
+		"user":     {user},
+		"title":    {truncateText(pushTitle(change), pushoverTitleLimit)},
+		"message":  {pushMessage(change, pushoverMessageLimit)},
+		"priority": {strconv.Itoa(int(priority))},
+	}
+	if priority == PriorityUrgent-PriorityNormal {
+		form.Set("retry", "60")
+		form.Set("expire", "3600")
+	}
+	if len(change.URL) <= pushoverURLLimit {
+		form.Set("url", change.URL)
+	}
+
+	return post(ctx, n.client, n.name, n.url, "application/x-www-form-urlencoded", []byte(form.Encode()))
+}
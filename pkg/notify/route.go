@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/schedule"
+)
+
+// Severity ranks events for routing, see Rule.MinSeverity
+type Severity int
+
+// Severities, from the least to the most severe
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityCritical: "critical",
+}
+
+// ParseSeverity parses info, warning or critical
+func ParseSeverity(name string) (Severity, error) {
+	for severity, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity '%s': must be info, warning or critical", name)
+}
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// SeverityOf returns the severity of a change: errors and passed deadlines
+// are critical; content changes, met conditions and composites, and URLs
+// robots.txt disallows are warnings; other events, such as recoveries, are
+// info
+func SeverityOf(change monitor.Change) Severity {
+	switch eventType(change) {
+	case monitor.EventError, monitor.EventDeadlinePassed:
+		return SeverityCritical
+	case monitor.EventChange, monitor.EventConditionMet, monitor.EventComposite, monitor.EventDisallowed:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// Rule selects the changes sent to a notifier. A change must meet every
+// condition that is set.
+type Rule struct {
+	// Events limits the event types, e.g. to errors only
+	Events []monitor.EventType
+	// Match must match the details of the change
+	Match *regexp.Regexp
+	// QuietHours are windows in which nothing is sent, by the time of the
+	// change
+	QuietHours schedule.Windows
+	// MinSeverity is the least severity sent, see SeverityOf
+	MinSeverity Severity
+}
+
+// Allow reports whether a change meets the conditions of the rule
+func (r Rule) Allow(change monitor.Change) bool {
+	if len(r.Events) > 0 && !slices.Contains(r.Events, eventType(change)) {
+		return false
+	}
+	if r.Match != nil && !r.Match.MatchString(change.Details) {
+		return false
+	}
+	if SeverityOf(change) < r.MinSeverity {
+		return false
+	}
+	if len(r.QuietHours) > 0 {
+		at := change.Timestamp
+		if at.IsZero() {
+			at = time.Now()
+		}
+		for _, window := range r.QuietHours {
+			if window.Contains(at) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Route wraps a notifier so it is only sent the changes that at least one of
+// the rules allows. With no rules every change is sent.
+func Route(notifier Notifier, rules ...Rule) Notifier {
+	if len(rules) == 0 {
+		return notifier
+	}
+	return &router{Notifier: notifier, rules: rules}
+}
+
+// router drops the changes no rule of a notifier allows
+type router struct {
+	Notifier
+	rules []Rule
+}
+
+// Notify implements Notifier.Notify
+func (r *router) Notify(ctx context.Context, change monitor.Change) error {
+	for _, rule := range r.rules {
+		if rule.Allow(change) {
+			return r.Notifier.Notify(ctx, change)
+		}
+	}
+	return nil
+}
+
+// Unwrap returns the routed notifier
+func (r *router) Unwrap() Notifier {
+	return r.Notifier
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+const (
+	// slackMessageLimit is the length of the text of a message. Slack accepts
+	// up to 40,000 characters but shortens messages over 4,000 when showing
+	// them, so diffs are split well below that.
+	slackMessageLimit = 3500
+	// slackMaxMessages is the most messages a change is split into, so a
+	// huge diff doesn't flood a channel
+	slackMaxMessages = 5
+)
+
+// SlackNotifier posts changes to a Slack incoming webhook. Diffs too long for
+// one message are split at line breaks into several.
+type SlackNotifier struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewSlackNotifier creates a notifier that posts each change to the Slack
+// incoming webhook url
+func NewSlackNotifier(name, url string) *SlackNotifier {
+	return &SlackNotifier{
+		name:   name,
+		url:    url,
+		client: customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// Name implements Notifier.Name
+func (n *SlackNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify
+func (n *SlackNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	for _, text := range slackMessages(change) {
+		body, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return err
+		}
+		if err := post(ctx, n.client, n.name, n.url, "application/json", body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slackMessages formats a change as one message, or several if its diff
+// doesn't fit. Parts beyond slackMaxMessages are left out with a note.
+func slackMessages(change monitor.Change) []string {
+	summary, diff := message(change)
+	summary = truncateText(summary, slackMessageLimit/2)
+	if diff == "" {
+		return []string{slackEscape(summary)}
+	}
+
+	// Room is left for the summary, the code block and the part numbers
+	parts := splitText(diff, slackMessageLimit-textLength(summary)-100)
+	omitted := ""
+	if len(parts) > slackMaxMessages {
+		rest := strings.Join(parts[slackMaxMessages:], "")
+		omitted = fmt.Sprintf("\n… %d more lines of the diff were left out", strings.Count(rest, "\n")+1)
+		parts = parts[:slackMaxMessages]
+	}
+
+	messages := make([]string, len(parts))
+	for i, part := range parts {
+		var b strings.Builder
+		if i == 0 {
+			b.WriteString(slackEscape(summary))
+		}
+		if len(parts) > 1 {
+			fmt.Fprintf(&b, " (%d/%d)", i+1, len(parts))
+		}
+		b.WriteString("\n```\n" + slackEscape(strings.TrimSuffix(part, "\n")) + "\n```")
+		if i == len(parts)-1 {
+			b.WriteString(omitted)
+		}
+		messages[i] = strings.TrimPrefix(b.String(), " ")
+	}
+	return messages
+}
+
+// slackEscape escapes the characters Slack treats as markup. Escaping makes
+// text longer, but stays far below Slack's hard limit.
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
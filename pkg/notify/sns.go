@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// snsMaxMessage is the largest message SNS accepts
+const snsMaxMessage = 256 * 1024
+
+// SNSNotifier publishes changes as JSON to an Amazon SNS topic, e.g. for
+// Lambda functions or SQS queues subscribed to it. The event type, the URL
+// and the baggage of each change are message attributes, so subscriptions
+// can filter on them.
+type SNSNotifier struct {
+	name        string
+	topicARN    string
+	region      string
+	credentials *awsCredentialChain
+	client      *http.Client
+}
+
+// NewSNSNotifier creates a notifier that publishes each change to the topic
+// with the given ARN, such as arn:aws:sns:eu-west-1:123456789012:changes.
+// Credentials are found like the AWS SDKs do: from the environment, the
+// shared credentials file, or the role of the container or instance.
+func NewSNSNotifier(name, topicARN string) *SNSNotifier {
+	region := ""
+	if parts := strings.Split(topicARN, ":"); len(parts) == 6 {
+		region = parts[3]
+	}
+	return &SNSNotifier{
+		name:        name,
+		topicARN:    topicARN,
+		region:      region,
+		credentials: newAWSCredentialChain(),
+		client:      customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10}),
+	}
+}
+
+// Name implements Notifier.Name
+func (n *SNSNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify. Like for webhooks, the complete diff
+// is left out of the message. FIFO topics receive the changes of each URL
+// in order.
+func (n *SNSNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	if err := offline.Check(fmt.Sprintf("sns '%s'", n.name)); err != nil {
+		return err
+	}
+	if n.region == "" {
+		return fmt.Errorf("sns '%s': invalid topic ARN '%s'", n.name, n.topicARN)
+	}
+
+	change.Hunks = nil
+	message, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if len(message) > snsMaxMessage {
+		return fmt.Errorf("sns '%s': message of %d bytes is larger than SNS accepts, limit the details with --max-details-bytes", n.name, len(message))
+	}
+
+	form := url.Values{
+		"Action":   {"Publish"},
+		"Version":  {"2010-03-31"},
+		"TopicArn": {n.topicARN},
+		"Message":  {string(message)},
+		"Subject":  {snsSubject(change)},
+	}
+	for i, attribute := range messageAttributes(change) {
+		prefix := fmt.Sprintf("MessageAttributes.entry.%d.", i+1)
+		form.Set(prefix+"Name", attribute[0])
+		form.Set(prefix+"Value.DataType", "String")
+		form.Set(prefix+"Value.StringValue", attribute[1])
+	}
+	if strings.HasSuffix(n.topicARN, ".fifo") {
+		group := sha256.Sum256([]byte(change.URL))
+		form.Set("MessageGroupId", hex.EncodeToString(group[:]))
+		dedup := sha256.Sum256(message)
+		form.Set("MessageDeduplicationId", hex.EncodeToString(dedup[:]))
+	}
+
+	creds, err := n.credentials.retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("sns '%s': %w", n.name, err)
+	}
+	body := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, awsEndpoint("sns", n.region), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set("User-Agent", version.UserAgent())
+	signAWS(req, body, creds, "sns", n.region, time.Now())
+
+	if err := awsQuery(n.client, req, nil); err != nil {
+		// Credentials that were rotated or revoked are looked up again
+		n.credentials.reset()
+		return fmt.Errorf("sns '%s': %w", n.name, err)
+	}
+	return nil
+}
+
+// snsSubject returns the subject of a message, used by email subscriptions.
+// SNS only accepts printable ASCII subjects of up to 100 characters.
+func snsSubject(change monitor.Change) string {
+	summary, _ := message(change)
+	subject := strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			return ' '
+		case r < ' ' || r > '~':
+			return '?'
+		}
+		return r
+	}, summary)
+	if len(subject) > 100 {
+		subject = subject[:97] + "..."
+	}
+	return subject
+}
+
+// messageAttributes returns the attributes of the message of a change that
+// subscriptions can filter on
+func messageAttributes(change monitor.Change) [][2]string {
+	attributes := [][2]string{
+		{"event_type", string(eventType(change))},
+		{"url", change.URL},
+	}
+	if len(change.Baggage) > 0 {
+		attributes = append(attributes, [2]string{"baggage", monitor.FormatBaggage(change.Baggage)})
+	}
+	return attributes
+}
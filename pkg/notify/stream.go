@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// Format is the serialization of changes published to Kafka or NATS
+type Format string
+
+// Formats of published changes
+const (
+	// FormatJSON publishes changes as JSON, like webhooks
+	FormatJSON Format = "json"
+	// FormatAvro publishes changes in the Avro binary encoding of
+	// ChangeSchema
+	FormatAvro Format = "avro"
+)
+
+// ParseFormat parses the name of a format. An empty name is FormatJSON.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", string(FormatJSON):
+		return FormatJSON, nil
+	case string(FormatAvro):
+		return FormatAvro, nil
+	default:
+		return "", fmt.Errorf("unknown format '%s' (expected json or avro)", name)
+	}
+}
+
+// ContentType returns the content type of messages in the format
+func (f Format) ContentType() string {
+	if f == FormatAvro {
+		return "avro/binary"
+	}
+	return "application/json"
+}
+
+// streamEncoder serializes the changes published to a topic. With a schema
+// registry, Avro messages are prefixed with the ID of ChangeSchema in the
+// Confluent wire format, so consumers can look the schema up.
+type streamEncoder struct {
+	format   Format
+	topic    string
+	registry string
+	client   *http.Client
+
+	mu       sync.Mutex
+	schemaID int32
+}
+
+// newStreamEncoder creates an encoder of changes published to topic.
+// registry is the URL of a Confluent-compatible schema registry, or empty.
+func newStreamEncoder(format Format, topic, registry string) *streamEncoder {
+	return &streamEncoder{
+		format:   format,
+		topic:    topic,
+		registry: strings.TrimSuffix(registry, "/"),
+		client:   customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// encode serializes a change
+func (e *streamEncoder) encode(ctx context.Context, change monitor.Change) ([]byte, error) {
+	if e.format != FormatAvro {
+		return json.Marshal(change)
+	}
+
+	payload := encodeAvro(change)
+	if e.registry == "" {
+		return payload, nil
+	}
+	id, err := e.registerSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The magic byte 0 and the schema ID come first
+	message := binary.BigEndian.AppendUint32([]byte{0}, uint32(id))
+	return append(message, payload...), nil
+}
+
+// registerSchema registers ChangeSchema as the value schema of the topic
+// and returns its ID. Registering a known schema returns its existing ID;
+// the ID is kept once it is known.
+func (e *streamEncoder) registerSchema(ctx context.Context) (int32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.schemaID != 0 {
+		return e.schemaID, nil
+	}
+
+	registry, err := secret.Resolve(e.registry)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry: %w", err)
+	}
+	body, err := json.Marshal(map[string]string{"schema": ChangeSchema})
+	if err != nil {
+		return 0, err
+	}
+	url := fmt.Sprintf("%s/subjects/%s-value/versions", registry, e.topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("schema registry returned status code %d", resp.StatusCode)
+	}
+
+	var registered struct {
+		ID int32 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err != nil {
+		return 0, fmt.Errorf("schema registry: %w", err)
+	}
+	e.schemaID = registered.ID
+	return e.schemaID, nil
+}
@@ -0,0 +1,340 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+// streamMessage is a message received by a fake Kafka or NATS server
+type streamMessage struct {
+	partition int32
+	topic     string
+	key       string
+	value     []byte
+	headers   map[string]string
+}
+
+// fakeKafka starts a single Kafka broker with a topic of two partitions and
+// returns its address and the messages produced to it
+func fakeKafka(t *testing.T, topic string) (string, <-chan streamMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	host, portString, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portString)
+
+	messages := make(chan streamMessage, 10)
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			request := make([]byte, binary.BigEndian.Uint32(size[:]))
+			if _, err := io.ReadFull(conn, request); err != nil {
+				return
+			}
+			r := &kafkaReader{buf: request}
+			apiKey, _, correlation := r.int16(), r.int16(), r.int32()
+			r.string() // Client ID
+
+			var w kafkaWriter
+			w.int32(0)
+			w.int32(correlation)
+			switch apiKey {
+			case kafkaMetadata:
+				r.int32()
+				requested := r.string()
+				w.int32(1)
+				w.int32(1)
+				w.string(host)
+				w.int32(int32(port))
+				w.int16(-1) // No rack
+				w.int32(1)  // Controller
+				w.int32(1)
+				if requested != topic {
+					w.int16(3)
+					w.string(requested)
+					w.buf = append(w.buf, 0)
+					w.int32(0)
+					break
+				}
+				w.int16(0)
+				w.string(topic)
+				w.buf = append(w.buf, 0)
+				w.int32(2)
+				for partition := int32(1); partition >= 0; partition-- {
+					w.int16(0)
+					w.int32(partition)
+					w.int32(1) // Leader
+					w.int32(1)
+					w.int32(1)
+					w.int32(1)
+					w.int32(1)
+				}
+			case kafkaProduce:
+				r.int16()
+				require.Equal(t, int16(1), r.int16(), "acks")
+				r.int32()
+				r.int32()
+				message := streamMessage{topic: r.string(), headers: make(map[string]string)}
+				r.int32()
+				message.partition = r.int32()
+				batch := &kafkaReader{buf: r.bytes()}
+				require.NoError(t, r.err)
+
+				batch.int64()
+				require.Equal(t, int(batch.int32()), len(batch.buf))
+				batch.int32()
+				require.Equal(t, []byte{2}, batch.next(1), "magic")
+				checksum := uint32(batch.int32())
+				require.Equal(t, crc32.Checksum(batch.buf, crc32.MakeTable(crc32.Castagnoli)), checksum)
+				batch.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+				require.Equal(t, int32(1), batch.int32(), "records")
+
+				buf := batch.buf
+				varint := func() int64 {
+					v, n := binary.Varint(buf)
+					buf = buf[n:]
+					return v
+				}
+				take := func() string {
+					n := varint()
+					s := string(buf[:n])
+					buf = buf[n:]
+					return s
+				}
+				varint()
+				buf = buf[1:]
+				varint()
+				varint()
+				message.key = take()
+				message.value = []byte(take())
+				for headers := varint(); headers > 0; headers-- {
+					key := take()
+					message.headers[key] = take()
+				}
+				messages <- message
+
+				w.int32(1)
+				w.string(message.topic)
+				w.int32(1)
+				w.int32(message.partition)
+				w.int16(0)
+				w.int64(0)
+				w.int64(-1)
+				w.int32(0)
+			}
+			binary.BigEndian.PutUint32(w.buf, uint32(len(w.buf)-4))
+			if _, err := conn.Write(w.buf); err != nil {
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestKafkaNotifier(t *testing.T) {
+	addr, messages := fakeKafka(t, "changes")
+	notifier := NewKafkaNotifier("stream", []string{"127.0.0.1:1", addr}, "changes", FormatJSON)
+	require.Equal(t, "stream", notifier.Name())
+
+	change := monitor.Change{URL: "https://example.com", HasChanged: true, Details: "summary", Baggage: map[string]string{"tenant": "acme"}}
+	require.NoError(t, notifier.Notify(context.Background(), change))
+	message := <-messages
+	require.Equal(t, "changes", message.topic)
+	require.Equal(t, "https://example.com", message.key)
+	require.Equal(t, "application/json", message.headers["content-type"])
+	require.Equal(t, "tenant=acme", message.headers[monitor.BaggageHeader])
+	var received monitor.Change
+	require.NoError(t, json.Unmarshal(message.value, &received))
+	require.Equal(t, "summary", received.Details)
+
+	// The changes of a URL go to the same partition
+	change.Details = "again"
+	require.NoError(t, notifier.Notify(context.Background(), change))
+	require.Equal(t, message.partition, (<-messages).partition)
+
+	notifier = NewKafkaNotifier("stream", []string{addr}, "missing", FormatJSON)
+	err := notifier.Notify(context.Background(), change)
+	require.ErrorContains(t, err, "kafka 'stream': topic 'missing': broker error 3 (UNKNOWN_TOPIC_OR_PARTITION)")
+}
+
+// fakeNATS starts a NATS server that denies publishing to "denied" and
+// returns its address, the options clients connected with and the messages
+// published to it
+func fakeNATS(t *testing.T) (string, <-chan map[string]any, <-chan streamMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	connects := make(chan map[string]any, 10)
+	messages := make(chan streamMessage, 10)
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "CONNECT":
+				var options map[string]any
+				json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &options)
+				connects <- options
+			case "PING":
+				conn.Write([]byte("PONG\r\n"))
+			case "PUB":
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				io.ReadFull(r, payload)
+				if fields[1] == "denied" {
+					conn.Write([]byte("-ERR 'Permissions Violation for Publish to \"denied\"'\r\n"))
+					continue
+				}
+				messages <- streamMessage{topic: fields[1], value: payload[:size]}
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String(), connects, messages
+}
+
+func TestNATSNotifier(t *testing.T) {
+	addr, connects, messages := fakeNATS(t)
+	notifier := NewNATSNotifier("stream", "nats://hawkeye:secret@"+addr, "hawkeye.changes", FormatJSON)
+	require.Equal(t, "stream", notifier.Name())
+
+	change := monitor.Change{URL: "https://example.com", HasChanged: true, Details: "line 1\r\nline 2"}
+	require.NoError(t, notifier.Notify(context.Background(), change))
+	options := <-connects
+	require.Equal(t, "hawkeye", options["user"])
+	require.Equal(t, "secret", options["pass"])
+	message := <-messages
+	require.Equal(t, "hawkeye.changes", message.topic)
+	var received monitor.Change
+	require.NoError(t, json.Unmarshal(message.value, &received))
+	require.Equal(t, "line 1\r\nline 2", received.Details)
+
+	// The connection is kept
+	require.NoError(t, notifier.Notify(context.Background(), change))
+	<-messages
+	require.Empty(t, connects)
+
+	notifier = NewNATSNotifier("stream", "nats://token@"+addr, "denied", FormatJSON)
+	err := notifier.Notify(context.Background(), change)
+	require.EqualError(t, err, `nats 'stream': Permissions Violation for Publish to "denied"`)
+	require.Equal(t, "token", (<-connects)["auth_token"])
+}
+
+func TestAvroFormat(t *testing.T) {
+	change := monitor.Change{
+		URL:        "https://example.com",
+		Event:      monitor.EventChange,
+		Timestamp:  time.UnixMilli(1718000000000),
+		HasChanged: true,
+		StatusCode: 200,
+		Latency:    time.Microsecond * 1500,
+		Baggage:    map[string]string{"tenant": "acme"},
+		Hunks:      []monitor.DiffHunk{{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []monitor.DiffLine{{Type: "added", Text: "new"}}}},
+	}
+	buf := encodeAvro(change)
+	varint := func() int64 {
+		v, n := binary.Varint(buf)
+		buf = buf[n:]
+		return v
+	}
+	take := func() string {
+		n := varint()
+		s := string(buf[:n])
+		buf = buf[n:]
+		return s
+	}
+	require.Equal(t, "https://example.com", take())
+	require.Equal(t, "change", take())
+	require.Equal(t, int64(1718000000000), varint())
+	require.Equal(t, byte(1), buf[0])
+	buf = buf[1:]
+	require.Equal(t, int64(200), varint())
+	for range 4 {
+		require.Empty(t, take())
+	}
+	require.Equal(t, uint64(0x3ff8000000000000), binary.LittleEndian.Uint64(buf), "1.5 ms")
+	buf = buf[8:]
+	take()
+	take()
+	varint()
+	varint()
+	buf = buf[1:]
+	require.Equal(t, int64(1), varint())
+	require.Equal(t, "tenant", take())
+	require.Equal(t, "acme", take())
+	require.Zero(t, varint())
+	require.Equal(t, int64(1), varint())
+	for range 4 {
+		require.Equal(t, int64(1), varint())
+	}
+	require.Equal(t, int64(1), varint())
+	require.Equal(t, "added", take())
+	require.Equal(t, "new", take())
+	require.Equal(t, []byte{0, 0}, buf)
+
+	var registrations atomic.Int64
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registrations.Add(1)
+		require.Equal(t, "/subjects/changes-value/versions", r.URL.Path)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.JSONEq(t, ChangeSchema, body["schema"])
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer registry.Close()
+
+	encoder := newStreamEncoder(FormatAvro, "changes", registry.URL)
+	for range 2 {
+		message, err := encoder.encode(context.Background(), change)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0, 0, 0, 7}, message[:5])
+		require.Equal(t, encodeAvro(change), message[5:])
+	}
+	require.Equal(t, int64(1), registrations.Load())
+
+	_, err := ParseFormat("protobuf")
+	require.EqualError(t, err, "unknown format 'protobuf' (expected json or avro)")
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// Throttle wraps a notifier so it is sent at most one change per URL every
+// interval. Changes in between are dropped with an error wrapping
+// ErrThrottled. A zero interval sends every change.
+func Throttle(notifier Notifier, interval time.Duration) Notifier {
+	if interval <= 0 {
+		return notifier
+	}
+	return &throttler{Notifier: notifier, interval: interval, now: time.Now, last: make(map[string]time.Time)}
+}
+
+// throttler drops the changes of a URL that come too soon after the last one
+// sent
+type throttler struct {
+	Notifier
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// Notify implements Notifier.Notify
+func (t *throttler) Notify(ctx context.Context, change monitor.Change) error {
+	t.mu.Lock()
+	now := t.now()
+	last, ok := t.last[change.URL]
+	if ok && now.Sub(last) < t.interval {
+		t.mu.Unlock()
+		return fmt.Errorf("notification '%s': %w, a change of %s was sent less than %s ago", t.Name(), ErrThrottled, change.URL, t.interval)
+	}
+	t.last[change.URL] = now
+	t.mu.Unlock()
+
+	return t.Notifier.Notify(ctx, change)
+}
+
+// Unwrap returns the throttled notifier
+func (t *throttler) Unwrap() Notifier {
+	return t.Notifier
+}
+
+// Deduplicate wraps a notifier so a change isn't sent again when it is the
+// same as the previous change of its URL: the same event with the same
+// details, error and diff, e.g. of a page that flips back and forth between
+// two versions of a banner.
+func Deduplicate(notifier Notifier) Notifier {
+	return &deduplicator{Notifier: notifier, last: make(map[string][sha256.Size]byte)}
+}
+
+// deduplicator drops changes identical to the previous change of their URL
+type deduplicator struct {
+	Notifier
+
+	mu   sync.Mutex
+	last map[string][sha256.Size]byte
+}
+
+// Notify implements Notifier.Notify
+func (d *deduplicator) Notify(ctx context.Context, change monitor.Change) error {
+	h := sha256.New()
+	for _, part := range []string{string(eventType(change)), change.Details, change.Error, change.Diff} {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+
+	d.mu.Lock()
+	duplicate := d.last[change.URL] == sum
+	d.last[change.URL] = sum
+	d.mu.Unlock()
+
+	if duplicate {
+		return nil
+	}
+	return d.Notifier.Notify(ctx, change)
+}
+
+// Unwrap returns the deduplicated notifier
+func (d *deduplicator) Unwrap() Notifier {
+	return d.Notifier
+}
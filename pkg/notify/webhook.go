@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+// WebhookNotifier posts changes as JSON to a URL
+type WebhookNotifier struct {
+	name    string
+	url     string
+	headers map[string]string
+	secret  string
+	client  *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that POSTs each change to url
+func NewWebhookNotifier(name, url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{
+		name:    name,
+		url:     url,
+		headers: headers,
+		client:  customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 10, FollowRedirects: true}),
+	}
+}
+
+// WithSecret signs every request with an HMAC of the payload keyed by secret,
+// so receivers can check with VerifySignature that it came from hawkeye
+func (n *WebhookNotifier) WithSecret(secret string) *WebhookNotifier {
+	n.secret = secret
+	return n
+}
+
+// Name implements Notifier.Name
+func (n *WebhookNotifier) Name() string {
+	return n.name
+}
+
+// Notify implements Notifier.Notify. The complete diff is left out of the
+// payload; the capped summary in Details is sent instead. The baggage of
+// the change is passed on in the Baggage header. Secret placeholders in the
+// URL, headers and signing secret are resolved for every request.
+func (n *WebhookNotifier) Notify(ctx context.Context, change monitor.Change) error {
+	if err := offline.Check(fmt.Sprintf("webhook '%s'", n.name)); err != nil {
+		return err
+	}
+
+	change.Hunks = nil
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	url, err := secret.Resolve(n.url)
+	if err != nil {
+		return fmt.Errorf("webhook '%s': %w", n.name, err)
+	}
+	headers, err := secret.ResolveMap(n.headers)
+	if err != nil {
+		return fmt.Errorf("webhook '%s': header %w", n.name, err)
+	}
+	key, err := secret.Resolve(n.secret)
+	if err != nil {
+		return fmt.Errorf("webhook '%s': secret: %w", n.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	customhttp.AddHeaders(req, headers, version.UserAgent())
+	if len(change.Baggage) > 0 {
+		req.Header.Set(monitor.BaggageHeader, monitor.FormatBaggage(change.Baggage))
+	}
+
+	if key != "" {
+		if err := signRequest(req, key, body, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook '%s' returned status code %d", n.name, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build offline
+
+package offline
+
+// built is set by the offline build tag
+const built = true
@@ -0,0 +1,41 @@
+// Package offline switches hawkeye to a mode without external calls, for
+// air-gapped deployments: only the monitored URLs are requested, and
+// integrations such as notifications and heartbeats are refused. Binaries
+// built with the offline build tag are always offline.
+package offline
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrOffline is returned for external calls refused in offline mode
+var ErrOffline = errors.New("external calls are disabled in offline mode")
+
+// enabled is set by Set
+var enabled atomic.Bool
+
+// Enabled reports whether hawkeye is offline
+func Enabled() bool {
+	return built || enabled.Load()
+}
+
+// Built reports whether the binary was built with the offline build tag
+func Built() bool {
+	return built
+}
+
+// Set turns offline mode on or off. Builds with the offline tag stay offline.
+func Set(on bool) {
+	enabled.Store(on)
+}
+
+// Check returns an error wrapping ErrOffline, naming the integration, if
+// hawkeye is offline
+func Check(integration string) error {
+	if Enabled() {
+		return fmt.Errorf("%s: %w", integration, ErrOffline)
+	}
+	return nil
+}
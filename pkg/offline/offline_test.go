@@ -0,0 +1,25 @@
+package offline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	defer Set(false)
+
+	require.Equal(t, built, Enabled())
+	if !built {
+		require.NoError(t, Check("webhook 'ops'"))
+	}
+
+	Set(true)
+	require.True(t, Enabled())
+	err := Check("webhook 'ops'")
+	require.ErrorIs(t, err, ErrOffline)
+	require.EqualError(t, err, "webhook 'ops': external calls are disabled in offline mode")
+
+	Set(false)
+	require.Equal(t, built, Enabled())
+}
@@ -0,0 +1,53 @@
+package optimize
+
+import "time"
+
+// Heatmap counts changes by day of the week and hour of the day, to show
+// when a page is usually updated, e.g. to pick a schedule for it
+type Heatmap [7][24]int
+
+// NewHeatmap counts the changes at the given times in loc. The first time
+// is when the page was first seen and isn't counted, like the changes
+// before since.
+func NewHeatmap(changes []time.Time, since time.Time, loc *time.Location) Heatmap {
+	var h Heatmap
+	for i := 1; i < len(changes); i++ {
+		if changes[i].Before(since) {
+			continue
+		}
+		t := changes[i].In(loc)
+		h[t.Weekday()][t.Hour()]++
+	}
+	return h
+}
+
+// Max returns the largest count of the heatmap
+func (h Heatmap) Max() int {
+	max := 0
+	for _, hours := range h {
+		for _, n := range hours {
+			if n > max {
+				max = n
+			}
+		}
+	}
+	return max
+}
+
+// Trend counts the changes after the first time in each week from since to
+// now, oldest week first, to show whether a page is updated more or less
+// often than it used to be
+func Trend(changes []time.Time, since, now time.Time) []int {
+	week := time.Hour * 24 * 7
+	if !now.After(since) {
+		return nil
+	}
+	counts := make([]int, (now.Sub(since)+week-1)/week)
+	for i := 1; i < len(changes); i++ {
+		if changes[i].Before(since) || changes[i].After(now) {
+			continue
+		}
+		counts[changes[i].Sub(since)/week]++
+	}
+	return counts
+}
@@ -0,0 +1,42 @@
+package optimize
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatmap(t *testing.T) {
+	monday := time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)
+	changes := []time.Time{
+		monday.Add(-time.Hour * 24 * 30),
+		monday.Add(-time.Hour * 24 * 14),
+		monday,
+		monday.Add(time.Minute * 10),
+		monday.Add(time.Hour * 24 * 4),
+	}
+
+	h := NewHeatmap(changes, monday.Add(-time.Hour*24*20), time.UTC)
+	require.Equal(t, 3, h[time.Monday][9])
+	require.Equal(t, 1, h[time.Friday][9])
+	require.Equal(t, 3, h.Max())
+
+	// Counted in the given location
+	h = NewHeatmap(changes, time.Time{}, time.FixedZone("UTC-10", -10*60*60))
+	require.Equal(t, 3, h[time.Sunday][23])
+}
+
+func TestTrend(t *testing.T) {
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	now := since.Add(time.Hour * 24 * 20)
+	changes := []time.Time{
+		since.Add(-time.Hour),
+		since.Add(time.Hour),
+		since.Add(time.Hour * 24 * 8),
+		since.Add(time.Hour * 24 * 9),
+		since.Add(time.Hour * 24 * 19),
+	}
+	require.Equal(t, []int{1, 2, 1}, Trend(changes, since, now))
+	require.Nil(t, Trend(changes, now, since))
+}
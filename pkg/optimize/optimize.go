@@ -0,0 +1,134 @@
+// Package optimize analyzes the history of changes of a page: it suggests
+// check intervals, e.g. checking a page that changed twice in 90 days every
+// day instead of every minute, and shows when and how often the page is
+// updated with heatmaps and trends.
+package optimize
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Intervals are the intervals that are suggested, shortest first
+var Intervals = []time.Duration{
+	time.Minute,
+	time.Minute * 5,
+	time.Minute * 15,
+	time.Minute * 30,
+	time.Hour,
+	time.Hour * 2,
+	time.Hour * 6,
+	time.Hour * 12,
+	time.Hour * 24,
+}
+
+// MinHistory is how long a page must have been watched before an interval
+// is suggested for it
+const MinHistory = time.Hour * 24 * 7
+
+// ChecksPerChange is the number of checks suggested for the typical time
+// between two changes, so that changes are caught early and not merged
+const ChecksPerChange = 4
+
+// Suggestion is the interval suggested for a monitor
+type Suggestion struct {
+	URL string
+	// Current is the interval of the monitor
+	Current time.Duration
+	// Suggested is the interval suggested, or zero if there isn't enough
+	// history to suggest one
+	Suggested time.Duration
+	// Changes is the number of changes seen in Period
+	Changes int
+	// Period is how long the page was watched, up to the analyzed period
+	Period time.Duration
+	// Reason explains the suggestion
+	Reason string
+}
+
+// Changed reports whether the suggested interval differs from the current
+// one
+func (s Suggestion) Changed() bool {
+	return s.Suggested != 0 && s.Suggested != s.Current
+}
+
+// Suggest suggests an interval for the monitor of url checked every
+// current, from the times its content changed, oldest first. The first time
+// is when the page was first seen. Only changes after since are counted.
+//
+// The suggested interval is the longest of Intervals that checks the page
+// ChecksPerChange times in the median time between its changes. Intervals
+// within a factor of two of the current one are kept, as the history is
+// too short to tell them apart.
+func Suggest(url string, current time.Duration, changes []time.Time, since, now time.Time) Suggestion {
+	s := Suggestion{URL: url, Current: current}
+	if len(changes) == 0 {
+		s.Reason = "no history of changes"
+		return s
+	}
+
+	start := changes[0]
+	if start.Before(since) {
+		start = since
+	}
+	s.Period = now.Sub(start)
+
+	var gaps []time.Duration
+	for i := 1; i < len(changes); i++ {
+		if changes[i].Before(start) {
+			continue
+		}
+		s.Changes++
+		gaps = append(gaps, changes[i].Sub(changes[i-1]))
+	}
+
+	if s.Period < MinHistory {
+		s.Reason = fmt.Sprintf("watched for %s only, at least %s are needed", days(s.Period), days(MinHistory))
+		return s
+	}
+
+	target := Intervals[len(Intervals)-1]
+	if len(gaps) > 0 {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		target = gaps[(len(gaps)-1)/2] / ChecksPerChange
+	}
+	s.Suggested = Intervals[0]
+	for _, interval := range Intervals {
+		if interval <= target {
+			s.Suggested = interval
+		}
+	}
+
+	seen := fmt.Sprintf("changed %s in %s", times(s.Changes), days(s.Period))
+	if s.Changes == 0 {
+		seen = "unchanged for " + days(s.Period)
+	}
+	switch {
+	case s.Suggested >= current*2:
+		s.Reason = fmt.Sprintf("%s; checking every %s is wasteful", seen, current)
+	case s.Suggested*2 <= current:
+		s.Reason = fmt.Sprintf("%s; checking every %s catches changes late or merges them", seen, current)
+	default:
+		s.Suggested = current
+		s.Reason = fmt.Sprintf("%s; checking every %s fits", seen, current)
+	}
+	return s
+}
+
+// days formats d as a number of days
+func days(d time.Duration) string {
+	n := int(d / (time.Hour * 24))
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// times formats a number of changes
+func times(n int) string {
+	if n == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d times", n)
+}
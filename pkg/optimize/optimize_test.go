@@ -0,0 +1,58 @@
+package optimize
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggest(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	day := time.Hour * 24
+	since := now.Add(-day * 90)
+
+	// Changed twice in 90 days: check daily
+	s := Suggest("https://example.com", time.Minute, []time.Time{now.Add(-day * 200), now.Add(-day * 60), now.Add(-day * 10)}, since, now)
+	require.True(t, s.Changed())
+	require.Equal(t, day, s.Suggested)
+	require.Equal(t, 2, s.Changes)
+	require.Equal(t, day*90, s.Period)
+	require.Equal(t, "changed 2 times in 90 days; checking every 1m0s is wasteful", s.Reason)
+
+	// Never changed
+	s = Suggest("https://example.com", time.Hour, []time.Time{now.Add(-day * 30)}, since, now)
+	require.Equal(t, day, s.Suggested)
+	require.Equal(t, "unchanged for 30 days; checking every 1h0m0s is wasteful", s.Reason)
+
+	// Changes about every hour: check every 15 minutes
+	changes := []time.Time{now.Add(-day * 10)}
+	for at := changes[0]; at.Before(now); at = at.Add(time.Hour) {
+		changes = append(changes, at.Add(time.Hour))
+	}
+	s = Suggest("https://example.com", time.Hour*6, changes, since, now)
+	require.Equal(t, time.Minute*15, s.Suggested)
+	require.Contains(t, s.Reason, "catches changes late")
+
+	// Close enough
+	s = Suggest("https://example.com", time.Minute*20, changes, since, now)
+	require.False(t, s.Changed())
+	require.Equal(t, time.Minute*20, s.Suggested)
+	require.Contains(t, s.Reason, "fits")
+
+	// Changes faster than the shortest interval
+	s = Suggest("https://example.com", time.Hour, []time.Time{now.Add(-day * 8), now.Add(-time.Minute * 2), now.Add(-time.Minute)}, since, now)
+	require.Equal(t, time.Minute, s.Suggested)
+}
+
+func TestSuggestShortHistory(t *testing.T) {
+	now := time.Now()
+	s := Suggest("https://example.com", time.Minute, []time.Time{now.Add(-time.Hour * 24 * 3)}, now.Add(-time.Hour*24*90), now)
+	require.False(t, s.Changed())
+	require.Zero(t, s.Suggested)
+	require.Equal(t, "watched for 3 days only, at least 7 days are needed", s.Reason)
+
+	s = Suggest("https://example.com", time.Minute, nil, now.Add(-time.Hour*24*90), now)
+	require.False(t, s.Changed())
+	require.Equal(t, "no history of changes", s.Reason)
+}
@@ -0,0 +1,210 @@
+// Package recorder records the HTTP sessions of monitors to cassette files
+// and replays them, so change detection can be reproduced offline when
+// debugging why a monitor did or didn't alert.
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Body encodings
+const (
+	EncodingText   = ""
+	EncodingBase64 = "base64"
+)
+
+// ErrEmptyCassette is returned when a cassette holds no interactions
+var ErrEmptyCassette = errors.New("cassette has no recorded interactions")
+
+// ErrBodyOmitted is returned for the body of a response recorded without it
+var ErrBodyOmitted = errors.New("response body was not recorded")
+
+// RecordedRequest is the recorded part of a request
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// RecordedResponse is the recorded part of a response
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+	BodyOmitted  bool        `json:"body_omitted,omitempty"`
+}
+
+// Interaction is a single recorded request/response pair
+type Interaction struct {
+	RecordedAt time.Time         `json:"recorded_at"`
+	Duration   time.Duration     `json:"duration"`
+	Request    RecordedRequest   `json:"request"`
+	Response   *RecordedResponse `json:"response,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Cassette is the recorded session of a single monitor
+type Cassette struct {
+	Path         string
+	Interactions []Interaction
+}
+
+// encodeBody stores body as text when possible, and as base64 otherwise
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), EncodingText
+	}
+	return base64.StdEncoding.EncodeToString(body), EncodingBase64
+}
+
+// BodyBytes returns the decoded response body
+func (r *RecordedResponse) BodyBytes() ([]byte, error) {
+	if r.BodyOmitted {
+		return nil, ErrBodyOmitted
+	}
+	switch r.BodyEncoding {
+	case EncodingText:
+		return []byte(r.Body), nil
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(r.Body)
+	default:
+		return nil, fmt.Errorf("unknown body encoding '%s'", r.BodyEncoding)
+	}
+}
+
+// unsafeChars matches characters that are replaced in cassette file names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CassettePath returns the path of the cassette for rawURL inside dir. The
+// name is readable but shortened, with a hash of the full URL for uniqueness.
+func CassettePath(dir, rawURL string) string {
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+
+	name = strings.Trim(unsafeChars.ReplaceAllString(name, "_"), "_")
+	if len(name) > 60 {
+		name = name[:60]
+	}
+
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.jsonl", name, hex.EncodeToString(sum[:4])))
+}
+
+// LoadCassette reads a cassette file. Each line holds one JSON interaction.
+func LoadCassette(path string) (*Cassette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cassette := &Cassette{Path: path}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		cassette.Interactions = append(cassette.Interactions, interaction)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(cassette.Interactions) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrEmptyCassette)
+	}
+
+	return cassette, nil
+}
+
+// URL returns the URL recorded in the cassette
+func (c *Cassette) URL() string {
+	if len(c.Interactions) == 0 {
+		return ""
+	}
+	return c.Interactions[0].Request.URL
+}
+
+// NewResponse records a response with body
+func NewResponse(statusCode int, header http.Header, body []byte) *RecordedResponse {
+	encoded, encoding := encodeBody(body)
+	return &RecordedResponse{
+		StatusCode:   statusCode,
+		Header:       header,
+		Body:         encoded,
+		BodyEncoding: encoding,
+	}
+}
+
+// Merge adds interactions to those of a cassette, in the order they were
+// recorded. Added interactions recorded at the same time as one already
+// present are skipped, so the same interactions can be merged again.
+func Merge(existing, added []Interaction) []Interaction {
+	seen := make(map[time.Time]bool, len(existing))
+	for _, interaction := range existing {
+		seen[interaction.RecordedAt.UTC()] = true
+	}
+
+	merged := append([]Interaction(nil), existing...)
+	for _, interaction := range added {
+		if !seen[interaction.RecordedAt.UTC()] {
+			seen[interaction.RecordedAt.UTC()] = true
+			merged = append(merged, interaction)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].RecordedAt.Before(merged[j].RecordedAt)
+	})
+	return merged
+}
+
+// SaveCassette replaces the cassette at path with interactions. Like those
+// written by a Recorder, the file can only be read by its owner.
+func SaveCassette(path string, interactions []Interaction) error {
+	var buf bytes.Buffer
+	for _, interaction := range interactions {
+		data, err := json.Marshal(interaction)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	// Write to a temporary file first so a failed write doesn't lose the
+	// recorded interactions
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,195 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/secret"
+)
+
+// Redacted replaces the values of headers and query parameters carrying
+// credentials in cassettes
+const Redacted = "[redacted]"
+
+// redactedHeaders are the canonical names of the headers whose values are
+// always redacted
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// Recorder is an http.RoundTripper that records every request/response pair
+// it forwards to a cassette file. The values of headers and query parameters
+// carrying credentials are redacted, see WithRedacted. Response bodies are
+// only recorded with WithBodies, as those of login and token endpoints hold
+// credentials too.
+type Recorder struct {
+	transport http.RoundTripper
+	path      string
+	redacted  map[string]bool
+	bodies    bool
+	onError   func(error)
+	mu        sync.Mutex
+}
+
+// NewRecorder creates a recorder that appends interactions to the cassette at
+// path. If transport is nil http.DefaultTransport is used.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{
+		transport: transport,
+		path:      path,
+	}
+}
+
+// WithRedacted redacts the values of more headers, such as those set from
+// secret placeholders
+func (r *Recorder) WithRedacted(names ...string) *Recorder {
+	r.redacted = make(map[string]bool, len(names))
+	for _, name := range names {
+		r.redacted[http.CanonicalHeaderKey(name)] = true
+	}
+	return r
+}
+
+// WithBodies records response bodies, which replaying a cassette needs
+func (r *Recorder) WithBodies() *Recorder {
+	r.bodies = true
+	return r
+}
+
+// WithOnError sets a function called when an interaction can't be recorded.
+// Recording is best effort and never fails the request.
+func (r *Recorder) WithOnError(fn func(error)) *Recorder {
+	r.onError = fn
+	return r
+}
+
+// Path returns the cassette path
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	interaction := Interaction{
+		RecordedAt: start,
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: r.redact(req.Header),
+		},
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		interaction.Duration = time.Since(start)
+		interaction.Error = err.Error()
+		r.save(interaction)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	interaction.Duration = time.Since(start)
+	if err != nil {
+		interaction.Error = err.Error()
+		r.save(interaction)
+		return nil, err
+	}
+
+	interaction.Response = &RecordedResponse{
+		StatusCode:  resp.StatusCode,
+		Header:      r.redact(resp.Header),
+		BodyOmitted: !r.bodies,
+	}
+	if r.bodies {
+		interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(body)
+	}
+	r.save(interaction)
+
+	// Hand the caller a fresh reader over the body we consumed
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// redact returns a copy of header with the values of the headers carrying
+// credentials redacted
+func (r *Recorder) redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name, values := range redacted {
+		canonical := http.CanonicalHeaderKey(name)
+		if redactedHeaders[canonical] || r.redacted[canonical] {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	return redacted
+}
+
+// redactURL returns u with the password and the values of the query
+// parameters carrying credentials redacted. The order of the parameters is
+// kept.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if _, ok := u.User.Password(); ok {
+		redacted.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil && secret.IsSecretParam(unescaped) {
+				params[i] = name + "=" + Redacted
+			}
+		}
+		redacted.RawQuery = strings.Join(params, "&")
+	}
+	return redacted.String()
+}
+
+// save appends an interaction to the cassette file, which only its owner
+// can read. Failures are passed to the function set with WithOnError.
+func (r *Recorder) save(interaction Interaction) {
+	if err := r.write(interaction); err != nil && r.onError != nil {
+		r.onError(fmt.Errorf("recording interaction: %w", err))
+	}
+}
+
+// write appends an interaction to the cassette file
+func (r *Recorder) write(interaction Interaction) error {
+	data, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
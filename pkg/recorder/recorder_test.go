@@ -0,0 +1,214 @@
+package recorder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassettePath(t *testing.T) {
+	path := CassettePath("/tmp/cassettes", "https://example.com/news?page=1")
+	require.Equal(t, "/tmp/cassettes", filepath.Dir(path))
+	require.True(t, strings.HasPrefix(filepath.Base(path), "example.com_news-"))
+	require.True(t, strings.HasSuffix(path, ".jsonl"))
+
+	// Different URLs with the same readable name get different files
+	require.NotEqual(t, path, CassettePath("/tmp/cassettes", "https://example.com/news?page=2"))
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	responses := []string{"version 1", "version 1", "version 2"}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls >= len(responses) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(responses[calls]))
+		calls++
+	}))
+	defer server.Close()
+
+	path := CassettePath(t.TempDir(), server.URL)
+
+	// Record a session
+	config := monitor.DefaultConfig(server.URL)
+	config.RetryCount = 0
+	config.Transport = NewRecorder(path, nil).WithBodies()
+	m := monitor.NewMonitorWithConfig(config)
+	for i := 0; i < 4; i++ {
+		m.Check()
+	}
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 4)
+	require.Equal(t, server.URL, cassette.URL())
+	require.Equal(t, "version 2", cassette.Interactions[2].Response.Body)
+	require.Equal(t, http.StatusServiceUnavailable, cassette.Interactions[3].Response.StatusCode)
+
+	// Replay it through detection
+	steps, err := Replay(cassette, monitor.DefaultConfig(""))
+	require.NoError(t, err)
+	require.Len(t, steps, 4)
+	require.False(t, steps[0].Change.HasChanged)
+	require.False(t, steps[1].Change.HasChanged)
+	require.True(t, steps[2].Change.HasChanged)
+	require.Equal(t, cassette.Interactions[2].RecordedAt, steps[2].Change.Timestamp)
+	require.Contains(t, steps[3].Change.Error, "503")
+
+	// Replaying with a length comparison ignores same-length edits
+	lengthConfig := monitor.DefaultConfig("")
+	lengthConfig.Method = monitor.MethodLength
+	steps, err = Replay(cassette, lengthConfig)
+	require.NoError(t, err)
+	require.False(t, steps[2].Change.HasChanged)
+}
+
+func TestRecorderRecordsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+
+	config := monitor.DefaultConfig("http://127.0.0.1:1/")
+	config.RetryCount = 0
+	config.Timeout = time.Second
+	config.Transport = NewRecorder(path, nil)
+	change := monitor.NewMonitorWithConfig(config).Check()
+	require.NotEmpty(t, change.Error)
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 1)
+	require.NotEmpty(t, cassette.Interactions[0].Error)
+	require.Nil(t, cassette.Interactions[0].Response)
+
+	steps, err := Replay(cassette, monitor.DefaultConfig(""))
+	require.NoError(t, err)
+	require.Contains(t, steps[0].Change.Error, "recorded error")
+}
+
+func TestRecorderRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3ss10n"})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	t.Setenv("HAWKEYE_TEST_API_KEY", "k3y")
+	path := filepath.Join(t.TempDir(), "secrets.jsonl")
+	config := monitor.DefaultConfig(server.URL + "/feed?page=2&api_key=4p1k3y&Access-Token=4cc355")
+	config.BearerToken = "t0k3n"
+	config.Headers = map[string]string{"X-Api-Key": "${HAWKEYE_TEST_API_KEY}", "Accept-Language": "en"}
+	config.Transport = NewRecorder(path, nil).WithRedacted("x-api-key")
+	require.Empty(t, monitor.NewMonitorWithConfig(config).Check().Error)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{"t0k3n", "k3y", "s3ss10n", "4cc355"} {
+		require.NotContains(t, string(data), secret)
+	}
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	interaction := cassette.Interactions[0]
+	require.Equal(t, Redacted, interaction.Request.Header.Get("Authorization"))
+	require.Equal(t, Redacted, interaction.Request.Header.Get("X-Api-Key"))
+	require.Equal(t, "en", interaction.Request.Header.Get("Accept-Language"))
+	require.Equal(t, Redacted, interaction.Response.Header.Get("Set-Cookie"))
+	require.Equal(t, server.URL+"/feed?page=2&api_key=[redacted]&Access-Token=[redacted]", interaction.Request.URL)
+	require.True(t, interaction.Response.BodyOmitted)
+	require.Empty(t, interaction.Response.Body)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestRecorderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var errs []error
+	config := monitor.DefaultConfig(server.URL)
+	config.Transport = NewRecorder(filepath.Join(t.TempDir(), "missing", "cassette.jsonl"), nil).
+		WithOnError(func(err error) { errs = append(errs, err) })
+
+	// Failing to record doesn't fail the check
+	require.Empty(t, monitor.NewMonitorWithConfig(config).Check().Error)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], os.ErrNotExist)
+}
+
+func TestReplayWithoutBodies(t *testing.T) {
+	cassette := &Cassette{Interactions: []Interaction{{
+		Request:  RecordedRequest{Method: http.MethodGet, URL: "https://example.com"},
+		Response: &RecordedResponse{StatusCode: http.StatusOK, BodyOmitted: true},
+	}}}
+	_, err := Replay(cassette, monitor.DefaultConfig(""))
+	require.ErrorIs(t, err, ErrBodyOmitted)
+}
+
+func TestBinaryBodies(t *testing.T) {
+	body := []byte{0xff, 0xfe, 0x00, 0x01}
+	encoded, encoding := encodeBody(body)
+	require.Equal(t, EncodingBase64, encoding)
+
+	decoded, err := (&RecordedResponse{Body: encoded, BodyEncoding: encoding}).BodyBytes()
+	require.NoError(t, err)
+	require.Equal(t, body, decoded)
+}
+
+func TestLoadCassetteErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "empty.jsonl")
+	require.NoError(t, os.WriteFile(empty, nil, 0644))
+	_, err := LoadCassette(empty)
+	require.ErrorIs(t, err, ErrEmptyCassette)
+
+	corrupt := filepath.Join(dir, "corrupt.jsonl")
+	require.NoError(t, os.WriteFile(corrupt, []byte("{}\nnot json\n"), 0644))
+	_, err = LoadCassette(corrupt)
+	require.ErrorContains(t, err, "corrupt.jsonl:2")
+}
+
+func TestMergeAndSaveCassette(t *testing.T) {
+	interaction := func(day int, body string) Interaction {
+		return Interaction{
+			RecordedAt: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			Request:    RecordedRequest{Method: http.MethodGet, URL: "https://example.com"},
+			Response:   NewResponse(http.StatusOK, nil, []byte(body)),
+		}
+	}
+
+	recorded := []Interaction{interaction(10, "new"), interaction(11, "newer")}
+	backfilled := []Interaction{interaction(1, "old"), interaction(10, "duplicate")}
+	merged := Merge(recorded, backfilled)
+	require.Len(t, merged, 3)
+	require.Equal(t, "old", merged[0].Response.Body)
+	require.Equal(t, "new", merged[1].Response.Body)
+
+	// Merging again adds nothing
+	require.Len(t, Merge(merged, backfilled), 3)
+
+	path := filepath.Join(t.TempDir(), "example.jsonl")
+	require.NoError(t, SaveCassette(path, merged))
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Equal(t, merged, cassette.Interactions)
+
+	steps, err := Replay(cassette, monitor.DefaultConfig(""))
+	require.NoError(t, err)
+	require.False(t, steps[0].Change.HasChanged)
+	require.True(t, steps[1].Change.HasChanged)
+	require.Equal(t, merged[1].RecordedAt, steps[1].Change.Timestamp)
+}
@@ -0,0 +1,132 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// ErrCassetteExhausted is returned when more requests are made than a
+// cassette has recorded interactions
+var ErrCassetteExhausted = errors.New("no more recorded interactions")
+
+// Replayer is an http.RoundTripper that serves the interactions of a
+// cassette in the order they were recorded
+type Replayer struct {
+	cassette *Cassette
+	next     int
+	mu       sync.Mutex
+}
+
+// NewReplayer creates a replayer for a cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{cassette: cassette}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	if r.next >= len(r.cassette.Interactions) {
+		r.mu.Unlock()
+		return nil, ErrCassetteExhausted
+	}
+	interaction := r.cassette.Interactions[r.next]
+	r.next++
+	r.mu.Unlock()
+
+	if interaction.Error != "" || interaction.Response == nil {
+		return nil, fmt.Errorf("recorded error: %s", interaction.Error)
+	}
+
+	body, err := interaction.Response.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	status := interaction.Response.StatusCode
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Step is the outcome of replaying a single recorded interaction
+type Step struct {
+	Interaction Interaction
+	Change      monitor.Change
+}
+
+// Replay runs change detection with config against every interaction of the
+// cassette, in order, and returns the outcome of each check. The transport,
+// clock and retries of config are overridden for the replay, its URL is the
+// cassette's unless set, and recorded responses are compared as fetched,
+// without a browser. Cassettes recorded without response bodies can't be
+// replayed.
+func Replay(cassette *Cassette, config *monitor.Config) ([]Step, error) {
+	if len(cassette.Interactions) == 0 {
+		return nil, ErrEmptyCassette
+	}
+	for _, interaction := range cassette.Interactions {
+		if interaction.Response != nil && interaction.Response.BodyOmitted {
+			return nil, fmt.Errorf("interaction recorded at %s: %w", interaction.RecordedAt.Format(time.RFC3339), ErrBodyOmitted)
+		}
+	}
+
+	clock := &replayClock{}
+
+	cfg := *config
+	if cfg.URL == "" {
+		cfg.URL = cassette.URL()
+	}
+	cfg.Transport = NewReplayer(cassette)
+	cfg.Clock = clock
+	cfg.RetryCount = 0
+	cfg.Fetcher = monitor.FetcherHTTP
+
+	m := monitor.NewMonitorWithConfig(&cfg)
+	defer m.Stop()
+
+	steps := make([]Step, 0, len(cassette.Interactions))
+	for _, interaction := range cassette.Interactions {
+		clock.now = interaction.RecordedAt
+		steps = append(steps, Step{
+			Interaction: interaction,
+			Change:      m.Check(),
+		})
+	}
+
+	return steps, nil
+}
+
+// replayClock reports the time of the interaction being replayed
+type replayClock struct {
+	now time.Time
+}
+
+// Now implements monitor.Clock.Now
+func (c *replayClock) Now() time.Time {
+	return c.now
+}
+
+// NewTicker implements monitor.Clock.NewTicker
+func (c *replayClock) NewTicker(d time.Duration) monitor.Ticker {
+	return monitor.RealClock{}.NewTicker(d)
+}
+
+// After implements monitor.Clock.After
+func (c *replayClock) After(d time.Duration) <-chan time.Time {
+	return monitor.RealClock{}.After(d)
+}
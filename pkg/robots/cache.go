@@ -0,0 +1,136 @@
+package robots
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a robots.txt file is cached
+	DefaultTTL = 24 * time.Hour
+
+	// retryTTL is how long a robots.txt that couldn't be fetched is
+	// treated as allowing everything before it is fetched again
+	retryTTL = time.Hour
+
+	// maxSize is the largest part of a robots.txt file read, the limit of
+	// RFC 9309
+	maxSize = 500 << 10
+)
+
+// Cache fetches robots.txt files and keeps them for a day, per site and
+// user agent. It is safe for concurrent use; a file requested by several
+// goroutines at once is fetched once.
+type Cache struct {
+	// TTL is how long a file is kept. Defaults to DefaultTTL.
+	TTL time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is a cached robots.txt file. Its mutex is held while it is
+// fetched.
+type entry struct {
+	mu      sync.Mutex
+	robots  *Robots
+	expires time.Time
+}
+
+// NewCache creates an empty cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*entry)}
+}
+
+// Get returns the rules of the robots.txt of the site of u for userAgent,
+// fetching the file with client if it isn't cached or has expired. A
+// missing file allows everything. So does a file that can't be fetched,
+// e.g. because of a server error, as monitoring a site shouldn't stop when
+// its robots.txt is briefly unavailable; the previous rules are kept if
+// there are any, and fetching is retried after an hour.
+func (c *Cache) Get(ctx context.Context, client *http.Client, u *url.URL, userAgent string) *Robots {
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*entry)
+	}
+	e, ok := c.entries[origin+" "+userAgent]
+	if !ok {
+		e = &entry{}
+		c.entries[origin+" "+userAgent] = e
+	}
+	c.mu.Unlock()
+
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.robots != nil && now().Before(e.expires) {
+		return e.robots
+	}
+
+	robots, err := fetch(ctx, client, origin+"/robots.txt", userAgent)
+	switch {
+	case err == nil:
+		e.robots, e.expires = robots, now().Add(ttl)
+	case e.robots == nil:
+		e.robots, e.expires = &Robots{}, now().Add(retryTTL)
+	default:
+		e.expires = now().Add(retryTTL)
+	}
+	return e.robots
+}
+
+// errUnavailable is returned by fetch when the server fails to serve the file
+type errUnavailable struct {
+	status int
+}
+
+func (e errUnavailable) Error() string {
+	return "robots.txt unavailable: status " + http.StatusText(e.status)
+}
+
+// fetch fetches and parses a robots.txt file. A file that doesn't exist
+// allows everything.
+func fetch(ctx context.Context, client *http.Client, robotsURL, userAgent string) (*Robots, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		content, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+		if err != nil {
+			return nil, err
+		}
+		return Parse(content, userAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &Robots{}, nil
+	default:
+		return nil, errUnavailable{status: resp.StatusCode}
+	}
+}
@@ -0,0 +1,65 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	var calls atomic.Int64
+	var status atomic.Int64
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/robots.txt", r.URL.Path)
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewCache()
+	cache.Now = func() time.Time { return now }
+	page, _ := url.Parse(server.URL + "/private/page")
+	get := func() *Robots {
+		return cache.Get(context.Background(), server.Client(), page, "Hawkeye/1.0")
+	}
+
+	require.False(t, get().Allowed(page))
+	require.False(t, get().Allowed(page))
+	require.Equal(t, int64(1), calls.Load())
+
+	// A server error keeps the rules until the retry
+	status.Store(http.StatusServiceUnavailable)
+	now = now.Add(DefaultTTL)
+	require.False(t, get().Allowed(page))
+	require.Equal(t, int64(2), calls.Load())
+	now = now.Add(time.Minute)
+	require.False(t, get().Allowed(page))
+	require.Equal(t, int64(2), calls.Load())
+
+	// A missing file allows everything
+	status.Store(http.StatusNotFound)
+	now = now.Add(retryTTL)
+	require.True(t, get().Allowed(page))
+	require.Equal(t, int64(3), calls.Load())
+}
+
+func TestCacheUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// Monitoring goes on when robots.txt can't be fetched
+	page, _ := url.Parse(server.URL + "/page")
+	robots := NewCache().Get(context.Background(), server.Client(), page, "Hawkeye/1.0")
+	require.True(t, robots.Allowed(page))
+}
@@ -0,0 +1,141 @@
+// Package robots parses robots.txt files and caches them per site, so that
+// crawling and monitoring follow the rules sites set for automated clients.
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Robots holds the rules of a robots.txt file that apply to one user agent
+type Robots struct {
+	rules []rule
+	// CrawlDelay is the time to wait between requests asked for by the
+	// site, or zero
+	CrawlDelay time.Duration
+}
+
+type rule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// agentGroup is a group of rules and the user agents they apply to
+type agentGroup struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// Parse parses a robots.txt file and returns the rules for the user
+// agent, e.g. "Hawkeye/1.0". The rules of the groups naming the product
+// token of the agent are used, or those of the "*" group if none does.
+func Parse(content []byte, userAgent string) *Robots {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var groups []*agentGroup
+	var current *agentGroup
+	inAgents := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			// Consecutive user-agent lines share a group
+			if !inAgents {
+				current = &agentGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, newRule(value, field == "allow"))
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		default:
+			// Sitemap and unknown lines don't end the list of agents
+		}
+	}
+
+	robots := &Robots{}
+	for _, name := range []string{token, "*"} {
+		matched := false
+		for _, group := range groups {
+			if slices.Contains(group.agents, name) {
+				matched = true
+				robots.rules = append(robots.rules, group.rules...)
+				robots.CrawlDelay = max(robots.CrawlDelay, group.crawlDelay)
+			}
+		}
+		if matched {
+			break
+		}
+	}
+	return robots
+}
+
+// newRule compiles a path pattern, in which "*" matches any characters
+// and a trailing "$" anchors the end of the path
+func newRule(path string, allow bool) rule {
+	anchored := strings.HasSuffix(path, "$")
+	pattern := regexp.QuoteMeta(strings.TrimSuffix(path, "$"))
+	pattern = "^" + strings.ReplaceAll(pattern, `\*`, ".*")
+	if anchored {
+		pattern += "$"
+	}
+	return rule{allow: allow, length: len(path), pattern: regexp.MustCompile(pattern)}
+}
+
+// Allowed reports whether a URL may be fetched. The longest matching rule
+// decides, with allow rules winning ties. A nil Robots allows everything.
+func (r *Robots) Allowed(u *url.URL) bool {
+	if r == nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > longest || (rule.length == longest && rule.allow) {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,63 @@
+package robots
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRobots = `# Example robots.txt
+User-agent: *
+Disallow: /private/
+Crawl-delay: 5
+
+User-agent: Googlebot
+User-agent: Hawkeye
+Disallow: /admin
+Disallow: /*.pdf$
+Allow: /admin/public
+Disallow: /search?
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParse(t *testing.T) {
+	robots := Parse([]byte(testRobots), "Hawkeye/1.0")
+	require.Zero(t, robots.CrawlDelay)
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{path: "/", allowed: true},
+		{path: "/private/page", allowed: true},
+		{path: "/admin", allowed: false},
+		{path: "/admin/users", allowed: false},
+		{path: "/admin/public/page", allowed: true},
+		{path: "/files/report.pdf", allowed: false},
+		{path: "/files/report.pdf?download=1", allowed: true},
+		{path: "/search", allowed: true},
+		{path: "/search?q=widgets", allowed: false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse("https://example.com" + tt.path)
+		require.Equal(t, tt.allowed, robots.Allowed(u), tt.path)
+	}
+
+	// Other agents get the rules of the * group
+	robots = Parse([]byte(testRobots), "OtherBot/2.0")
+	require.Equal(t, 5*time.Second, robots.CrawlDelay)
+	u, _ := url.Parse("https://example.com/private/page")
+	require.False(t, robots.Allowed(u))
+	u, _ = url.Parse("https://example.com/admin")
+	require.True(t, robots.Allowed(u))
+
+	// An empty Disallow of the agent's own group allows everything
+	robots = Parse([]byte("User-agent: *\nDisallow: /\n\nUser-agent: hawkeye\nDisallow:\n"), "Hawkeye/1.0")
+	require.True(t, robots.Allowed(u))
+
+	var none *Robots
+	require.True(t, none.Allowed(u))
+}
@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the layouts accepted by ParseTime, without a zone except
+// for RFC 3339
+var timeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseTime parses the time of a one-time check, such as
+// "2024-07-01T09:00" or "2024-07-01T09:00:00+02:00". Times without a zone
+// are in loc, or in the local time zone if loc is nil.
+func ParseTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time '%s' (expected e.g. 2024-07-01T09:00)", value)
+}
+
+// ParseDeadline parses a deadline given either as a time accepted by
+// ParseTime or as a duration from now, such as "48h", "14d" or "2w"
+func ParseDeadline(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if d, err := time.ParseDuration(strings.TrimSpace(value)); err == nil {
+		return now.Add(d), nil
+	}
+	if days, ok := parseDays(strings.TrimSpace(value)); ok {
+		return now.AddDate(0, 0, days), nil
+	}
+	return ParseTime(value, loc)
+}
+
+// parseDays parses a number of days or weeks, such as "14d" or "2w"
+func parseDays(value string) (int, bool) {
+	unit := 1
+	switch {
+	case strings.HasSuffix(value, "d"):
+	case strings.HasSuffix(value, "w"):
+		unit = 7
+	default:
+		return 0, false
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n * unit, true
+}
@@ -0,0 +1,60 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	loc := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{input: "2024-07-01T09:00", expected: time.Date(2024, 7, 1, 9, 0, 0, 0, loc)},
+		{input: "2024-07-01 09:00:30", expected: time.Date(2024, 7, 1, 9, 0, 30, 0, loc)},
+		{input: "2024-07-01T09:00:00Z", expected: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)},
+		{input: "2024-07-01T09:00:00-05:00", expected: time.Date(2024, 7, 1, 14, 0, 0, 0, time.UTC)},
+		{input: "tomorrow", wantErr: true},
+		{input: "2024-07-01", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			parsed, err := ParseTime(tc.input, loc)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.expected.Equal(parsed), "got %s", parsed)
+		})
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+
+	deadline, err := ParseDeadline("48h", now, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Hour*48), deadline)
+
+	deadline, err = ParseDeadline("14d", now, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 7, 15, 9, 0, 0, 0, time.UTC), deadline)
+
+	deadline, err = ParseDeadline("2w", now, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 7, 15, 9, 0, 0, 0, time.UTC), deadline)
+
+	deadline, err = ParseDeadline("2024-07-02T12:00", now, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 7, 2, 12, 0, 0, 0, time.UTC), deadline)
+
+	_, err = ParseDeadline("later", now, time.UTC)
+	require.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@sometimes",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := ParseCron(expr)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, time.January, 10, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{expr: "* * * * *", expected: time.Date(2024, time.January, 10, 10, 31, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", expected: time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC)},
+		{expr: "0 9 * * *", expected: time.Date(2024, time.January, 11, 9, 0, 0, 0, time.UTC)},
+		{expr: "0 9 * * mon-fri", expected: time.Date(2024, time.January, 11, 9, 0, 0, 0, time.UTC)},
+		{expr: "30 2 * * sat,sun", expected: time.Date(2024, time.January, 13, 2, 30, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", expected: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 feb *", expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", expected: time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", expected: time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC)},
+		// Day of month and day of week are alternatives when both are set
+		{expr: "0 0 15 * fri", expected: time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			cron, err := ParseCron(tc.expr)
+			require.NoError(t, err)
+
+			next, err := cron.Next(from)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, next)
+			require.True(t, cron.Matches(next))
+		})
+	}
+}
+
+func TestCronNoNextTime(t *testing.T) {
+	cron := MustParseCron("0 0 31 feb *")
+	_, err := cron.Next(time.Now())
+	require.ErrorIs(t, err, ErrNoNextTime)
+}
@@ -0,0 +1,242 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Mode controls what happens to checks during a window
+type Mode int
+
+const (
+	// ModeSkip skips checks during the window
+	ModeSkip Mode = iota
+	// ModeSilence performs checks and records changes, but doesn't notify
+	ModeSilence
+)
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeSkip:
+		return "skip"
+	case ModeSilence:
+		return "silence"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseMode parses a mode name. An empty name is ModeSkip.
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(name) {
+	case "", "skip":
+		return ModeSkip, nil
+	case "silence":
+		return ModeSilence, nil
+	default:
+		return ModeSkip, fmt.Errorf("unknown window mode '%s' (expected skip or silence)", name)
+	}
+}
+
+// weekdayNames maps the accepted names of weekdays to their values
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Window is a recurring period of time, written either as weekdays and a time
+// range or as a cron expression and a duration:
+//
+//	02:00-04:00                   every day
+//	Sat 02:00-04:00               on Saturdays
+//	Mon-Fri 22:00-06:00           overnight, starting Monday to Friday
+//	Sat,Sun 00:00-24:00           all weekend
+//	0 2 * * sat for 2h            from every cron match, for 2 hours
+//	Sat 02:00-04:00 Europe/Berlin in a specific time zone
+//
+// Times are in the local time zone unless a zone is given.
+type Window struct {
+	Mode Mode
+
+	spec     string
+	location *time.Location
+
+	// Weekday and time range windows
+	days       [7]bool
+	start, end int // minutes since midnight
+
+	// Cron windows
+	cron     *Cron
+	duration time.Duration
+}
+
+// ParseWindow parses a window specification
+func ParseWindow(spec string, mode Mode) (*Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty window")
+	}
+
+	w := &Window{Mode: mode, spec: strings.Join(fields, " "), location: time.Local}
+
+	// An optional trailing time zone
+	if last := fields[len(fields)-1]; last == "UTC" || last == "Local" || strings.Contains(last, "/") {
+		location, err := time.LoadLocation(last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: unknown time zone '%s'", spec, last)
+		}
+		w.location = location
+		fields = fields[:len(fields)-1]
+	}
+
+	for i, field := range fields {
+		if field != "for" {
+			continue
+		}
+		if i+2 != len(fields) {
+			return nil, fmt.Errorf("invalid window %q: expected a duration after 'for'", spec)
+		}
+
+		cron, err := ParseCron(strings.Join(fields[:i], " "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+		}
+		duration, err := time.ParseDuration(fields[i+1])
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid window %q: invalid duration '%s'", spec, fields[i+1])
+		}
+
+		w.cron = cron
+		w.duration = duration
+		return w, nil
+	}
+
+	switch len(fields) {
+	case 1:
+		for day := range w.days {
+			w.days[day] = true
+		}
+	case 2:
+		if err := w.parseDays(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid window %q: expected [days] HH:MM-HH:MM or a cron expression with 'for <duration>'", spec)
+	}
+
+	startPart, endPart, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q: expected a time range such as 02:00-04:00", spec)
+	}
+
+	var err error
+	if w.start, err = parseClock(startPart); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if w.end, err = parseClock(endPart); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("invalid window %q: start and end are the same", spec)
+	}
+
+	return w, nil
+}
+
+// String returns the window specification
+func (w *Window) String() string {
+	return w.spec
+}
+
+// Contains reports whether t falls within the window
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.location)
+
+	if w.cron != nil {
+		// Look for a start of the window within the last duration
+		start := t.Truncate(time.Minute)
+		for earliest := t.Add(-w.duration); start.After(earliest); start = start.Add(-time.Minute) {
+			if w.cron.Matches(start) {
+				return true
+			}
+		}
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+
+	// The window wraps past midnight into the next day
+	previous := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[previous] && minute < w.end)
+}
+
+// parseDays parses a list of weekdays and weekday ranges such as Mon-Fri,Sun
+func (w *Window) parseDays(value string) error {
+	if value == "*" || strings.EqualFold(value, "daily") {
+		for day := range w.days {
+			w.days[day] = true
+		}
+		return nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		startName, endName, isRange := strings.Cut(part, "-")
+
+		start, ok := weekdayNames[strings.ToLower(startName)]
+		if !ok {
+			return fmt.Errorf("unknown weekday '%s'", startName)
+		}
+		end := start
+		if isRange {
+			if end, ok = weekdayNames[strings.ToLower(endName)]; !ok {
+				return fmt.Errorf("unknown weekday '%s'", endName)
+			}
+		}
+
+		// Ranges may wrap around the end of the week, e.g. Fri-Mon
+		for day := start; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseClock parses HH:MM into minutes since midnight. 24:00 is accepted as
+// the end of the day.
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || len(value) < 4 {
+		return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", value)
+	}
+	if hour == 24 && minute == 0 {
+		return 24 * 60, nil
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// Windows is a list of windows
+type Windows []*Window
+
+// Active returns the first window of the given mode containing t, or nil
+func (l Windows) Active(t time.Time, mode Mode) *Window {
+	for _, w := range l {
+		if w.Mode == mode && w.Contains(t) {
+			return w
+		}
+	}
+	return nil
+}
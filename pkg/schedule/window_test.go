@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowContains(t *testing.T) {
+	// 2024-01-13 is a Saturday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec    string
+		inside  []time.Time
+		outside []time.Time
+	}{
+		{
+			spec:    "02:00-04:00 UTC",
+			inside:  []time.Time{at(10, 2, 0), at(13, 3, 59)},
+			outside: []time.Time{at(10, 1, 59), at(10, 4, 0)},
+		},
+		{
+			spec:    "Sat 02:00-04:00 UTC",
+			inside:  []time.Time{at(13, 2, 30)},
+			outside: []time.Time{at(12, 2, 30), at(14, 2, 30)},
+		},
+		{
+			spec:    "Mon-Fri 22:00-06:00 UTC",
+			inside:  []time.Time{at(12, 23, 0), at(13, 5, 0), at(8, 22, 0)},
+			outside: []time.Time{at(13, 23, 0), at(8, 5, 0), at(12, 6, 0)},
+		},
+		{
+			spec:    "Sat,Sun 00:00-24:00 UTC",
+			inside:  []time.Time{at(13, 0, 0), at(14, 23, 59)},
+			outside: []time.Time{at(15, 0, 0)},
+		},
+		{
+			spec:    "Fri-Mon 12:00-13:00 UTC",
+			inside:  []time.Time{at(12, 12, 0), at(15, 12, 30)},
+			outside: []time.Time{at(16, 12, 30)},
+		},
+		{
+			spec:    "0 2 * * sat for 2h UTC",
+			inside:  []time.Time{at(13, 2, 0), at(13, 3, 59)},
+			outside: []time.Time{at(13, 1, 59), at(13, 4, 0), at(14, 2, 30)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			w, err := ParseWindow(tc.spec, ModeSkip)
+			require.NoError(t, err)
+			require.Equal(t, tc.spec, w.String())
+
+			for _, ts := range tc.inside {
+				require.True(t, w.Contains(ts), "expected %s inside", ts)
+			}
+			for _, ts := range tc.outside {
+				require.False(t, w.Contains(ts), "expected %s outside", ts)
+			}
+		})
+	}
+}
+
+func TestWindowTimeZone(t *testing.T) {
+	w, err := ParseWindow("02:00-04:00 Asia/Tokyo", ModeSkip)
+	require.NoError(t, err)
+
+	// 02:30 in Tokyo is 17:30 UTC the day before
+	require.True(t, w.Contains(time.Date(2024, time.January, 9, 17, 30, 0, 0, time.UTC)))
+	require.False(t, w.Contains(time.Date(2024, time.January, 10, 2, 30, 0, 0, time.UTC)))
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"02:00",
+		"Sat",
+		"Someday 02:00-04:00",
+		"25:00-26:00",
+		"02:00-02:00",
+		"Sat 02:00-04:00 Mars/Olympus",
+		"0 2 * * sat for",
+		"0 2 * * sat for soon",
+		"0 2 * * for 2h",
+		"every Sat 02:00-04:00",
+	}
+
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseWindow(spec, ModeSkip)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestWindowsActive(t *testing.T) {
+	skip, err := ParseWindow("02:00-04:00 UTC", ModeSkip)
+	require.NoError(t, err)
+	silence, err := ParseWindow("03:00-05:00 UTC", ModeSilence)
+	require.NoError(t, err)
+
+	windows := Windows{skip, silence}
+	at := time.Date(2024, time.January, 10, 3, 30, 0, 0, time.UTC)
+	require.Same(t, skip, windows.Active(at, ModeSkip))
+	require.Same(t, silence, windows.Active(at, ModeSilence))
+	require.Nil(t, windows.Active(at.Add(time.Hour*2), ModeSilence))
+
+	mode, err := ParseMode("silence")
+	require.NoError(t, err)
+	require.Equal(t, ModeSilence, mode)
+	require.Equal(t, "silence", mode.String())
+	_, err = ParseMode("snooze")
+	require.Error(t, err)
+}
@@ -0,0 +1,159 @@
+// Package secret resolves placeholders for credentials in headers, auth
+// settings and notification URLs, so they can be kept out of definition
+// files and the saved state. ${NAME} is replaced by the environment variable
+// NAME, and a value of file:/path by the contents of the file. Values are
+// resolved whenever they are used, so rotated credentials are picked up
+// without a restart.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// FilePrefix marks a value read from a file
+const FilePrefix = "file:"
+
+// ErrUnset is returned for a placeholder whose environment variable isn't set
+var ErrUnset = errors.New("environment variable is not set")
+
+// secretParams are the query parameters that conventionally carry
+// credentials, in lower case with underscores
+var secretParams = map[string]bool{
+	"access_token":  true,
+	"api_key":       true,
+	"apikey":        true,
+	"auth":          true,
+	"client_secret": true,
+	"key":           true,
+	"password":      true,
+	"secret":        true,
+	"sig":           true,
+	"signature":     true,
+	"token":         true,
+}
+
+// IsSecretParam reports whether the query parameter name conventionally
+// carries a credential, such as api_key or token. Case is ignored and
+// dashes are taken as underscores.
+func IsSecretParam(name string) bool {
+	return secretParams[strings.ReplaceAll(strings.ToLower(name), "-", "_")]
+}
+
+// Contains reports whether value has placeholders to resolve
+func Contains(value string) bool {
+	return strings.HasPrefix(value, FilePrefix) || strings.Contains(value, "${")
+}
+
+// Resolve returns value with its placeholders replaced. $${ stands for a
+// literal ${. Files are read without their trailing line break.
+func Resolve(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, FilePrefix); ok {
+		if path == "" {
+			return "", errors.New("secret file path is empty")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return expand(value, func(name string) (string, error) {
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnset, name)
+		}
+		return resolved, nil
+	})
+}
+
+// Validate reports malformed placeholders in value without resolving them,
+// e.g. when a configuration is loaded before its environment is set up
+func Validate(value string) error {
+	if value == FilePrefix {
+		return errors.New("secret file path is empty")
+	}
+	if strings.HasPrefix(value, FilePrefix) {
+		return nil
+	}
+	_, err := expand(value, func(string) (string, error) { return "", nil })
+	return err
+}
+
+// ResolveMap resolves every value of m. m itself is returned when it has no
+// placeholders.
+func ResolveMap(m map[string]string) (map[string]string, error) {
+	var resolved map[string]string
+	for key, value := range m {
+		if !Contains(value) {
+			continue
+		}
+		if resolved == nil {
+			resolved = maps.Clone(m)
+		}
+		var err error
+		if resolved[key], err = Resolve(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if resolved == nil {
+		return m, nil
+	}
+	return resolved, nil
+}
+
+// expand replaces the ${NAME} placeholders of value using lookup
+func expand(value string, lookup func(name string) (string, error)) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		if i > 0 && value[i-1] == '$' {
+			// An escaped $${ is written as ${
+			b.WriteString(value[:i-1])
+			b.WriteString("${")
+			value = value[i+2:]
+			continue
+		}
+
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in '%s'", value[i:])
+		}
+		name := value[i+2 : i+end]
+		if !validName(name) {
+			return "", fmt.Errorf("invalid placeholder '${%s}'", name)
+		}
+		resolved, err := lookup(name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value[:i])
+		b.WriteString(resolved)
+		value = value[i+end+1:]
+	}
+}
+
+// validName reports whether name is an environment variable name: letters,
+// digits and underscores, not starting with a digit
+func validName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("HAWKEYE_TEST_TOKEN", "s3cret")
+	t.Setenv("HAWKEYE_TEST_EMPTY", "")
+
+	tests := []struct {
+		value    string
+		resolved string
+	}{
+		{"plain", "plain"},
+		{"Bearer ${HAWKEYE_TEST_TOKEN}", "Bearer s3cret"},
+		{"${HAWKEYE_TEST_TOKEN}:${HAWKEYE_TEST_TOKEN}", "s3cret:s3cret"},
+		{"[${HAWKEYE_TEST_EMPTY}]", "[]"},
+		{"$5 or $${HAWKEYE_TEST_TOKEN}", "$5 or ${HAWKEYE_TEST_TOKEN}"},
+	}
+	for _, tt := range tests {
+		resolved, err := Resolve(tt.value)
+		require.NoError(t, err, tt.value)
+		require.Equal(t, tt.resolved, resolved, tt.value)
+	}
+
+	_, err := Resolve("${HAWKEYE_TEST_UNSET}")
+	require.ErrorIs(t, err, ErrUnset)
+	require.ErrorContains(t, err, "HAWKEYE_TEST_UNSET")
+	_, err = Resolve("${HAWKEYE_TEST_TOKEN")
+	require.ErrorContains(t, err, "unterminated placeholder")
+	_, err = Resolve("${1TOKEN}")
+	require.ErrorContains(t, err, "invalid placeholder")
+}
+
+func TestResolveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0o600))
+
+	resolved, err := Resolve(FilePrefix + path)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", resolved, "the trailing line break is dropped")
+
+	_, err = Resolve(FilePrefix + filepath.Join(t.TempDir(), "missing"))
+	require.ErrorContains(t, err, "reading secret")
+	_, err = Resolve(FilePrefix)
+	require.ErrorContains(t, err, "path is empty")
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate("${HAWKEYE_TEST_UNSET}"), "variables may be set later")
+	require.NoError(t, Validate("file:/run/secrets/missing"))
+	require.Error(t, Validate("${HAWKEYE_TEST"))
+	require.Error(t, Validate("file:"))
+}
+
+func TestResolveMap(t *testing.T) {
+	t.Setenv("HAWKEYE_TEST_TOKEN", "s3cret")
+
+	headers := map[string]string{"Accept": "text/html", "X-Token": "${HAWKEYE_TEST_TOKEN}"}
+	resolved, err := ResolveMap(headers)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"Accept": "text/html", "X-Token": "s3cret"}, resolved)
+	require.Equal(t, "${HAWKEYE_TEST_TOKEN}", headers["X-Token"], "the map is not modified")
+
+	_, err = ResolveMap(map[string]string{"X-Token": "${HAWKEYE_TEST_UNSET}"})
+	require.ErrorContains(t, err, "X-Token")
+}
+
+func TestIsSecretParam(t *testing.T) {
+	for _, name := range []string{"api_key", "API-KEY", "token", "access_token", "Signature"} {
+		require.True(t, IsSecretParam(name), name)
+	}
+	for _, name := range []string{"page", "q", "tokens", ""} {
+		require.False(t, IsSecretParam(name), name)
+	}
+}
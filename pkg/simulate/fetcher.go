@@ -0,0 +1,93 @@
+package simulate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mockFetcher is an http.RoundTripper that serves synthetic content without
+// touching the network. Each URL's content changes with a given probability
+// on every fetch.
+type mockFetcher struct {
+	latency    time.Duration
+	changeRate float64
+	bodySize   int
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	versions map[string]int
+
+	requests atomic.Int64
+}
+
+// newMockFetcher creates a mock fetcher with the given per-request latency,
+// probability of content changing between fetches and response body size
+func newMockFetcher(latency time.Duration, changeRate float64, bodySize int, seed int64) *mockFetcher {
+	return &mockFetcher{
+		latency:    latency,
+		changeRate: changeRate,
+		bodySize:   bodySize,
+		rng:        rand.New(rand.NewSource(seed)),
+		versions:   make(map[string]int),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (f *mockFetcher) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests.Add(1)
+
+	if f.latency > 0 {
+		timer := time.NewTimer(f.latency)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+
+	url := req.URL.String()
+
+	f.mu.Lock()
+	if f.rng.Float64() < f.changeRate {
+		f.versions[url]++
+	}
+	version := f.versions[url]
+	f.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Body:       io.NopCloser(bytes.NewReader(f.body(url, version))),
+		Request:    req,
+	}, nil
+}
+
+// body builds a deterministic response body for a URL version
+func (f *mockFetcher) body(url string, version int) []byte {
+	header := fmt.Sprintf("<html><!-- %s version %d -->", url, version)
+
+	size := f.bodySize
+	if size < len(header) {
+		size = len(header)
+	}
+
+	body := make([]byte, size)
+	copy(body, header)
+	for i := len(header); i < size; i++ {
+		body[i] = 'x'
+	}
+
+	return body
+}
+
+// Requests returns the number of requests served
+func (f *mockFetcher) Requests() int64 {
+	return f.requests.Load()
+}
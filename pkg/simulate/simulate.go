@@ -0,0 +1,264 @@
+// Package simulate runs the monitor scheduler against a mock fetcher at
+// accelerated time. It reports how many checks a machine actually performed
+// compared to what the configured monitors and intervals demand, which helps
+// with capacity planning before a production rollout.
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// DefaultThreshold is the minimum efficiency for a run to count as sustained
+const DefaultThreshold = 0.95
+
+// Error definitions
+var (
+	ErrInvalidMonitors = errors.New("number of monitors must be greater than zero")
+	ErrInvalidDuration = errors.New("duration must be greater than zero")
+	ErrInvalidSpeed    = errors.New("speed must be at least 1")
+	ErrSpeedTooHigh    = errors.New("speed is too high for the interval: accelerated interval is below 1ms")
+)
+
+// Options configures a simulation run
+type Options struct {
+	// Monitors is the number of simulated monitors
+	Monitors int
+	// Interval is the simulated check interval of every monitor
+	Interval time.Duration
+	// Duration is the simulated length of the run
+	Duration time.Duration
+	// Speed is the time acceleration factor. A speed of 60 runs one
+	// simulated minute per real second.
+	Speed float64
+	// Latency is the simulated response latency of the mock fetcher
+	Latency time.Duration
+	// ChangeRate is the probability that content changes between fetches
+	ChangeRate float64
+	// BodySize is the size in bytes of each mock response body
+	BodySize int
+	// Method is the change detection method used by every monitor
+	Method monitor.ChangeDetectionMethod
+	// Seed seeds the mock fetcher's random change generator
+	Seed int64
+}
+
+// DefaultOptions returns default simulation options
+func DefaultOptions() *Options {
+	return &Options{
+		Monitors:   100,
+		Interval:   time.Minute,
+		Duration:   time.Hour,
+		Speed:      60,
+		Latency:    time.Millisecond * 200,
+		ChangeRate: 0.1,
+		BodySize:   16 * 1024,
+		Method:     monitor.MethodHash,
+		Seed:       1,
+	}
+}
+
+// Result holds the outcome of a simulation run
+type Result struct {
+	Monitors          int           `json:"monitors"`
+	Interval          time.Duration `json:"interval"`
+	Speed             float64       `json:"speed"`
+	SimulatedDuration time.Duration `json:"simulated_duration"`
+	WallDuration      time.Duration `json:"wall_duration"`
+	ExpectedChecks    int64         `json:"expected_checks"`
+	PerformedChecks   int64         `json:"performed_checks"`
+	Changes           int64         `json:"changes"`
+	Errors            int64         `json:"errors"`
+	// Efficiency is the ratio of performed to expected checks
+	Efficiency float64 `json:"efficiency"`
+	// EquivalentMonitors is the real-time load the run represents: the number
+	// of monitors at Interval that produce the same request rate without
+	// acceleration
+	EquivalentMonitors int    `json:"equivalent_monitors"`
+	HeapBytes          uint64 `json:"heap_bytes"`
+	Goroutines         int    `json:"goroutines"`
+}
+
+// Sustained reports whether the run kept up with the schedule
+func (r *Result) Sustained(threshold float64) bool {
+	return r.Efficiency >= threshold
+}
+
+// String returns a human-readable summary of the result
+func (r *Result) String() string {
+	return fmt.Sprintf("%d monitors every %s at %gx (≈%d real-time monitors): %d/%d checks (%.1f%%), %d changes, %d errors, %.1f MiB heap, %d goroutines",
+		r.Monitors, r.Interval, r.Speed, r.EquivalentMonitors,
+		r.PerformedChecks, r.ExpectedChecks, r.Efficiency*100,
+		r.Changes, r.Errors, float64(r.HeapBytes)/(1024*1024), r.Goroutines)
+}
+
+// validate checks the options and returns the accelerated interval and duration
+func (o *Options) validate() (time.Duration, time.Duration, error) {
+	if o.Monitors <= 0 {
+		return 0, 0, ErrInvalidMonitors
+	}
+	if o.Interval <= 0 {
+		return 0, 0, monitor.ErrInvalidInterval
+	}
+	if o.Duration <= 0 {
+		return 0, 0, ErrInvalidDuration
+	}
+	if o.Speed < 1 {
+		return 0, 0, ErrInvalidSpeed
+	}
+
+	interval := time.Duration(float64(o.Interval) / o.Speed)
+	if interval < time.Millisecond {
+		return 0, 0, ErrSpeedTooHigh
+	}
+
+	return interval, time.Duration(float64(o.Duration) / o.Speed), nil
+}
+
+// Run performs a single simulation run
+func Run(ctx context.Context, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	interval, duration, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher := newMockFetcher(time.Duration(float64(opts.Latency)/opts.Speed), opts.ChangeRate, opts.BodySize, opts.Seed)
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	monitors := make([]*monitor.Monitor, 0, opts.Monitors)
+	for i := 0; i < opts.Monitors; i++ {
+		config := monitor.DefaultConfig(fmt.Sprintf("http://sim-%d.hawkeye.invalid/", i))
+		config.Interval = interval
+		config.Method = opts.Method
+		config.RetryCount = 0
+		config.Transport = fetcher
+		monitors = append(monitors, monitor.NewMonitorWithConfig(config))
+	}
+
+	var changes, errs atomic.Int64
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for _, m := range monitors {
+		ch := m.Start()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for change := range ch {
+				if change.Error != "" {
+					errs.Add(1)
+				} else if change.HasChanged {
+					changes.Add(1)
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(duration)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	elapsed := time.Since(start)
+
+	// Snapshot before stopping so cancellation errors are not counted
+	result := &Result{
+		Monitors:           opts.Monitors,
+		Interval:           opts.Interval,
+		Speed:              opts.Speed,
+		SimulatedDuration:  time.Duration(float64(elapsed) * opts.Speed),
+		WallDuration:       elapsed,
+		ExpectedChecks:     int64(opts.Monitors) * expectedChecks(duration, interval),
+		Changes:            changes.Load(),
+		Errors:             errs.Load(),
+		EquivalentMonitors: int(float64(opts.Monitors) * opts.Speed),
+		Goroutines:         runtime.NumGoroutine(),
+	}
+
+	for _, m := range monitors {
+		_, _, count := m.GetStatus()
+		result.PerformedChecks += count
+	}
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > before.HeapAlloc {
+		result.HeapBytes = after.HeapAlloc - before.HeapAlloc
+	}
+
+	result.Efficiency = float64(result.PerformedChecks) / float64(result.ExpectedChecks)
+	if result.Efficiency > 1 {
+		result.Efficiency = 1
+	}
+
+	for _, m := range monitors {
+		m.Stop()
+	}
+	wg.Wait()
+
+	return result, ctx.Err()
+}
+
+// expectedChecks returns the number of checks a single monitor is scheduled
+// to start within duration: one immediately and one per elapsed interval,
+// excluding a tick that falls exactly on the end of the run
+func expectedChecks(duration, interval time.Duration) int64 {
+	return int64((duration + interval - 1) / interval)
+}
+
+// Ramp runs simulations with a doubling number of monitors, starting at
+// opts.Monitors, until a run falls below threshold or maxMonitors is
+// exceeded. It returns the results of every run; the last sustained result
+// is the estimated capacity of the machine.
+func Ramp(ctx context.Context, opts *Options, threshold float64, maxMonitors int) ([]*Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	var results []*Result
+	run := *opts
+
+	for run.Monitors <= maxMonitors {
+		result, err := Run(ctx, &run)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, err
+		}
+
+		if !result.Sustained(threshold) {
+			break
+		}
+
+		run.Monitors *= 2
+	}
+
+	return results, nil
+}
+
+// Capacity returns the last sustained result from a ramp, or nil if none
+func Capacity(results []*Result, threshold float64) *Result {
+	var best *Result
+	for _, result := range results {
+		if result.Sustained(threshold) {
+			best = result
+		}
+	}
+	return best
+}
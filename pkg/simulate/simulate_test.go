@@ -0,0 +1,103 @@
+package simulate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", modify: func(o *Options) {}},
+		{name: "no monitors", modify: func(o *Options) { o.Monitors = 0 }, wantErr: ErrInvalidMonitors},
+		{name: "no duration", modify: func(o *Options) { o.Duration = 0 }, wantErr: ErrInvalidDuration},
+		{name: "slow speed", modify: func(o *Options) { o.Speed = 0.5 }, wantErr: ErrInvalidSpeed},
+		{name: "speed too high", modify: func(o *Options) { o.Speed = 1e9 }, wantErr: ErrSpeedTooHigh},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tc.modify(opts)
+
+			_, _, err := opts.validate()
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestMockFetcher(t *testing.T) {
+	fetcher := newMockFetcher(0, 1, 64, 1)
+
+	req, err := http.NewRequest(http.MethodGet, "http://sim.hawkeye.invalid/", nil)
+	require.NoError(t, err)
+
+	resp, err := fetcher.RoundTrip(req)
+	require.NoError(t, err)
+	first, _ := io.ReadAll(resp.Body)
+	require.Len(t, first, 64)
+
+	// With a change rate of 1 every fetch returns new content
+	resp, err = fetcher.RoundTrip(req)
+	require.NoError(t, err)
+	second, _ := io.ReadAll(resp.Body)
+	require.NotEqual(t, first, second)
+	require.Equal(t, int64(2), fetcher.Requests())
+}
+
+func TestRun(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Monitors = 5
+	opts.Interval = time.Second
+	opts.Duration = time.Second * 10
+	opts.Speed = 20
+	opts.Latency = time.Millisecond * 20
+	opts.ChangeRate = 1
+
+	result, err := Run(context.Background(), opts)
+	require.NoError(t, err)
+	require.Equal(t, 5, result.Monitors)
+	require.Equal(t, 100, result.EquivalentMonitors)
+	require.Positive(t, result.ExpectedChecks)
+	require.Positive(t, result.PerformedChecks)
+	require.Positive(t, result.Changes)
+	require.Zero(t, result.Errors)
+	require.Greater(t, result.Efficiency, 0.5)
+	require.NotEmpty(t, result.String())
+}
+
+func TestRamp(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Monitors = 1
+	opts.Interval = time.Second
+	opts.Duration = time.Second * 2
+	opts.Speed = 20
+	opts.Latency = 0
+
+	results, err := Ramp(context.Background(), opts, 0.5, 4)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, 1, results[0].Monitors)
+
+	capacity := Capacity(results, 0.5)
+	require.NotNil(t, capacity)
+	require.LessOrEqual(t, capacity.Monitors, 4)
+}
+
+func TestExpectedChecks(t *testing.T) {
+	require.Equal(t, int64(1), expectedChecks(time.Second, time.Second))
+	require.Equal(t, int64(2), expectedChecks(time.Second*2, time.Second))
+	require.Equal(t, int64(3), expectedChecks(time.Millisecond*2500, time.Second))
+}
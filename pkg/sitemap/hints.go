@@ -0,0 +1,72 @@
+package sitemap
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPriority is the priority of pages whose sitemap doesn't give one,
+// as defined by the sitemaps protocol
+const DefaultPriority = 0.5
+
+// ChangeFreqIntervals are the check intervals of the change frequencies of
+// the sitemaps protocol. Pages are checked a few times within the period in
+// which they are expected to change; pages that never change are still
+// checked weekly, as sitemaps are often out of date.
+var ChangeFreqIntervals = map[string]time.Duration{
+	"always":  5 * time.Minute,
+	"hourly":  15 * time.Minute,
+	"daily":   time.Hour,
+	"weekly":  6 * time.Hour,
+	"monthly": 24 * time.Hour,
+	"yearly":  7 * 24 * time.Hour,
+	"never":   7 * 24 * time.Hour,
+}
+
+// Page is a URL listed in a sitemap with the hints given for it
+type Page struct {
+	URL string
+	// ChangeFreq is how often the page is expected to change, one of the
+	// keys of ChangeFreqIntervals, or empty if the sitemap doesn't say
+	ChangeFreq string
+	// Priority is the importance of the page relative to the other pages
+	// of the site, from 0.0 to 1.0
+	Priority float64
+}
+
+// newPage returns the page of a sitemap entry. Unknown change frequencies
+// and invalid priorities are ignored.
+func newPage(loc, changeFreq, priority string) Page {
+	page := Page{URL: loc, Priority: DefaultPriority}
+	changeFreq = strings.ToLower(strings.TrimSpace(changeFreq))
+	if _, ok := ChangeFreqIntervals[changeFreq]; ok {
+		page.ChangeFreq = changeFreq
+	}
+	if value, err := strconv.ParseFloat(strings.TrimSpace(priority), 64); err == nil && value >= 0 && value <= 1 {
+		page.Priority = value
+	}
+	return page
+}
+
+// Interval returns the check interval suggested by the hints of the page:
+// the interval of its change frequency, or fallback without one, halved for
+// pages of priority 1.0 and doubled for pages of priority 0.0
+func (p Page) Interval(fallback time.Duration) time.Duration {
+	interval := fallback
+	if freq, ok := ChangeFreqIntervals[p.ChangeFreq]; ok {
+		interval = freq
+	}
+	factor := math.Pow(2, 1-2*p.Priority)
+	return time.Duration(float64(interval) * factor).Round(time.Second)
+}
+
+// pageURLs returns the URLs of pages
+func pageURLs(pages []Page) []string {
+	urls := make([]string, len(pages))
+	for i, page := range pages {
+		urls[i] = page.URL
+	}
+	return urls
+}
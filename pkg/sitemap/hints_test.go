@@ -0,0 +1,48 @@
+package sitemap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testHints = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc><changefreq>hourly</changefreq><priority>1.0</priority></url>
+  <url><loc>https://example.com/about</loc><changefreq> Yearly </changefreq></url>
+  <url><loc>https://example.com/news</loc><changefreq>sometimes</changefreq><priority>2</priority></url>
+  <url><loc>https://example.com/archive</loc><priority>0.0</priority></url>
+</urlset>`
+
+func TestParsePages(t *testing.T) {
+	pages, _, err := ParsePages([]byte(testHints), nil)
+	require.NoError(t, err)
+	require.Equal(t, []Page{
+		{URL: "https://example.com/", ChangeFreq: "hourly", Priority: 1},
+		{URL: "https://example.com/about", ChangeFreq: "yearly", Priority: DefaultPriority},
+		{URL: "https://example.com/news", Priority: DefaultPriority},
+		{URL: "https://example.com/archive", Priority: 0},
+	}, pages)
+
+	// Text sitemaps have no hints
+	pages, _, err = ParsePages([]byte("https://example.com/a\n"), nil)
+	require.NoError(t, err)
+	require.Equal(t, []Page{{URL: "https://example.com/a", Priority: DefaultPriority}}, pages)
+}
+
+func TestPageInterval(t *testing.T) {
+	tests := []struct {
+		page     Page
+		interval time.Duration
+	}{
+		{Page{ChangeFreq: "daily", Priority: DefaultPriority}, time.Hour},
+		{Page{ChangeFreq: "hourly", Priority: 1}, 7*time.Minute + 30*time.Second},
+		{Page{ChangeFreq: "weekly", Priority: 0}, 12 * time.Hour},
+		{Page{Priority: DefaultPriority}, 5 * time.Minute},
+		{Page{Priority: 0.75}, 3*time.Minute + 32*time.Second},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.interval, tt.page.Interval(5*time.Minute), "%+v", tt.page)
+	}
+}
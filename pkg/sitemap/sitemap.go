@@ -0,0 +1,315 @@
+// Package sitemap reads the URLs listed in sitemaps and sitemap indexes and
+// keeps the monitors of a manager in line with them as the sitemap changes.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+)
+
+// MaxSize is the largest sitemap read, after decompression. It is the limit
+// of the sitemaps protocol.
+const MaxSize = 50 << 20
+
+// DefaultMaxSitemaps is the number of sitemaps fetched at most when
+// following sitemap indexes
+const DefaultMaxSitemaps = 100
+
+var (
+	// ErrNotSitemap is returned for content that is neither a sitemap, a
+	// sitemap index nor a text sitemap
+	ErrNotSitemap = errors.New("content is not a sitemap")
+
+	// ErrEmpty is returned when a sitemap lists no URLs at all, which is
+	// more likely a broken sitemap than a site without pages
+	ErrEmpty = errors.New("sitemap lists no URLs")
+)
+
+// document holds the locations of a sitemap (urlset) or a sitemap index
+type document struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc        string `xml:"loc"`
+		ChangeFreq string `xml:"changefreq"`
+		Priority   string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// Parse parses a sitemap, returning the page URLs it lists and, for sitemap
+// indexes, the URLs of the sitemaps it refers to. XML sitemaps, gzipped or
+// not, and text sitemaps with one URL per line are accepted. Relative URLs
+// are resolved against base, which may be nil.
+func Parse(content []byte, base *url.URL) (pages, sitemaps []string, err error) {
+	found, sitemaps, err := ParsePages(content, base)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pageURLs(found), sitemaps, nil
+}
+
+// ParsePages parses a sitemap like Parse, returning its pages with their
+// change frequency and priority hints
+func ParsePages(content []byte, base *url.URL) (pages []Page, sitemaps []string, err error) {
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrNotSitemap, err)
+		}
+		content, err = io.ReadAll(io.LimitReader(reader, MaxSize+1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrNotSitemap, err)
+		}
+		if len(content) > MaxSize {
+			return nil, nil, fmt.Errorf("sitemap is larger than %d bytes", MaxSize)
+		}
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		return parseText(trimmed, base)
+	}
+
+	var doc document
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotSitemap, err)
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, u := range doc.URLs {
+			if loc := resolve(base, u.Loc); loc != "" {
+				pages = append(pages, newPage(loc, u.ChangeFreq, u.Priority))
+			}
+		}
+	case "sitemapindex":
+		for _, s := range doc.Sitemaps {
+			if loc := resolve(base, s.Loc); loc != "" {
+				sitemaps = append(sitemaps, loc)
+			}
+		}
+	default:
+		return nil, nil, ErrNotSitemap
+	}
+	return pages, sitemaps, nil
+}
+
+// parseText parses a text sitemap, in which every line is a URL
+func parseText(content []byte, base *url.URL) ([]Page, []string, error) {
+	var pages []Page
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, MaxSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			return nil, nil, ErrNotSitemap
+		}
+		if loc := resolve(base, line); loc != "" {
+			pages = append(pages, newPage(loc, "", ""))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotSitemap, err)
+	}
+	return pages, nil, nil
+}
+
+// resolve returns the absolute form of a location, or "" if it isn't a
+// valid HTTP URL
+func resolve(base *url.URL, loc string) string {
+	loc = strings.TrimSpace(loc)
+	u, err := url.Parse(loc)
+	if err != nil || loc == "" {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+// Fetcher fetches sitemaps and follows sitemap indexes
+type Fetcher struct {
+	// Client fetches the sitemaps. Defaults to a client with
+	// customhttp.DefaultClientOptions.
+	Client *http.Client
+	// Headers are sent with every request, e.g. for authentication
+	Headers map[string]string
+	// MaxSitemaps bounds the sitemaps fetched for one URL, counting the
+	// sitemap indexes. Defaults to DefaultMaxSitemaps.
+	MaxSitemaps int
+}
+
+// Fetch returns the page URLs listed by the sitemap at sitemapURL, following
+// sitemap indexes. URLs are in the order of the sitemaps and listed once.
+// If any of the sitemaps can't be read, the error is returned rather than a
+// partial list.
+func (f *Fetcher) Fetch(ctx context.Context, sitemapURL string) ([]string, error) {
+	pages, err := f.FetchPages(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	return pageURLs(pages), nil
+}
+
+// FetchPages returns the pages listed by the sitemap at sitemapURL like
+// Fetch, with their hints. A page listed more than once keeps the hints of
+// its first listing.
+func (f *Fetcher) FetchPages(ctx context.Context, sitemapURL string) ([]Page, error) {
+	limit := f.MaxSitemaps
+	if limit <= 0 {
+		limit = DefaultMaxSitemaps
+	}
+
+	var pages []Page
+	seenPages := make(map[string]bool)
+	seenSitemaps := map[string]bool{sitemapURL: true}
+	queue := []string{sitemapURL}
+	for fetched := 0; len(queue) > 0; fetched++ {
+		if fetched == limit {
+			return nil, fmt.Errorf("sitemap %s refers to more than %d sitemaps", sitemapURL, limit)
+		}
+		current := queue[0]
+		queue = queue[1:]
+
+		found, sitemaps, err := f.fetchOne(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, page := range found {
+			if !seenPages[page.URL] {
+				seenPages[page.URL] = true
+				pages = append(pages, page)
+			}
+		}
+		for _, sitemap := range sitemaps {
+			if !seenSitemaps[sitemap] {
+				seenSitemaps[sitemap] = true
+				queue = append(queue, sitemap)
+			}
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%s: %w", sitemapURL, ErrEmpty)
+	}
+	return pages, nil
+}
+
+// fetchOne fetches and parses a single sitemap
+func (f *Fetcher) fetchOne(ctx context.Context, sitemapURL string) ([]Page, []string, error) {
+	base, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sitemap URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sitemap URL: %w", err)
+	}
+	options := customhttp.DefaultClientOptions()
+	customhttp.AddHeaders(req, f.Headers, options.UserAgent)
+
+	client := f.Client
+	if client == nil {
+		client = customhttp.NewClient(options)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching sitemap %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("error fetching sitemap %s: status %d", sitemapURL, resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading sitemap %s: %w", sitemapURL, err)
+	}
+	if len(content) > MaxSize {
+		return nil, nil, fmt.Errorf("sitemap %s is larger than %d bytes", sitemapURL, MaxSize)
+	}
+
+	pages, sitemaps, err := ParsePages(content, base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", sitemapURL, err)
+	}
+	return pages, sitemaps, nil
+}
+
+// Filter selects the URLs of a sitemap to watch
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewFilter creates a filter from regular expressions matched anywhere in
+// the URL. A URL is selected if it matches any include pattern, or if
+// there are none, and no exclude pattern.
+func NewFilter(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+	for _, pattern := range include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+		}
+		f.include = append(f.include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	return f, nil
+}
+
+// Match reports whether the URL is selected. A nil filter selects every URL.
+func (f *Filter) Match(u string) bool {
+	if f == nil {
+		return true
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(u) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
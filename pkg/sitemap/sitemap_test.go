@@ -0,0 +1,117 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc><lastmod>2024-07-01</lastmod></url>
+  <url><loc> https://example.com/about </loc></url>
+  <url><loc>/news#top</loc></url>
+  <url><loc>ftp://example.com/file</loc></url>
+</urlset>`
+
+const testIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-pages.xml</loc></sitemap>
+  <sitemap><loc>/sitemap-news.xml.gz</loc></sitemap>
+</sitemapindex>`
+
+func gzipped(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/sitemap.xml")
+
+	pages, sitemaps, err := Parse([]byte(testURLSet), base)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/", "https://example.com/about", "https://example.com/news"}, pages)
+	require.Empty(t, sitemaps)
+
+	pages, sitemaps, err = Parse([]byte(testIndex), base)
+	require.NoError(t, err)
+	require.Empty(t, pages)
+	require.Equal(t, []string{"https://example.com/sitemap-pages.xml", "https://example.com/sitemap-news.xml.gz"}, sitemaps)
+
+	pages, _, err = Parse(gzipped(t, testURLSet), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/", "https://example.com/about"}, pages)
+
+	pages, _, err = Parse([]byte("https://example.com/a\n\nhttps://example.com/b\n"), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, pages)
+
+	for _, content := range []string{"<html><body>Not found</body></html>", "not a sitemap", "<urlset"} {
+		_, _, err := Parse([]byte(content), nil)
+		require.ErrorIs(t, err, ErrNotSitemap, content)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<sitemapindex>
+  <sitemap><loc>/pages.xml</loc></sitemap>
+  <sitemap><loc>/news.xml.gz</loc></sitemap>
+  <sitemap><loc>/sitemap.xml</loc></sitemap>
+</sitemapindex>`))
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<urlset><url><loc>/</loc></url><url><loc>/about</loc></url></urlset>`))
+	})
+	mux.HandleFunc("/news.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gzipped(t, `<urlset><url><loc>/news/1</loc></url><url><loc>/about</loc></url></urlset>`))
+	})
+	mux.HandleFunc("/empty.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<urlset></urlset>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := &Fetcher{Client: server.Client()}
+	pages, err := fetcher.Fetch(context.Background(), server.URL+"/sitemap.xml")
+	require.NoError(t, err)
+	require.Equal(t, []string{server.URL + "/", server.URL + "/about", server.URL + "/news/1"}, pages)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/empty.xml")
+	require.ErrorIs(t, err, ErrEmpty)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing.xml")
+	require.ErrorContains(t, err, "status 404")
+
+	fetcher.MaxSitemaps = 2
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/sitemap.xml")
+	require.ErrorContains(t, err, "more than 2 sitemaps")
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := NewFilter([]string{"/blog/", "/news/"}, []string{`\?page=`, "/drafts/"})
+	require.NoError(t, err)
+
+	require.True(t, filter.Match("https://example.com/blog/post"))
+	require.True(t, filter.Match("https://example.com/news/1"))
+	require.False(t, filter.Match("https://example.com/about"))
+	require.False(t, filter.Match("https://example.com/blog/?page=2"))
+	require.False(t, filter.Match("https://example.com/blog/drafts/x"))
+
+	var none *Filter
+	require.True(t, none.Match("https://example.com/about"))
+
+	_, err = NewFilter([]string{"("}, nil)
+	require.ErrorContains(t, err, "invalid include pattern")
+}
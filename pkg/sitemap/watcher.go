@@ -0,0 +1,155 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// DefaultRefreshInterval is how often a watcher re-reads its sitemap
+const DefaultRefreshInterval = time.Hour
+
+// Watcher keeps a monitor in a manager for every URL of a sitemap selected
+// by a filter. Monitors are added for URLs that appear in the sitemap and
+// removed once they leave it. URLs the manager already watches, e.g.
+// because they were added by hand or by another sitemap, are left alone.
+type Watcher struct {
+	// URL is the URL of the sitemap or sitemap index
+	URL string
+	// Fetcher reads the sitemap. Defaults to a Fetcher with default
+	// settings.
+	Fetcher *Fetcher
+	// Filter selects the URLs to watch. Every URL is watched if it's nil.
+	Filter *Filter
+	// Manager runs the monitors
+	Manager *monitor.Manager
+	// Config returns the configuration of the monitor of a URL. Defaults
+	// to monitor.DefaultConfig.
+	Config func(url string) *monitor.Config
+	// UseHints sets the interval of each new monitor from the change
+	// frequency and priority of its page, see Page.Interval, unless it runs
+	// on a schedule or once
+	UseHints bool
+	// Interval is the time between refreshes of the sitemap. Defaults to
+	// DefaultRefreshInterval.
+	Interval time.Duration
+	// Clock drives refreshes. Defaults to monitor.RealClock.
+	Clock monitor.Clock
+
+	mu sync.Mutex
+	// owned holds the URLs whose monitors the watcher added
+	owned map[string]bool
+}
+
+// Sync reads the sitemap and adds and removes monitors to match it,
+// returning the URLs it added and removed. If the sitemap can't be read,
+// nothing is changed. Monitors that can't be added are reported in the
+// error, while the others are still added.
+func (w *Watcher) Sync(ctx context.Context) (added, removed []string, err error) {
+	fetcher := w.Fetcher
+	if fetcher == nil {
+		fetcher = &Fetcher{}
+	}
+	pages, err := fetcher.FetchPages(ctx, w.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.owned == nil {
+		w.owned = make(map[string]bool)
+	}
+
+	var errs []error
+	listed := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		url := page.URL
+		if !w.Filter.Match(url) {
+			continue
+		}
+		listed[url] = true
+		if w.owned[url] {
+			continue
+		}
+		if _, err := w.Manager.GetMonitor(url); err == nil {
+			continue
+		}
+
+		config := monitor.DefaultConfig(url)
+		if w.Config != nil {
+			config = w.Config(url)
+		}
+		if w.UseHints && config.Schedule == nil && config.At.IsZero() {
+			config.Interval = page.Interval(config.Interval)
+		}
+		if _, err := w.Manager.AddMonitorWithConfig(config); err != nil {
+			errs = append(errs, fmt.Errorf("error adding monitor for %s: %w", url, err))
+			continue
+		}
+		w.owned[url] = true
+		added = append(added, url)
+	}
+
+	for url := range w.owned {
+		if listed[url] {
+			continue
+		}
+		delete(w.owned, url)
+		// The monitor may have finished or been removed by hand already
+		if err := w.Manager.RemoveMonitor(url); err == nil {
+			removed = append(removed, url)
+		}
+	}
+	sort.Strings(removed)
+
+	return added, removed, errors.Join(errs...)
+}
+
+// URLs returns the URLs whose monitors the watcher added, sorted
+func (w *Watcher) URLs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	urls := make([]string, 0, len(w.owned))
+	for url := range w.owned {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Run syncs the monitors every Interval until ctx is done. The outcome of
+// every refresh is passed to report, which may be nil. Run doesn't sync
+// right away; call Sync first to set up the monitors before starting the
+// manager.
+func (w *Watcher) Run(ctx context.Context, report func(added, removed []string, err error)) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	clock := w.Clock
+	if clock == nil {
+		clock = monitor.RealClock{}
+	}
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			added, removed, err := w.Sync(ctx)
+			if report != nil && ctx.Err() == nil {
+				report(added, removed, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package sitemap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/monitortest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherSync(t *testing.T) {
+	var content atomic.Value
+	content.Store("https://example.com/a\nhttps://example.com/b\nhttps://example.com/skip/c\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+
+	manager := monitor.NewManager()
+	_, err := manager.AddMonitorWithConfig(monitor.DefaultConfig("https://example.com/b"))
+	require.NoError(t, err)
+
+	filter, err := NewFilter(nil, []string{"/skip/"})
+	require.NoError(t, err)
+	w := &Watcher{
+		URL:     server.URL,
+		Fetcher: &Fetcher{Client: server.Client()},
+		Filter:  filter,
+		Manager: manager,
+		Config: func(url string) *monitor.Config {
+			config := monitor.DefaultConfig(url)
+			config.Interval = time.Hour
+			return config
+		},
+	}
+
+	// b was added by hand, so it's left to its owner
+	added, removed, err := w.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/a"}, added)
+	require.Empty(t, removed)
+	require.ElementsMatch(t, []string{"https://example.com/a", "https://example.com/b"}, manager.ListMonitors())
+	m, err := manager.GetMonitor("https://example.com/a")
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, m.GetConfig().Interval)
+
+	content.Store("https://example.com/c\n")
+	added, removed, err = w.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/c"}, added)
+	require.Equal(t, []string{"https://example.com/a"}, removed)
+	require.ElementsMatch(t, []string{"https://example.com/b", "https://example.com/c"}, manager.ListMonitors())
+	require.Equal(t, []string{"https://example.com/c"}, w.URLs())
+
+	// A broken sitemap leaves the monitors in place
+	content.Store("")
+	_, _, err = w.Sync(context.Background())
+	require.ErrorIs(t, err, ErrEmpty)
+	require.Equal(t, []string{"https://example.com/c"}, w.URLs())
+}
+
+func TestWatcherHints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testHints))
+	}))
+	defer server.Close()
+
+	manager := monitor.NewManager()
+	w := &Watcher{
+		URL:      server.URL,
+		Fetcher:  &Fetcher{Client: server.Client()},
+		Manager:  manager,
+		UseHints: true,
+	}
+	_, _, err := w.Sync(context.Background())
+	require.NoError(t, err)
+
+	intervals := make(map[string]time.Duration)
+	for _, url := range manager.ListMonitors() {
+		m, err := manager.GetMonitor(url)
+		require.NoError(t, err)
+		intervals[url] = m.GetConfig().Interval
+	}
+	require.Equal(t, map[string]time.Duration{
+		"https://example.com/":        7*time.Minute + 30*time.Second,
+		"https://example.com/about":   7 * 24 * time.Hour,
+		"https://example.com/news":    5 * time.Minute,
+		"https://example.com/archive": 10 * time.Minute,
+	}, intervals)
+}
+
+func TestWatcherRun(t *testing.T) {
+	var content atomic.Value
+	content.Store("https://example.com/a\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+
+	clock := monitortest.NewFakeClock(time.Now())
+	w := &Watcher{
+		URL:      server.URL,
+		Fetcher:  &Fetcher{Client: server.Client()},
+		Manager:  monitor.NewManager(),
+		Interval: time.Minute,
+		Clock:    clock,
+	}
+	_, _, err := w.Sync(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan error)
+	go w.Run(ctx, func(added, removed []string, err error) {
+		reports <- err
+	})
+
+	content.Store("https://example.com/b\n")
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	require.NoError(t, <-reports)
+	require.Equal(t, []string{"https://example.com/b"}, w.URLs())
+}
@@ -0,0 +1,123 @@
+package summarize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+const (
+	// DefaultPrompt is the instruction sent to the model with the diff
+	DefaultPrompt = "You summarize changes to monitored web pages for alerts. Reply with one short sentence, " +
+		"without preamble, saying what changed in the unified diff of the page at the given URL."
+
+	// maxDiffBytes bounds the part of the diff sent to the model
+	maxDiffBytes = 16 << 10
+
+	// maxResponseSize bounds the responses read from the endpoint
+	maxResponseSize = 1 << 20
+)
+
+// LLM summarizes changes with a language model behind an OpenAI-compatible
+// chat completions endpoint, e.g. https://api.openai.com/v1/chat/completions
+// or a local server such as Ollama or llama.cpp. The diff of the change,
+// capped to 16 KiB, is sent to the endpoint, which is refused in offline
+// mode.
+type LLM struct {
+	// URL is the chat completions endpoint
+	URL string
+	// Model is the name of the model, if the endpoint requires one
+	Model string
+	// APIKey is sent as a bearer token, if set
+	APIKey string
+	// Prompt replaces DefaultPrompt
+	Prompt string
+	// Client sends the requests. Defaults to a client with a 30s timeout.
+	Client *http.Client
+}
+
+// Summarize implements Summarizer
+func (l *LLM) Summarize(ctx context.Context, change monitor.Change) (string, error) {
+	if err := offline.Check("summarizer"); err != nil {
+		return "", err
+	}
+
+	diff := change.Diff
+	if diff == "" {
+		diff = monitor.FormatHunks(change.Hunks)
+	}
+	if len(diff) > maxDiffBytes {
+		diff = diff[:maxDiffBytes] + "\n[diff truncated]"
+	}
+	prompt := l.Prompt
+	if prompt == "" {
+		prompt = DefaultPrompt
+	}
+
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	body, err := json.Marshal(struct {
+		Model    string    `json:"model,omitempty"`
+		Messages []message `json:"messages"`
+	}{
+		Model: l.Model,
+		Messages: []message{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: fmt.Sprintf("URL: %s\n\n%s", change.URL, diff)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.UserAgent())
+	if l.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.APIKey)
+	}
+
+	client := l.Client
+	if client == nil {
+		client = customhttp.NewClient(&customhttp.ClientOptions{Timeout: time.Second * 30, FollowRedirects: true})
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error summarizing change: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error summarizing change: status code %d", resp.StatusCode)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&completion); err != nil {
+		return "", fmt.Errorf("error summarizing change: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("error summarizing change: no choices in the response")
+	}
+
+	// Keep the first line, as summaries are shown in one
+	summary, _, _ := strings.Cut(strings.TrimSpace(completion.Choices[0].Message.Content), "\n")
+	return strings.TrimSpace(summary), nil
+}
@@ -0,0 +1,123 @@
+// Package summarize describes content changes in one line, e.g. `"$10"
+// changed to "$12"`, so notifications say what changed instead of where the
+// bytes first differ.
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+)
+
+// maxQuoteLength is the number of characters of a line quoted in a summary
+const maxQuoteLength = 60
+
+// Summarizer describes a change in one line
+type Summarizer interface {
+	// Summarize returns the summary of a content change, or an empty string
+	// if it has nothing to add to the change's details
+	Summarize(ctx context.Context, change monitor.Change) (string, error)
+}
+
+// Apply sets the summary of a content change. If s fails, the heuristic
+// summary is used and the error returned.
+func Apply(ctx context.Context, s Summarizer, change monitor.Change) (monitor.Change, error) {
+	if s == nil || !change.HasChanged || len(change.Hunks) == 0 {
+		return change, nil
+	}
+
+	summary, err := s.Summarize(ctx, change)
+	if err != nil {
+		summary, _ = Heuristic{}.Summarize(ctx, change)
+	}
+	change.Summary = summary
+	return change, err
+}
+
+// Heuristic summarizes a change from its line diff: a single changed line
+// is narrowed down to the words that changed, and other changes are
+// described by the number of lines added and removed and the first of them
+type Heuristic struct{}
+
+// Summarize implements Summarizer
+func (Heuristic) Summarize(ctx context.Context, change monitor.Change) (string, error) {
+	var added, removed []string
+	for _, hunk := range change.Hunks {
+		for _, line := range hunk.Lines {
+			if strings.TrimSpace(line.Text) == "" {
+				continue
+			}
+			switch line.Type {
+			case monitor.DiffAdded:
+				added = append(added, line.Text)
+			case monitor.DiffRemoved:
+				removed = append(removed, line.Text)
+			}
+		}
+	}
+
+	places := ""
+	if len(change.Hunks) > 1 {
+		places = fmt.Sprintf(" in %d places", len(change.Hunks))
+	}
+
+	switch {
+	case len(added) == 1 && len(removed) == 1:
+		before, after := changedWords(removed[0], added[0])
+		switch {
+		case before == "" && after == "":
+			return "Whitespace changed", nil
+		case before == "":
+			return fmt.Sprintf("Added %s", quote(after)), nil
+		case after == "":
+			return fmt.Sprintf("Removed %s", quote(before)), nil
+		}
+		return fmt.Sprintf("%s changed to %s", quote(before), quote(after)), nil
+	case len(removed) == 0 && len(added) > 0:
+		return fmt.Sprintf("Added %s%s: %s", lines(len(added)), places, quote(added[0])), nil
+	case len(added) == 0 && len(removed) > 0:
+		return fmt.Sprintf("Removed %s%s: %s", lines(len(removed)), places, quote(removed[0])), nil
+	case len(added) > 0:
+		return fmt.Sprintf("Changed %s%s (%d added, %d removed): %s",
+			lines(len(added)+len(removed)), places, len(added), len(removed), quote(added[0])), nil
+	}
+	return "Whitespace changed", nil
+}
+
+// changedWords returns the words of before and after between their common
+// leading and trailing words
+func changedWords(before, after string) (string, string) {
+	oldWords, newWords := strings.Fields(before), strings.Fields(after)
+
+	prefix := 0
+	for prefix < len(oldWords) && prefix < len(newWords) && oldWords[prefix] == newWords[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldWords)-prefix && suffix < len(newWords)-prefix &&
+		oldWords[len(oldWords)-1-suffix] == newWords[len(newWords)-1-suffix] {
+		suffix++
+	}
+
+	return strings.Join(oldWords[prefix:len(oldWords)-suffix], " "), strings.Join(newWords[prefix:len(newWords)-suffix], " ")
+}
+
+// lines formats a number of lines
+func lines(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
+
+// quote quotes text for a summary, shortened to maxQuoteLength characters
+func quote(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > maxQuoteLength {
+		text = string([]rune(text)[:maxQuoteLength-1]) + "…"
+	}
+	return `"` + text + `"`
+}
@@ -0,0 +1,145 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nemuizzz/hawkeye/pkg/monitor"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/stretchr/testify/require"
+)
+
+// hunk builds a hunk of lines prefixed with '-', '+' or ' '
+func hunk(lines ...string) monitor.DiffHunk {
+	var h monitor.DiffHunk
+	for _, line := range lines {
+		kind := monitor.DiffContext
+		switch line[0] {
+		case '-':
+			kind = monitor.DiffRemoved
+		case '+':
+			kind = monitor.DiffAdded
+		}
+		h.Lines = append(h.Lines, monitor.DiffLine{Type: kind, Text: line[1:]})
+	}
+	return h
+}
+
+func TestHeuristic(t *testing.T) {
+	tests := []struct {
+		name  string
+		hunks []monitor.DiffHunk
+		want  string
+	}{
+		{
+			name:  "changed words",
+			hunks: []monitor.DiffHunk{hunk(" <h1>Shop</h1>", "-<p>Price: $10 per month</p>", "+<p>Price: $12 per month</p>")},
+			want:  `"$10" changed to "$12"`,
+		},
+		{
+			name:  "added words",
+			hunks: []monitor.DiffHunk{hunk("-Open Monday", "+Open Monday to Friday")},
+			want:  `Added "to Friday"`,
+		},
+		{
+			name:  "added lines",
+			hunks: []monitor.DiffHunk{hunk("+<li>New release 2.0</li>", "+<li>Bug fixes</li>", "+")},
+			want:  `Added 2 lines: "<li>New release 2.0</li>"`,
+		},
+		{
+			name:  "removed lines in several places",
+			hunks: []monitor.DiffHunk{hunk("-Sold out"), hunk("-Back soon")},
+			want:  `Removed 2 lines in 2 places: "Sold out"`,
+		},
+		{
+			name:  "mixed",
+			hunks: []monitor.DiffHunk{hunk("-a", "-b", "+c")},
+			want:  `Changed 3 lines (1 added, 2 removed): "c"`,
+		},
+		{
+			name:  "whitespace",
+			hunks: []monitor.DiffHunk{hunk("-a  b", "+a b")},
+			want:  "Whitespace changed",
+		},
+		{
+			name:  "long line",
+			hunks: []monitor.DiffHunk{hunk("+" + strings.Repeat("x", 100))},
+			want:  `Added 1 line: "` + strings.Repeat("x", 59) + `…"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := Heuristic{}.Summarize(context.Background(), monitor.Change{Hunks: tt.hunks})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, summary)
+		})
+	}
+}
+
+func TestLLM(t *testing.T) {
+	var request struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if fail {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": " The price rose from $10 to $12.\nIt is a 20% increase."}}]}`)
+	}))
+	defer server.Close()
+
+	llm := &LLM{URL: server.URL, Model: "small", APIKey: "key"}
+	change := monitor.Change{
+		URL:        "https://shop.example.com",
+		HasChanged: true,
+		Hunks:      []monitor.DiffHunk{hunk("-Price: $10", "+Price: $12")},
+	}
+
+	change, err := Apply(context.Background(), llm, change)
+	require.NoError(t, err)
+	require.Equal(t, "The price rose from $10 to $12.", change.Summary)
+	require.Equal(t, "small", request.Model)
+	require.Len(t, request.Messages, 2)
+	require.Equal(t, DefaultPrompt, request.Messages[0].Content)
+	require.Contains(t, request.Messages[1].Content, "https://shop.example.com")
+	require.Contains(t, request.Messages[1].Content, "+Price: $12")
+
+	// Failures fall back to the heuristic
+	fail = true
+	change, err = Apply(context.Background(), llm, change)
+	require.ErrorContains(t, err, "status code 429")
+	require.Equal(t, `"$10" changed to "$12"`, change.Summary)
+
+	offline.Set(true)
+	defer offline.Set(false)
+	_, err = llm.Summarize(context.Background(), change)
+	require.ErrorIs(t, err, offline.ErrOffline)
+}
+
+func TestApply(t *testing.T) {
+	// Changes without a diff, such as errors and value changes, are left
+	// alone
+	change := monitor.Change{URL: "https://example.com", Error: "timeout"}
+	applied, err := Apply(context.Background(), Heuristic{}, change)
+	require.NoError(t, err)
+	require.Equal(t, change, applied)
+
+	changed := monitor.Change{HasChanged: true, Hunks: []monitor.DiffHunk{hunk("+new")}}
+	applied, err = Apply(context.Background(), nil, changed)
+	require.NoError(t, err)
+	require.Empty(t, applied.Summary)
+}
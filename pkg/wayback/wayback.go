@@ -0,0 +1,220 @@
+// Package wayback reads snapshots of a URL from the Internet Archive's
+// Wayback Machine, so that the history of a page from before it was watched
+// can be replayed through change detection.
+package wayback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	customhttp "github.com/nemuizzz/hawkeye/pkg/http"
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/nemuizzz/hawkeye/pkg/recorder"
+	"github.com/nemuizzz/hawkeye/pkg/version"
+)
+
+const (
+	// DefaultBaseURL is the address of the Wayback Machine
+	DefaultBaseURL = "https://web.archive.org"
+
+	// DefaultLimit is the number of snapshots read when no limit is set
+	DefaultLimit = 20
+
+	// timestampFormat is the layout of Wayback Machine timestamps
+	timestampFormat = "20060102150405"
+
+	// maxSnapshotSize bounds the body read of a snapshot
+	maxSnapshotSize = 10 << 20
+)
+
+// Snapshot is a capture of a URL by the Wayback Machine
+type Snapshot struct {
+	// Time is when the URL was captured
+	Time time.Time
+	// URL is the captured URL as the archive knows it
+	URL string
+	// Digest is a hash of the captured content
+	Digest string
+}
+
+// Archive reads snapshots from the Wayback Machine. Only captures with a 200
+// status are read, and of successive captures with the same content only
+// the first, so every snapshot after the first is a change.
+type Archive struct {
+	// BaseURL is the address of the archive. Defaults to DefaultBaseURL.
+	BaseURL string
+	// Client fetches from the archive. Defaults to a client with
+	// customhttp.DefaultClientOptions.
+	Client *http.Client
+	// From and To bound the time of the snapshots, if not zero
+	From time.Time
+	To   time.Time
+	// Limit is the number of most recent snapshots read. Defaults to
+	// DefaultLimit.
+	Limit int
+	// Delay is the time between requests, as the archive limits the rate
+	// of clients
+	Delay time.Duration
+}
+
+// client returns the HTTP client of the archive
+func (a *Archive) client() *http.Client {
+	if a.Client != nil {
+		return a.Client
+	}
+	return customhttp.NewClient(customhttp.DefaultClientOptions())
+}
+
+// baseURL returns the address of the archive
+func (a *Archive) baseURL() string {
+	if a.BaseURL != "" {
+		return a.BaseURL
+	}
+	return DefaultBaseURL
+}
+
+// Snapshots lists the snapshots of rawURL, oldest first
+func (a *Archive) Snapshots(ctx context.Context, rawURL string) ([]Snapshot, error) {
+	if err := offline.Check("Wayback Machine"); err != nil {
+		return nil, err
+	}
+
+	limit := a.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	query := url.Values{
+		"url":      {rawURL},
+		"output":   {"json"},
+		"fl":       {"timestamp,original,digest"},
+		"filter":   {"statuscode:200"},
+		"collapse": {"digest"},
+		// A negative limit keeps the most recent captures
+		"limit": {strconv.Itoa(-limit)},
+	}
+	if !a.From.IsZero() {
+		query.Set("from", a.From.UTC().Format(timestampFormat))
+	}
+	if !a.To.IsZero() {
+		query.Set("to", a.To.UTC().Format(timestampFormat))
+	}
+
+	body, _, err := a.get(ctx, a.baseURL()+"/cdx/search/cdx?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots of %s: %w", rawURL, err)
+	}
+
+	// The first row names the fields; an unknown URL returns no rows
+	var rows [][]string
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &rows); err != nil {
+			return nil, fmt.Errorf("error listing snapshots of %s: %w", rawURL, err)
+		}
+	}
+	snapshots := make([]Snapshot, 0, len(rows))
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(row) != 3 {
+			return nil, fmt.Errorf("error listing snapshots of %s: unexpected row %q", rawURL, row)
+		}
+		captured, err := time.Parse(timestampFormat, row[0])
+		if err != nil {
+			return nil, fmt.Errorf("error listing snapshots of %s: %w", rawURL, err)
+		}
+		snapshots = append(snapshots, Snapshot{Time: captured, URL: row[1], Digest: row[2]})
+	}
+	return snapshots, nil
+}
+
+// Fetch reads the content of a snapshot as it was captured, without the
+// links the Wayback Machine rewrites for browsing
+func (a *Archive) Fetch(ctx context.Context, snapshot Snapshot) ([]byte, http.Header, error) {
+	if err := offline.Check("Wayback Machine"); err != nil {
+		return nil, nil, err
+	}
+
+	raw := fmt.Sprintf("%s/web/%sid_/%s", a.baseURL(), snapshot.Time.UTC().Format(timestampFormat), snapshot.URL)
+	body, header, err := a.get(ctx, raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching snapshot of %s from %s: %w", snapshot.URL, snapshot.Time.Format(time.RFC3339), err)
+	}
+	return body, header, nil
+}
+
+// Backfill reads the snapshots of rawURL as recorded interactions, oldest
+// first, to be merged into its cassette. Snapshots that fail to load are
+// left out and their errors returned along with the others.
+func (a *Archive) Backfill(ctx context.Context, rawURL string) ([]recorder.Interaction, error) {
+	// Share one client between the requests
+	archive := *a
+	archive.Client = a.client()
+	a = &archive
+
+	snapshots, err := a.Snapshots(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var interactions []recorder.Interaction
+	var errs []error
+	for _, snapshot := range snapshots {
+		select {
+		case <-ctx.Done():
+			return interactions, errors.Join(append(errs, ctx.Err())...)
+		case <-time.After(a.Delay):
+		}
+
+		body, header, err := a.Fetch(ctx, snapshot)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Keep the headers change detection looks at
+		recorded := http.Header{}
+		for _, name := range []string{"Content-Type", "Memento-Datetime"} {
+			if value := header.Get(name); value != "" {
+				recorded.Set(name, value)
+			}
+		}
+		interactions = append(interactions, recorder.Interaction{
+			RecordedAt: snapshot.Time,
+			Request:    recorder.RecordedRequest{Method: http.MethodGet, URL: rawURL},
+			Response:   recorder.NewResponse(http.StatusOK, recorded, body),
+		})
+	}
+	return interactions, errors.Join(errs...)
+}
+
+// get fetches raw from the archive
+func (a *Archive) get(ctx context.Context, raw string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	customhttp.AddHeaders(req, nil, version.UserAgent())
+
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("archive returned status code %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSnapshotSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Header, nil
+}
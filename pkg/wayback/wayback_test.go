@@ -0,0 +1,84 @@
+package wayback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nemuizzz/hawkeye/pkg/offline"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveServer serves a CDX listing and the snapshots of it
+func archiveServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cdx/search/cdx":
+			require.Equal(t, "https://example.com/pricing", r.URL.Query().Get("url"))
+			require.Equal(t, "digest", r.URL.Query().Get("collapse"))
+			require.Equal(t, "-20", r.URL.Query().Get("limit"))
+			if r.URL.Query().Get("from") == "20300101000000" {
+				return
+			}
+			w.Write([]byte(`[["timestamp","original","digest"],
+				["20230105120000","https://example.com/pricing","AAA"],
+				["20230610080000","https://example.com/pricing","BBB"],
+				["20240101000000","https://example.com/pricing","CCC"]]`))
+		case "/web/20230105120000id_/https://example.com/pricing":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>$10</p>"))
+		case "/web/20230610080000id_/https://example.com/pricing":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>$12</p>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSnapshots(t *testing.T) {
+	server := archiveServer(t)
+	archive := &Archive{BaseURL: server.URL}
+
+	snapshots, err := archive.Snapshots(context.Background(), "https://example.com/pricing")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	require.Equal(t, time.Date(2023, 1, 5, 12, 0, 0, 0, time.UTC), snapshots[0].Time)
+	require.Equal(t, "BBB", snapshots[1].Digest)
+
+	// No captures in the range
+	archive.From = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots, err = archive.Snapshots(context.Background(), "https://example.com/pricing")
+	require.NoError(t, err)
+	require.Empty(t, snapshots)
+}
+
+func TestBackfill(t *testing.T) {
+	server := archiveServer(t)
+	archive := &Archive{BaseURL: server.URL}
+
+	// The last snapshot fails to load and is left out
+	interactions, err := archive.Backfill(context.Background(), "https://example.com/pricing")
+	require.ErrorContains(t, err, "error fetching snapshot of https://example.com/pricing from 2024-01-01T00:00:00Z: archive returned status code 404")
+	require.Len(t, interactions, 2)
+
+	require.Equal(t, "https://example.com/pricing", interactions[0].Request.URL)
+	require.Equal(t, time.Date(2023, 6, 10, 8, 0, 0, 0, time.UTC), interactions[1].RecordedAt)
+	require.Equal(t, http.StatusOK, interactions[1].Response.StatusCode)
+	require.Equal(t, "text/html", interactions[1].Response.Header.Get("Content-Type"))
+	body, err := interactions[1].Response.BodyBytes()
+	require.NoError(t, err)
+	require.Equal(t, "<p>$12</p>", string(body))
+}
+
+func TestOffline(t *testing.T) {
+	offline.Set(true)
+	defer offline.Set(false)
+
+	_, err := (&Archive{BaseURL: "http://127.0.0.1:0"}).Backfill(context.Background(), "https://example.com")
+	require.ErrorIs(t, err, offline.ErrOffline)
+}
@@ -0,0 +1,46 @@
+// Package zabbix formats the results of low-level discovery (LLD), so
+// hawkeye monitors can be discovered as items of a Zabbix host.
+package zabbix
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Discovery is the result of a low-level discovery rule: the macros of each
+// discovered entity, such as [{"{#URL}": "https://example.com"}]
+type Discovery []map[string]string
+
+// Add adds an entity with its macros, named without braces, e.g. "URL"
+func (d *Discovery) Add(macros map[string]string) {
+	entity := make(map[string]string, len(macros))
+	for name, value := range macros {
+		entity[Macro(name)] = value
+	}
+	*d = append(*d, entity)
+}
+
+// MarshalJSON encodes the discovery as a JSON array, which is empty rather
+// than null without entities so Zabbix removes the discovered items
+func (d Discovery) MarshalJSON() ([]byte, error) {
+	if d == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]map[string]string(d))
+}
+
+// Macro returns the LLD macro of name, e.g. "{#URL}" for "url". Macro names
+// may only hold A-Z, 0-9, _ and ., so other characters become _.
+func Macro(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, name)
+	return "{#" + name + "}"
+}
@@ -0,0 +1,30 @@
+package zabbix
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMacro(t *testing.T) {
+	require.Equal(t, "{#URL}", Macro("url"))
+	require.Equal(t, "{#CHECK_COUNT}", Macro("check_count"))
+	require.Equal(t, "{#LAST_CHECK.TIME}", Macro("last-check.time"))
+}
+
+func TestDiscovery(t *testing.T) {
+	var discovery Discovery
+	data, err := json.Marshal(discovery)
+	require.NoError(t, err)
+	require.JSONEq(t, `[]`, string(data))
+
+	discovery.Add(map[string]string{"url": "https://example.com", "method": "hash"})
+	discovery.Add(map[string]string{"url": "https://example.org", "method": "status"})
+	data, err = json.Marshal(discovery)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"{#URL}": "https://example.com", "{#METHOD}": "hash"},
+		{"{#URL}": "https://example.org", "{#METHOD}": "status"}
+	]`, string(data))
+}